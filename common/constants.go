@@ -34,6 +34,9 @@ var DefaultCollapseSidebar = false // default value of collapse sidebar
 
 var SessionSecret = uuid.New().String()
 var CryptoSecret = uuid.New().String()
+
+// CryptoSecretConfigured 表示 CryptoSecret 来自 CRYPTO_SECRET / SESSION_SECRET，而非进程内随机生成
+var CryptoSecretConfigured = false
 var SessionCookieSecure = false
 var SessionCookieTrustedURLs []string
 
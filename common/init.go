@@ -59,8 +59,10 @@ func InitEnv() {
 	}
 	if os.Getenv("CRYPTO_SECRET") != "" {
 		CryptoSecret = os.Getenv("CRYPTO_SECRET")
+		CryptoSecretConfigured = true
 	} else {
 		CryptoSecret = SessionSecret
+		CryptoSecretConfigured = os.Getenv("SESSION_SECRET") != ""
 	}
 	if err := InitSessionCookieSettings(); err != nil {
 		log.Fatal(err)
@@ -192,12 +194,16 @@ func initConstantEnv() {
 	constant.NotificationLimitDurationMinute = GetEnvOrDefault("NOTIFICATION_LIMIT_DURATION_MINUTE", 10)
 	// GenerateDefaultToken 是否生成初始令牌，默认关闭。
 	constant.GenerateDefaultToken = GetEnvOrDefaultBool("GENERATE_DEFAULT_TOKEN", false)
+	// TokenKeyRevealEnabled 兼容模式：额外保存令牌明文，允许控制台再次查看完整密钥，默认关闭。
+	constant.TokenKeyRevealEnabled = GetEnvOrDefaultBool("TOKEN_KEY_REVEAL_ENABLED", false)
 	// 是否启用错误日志
 	constant.ErrorLogEnabled = GetEnvOrDefaultBool("ERROR_LOG_ENABLED", false)
 	// 任务轮询时查询的最大数量
 	constant.TaskQueryLimit = GetEnvOrDefault("TASK_QUERY_LIMIT", 1000)
 	// 异步任务超时时间（分钟），超过此时间未完成的任务将被标记为失败并退款。0 表示禁用。
 	constant.TaskTimeoutMinutes = GetEnvOrDefault("TASK_TIMEOUT_MINUTES", 1440)
+	// Claude Message Batches 超时时间（分钟），上游批次最长处理 24 小时，默认多留 6 小时拉取结果。
+	constant.ClaudeBatchTimeoutMinutes = GetEnvOrDefault("CLAUDE_BATCH_TIMEOUT_MINUTES", 1800)
 
 	soraPatchStr := GetEnvOrDefaultString("TASK_PRICE_PATCH", "")
 	if soraPatchStr != "" {
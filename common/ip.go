@@ -49,3 +49,38 @@ func IsIpInCIDRList(ip net.IP, cidrList []string) bool {
 	}
 	return false
 }
+
+// IsCIDRWithinList 判断单个 IP 或 CIDR 网段是否完整落在 cidrList 的某一项之内
+func IsCIDRWithinList(cidr string, cidrList []string) bool {
+	network := parseIPNetwork(cidr)
+	if network == nil {
+		return false
+	}
+	ones, bits := network.Mask.Size()
+	for _, item := range cidrList {
+		outer := parseIPNetwork(item)
+		if outer == nil {
+			continue
+		}
+		outerOnes, outerBits := outer.Mask.Size()
+		if outerBits == bits && outerOnes <= ones && outer.Contains(network.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIPNetwork 将单个 IP 视为主机网段（/32 或 /128）
+func parseIPNetwork(s string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 客户端证书标识的前缀，令牌的 client_cert 字段保存其中一种
+const (
+	ClientCertIdentitySHA256  = "sha256:"
+	ClientCertIdentitySubject = "subject:"
+	ClientCertIdentitySAN     = "san:"
+)
+
+// TLSListenerConfig 可选的 TLS 监听配置，由 TLS_* 环境变量给出
+type TLSListenerConfig struct {
+	Port      string
+	TLSConfig *tls.Config
+}
+
+// LoadTLSListenerConfig 读取 TLS_CERT_FILE / TLS_KEY_FILE，未配置时返回 nil。
+// 配置了 TLS_CLIENT_CA_FILE 时校验客户端证书：默认仅在客户端提供证书时校验，
+// TLS_CLIENT_CERT_REQUIRED=true 时拒绝未携带证书的连接。
+func LoadTLSListenerConfig() (*TLSListenerConfig, error) {
+	certFile := strings.TrimSpace(os.Getenv("TLS_CERT_FILE"))
+	keyFile := strings.TrimSpace(os.Getenv("TLS_KEY_FILE"))
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+	if caFile := strings.TrimSpace(os.Getenv("TLS_CLIENT_CA_FILE")); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS_CLIENT_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("TLS_CLIENT_CA_FILE does not contain any PEM certificate")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if GetEnvOrDefaultBool("TLS_CLIENT_CERT_REQUIRED", false) {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if GetEnvOrDefaultBool("TLS_CLIENT_CERT_REQUIRED", false) {
+		return nil, errors.New("TLS_CLIENT_CERT_REQUIRED requires TLS_CLIENT_CA_FILE")
+	}
+	return &TLSListenerConfig{
+		Port:      GetEnvOrDefaultString("TLS_PORT", "8443"),
+		TLSConfig: config,
+	}, nil
+}
+
+// VerifiedClientCertificate 返回已通过 TLS_CLIENT_CA_FILE 校验的客户端叶子证书，未提供或未校验时返回 nil
+func VerifiedClientCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// ClientCertIdentities 按匹配优先级列出证书的全部标识：指纹、各 SAN、Subject
+func ClientCertIdentities(cert *x509.Certificate) []string {
+	if cert == nil {
+		return nil
+	}
+	sum := sha256.Sum256(cert.Raw)
+	identities := []string{ClientCertIdentitySHA256 + hex.EncodeToString(sum[:])}
+	for _, uri := range cert.URIs {
+		identities = append(identities, ClientCertIdentitySAN+uri.String())
+	}
+	for _, name := range cert.DNSNames {
+		identities = append(identities, ClientCertIdentitySAN+name)
+	}
+	for _, email := range cert.EmailAddresses {
+		identities = append(identities, ClientCertIdentitySAN+email)
+	}
+	for _, ip := range cert.IPAddresses {
+		identities = append(identities, ClientCertIdentitySAN+ip.String())
+	}
+	if subject := cert.Subject.String(); subject != "" {
+		identities = append(identities, ClientCertIdentitySubject+subject)
+	}
+	return identities
+}
+
+// NormalizeClientCertIdentity 规范化管理员填写的证书标识；指纹允许冒号分隔与大写
+func NormalizeClientCertIdentity(identity string) (string, error) {
+	identity = strings.TrimSpace(identity)
+	if identity == "" {
+		return "", nil
+	}
+	prefix, value, ok := strings.Cut(identity, ":")
+	value = strings.TrimSpace(value)
+	if !ok || value == "" {
+		return "", errors.New("证书标识格式应为 sha256:<指纹>、san:<值> 或 subject:<DN>")
+	}
+	switch strings.ToLower(prefix) + ":" {
+	case ClientCertIdentitySHA256:
+		fingerprint := strings.ToLower(strings.ReplaceAll(value, ":", ""))
+		if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != sha256.Size {
+			return "", errors.New("证书指纹必须是 SHA-256 十六进制值")
+		}
+		return ClientCertIdentitySHA256 + fingerprint, nil
+	case ClientCertIdentitySAN:
+		return ClientCertIdentitySAN + value, nil
+	case ClientCertIdentitySubject:
+		return ClientCertIdentitySubject + value, nil
+	default:
+		return "", errors.New("证书标识格式应为 sha256:<指纹>、san:<值> 或 subject:<DN>")
+	}
+}
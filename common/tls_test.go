@@ -0,0 +1,124 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestCertificate(t *testing.T, dir string) (certFile string, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	spiffe, err := url.Parse("spiffe://mesh.local/ns/billing/sa/worker")
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "billing-worker", Organization: []string{"mesh"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{"worker.billing.svc"},
+		URIs:                  []*url.URL{spiffe},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile, cert
+}
+
+func TestClientCertIdentitiesAndNormalization(t *testing.T) {
+	_, _, cert := writeTestCertificate(t, t.TempDir())
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	assert.Equal(t, []string{
+		"sha256:" + fingerprint,
+		"san:spiffe://mesh.local/ns/billing/sa/worker",
+		"san:worker.billing.svc",
+		"subject:CN=billing-worker,O=mesh",
+	}, ClientCertIdentities(cert))
+	assert.Nil(t, ClientCertIdentities(nil))
+
+	colonFingerprint := ""
+	for i, b := range sum {
+		if i > 0 {
+			colonFingerprint += ":"
+		}
+		colonFingerprint += hex.EncodeToString([]byte{b})
+	}
+	normalized, err := NormalizeClientCertIdentity(" SHA256:" + colonFingerprint)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:"+fingerprint, normalized)
+
+	normalized, err = NormalizeClientCertIdentity("SAN: worker.billing.svc")
+	require.NoError(t, err)
+	assert.Equal(t, "san:worker.billing.svc", normalized)
+
+	normalized, err = NormalizeClientCertIdentity("")
+	require.NoError(t, err)
+	assert.Empty(t, normalized)
+
+	for _, invalid := range []string{"worker.billing.svc", "sha256:abcd", "issuer:CN=ca", "san:"} {
+		_, err := NormalizeClientCertIdentity(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadTLSListenerConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := writeTestCertificate(t, dir)
+
+	config, err := LoadTLSListenerConfig()
+	require.NoError(t, err)
+	assert.Nil(t, config, "TLS is disabled unless a certificate is configured")
+
+	t.Setenv("TLS_CERT_FILE", certFile)
+	_, err = LoadTLSListenerConfig()
+	assert.Error(t, err, "certificate and key must be configured together")
+
+	t.Setenv("TLS_KEY_FILE", keyFile)
+	config, err = LoadTLSListenerConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "8443", config.Port)
+	assert.Equal(t, tls.NoClientCert, config.TLSConfig.ClientAuth)
+
+	t.Setenv("TLS_CLIENT_CA_FILE", certFile)
+	t.Setenv("TLS_PORT", "9443")
+	config, err = LoadTLSListenerConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "9443", config.Port)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, config.TLSConfig.ClientAuth)
+	assert.NotNil(t, config.TLSConfig.ClientCAs)
+
+	t.Setenv("TLS_CLIENT_CERT_REQUIRED", "true")
+	config, err = LoadTLSListenerConfig()
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, config.TLSConfig.ClientAuth)
+}
@@ -20,6 +20,11 @@ const (
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
 	ContextKeyTokenAutoGroups        ContextKey = "token_auto_groups"
+	ContextKeyTokenEndUserId         ContextKey = "token_end_user_id"
+	ContextKeyTokenParentId          ContextKey = "token_parent_id"
+	ContextKeyTokenEndUserHeader     ContextKey = "token_end_user_header"
+	ContextKeyTokenEndUserRateLimit  ContextKey = "token_end_user_rate_limit"
+	ContextKeyTokenEndUserDailyQuota ContextKey = "token_end_user_daily_quota"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -44,19 +49,31 @@ const (
 	ContextKeyAutoGroupRetryIndex ContextKey = "auto_group_retry_index"
 
 	/* user related keys */
-	ContextKeyUserId      ContextKey = "id"
-	ContextKeyUserSetting ContextKey = "user_setting"
-	ContextKeyUserQuota   ContextKey = "user_quota"
-	ContextKeyUserStatus  ContextKey = "user_status"
-	ContextKeyUserEmail   ContextKey = "user_email"
-	ContextKeyUserGroup   ContextKey = "user_group"
-	ContextKeyUsingGroup  ContextKey = "group"
-	ContextKeyUserName    ContextKey = "username"
+	ContextKeyUserId           ContextKey = "id"
+	ContextKeyUserSetting      ContextKey = "user_setting"
+	ContextKeyUserQuota        ContextKey = "user_quota"
+	ContextKeyUserStatus       ContextKey = "user_status"
+	ContextKeyUserEmail        ContextKey = "user_email"
+	ContextKeyUserGroup        ContextKey = "user_group"
+	ContextKeyUsingGroup       ContextKey = "group"
+	ContextKeyUserName         ContextKey = "username"
+	ContextKeyUserUsableGroups ContextKey = "user_usable_groups"
 
 	ContextKeyLocalCountTokens ContextKey = "local_count_tokens"
 
+	// ContextKeyEndUserId stores the end-user identifier resolved for the relay request,
+	// used for per-end-user limits and persisted into consume/error logs.
+	ContextKeyEndUserId ContextKey = "end_user_id"
+
+	// ContextKeyLogLabels stores the JSON-encoded cost-allocation labels (token labels merged
+	// with the X-NewAPI-Tags request header) persisted into consume/error logs.
+	ContextKeyLogLabels ContextKey = "log_labels"
+
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
 
+	// ContextKeyImagePreprocessStats stores *service.ImagePreprocessStats for the consume log.
+	ContextKeyImagePreprocessStats ContextKey = "image_preprocess_stats"
+
 	// ContextKeyFileSourcesToCleanup stores file sources that need cleanup when request ends
 	ContextKeyFileSourcesToCleanup ContextKey = "file_sources_to_cleanup"
 
@@ -64,6 +81,22 @@ const (
 	// It is not returned to end users, but can be persisted into consume/error logs for debugging.
 	ContextKeyAdminRejectReason ContextKey = "admin_reject_reason"
 
+	// ContextKeyUpstreamCapture stores *relaycommon.UpstreamCapture when a debugging tool
+	// (channel replay) wants the exact upstream request and raw response recorded.
+	ContextKeyUpstreamCapture ContextKey = "upstream_capture"
+
+	// ContextKeyRelayUsage stores the *dto.Usage settled for a successful text relay,
+	// so shadow traffic can compare it with the shadow channel's usage.
+	ContextKeyRelayUsage ContextKey = "relay_usage"
+
+	// ContextKeyTrafficSplit stores the *service.TrafficSplitAssignment chosen by the
+	// distributor when a canary traffic-split rule matches the request.
+	ContextKeyTrafficSplit ContextKey = "traffic_split"
+
+	// ContextKeyHedge stores the *service.HedgeOutcome shared by both attempts of
+	// a hedged request.
+	ContextKeyHedge ContextKey = "hedge"
+
 	// ContextKeyLanguage stores the user's language preference for i18n
 	ContextKeyLanguage ContextKey = "language"
 	ContextKeyIsStream ContextKey = "is_stream"
@@ -5,6 +5,8 @@ type TaskPlatform string
 const (
 	TaskPlatformSuno       TaskPlatform = "suno"
 	TaskPlatformMidjourney              = "mj"
+	// TaskPlatformClaudeBatch tracks Anthropic Message Batches submitted through /v1/messages/batches.
+	TaskPlatformClaudeBatch TaskPlatform = "claude_batch"
 )
 
 const (
@@ -16,6 +18,7 @@ const (
 	TaskActionFirstTailGenerate = "firstTailGenerate"
 	TaskActionReferenceGenerate = "referenceGenerate"
 	TaskActionRemix             = "remixGenerate"
+	TaskActionMessageBatch      = "messageBatch"
 )
 
 var SunoModel2Action = map[string]string{
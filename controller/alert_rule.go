@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleRequest 创建或修改告警规则的请求
+type AlertRuleRequest struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	Enabled       bool    `json:"enabled"`
+	Threshold     float64 `json:"threshold"`
+	Period        string  `json:"period"`
+	WindowMinutes int     `json:"window_minutes"`
+	MinQuota      int     `json:"min_quota"`
+	TokenId       int     `json:"token_id"`
+	ModelName     string  `json:"model_name"`
+	ChannelId     int     `json:"channel_id"`
+	Title         string  `json:"title"`
+	Template      string  `json:"template"`
+}
+
+func (req *AlertRuleRequest) apply(rule *model.AlertRule) {
+	rule.Name = req.Name
+	rule.Type = req.Type
+	rule.Enabled = req.Enabled
+	rule.Threshold = req.Threshold
+	rule.Period = req.Period
+	rule.WindowMinutes = req.WindowMinutes
+	rule.MinQuota = req.MinQuota
+	rule.TokenId = req.TokenId
+	rule.ModelName = req.ModelName
+	rule.ChannelId = req.ChannelId
+	rule.Title = req.Title
+	rule.Template = req.Template
+}
+
+// validateAlertRule 校验规则内容以及令牌归属、渠道错误规则的权限
+func validateAlertRule(c *gin.Context, rule *model.AlertRule) error {
+	if err := rule.Normalize(); err != nil {
+		return err
+	}
+	if rule.Type == model.AlertRuleTypeChannelError && c.GetInt("role") < common.RoleAdminUser {
+		return errors.New("渠道错误告警仅管理员可用")
+	}
+	if rule.TokenId != 0 {
+		if _, err := model.GetTokenByIds(rule.TokenId, rule.UserId); err != nil {
+			return errors.New("令牌不存在")
+		}
+	}
+	return nil
+}
+
+func getAlertRuleParam(c *gin.Context) (*model.AlertRule, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return nil, false
+	}
+	rule, err := model.GetUserAlertRuleById(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "告警规则不存在")
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetAlertRules 返回当前用户的告警规则
+func GetAlertRules(c *gin.Context) {
+	rules, err := model.GetUserAlertRules(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, rules)
+}
+
+// CreateAlertRule 为当前用户创建告警规则
+func CreateAlertRule(c *gin.Context) {
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	rule := &model.AlertRule{UserId: c.GetInt("id")}
+	req.apply(rule)
+	if err := validateAlertRule(c, rule); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if err := rule.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    rule,
+	})
+}
+
+// UpdateAlertRule 修改告警规则，修改后重新开始计算触发周期
+func UpdateAlertRule(c *gin.Context) {
+	rule, ok := getAlertRuleParam(c)
+	if !ok {
+		return
+	}
+	var req AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	req.apply(rule)
+	if err := validateAlertRule(c, rule); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if err := rule.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    rule,
+	})
+}
+
+// DeleteAlertRule 删除告警规则
+func DeleteAlertRule(c *gin.Context) {
+	rule, ok := getAlertRuleParam(c)
+	if !ok {
+		return
+	}
+	if err := model.DeleteUserAlertRule(rule.Id, rule.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// TestAlertRule 按规则模板和当前通知设置发送一条示例告警
+func TestAlertRule(c *gin.Context) {
+	rule, ok := getAlertRuleParam(c)
+	if !ok {
+		return
+	}
+	user, err := model.GetUserById(rule.UserId, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.SendAlertRuleTest(rule, user); err != nil {
+		common.ApiErrorMsg(c, "发送失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已发送",
+	})
+}
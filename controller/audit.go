@@ -30,6 +30,16 @@ var auditContentTemplates = map[string]string{
 	"user.reset_passkey":    "Reset the user passkey",
 	"option.update":         "Updated system setting ${key}",
 
+	"token.admin_enable":  "Enabled token ${name} (ID: ${id}) of the user",
+	"token.admin_disable": "Disabled token ${name} (ID: ${id}) of the user",
+
+	"log.export": "Exported usage logs as ${format}",
+
+	"authz.role_create": "Created authorization role ${name} (${key})",
+	"authz.role_update": "Updated authorization role ${name} (${key})",
+	"authz.role_delete": "Deleted authorization role ${key}",
+	"authz.user_role":   "Set authorization role of user ${username} to ${role}",
+
 	"channel.create":             "Created channel ${name} (type ${type}, count ${count})",
 	"channel.update":             "Updated channel ${name} (ID: ${id})",
 	"channel.delete":             "Deleted channel ${name} (ID: ${id})",
@@ -47,6 +57,21 @@ var auditContentTemplates = map[string]string{
 
 	"redemption.create": "Created ${count} redemption codes named ${name} (${quota} each)",
 
+	"scim.user_create":      "SCIM provisioned user ${username} (${userName})",
+	"scim.user_update":      "SCIM updated ${fields} of user ${username}",
+	"scim.user_deactivate":  "SCIM deactivated user ${username}",
+	"scim.user_reactivate":  "SCIM reactivated user ${username}",
+	"scim.user_delete":      "SCIM deleted user ${username} (${userName})",
+	"scim.user_access_sync": "SCIM set user ${username} to group ${group} and role ${role}",
+	"scim.group_create":     "SCIM created group ${displayName} (ID: ${id})",
+	"scim.group_update":     "SCIM updated group ${displayName} (ID: ${id})",
+	"scim.group_delete":     "SCIM deleted group ${displayName} (ID: ${id})",
+	"scim.group_members":    "SCIM changed members of group ${displayName} (added ${added}, removed ${removed})",
+
+	"ldap.user_access_sync": "LDAP set user ${username} to group ${group} and role ${role}",
+
+	"oauth.user_access_sync": "${provider} set user ${username} to group ${group}, role ${role} and usable groups ${usableGroups}",
+
 	"subscription.plan_reset":      "Reset active subscriptions for plan ${plan_id}",
 	"subscription.user_plan_reset": "Reset active plan ${plan_id} subscriptions for user ${target_user_id}",
 }
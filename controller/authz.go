@@ -2,10 +2,15 @@ package controller
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/service/authz"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetPermissionCatalog returns the permission schema used by the client to
@@ -22,3 +27,105 @@ func GetPermissionCatalog(c *gin.Context) {
 		},
 	})
 }
+
+// GetAuthzRoles lists the built-in and custom roles with their grants.
+func GetAuthzRoles(c *gin.Context) {
+	common.ApiSuccess(c, authz.Roles())
+}
+
+// CreateAuthzRole creates a custom role from a key, a name and a grant matrix.
+func CreateAuthzRole(c *gin.Context) {
+	var input authz.CustomRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	role, err := authz.CreateCustomRole(input)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "authz.role_create", map[string]interface{}{
+		"key":  role.Key,
+		"name": role.Name,
+	})
+	common.ApiSuccess(c, role)
+}
+
+// UpdateAuthzRole updates a custom role. Built-in roles cannot be changed.
+func UpdateAuthzRole(c *gin.Context) {
+	var input authz.CustomRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	role, err := authz.UpdateCustomRole(c.Param("key"), input)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "authz.role_update", map[string]interface{}{
+		"key":  role.Key,
+		"name": role.Name,
+	})
+	common.ApiSuccess(c, role)
+}
+
+// DeleteAuthzRole deletes a custom role; its users fall back to the admin role.
+func DeleteAuthzRole(c *gin.Context) {
+	key := c.Param("key")
+	if err := authz.DeleteCustomRole(key); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAudit(c, "authz.role_delete", map[string]interface{}{
+		"key": key,
+	})
+	common.ApiSuccess(c, nil)
+}
+
+type userAuthzRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// SetUserAuthzRole assigns a custom role to an admin user, replacing the
+// built-in admin baseline. An empty role restores the admin baseline.
+func SetUserAuthzRole(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	var req userAuthzRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	user, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if user.Role != common.RoleAdminUser {
+		common.ApiErrorMsg(c, "only admin users can be assigned an authorization role")
+		return
+	}
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		return authz.AssignUserRoleInTx(tx, user.Id, req.Role)
+	}); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := authz.ReloadPolicy(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	recordManageAuditFor(c, user.Id, "authz.user_role", map[string]interface{}{
+		"username": user.Username,
+		"role":     req.Role,
+	})
+	common.ApiSuccess(c, gin.H{
+		"authz_role":        authz.UserRoleKey(user.Id),
+		"admin_permissions": authz.Capabilities(user.Id, user.Role),
+	})
+}
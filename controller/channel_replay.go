@@ -0,0 +1,323 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	maxChannelReplayTargets       = 5
+	maxChannelReplayResponseBytes = 1 << 20
+	defaultChannelReplayPath      = "/v1/chat/completions"
+)
+
+type channelReplayRequest struct {
+	// RequestId 可选，引用一条消费/错误日志以预填路径、模型、分组与原渠道
+	RequestId  string            `json:"request_id"`
+	Path       string            `json:"path"`
+	Model      string            `json:"model"`
+	Group      string            `json:"group"`
+	Body       json.RawMessage   `json:"body"`
+	Headers    map[string]string `json:"headers"`
+	ChannelIds []int             `json:"channel_ids"`
+}
+
+type channelReplayResult struct {
+	ChannelId       int                                  `json:"channel_id"`
+	ChannelName     string                               `json:"channel_name"`
+	ChannelType     int                                  `json:"channel_type"`
+	UpstreamModel   string                               `json:"upstream_model,omitempty"`
+	Upstream        *relaycommon.UpstreamCaptureSnapshot `json:"upstream,omitempty"`
+	Response        string                               `json:"response"`
+	HeaderLatencyMs int64                                `json:"header_latency_ms"`
+	LatencyMs       int64                                `json:"latency_ms"`
+	Usage           *dto.Usage                           `json:"usage,omitempty"`
+	Error           string                               `json:"error,omitempty"`
+	ErrorCode       string                               `json:"error_code,omitempty"`
+	// quota 按影子渠道价格估算的消耗，仅影子流量用于累计管理员预算
+	quota int
+}
+
+// ReplayChannelRequest 将一次请求经由正常的转换与覆盖流程重放到一个或多个渠道，
+// 返回实际发往上游的请求（已掩码）、原始响应、延迟与用量，便于对比渠道行为；不计费、不记消费日志
+func ReplayChannelRequest(c *gin.Context) {
+	var req channelReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	var loggedModel string
+	if req.RequestId = strings.TrimSpace(req.RequestId); req.RequestId != "" {
+		log, err := model.GetRelayLogByRequestId(req.RequestId)
+		if err != nil {
+			common.ApiErrorI18n(c, i18n.MsgChannelReplayLogNotFound)
+			return
+		}
+		prefillChannelReplayFromLog(&req, log)
+		loggedModel = log.ModelName
+	}
+
+	body, ok := normalizeChannelReplayBody(req.Body)
+	if !ok {
+		common.ApiErrorI18n(c, i18n.MsgChannelReplayBodyRequired)
+		return
+	}
+	path := strings.TrimSpace(req.Path)
+	if path == "" {
+		path = defaultChannelReplayPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	// 显式指定的模型优先，其次是请求体（Gemini 为路径）中的模型，最后才用日志中的模型补全
+	modelName := strings.TrimSpace(req.Model)
+	if modelName == "" || isGeminiReplayPath(path) {
+		modelName = channelReplayModelName(path, body)
+	}
+	if modelName == "" {
+		modelName = loggedModel
+	}
+	if modelName != "" && !isGeminiReplayPath(path) {
+		body, _ = sjson.SetBytes(body, "model", modelName)
+	}
+
+	channelIds := lo.Uniq(lo.Filter(req.ChannelIds, func(id int, _ int) bool { return id > 0 }))
+	if len(channelIds) == 0 {
+		common.ApiErrorI18n(c, i18n.MsgChannelReplayNoChannel)
+		return
+	}
+	if len(channelIds) > maxChannelReplayTargets {
+		common.ApiErrorI18n(c, i18n.MsgBatchTooMany, map[string]any{"Max": maxChannelReplayTargets})
+		return
+	}
+	channels := make([]*model.Channel, 0, len(channelIds))
+	for _, id := range channelIds {
+		channel, err := model.GetChannelById(id, true)
+		if err != nil {
+			common.ApiErrorI18n(c, i18n.MsgChannelNotExists)
+			return
+		}
+		channels = append(channels, channel)
+	}
+
+	userId, err := resolveChannelTestUserID(c)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	group := strings.TrimSpace(req.Group)
+	if group == "" {
+		group, _ = model.GetUserGroup(userId, false)
+	}
+
+	results := make([]channelReplayResult, len(channels))
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = replayChannel(c.Request.Context(), channel, userId, group, path, modelName, body, req.Headers)
+		}()
+	}
+	wg.Wait()
+	common.ApiSuccess(c, gin.H{
+		"path":    path,
+		"model":   modelName,
+		"group":   group,
+		"results": results,
+	})
+}
+
+func prefillChannelReplayFromLog(req *channelReplayRequest, log *model.Log) {
+	if req.Path == "" {
+		other, _ := common.StrToMap(log.Other)
+		if path, ok := other["request_path"].(string); ok {
+			req.Path = path
+		}
+	}
+	if req.Group == "" {
+		req.Group = log.Group
+	}
+	if len(req.ChannelIds) == 0 && log.ChannelId > 0 {
+		req.ChannelIds = []int{log.ChannelId}
+	}
+}
+
+// normalizeChannelReplayBody 接受 JSON 对象，或粘贴为字符串的 JSON
+func normalizeChannelReplayBody(raw json.RawMessage) ([]byte, bool) {
+	body := bytes.TrimSpace(raw)
+	if len(body) > 0 && body[0] == '"' {
+		var pasted string
+		if err := common.Unmarshal(body, &pasted); err != nil {
+			return nil, false
+		}
+		body = bytes.TrimSpace([]byte(pasted))
+	}
+	if len(body) == 0 || body[0] != '{' || !json.Valid(body) {
+		return nil, false
+	}
+	return body, true
+}
+
+func isGeminiReplayPath(path string) bool {
+	return strings.Contains(path, "/v1beta/models")
+}
+
+func channelReplayModelName(path string, body []byte) string {
+	if modelName := gjson.GetBytes(body, "model").String(); modelName != "" {
+		return modelName
+	}
+	// Gemini 原生请求的模型在路径中：/v1beta/models/gemini-2.0-flash:generateContent
+	if idx := strings.Index(path, "/models/"); idx >= 0 {
+		modelName := path[idx+len("/models/"):]
+		if end := strings.IndexAny(modelName, ":/?"); end >= 0 {
+			modelName = modelName[:end]
+		}
+		return modelName
+	}
+	return ""
+}
+
+func replayChannel(ctx context.Context, channel *model.Channel, userId int, group string, path string, modelName string, body []byte, headers map[string]string) channelReplayResult {
+	result := channelReplayResult{
+		ChannelId:   channel.Id,
+		ChannelName: channel.Name,
+		ChannelType: channel.Type,
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	for name, value := range headers {
+		c.Request.Header.Set(name, value)
+	}
+	c.Request.Header.Set("Content-Type", "application/json")
+	capture := relaycommon.AttachUpstreamCapture(c)
+
+	secrets := strings.Split(channel.Key, "\n")
+	tik := time.Now()
+	finish := func(err error, apiErr *types.NewAPIError) channelReplayResult {
+		result.LatencyMs = time.Since(tik).Milliseconds()
+		if capture.Captured() {
+			snapshot := capture.Snapshot(secrets...)
+			result.Upstream = &snapshot
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(w.Result().Body, maxChannelReplayResponseBytes))
+		result.Response = relaycommon.MaskUpstreamSecrets(string(respBody), secrets...)
+		if apiErr != nil {
+			result.ErrorCode = string(apiErr.GetErrorCode())
+		}
+		if err != nil {
+			result.Error = relaycommon.MaskUpstreamSecrets(err.Error(), secrets...)
+		}
+		return result
+	}
+
+	cache, err := model.GetUserCache(userId)
+	if err != nil {
+		return finish(err, nil)
+	}
+	cache.WriteContext(c)
+	c.Set("id", userId)
+	c.Set("channel", channel.Type)
+	c.Set("base_url", channel.GetBaseURL())
+	c.Set("group", group)
+
+	relayFormat := testRelayFormatForPath(path)
+	request, err := helper.GetAndValidateRequest(c, relayFormat)
+	if err != nil {
+		return finish(err, types.NewError(err, types.ErrorCodeInvalidRequest))
+	}
+	if newAPIError := middleware.SetupContextForSelectedChannel(c, channel, modelName); newAPIError != nil {
+		return finish(newAPIError, newAPIError)
+	}
+	secrets = append(secrets, common.GetContextKeyString(c, constant.ContextKeyChannelKey))
+
+	info, err := relaycommon.GenRelayInfo(c, relayFormat, request, nil)
+	if err != nil {
+		return finish(err, types.NewError(err, types.ErrorCodeGenRelayInfoFailed))
+	}
+	info.IsChannelTest = true
+	info.InitChannelMeta(c)
+	if err := helper.ModelMappedHelper(c, info, request); err != nil {
+		return finish(err, types.NewError(err, types.ErrorCodeChannelModelMappedError))
+	}
+	request.SetModelName(info.UpstreamModelName)
+	result.UpstreamModel = info.UpstreamModelName
+
+	apiType, _ := common.ChannelType2APIType(channel.Type)
+	adaptor := relay.GetAdaptor(apiType)
+	if adaptor == nil {
+		err := fmt.Errorf("invalid api type: %d, adaptor is nil", apiType)
+		return finish(err, types.NewError(err, types.ErrorCodeInvalidApiType))
+	}
+	// 回放不计费，价格仅用于部分适配器的用量计算与影子流量的预算估算，未配置价格时照常回放
+	priceData, _ := helper.ModelPriceHelper(c, info, 0, request.GetTokenCountMeta())
+	adaptor.Init(info)
+
+	convertedRequest, err := convertTestRequest(c, adaptor, info, request)
+	if err != nil {
+		return finish(err, types.NewError(err, types.ErrorCodeConvertRequestFailed))
+	}
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return finish(err, types.NewError(err, types.ErrorCodeJsonMarshalFailed))
+	}
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverrideWithRelayInfo(jsonData, info)
+		if err != nil {
+			if fixedErr, ok := relaycommon.AsParamOverrideReturnError(err); ok {
+				return finish(fixedErr, relaycommon.NewAPIErrorFromParamOverride(fixedErr))
+			}
+			return finish(err, types.NewError(err, types.ErrorCodeChannelParamOverrideInvalid))
+		}
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(jsonData))
+	resp, err := adaptor.DoRequest(c, info, bytes.NewBuffer(jsonData))
+	result.HeaderLatencyMs = time.Since(tik).Milliseconds()
+	if err != nil {
+		return finish(err, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError))
+	}
+	var httpResp *http.Response
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		if httpResp.StatusCode != http.StatusOK {
+			apiErr := service.RelayErrorHandler(c.Request.Context(), httpResp, true)
+			return finish(apiErr, apiErr)
+		}
+	}
+	usageA, apiErr := adaptor.DoResponse(c, httpResp, info)
+	if apiErr != nil {
+		return finish(apiErr, apiErr)
+	}
+	if usage, err := coerceTestUsage(usageA, info.IsStream, info.GetEstimatePromptTokens()); err == nil {
+		result.Usage = usage
+		result.quota, _ = settleTestQuota(info, priceData, usage)
+	}
+	return finish(nil, nil)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestReplayChannelRequestReturnsUpstreamExchangeWithoutBilling(t *testing.T) {
+	db := setupModelListControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Log{}))
+
+	var upstreamBody []byte
+	var upstreamHeader http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = io.ReadAll(r.Body)
+		upstreamHeader = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o-mini-2024","choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":1,"total_tokens":8}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	user := &model.User{Id: 1, Username: "replay_admin", Role: common.RoleRootUser, Status: common.UserStatusEnabled, Group: "default"}
+	require.NoError(t, db.Create(user).Error)
+	channel := &model.Channel{
+		Id:             11,
+		Type:           constant.ChannelTypeOpenAI,
+		Name:           "replay-openai",
+		Key:            "sk-replay-secret-key-123",
+		Status:         common.ChannelStatusEnabled,
+		BaseURL:        common.GetPointer(upstream.URL),
+		Models:         "gpt-4o-mini",
+		Group:          "default",
+		ModelMapping:   common.GetPointer(`{"gpt-4o-mini":"gpt-4o-mini-2024"}`),
+		ParamOverride:  common.GetPointer(`{"temperature":0.2}`),
+		HeaderOverride: common.GetPointer(`{"X-Debug":"replay"}`),
+	}
+	require.NoError(t, db.Create(channel).Error)
+	require.NoError(t, db.Create(&model.Log{
+		UserId: 5, Type: model.LogTypeError, ModelName: "gpt-4o-mini", ChannelId: channel.Id, Group: "default",
+		RequestId: "req-replay-1", Other: `{"request_path":"/v1/chat/completions"}`,
+	}).Error)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("id", user.Id)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/api/channel/replay", bytes.NewBufferString(
+		`{"request_id":"req-replay-1","body":"{\"messages\":[{\"role\":\"user\",\"content\":\"ping\"}]}"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	ReplayChannelRequest(ctx)
+
+	response := recorder.Body.String()
+	require.True(t, gjson.Get(response, "success").Bool(), response)
+	assert.Equal(t, "/v1/chat/completions", gjson.Get(response, "data.path").String())
+	assert.Equal(t, "gpt-4o-mini", gjson.Get(response, "data.model").String(), "the model is filled in from the log")
+
+	result := gjson.Get(response, "data.results.0")
+	assert.Empty(t, result.Get("error").String())
+	assert.Equal(t, "gpt-4o-mini-2024", result.Get("upstream_model").String())
+	assert.Equal(t, "gpt-4o-mini-2024", gjson.GetBytes(upstreamBody, "model").String())
+	assert.Equal(t, 0.2, gjson.GetBytes(upstreamBody, "temperature").Float())
+	assert.Equal(t, "replay", upstreamHeader.Get("X-Debug"))
+
+	upstreamRequest := result.Get("upstream")
+	assert.Equal(t, upstream.URL+"/v1/chat/completions", upstreamRequest.Get("url").String())
+	assert.JSONEq(t, string(upstreamBody), upstreamRequest.Get("request_body").String(), "the captured body is the one sent after overrides")
+	assert.Equal(t, "***masked***", upstreamRequest.Get("request_headers.Authorization").String())
+	assert.Equal(t, "replay", upstreamRequest.Get("request_headers.X-Debug").String())
+	assert.EqualValues(t, http.StatusOK, upstreamRequest.Get("status_code").Int())
+	assert.Contains(t, upstreamRequest.Get("response_body").String(), `"content":"pong"`)
+	assert.NotContains(t, response, "sk-replay-secret-key-123")
+
+	assert.Contains(t, result.Get("response").String(), "pong")
+	assert.EqualValues(t, 7, result.Get("usage.prompt_tokens").Int())
+
+	var logCount int64
+	require.NoError(t, db.Model(&model.Log{}).Count(&logCount).Error)
+	assert.EqualValues(t, 1, logCount, "a replay does not record a consume log")
+}
+
+func TestReplayChannelRequestValidatesInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for name, payload := range map[string]string{
+		"missing body":    `{"channel_ids":[1]}`,
+		"non-object body": `{"body":"[1,2]","channel_ids":[1]}`,
+		"no channel":      `{"body":{"model":"gpt-4o"}}`,
+		"too many":        `{"body":{"model":"gpt-4o"},"channel_ids":[1,2,3,4,5,6]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/channel/replay", bytes.NewBufferString(payload))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+
+			ReplayChannelRequest(ctx)
+
+			assert.False(t, gjson.Get(recorder.Body.String(), "success").Bool(), recorder.Body.String())
+		})
+	}
+}
+
+func TestChannelReplayModelName(t *testing.T) {
+	assert.Equal(t, "gpt-4o", channelReplayModelName("/v1/chat/completions", []byte(`{"model":"gpt-4o"}`)))
+	assert.Equal(t, "gemini-2.0-flash", channelReplayModelName("/v1beta/models/gemini-2.0-flash:streamGenerateContent", []byte(`{"contents":[]}`)))
+	assert.Empty(t, channelReplayModelName("/v1/chat/completions", []byte(`{}`)))
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/task/claudebatch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claudeBatchError returns an Anthropic-style error response.
+func claudeBatchError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"type": "error",
+		"error": gin.H{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
+
+func getClaudeBatchTask(c *gin.Context) (*model.Task, bool) {
+	batchID := c.Param("batch_id")
+	task, exists, err := model.GetByTaskId(c.GetInt("id"), batchID)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to query batch %s: %s", batchID, err.Error()))
+		claudeBatchError(c, http.StatusInternalServerError, "api_error", "Failed to query batch")
+		return nil, false
+	}
+	if !exists || task == nil || task.Platform != constant.TaskPlatformClaudeBatch {
+		claudeBatchError(c, http.StatusNotFound, "not_found_error", "Batch not found")
+		return nil, false
+	}
+	return task, true
+}
+
+// doClaudeBatchUpstream 使用提交批次时的渠道与 key 请求上游批次接口。
+func doClaudeBatchUpstream(ctx context.Context, c *gin.Context, task *model.Task, path string) (*http.Response, error) {
+	ch, err := model.CacheGetChannel(task.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("get channel failed: %w", err)
+	}
+	return claudebatch.DoUpstreamRequest(ctx, claudebatch.NewUpstream(ch, task), task.GetUpstreamTaskID(), path, c.Request.Header)
+}
+
+// relayClaudeBatchObject 请求上游批次对象接口，并把 id / results_url 改写为网关侧地址后返回。
+func relayClaudeBatchObject(c *gin.Context, path string) {
+	task, ok := getClaudeBatchTask(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+	resp, err := doClaudeBatchUpstream(ctx, c, task, path)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to request upstream batch %s: %s", task.TaskID, err.Error()))
+		claudeBatchError(c, http.StatusBadGateway, "api_error", "Failed to request upstream batch")
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		claudeBatchError(c, http.StatusBadGateway, "api_error", "Failed to read upstream response")
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Upstream returned status %d for batch %s: %s", resp.StatusCode, task.TaskID, body))
+		claudeBatchError(c, resp.StatusCode, "api_error", fmt.Sprintf("Upstream service returned status %d", resp.StatusCode))
+		return
+	}
+	publicBody, err := claudebatch.ToPublicBatch(body, task.TaskID)
+	if err != nil {
+		claudeBatchError(c, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/json", publicBody)
+}
+
+// ClaudeBatchRetrieve GET /v1/messages/batches/:batch_id
+func ClaudeBatchRetrieve(c *gin.Context) {
+	relayClaudeBatchObject(c, claudebatch.UpstreamPathRetrieve)
+}
+
+// ClaudeBatchCancel POST /v1/messages/batches/:batch_id/cancel
+func ClaudeBatchCancel(c *gin.Context) {
+	relayClaudeBatchObject(c, claudebatch.UpstreamPathCancel)
+}
+
+// ClaudeBatchResults GET /v1/messages/batches/:batch_id/results
+// 以流的方式透传上游 JSONL 结果，不在网关缓存。
+func ClaudeBatchResults(c *gin.Context) {
+	task, ok := getClaudeBatchTask(c)
+	if !ok {
+		return
+	}
+	if task.Status != model.TaskStatusSuccess && task.Status != model.TaskStatusFailure {
+		claudeBatchError(c, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("Batch is still processing, current status: %s", task.Status))
+		return
+	}
+	resp, err := doClaudeBatchUpstream(c.Request.Context(), c, task, claudebatch.UpstreamPathResults)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to fetch results for batch %s: %s", task.TaskID, err.Error()))
+		claudeBatchError(c, http.StatusBadGateway, "api_error", "Failed to fetch batch results")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Upstream returned status %d for batch %s results", resp.StatusCode, task.TaskID))
+		claudeBatchError(c, resp.StatusCode, "api_error", fmt.Sprintf("Upstream service returned status %d", resp.StatusCode))
+		return
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/x-jsonl"
+	}
+	c.Writer.Header().Set("Content-Type", contentType)
+	c.Writer.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(c.Writer, resp.Body); err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("Failed to stream results for batch %s: %s", task.TaskID, err.Error()))
+	}
+}
+
+// ClaudeBatchList GET /v1/messages/batches
+// 返回当前用户提交过的批次（最近一次轮询的快照），按提交时间倒序。
+func ClaudeBatchList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 1000 {
+		limit = 20
+	}
+	tasks := model.TaskGetAllUserTask(c.GetInt("id"), 0, limit+1, model.SyncTaskQueryParams{
+		Platform: constant.TaskPlatformClaudeBatch,
+	})
+	hasMore := len(tasks) > limit
+	if hasMore {
+		tasks = tasks[:limit]
+	}
+	data := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		if len(task.Data) == 0 {
+			continue
+		}
+		body, err := claudebatch.ToPublicBatch(task.Data, task.TaskID)
+		if err != nil {
+			continue
+		}
+		data = append(data, json.RawMessage(body))
+	}
+	var firstID, lastID any
+	if len(tasks) > 0 {
+		firstID = tasks[0].TaskID
+		lastID = tasks[len(tasks)-1].TaskID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":     data,
+		"has_more": hasMore,
+		"first_id": firstID,
+		"last_id":  lastID,
+	})
+}
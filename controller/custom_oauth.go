@@ -36,6 +36,7 @@ type CustomOAuthProviderResponse struct {
 	AuthStyle             int    `json:"auth_style"`
 	AccessPolicy          string `json:"access_policy"`
 	AccessDeniedMessage   string `json:"access_denied_message"`
+	ClaimMapping          string `json:"claim_mapping"`
 }
 
 type UserOAuthBindingResponse struct {
@@ -66,6 +67,7 @@ func toCustomOAuthProviderResponse(p *model.CustomOAuthProvider) *CustomOAuthPro
 		AuthStyle:             p.AuthStyle,
 		AccessPolicy:          p.AccessPolicy,
 		AccessDeniedMessage:   p.AccessDeniedMessage,
+		ClaimMapping:          p.ClaimMapping,
 	}
 }
 
@@ -131,6 +133,7 @@ type CreateCustomOAuthProviderRequest struct {
 	AuthStyle             int    `json:"auth_style"`
 	AccessPolicy          string `json:"access_policy"`
 	AccessDeniedMessage   string `json:"access_denied_message"`
+	ClaimMapping          string `json:"claim_mapping"`
 }
 
 type FetchCustomOAuthDiscoveryRequest struct {
@@ -249,6 +252,11 @@ func CreateCustomOAuthProvider(c *gin.Context) {
 		AuthStyle:             req.AuthStyle,
 		AccessPolicy:          req.AccessPolicy,
 		AccessDeniedMessage:   req.AccessDeniedMessage,
+		ClaimMapping:          req.ClaimMapping,
+	}
+	if _, err := oauth.ParseClaimMapping(provider.ClaimMapping); err != nil {
+		common.ApiErrorMsg(c, "claim_mapping is invalid: "+err.Error())
+		return
 	}
 
 	if err := model.CreateCustomOAuthProvider(provider); err != nil {
@@ -286,6 +294,7 @@ type UpdateCustomOAuthProviderRequest struct {
 	AuthStyle             *int    `json:"auth_style"`            // Optional: if nil, keep existing
 	AccessPolicy          *string `json:"access_policy"`         // Optional: if nil, keep existing
 	AccessDeniedMessage   *string `json:"access_denied_message"` // Optional: if nil, keep existing
+	ClaimMapping          *string `json:"claim_mapping"`         // Optional: if nil, keep existing
 }
 
 // UpdateCustomOAuthProvider updates an existing custom OAuth provider
@@ -380,6 +389,13 @@ func UpdateCustomOAuthProvider(c *gin.Context) {
 	if req.AccessDeniedMessage != nil {
 		provider.AccessDeniedMessage = *req.AccessDeniedMessage
 	}
+	if req.ClaimMapping != nil {
+		if _, err := oauth.ParseClaimMapping(*req.ClaimMapping); err != nil {
+			common.ApiErrorMsg(c, "claim_mapping is invalid: "+err.Error())
+			return
+		}
+		provider.ClaimMapping = *req.ClaimMapping
+	}
 
 	if err := model.UpdateCustomOAuthProvider(provider); err != nil {
 		common.ApiError(c, err)
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/authz"
+	"gorm.io/gorm"
+)
+
+// applyDirectoryUserAccess 把目录服务（SCIM、LDAP）或身份源 claim 映射计算出的分组、
+// 角色与额外可用分组写回用户，返回是否发生了变更。usableGroups 为 nil 表示不管理
+// 可用分组。降级管理员时一并清理 authz 授权；auth_version 推进后以 revokeReason
+// 吊销该用户的全部会话。root 用户由调用方排除。
+func applyDirectoryUserAccess(user *model.User, targetGroup string, targetRole int, usableGroups []string, revokeReason string) (bool, error) {
+	usableChanged := usableGroups != nil && strings.Join(usableGroups, ",") != user.UsableGroups
+	if targetGroup == user.Group && targetRole == user.Role && !usableChanged {
+		return false, nil
+	}
+	demoted := user.Role >= common.RoleAdminUser && targetRole < common.RoleAdminUser
+	previousAuthVersion := user.AuthVersion
+	user.Group = targetGroup
+	user.Role = targetRole
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := user.UpdateWithTx(tx, false); err != nil {
+			return err
+		}
+		if usableChanged {
+			if err := user.UpdateUsableGroupsWithTx(tx, usableGroups); err != nil {
+				return err
+			}
+		}
+		if demoted {
+			return authz.ClearUserAuthorizationInTx(tx, user.Id)
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	if demoted {
+		if err := authz.ReloadPolicy(); err != nil {
+			return false, err
+		}
+	}
+	if err := model.PublishUserAuthCache(user.Id); err != nil {
+		return false, err
+	}
+	if user.AuthVersion > previousAuthVersion {
+		if _, err := model.RevokeAllUserSessions(user.Id, revokeReason); err != nil {
+			return false, err
+		}
+	}
+	if err := model.InvalidateUserTokensCache(user.Id); err != nil {
+		common.SysLog(fmt.Sprintf("failed to invalidate tokens cache for user %d: %s", user.Id, err.Error()))
+	}
+	return true, nil
+}
@@ -27,8 +27,12 @@ func GetUserGroups(c *gin.Context) {
 	usableGroups := make(map[string]map[string]interface{})
 	userGroup := ""
 	userId := c.GetInt("id")
-	userGroup, _ = model.GetUserGroup(userId, false)
-	userUsableGroups := service.GetUserUsableGroups(userGroup)
+	var extraGroups []string
+	if user, err := model.GetUserCache(userId); err == nil {
+		userGroup = user.Group
+		extraGroups = user.GetUsableGroups()
+	}
+	userUsableGroups := service.GetUserUsableGroups(userGroup, extraGroups...)
 	for groupName, _ := range ratio_setting.GetGroupRatioCopy() {
 		// UserUsableGroups contains the groups that the user can use
 		if desc, ok := userUsableGroups[groupName]; ok {
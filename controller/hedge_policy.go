@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// HedgePolicyRequest 创建或修改对冲策略的请求
+type HedgePolicyRequest struct {
+	Name               string `json:"name"`
+	Enabled            bool   `json:"enabled"`
+	ModelName          string `json:"model_name"`
+	Group              string `json:"group"`
+	DelayMs            int    `json:"delay_ms"`
+	FallbackDelayMs    int    `json:"fallback_delay_ms"`
+	LoserBillingUserId int    `json:"loser_billing_user_id"`
+}
+
+func (req *HedgePolicyRequest) apply(policy *model.HedgePolicy) {
+	policy.Name = req.Name
+	policy.Enabled = req.Enabled
+	policy.ModelName = req.ModelName
+	policy.Group = req.Group
+	policy.DelayMs = req.DelayMs
+	policy.FallbackDelayMs = req.FallbackDelayMs
+	policy.LoserBillingUserId = req.LoserBillingUserId
+}
+
+func validateHedgePolicy(c *gin.Context, policy *model.HedgePolicy) bool {
+	if err := policy.Normalize(); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return false
+	}
+	if policy.LoserBillingUserId > 0 {
+		if _, err := model.GetUserById(policy.LoserBillingUserId, false); err != nil {
+			common.ApiErrorMsg(c, "落选用量计费用户不存在")
+			return false
+		}
+	}
+	return true
+}
+
+func getHedgePolicyParam(c *gin.Context) (*model.HedgePolicy, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return nil, false
+	}
+	policy, err := model.GetHedgePolicyById(id)
+	if err != nil {
+		common.ApiErrorMsg(c, "对冲策略不存在")
+		return nil, false
+	}
+	return policy, true
+}
+
+// GetHedgePolicies 返回全部对冲策略
+func GetHedgePolicies(c *gin.Context) {
+	policies, err := model.GetHedgePolicies()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, policies)
+}
+
+// CreateHedgePolicy 创建对冲策略
+func CreateHedgePolicy(c *gin.Context) {
+	var req HedgePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	policy := &model.HedgePolicy{}
+	req.apply(policy)
+	if !validateHedgePolicy(c, policy) {
+		return
+	}
+	if err := policy.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateHedgePolicyCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    policy,
+	})
+}
+
+// UpdateHedgePolicy 修改对冲策略
+func UpdateHedgePolicy(c *gin.Context) {
+	policy, ok := getHedgePolicyParam(c)
+	if !ok {
+		return
+	}
+	var req HedgePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	req.apply(policy)
+	if !validateHedgePolicy(c, policy) {
+		return
+	}
+	if err := policy.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateHedgePolicyCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    policy,
+	})
+}
+
+// DeleteHedgePolicy 删除对冲策略
+func DeleteHedgePolicy(c *gin.Context) {
+	policy, ok := getHedgePolicyParam(c)
+	if !ok {
+		return
+	}
+	if err := model.DeleteHedgePolicy(policy.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateHedgePolicyCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ldapProvider = &oauth.LDAPProvider{}
+
+type ldapTestRequest struct {
+	Settings *system_setting.LDAPSettings `json:"settings,omitempty"`
+	Username string                       `json:"username,omitempty"`
+}
+
+// LDAPLogin 使用目录账号密码登录。首次登录的目录用户按 OAuth 注册路径即时创建，
+// 目录条目通过 external identity claim 与本地用户关联；每次登录都会按组映射
+// 重新同步分组与管理员角色，之后与密码登录一样处理 2FA 并建立会话。
+func LDAPLogin(c *gin.Context) {
+	settings := system_setting.GetLDAPSettings()
+	if !settings.Enabled {
+		common.ApiErrorI18n(c, i18n.MsgOAuthNotEnabled, providerParams(settings.GetEffectiveDisplayName()))
+		return
+	}
+	var loginRequest LoginRequest
+	if err := common.DecodeJson(c.Request.Body, &loginRequest); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if strings.TrimSpace(loginRequest.Username) == "" || loginRequest.Password == "" {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+
+	ldapUser, err := ldapProvider.Authenticate(c.Request.Context(), settings, loginRequest.Username, loginRequest.Password)
+	if err != nil {
+		if errors.Is(err, oauth.ErrLDAPInvalidCredentials) {
+			common.ApiErrorI18n(c, i18n.MsgUserUsernameOrPasswordError)
+			return
+		}
+		handleOAuthError(c, err)
+		return
+	}
+
+	user, err := findOrCreateLDAPUser(ldapUser)
+	if err != nil {
+		switch err.(type) {
+		case *OAuthUserDeletedError:
+			common.ApiErrorI18n(c, i18n.MsgOAuthUserDeleted)
+		case *OAuthRegistrationDisabledError:
+			common.ApiErrorI18n(c, i18n.MsgUserRegisterDisabled)
+		case *OAuthEmailAlreadyTakenError:
+			common.ApiErrorI18n(c, i18n.MsgUserEmailAlreadyTaken)
+		default:
+			common.ApiError(c, err)
+		}
+		return
+	}
+	if user.Status != common.UserStatusEnabled {
+		common.ApiErrorI18n(c, i18n.MsgOAuthUserBanned)
+		return
+	}
+	groups, _ := ldapUser.Extra["groups"].([]string)
+	if err := syncLDAPUserAccess(c, user, groups); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	if startTwoFALogin(user, c) {
+		return
+	}
+	setupLogin(user, c)
+}
+
+// findOrCreateLDAPUser 按目录条目的稳定标识查找已关联的用户，找不到时即时创建。
+func findOrCreateLDAPUser(ldapUser *oauth.OAuthUser) (*model.User, error) {
+	userId, err := model.GetUserIdByExternalIdentity(model.ExternalIdentityProviderLDAP, ldapUser.ProviderUserID)
+	if err != nil {
+		return nil, err
+	}
+	if userId != 0 {
+		user, err := model.GetUserById(userId, false)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &OAuthUserDeletedError{}
+		}
+		return user, err
+	}
+
+	if !common.RegisterEnabled {
+		return nil, &OAuthRegistrationDisabledError{}
+	}
+	user := &model.User{
+		Username: ldapProvider.GetProviderPrefix() + strconv.Itoa(model.GetMaxUserId()+1),
+		Role:     common.RoleCommonUser,
+		Status:   common.UserStatusEnabled,
+	}
+	if ldapUser.Username != "" && len(ldapUser.Username) <= model.UserNameMaxLength {
+		if exists, err := model.CheckUserExistOrDeleted(ldapUser.Username, ""); err == nil && !exists {
+			user.Username = ldapUser.Username
+		}
+	}
+	if ldapUser.DisplayName != "" {
+		user.DisplayName = ldapUser.DisplayName
+	} else {
+		user.DisplayName = ldapUser.Username
+	}
+	if utf8.RuneCountInString(user.DisplayName) > scimDisplayMaxRunes {
+		user.DisplayName = string([]rune(user.DisplayName)[:scimDisplayMaxRunes])
+	}
+	if ldapUser.Email != "" {
+		user.Email = model.NormalizeEmail(ldapUser.Email)
+		if err := model.EnsureEmailAvailable(user.Email, 0); err != nil {
+			if errors.Is(err, model.ErrEmailAlreadyTaken) {
+				return nil, &OAuthEmailAlreadyTakenError{}
+			}
+			return nil, err
+		}
+	}
+
+	err = model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := user.InsertWithTx(tx, 0); err != nil {
+			return err
+		}
+		return model.ClaimExternalIdentityWithTx(tx, model.ExternalIdentityProviderLDAP, ldapUser.ProviderUserID, user.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.FinalizeOAuthUserCreation(0)
+	return model.GetUserById(user.Id, false)
+}
+
+// syncLDAPUserAccess 按目录组映射同步分组与管理员角色：配置了 ldap.group_mapping 时
+// 取第一个有映射的组，都没有时回到 default；配置了 ldap.role_mapping 时映射到 admin
+// 的组成员为管理员，其余为普通用户。root 用户不受目录控制。
+func syncLDAPUserAccess(c *gin.Context, user *model.User, groups []string) error {
+	if user.Role == common.RoleRootUser {
+		return nil
+	}
+	settings := system_setting.GetLDAPSettings()
+	targetGroup, targetRole := user.Group, user.Role
+	if len(settings.GroupMapping) > 0 {
+		targetGroup = resolveLDAPUserGroup(settings, groups)
+	}
+	if len(settings.RoleMapping) > 0 {
+		targetRole = resolveLDAPUserRole(settings, groups)
+	}
+	fromGroup, fromRole := user.Group, user.Role
+	changed, err := applyDirectoryUserAccess(user, targetGroup, targetRole, nil, "ldap_access_changed")
+	if err != nil || !changed {
+		return err
+	}
+	params := map[string]interface{}{
+		"username":  user.Username,
+		"group":     targetGroup,
+		"role":      targetRole,
+		"fromGroup": fromGroup,
+		"fromRole":  fromRole,
+	}
+	model.RecordOperationAuditLog(user.Id, auditContentEN("ldap.user_access_sync", params), c.ClientIP(),
+		"ldap.user_access_sync", params, map[string]interface{}{"auth_method": "ldap"}, nil)
+	return nil
+}
+
+func resolveLDAPUserGroup(settings *system_setting.LDAPSettings, groups []string) string {
+	for _, group := range groups {
+		if mapped := oauth.LDAPGroupMapping(settings.GroupMapping, group); mapped != "" {
+			return mapped
+		}
+	}
+	return "default"
+}
+
+func resolveLDAPUserRole(settings *system_setting.LDAPSettings, groups []string) int {
+	for _, group := range groups {
+		// root 不可经由目录授予，只识别 admin
+		if oauth.LDAPGroupMapping(settings.RoleMapping, group) == authz.BuiltInRoleAdmin {
+			return common.RoleAdminUser
+		}
+	}
+	return common.RoleCommonUser
+}
+
+// TestLDAPConnection 用请求中的设置（未保存的表单）或当前设置测试服务账号绑定；
+// 提供 username 时额外执行一次用户搜索并返回映射结果，不校验用户密码。
+// 请求中 bind_secret 留空且服务器地址与 bind_dn 未改动时沿用已保存的值，
+// 前端无需回显密钥，也不会把已保存的密钥发往新填写的服务器。
+func TestLDAPConnection(c *gin.Context) {
+	var req ldapTestRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	current := system_setting.GetLDAPSettings()
+	settings := *current
+	if req.Settings != nil {
+		settings = *req.Settings
+		if settings.BindSecret == "" && settings.ServerURL == current.ServerURL && settings.BindDN == current.BindDN {
+			settings.BindSecret = current.BindSecret
+		}
+	}
+
+	ldapUser, err := ldapProvider.TestConnection(c.Request.Context(), &settings, req.Username)
+	if err != nil {
+		var oauthErr *oauth.OAuthError
+		if errors.As(err, &oauthErr) && oauthErr.RawError != "" {
+			common.ApiErrorMsg(c, oauthErr.RawError)
+			return
+		}
+		if errors.Is(err, oauth.ErrLDAPInvalidCredentials) {
+			common.ApiErrorMsg(c, "no unique directory entry matches the user filter")
+			return
+		}
+		handleOAuthError(c, err)
+		return
+	}
+	data := gin.H{"connected": true}
+	if ldapUser != nil {
+		groups, _ := ldapUser.Extra["groups"].([]string)
+		role := "user"
+		if len(settings.RoleMapping) > 0 && resolveLDAPUserRole(&settings, groups) == common.RoleAdminUser {
+			role = authz.BuiltInRoleAdmin
+		}
+		group := ""
+		if len(settings.GroupMapping) > 0 {
+			group = resolveLDAPUserGroup(&settings, groups)
+		}
+		data["user"] = gin.H{
+			"dn":           ldapUser.Extra["dn"],
+			"subject":      ldapUser.ProviderUserID,
+			"username":     ldapUser.Username,
+			"email":        ldapUser.Email,
+			"display_name": ldapUser.DisplayName,
+			"groups":       groups,
+			"group":        group,
+			"role":         role,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    data,
+	})
+}
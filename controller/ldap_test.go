@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth/ldaptest"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLDAPTest(t *testing.T) (*gin.Engine, *system_setting.LDAPSettings) {
+	t.Helper()
+	db := setupManageUserTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.ExternalIdentityClaim{}, &model.TwoFA{}))
+	previousMaster, previousRegister := common.IsMasterNode, common.RegisterEnabled
+	common.IsMasterNode = false
+	common.RegisterEnabled = true
+	require.NoError(t, authz.Init(model.DB))
+
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: "cn=service,dc=corp,dc=example", Password: "service-secret"},
+		ldaptest.Entry{
+			DN:       "uid=alice,ou=people,dc=corp,dc=example",
+			Password: "wonderland",
+			Attributes: map[string][]string{
+				"uid":       {"alice"},
+				"entryUUID": {"5d1f7c0e-alice"},
+				"mail":      {"alice@corp.example"},
+				"cn":        {"Alice Liddell"},
+				"memberOf": {
+					"cn=engineering,ou=groups,dc=corp,dc=example",
+					"cn=admins,ou=groups,dc=corp,dc=example",
+				},
+			},
+		},
+	)
+
+	settings := system_setting.GetLDAPSettings()
+	previous := *settings
+	*settings = system_setting.LDAPSettings{
+		Enabled:              true,
+		ServerURL:            server.URL,
+		BindDN:               "cn=service,dc=corp,dc=example",
+		BindSecret:           "service-secret",
+		BaseDN:               "dc=corp,dc=example",
+		UserFilter:           "(uid=%s)",
+		IdentityAttribute:    "entryUUID",
+		UsernameAttribute:    "uid",
+		EmailAttribute:       "mail",
+		DisplayNameAttribute: "cn",
+		GroupMembershipAttr:  "memberOf",
+		GroupMapping:         map[string]string{"engineering": "vip"},
+		RoleMapping:          map[string]string{"admins": authz.BuiltInRoleAdmin},
+	}
+	t.Cleanup(func() {
+		*settings = previous
+		server.Close()
+		common.IsMasterNode, common.RegisterEnabled = previousMaster, previousRegister
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/user/login/ldap", LDAPLogin)
+	router.POST("/api/option/ldap/test", TestLDAPConnection)
+	return router, settings
+}
+
+func performLDAPRequest(t *testing.T, router *gin.Engine, target string, body string) map[string]any {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var response map[string]any
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+	return response
+}
+
+func TestLDAPLoginCreatesUserAndSyncsGroupMapping(t *testing.T) {
+	router, settings := setupLDAPTest(t)
+
+	response := performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wrong"}`)
+	assert.Equal(t, false, response["success"])
+
+	response = performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wonderland"}`)
+	require.Equal(t, true, response["success"], response["message"])
+	data := response["data"].(map[string]any)
+	assert.NotEmpty(t, data["access_token"])
+
+	userId, err := model.GetUserIdByExternalIdentity(model.ExternalIdentityProviderLDAP, "5d1f7c0e-alice")
+	require.NoError(t, err)
+	require.NotZero(t, userId)
+	var user model.User
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "Alice Liddell", user.DisplayName)
+	assert.Equal(t, "alice@corp.example", user.Email)
+	assert.Equal(t, "vip", user.Group)
+	assert.Equal(t, common.RoleAdminUser, user.Role)
+
+	var session model.UserSession
+	require.NoError(t, model.DB.Where("user_id = ?", userId).First(&session).Error)
+	assert.Equal(t, "ldap", session.LoginMethod)
+
+	// 目录侧撤销管理员映射后，下次登录降级并吊销已有会话
+	settings.RoleMapping = map[string]string{"platform-admins": authz.BuiltInRoleAdmin}
+	response = performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wonderland"}`)
+	require.Equal(t, true, response["success"], response["message"])
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, common.RoleCommonUser, user.Role)
+	require.NoError(t, model.DB.First(&session, "sid = ?", session.SID).Error)
+	assert.Equal(t, model.UserSessionStatusRevoked, session.Status)
+	assert.Equal(t, "ldap_access_changed", session.RevokedReason)
+
+	var count int64
+	require.NoError(t, model.DB.Model(&model.User{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count, "repeat logins must reuse the linked account")
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).
+		Where("user_id = ? AND type = ? AND other LIKE ?", userId, model.LogTypeManage, "%ldap.user_access_sync%").
+		Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestLDAPLoginRespectsRegistrationAndUserStatus(t *testing.T) {
+	router, _ := setupLDAPTest(t)
+
+	common.RegisterEnabled = false
+	response := performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wonderland"}`)
+	assert.Equal(t, false, response["success"])
+
+	common.RegisterEnabled = true
+	response = performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wonderland"}`)
+	require.Equal(t, true, response["success"], response["message"])
+
+	userId, err := model.GetUserIdByExternalIdentity(model.ExternalIdentityProviderLDAP, "5d1f7c0e-alice")
+	require.NoError(t, err)
+	require.NoError(t, model.DB.Model(&model.User{}).Where("id = ?", userId).Update("status", common.UserStatusDisabled).Error)
+	response = performLDAPRequest(t, router, "/api/user/login/ldap", `{"username":"alice","password":"wonderland"}`)
+	assert.Equal(t, false, response["success"])
+}
+
+func TestLDAPTestConnectionReusesSecretOnlyForSameServer(t *testing.T) {
+	router, settings := setupLDAPTest(t)
+
+	response := performLDAPRequest(t, router, "/api/option/ldap/test", `{"username":"alice"}`)
+	require.Equal(t, true, response["success"], response["message"])
+	user := response["data"].(map[string]any)["user"].(map[string]any)
+	assert.Equal(t, "uid=alice,ou=people,dc=corp,dc=example", user["dn"])
+	assert.Equal(t, "vip", user["group"])
+	assert.Equal(t, authz.BuiltInRoleAdmin, user["role"])
+
+	form := *settings
+	form.BindSecret = ""
+	body, err := common.Marshal(ldapTestRequest{Settings: &form})
+	require.NoError(t, err)
+	response = performLDAPRequest(t, router, "/api/option/ldap/test", string(body))
+	assert.Equal(t, true, response["success"], response["message"])
+
+	// 改了服务器地址时不会把已保存的密钥发过去
+	other := ldaptest.NewServer(ldaptest.Entry{DN: "cn=service,dc=corp,dc=example", Password: "service-secret"})
+	defer other.Close()
+	form.ServerURL = other.URL
+	body, err = common.Marshal(ldapTestRequest{Settings: &form})
+	require.NoError(t, err)
+	response = performLDAPRequest(t, router, "/api/option/ldap/test", string(body))
+	assert.Equal(t, false, response["success"])
+	assert.Empty(t, other.Binds())
+}
@@ -22,7 +22,8 @@ func GetAllLogs(c *gin.Context) {
 	group := c.Query("group")
 	requestId := c.Query("request_id")
 	upstreamRequestId := c.Query("upstream_request_id")
-	logs, total, err := model.GetAllLogs(logType, startTimestamp, endTimestamp, modelName, username, tokenName, pageInfo.GetStartIdx(), pageInfo.GetPageSize(), channel, group, requestId, upstreamRequestId)
+	endUserId := c.Query("end_user_id")
+	logs, total, err := model.GetAllLogs(logType, startTimestamp, endTimestamp, modelName, username, tokenName, pageInfo.GetStartIdx(), pageInfo.GetPageSize(), channel, group, requestId, upstreamRequestId, endUserId)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -44,7 +45,8 @@ func GetUserLogs(c *gin.Context) {
 	group := c.Query("group")
 	requestId := c.Query("request_id")
 	upstreamRequestId := c.Query("upstream_request_id")
-	logs, total, err := model.GetUserLogs(userId, logType, startTimestamp, endTimestamp, modelName, tokenName, pageInfo.GetStartIdx(), pageInfo.GetPageSize(), group, requestId, upstreamRequestId)
+	endUserId := c.Query("end_user_id")
+	logs, total, err := model.GetUserLogs(userId, logType, startTimestamp, endTimestamp, modelName, tokenName, pageInfo.GetStartIdx(), pageInfo.GetPageSize(), group, requestId, upstreamRequestId, endUserId)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -55,6 +57,52 @@ func GetUserLogs(c *gin.Context) {
 	return
 }
 
+// GetAllEndUserUsage 管理员按终端用户汇总消费，可按用户名、令牌名称与时间范围过滤
+func GetAllEndUserUsage(c *gin.Context) {
+	filter := endUserUsageFilterFromQuery(c)
+	filter.Username = c.Query("username")
+	respondEndUserUsage(c, filter)
+}
+
+// GetUserEndUserUsage 当前用户按终端用户汇总自己令牌下的消费
+func GetUserEndUserUsage(c *gin.Context) {
+	filter := endUserUsageFilterFromQuery(c)
+	filter.UserId = c.GetInt("id")
+	respondEndUserUsage(c, filter)
+}
+
+// GetTokenEndUserUsage 令牌按终端用户汇总自身的消费，供接入方向自己的客户计费
+func GetTokenEndUserUsage(c *gin.Context) {
+	filter := endUserUsageFilterFromQuery(c)
+	filter.UserId = c.GetInt("id")
+	filter.TokenId = c.GetInt("token_id")
+	filter.TokenName = ""
+	respondEndUserUsage(c, filter)
+}
+
+func endUserUsageFilterFromQuery(c *gin.Context) model.EndUserUsageFilter {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return model.EndUserUsageFilter{
+		TokenName:      c.Query("token_name"),
+		EndUserId:      c.Query("end_user_id"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+}
+
+func respondEndUserUsage(c *gin.Context, filter model.EndUserUsageFilter) {
+	pageInfo := common.GetPageQuery(c)
+	usages, total, err := model.GetEndUserUsage(filter, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(usages)
+	common.ApiSuccess(c, pageInfo)
+}
+
 // Deprecated: SearchAllLogs 已废弃，前端未使用该接口。
 func SearchAllLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -110,14 +158,26 @@ func GetLogsStat(c *gin.Context) {
 		return
 	}
 	//tokenNum := model.SumUsedToken(logType, startTimestamp, endTimestamp, modelName, username, "")
+	data := gin.H{
+		"quota": stat.Quota,
+		"rpm":   stat.Rpm,
+		"tpm":   stat.Tpm,
+	}
+	if !addLabelUsage(c, data, model.LabelUsageFilter{
+		Username:       username,
+		TokenName:      tokenName,
+		ModelName:      modelName,
+		Group:          group,
+		ChannelId:      channel,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data": gin.H{
-			"quota": stat.Quota,
-			"rpm":   stat.Rpm,
-			"tpm":   stat.Tpm,
-		},
+		"data":    data,
 	})
 	return
 }
@@ -137,15 +197,42 @@ func GetLogsSelfStat(c *gin.Context) {
 		return
 	}
 	//tokenNum := model.SumUsedToken(logType, startTimestamp, endTimestamp, modelName, username, tokenName)
+	data := gin.H{
+		"quota": quotaNum.Quota,
+		"rpm":   quotaNum.Rpm,
+		"tpm":   quotaNum.Tpm,
+		//"token": tokenNum,
+	}
+	if !addLabelUsage(c, data, model.LabelUsageFilter{
+		UserId:         c.GetInt("id"),
+		TokenName:      tokenName,
+		ModelName:      modelName,
+		Group:          group,
+		ChannelId:      channel,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}) {
+		return
+	}
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "",
-		"data": gin.H{
-			"quota": quotaNum.Quota,
-			"rpm":   quotaNum.Rpm,
-			"tpm":   quotaNum.Tpm,
-			//"token": tokenNum,
-		},
+		"data":    data,
 	})
 	return
 }
+
+// addLabelUsage 请求携带 group_by_label 时在统计结果中附加按该标签取值汇总的消费，返回 false 时已写入错误响应
+func addLabelUsage(c *gin.Context, data gin.H, filter model.LabelUsageFilter) bool {
+	filter.Label = c.Query("group_by_label")
+	if filter.Label == "" {
+		return true
+	}
+	usages, err := model.GetLabelUsage(filter)
+	if err != nil {
+		common.ApiError(c, err)
+		return false
+	}
+	data["labels"] = usages
+	return true
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type endUserUsagePage struct {
+	Total int                   `json:"total"`
+	Items []*model.EndUserUsage `json:"items"`
+}
+
+func recordEndUserConsume(t *testing.T, userId int, tokenId int, endUserId string, quota int) {
+	t.Helper()
+	ctx, _ := newAuthenticatedContext(t, http.MethodPost, "/v1/chat/completions", nil, userId)
+	common.SetContextKey(ctx, constant.ContextKeyTokenId, tokenId)
+	common.SetContextKey(ctx, constant.ContextKeyTokenEndUserDailyQuota, 1000)
+	if endUserId != "" {
+		common.SetContextKey(ctx, constant.ContextKeyEndUserId, endUserId)
+	}
+	model.RecordConsumeLog(ctx, userId, model.RecordConsumeLogParams{
+		ModelName:        "gpt-4o-mini",
+		TokenName:        "gateway",
+		TokenId:          tokenId,
+		Quota:            quota,
+		PromptTokens:     10,
+		CompletionTokens: 5,
+	})
+}
+
+func TestEndUserUsageAggregatesConsumeLogs(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Log{}))
+
+	recordEndUserConsume(t, 1, 7, "alice", 100)
+	recordEndUserConsume(t, 1, 7, "alice", 50)
+	recordEndUserConsume(t, 1, 7, "bob", 300)
+	recordEndUserConsume(t, 1, 7, "", 999)
+	recordEndUserConsume(t, 1, 8, "carol", 10)
+	recordEndUserConsume(t, 2, 9, "dave", 10)
+
+	ctx, recorder := newAuthenticatedContext(t, http.MethodGet, "/api/log/self/end_user", nil, 1)
+	GetUserEndUserUsage(ctx)
+	response := decodeAPIResponse(t, recorder)
+	require.True(t, response.Success, response.Message)
+	var page endUserUsagePage
+	require.NoError(t, common.Unmarshal(response.Data, &page))
+	assert.Equal(t, 3, page.Total, "requests without an end user and other users' logs are excluded")
+	require.Len(t, page.Items, 3)
+	assert.Equal(t, "bob", page.Items[0].EndUserId)
+	assert.Equal(t, "alice", page.Items[1].EndUserId)
+	assert.EqualValues(t, 2, page.Items[1].Requests)
+	assert.EqualValues(t, 150, page.Items[1].Quota)
+	assert.EqualValues(t, 20, page.Items[1].PromptTokens)
+	assert.EqualValues(t, 10, page.Items[1].CompletionTokens)
+
+	ctx, recorder = newAuthenticatedContext(t, http.MethodGet, "/api/usage/token/end_user", nil, 1)
+	ctx.Set("token_id", 8)
+	GetTokenEndUserUsage(ctx)
+	response = decodeAPIResponse(t, recorder)
+	require.True(t, response.Success, response.Message)
+	require.NoError(t, common.Unmarshal(response.Data, &page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "carol", page.Items[0].EndUserId)
+
+	logs, total, err := model.GetUserLogs(1, model.LogTypeConsume, 0, 0, "", "", 0, 10, "", "", "", "alice")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Len(t, logs, 2)
+
+	used, err := model.GetEndUserDailyQuota(7, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 150, used, "consumption counts toward the end user's daily quota")
+}
@@ -84,6 +84,15 @@ func initModelListColumnNames(t *testing.T) {
 	common.SQLitePath = fmt.Sprintf("file:%s_init?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
 	common.SetDatabaseTypes(common.DatabaseTypeSQLite, common.DatabaseTypeSQLite)
 	require.NoError(t, os.Setenv("SQL_DSN", "local"))
+	// Non-master nodes expect the master to have created the options table already
+	seedDB, err := gorm.Open(sqlite.Open(common.SQLitePath), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, seedDB.AutoMigrate(&model.Option{}))
+	defer func() {
+		if sqlDB, err := seedDB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}()
 
 	require.NoError(t, model.InitDB())
 	if model.DB != nil {
@@ -218,7 +218,13 @@ func HandleOAuth(c *gin.Context) {
 		return
 	}
 
-	// 9. Setup login
+	// 9. Sync group, usable groups and role from the provider's claim mapping
+	if err := syncOAuthClaimAccess(c, providerName, provider, user, oauthUser); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	// 10. Setup login
 	setupLogin(user, c)
 }
 
@@ -366,13 +372,17 @@ func findOrCreateOAuthUser(c *gin.Context, provider oauth.Provider, oauthUser *o
 	if affiliateCode != "" {
 		inviterId, _ = model.GetUserIdByAffCode(affiliateCode)
 	}
+	initialQuota, err := oauthInitialQuota(provider, oauthUser)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use transaction to ensure user creation and OAuth binding are atomic
 	if genericProvider, ok := provider.(*oauth.GenericOAuthProvider); ok {
 		// Custom provider: create user and binding in a transaction
 		err := model.DB.Transaction(func(tx *gorm.DB) error {
 			// Create user
-			if err := user.InsertWithTx(tx, inviterId); err != nil {
+			if err := insertOAuthUserWithTx(tx, user, inviterId, initialQuota); err != nil {
 				return err
 			}
 
@@ -398,7 +408,7 @@ func findOrCreateOAuthUser(c *gin.Context, provider oauth.Provider, oauthUser *o
 		// Built-in provider: create user and update provider ID in a transaction
 		err := model.DB.Transaction(func(tx *gorm.DB) error {
 			// Create user
-			if err := user.InsertWithTx(tx, inviterId); err != nil {
+			if err := insertOAuthUserWithTx(tx, user, inviterId, initialQuota); err != nil {
 				return err
 			}
 
@@ -428,6 +438,19 @@ func findOrCreateOAuthUser(c *gin.Context, provider oauth.Provider, oauthUser *o
 	return user, nil
 }
 
+// insertOAuthUserWithTx creates the user, replacing the default new-user quota
+// with the one assigned by the provider's claim mapping when present.
+func insertOAuthUserWithTx(tx *gorm.DB, user *model.User, inviterId int, initialQuota *int) error {
+	if err := user.InsertWithTx(tx, inviterId); err != nil {
+		return err
+	}
+	if initialQuota == nil {
+		return nil
+	}
+	user.Quota = *initialQuota
+	return tx.Model(&model.User{}).Where("id = ?", user.Id).Update("quota", user.Quota).Error
+}
+
 // Error types for OAuth
 type OAuthUserDeletedError struct{}
 
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthClaimMapping 返回身份源配置的 claim 映射，未配置时返回 nil。
+func oauthClaimMapping(provider oauth.Provider) (*oauth.ClaimMapping, error) {
+	source, ok := provider.(oauth.ClaimMappingSource)
+	if !ok {
+		return nil, nil
+	}
+	return oauth.ParseClaimMapping(source.ClaimMapping())
+}
+
+func oauthUserClaims(oauthUser *oauth.OAuthUser) string {
+	claims, _ := oauthUser.Extra[oauth.ExtraClaimsKey].(string)
+	return claims
+}
+
+// oauthInitialQuota 返回 claim 映射为新用户指定的初始额度，未指定时返回 nil。
+func oauthInitialQuota(provider oauth.Provider, oauthUser *oauth.OAuthUser) (*int, error) {
+	mapping, err := oauthClaimMapping(provider)
+	if err != nil || mapping == nil {
+		return nil, err
+	}
+	return mapping.Evaluate(oauthUserClaims(oauthUser)).Quota, nil
+}
+
+// syncOAuthClaimAccess 每次登录时按身份源的 claim 映射重新同步分组、额外可用分组与
+// 角色，并以该身份源的 locked_fields 替换用户的字段锁。未配置映射的身份源不做处理；
+// root 用户不受映射控制。
+func syncOAuthClaimAccess(c *gin.Context, providerName string, provider oauth.Provider, user *model.User, oauthUser *oauth.OAuthUser) error {
+	mapping, err := oauthClaimMapping(provider)
+	if err != nil || mapping == nil || user.Role == common.RoleRootUser {
+		return err
+	}
+	result := mapping.Evaluate(oauthUserClaims(oauthUser))
+	targetGroup, targetRole := user.Group, user.Role
+	if result.Group != "" {
+		targetGroup = result.Group
+	}
+	switch result.Role {
+	case oauth.ClaimRoleAdmin:
+		targetRole = common.RoleAdminUser
+	case oauth.ClaimRoleUser:
+		targetRole = common.RoleCommonUser
+	}
+	fromGroup, fromRole, fromUsableGroups := user.Group, user.Role, user.UsableGroups
+	changed, err := applyDirectoryUserAccess(user, targetGroup, targetRole, result.UsableGroups, "oauth_access_changed")
+	if err != nil {
+		return err
+	}
+	if err := model.SetUserLockedFields(user.Id, providerName, result.LockedFields); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	params := map[string]interface{}{
+		"provider":         provider.GetName(),
+		"username":         user.Username,
+		"group":            user.Group,
+		"role":             user.Role,
+		"usableGroups":     strings.Join(user.GetUsableGroups(), ", "),
+		"fromGroup":        fromGroup,
+		"fromRole":         fromRole,
+		"fromUsableGroups": strings.Join(model.SplitUserUsableGroups(fromUsableGroups), ", "),
+	}
+	model.RecordOperationAuditLog(user.Id, auditContentEN("oauth.user_access_sync", params), c.ClientIP(),
+		"oauth.user_access_sync", params, map[string]interface{}{"auth_method": providerName}, nil)
+	return nil
+}
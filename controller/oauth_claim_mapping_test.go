@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOAuthClaimMapping = `{
+	"rules": [
+		{"when": {"conditions": [{"field": "groups", "op": "contains", "value": "platform-admins"}]}, "role": "admin"},
+		{"when": {"conditions": [{"field": "department", "op": "eq", "value": "research"}]}, "group": "vip", "usable_groups": ["gpu"], "quota": 1234}
+	],
+	"locked_fields": ["group", "role"]
+}`
+
+func setupOAuthClaimMappingTest(t *testing.T) *oauth.GenericOAuthProvider {
+	t.Helper()
+	db := setupManageUserTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.UserOAuthBinding{}))
+	previousMaster, previousRegister := common.IsMasterNode, common.RegisterEnabled
+	common.IsMasterNode = false
+	common.RegisterEnabled = true
+	t.Cleanup(func() {
+		common.IsMasterNode, common.RegisterEnabled = previousMaster, previousRegister
+	})
+	require.NoError(t, authz.Init(model.DB))
+	return oauth.NewGenericOAuthProvider(&model.CustomOAuthProvider{
+		Id: 7, Name: "Acme SSO", Slug: "acme", Enabled: true, ClaimMapping: testOAuthClaimMapping,
+	})
+}
+
+func oauthClaimLogin(t *testing.T, provider *oauth.GenericOAuthProvider, claims string) *model.User {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/oauth/acme", nil)
+	oauthUser := &oauth.OAuthUser{
+		ProviderUserID: "acme-alice",
+		Username:       "alice",
+		Extra:          map[string]any{oauth.ExtraClaimsKey: claims},
+	}
+	user, err := findOrCreateOAuthUser(c, provider, oauthUser, "")
+	require.NoError(t, err)
+	require.NoError(t, syncOAuthClaimAccess(c, "acme", provider, user, oauthUser))
+	return user
+}
+
+func TestOAuthClaimMappingSyncsAccessOnEveryLogin(t *testing.T) {
+	provider := setupOAuthClaimMappingTest(t)
+
+	user := oauthClaimLogin(t, provider, `{"groups": ["platform-admins"], "department": "research"}`)
+	var stored model.User
+	require.NoError(t, model.DB.First(&stored, user.Id).Error)
+	assert.Equal(t, "vip", stored.Group)
+	assert.Equal(t, common.RoleAdminUser, stored.Role)
+	assert.Equal(t, []string{"gpu"}, stored.GetUsableGroups())
+	assert.Equal(t, 1234, stored.Quota, "the mapped quota replaces the default new-user quota")
+	_, ok := service.GetUserUsableGroups(stored.Group, stored.GetUsableGroups()...)["gpu"]
+	assert.True(t, ok)
+	locked, err := model.GetUserLockedFields(user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oauth.ClaimFieldGroup, oauth.ClaimFieldRole}, locked)
+
+	now := time.Now().Unix()
+	require.NoError(t, model.DB.Create(&model.UserSession{
+		SID: "claim-session", UserID: user.Id, Version: 1, UserAuthVersion: stored.AuthVersion,
+		Status: model.UserSessionStatusActive, RefreshHash: "refresh-claim", LoginMethod: "acme",
+		LastActiveAt: now, ExpiresAt: now + 3600,
+	}).Error)
+
+	// 身份源侧移出部门与管理员组后，下次登录降级、清空额外分组并吊销已有会话，额度不变
+	user = oauthClaimLogin(t, provider, `{"groups": []}`)
+	require.NoError(t, model.DB.First(&stored, user.Id).Error)
+	assert.Equal(t, "default", stored.Group)
+	assert.Equal(t, common.RoleCommonUser, stored.Role)
+	assert.Empty(t, stored.UsableGroups)
+	assert.Equal(t, 1234, stored.Quota)
+	var session model.UserSession
+	require.NoError(t, model.DB.First(&session, "sid = ?", "claim-session").Error)
+	assert.Equal(t, model.UserSessionStatusRevoked, session.Status)
+	assert.Equal(t, "oauth_access_changed", session.RevokedReason)
+
+	var count int64
+	require.NoError(t, model.DB.Model(&model.User{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).
+		Where("user_id = ? AND type = ? AND other LIKE ?", user.Id, model.LogTypeManage, "%oauth.user_access_sync%").
+		Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestOAuthClaimMappingLockedFieldsRejectManualChanges(t *testing.T) {
+	provider := setupOAuthClaimMappingTest(t)
+	user := oauthClaimLogin(t, provider, `{"groups": ["platform-admins"], "department": "research"}`)
+
+	recorder := performManageUserRequest(t, fmt.Sprintf(`{"id":%d,"action":"demote"}`, user.Id))
+	assert.Contains(t, recorder.Body.String(), `"success":false`)
+
+	recorder = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/user/", strings.NewReader(
+		fmt.Sprintf(`{"id":%d,"username":"alice","display_name":"Alice","group":"default"}`, user.Id)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("id", 9999)
+	c.Set("role", common.RoleRootUser)
+	UpdateUser(c)
+	assert.Contains(t, recorder.Body.String(), `"success":false`)
+
+	var stored model.User
+	require.NoError(t, model.DB.First(&stored, user.Id).Error)
+	assert.Equal(t, "vip", stored.Group)
+	assert.Equal(t, common.RoleAdminUser, stored.Role)
+
+	// 映射去掉 locked_fields 后，下次登录解除锁定，后台可以再次调整
+	provider.GetConfig().ClaimMapping = strings.Replace(testOAuthClaimMapping, `"locked_fields": ["group", "role"]`, `"locked_fields": []`, 1)
+	oauthClaimLogin(t, provider, `{"groups": ["platform-admins"], "department": "research"}`)
+	recorder = performManageUserRequest(t, fmt.Sprintf(`{"id":%d,"action":"demote"}`, user.Id))
+	assert.Contains(t, recorder.Body.String(), `"success":true`)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
 	"github.com/QuantumNous/new-api/setting"
 	"github.com/QuantumNous/new-api/setting/console_setting"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -178,6 +179,22 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "oidc.claim_mapping":
+		if _, err := oauth.ParseClaimMapping(option.Value.(string)); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "OIDC claim 映射规则无效：" + err.Error(),
+			})
+			return
+		}
+	case "ldap.enabled":
+		if option.Value == "true" && (system_setting.GetLDAPSettings().ServerURL == "" || system_setting.GetLDAPSettings().BaseDN == "") {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无法启用 LDAP 登录，请先填入 LDAP 服务器地址以及 Base DN！",
+			})
+			return
+		}
 	case "LinuxDOOAuthEnabled":
 		if option.Value == "true" && common.LinuxDOClientId == "" {
 			c.JSON(http.StatusOK, gin.H{
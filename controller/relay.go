@@ -68,6 +68,32 @@ func geminiRelayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewA
 	return err
 }
 
+// attemptResult 一次转发尝试的结果，ctx 与 info 为该尝试实际使用的上下文
+type attemptResult struct {
+	ctx     *gin.Context
+	info    *relaycommon.RelayInfo
+	channel *model.Channel
+	err     *types.NewAPIError
+	// lost 对冲请求中该尝试返回时已经落选，其错误来自被取消，不计入渠道错误
+	lost bool
+}
+
+// relayAttempt 用当前上下文中已选定的渠道转发一次请求
+func relayAttempt(c *gin.Context, relayInfo *relaycommon.RelayInfo, relayFormat types.RelayFormat) *types.NewAPIError {
+	// 每次尝试都从空的图片预处理统计开始，未命中策略或透传的重试不沿用上一次的结果
+	service.ResetImagePreprocessStats(c, relayInfo)
+	switch relayFormat {
+	case types.RelayFormatOpenAIRealtime:
+		return relay.WssHelper(c, relayInfo)
+	case types.RelayFormatClaude:
+		return relay.ClaudeHelper(c, relayInfo)
+	case types.RelayFormatGemini:
+		return geminiRelayHandler(c, relayInfo)
+	default:
+		return relayHandler(c, relayInfo)
+	}
+}
+
 func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	requestId := c.GetString(common.RequestIdKey)
@@ -190,9 +216,14 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	}
 	relayInfo.RetryIndex = 0
 	relayInfo.LastError = nil
+	shadow := newShadowMirror(c, relayInfo, relayFormat)
+	split := service.GetTrafficSplitAssignment(c)
+	hedge := newRelayHedge(c, relayInfo, relayFormat, split)
 
 	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
 		relayInfo.RetryIndex = retryParam.GetRetry()
+		// 灰度分流的 canary 分支只作用于首次尝试，重试回到正常选路
+		relayInfo.TrafficSplitCanary = split.IsCanary() && relayInfo.RetryIndex == 0
 		channel, channelErr := getChannel(c, relayInfo, retryParam)
 		if channelErr != nil {
 			logger.LogError(c, channelErr.Error())
@@ -217,26 +248,29 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 		c.Request.Body = io.NopCloser(bodyStorage)
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
+		// 对冲只作用于首次尝试，胜出的一路可能是另一个渠道，渠道错误按实际返回结果的那一路处理
+		attempt := attemptResult{ctx: c, info: relayInfo, channel: channel}
+		if hedge != nil && relayInfo.RetryIndex == 0 {
+			attempt = hedge.run(c, relayInfo, channel)
+		} else {
+			attempt.err = relayAttempt(c, relayInfo, relayFormat)
+		}
+		newAPIError = attempt.err
+		if relayInfo.TrafficSplitCanary {
+			service.RecordTrafficSplitResult(split, time.Since(relayInfo.StartTime).Milliseconds(), newAPIError == nil)
 		}
 
 		if newAPIError == nil {
 			relayInfo.LastError = nil
+			shadow.start(c, attempt.info)
 			return
 		}
 
 		newAPIError = service.NormalizeViolationFeeError(newAPIError)
 		relayInfo.LastError = newAPIError
+		attempt.err = newAPIError
 
-		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
+		processAttemptChannelError(attempt)
 
 		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
 			break
@@ -360,6 +394,13 @@ func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) b
 	return operation_setting.ShouldRetryByStatusCode(code)
 }
 
+// processAttemptChannelError 按一次尝试实际使用的渠道与上下文处理渠道错误
+func processAttemptChannelError(attempt attemptResult) {
+	channel := attempt.channel
+	processChannelError(attempt.ctx, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey,
+		common.GetContextKeyString(attempt.ctx, constant.ContextKeyChannelKey), channel.GetAutoBan()), attempt.err)
+}
+
 func processChannelError(c *gin.Context, channelError types.ChannelError, err *types.NewAPIError) {
 	logger.LogError(c, fmt.Sprintf("channel error (channel #%d, status code: %d): %s", channelError.ChannelId, err.StatusCode, common.LocalLogPreview(err.Error())))
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
@@ -396,6 +437,8 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 			adminInfo["multi_key_index"] = common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
 		}
 		service.AppendChannelAffinityAdminInfo(c, adminInfo)
+		service.AppendTrafficSplitAdminInfo(c, adminInfo)
+		service.AppendHedgeAdminInfo(c, adminInfo)
 		other["admin_info"] = adminInfo
 		startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime)
 		if startTime.IsZero() {
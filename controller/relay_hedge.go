@@ -0,0 +1,358 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	hedgeWriterPending int32 = iota
+	hedgeWriterWon
+	hedgeWriterLost
+)
+
+// hedgeRace 两路尝试共用的裁决：先向客户端写出响应体的一方胜出，其余尝试被标记为落选并取消
+type hedgeRace struct {
+	mu      sync.Mutex
+	writers []*hedgeResponseWriter
+	decided bool
+	outcome *service.HedgeOutcome
+}
+
+// hedgeResponseWriter 一路尝试的响应写入器。胜负未定时响应头与状态码只记录在本地，
+// 首次写入响应体时参与裁决：胜出后把响应头复制到客户端连接并直接写出，落选后丢弃全部写入。
+type hedgeResponseWriter struct {
+	gin.ResponseWriter
+	race   *hedgeRace
+	arm    *relaycommon.HedgeArm
+	cancel context.CancelFunc
+	state  atomic.Int32
+	header http.Header
+	status int
+}
+
+// join 登记一路尝试，胜负已定时返回 nil，此时不应再发出该尝试
+func (r *hedgeRace) join(out gin.ResponseWriter, arm *relaycommon.HedgeArm, cancel context.CancelFunc) *hedgeResponseWriter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.decided {
+		return nil
+	}
+	w := &hedgeResponseWriter{ResponseWriter: out, race: r, arm: arm, cancel: cancel, header: make(http.Header)}
+	r.writers = append(r.writers, w)
+	return w
+}
+
+// claim 由首次写出响应体的尝试调用，返回该尝试是否胜出
+func (r *hedgeRace) claim(w *hedgeResponseWriter) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.decided {
+		return w.state.Load() == hedgeWriterWon
+	}
+	r.decided = true
+	for _, other := range r.writers {
+		if other == w {
+			other.state.Store(hedgeWriterWon)
+			continue
+		}
+		other.state.Store(hedgeWriterLost)
+		other.arm.MarkLost()
+		other.cancel()
+	}
+	if w.arm.Hedge {
+		r.outcome.SetWinner(service.HedgeWinnerHedge)
+	} else {
+		r.outcome.SetWinner(service.HedgeWinnerPrimary)
+	}
+	return true
+}
+
+func (r *hedgeRace) isDecided() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.decided
+}
+
+func (w *hedgeResponseWriter) won() bool {
+	return w.state.Load() == hedgeWriterWon
+}
+
+func (w *hedgeResponseWriter) Header() http.Header {
+	if w.won() {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *hedgeResponseWriter) WriteHeader(code int) {
+	switch w.state.Load() {
+	case hedgeWriterWon:
+		w.ResponseWriter.WriteHeader(code)
+	case hedgeWriterPending:
+		w.status = code
+	}
+}
+
+func (w *hedgeResponseWriter) WriteHeaderNow() {
+	if w.won() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *hedgeResponseWriter) Write(b []byte) (int, error) {
+	if w.state.Load() == hedgeWriterPending {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		if !w.race.claim(w) {
+			return len(b), nil
+		}
+		out := w.ResponseWriter.Header()
+		for name, values := range w.header {
+			out[name] = values
+		}
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	if !w.won() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hedgeResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *hedgeResponseWriter) Flush() {
+	if w.won() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *hedgeResponseWriter) Status() int {
+	if w.won() {
+		return w.ResponseWriter.Status()
+	}
+	if w.status != 0 {
+		return w.status
+	}
+	return http.StatusOK
+}
+
+func (w *hedgeResponseWriter) Size() int {
+	if w.won() {
+		return w.ResponseWriter.Size()
+	}
+	return -1
+}
+
+func (w *hedgeResponseWriter) Written() bool {
+	return w.won() && w.ResponseWriter.Written()
+}
+
+// relayHedge 一次请求命中的对冲策略
+type relayHedge struct {
+	policy      *model.HedgePolicy
+	group       string
+	delay       time.Duration
+	delaySource string
+	relayFormat types.RelayFormat
+}
+
+// newRelayHedge 匹配对冲策略，未命中返回 nil。只对冲 JSON 文本生成接口；
+// 指定渠道的请求与灰度分流的 canary 请求不做对冲，避免干扰其结果。
+func newRelayHedge(c *gin.Context, relayInfo *relaycommon.RelayInfo, relayFormat types.RelayFormat, split *service.TrafficSplitAssignment) *relayHedge {
+	switch relayFormat {
+	case types.RelayFormatOpenAI:
+		if relayInfo.RelayMode != relayconstant.RelayModeChatCompletions && relayInfo.RelayMode != relayconstant.RelayModeCompletions {
+			return nil
+		}
+	case types.RelayFormatOpenAIResponses:
+		if relayInfo.RelayMode != relayconstant.RelayModeResponses {
+			return nil
+		}
+	case types.RelayFormatClaude:
+	case types.RelayFormatGemini:
+		if strings.Contains(c.Request.URL.Path, "embed") {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if _, ok := c.Get("specific_channel_id"); ok || split.IsCanary() {
+		return nil
+	}
+	group := relayInfo.UsingGroup
+	if autoGroup := common.GetContextKeyString(c, constant.ContextKeyAutoGroup); autoGroup != "" {
+		group = autoGroup
+	}
+	policy := service.MatchHedgePolicy(relayInfo.OriginModelName, group)
+	if policy == nil {
+		return nil
+	}
+	delay, source := service.HedgeDelay(policy, relayInfo.OriginModelName, relayInfo.IsStream)
+	return &relayHedge{policy: policy, group: group, delay: delay, delaySource: source, relayFormat: relayFormat}
+}
+
+// run 向首个渠道发起请求，超过等待时间仍未写出响应时把同一请求发给另一个渠道，返回胜出一方的结果。
+// 两路都没有写出响应时返回首个渠道的结果；未被返回的一方如果是自身失败，其渠道错误在这里处理。
+func (h *relayHedge) run(c *gin.Context, relayInfo *relaycommon.RelayInfo, channel *model.Channel) attemptResult {
+	primary := attemptResult{ctx: c, info: relayInfo, channel: channel}
+	storage, err := common.GetBodyStorage(c)
+	var body []byte
+	if err == nil {
+		body, err = storage.Bytes()
+	}
+	if err != nil {
+		primary.err = relayAttempt(c, relayInfo, h.relayFormat)
+		return primary
+	}
+	body = bytes.Clone(body)
+
+	outcome := service.NewHedgeOutcome(h.policy, h.delay, h.delaySource, channel.Id)
+	common.SetContextKey(c, constant.ContextKeyHedge, outcome)
+	race := &hedgeRace{outcome: outcome}
+	originWriter, originRequest := c.Writer, c.Request
+
+	// 对冲尝试的上下文必须在首个尝试开始前复制，之后两路各自读写
+	hedgeCtx := c.Copy()
+	hedgeRequestCtx, cancelHedge := context.WithCancel(originRequest.Context())
+	defer cancelHedge()
+	hedgeCtx.Request = originRequest.Clone(hedgeRequestCtx)
+	hedgeInfo := relayInfo.CloneForHedge(nil)
+
+	primaryRequestCtx, cancelPrimary := context.WithCancel(originRequest.Context())
+	defer cancelPrimary()
+	primaryArm := relaycommon.NewHedgeArm(primaryRequestCtx, false, h.policy.LoserBillingUserId)
+	c.Writer = race.join(originWriter, primaryArm, cancelPrimary)
+	c.Request = originRequest.WithContext(primaryRequestCtx)
+	relayInfo.HedgeArm = primaryArm
+
+	primaryDone := make(chan struct{})
+	hedgeResult := make(chan *attemptResult, 1)
+	go func() {
+		var attempt *attemptResult
+		defer func() {
+			if r := recover(); r != nil {
+				logger.LogError(hedgeCtx, fmt.Sprintf("hedge attempt panic: %v", r))
+				if attempt != nil {
+					attempt.err = types.NewError(fmt.Errorf("hedge attempt panic: %v", r), types.ErrorCodeDoRequestFailed)
+					attempt.lost = attempt.info.HedgeArm.Lost()
+				}
+			}
+			hedgeResult <- attempt
+		}()
+		attempt = h.fire(race, primaryDone, hedgeCtx, hedgeInfo, originWriter, cancelHedge, body, channel.Id)
+		if attempt != nil && attempt.err == nil {
+			attempt.err = relayAttempt(attempt.ctx, attempt.info, h.relayFormat)
+			attempt.lost = attempt.info.HedgeArm.Lost()
+		}
+	}()
+
+	primary.err = relayAttempt(c, relayInfo, h.relayFormat)
+	primary.lost = primaryArm.Lost()
+	close(primaryDone)
+	hedged := <-hedgeResult
+
+	c.Writer, c.Request = originWriter, originRequest
+	relayInfo.HedgeArm = nil
+	if hedged == nil {
+		return primary
+	}
+	addUsedChannel(c, hedged.channel.Id)
+	if storage, ok := hedged.ctx.Get(common.KeyBodyStorage); ok {
+		if bs, ok := storage.(common.BodyStorage); ok {
+			_ = bs.Close()
+		}
+	}
+	hedged.info.HedgeArm = nil
+
+	hedgeWon := outcome.Winner() == service.HedgeWinnerHedge ||
+		(outcome.Winner() == "" && hedged.err == nil && primary.err != nil)
+	if !hedgeWon {
+		if hedged.err != nil && !hedged.lost {
+			processAttemptChannelError(*hedged)
+		}
+		return primary
+	}
+	if primary.err != nil && !primary.lost {
+		processAttemptChannelError(primary)
+	}
+	if usage, ok := common.GetContextKey(hedged.ctx, constant.ContextKeyRelayUsage); ok {
+		common.SetContextKey(c, constant.ContextKeyRelayUsage, usage)
+	}
+	return *hedged
+}
+
+// fire 等待首个尝试的首字节，超时且胜负未定时选出对冲渠道并准备好对冲尝试的上下文，不需要对冲时返回 nil。
+// 准备过程中出错时返回带错误的尝试，由调用方按对冲失败处理。
+func (h *relayHedge) fire(race *hedgeRace, primaryDone <-chan struct{}, hedgeCtx *gin.Context, hedgeInfo *relaycommon.RelayInfo,
+	out gin.ResponseWriter, cancel context.CancelFunc, body []byte, primaryChannelId int) *attemptResult {
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	select {
+	case <-primaryDone:
+		return nil
+	case <-timer.C:
+	}
+	if race.isDecided() {
+		return nil
+	}
+	channel := service.SelectHedgeChannel(h.group, hedgeInfo.OriginModelName, hedgeCtx.Request.URL.Path, primaryChannelId)
+	if channel == nil {
+		logger.LogWarn(hedgeCtx, fmt.Sprintf("hedge policy %d skipped: no other channel available for model %s", h.policy.Id, hedgeInfo.OriginModelName))
+		return nil
+	}
+	arm := relaycommon.NewHedgeArm(hedgeCtx.Request.Context(), true, h.policy.LoserBillingUserId)
+	writer := race.join(out, arm, cancel)
+	if writer == nil {
+		return nil
+	}
+	hedgeCtx.Writer = writer
+	hedgeInfo.HedgeArm = arm
+	race.outcome.SetHedgeChannel(channel.Id)
+	logger.LogInfo(hedgeCtx, fmt.Sprintf("hedge policy %d: no first byte from channel #%d after %s, hedging to channel #%d",
+		h.policy.Id, primaryChannelId, h.delay, channel.Id))
+
+	attempt := &attemptResult{ctx: hedgeCtx, info: hedgeInfo, channel: channel}
+	storage, err := common.CreateBodyStorage(body)
+	if err != nil {
+		attempt.err = types.NewError(err, types.ErrorCodeReadRequestBodyFailed)
+		return attempt
+	}
+	hedgeCtx.Set(common.KeyBodyStorage, storage)
+	if attempt.err = middleware.SetupContextForSelectedChannel(hedgeCtx, channel, hedgeInfo.OriginModelName); attempt.err != nil {
+		return attempt
+	}
+	request, err := helper.GetAndValidateRequest(hedgeCtx, h.relayFormat)
+	if err != nil {
+		attempt.err = types.NewError(err, types.ErrorCodeInvalidRequest)
+		return attempt
+	}
+	hedgeInfo.Request = request
+	hedgeCtx.Request.Body = io.NopCloser(storage)
+	return attempt
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestHedgeResponseWriterFirstWriterWins(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	outcome := service.NewHedgeOutcome(&model.HedgePolicy{Id: 1, Name: "chat"}, 100*time.Millisecond, service.HedgeDelaySourceFixed, 7)
+	race := &hedgeRace{outcome: outcome}
+
+	primaryCtx, cancelPrimary := context.WithCancel(context.Background())
+	defer cancelPrimary()
+	primaryArm := relaycommon.NewHedgeArm(primaryCtx, false, 0)
+	primary := race.join(ctx.Writer, primaryArm, cancelPrimary)
+	hedgeArm := relaycommon.NewHedgeArm(context.Background(), true, 0)
+	hedge := race.join(ctx.Writer, hedgeArm, func() {})
+
+	primary.Header().Set("X-Arm", "primary")
+	hedge.Header().Set("X-Arm", "hedge")
+	hedge.WriteHeader(http.StatusCreated)
+	hedge.Flush()
+	assert.False(t, hedge.Written(), "nothing reaches the client before the first body byte")
+	assert.Equal(t, http.StatusCreated, hedge.Status())
+
+	_, err := hedge.WriteString("data: hedge\n\n")
+	require.NoError(t, err)
+	n, err := primary.Write([]byte("data: primary\n\n"))
+	require.NoError(t, err)
+	assert.Equal(t, len("data: primary\n\n"), n, "the loser's writes are swallowed")
+
+	assert.Equal(t, "data: hedge\n\n", recorder.Body.String())
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "hedge", recorder.Header().Get("X-Arm"))
+	assert.True(t, primaryArm.Lost())
+	assert.False(t, hedgeArm.Lost())
+	assert.ErrorIs(t, primaryCtx.Err(), context.Canceled, "the loser is canceled")
+	assert.Equal(t, service.HedgeWinnerHedge, outcome.Winner())
+	assert.Nil(t, race.join(ctx.Writer, relaycommon.NewHedgeArm(context.Background(), false, 0), func() {}), "no attempt joins a decided race")
+}
+
+func TestRelayHedgeServesFasterChannel(t *testing.T) {
+	db := setupModelListControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Log{}, &model.Token{}, &model.HedgePolicy{}))
+	service.InvalidateHedgePolicyCache()
+	t.Cleanup(service.InvalidateHedgePolicyCache)
+	originalRatios := ratio_setting.ModelRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"gpt-4o-mini":1}`))
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(originalRatios))
+	})
+
+	var slowCanceled atomic.Bool
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices a client disconnect once the request body is consumed
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+			slowCanceled.Store(true)
+		case <-time.After(5 * time.Second):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"chatcmpl-slow","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"slow"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":1,"total_tokens":10}}`))
+		}
+	}))
+	t.Cleanup(slow.Close)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-fast","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"fast"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":3,"total_tokens":12}}`))
+	}))
+	t.Cleanup(fast.Close)
+
+	user := &model.User{Id: 4, Username: "hedge_user", Status: common.UserStatusEnabled, Group: "default", Quota: 1000000}
+	require.NoError(t, db.Create(user).Error)
+	slowChannel := &model.Channel{Id: 31, Type: constant.ChannelTypeOpenAI, Name: "slow", Key: "sk-slow", Status: common.ChannelStatusEnabled,
+		BaseURL: common.GetPointer(slow.URL), Models: "gpt-4o-mini", Group: "default", Priority: common.GetPointer(int64(0))}
+	fastChannel := &model.Channel{Id: 32, Type: constant.ChannelTypeOpenAI, Name: "fast", Key: "sk-fast", Status: common.ChannelStatusEnabled,
+		BaseURL: common.GetPointer(fast.URL), Models: "gpt-4o-mini", Group: "default", Priority: common.GetPointer(int64(10))}
+	require.NoError(t, slowChannel.Insert())
+	require.NoError(t, fastChannel.Insert())
+	policy := &model.HedgePolicy{Name: "chat", Enabled: true, Group: "default", DelayMs: 50}
+	require.NoError(t, policy.Normalize())
+	require.NoError(t, policy.Insert())
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(
+		`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"ping"}]}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Set(common.RequestIdKey, "req-hedge-1")
+	cache, err := model.GetUserCache(user.Id)
+	require.NoError(t, err)
+	cache.WriteContext(ctx)
+	ctx.Set("id", user.Id)
+	ctx.Set("group", "default")
+	common.SetContextKey(ctx, constant.ContextKeyUsingGroup, "default")
+
+	request, err := helper.GetAndValidateRequest(ctx, types.RelayFormatOpenAI)
+	require.NoError(t, err)
+	require.Nil(t, middleware.SetupContextForSelectedChannel(ctx, slowChannel, "gpt-4o-mini"))
+	info, err := relaycommon.GenRelayInfo(ctx, types.RelayFormatOpenAI, request, nil)
+	require.NoError(t, err)
+	_, err = helper.ModelPriceHelper(ctx, info, 9, request.GetTokenCountMeta())
+	require.NoError(t, err)
+
+	hedge := newRelayHedge(ctx, info, types.RelayFormatOpenAI, nil)
+	require.NotNil(t, hedge)
+	assert.Nil(t, newRelayHedge(ctx, info, types.RelayFormatEmbedding, nil), "only text generation is hedged")
+
+	storage, err := common.GetBodyStorage(ctx)
+	require.NoError(t, err)
+	ctx.Request.Body = io.NopCloser(storage)
+	attempt := hedge.run(ctx, info, &model.Channel{Id: slowChannel.Id, Type: slowChannel.Type, Name: slowChannel.Name})
+
+	require.Nil(t, attempt.err)
+	assert.Equal(t, fastChannel.Id, attempt.channel.Id)
+	assert.Equal(t, "fast", gjson.Get(recorder.Body.String(), "choices.0.message.content").String())
+	assert.Eventually(t, slowCanceled.Load, time.Second, 10*time.Millisecond, "the slow channel's request is canceled once the hedge wins")
+	assert.Nil(t, info.HedgeArm)
+	assert.Equal(t, []string{"32"}, ctx.GetStringSlice("use_channel"))
+
+	var logs []model.Log
+	require.NoError(t, db.Where("type = ?", model.LogTypeConsume).Find(&logs).Error)
+	require.Len(t, logs, 1, "only the winner is billed")
+	assert.Equal(t, fastChannel.Id, logs[0].ChannelId)
+	assert.EqualValues(t, 3, logs[0].CompletionTokens)
+	assert.Equal(t, "hedge", gjson.Get(logs[0].Other, "admin_info.hedge.winner").String())
+	assert.EqualValues(t, 50, gjson.Get(logs[0].Other, "admin_info.hedge.delay_ms").Int())
+	assert.EqualValues(t, slowChannel.Id, gjson.Get(logs[0].Other, "admin_info.hedge.primary_channel_id").Int())
+}
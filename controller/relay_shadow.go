@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxShadowRequestBodyBytes  = 4 << 20
+	maxShadowPrimaryCaptureLen = 256 << 10
+	shadowRequestTimeout       = 5 * time.Minute
+)
+
+// 影子请求不转发客户端凭据，上游鉴权使用影子渠道自身的密钥
+var shadowSkippedHeaders = map[string]struct{}{
+	"Authorization":     {},
+	"X-Api-Key":         {},
+	"X-Goog-Api-Key":    {},
+	"Cookie":            {},
+	"Content-Length":    {},
+	"Content-Type":      {},
+	"Accept-Encoding":   {},
+	"Connection":        {},
+	"Transfer-Encoding": {},
+}
+
+// shadowResponseWriter 在开启响应对比时复制一份主响应到有限大小的缓冲区，
+// 超出上限后不再缓存，该次采样不计算相似度
+type shadowResponseWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *shadowResponseWriter) Write(b []byte) (int, error) {
+	if !w.truncated {
+		if w.body.Len()+len(b) > maxShadowPrimaryCaptureLen {
+			w.truncated = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *shadowResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// shadowMirror 一次请求命中的影子规则，主请求成功后由 start 异步发出影子请求
+type shadowMirror struct {
+	rule   *model.ShadowRule
+	writer *shadowResponseWriter
+}
+
+// newShadowMirror 在转发前采样影子规则，未命中返回 nil。
+// 只镜像与回放入口一致的 JSON 文本接口，实时、音频、图片等请求不参与。
+func newShadowMirror(c *gin.Context, relayInfo *relaycommon.RelayInfo, relayFormat types.RelayFormat) *shadowMirror {
+	switch relayFormat {
+	case types.RelayFormatOpenAI, types.RelayFormatClaude, types.RelayFormatGemini,
+		types.RelayFormatOpenAIResponses, types.RelayFormatEmbedding, types.RelayFormatRerank:
+	default:
+		return nil
+	}
+	if testRelayFormatForPath(c.Request.URL.Path) != relayFormat {
+		return nil
+	}
+	rule := service.SampleShadowRule(relayInfo.OriginModelName, relayInfo.UsingGroup)
+	if rule == nil {
+		return nil
+	}
+	mirror := &shadowMirror{rule: rule}
+	if rule.CompareResponse {
+		mirror.writer = &shadowResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = mirror.writer
+	}
+	return mirror
+}
+
+// start 在主响应已写回客户端后调用，复制请求所需的数据后异步执行影子请求，不阻塞也不影响主请求。
+// 影子请求不计费、不记消费日志，估算消耗计入规则预算。
+func (m *shadowMirror) start(c *gin.Context, relayInfo *relaycommon.RelayInfo) {
+	if m == nil {
+		return
+	}
+	rule := m.rule
+	// 自动分组在选择渠道时才确定实际分组，此处按最终分组再确认一次
+	if !rule.Matches(relayInfo.OriginModelName, relayInfo.UsingGroup) || relayInfo.ChannelId == rule.ShadowChannelId {
+		return
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil || storage.Size() > maxShadowRequestBodyBytes {
+		return
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return
+	}
+	body = bytes.Clone(bytes.TrimSpace(body))
+	if len(body) == 0 || body[0] != '{' {
+		return
+	}
+	headers := make(map[string]string)
+	for name, values := range c.Request.Header {
+		if _, skip := shadowSkippedHeaders[http.CanonicalHeaderKey(name)]; skip || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+
+	metric := &model.ShadowMetric{
+		RuleId:           rule.Id,
+		RequestId:        c.GetString(common.RequestIdKey),
+		ModelName:        relayInfo.OriginModelName,
+		Group:            relayInfo.UsingGroup,
+		IsStream:         relayInfo.IsStream,
+		PrimaryChannelId: relayInfo.ChannelId,
+		PrimaryLatencyMs: time.Since(relayInfo.StartTime).Milliseconds(),
+		ShadowChannelId:  rule.ShadowChannelId,
+		Similarity:       model.ShadowSimilarityNotComputed,
+	}
+	if usage, ok := common.GetContextKeyType[*dto.Usage](c, constant.ContextKeyRelayUsage); ok && usage != nil {
+		metric.PrimaryPromptTokens = usage.PromptTokens
+		metric.PrimaryCompletionTokens = usage.CompletionTokens
+	}
+	primaryText := ""
+	if m.writer != nil && !m.writer.truncated {
+		primaryText = service.ShadowResponseText(m.writer.body.Bytes())
+	}
+	path := c.Request.URL.Path
+	userId := relayInfo.UserId
+
+	if !service.TryAcquireShadowSlot() {
+		logger.LogWarn(c, fmt.Sprintf("shadow rule %d skipped: too many concurrent shadow requests", rule.Id))
+		return
+	}
+	gopool.Go(func() {
+		defer service.ReleaseShadowSlot()
+		runShadowRequest(rule, metric, userId, path, body, headers, primaryText)
+	})
+}
+
+func runShadowRequest(rule *model.ShadowRule, metric *model.ShadowMetric, userId int, path string, body []byte, headers map[string]string, primaryText string) {
+	channel, err := model.GetChannelById(rule.ShadowChannelId, true)
+	if err != nil {
+		metric.ShadowError = "shadow channel not found"
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), shadowRequestTimeout)
+		result := replayChannel(ctx, channel, userId, metric.Group, path, metric.ModelName, body, headers)
+		cancel()
+		metric.ShadowLatencyMs = result.LatencyMs
+		metric.ShadowSuccess = result.Error == ""
+		metric.ShadowError = result.Error
+		metric.ShadowQuota = result.quota
+		if result.Upstream != nil {
+			metric.ShadowStatusCode = result.Upstream.StatusCode
+		}
+		if result.Usage != nil {
+			metric.ShadowPromptTokens = result.Usage.PromptTokens
+			metric.ShadowCompletionTokens = result.Usage.CompletionTokens
+		}
+		if rule.CompareResponse && metric.ShadowSuccess && primaryText != "" {
+			if similarity, ok := service.ShadowResponseSimilarity(primaryText, service.ShadowResponseText([]byte(result.Response))); ok {
+				metric.Similarity = similarity
+			}
+		}
+	}
+	if err := model.RecordShadowMetric(metric); err != nil {
+		common.SysError(fmt.Sprintf("failed to record shadow metric for rule %d: %s", rule.Id, err.Error()))
+	}
+	if err := service.AddShadowRuleUsage(rule.Id, metric.ShadowQuota); err != nil {
+		common.SysError(fmt.Sprintf("failed to add shadow usage for rule %d: %s", rule.Id, err.Error()))
+	}
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestShadowMirrorCopiesRequestToShadowChannel(t *testing.T) {
+	db := setupModelListControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Log{}, &model.ShadowRule{}, &model.ShadowMetric{}))
+	service.InvalidateShadowRuleCache()
+	t.Cleanup(service.InvalidateShadowRuleCache)
+	originalRatios := ratio_setting.ModelRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(`{"gpt-4o-mini":1}`))
+	t.Cleanup(func() {
+		require.NoError(t, ratio_setting.UpdateModelRatioByJSONString(originalRatios))
+	})
+
+	upstreamAuth := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamAuth <- r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-2","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":3,"total_tokens":12}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	require.NoError(t, db.Create(&model.User{Id: 3, Username: "shadow_user", Status: common.UserStatusEnabled, Group: "default"}).Error)
+	shadowChannel := &model.Channel{
+		Id:      21,
+		Type:    constant.ChannelTypeOpenAI,
+		Name:    "candidate",
+		Key:     "sk-shadow-channel-key",
+		Status:  common.ChannelStatusManuallyDisabled,
+		BaseURL: common.GetPointer(upstream.URL),
+		Models:  "gpt-4o-mini",
+		Group:   "default",
+	}
+	require.NoError(t, db.Create(shadowChannel).Error)
+	rule := &model.ShadowRule{Name: "candidate", Enabled: true, ModelName: "gpt-4o-mini", ShadowChannelId: shadowChannel.Id,
+		SampleRate: 100, CompareResponse: true, BudgetQuota: 1000000}
+	require.NoError(t, rule.Insert())
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(
+		`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"ping"}]}`))
+	ctx.Request.Header.Set("Authorization", "Bearer sk-user-token")
+	ctx.Set(common.RequestIdKey, "req-shadow-1")
+	info := &relaycommon.RelayInfo{
+		UserId:          3,
+		UsingGroup:      "default",
+		OriginModelName: "gpt-4o-mini",
+		StartTime:       time.Now().Add(-time.Second),
+		ChannelMeta:     &relaycommon.ChannelMeta{ChannelId: 20},
+	}
+
+	assert.Nil(t, newShadowMirror(ctx, &relaycommon.RelayInfo{OriginModelName: "gpt-4o"}, types.RelayFormatOpenAI), "other models are not mirrored")
+	assert.Nil(t, newShadowMirror(ctx, info, types.RelayFormatOpenAIImage), "unsupported formats are not mirrored")
+
+	mirror := newShadowMirror(ctx, info, types.RelayFormatOpenAI)
+	require.NotNil(t, mirror)
+	_, err := ctx.Writer.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"pong"}}]}`))
+	require.NoError(t, err)
+	common.SetContextKey(ctx, constant.ContextKeyRelayUsage, &dto.Usage{PromptTokens: 8, CompletionTokens: 2})
+
+	mirror.start(ctx, info)
+
+	select {
+	case auth := <-upstreamAuth:
+		assert.Equal(t, "Bearer sk-shadow-channel-key", auth, "client credentials are not forwarded")
+	case <-time.After(5 * time.Second):
+		t.Fatal("shadow request was not sent")
+	}
+	var metric model.ShadowMetric
+	require.Eventually(t, func() bool {
+		return db.Where("rule_id = ?", rule.Id).First(&metric).Error == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "req-shadow-1", metric.RequestId)
+	assert.Equal(t, 20, metric.PrimaryChannelId)
+	assert.GreaterOrEqual(t, metric.PrimaryLatencyMs, int64(1000))
+	assert.Equal(t, 8, metric.PrimaryPromptTokens)
+	assert.True(t, metric.ShadowSuccess, metric.ShadowError)
+	assert.Equal(t, http.StatusOK, metric.ShadowStatusCode)
+	assert.Equal(t, 9, metric.ShadowPromptTokens)
+	assert.Equal(t, 3, metric.ShadowCompletionTokens)
+	assert.InDelta(t, 1, metric.Similarity, 1e-9)
+	assert.Positive(t, metric.ShadowQuota, "shadow cost is estimated from the shadow channel usage")
+	assert.Equal(t, "pong", gjson.GetBytes(recorder.Body.Bytes(), "choices.0.message.content").String(), "the client response is untouched")
+
+	require.Eventually(t, func() bool {
+		stored, err := model.GetShadowRuleById(rule.Id)
+		return err == nil && stored.UsedQuota == metric.ShadowQuota
+	}, 5*time.Second, 20*time.Millisecond)
+
+	var logCount int64
+	require.NoError(t, db.Model(&model.Log{}).Count(&logCount).Error)
+	assert.Zero(t, logCount, "shadow requests are not billed to the user")
+
+	info.ChannelId = shadowChannel.Id
+	mirror = newShadowMirror(ctx, info, types.RelayFormatOpenAI)
+	require.NotNil(t, mirror)
+	mirror.start(ctx, info)
+	select {
+	case <-upstreamAuth:
+		t.Fatal("a request served by the shadow channel itself is not mirrored")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
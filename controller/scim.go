@@ -0,0 +1,816 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SCIM 2.0 开通接口（RFC 7643 / RFC 7644）。IdP 使用 scim.bearer_secret 调用
+// /scim/v2，创建、更新、停用本地用户；组成员关系按 scim.group_mapping /
+// scim.role_mapping 映射为用户分组与 authz 管理员角色。root 用户不受 SCIM 管理。
+// 每一次变更都写入操作审计日志，用户变更归属于被操作用户，组变更归属于系统（0）。
+
+const (
+	scimDefaultCount     = 100
+	scimMaxCount         = 200
+	scimIdentityMaxBytes = 128
+	scimEmailMaxLength   = 50
+	scimDisplayMaxRunes  = 20
+)
+
+var scimFilterPattern = regexp.MustCompile(`^\s*([A-Za-z][\w.:]*)\s+(?i:eq)\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+type scimRequestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimRequestError) Error() string {
+	return e.detail
+}
+
+func newScimRequestError(status int, scimType string, detail string) error {
+	return &scimRequestError{status: status, scimType: scimType, detail: detail}
+}
+
+func scimJSON(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", dto.ScimContentType)
+	c.JSON(status, body)
+}
+
+// scimAbort 把处理过程中的错误转换为 SCIM 错误响应。
+func scimAbort(c *gin.Context, err error) {
+	var reqErr *scimRequestError
+	switch {
+	case errors.As(err, &reqErr):
+		scimJSON(c, reqErr.status, dto.NewScimError(reqErr.status, reqErr.scimType, reqErr.detail))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		scimJSON(c, http.StatusNotFound, dto.NewScimError(http.StatusNotFound, "", "resource not found"))
+	case errors.Is(err, model.ErrExternalIdentityAlreadyClaimed),
+		errors.Is(err, model.ErrEmailAlreadyTaken),
+		errors.Is(err, model.ErrScimGroupNameTaken):
+		scimJSON(c, http.StatusConflict, dto.NewScimError(http.StatusConflict, dto.ScimErrorUniqueness, err.Error()))
+	default:
+		common.SysError("scim request failed: " + err.Error())
+		scimJSON(c, http.StatusInternalServerError, dto.NewScimError(http.StatusInternalServerError, "", "internal server error"))
+	}
+}
+
+// parseScimPagination 解析 1 起始的 startIndex 与 count（RFC 7644 §3.4.2.4）。
+func parseScimPagination(c *gin.Context) (startIndex int, count int) {
+	startIndex, err := strconv.Atoi(c.Query("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.Query("count"))
+	if err != nil {
+		count = scimDefaultCount
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+	return startIndex, count
+}
+
+// parseScimFilter 只支持 IdP 实际使用的 `attribute eq "value"` 形式，
+// 返回去掉 schema 前缀并转为小写的属性名。
+func parseScimFilter(filter string) (attr string, value string, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+	matches := scimFilterPattern.FindStringSubmatch(filter)
+	if matches == nil {
+		return "", "", newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidFilter, `only 'attribute eq "value"' filters are supported`)
+	}
+	if err := common.Unmarshal([]byte(matches[2]), &value); err != nil {
+		return "", "", newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidFilter, "invalid filter value")
+	}
+	return scimAttributeName(matches[1]), value, nil
+}
+
+// scimAttributeName 规范化属性路径：去掉 URN schema 前缀并转为小写。
+func scimAttributeName(path string) string {
+	path = strings.TrimSpace(path)
+	for _, schema := range []string{dto.ScimSchemaUser, dto.ScimSchemaGroup} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)], schema) && path[len(schema)] == ':' {
+			path = path[len(schema)+1:]
+			break
+		}
+	}
+	return strings.ToLower(path)
+}
+
+func newScimListResponse(total int64, startIndex int, resources []any) dto.ScimListResponse {
+	return dto.ScimListResponse{
+		Schemas:      []string{dto.ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func scimLocation(resourceType string, id int) string {
+	return fmt.Sprintf("%s/scim/v2/%s/%d", strings.TrimSuffix(system_setting.ServerAddress, "/"), resourceType, id)
+}
+
+func scimTimestamp(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+func scimResourceId(c *gin.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return id, nil
+}
+
+// recordScimAudit 记录一条 SCIM 变更审计日志；SCIM 请求没有管理员操作者，
+// admin_info 只标记鉴权方式。
+func recordScimAudit(c *gin.Context, logUserId int, action string, params map[string]interface{}) {
+	model.RecordOperationAuditLog(logUserId, auditContentEN(action, params), c.ClientIP(), action, params,
+		map[string]interface{}{"auth_method": "scim"}, nil)
+}
+
+// ScimServiceProviderConfig 描述本服务支持的 SCIM 能力（RFC 7643 §5）。
+func ScimServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the configured SCIM bearer secret",
+			"primary":     true,
+		}},
+	})
+}
+
+// scimUserState 是 SCIM 用户资源中由 IdP 管理的字段。
+type scimUserState struct {
+	UserName    string
+	ExternalId  string
+	DisplayName string
+	Email       string
+	Active      bool
+}
+
+func scimUserStateFromResource(resource *dto.ScimUser) scimUserState {
+	state := scimUserState{
+		UserName:    strings.TrimSpace(resource.UserName),
+		ExternalId:  strings.TrimSpace(resource.ExternalId),
+		DisplayName: strings.TrimSpace(resource.DisplayName),
+		Email:       scimPrimaryEmail(resource.Emails),
+		Active:      resource.Active == nil || *resource.Active,
+	}
+	if state.DisplayName == "" && resource.Name != nil {
+		state.DisplayName = scimFormattedName(resource.Name)
+	}
+	return state
+}
+
+func loadScimUserState(user *model.User) (scimUserState, error) {
+	externalId, userName, err := model.GetScimIdentity(user.Id)
+	if err != nil {
+		return scimUserState{}, err
+	}
+	if userName == "" {
+		userName = user.Username
+	}
+	return scimUserState{
+		UserName:    userName,
+		ExternalId:  externalId,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		Active:      user.Status == common.UserStatusEnabled,
+	}, nil
+}
+
+func (s scimUserState) validate() error {
+	switch {
+	case s.UserName == "":
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "userName is required")
+	case len(s.UserName) > scimIdentityMaxBytes:
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "userName is too long")
+	case len(s.ExternalId) > scimIdentityMaxBytes:
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "externalId is too long")
+	case len(s.Email) > scimEmailMaxLength:
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "email is too long")
+	}
+	return nil
+}
+
+// changedFields 返回除 active 之外发生变化的属性名，用于审计。
+func (s scimUserState) changedFields(after scimUserState) []string {
+	var fields []string
+	if s.UserName != after.UserName {
+		fields = append(fields, "userName")
+	}
+	if s.ExternalId != after.ExternalId {
+		fields = append(fields, "externalId")
+	}
+	if scimDisplayName(s) != scimDisplayName(after) {
+		fields = append(fields, "displayName")
+	}
+	if !strings.EqualFold(s.Email, after.Email) {
+		fields = append(fields, "emails")
+	}
+	return fields
+}
+
+func scimPrimaryEmail(emails []dto.ScimMultiValue) string {
+	for _, email := range emails {
+		if email.Primary {
+			return strings.TrimSpace(email.Value)
+		}
+	}
+	if len(emails) > 0 {
+		return strings.TrimSpace(emails[0].Value)
+	}
+	return ""
+}
+
+func scimFormattedName(name *dto.ScimName) string {
+	if formatted := strings.TrimSpace(name.Formatted); formatted != "" {
+		return formatted
+	}
+	return strings.TrimSpace(strings.TrimSpace(name.GivenName) + " " + strings.TrimSpace(name.FamilyName))
+}
+
+// scimDisplayName 返回写入本地的显示名，超出本地长度限制时按字符截断。
+func scimDisplayName(s scimUserState) string {
+	displayName := s.DisplayName
+	if displayName == "" {
+		displayName = s.UserName
+	}
+	if utf8.RuneCountInString(displayName) > scimDisplayMaxRunes {
+		displayName = string([]rune(displayName)[:scimDisplayMaxRunes])
+	}
+	return displayName
+}
+
+// scimLocalUsername 选择本地用户名：userName 可直接使用时沿用，否则按 OAuth
+// 注册的方式生成 scim_<id>。
+func scimLocalUsername(userName string) (string, error) {
+	if utf8.RuneCountInString(userName) <= model.UserNameMaxLength {
+		exists, err := model.CheckUserExistOrDeleted(userName, "")
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return userName, nil
+		}
+	}
+	return "scim_" + strconv.Itoa(model.GetMaxUserId()+1), nil
+}
+
+// ensureScimUserIdentityAvailable 检查 userName / externalId 未被其他用户占用。
+func ensureScimUserIdentityAvailable(userId int, state scimUserState) error {
+	ids, err := model.FindUserIdsByScimUserName(state.UserName)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if id != userId {
+			return newScimRequestError(http.StatusConflict, dto.ScimErrorUniqueness, "userName is already in use")
+		}
+	}
+	if state.ExternalId == "" {
+		return nil
+	}
+	ownerId, err := model.GetUserIdByExternalIdentity(model.ExternalIdentityProviderSCIM, state.ExternalId)
+	if err != nil {
+		return err
+	}
+	if ownerId != 0 && ownerId != userId {
+		return newScimRequestError(http.StatusConflict, dto.ScimErrorUniqueness, "externalId is already in use")
+	}
+	return nil
+}
+
+func buildScimUser(user *model.User, state scimUserState) (dto.ScimUser, error) {
+	groups, err := model.GetScimGroupsByUserId(user.Id)
+	if err != nil {
+		return dto.ScimUser{}, err
+	}
+	active := user.Status == common.UserStatusEnabled
+	resource := dto.ScimUser{
+		Schemas:     []string{dto.ScimSchemaUser},
+		Id:          strconv.Itoa(user.Id),
+		ExternalId:  state.ExternalId,
+		UserName:    state.UserName,
+		Name:        &dto.ScimName{Formatted: user.DisplayName},
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Meta: &dto.ScimMeta{
+			ResourceType: "User",
+			Created:      scimTimestamp(user.CreatedAt),
+			Location:     scimLocation("Users", user.Id),
+		},
+	}
+	if user.Email != "" {
+		resource.Emails = []dto.ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, dto.ScimMemberRef{
+			Value:   strconv.Itoa(group.Id),
+			Display: group.DisplayName,
+			Ref:     scimLocation("Groups", group.Id),
+		})
+	}
+	return resource, nil
+}
+
+func loadScimUser(c *gin.Context) (*model.User, scimUserState, error) {
+	id, err := scimResourceId(c)
+	if err != nil {
+		return nil, scimUserState{}, err
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		return nil, scimUserState{}, err
+	}
+	state, err := loadScimUserState(user)
+	return user, state, err
+}
+
+func respondScimUser(c *gin.Context, status int, user *model.User) {
+	state, err := loadScimUserState(user)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	resource, err := buildScimUser(user, state)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	scimJSON(c, status, resource)
+}
+
+func ScimListUsers(c *gin.Context) {
+	attr, value, err := parseScimFilter(c.Query("filter"))
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	var ids []int
+	switch attr {
+	case "":
+	case "username":
+		ids, err = model.FindUserIdsByScimUserName(value)
+	case "externalid":
+		var id int
+		id, err = model.GetUserIdByExternalIdentity(model.ExternalIdentityProviderSCIM, value)
+		ids = []int{id}
+	case "id":
+		id, _ := strconv.Atoi(value)
+		ids = []int{id}
+	default:
+		err = newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidFilter, "unsupported filter attribute")
+	}
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+
+	startIndex, count := parseScimPagination(c)
+	users, total, err := model.ListScimUsers(ids, startIndex-1, count)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	resources := make([]any, 0, len(users))
+	for _, user := range users {
+		state, err := loadScimUserState(user)
+		if err != nil {
+			scimAbort(c, err)
+			return
+		}
+		resource, err := buildScimUser(user, state)
+		if err != nil {
+			scimAbort(c, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	scimJSON(c, http.StatusOK, newScimListResponse(total, startIndex, resources))
+}
+
+func ScimGetUser(c *gin.Context) {
+	user, state, err := loadScimUser(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	resource, err := buildScimUser(user, state)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	scimJSON(c, http.StatusOK, resource)
+}
+
+func ScimCreateUser(c *gin.Context) {
+	var resource dto.ScimUser
+	if err := common.DecodeJson(c.Request.Body, &resource); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	state := scimUserStateFromResource(&resource)
+	if err := state.validate(); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := ensureScimUserIdentityAvailable(0, state); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	username, err := scimLocalUsername(state.UserName)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+
+	user := &model.User{
+		Username:    username,
+		DisplayName: scimDisplayName(state),
+		Email:       state.Email,
+		Role:        common.RoleCommonUser,
+		Status:      common.UserStatusEnabled,
+	}
+	if !state.Active {
+		user.Status = common.UserStatusDisabled
+	}
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		if err := user.InsertWithTx(tx, 0); err != nil {
+			return err
+		}
+		return model.SetScimIdentityWithTx(tx, user.Id, state.ExternalId, state.UserName)
+	}); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	user.FinishInsert(0)
+
+	recordScimAudit(c, user.Id, "scim.user_create", map[string]interface{}{
+		"username":   user.Username,
+		"userName":   state.UserName,
+		"externalId": state.ExternalId,
+		"active":     state.Active,
+	})
+	respondScimUser(c, http.StatusCreated, user)
+}
+
+func ScimReplaceUser(c *gin.Context) {
+	user, before, err := loadScimUser(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	var resource dto.ScimUser
+	if err := common.DecodeJson(c.Request.Body, &resource); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	if err := saveScimUser(c, user, before, scimUserStateFromResource(&resource)); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimUser(c, http.StatusOK, user)
+}
+
+func ScimPatchUser(c *gin.Context) {
+	user, before, err := loadScimUser(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	var request dto.ScimPatchRequest
+	if err := common.DecodeJson(c.Request.Body, &request); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	after := before
+	if err := applyScimUserPatch(&after, request.Operations); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := saveScimUser(c, user, before, after); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimUser(c, http.StatusOK, user)
+}
+
+func ScimDeleteUser(c *gin.Context) {
+	user, state, err := loadScimUser(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if user.Role == common.RoleRootUser {
+		scimAbort(c, errScimRootUser)
+		return
+	}
+	if err := user.Delete(); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		return model.ReleaseScimUserWithTx(tx, user.Id)
+	}); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := model.InvalidateUserTokensCache(user.Id); err != nil {
+		common.SysLog(fmt.Sprintf("failed to invalidate tokens cache for user %d: %s", user.Id, err.Error()))
+	}
+	recordScimAudit(c, user.Id, "scim.user_delete", map[string]interface{}{
+		"username": user.Username,
+		"userName": state.UserName,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+var errScimRootUser = newScimRequestError(http.StatusForbidden, dto.ScimErrorMutability, "the root user cannot be managed via SCIM")
+
+// saveScimUser 写入 PUT/PATCH 之后的用户状态。停用时吊销全部会话并清理令牌缓存。
+func saveScimUser(c *gin.Context, user *model.User, before scimUserState, after scimUserState) error {
+	if err := after.validate(); err != nil {
+		return err
+	}
+	fields := before.changedFields(after)
+	if len(fields) == 0 && before.Active == after.Active {
+		return nil
+	}
+	if user.Role == common.RoleRootUser {
+		return errScimRootUser
+	}
+	if err := ensureScimUserIdentityAvailable(user.Id, after); err != nil {
+		return err
+	}
+
+	previousAuthVersion := user.AuthVersion
+	user.DisplayName = scimDisplayName(after)
+	user.Email = after.Email
+	user.Status = common.UserStatusEnabled
+	if !after.Active {
+		user.Status = common.UserStatusDisabled
+	}
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		return model.ApplyScimUserWithTx(tx, user, after.ExternalId, after.UserName)
+	}); err != nil {
+		return err
+	}
+	if err := model.PublishUserAuthCache(user.Id); err != nil {
+		return err
+	}
+
+	deactivated := before.Active && !after.Active
+	if user.AuthVersion > previousAuthVersion {
+		reason := "user_security_changed"
+		if deactivated {
+			reason = "scim_deactivated"
+		}
+		if _, err := model.RevokeAllUserSessions(user.Id, reason); err != nil {
+			return err
+		}
+		if err := model.InvalidateUserTokensCache(user.Id); err != nil {
+			common.SysLog(fmt.Sprintf("failed to invalidate tokens cache for user %d: %s", user.Id, err.Error()))
+		}
+	}
+
+	if len(fields) > 0 {
+		recordScimAudit(c, user.Id, "scim.user_update", map[string]interface{}{
+			"username": user.Username,
+			"fields":   strings.Join(fields, ", "),
+		})
+	}
+	switch {
+	case deactivated:
+		recordScimAudit(c, user.Id, "scim.user_deactivate", map[string]interface{}{
+			"username": user.Username,
+		})
+	case !before.Active && after.Active:
+		recordScimAudit(c, user.Id, "scim.user_reactivate", map[string]interface{}{
+			"username": user.Username,
+		})
+	}
+	return nil
+}
+
+var scimEmailValuePath = regexp.MustCompile(`^emails\[type eq "[^"]*"\]\.value$`)
+
+// applyScimUserPatch 按 RFC 7644 §3.5.2 应用 PATCH 操作。未知属性（如 title、
+// addresses）直接忽略，避免 IdP 推送的扩展字段使整个开通失败。
+func applyScimUserPatch(state *scimUserState, operations []dto.ScimPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(strings.TrimSpace(operation.Op))
+		if op != "add" && op != "replace" && op != "remove" {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "unsupported patch op "+operation.Op)
+		}
+		if strings.TrimSpace(operation.Path) == "" {
+			if op == "remove" {
+				return newScimRequestError(http.StatusBadRequest, dto.ScimErrorNoTarget, "remove requires a path")
+			}
+			var values map[string]json.RawMessage
+			if err := common.Unmarshal(operation.Value, &values); err != nil {
+				return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "patch value must be an object when path is omitted")
+			}
+			for attr, value := range values {
+				if err := setScimUserAttribute(state, scimAttributeName(attr), value, false); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := setScimUserAttribute(state, scimAttributeName(operation.Path), operation.Value, op == "remove"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setScimUserAttribute(state *scimUserState, attr string, value json.RawMessage, remove bool) error {
+	switch {
+	case attr == "username":
+		if remove {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorMutability, "userName is required")
+		}
+		return decodeScimString(value, &state.UserName)
+	case attr == "externalid":
+		if remove {
+			state.ExternalId = ""
+			return nil
+		}
+		return decodeScimString(value, &state.ExternalId)
+	case attr == "displayname", attr == "name.formatted":
+		if remove {
+			state.DisplayName = ""
+			return nil
+		}
+		return decodeScimString(value, &state.DisplayName)
+	case attr == "name":
+		if remove {
+			state.DisplayName = ""
+			return nil
+		}
+		var name dto.ScimName
+		if err := common.Unmarshal(value, &name); err != nil {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "invalid name")
+		}
+		if formatted := scimFormattedName(&name); formatted != "" {
+			state.DisplayName = formatted
+		}
+		return nil
+	case attr == "active":
+		if remove {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorMutability, "active cannot be removed")
+		}
+		return decodeScimBool(value, &state.Active)
+	case attr == "emails":
+		if remove {
+			state.Email = ""
+			return nil
+		}
+		var emails []dto.ScimMultiValue
+		if err := common.Unmarshal(value, &emails); err != nil {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "invalid emails")
+		}
+		state.Email = scimPrimaryEmail(emails)
+		return nil
+	case scimEmailValuePath.MatchString(attr):
+		if remove {
+			state.Email = ""
+			return nil
+		}
+		return decodeScimString(value, &state.Email)
+	}
+	return nil
+}
+
+func decodeScimString(value json.RawMessage, target *string) error {
+	var s string
+	if err := common.Unmarshal(value, &s); err != nil {
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "expected a string value")
+	}
+	*target = strings.TrimSpace(s)
+	return nil
+}
+
+// decodeScimBool 兼容部分 IdP 以字符串 "True"/"False" 发送布尔值。
+func decodeScimBool(value json.RawMessage, target *bool) error {
+	if err := common.Unmarshal(value, target); err == nil {
+		return nil
+	}
+	var s string
+	if err := common.Unmarshal(value, &s); err == nil {
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			*target = parsed
+			return nil
+		}
+	}
+	return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "expected a boolean value")
+}
+
+// syncScimUserAccess 按用户当前所属的 SCIM 组重新计算分组与管理员角色：
+// 分组取第一个（按显示名排序）有映射或存在同名分组的 SCIM 组，都没有时回到 default；
+// 只有配置了 scim.role_mapping 时才管理角色，映射到 admin 的组成员为管理员。
+func syncScimUserAccess(c *gin.Context, userId int) error {
+	user, err := model.GetUserById(userId, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Role == common.RoleRootUser {
+		return nil
+	}
+	groups, err := model.GetScimGroupsByUserId(userId)
+	if err != nil {
+		return err
+	}
+	settings := system_setting.GetSCIMSettings()
+	targetGroup := resolveScimUserGroup(settings, groups)
+	targetRole := user.Role
+	if len(settings.RoleMapping) > 0 {
+		targetRole = resolveScimUserRole(settings, groups)
+	}
+	fromGroup, fromRole := user.Group, user.Role
+	changed, err := applyDirectoryUserAccess(user, targetGroup, targetRole, nil, "scim_access_changed")
+	if err != nil || !changed {
+		return err
+	}
+	recordScimAudit(c, user.Id, "scim.user_access_sync", map[string]interface{}{
+		"username":  user.Username,
+		"group":     targetGroup,
+		"role":      targetRole,
+		"fromGroup": fromGroup,
+		"fromRole":  fromRole,
+	})
+	return nil
+}
+
+func resolveScimUserGroup(settings *system_setting.SCIMSettings, groups []*model.ScimGroup) string {
+	for _, group := range groups {
+		if mapped := strings.TrimSpace(settings.GroupMapping[group.DisplayName]); mapped != "" {
+			return mapped
+		}
+		if ratio_setting.ContainsGroupRatio(group.DisplayName) {
+			return group.DisplayName
+		}
+	}
+	return "default"
+}
+
+func resolveScimUserRole(settings *system_setting.SCIMSettings, groups []*model.ScimGroup) int {
+	for _, group := range groups {
+		// root 不可经由 SCIM 授予，只识别 admin
+		if strings.TrimSpace(settings.RoleMapping[group.DisplayName]) == authz.BuiltInRoleAdmin {
+			return common.RoleAdminUser
+		}
+	}
+	return common.RoleCommonUser
+}
+
+// syncScimUsersAccess 对受组变更影响的用户逐一重新计算访问权限。
+func syncScimUsersAccess(c *gin.Context, userIds map[int]struct{}) error {
+	for userId := range userIds {
+		if err := syncScimUserAccess(c, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
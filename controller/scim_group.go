@@ -0,0 +1,466 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scimGroupState 是 SCIM 组资源中可修改的字段，Members 为用户 id 集合。
+type scimGroupState struct {
+	DisplayName string
+	ExternalId  string
+	Members     map[int]struct{}
+}
+
+func (s scimGroupState) validate() error {
+	switch {
+	case s.DisplayName == "":
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "displayName is required")
+	case len(s.DisplayName) > scimIdentityMaxBytes:
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "displayName is too long")
+	case len(s.ExternalId) > scimIdentityMaxBytes:
+		return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "externalId is too long")
+	}
+	return nil
+}
+
+func scimGroupStateFromResource(resource *dto.ScimGroup) (scimGroupState, error) {
+	state := scimGroupState{
+		DisplayName: strings.TrimSpace(resource.DisplayName),
+		ExternalId:  strings.TrimSpace(resource.ExternalId),
+		Members:     map[int]struct{}{},
+	}
+	ids, err := parseScimMemberIds(resource.Members)
+	for _, id := range ids {
+		state.Members[id] = struct{}{}
+	}
+	return state, err
+}
+
+func loadScimGroup(c *gin.Context) (*model.ScimGroup, scimGroupState, error) {
+	id, err := scimResourceId(c)
+	if err != nil {
+		return nil, scimGroupState{}, err
+	}
+	group, err := model.GetScimGroupById(id)
+	if err != nil {
+		return nil, scimGroupState{}, err
+	}
+	memberIds, err := model.GetScimGroupMemberIds(group.Id)
+	if err != nil {
+		return nil, scimGroupState{}, err
+	}
+	state := scimGroupState{
+		DisplayName: group.DisplayName,
+		ExternalId:  group.ExternalId,
+		Members:     make(map[int]struct{}, len(memberIds)),
+	}
+	for _, id := range memberIds {
+		state.Members[id] = struct{}{}
+	}
+	return group, state, nil
+}
+
+func parseScimMemberIds(members []dto.ScimMemberRef) ([]int, error) {
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.Atoi(strings.TrimSpace(member.Value))
+		if err != nil || id <= 0 {
+			return nil, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "invalid member value "+member.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func buildScimGroup(group *model.ScimGroup, includeMembers bool) (dto.ScimGroup, error) {
+	resource := dto.ScimGroup{
+		Schemas:     []string{dto.ScimSchemaGroup},
+		Id:          strconv.Itoa(group.Id),
+		ExternalId:  group.ExternalId,
+		DisplayName: group.DisplayName,
+		Meta: &dto.ScimMeta{
+			ResourceType: "Group",
+			Created:      scimTimestamp(group.CreatedAt),
+			LastModified: scimTimestamp(group.UpdatedAt),
+			Location:     scimLocation("Groups", group.Id),
+		},
+	}
+	if !includeMembers {
+		return resource, nil
+	}
+	memberIds, err := model.GetScimGroupMemberIds(group.Id)
+	if err != nil {
+		return dto.ScimGroup{}, err
+	}
+	for _, id := range memberIds {
+		resource.Members = append(resource.Members, dto.ScimMemberRef{
+			Value: strconv.Itoa(id),
+			Ref:   scimLocation("Users", id),
+		})
+	}
+	return resource, nil
+}
+
+// scimIncludeMembers 支持 excludedAttributes=members，IdP 查找组时借此避免拉取大组成员。
+func scimIncludeMembers(c *gin.Context) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if scimAttributeName(attr) == "members" {
+			return false
+		}
+	}
+	return true
+}
+
+func respondScimGroup(c *gin.Context, status int, group *model.ScimGroup) {
+	resource, err := buildScimGroup(group, scimIncludeMembers(c))
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	scimJSON(c, status, resource)
+}
+
+func ScimListGroups(c *gin.Context) {
+	attr, value, err := parseScimFilter(c.Query("filter"))
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	column := ""
+	switch attr {
+	case "":
+	case "id":
+		column = "id"
+	case "displayname":
+		column = "display_name"
+	case "externalid":
+		column = "external_id"
+	default:
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidFilter, "unsupported filter attribute"))
+		return
+	}
+
+	startIndex, count := parseScimPagination(c)
+	groups, total, err := model.ListScimGroups(column, value, startIndex-1, count)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	includeMembers := scimIncludeMembers(c)
+	resources := make([]any, 0, len(groups))
+	for _, group := range groups {
+		resource, err := buildScimGroup(group, includeMembers)
+		if err != nil {
+			scimAbort(c, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	scimJSON(c, http.StatusOK, newScimListResponse(total, startIndex, resources))
+}
+
+func ScimGetGroup(c *gin.Context) {
+	group, _, err := loadScimGroup(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimGroup(c, http.StatusOK, group)
+}
+
+func ScimCreateGroup(c *gin.Context) {
+	var resource dto.ScimGroup
+	if err := common.DecodeJson(c.Request.Body, &resource); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	state, err := scimGroupStateFromResource(&resource)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	group := &model.ScimGroup{}
+	if err := saveScimGroup(c, group, scimGroupState{Members: map[int]struct{}{}}, state); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimGroup(c, http.StatusCreated, group)
+}
+
+func ScimReplaceGroup(c *gin.Context) {
+	group, before, err := loadScimGroup(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	var resource dto.ScimGroup
+	if err := common.DecodeJson(c.Request.Body, &resource); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	after, err := scimGroupStateFromResource(&resource)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := saveScimGroup(c, group, before, after); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimGroup(c, http.StatusOK, group)
+}
+
+func ScimPatchGroup(c *gin.Context) {
+	group, before, err := loadScimGroup(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	var request dto.ScimPatchRequest
+	if err := common.DecodeJson(c.Request.Body, &request); err != nil {
+		scimAbort(c, newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "invalid request body"))
+		return
+	}
+	after := scimGroupState{
+		DisplayName: before.DisplayName,
+		ExternalId:  before.ExternalId,
+		Members:     make(map[int]struct{}, len(before.Members)),
+	}
+	for id := range before.Members {
+		after.Members[id] = struct{}{}
+	}
+	if err := applyScimGroupPatch(&after, request.Operations); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := saveScimGroup(c, group, before, after); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	respondScimGroup(c, http.StatusOK, group)
+}
+
+func ScimDeleteGroup(c *gin.Context) {
+	group, state, err := loadScimGroup(c)
+	if err != nil {
+		scimAbort(c, err)
+		return
+	}
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		return model.DeleteScimGroupWithTx(tx, group.Id)
+	}); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	recordScimAudit(c, 0, "scim.group_delete", map[string]interface{}{
+		"displayName": group.DisplayName,
+		"id":          group.Id,
+		"members":     len(state.Members),
+	})
+	if err := syncScimUsersAccess(c, state.Members); err != nil {
+		scimAbort(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// saveScimGroup 创建（group.Id 为 0）或更新组并同步成员差异，随后为受影响的用户
+// 重新计算分组与角色。组改名会改变映射结果，因此改名时全部成员都需重新计算。
+func saveScimGroup(c *gin.Context, group *model.ScimGroup, before scimGroupState, after scimGroupState) error {
+	if err := after.validate(); err != nil {
+		return err
+	}
+	added, removed := diffScimMembers(before.Members, after.Members)
+	if len(added) > 0 {
+		existing, err := model.GetExistingUserIds(added)
+		if err != nil {
+			return err
+		}
+		if len(existing) != len(added) {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "one or more members do not exist")
+		}
+	}
+
+	creating := group.Id == 0
+	renamed := before.DisplayName != after.DisplayName
+	attributesChanged := renamed || before.ExternalId != after.ExternalId
+	if !creating && !attributesChanged && len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	group.DisplayName = after.DisplayName
+	group.ExternalId = after.ExternalId
+	if err := model.DB.Transaction(func(tx *gorm.DB) error {
+		switch {
+		case creating:
+			if err := group.InsertWithTx(tx); err != nil {
+				return err
+			}
+		case attributesChanged:
+			if err := group.UpdateWithTx(tx); err != nil {
+				return err
+			}
+		}
+		if err := model.AddScimGroupMembersWithTx(tx, group.Id, added); err != nil {
+			return err
+		}
+		return model.RemoveScimGroupMembersWithTx(tx, group.Id, removed)
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case creating:
+		recordScimAudit(c, 0, "scim.group_create", map[string]interface{}{
+			"displayName": group.DisplayName,
+			"externalId":  group.ExternalId,
+			"id":          group.Id,
+		})
+	case attributesChanged:
+		recordScimAudit(c, 0, "scim.group_update", map[string]interface{}{
+			"displayName": group.DisplayName,
+			"fromName":    before.DisplayName,
+			"externalId":  group.ExternalId,
+			"id":          group.Id,
+		})
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		recordScimAudit(c, 0, "scim.group_members", map[string]interface{}{
+			"displayName": group.DisplayName,
+			"id":          group.Id,
+			"added":       added,
+			"removed":     removed,
+		})
+	}
+
+	affected := make(map[int]struct{}, len(added)+len(removed))
+	for _, id := range append(added, removed...) {
+		affected[id] = struct{}{}
+	}
+	if renamed {
+		for id := range after.Members {
+			affected[id] = struct{}{}
+		}
+	}
+	return syncScimUsersAccess(c, affected)
+}
+
+func diffScimMembers(before map[int]struct{}, after map[int]struct{}) (added []int, removed []int) {
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Ints(added)
+	sort.Ints(removed)
+	return added, removed
+}
+
+var scimMemberFilterPath = regexp.MustCompile(`^members\[value eq "([^"]*)"\]$`)
+
+// applyScimGroupPatch 按 RFC 7644 §3.5.2 应用组 PATCH 操作，支持成员的增删替换
+// 以及 displayName / externalId 的修改。
+func applyScimGroupPatch(state *scimGroupState, operations []dto.ScimPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(strings.TrimSpace(operation.Op))
+		if op != "add" && op != "replace" && op != "remove" {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidSyntax, "unsupported patch op "+operation.Op)
+		}
+		path := scimAttributeName(operation.Path)
+		if path == "" {
+			if op == "remove" {
+				return newScimRequestError(http.StatusBadRequest, dto.ScimErrorNoTarget, "remove requires a path")
+			}
+			var values map[string]json.RawMessage
+			if err := common.Unmarshal(operation.Value, &values); err != nil {
+				return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "patch value must be an object when path is omitted")
+			}
+			for attr, value := range values {
+				if err := setScimGroupAttribute(state, op, scimAttributeName(attr), value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := setScimGroupAttribute(state, op, path, operation.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setScimGroupAttribute(state *scimGroupState, op string, attr string, value json.RawMessage) error {
+	if matches := scimMemberFilterPath.FindStringSubmatch(attr); matches != nil {
+		if op != "remove" {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidPath, "member filters are only supported for remove")
+		}
+		ids, err := parseScimMemberIds([]dto.ScimMemberRef{{Value: matches[1]}})
+		if err != nil {
+			return err
+		}
+		delete(state.Members, ids[0])
+		return nil
+	}
+
+	switch attr {
+	case "displayname":
+		if op == "remove" {
+			return newScimRequestError(http.StatusBadRequest, dto.ScimErrorMutability, "displayName is required")
+		}
+		return decodeScimString(value, &state.DisplayName)
+	case "externalid":
+		if op == "remove" {
+			state.ExternalId = ""
+			return nil
+		}
+		return decodeScimString(value, &state.ExternalId)
+	case "members":
+		var members []dto.ScimMemberRef
+		if len(value) > 0 && string(value) != "null" {
+			if err := common.Unmarshal(value, &members); err != nil {
+				return newScimRequestError(http.StatusBadRequest, dto.ScimErrorInvalidValue, "invalid members")
+			}
+		}
+		ids, err := parseScimMemberIds(members)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case "replace":
+			state.Members = make(map[int]struct{}, len(ids))
+			for _, id := range ids {
+				state.Members[id] = struct{}{}
+			}
+		case "add":
+			for _, id := range ids {
+				state.Members[id] = struct{}{}
+			}
+		case "remove":
+			// 不带 value 的 remove 清空全部成员
+			if len(ids) == 0 {
+				state.Members = map[int]struct{}{}
+			}
+			for _, id := range ids {
+				delete(state.Members, id)
+			}
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scimTestSecret = "scim-test-secret"
+
+func setupScimTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	db := setupManageUserTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.ExternalIdentityClaim{}, &model.ScimGroup{}, &model.ScimGroupMember{}))
+
+	settings := system_setting.GetSCIMSettings()
+	previous := *settings
+	settings.Enabled = true
+	settings.BearerSecret = scimTestSecret
+	settings.GroupMapping = map[string]string{}
+	settings.RoleMapping = map[string]string{}
+	t.Cleanup(func() { *settings = previous })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	scimRouter := router.Group("/scim/v2", middleware.ScimAuth())
+	scimRouter.GET("/Users", ScimListUsers)
+	scimRouter.POST("/Users", ScimCreateUser)
+	scimRouter.GET("/Users/:id", ScimGetUser)
+	scimRouter.PATCH("/Users/:id", ScimPatchUser)
+	scimRouter.DELETE("/Users/:id", ScimDeleteUser)
+	scimRouter.POST("/Groups", ScimCreateGroup)
+	scimRouter.PATCH("/Groups/:id", ScimPatchGroup)
+	return router
+}
+
+func performScimRequest(t *testing.T, router *gin.Engine, method string, target string, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", dto.ScimContentType)
+	request.Header.Set("Authorization", "Bearer "+scimTestSecret)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if out != nil {
+		require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), out), recorder.Body.String())
+	}
+	return recorder
+}
+
+func scimAuditActions(t *testing.T, userId int) []string {
+	t.Helper()
+	var logs []model.Log
+	require.NoError(t, model.LOG_DB.Where("user_id = ? AND type = ?", userId, model.LogTypeManage).Order("id").Find(&logs).Error)
+	actions := make([]string, 0, len(logs))
+	for _, log := range logs {
+		var other struct {
+			Op struct {
+				Action string `json:"action"`
+			} `json:"op"`
+			AdminInfo map[string]any `json:"admin_info"`
+		}
+		require.NoError(t, common.UnmarshalJsonStr(log.Other, &other))
+		assert.Equal(t, "scim", other.AdminInfo["auth_method"])
+		actions = append(actions, other.Op.Action)
+	}
+	return actions
+}
+
+func TestScimAuthRequiresEnabledSettingAndBearerSecret(t *testing.T) {
+	router := setupScimTest(t)
+
+	request := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+	request.Header.Set("Authorization", "Bearer wrong-secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), dto.ScimContentType)
+	assert.Contains(t, recorder.Body.String(), dto.ScimSchemaError)
+
+	assert.Equal(t, http.StatusOK, performScimRequest(t, router, http.MethodGet, "/scim/v2/Users", "", nil).Code)
+
+	system_setting.GetSCIMSettings().Enabled = false
+	assert.Equal(t, http.StatusNotFound, performScimRequest(t, router, http.MethodGet, "/scim/v2/Users", "", nil).Code)
+}
+
+func TestScimUserProvisioningFilteringAndDeactivation(t *testing.T) {
+	router := setupScimTest(t)
+
+	var created dto.ScimUser
+	recorder := performScimRequest(t, router, http.MethodPost, "/scim/v2/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "jane.doe@corp.example.com",
+		"externalId": "00u-jane",
+		"name": {"givenName": "Jane", "familyName": "Doe"},
+		"emails": [{"value": "jane.doe@corp.example.com", "type": "work", "primary": true}],
+		"active": true
+	}`, &created)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "jane.doe@corp.example.com", created.UserName)
+	assert.Equal(t, "00u-jane", created.ExternalId)
+	assert.Equal(t, "Jane Doe", created.DisplayName)
+	require.NotNil(t, created.Active)
+	assert.True(t, *created.Active)
+
+	userId, err := strconv.Atoi(created.Id)
+	require.NoError(t, err)
+	var user model.User
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.True(t, strings.HasPrefix(user.Username, "scim_"), "userName longer than the local limit gets a generated username")
+	assert.Equal(t, common.RoleCommonUser, user.Role)
+
+	recorder = performScimRequest(t, router, http.MethodPost, "/scim/v2/Users", `{"userName":"jane.doe@corp.example.com"}`, nil)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), dto.ScimErrorUniqueness)
+
+	var list dto.ScimListResponse
+	recorder = performScimRequest(t, router, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22jane.doe%40corp.example.com%22`, "", &list)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, 1, list.TotalResults)
+	recorder = performScimRequest(t, router, http.MethodGet, `/scim/v2/Users?filter=externalId+eq+%22missing%22`, "", &list)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, 0, list.TotalResults)
+	recorder = performScimRequest(t, router, http.MethodGet, `/scim/v2/Users?filter=userName+sw+%22jane%22`, "", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), dto.ScimErrorInvalidFilter)
+
+	now := time.Now().Unix()
+	require.NoError(t, model.DB.Create(&model.UserSession{
+		SID: "scim-deactivate-session", UserID: userId, Version: 1, UserAuthVersion: user.AuthVersion,
+		Status: model.UserSessionStatusActive, RefreshHash: "refresh-hash", LoginMethod: "oidc",
+		LastActiveAt: now, ExpiresAt: now + 3600,
+	}).Error)
+
+	// Entra ID 风格：省略 path，布尔值以字符串发送
+	var patched dto.ScimUser
+	recorder = performScimRequest(t, router, http.MethodPatch, "/scim/v2/Users/"+created.Id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "value": {"active": "False"}},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "jane@example.com"}
+		]
+	}`, &patched)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.NotNil(t, patched.Active)
+	assert.False(t, *patched.Active)
+
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, common.UserStatusDisabled, user.Status)
+	assert.Equal(t, "jane@example.com", user.Email)
+	var session model.UserSession
+	require.NoError(t, model.DB.First(&session, "sid = ?", "scim-deactivate-session").Error)
+	assert.Equal(t, model.UserSessionStatusRevoked, session.Status)
+	assert.Equal(t, "scim_deactivated", session.RevokedReason)
+
+	assert.Equal(t, []string{"scim.user_create", "scim.user_update", "scim.user_deactivate"}, scimAuditActions(t, userId))
+
+	recorder = performScimRequest(t, router, http.MethodDelete, "/scim/v2/Users/"+created.Id, "", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, http.StatusNotFound, performScimRequest(t, router, http.MethodGet, "/scim/v2/Users/"+created.Id, "", nil).Code)
+	// 删除后释放 SCIM 身份，IdP 可以重新开通同一账号
+	recorder = performScimRequest(t, router, http.MethodPost, "/scim/v2/Users", `{"userName":"jane.doe@corp.example.com","externalId":"00u-jane"}`, nil)
+	assert.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+}
+
+func TestScimUserCannotModifyRootUser(t *testing.T) {
+	router := setupScimTest(t)
+	root := model.User{
+		Username: "scim-root", Password: "password", Role: common.RoleRootUser,
+		Status: common.UserStatusEnabled, Group: "default", AuthVersion: 1,
+	}
+	require.NoError(t, model.DB.Create(&root).Error)
+
+	recorder := performScimRequest(t, router, http.MethodPatch, fmt.Sprintf("/scim/v2/Users/%d", root.Id),
+		`{"Operations":[{"op":"replace","path":"active","value":false}]}`, nil)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), dto.ScimErrorMutability)
+	require.NoError(t, model.DB.First(&root, root.Id).Error)
+	assert.Equal(t, common.UserStatusEnabled, root.Status)
+}
+
+func TestScimGroupMembershipMapsUserGroupAndAdminRole(t *testing.T) {
+	router := setupScimTest(t)
+	previousMaster := common.IsMasterNode
+	common.IsMasterNode = false
+	t.Cleanup(func() { common.IsMasterNode = previousMaster })
+	require.NoError(t, authz.Init(model.DB))
+
+	settings := system_setting.GetSCIMSettings()
+	settings.GroupMapping = map[string]string{"Engineering": "vip"}
+	settings.RoleMapping = map[string]string{"Platform Admins": authz.BuiltInRoleAdmin}
+
+	var created dto.ScimUser
+	recorder := performScimRequest(t, router, http.MethodPost, "/scim/v2/Users", `{"userName":"bob","externalId":"00u-bob"}`, &created)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	userId, err := strconv.Atoi(created.Id)
+	require.NoError(t, err)
+
+	var engineering dto.ScimGroup
+	recorder = performScimRequest(t, router, http.MethodPost, "/scim/v2/Groups",
+		fmt.Sprintf(`{"displayName":"Engineering","members":[{"value":"%d"}]}`, userId), &engineering)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	require.Len(t, engineering.Members, 1)
+
+	var user model.User
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, "vip", user.Group)
+	assert.Equal(t, common.RoleCommonUser, user.Role)
+
+	var admins dto.ScimGroup
+	recorder = performScimRequest(t, router, http.MethodPost, "/scim/v2/Groups", `{"displayName":"Platform Admins"}`, &admins)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	recorder = performScimRequest(t, router, http.MethodPatch, "/scim/v2/Groups/"+admins.Id, fmt.Sprintf(`{
+		"Operations": [{"op": "add", "path": "members", "value": [{"value": "%d"}]}]
+	}`, userId), nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, common.RoleAdminUser, user.Role)
+
+	var fetched dto.ScimUser
+	performScimRequest(t, router, http.MethodGet, "/scim/v2/Users/"+created.Id, "", &fetched)
+	assert.Len(t, fetched.Groups, 2)
+
+	recorder = performScimRequest(t, router, http.MethodPatch, "/scim/v2/Groups/"+admins.Id, fmt.Sprintf(`{
+		"Operations": [{"op": "remove", "path": "members[value eq \"%d\"]"}]
+	}`, userId), nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	recorder = performScimRequest(t, router, http.MethodPatch, "/scim/v2/Groups/"+engineering.Id,
+		`{"Operations": [{"op": "remove", "path": "members"}]}`, nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.NoError(t, model.DB.First(&user, userId).Error)
+	assert.Equal(t, common.RoleCommonUser, user.Role)
+	assert.Equal(t, "default", user.Group)
+
+	recorder = performScimRequest(t, router, http.MethodPatch, "/scim/v2/Groups/"+admins.Id,
+		`{"Operations": [{"op": "add", "path": "members", "value": [{"value": "999999"}]}]}`, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	assert.Equal(t, []string{"scim.user_create", "scim.user_access_sync", "scim.user_access_sync", "scim.user_access_sync", "scim.user_access_sync"},
+		scimAuditActions(t, userId))
+	assert.Equal(t, []string{"scim.group_create", "scim.group_members", "scim.group_create", "scim.group_members", "scim.group_members", "scim.group_members"},
+		scimAuditActions(t, 0))
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ShadowRuleRequest 创建或修改影子流量规则的请求
+type ShadowRuleRequest struct {
+	Name            string  `json:"name"`
+	Enabled         bool    `json:"enabled"`
+	ModelName       string  `json:"model_name"`
+	Group           string  `json:"group"`
+	ShadowChannelId int     `json:"shadow_channel_id"`
+	SampleRate      float64 `json:"sample_rate"`
+	CompareResponse bool    `json:"compare_response"`
+	BudgetQuota     int     `json:"budget_quota"`
+}
+
+func (req *ShadowRuleRequest) apply(rule *model.ShadowRule) {
+	rule.Name = req.Name
+	rule.Enabled = req.Enabled
+	rule.ModelName = req.ModelName
+	rule.Group = req.Group
+	rule.ShadowChannelId = req.ShadowChannelId
+	rule.SampleRate = req.SampleRate
+	rule.CompareResponse = req.CompareResponse
+	rule.BudgetQuota = req.BudgetQuota
+}
+
+// validateShadowRule 校验规则内容与影子渠道。影子渠道可以是已禁用的渠道，
+// 便于在不接入正常路由的情况下评估候选渠道。
+func validateShadowRule(c *gin.Context, rule *model.ShadowRule) bool {
+	if err := rule.Normalize(); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return false
+	}
+	if _, err := model.GetChannelById(rule.ShadowChannelId, false); err != nil {
+		common.ApiErrorMsg(c, "影子渠道不存在")
+		return false
+	}
+	return true
+}
+
+func getShadowRuleParam(c *gin.Context) (*model.ShadowRule, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return nil, false
+	}
+	rule, err := model.GetShadowRuleById(id)
+	if err != nil {
+		common.ApiErrorMsg(c, "影子流量规则不存在")
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetShadowRules 返回全部影子流量规则
+func GetShadowRules(c *gin.Context) {
+	rules, err := model.GetShadowRules()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, rules)
+}
+
+// CreateShadowRule 创建影子流量规则
+func CreateShadowRule(c *gin.Context) {
+	var req ShadowRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	rule := &model.ShadowRule{}
+	req.apply(rule)
+	if !validateShadowRule(c, rule) {
+		return
+	}
+	if err := rule.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateShadowRuleCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    rule,
+	})
+}
+
+// UpdateShadowRule 修改影子流量规则，已用额度保持不变
+func UpdateShadowRule(c *gin.Context) {
+	rule, ok := getShadowRuleParam(c)
+	if !ok {
+		return
+	}
+	var req ShadowRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	req.apply(rule)
+	if !validateShadowRule(c, rule) {
+		return
+	}
+	if err := rule.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateShadowRuleCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    rule,
+	})
+}
+
+// DeleteShadowRule 删除影子流量规则及其指标
+func DeleteShadowRule(c *gin.Context) {
+	rule, ok := getShadowRuleParam(c)
+	if !ok {
+		return
+	}
+	if err := model.DeleteShadowRule(rule.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateShadowRuleCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// ResetShadowRuleUsage 清零规则已用额度，预算耗尽的规则恢复采样
+func ResetShadowRuleUsage(c *gin.Context) {
+	rule, ok := getShadowRuleParam(c)
+	if !ok {
+		return
+	}
+	if err := model.ResetShadowRuleUsage(rule.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateShadowRuleCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已重置",
+	})
+}
+
+// GetShadowRuleMetrics 分页返回规则的影子请求记录
+func GetShadowRuleMetrics(c *gin.Context) {
+	rule, ok := getShadowRuleParam(c)
+	if !ok {
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	metrics, total, err := model.GetShadowMetrics(rule.Id, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(metrics)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetShadowRuleSummary 汇总规则的错误率、延迟、用量与相似度，start_timestamp 可选
+func GetShadowRuleSummary(c *gin.Context) {
+	rule, ok := getShadowRuleParam(c)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	summary, err := model.SummarizeShadowMetrics(rule.Id, startTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"rule":    rule,
+		"summary": summary,
+	})
+}
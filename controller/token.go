@@ -31,14 +31,33 @@ func (input *tokenAutoGroupsInput) UnmarshalJSON(data []byte) error {
 	return common.Unmarshal(data, &input.Groups)
 }
 
+// tokenLabelsInput 与 tokenAutoGroupsInput 相同，用 Set 区分未提交与清空
+type tokenLabelsInput struct {
+	Set    bool
+	Labels map[string]string
+}
+
+func (input *tokenLabelsInput) UnmarshalJSON(data []byte) error {
+	input.Set = true
+	if strings.TrimSpace(string(data)) == "null" {
+		input.Labels = nil
+		return nil
+	}
+	return common.Unmarshal(data, &input.Labels)
+}
+
 type tokenRequest struct {
 	model.Token
 	AutoGroups tokenAutoGroupsInput `json:"auto_groups"`
+	Labels     tokenLabelsInput     `json:"labels"`
 }
 
 type tokenResponse struct {
 	*model.Token
-	AutoGroups []string `json:"auto_groups"`
+	AutoGroups []string          `json:"auto_groups"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// 新生成的请求签名密钥，仅在生成时返回一次
+	SigningSecret string `json:"signing_secret,omitempty"`
 }
 
 func buildMaskedTokenResponse(token *model.Token) *tokenResponse {
@@ -55,7 +74,12 @@ func buildMaskedTokenResponse(token *model.Token) *tokenResponse {
 	if len(autoGroups) == 0 {
 		autoGroups = nil
 	}
-	return &tokenResponse{Token: &maskedToken, AutoGroups: autoGroups}
+	labels, err := token.GetLabels()
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to parse labels for token %d: %v", token.Id, err))
+		labels = nil
+	}
+	return &tokenResponse{Token: &maskedToken, AutoGroups: autoGroups, Labels: labels}
 }
 
 func buildMaskedTokenResponses(tokens []*model.Token) []*tokenResponse {
@@ -103,7 +127,7 @@ func setTokenAutoGroups(c *gin.Context, token *model.Token, groups []string) boo
 			return false
 		}
 		seen[group] = struct{}{}
-		if !service.IsUserSelectableGroup(userGroup, group) {
+		if !service.IsUserSelectableGroup(userGroup, group, service.GetContextUserUsableGroups(c)...) {
 			common.ApiErrorI18n(c, i18n.MsgTokenAutoGroupsInvalid, map[string]any{"Group": group})
 			return false
 		}
@@ -169,7 +193,7 @@ func GetTokenAutoGroups(c *gin.Context) {
 		return
 	}
 	common.ApiSuccess(c, gin.H{
-		"groups":    service.GetUserAutoGroup(userGroup),
+		"groups":    service.GetUserAutoGroup(userGroup, service.GetContextUserUsableGroups(c)...),
 		"max_count": setting.GetMaxTokenAutoGroups(),
 	})
 }
@@ -186,8 +210,13 @@ func GetTokenKey(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	key := token.GetFullKey()
+	if key == "" {
+		common.ApiErrorI18n(c, i18n.MsgTokenKeyNotRevealable)
+		return
+	}
 	common.ApiSuccess(c, gin.H{
-		"key": token.GetFullKey(),
+		"key": key,
 	})
 }
 
@@ -261,6 +290,77 @@ func GetTokenUsage(c *gin.Context) {
 	})
 }
 
+// validateTokenEndUserLimits 校验终端用户识别请求头与限制配置，返回 false 时已写入错误响应
+func validateTokenEndUserLimits(c *gin.Context, token *model.Token) bool {
+	token.EndUserHeader = strings.TrimSpace(token.EndUserHeader)
+	if len(token.EndUserHeader) > 64 || !isValidHeaderName(token.EndUserHeader) {
+		common.ApiErrorMsg(c, "终端用户请求头名称无效")
+		return false
+	}
+	if token.EndUserRateLimit < 0 || token.EndUserDailyQuota < 0 {
+		common.ApiErrorMsg(c, "终端用户限制不能为负数")
+		return false
+	}
+	return true
+}
+
+// validateTokenClientCert 规范化令牌绑定的客户端证书标识，同一证书只能绑定一个令牌。
+// 新绑定的证书须证明持有：普通用户只能绑定当前 TLS 连接已校验的证书，管理员可直接绑定；
+// boundIdentity 为令牌已绑定的标识，保持不变时无需重新证明。
+func validateTokenClientCert(c *gin.Context, token *model.Token, tokenId int, boundIdentity string) bool {
+	identity, err := common.NormalizeClientCertIdentity(token.ClientCert)
+	if err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return false
+	}
+	token.ClientCert = identity
+	if identity == "" {
+		if token.ClientCertRequired {
+			common.ApiErrorMsg(c, "要求客户端证书时必须填写证书标识")
+			return false
+		}
+		return true
+	}
+	if identity != boundIdentity && c.GetInt("role") < common.RoleAdminUser &&
+		!common.StringsContains(common.ClientCertIdentities(common.VerifiedClientCertificate(c.Request.TLS)), identity) {
+		common.ApiErrorMsg(c, "只能绑定当前连接所使用的客户端证书，其它证书请联系管理员绑定")
+		return false
+	}
+	bound, err := model.IsClientCertBound(identity, tokenId)
+	if err != nil {
+		common.ApiError(c, err)
+		return false
+	}
+	if bound {
+		common.ApiErrorMsg(c, "该客户端证书已绑定到其它令牌")
+		return false
+	}
+	return true
+}
+
+// setTokenLabels 校验并保存令牌的成本分摊标签，返回 false 时已写入错误响应
+func setTokenLabels(c *gin.Context, token *model.Token, labels map[string]string) bool {
+	if err := model.ValidateLabels(labels, model.MaxTokenLabels); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return false
+	}
+	if err := token.SetLabels(labels); err != nil {
+		common.ApiError(c, err)
+		return false
+	}
+	return true
+}
+
+// isValidHeaderName 空字符串表示不使用请求头识别终端用户
+func isValidHeaderName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 func AddToken(c *gin.Context) {
 	request := tokenRequest{}
 	err := c.ShouldBindJSON(&request)
@@ -285,6 +385,10 @@ func AddToken(c *gin.Context) {
 			return
 		}
 	}
+	if !validateTokenEndUserLimits(c, &token) || !validateTokenClientCert(c, &token, 0, "") ||
+		!setTokenLabels(c, &token, request.Labels.Labels) {
+		return
+	}
 	// 检查用户令牌数量是否已达上限
 	maxTokens := operation_setting.GetMaxUserTokens()
 	count, err := model.CountUserTokens(c.GetInt("id"))
@@ -316,7 +420,6 @@ func AddToken(c *gin.Context) {
 	cleanToken := model.Token{
 		UserId:             c.GetInt("id"),
 		Name:               token.Name,
-		Key:                key,
 		CreatedTime:        common.GetTimestamp(),
 		AccessedTime:       common.GetTimestamp(),
 		ExpiredTime:        token.ExpiredTime,
@@ -328,15 +431,31 @@ func AddToken(c *gin.Context) {
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
 		AutoGroups:         token.AutoGroups,
+		EndUserHeader:      token.EndUserHeader,
+		EndUserRateLimit:   token.EndUserRateLimit,
+		EndUserDailyQuota:  token.EndUserDailyQuota,
+		ClientCert:         token.ClientCert,
+		ClientCertRequired: token.ClientCertRequired,
+		SigningRequired:    token.SigningRequired,
+		Labels:             token.Labels,
+	}
+	cleanToken.SetKey(key)
+	if cleanToken.SigningRequired && !ensureTokenSigningSecret(c, &cleanToken) {
+		return
 	}
 	err = cleanToken.Insert()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	// 库中只保存摘要，完整密钥仅在此处返回一次
+	created := buildMaskedTokenResponse(&cleanToken)
+	created.Key = key
+	created.SigningSecret = cleanToken.SigningSecret
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
+		"data":    created,
 	})
 }
 
@@ -379,20 +498,19 @@ func UpdateToken(c *gin.Context) {
 			return
 		}
 	}
+	if statusOnly == "" && !validateTokenEndUserLimits(c, &token) {
+		return
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
-	if token.Status == common.TokenStatusEnabled {
-		if cleanToken.Status == common.TokenStatusExpired && cleanToken.ExpiredTime <= common.GetTimestamp() && cleanToken.ExpiredTime != -1 {
-			common.ApiErrorI18n(c, i18n.MsgTokenExpiredCannotEnable)
-			return
-		}
-		if cleanToken.Status == common.TokenStatusExhausted && cleanToken.RemainQuota <= 0 && !cleanToken.UnlimitedQuota {
-			common.ApiErrorI18n(c, i18n.MsgTokenExhaustedCannotEable)
-			return
-		}
+	if statusOnly == "" && !validateTokenClientCert(c, &token, token.Id, cleanToken.ClientCert) {
+		return
+	}
+	if token.Status == common.TokenStatusEnabled && !validateTokenCanEnable(c, cleanToken) {
+		return
 	}
 	if statusOnly != "" {
 		cleanToken.Status = token.Status
@@ -407,6 +525,15 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.EndUserHeader = token.EndUserHeader
+		cleanToken.EndUserRateLimit = token.EndUserRateLimit
+		cleanToken.EndUserDailyQuota = token.EndUserDailyQuota
+		cleanToken.ClientCert = token.ClientCert
+		cleanToken.ClientCertRequired = token.ClientCertRequired
+		cleanToken.SigningRequired = token.SigningRequired
+		if request.Labels.Set && !setTokenLabels(c, cleanToken, request.Labels.Labels) {
+			return
+		}
 		if token.Group != "auto" {
 			cleanToken.CrossGroupRetry = false
 			_ = cleanToken.SetAutoGroups(nil)
@@ -416,15 +543,81 @@ func UpdateToken(c *gin.Context) {
 			}
 		}
 	}
+	// 首次开启签名时生成密钥；关闭签名保留原密钥，重新开启后客户端无需更换
+	generatedSigningSecret := ""
+	if cleanToken.SigningRequired && cleanToken.SigningSecret == "" {
+		if !ensureTokenSigningSecret(c, cleanToken) {
+			return
+		}
+		generatedSigningSecret = cleanToken.SigningSecret
+	}
 	err = cleanToken.Update()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	if cleanToken.Status == common.TokenStatusDisabled {
+		// 停用父令牌时一并吊销其签发的子令牌
+		if err := model.RevokeChildTokens(cleanToken.Id); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	updated := buildMaskedTokenResponse(cleanToken)
+	updated.SigningSecret = generatedSigningSecret
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data":    buildMaskedTokenResponse(cleanToken),
+		"data":    updated,
+	})
+}
+
+// validateTokenCanEnable 拒绝重新启用已过期或额度耗尽的令牌，失败时已写入错误响应
+func validateTokenCanEnable(c *gin.Context, token *model.Token) bool {
+	if token.Status == common.TokenStatusExpired && token.ExpiredTime <= common.GetTimestamp() && token.ExpiredTime != -1 {
+		common.ApiErrorI18n(c, i18n.MsgTokenExpiredCannotEnable)
+		return false
+	}
+	if token.Status == common.TokenStatusExhausted && token.RemainQuota <= 0 && !token.UnlimitedQuota {
+		common.ApiErrorI18n(c, i18n.MsgTokenExhaustedCannotEable)
+		return false
+	}
+	return true
+}
+
+// ensureTokenSigningSecret 为令牌生成新的请求签名密钥，失败时已写入错误响应
+func ensureTokenSigningSecret(c *gin.Context, token *model.Token) bool {
+	secret, err := common.GenerateKey()
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
+		common.SysLog("failed to generate token signing secret: " + err.Error())
+		return false
+	}
+	token.SigningSecret = secret
+	return true
+}
+
+// RotateTokenSigningSecret 重新生成令牌的请求签名密钥，旧密钥立即失效
+func RotateTokenSigningSecret(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token, err := model.GetTokenByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !ensureTokenSigningSecret(c, token) {
+		return
+	}
+	if err := token.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"signing_secret": token.SigningSecret,
 	})
 }
 
@@ -461,6 +654,10 @@ func GetTokenKeysBatch(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgBatchTooMany, map[string]any{"Max": 100})
 		return
 	}
+	if !constant.TokenKeyRevealEnabled {
+		common.ApiErrorI18n(c, i18n.MsgTokenKeyNotRevealable)
+		return
+	}
 	userId := c.GetInt("id")
 	tokens, err := model.GetTokenKeysByIds(tokenBatch.Ids, userId)
 	if err != nil {
@@ -469,7 +666,94 @@ func GetTokenKeysBatch(c *gin.Context) {
 	}
 	keysMap := make(map[int]string)
 	for _, t := range tokens {
-		keysMap[t.Id] = t.GetFullKey()
+		if key := t.GetFullKey(); key != "" {
+			keysMap[t.Id] = key
+		}
 	}
 	common.ApiSuccess(c, gin.H{"keys": keysMap})
 }
+
+// getManagedTokenOwner 解析路由中的用户 ID，并确认操作者有权管理该用户；失败时已写入错误响应
+func getManagedTokenOwner(c *gin.Context) (int, bool) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return 0, false
+	}
+	owner, err := model.GetUserById(userId, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return 0, false
+	}
+	if !canManageTargetRole(c.GetInt("role"), owner.Role) {
+		common.ApiErrorI18n(c, i18n.MsgUserNoPermissionSameLevel)
+		return 0, false
+	}
+	return userId, true
+}
+
+// AdminGetUserTokens 管理员查看指定用户的令牌，密钥始终脱敏
+func AdminGetUserTokens(c *gin.Context) {
+	userId, ok := getManagedTokenOwner(c)
+	if !ok {
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	tokens, err := model.GetAllUserTokens(userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	total, _ := model.CountUserTokens(userId)
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(buildMaskedTokenResponses(tokens))
+	common.ApiSuccess(c, pageInfo)
+}
+
+type adminTokenStatusRequest struct {
+	Status int `json:"status"`
+}
+
+// AdminUpdateUserTokenStatus 管理员启用或停用指定用户的令牌，例如处置泄露的密钥
+func AdminUpdateUserTokenStatus(c *gin.Context) {
+	userId, ok := getManagedTokenOwner(c)
+	if !ok {
+		return
+	}
+	tokenId, err := strconv.Atoi(c.Param("token_id"))
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	var req adminTokenStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Status != common.TokenStatusEnabled && req.Status != common.TokenStatusDisabled) {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	token, err := model.GetTokenByIds(tokenId, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Status == common.TokenStatusEnabled && !validateTokenCanEnable(c, token) {
+		return
+	}
+	token.Status = req.Status
+	if err := token.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	action := "token.admin_enable"
+	if token.Status == common.TokenStatusDisabled {
+		action = "token.admin_disable"
+		if err := model.RevokeChildTokens(token.Id); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	recordManageAuditFor(c, userId, action, map[string]interface{}{
+		"name": token.Name,
+		"id":   token.Id,
+	})
+	common.ApiSuccess(c, buildMaskedTokenResponse(token))
+}
@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultChildTokenTTL     = 3600
+	maxChildTokenTTL         = 86400
+	maxChildTokenNameLength  = 50
+	maxChildTokenEndUserSize = 128
+)
+
+// ChildTokenRequest 子令牌签发请求。未指定的分组、模型限制与 IP 白名单继承父令牌
+type ChildTokenRequest struct {
+	Name        string   `json:"name"`
+	EndUserId   string   `json:"end_user_id"`
+	ExpiresIn   int64    `json:"expires_in"`
+	Quota       int      `json:"quota"`
+	Group       string   `json:"group"`
+	ModelLimits []string `json:"model_limits"`
+	AllowIps    []string `json:"allow_ips"`
+}
+
+// CreateChildToken 由父令牌（TokenAuth 鉴权）签发一个短期子令牌。子令牌的分组、
+// 模型限制与 IP 白名单必须是父令牌的子集，额度从父令牌剩余额度中划出。
+func CreateChildToken(c *gin.Context) {
+	var req ChildTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	parent, err := model.GetTokenById(c.GetInt("token_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if parent.ParentTokenId != 0 || parent.WorkloadIssuerId != 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "子令牌或工作负载令牌不能签发子令牌",
+		})
+		return
+	}
+	// 隔离只作用于中转请求，疑似泄露的令牌若能签发子令牌，就能借子令牌拿回完整的模型权限
+	if parent.Quarantined || parent.LeakFlaggedAt != 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "令牌疑似泄露，确认安全或更换密钥前不能签发子令牌",
+		})
+		return
+	}
+
+	child, err := buildChildToken(parent, &req)
+	if err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	maxTokens := operation_setting.GetMaxUserTokens()
+	count, err := model.CountUserTokens(parent.UserId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if int(count) >= maxTokens {
+		common.ApiErrorMsg(c, fmt.Sprintf("已达到最大令牌数量限制 (%d)", maxTokens))
+		return
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
+		common.SysLog("failed to generate child token key: " + err.Error())
+		return
+	}
+	child.SetKey(key)
+	if child.SigningRequired && !ensureTokenSigningSecret(c, child) {
+		return
+	}
+	if err := model.CreateChildToken(parent, child); err != nil {
+		if errors.Is(err, model.ErrParentTokenQuotaInsufficient) {
+			common.ApiErrorMsg(c, "父令牌剩余额度不足")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	// 完整密钥与签名密钥仅在此处返回一次
+	created := buildMaskedTokenResponse(child)
+	created.Key = key
+	created.SigningSecret = child.SigningSecret
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    created,
+	})
+}
+
+func buildChildToken(parent *model.Token, req *ChildTokenRequest) (*model.Token, error) {
+	endUserId := strings.TrimSpace(req.EndUserId)
+	if endUserId == "" {
+		return nil, errors.New("end_user_id 不能为空")
+	}
+	if len(endUserId) > maxChildTokenEndUserSize {
+		return nil, fmt.Errorf("end_user_id 长度不能超过 %d", maxChildTokenEndUserSize)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = truncateTokenName(endUserId)
+	}
+	if len(name) > maxChildTokenNameLength {
+		return nil, errors.New("令牌名称过长")
+	}
+
+	ttl := req.ExpiresIn
+	if ttl == 0 {
+		ttl = defaultChildTokenTTL
+	}
+	if ttl < 0 || ttl > maxChildTokenTTL {
+		return nil, fmt.Errorf("expires_in 必须在 1 到 %d 秒之间", maxChildTokenTTL)
+	}
+	now := common.GetTimestamp()
+	expiredTime := now + ttl
+	if parent.ExpiredTime != -1 && parent.ExpiredTime < expiredTime {
+		expiredTime = parent.ExpiredTime
+	}
+
+	maxQuotaValue := common.QuotaFromFloat(1000000000 * common.QuotaPerUnit)
+	if req.Quota <= 0 {
+		return nil, errors.New("quota 必须大于 0")
+	}
+	if req.Quota > maxQuotaValue {
+		return nil, fmt.Errorf("quota 不能超过 %d", maxQuotaValue)
+	}
+
+	group := strings.TrimSpace(req.Group)
+	if group != "" && group != parent.Group {
+		return nil, fmt.Errorf("子令牌不能使用父令牌之外的分组 %s", group)
+	}
+
+	modelLimits, err := childTokenModelLimits(parent, req.ModelLimits)
+	if err != nil {
+		return nil, err
+	}
+	allowIps, err := childTokenAllowIps(parent, req.AllowIps)
+	if err != nil {
+		return nil, err
+	}
+
+	child := &model.Token{
+		UserId:             parent.UserId,
+		Name:               name,
+		Status:             common.TokenStatusEnabled,
+		CreatedTime:        now,
+		AccessedTime:       now,
+		ExpiredTime:        expiredTime,
+		RemainQuota:        req.Quota,
+		ModelLimitsEnabled: len(modelLimits) > 0,
+		ModelLimits:        strings.Join(modelLimits, ","),
+		AllowIps:           &allowIps,
+		Group:              parent.Group,
+		CrossGroupRetry:    parent.CrossGroupRetry,
+		AutoGroups:         parent.AutoGroups,
+		EndUserId:          endUserId,
+		EndUserRateLimit:   parent.EndUserRateLimit,
+		EndUserDailyQuota:  parent.EndUserDailyQuota,
+		Labels:             parent.Labels,
+		SigningRequired:    parent.SigningRequired,
+	}
+	// 父令牌要求客户端证书时子令牌沿用同一要求，避免借子令牌退回仅凭密钥访问
+	if parent.ClientCertRequired {
+		child.ClientCert = parent.ClientCert
+		child.ClientCertRequired = true
+	}
+	return child, nil
+}
+
+// childTokenModelLimits 未指定时继承父令牌的模型限制；父令牌限制了模型时只能从中选取
+func childTokenModelLimits(parent *model.Token, requested []string) ([]string, error) {
+	limits := make([]string, 0, len(requested))
+	for _, modelName := range requested {
+		if modelName = strings.TrimSpace(modelName); modelName != "" {
+			limits = append(limits, modelName)
+		}
+	}
+	if !parent.ModelLimitsEnabled {
+		return limits, nil
+	}
+	if len(limits) == 0 {
+		return parent.GetModelLimits(), nil
+	}
+	allowed := parent.GetModelLimitsMap()
+	for _, modelName := range limits {
+		if !allowed[modelName] {
+			return nil, fmt.Errorf("父令牌不允许使用模型 %s", modelName)
+		}
+	}
+	return limits, nil
+}
+
+// childTokenAllowIps 未指定时继承父令牌的 IP 白名单；父令牌限制了 IP 时每一项都必须落在其范围内
+func childTokenAllowIps(parent *model.Token, requested []string) (string, error) {
+	ips := make([]string, 0, len(requested))
+	for _, ip := range requested {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return "", fmt.Errorf("无效的 IP 或网段 %s", ip)
+		}
+		ips = append(ips, ip)
+	}
+	parentIps := parent.GetIpLimits()
+	if len(parentIps) > 0 {
+		if len(ips) == 0 {
+			return strings.Join(parentIps, "\n"), nil
+		}
+		for _, ip := range ips {
+			if !common.IsCIDRWithinList(ip, parentIps) {
+				return "", fmt.Errorf("IP %s 不在父令牌允许的范围内", ip)
+			}
+		}
+	}
+	return strings.Join(ips, "\n"), nil
+}
+
+func truncateTokenName(name string) string {
+	for len(name) > maxChildTokenNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
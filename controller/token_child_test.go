@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func seedParentToken(t *testing.T, db *gorm.DB) *model.Token {
+	t.Helper()
+	allowIps := "10.0.0.0/8"
+	parent := &model.Token{
+		UserId:             1,
+		Name:               "backend",
+		Status:             common.TokenStatusEnabled,
+		ExpiredTime:        -1,
+		RemainQuota:        10000,
+		ModelLimitsEnabled: true,
+		ModelLimits:        "gpt-4o-mini,gpt-4o",
+		AllowIps:           &allowIps,
+		Group:              "default",
+	}
+	parent.SetKey("parentkey1234567")
+	require.NoError(t, db.Create(parent).Error)
+	return parent
+}
+
+func mintChildToken(t *testing.T, parent *model.Token, body map[string]any) tokenAPIResponse {
+	t.Helper()
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/child", body, parent.UserId)
+	ctx.Set("token_id", parent.Id)
+	CreateChildToken(ctx)
+	return decodeAPIResponse(t, recorder)
+}
+
+func TestCreateChildTokenDrawsQuotaFromParentWithinItsLimits(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	parent := seedParentToken(t, db)
+
+	response := mintChildToken(t, parent, map[string]any{
+		"end_user_id":  "app-user-42",
+		"expires_in":   600,
+		"quota":        3000,
+		"model_limits": []string{"gpt-4o-mini"},
+		"allow_ips":    []string{"10.1.0.0/16"},
+	})
+	require.True(t, response.Success, response.Message)
+	var created tokenResponseItem
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+	assert.Len(t, created.Key, 48, "the full child key is returned once")
+
+	var child model.Token
+	require.NoError(t, db.First(&child, created.ID).Error)
+	assert.Equal(t, parent.Id, child.ParentTokenId)
+	assert.Equal(t, "app-user-42", child.EndUserId)
+	assert.Equal(t, "app-user-42", child.Name)
+	assert.Equal(t, 3000, child.RemainQuota)
+	assert.False(t, child.UnlimitedQuota)
+	assert.Equal(t, []string{"gpt-4o-mini"}, child.GetModelLimits())
+	assert.Equal(t, []string{"10.1.0.0/16"}, child.GetIpLimits())
+	assert.Equal(t, "default", child.Group)
+	assert.InDelta(t, time.Now().Unix()+600, child.ExpiredTime, 2)
+
+	require.NoError(t, db.First(parent, parent.Id).Error)
+	assert.Equal(t, 7000, parent.RemainQuota)
+	assert.Equal(t, 3000, parent.UsedQuota)
+
+	inherited := mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-43", "quota": 1000})
+	require.True(t, inherited.Success, inherited.Message)
+	require.NoError(t, common.Unmarshal(inherited.Data, &created))
+	child = model.Token{}
+	require.NoError(t, db.First(&child, created.ID).Error)
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-4o"}, child.GetModelLimits(), "unspecified limits are inherited")
+	assert.Equal(t, []string{"10.0.0.0/8"}, child.GetIpLimits())
+
+	for name, body := range map[string]map[string]any{
+		"missing end user":   {"quota": 100},
+		"unlimited quota":    {"end_user_id": "u", "quota": 0},
+		"quota over parent":  {"end_user_id": "u", "quota": 6001},
+		"model outside":      {"end_user_id": "u", "quota": 100, "model_limits": []string{"o3"}},
+		"ip outside":         {"end_user_id": "u", "quota": 100, "allow_ips": []string{"192.168.0.1"}},
+		"wider network":      {"end_user_id": "u", "quota": 100, "allow_ips": []string{"10.0.0.0/7"}},
+		"other group":        {"end_user_id": "u", "quota": 100, "group": "vip"},
+		"ttl over maximum":   {"end_user_id": "u", "quota": 100, "expires_in": maxChildTokenTTL + 1},
+		"invalid ip address": {"end_user_id": "u", "quota": 100, "allow_ips": []string{"10.1.1"}},
+	} {
+		assert.False(t, mintChildToken(t, parent, body).Success, name)
+	}
+	require.NoError(t, db.First(parent, parent.Id).Error)
+	assert.Equal(t, 6000, parent.RemainQuota, "rejected requests do not draw quota")
+
+	grandchild := mintChildToken(t, &child, map[string]any{"end_user_id": "u", "quota": 10})
+	assert.False(t, grandchild.Success, "child tokens cannot mint children")
+}
+
+func TestLeakFlaggedTokenCannotMintChildren(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	parent := seedParentToken(t, db)
+	response := mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-42", "quota": 1000})
+	require.True(t, response.Success, response.Message)
+	var created tokenResponseItem
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+
+	_, err := model.FlagTokenLeak(parent, "new_country: DE", true, time.Now().Unix())
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Model(&model.Token{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Zero(t, count, "quarantine revokes existing children")
+	response = mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-43", "quota": 1000})
+	assert.False(t, response.Success, "a quarantined token cannot mint children")
+
+	require.NoError(t, model.ClearTokenLeak(parent, time.Now().Unix()))
+	require.NoError(t, db.Model(parent).Update("leak_flagged_at", time.Now().Unix()).Error)
+	response = mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-43", "quota": 1000})
+	assert.False(t, response.Success, "a flagged token cannot mint children even without quarantine")
+
+	require.NoError(t, model.ClearTokenLeak(parent, time.Now().Unix()))
+	response = mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-43", "quota": 1000})
+	assert.True(t, response.Success, response.Message)
+}
+
+func TestChildTokenInheritsClientCertRequirement(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	parent := seedParentToken(t, db)
+	identity := "san:spiffe://mesh/billing-worker"
+	require.NoError(t, db.Model(parent).Updates(map[string]any{"client_cert": identity, "client_cert_required": true}).Error)
+
+	response := mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-42", "quota": 1000})
+	require.True(t, response.Success, response.Message)
+	var created tokenResponseItem
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+	var child model.Token
+	require.NoError(t, db.First(&child, created.ID).Error)
+	assert.Equal(t, identity, child.ClientCert)
+	assert.True(t, child.ClientCertRequired)
+
+	bound, err := model.IsClientCertBound(identity, parent.Id)
+	require.NoError(t, err)
+	assert.False(t, bound, "inherited bindings do not block the parent's own binding")
+}
+
+func TestChildTokenInheritsSigningRequirement(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	parent := seedParentToken(t, db)
+	require.NoError(t, db.Model(parent).Updates(map[string]any{"signing_required": true, "signing_secret": "parent-secret"}).Error)
+
+	response := mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-42", "quota": 1000})
+	require.True(t, response.Success, response.Message)
+	var created struct {
+		ID            int    `json:"id"`
+		SigningSecret string `json:"signing_secret"`
+	}
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+	var child model.Token
+	require.NoError(t, db.First(&child, created.ID).Error)
+	assert.True(t, child.SigningRequired)
+	assert.NotEmpty(t, created.SigningSecret, "the child's own signing secret is returned once")
+	assert.Equal(t, child.SigningSecret, created.SigningSecret)
+	assert.NotEqual(t, "parent-secret", child.SigningSecret)
+}
+
+func TestChildTokensAreReleasedOnExpiryAndParentDeletion(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	parent := seedParentToken(t, db)
+
+	response := mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-42", "quota": 3000})
+	require.True(t, response.Success, response.Message)
+	var created tokenResponseItem
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+
+	// 子令牌消耗 2000 后过期，清理时剩余的 1000 退回父令牌
+	require.NoError(t, db.Model(&model.Token{}).Where("id = ?", created.ID).Updates(map[string]any{
+		"remain_quota": 1000,
+		"used_quota":   2000,
+		"expired_time": time.Now().Add(-time.Minute).Unix(),
+	}).Error)
+	require.NoError(t, model.DeleteExpiredChildTokens(time.Now().Unix()))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&model.Token{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, db.First(parent, parent.Id).Error)
+	assert.Equal(t, 8000, parent.RemainQuota)
+	assert.Equal(t, 2000, parent.UsedQuota)
+
+	response = mintChildToken(t, parent, map[string]any{"end_user_id": "app-user-43", "quota": 500})
+	require.True(t, response.Success, response.Message)
+	require.NoError(t, common.Unmarshal(response.Data, &created))
+	require.NoError(t, model.DeleteTokenById(parent.Id, parent.UserId))
+	require.NoError(t, db.Unscoped().Model(&model.Token{}).Where("id = ?", created.ID).Count(&count).Error)
+	assert.Zero(t, count, "children are revoked with their parent")
+}
+
+func TestConsumeLogsCarryChildTokenEndUser(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Log{}))
+
+	ctx, _ := newAuthenticatedContext(t, http.MethodPost, "/v1/chat/completions", nil, 1)
+	common.SetContextKey(ctx, constant.ContextKeyEndUserId, "app-user-42")
+	model.RecordErrorLog(ctx, 1, 0, "gpt-4o-mini", "child", "upstream error", 7, 0, false, "default", nil)
+
+	var log model.Log
+	require.NoError(t, db.Where("token_id = ?", 7).First(&log).Error)
+	assert.Equal(t, "app-user-42", log.EndUserId)
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmTokenLeak 所有者确认疑似泄露的令牌为本人使用，解除标记与隔离
+func ConfirmTokenLeak(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token, err := model.GetTokenByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.ClearTokenLeak(token, common.GetTimestamp()); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.ResetTokenLeakProfile(token.Id)
+	common.ApiSuccess(c, buildMaskedTokenResponse(token))
+}
+
+// RotateTokenKey 为令牌生成新密钥并保留全部设置，旧密钥立即失效，新密钥仅在此处返回一次
+func RotateTokenKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token, err := model.GetTokenByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgTokenGenerateFailed)
+		common.SysLog("failed to generate token key: " + err.Error())
+		return
+	}
+	if err := model.RotateTokenKey(token, key); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.ResetTokenLeakProfile(token.Id)
+	rotated := buildMaskedTokenResponse(token)
+	rotated.Key = key
+	common.ApiSuccess(c, rotated)
+}
@@ -2,17 +2,21 @@ package controller
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
@@ -166,7 +170,6 @@ func seedToken(t *testing.T, db *gorm.DB, userID int, name string, rawKey string
 	token := &model.Token{
 		UserId:         userID,
 		Name:           name,
-		Key:            rawKey,
 		Status:         common.TokenStatusEnabled,
 		CreatedTime:    1,
 		AccessedTime:   1,
@@ -175,6 +178,7 @@ func seedToken(t *testing.T, db *gorm.DB, userID int, name string, rawKey string
 		UnlimitedQuota: true,
 		Group:          "default",
 	}
+	token.SetKey(rawKey)
 	if err := db.Create(token).Error; err != nil {
 		t.Fatalf("failed to create token: %v", err)
 	}
@@ -452,7 +456,7 @@ func TestGetAllTokensMasksKeyInResponse(t *testing.T) {
 	if page.Items[0].Key != token.GetMaskedKey() {
 		t.Fatalf("expected masked key %q, got %q", token.GetMaskedKey(), page.Items[0].Key)
 	}
-	if strings.Contains(recorder.Body.String(), token.Key) {
+	if strings.Contains(recorder.Body.String(), "abcd1234efgh5678") {
 		t.Fatalf("list response leaked raw token key: %s", recorder.Body.String())
 	}
 }
@@ -479,7 +483,7 @@ func TestSearchTokensMasksKeyInResponse(t *testing.T) {
 	if page.Items[0].Key != token.GetMaskedKey() {
 		t.Fatalf("expected masked search key %q, got %q", token.GetMaskedKey(), page.Items[0].Key)
 	}
-	if strings.Contains(recorder.Body.String(), token.Key) {
+	if strings.Contains(recorder.Body.String(), "ijkl1234mnop5678") {
 		t.Fatalf("search response leaked raw token key: %s", recorder.Body.String())
 	}
 }
@@ -504,7 +508,7 @@ func TestGetTokenMasksKeyInResponse(t *testing.T) {
 	if detail.Key != token.GetMaskedKey() {
 		t.Fatalf("expected masked detail key %q, got %q", token.GetMaskedKey(), detail.Key)
 	}
-	if strings.Contains(recorder.Body.String(), token.Key) {
+	if strings.Contains(recorder.Body.String(), "qrst1234uvwx5678") {
 		t.Fatalf("detail response leaked raw token key: %s", recorder.Body.String())
 	}
 }
@@ -540,14 +544,76 @@ func TestUpdateTokenMasksKeyInResponse(t *testing.T) {
 	if detail.Key != token.GetMaskedKey() {
 		t.Fatalf("expected masked update key %q, got %q", token.GetMaskedKey(), detail.Key)
 	}
-	if strings.Contains(recorder.Body.String(), token.Key) {
+	if strings.Contains(recorder.Body.String(), "yzab1234cdef5678") {
 		t.Fatalf("update response leaked raw token key: %s", recorder.Body.String())
 	}
 }
 
-func TestGetTokenKeyRequiresOwnershipAndReturnsFullKey(t *testing.T) {
+func TestAddTokenRevealsFullKeyOnlyOnce(t *testing.T) {
 	db := setupTokenControllerTestDB(t)
-	token := seedToken(t, db, 1, "owned-token", "owner1234token5678")
+
+	body := map[string]any{
+		"name":            "created-token",
+		"expired_time":    -1,
+		"unlimited_quota": true,
+		"group":           "default",
+	}
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+	AddToken(ctx)
+
+	response := decodeAPIResponse(t, recorder)
+	if !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	var created tokenResponseItem
+	if err := common.Unmarshal(response.Data, &created); err != nil {
+		t.Fatalf("failed to decode created token: %v", err)
+	}
+	if len(created.Key) != 48 {
+		t.Fatalf("expected the created response to carry the full 48-char key, got %q", created.Key)
+	}
+
+	var stored model.Token
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("failed to load created token: %v", err)
+	}
+	if stored.Key != model.HashTokenKey(created.Key) {
+		t.Fatalf("expected stored key to be the hash of the revealed key, got %q", stored.Key)
+	}
+	if stored.KeyPrefix != created.Key[:8] {
+		t.Fatalf("expected stored key prefix %q, got %q", created.Key[:8], stored.KeyPrefix)
+	}
+	if stored.PlainKey != "" {
+		t.Fatal("expected plaintext key not to be stored without TOKEN_KEY_REVEAL_ENABLED")
+	}
+
+	fetched, err := model.ValidateUserToken(created.Key)
+	if err != nil {
+		t.Fatalf("expected created key to authenticate: %v", err)
+	}
+	if fetched.Id != created.ID {
+		t.Fatalf("expected token %d, got %d", created.ID, fetched.Id)
+	}
+
+	keyCtx, keyRecorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/"+strconv.Itoa(created.ID)+"/key", nil, 1)
+	keyCtx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(created.ID)}}
+	GetTokenKey(keyCtx)
+	if decodeAPIResponse(t, keyRecorder).Success {
+		t.Fatal("expected key re-display to be refused")
+	}
+	if strings.Contains(keyRecorder.Body.String(), created.Key) {
+		t.Fatalf("key response leaked raw token key: %s", keyRecorder.Body.String())
+	}
+}
+
+func TestGetTokenKeyInRevealModeRequiresOwnershipAndReturnsFullKey(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+	originalReveal := constant.TokenKeyRevealEnabled
+	constant.TokenKeyRevealEnabled = true
+	t.Cleanup(func() { constant.TokenKeyRevealEnabled = originalReveal })
+
+	rawKey := "owner1234token5678"
+	token := seedToken(t, db, 1, "owned-token", rawKey)
 
 	authorizedCtx, authorizedRecorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/"+strconv.Itoa(token.Id)+"/key", nil, 1)
 	authorizedCtx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(token.Id)}}
@@ -562,8 +628,8 @@ func TestGetTokenKeyRequiresOwnershipAndReturnsFullKey(t *testing.T) {
 	if err := common.Unmarshal(authorizedResponse.Data, &keyData); err != nil {
 		t.Fatalf("failed to decode token key response: %v", err)
 	}
-	if keyData.Key != token.GetFullKey() {
-		t.Fatalf("expected full key %q, got %q", token.GetFullKey(), keyData.Key)
+	if keyData.Key != rawKey {
+		t.Fatalf("expected full key %q, got %q", rawKey, keyData.Key)
 	}
 
 	unauthorizedCtx, unauthorizedRecorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/"+strconv.Itoa(token.Id)+"/key", nil, 2)
@@ -574,7 +640,228 @@ func TestGetTokenKeyRequiresOwnershipAndReturnsFullKey(t *testing.T) {
 	if unauthorizedResponse.Success {
 		t.Fatalf("expected unauthorized key fetch to fail")
 	}
-	if strings.Contains(unauthorizedRecorder.Body.String(), token.Key) {
+	if strings.Contains(unauthorizedRecorder.Body.String(), rawKey) {
 		t.Fatalf("unauthorized key response leaked raw token key: %s", unauthorizedRecorder.Body.String())
 	}
 }
+
+func TestAddTokenValidatesClientCertBinding(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+
+	addToken := func(name string, clientCert string, required bool) tokenAPIResponse {
+		body := map[string]any{
+			"name":                 name,
+			"expired_time":         -1,
+			"unlimited_quota":      true,
+			"group":                "default",
+			"client_cert":          clientCert,
+			"client_cert_required": required,
+		}
+		ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+		ctx.Set("role", common.RoleAdminUser)
+		AddToken(ctx)
+		return decodeAPIResponse(t, recorder)
+	}
+
+	response := addToken("pinned", " SHA256:"+strings.Repeat("AB:", 31)+"AB", true)
+	if !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	var stored model.Token
+	if err := db.Where("name = ?", "pinned").First(&stored).Error; err != nil {
+		t.Fatalf("failed to load created token: %v", err)
+	}
+	if stored.ClientCert != "sha256:"+strings.Repeat("ab", 32) || !stored.ClientCertRequired {
+		t.Fatalf("expected a normalized required fingerprint binding, got %q required=%v", stored.ClientCert, stored.ClientCertRequired)
+	}
+
+	if addToken("duplicate", "sha256:"+strings.Repeat("ab", 32), false).Success {
+		t.Fatal("expected a certificate identity bound to another token to be rejected")
+	}
+	if addToken("unbound-required", "", true).Success {
+		t.Fatal("expected client_cert_required without client_cert to be rejected")
+	}
+	if addToken("bad-format", "CN=billing-worker", false).Success {
+		t.Fatal("expected an identity without a known prefix to be rejected")
+	}
+}
+
+func TestClientCertBindingRequiresPresentedCertificate(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+
+	workloadURI, err := url.Parse("spiffe://mesh/billing-worker")
+	if err != nil {
+		t.Fatalf("failed to parse workload uri: %v", err)
+	}
+	presented := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+		Raw:  []byte("billing-worker-cert"),
+		URIs: []*url.URL{workloadURI},
+	}}}}
+	addToken := func(name string, clientCert string, state *tls.ConnectionState) tokenAPIResponse {
+		body := map[string]any{
+			"name":            name,
+			"expired_time":    -1,
+			"unlimited_quota": true,
+			"group":           "default",
+			"client_cert":     clientCert,
+		}
+		ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+		ctx.Set("role", common.RoleCommonUser)
+		ctx.Request.TLS = state
+		AddToken(ctx)
+		return decodeAPIResponse(t, recorder)
+	}
+
+	if addToken("claimed", "san:spiffe://mesh/billing-worker", nil).Success {
+		t.Fatal("expected binding a certificate the caller did not present to be rejected")
+	}
+	if addToken("other-cert", "san:spiffe://mesh/other-worker", presented).Success {
+		t.Fatal("expected binding an identity outside the presented certificate to be rejected")
+	}
+	response := addToken("owned", "san:spiffe://mesh/billing-worker", presented)
+	if !response.Success {
+		t.Fatalf("expected binding the presented certificate to succeed, got message: %s", response.Message)
+	}
+
+	var stored model.Token
+	if err := db.Where("name = ?", "owned").First(&stored).Error; err != nil {
+		t.Fatalf("failed to load created token: %v", err)
+	}
+	// Keeping an existing binding does not require presenting the certificate again
+	body := map[string]any{
+		"id":              stored.Id,
+		"name":            "owned-renamed",
+		"expired_time":    -1,
+		"unlimited_quota": true,
+		"group":           "default",
+		"client_cert":     stored.ClientCert,
+	}
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPut, "/api/token/", body, 1)
+	ctx.Set("role", common.RoleCommonUser)
+	UpdateToken(ctx)
+	if response := decodeAPIResponse(t, recorder); !response.Success {
+		t.Fatalf("expected keeping the bound certificate to succeed, got message: %s", response.Message)
+	}
+}
+
+func TestTokenSigningSecretIsRevealedOnlyWhenGenerated(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+
+	body := map[string]any{
+		"name":             "signed-token",
+		"expired_time":     -1,
+		"unlimited_quota":  true,
+		"group":            "default",
+		"signing_required": true,
+	}
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+	AddToken(ctx)
+	response := decodeAPIResponse(t, recorder)
+	if !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	var created struct {
+		ID            int    `json:"id"`
+		SigningSecret string `json:"signing_secret"`
+	}
+	if err := common.Unmarshal(response.Data, &created); err != nil {
+		t.Fatalf("failed to decode created token: %v", err)
+	}
+	var stored model.Token
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("failed to load created token: %v", err)
+	}
+	if created.SigningSecret == "" || stored.SigningSecret != created.SigningSecret {
+		t.Fatalf("expected the created response to reveal the stored signing secret, got %q", created.SigningSecret)
+	}
+
+	ctx, recorder = newAuthenticatedContext(t, http.MethodGet, "/api/token/"+strconv.Itoa(created.ID), nil, 1)
+	ctx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(created.ID)}}
+	GetToken(ctx)
+	if strings.Contains(recorder.Body.String(), created.SigningSecret) {
+		t.Fatal("expected token reads to never include the signing secret")
+	}
+
+	ctx, recorder = newAuthenticatedContext(t, http.MethodPost, "/api/token/"+strconv.Itoa(created.ID)+"/signing_secret", nil, 1)
+	ctx.Params = gin.Params{{Key: "id", Value: strconv.Itoa(created.ID)}}
+	RotateTokenSigningSecret(ctx)
+	response = decodeAPIResponse(t, recorder)
+	var rotated struct {
+		SigningSecret string `json:"signing_secret"`
+	}
+	if err := common.Unmarshal(response.Data, &rotated); err != nil {
+		t.Fatalf("failed to decode rotated secret: %v", err)
+	}
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("failed to reload token: %v", err)
+	}
+	if rotated.SigningSecret == "" || rotated.SigningSecret == created.SigningSecret || stored.SigningSecret != rotated.SigningSecret {
+		t.Fatalf("expected rotation to store and reveal a new secret, got %q", rotated.SigningSecret)
+	}
+}
+
+func TestTokenLabelsAreValidatedAndKeptWhenOmitted(t *testing.T) {
+	db := setupTokenControllerTestDB(t)
+
+	body := map[string]any{
+		"name":            "labelled",
+		"expired_time":    -1,
+		"unlimited_quota": true,
+		"group":           "default",
+		"labels":          map[string]string{"team": "search", "env": "prod"},
+	}
+	ctx, recorder := newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+	AddToken(ctx)
+	response := decodeAPIResponse(t, recorder)
+	if !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	var created tokenResponse
+	if err := common.Unmarshal(response.Data, &created); err != nil {
+		t.Fatalf("failed to decode token create response: %v", err)
+	}
+	if created.Labels["team"] != "search" || created.Labels["env"] != "prod" {
+		t.Fatalf("expected labels in create response, got %v", created.Labels)
+	}
+
+	body["name"] = "bad-labels"
+	body["labels"] = map[string]string{"team": "a,b"}
+	ctx, recorder = newAuthenticatedContext(t, http.MethodPost, "/api/token/", body, 1)
+	AddToken(ctx)
+	if decodeAPIResponse(t, recorder).Success {
+		t.Fatal("expected a label value containing ',' to be rejected")
+	}
+
+	update := map[string]any{
+		"id":              created.Id,
+		"name":            "labelled-renamed",
+		"expired_time":    -1,
+		"unlimited_quota": true,
+		"group":           "default",
+	}
+	ctx, recorder = newAuthenticatedContext(t, http.MethodPut, "/api/token/", update, 1)
+	UpdateToken(ctx)
+	if response := decodeAPIResponse(t, recorder); !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	var stored model.Token
+	if err := db.First(&stored, created.Id).Error; err != nil {
+		t.Fatalf("failed to load updated token: %v", err)
+	}
+	if stored.Labels != `{"env":"prod","team":"search"}` {
+		t.Fatalf("expected labels to be kept when omitted, got %q", stored.Labels)
+	}
+
+	update["labels"] = nil
+	ctx, recorder = newAuthenticatedContext(t, http.MethodPut, "/api/token/", update, 1)
+	UpdateToken(ctx)
+	if response := decodeAPIResponse(t, recorder); !response.Success {
+		t.Fatalf("expected success response, got message: %s", response.Message)
+	}
+	if err := db.First(&stored, created.Id).Error; err != nil {
+		t.Fatalf("failed to load updated token: %v", err)
+	}
+	if stored.Labels != "" {
+		t.Fatalf("expected null labels to clear the token labels, got %q", stored.Labels)
+	}
+}
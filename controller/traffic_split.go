@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TrafficSplitRuleRequest 创建或修改灰度分流规则的请求
+type TrafficSplitRuleRequest struct {
+	Name                 string  `json:"name"`
+	Enabled              bool    `json:"enabled"`
+	ModelName            string  `json:"model_name"`
+	Group                string  `json:"group"`
+	CanaryChannelId      int     `json:"canary_channel_id"`
+	CanaryModel          string  `json:"canary_model"`
+	HashKey              string  `json:"hash_key"`
+	RampSteps            string  `json:"ramp_steps"`
+	RampIntervalMinutes  int     `json:"ramp_interval_minutes"`
+	MaxErrorRateIncrease float64 `json:"max_error_rate_increase"`
+	MaxLatencyIncrease   float64 `json:"max_latency_increase"`
+	MinSamples           int     `json:"min_samples"`
+}
+
+func (req *TrafficSplitRuleRequest) apply(rule *model.TrafficSplitRule) {
+	rule.Name = req.Name
+	rule.Enabled = req.Enabled
+	rule.ModelName = req.ModelName
+	rule.Group = req.Group
+	rule.CanaryChannelId = req.CanaryChannelId
+	rule.CanaryModel = req.CanaryModel
+	rule.HashKey = req.HashKey
+	rule.RampSteps = req.RampSteps
+	rule.RampIntervalMinutes = req.RampIntervalMinutes
+	rule.MaxErrorRateIncrease = req.MaxErrorRateIncrease
+	rule.MaxLatencyIncrease = req.MaxLatencyIncrease
+	rule.MinSamples = req.MinSamples
+}
+
+// trafficSplitRuleView 在规则上附带当前放量比例
+type trafficSplitRuleView struct {
+	*model.TrafficSplitRule
+	CurrentPercent float64 `json:"current_percent"`
+}
+
+func newTrafficSplitRuleView(rule *model.TrafficSplitRule) trafficSplitRuleView {
+	return trafficSplitRuleView{TrafficSplitRule: rule, CurrentPercent: rule.CurrentPercent(common.GetTimestamp())}
+}
+
+func validateTrafficSplitRule(c *gin.Context, rule *model.TrafficSplitRule) bool {
+	if err := rule.Normalize(); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return false
+	}
+	if rule.CanaryChannelId > 0 {
+		if _, err := model.GetChannelById(rule.CanaryChannelId, false); err != nil {
+			common.ApiErrorMsg(c, "canary 渠道不存在")
+			return false
+		}
+	}
+	return true
+}
+
+func getTrafficSplitRuleParam(c *gin.Context) (*model.TrafficSplitRule, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return nil, false
+	}
+	rule, err := model.GetTrafficSplitRuleById(id)
+	if err != nil {
+		common.ApiErrorMsg(c, "灰度分流规则不存在")
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetTrafficSplitRules 返回全部灰度分流规则及其当前放量比例
+func GetTrafficSplitRules(c *gin.Context) {
+	rules, err := model.GetTrafficSplitRules()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	views := make([]trafficSplitRuleView, 0, len(rules))
+	for _, rule := range rules {
+		views = append(views, newTrafficSplitRuleView(rule))
+	}
+	common.ApiSuccess(c, views)
+}
+
+// CreateTrafficSplitRule 创建灰度分流规则，立即从第一档开始放量
+func CreateTrafficSplitRule(c *gin.Context) {
+	var req TrafficSplitRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	rule := &model.TrafficSplitRule{}
+	req.apply(rule)
+	if !validateTrafficSplitRule(c, rule) {
+		return
+	}
+	if err := rule.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateTrafficSplitCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    newTrafficSplitRuleView(rule),
+	})
+}
+
+// UpdateTrafficSplitRule 修改灰度分流规则，放量进度与回滚状态保持不变
+func UpdateTrafficSplitRule(c *gin.Context) {
+	rule, ok := getTrafficSplitRuleParam(c)
+	if !ok {
+		return
+	}
+	var req TrafficSplitRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	req.apply(rule)
+	if !validateTrafficSplitRule(c, rule) {
+		return
+	}
+	if err := rule.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateTrafficSplitCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    newTrafficSplitRuleView(rule),
+	})
+}
+
+// DeleteTrafficSplitRule 删除灰度分流规则
+func DeleteTrafficSplitRule(c *gin.Context) {
+	rule, ok := getTrafficSplitRuleParam(c)
+	if !ok {
+		return
+	}
+	if err := model.DeleteTrafficSplitRule(rule.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateTrafficSplitCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// RestartTrafficSplitRule 清除回滚状态并从第一档重新放量
+func RestartTrafficSplitRule(c *gin.Context) {
+	rule, ok := getTrafficSplitRuleParam(c)
+	if !ok {
+		return
+	}
+	if err := model.RestartTrafficSplitRule(rule.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	service.InvalidateTrafficSplitCache()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已重新开始放量",
+	})
+}
+
+// GetTrafficSplitRuleStats 返回当前节点上 canary 分支与模型基线的近期错误率和 p95 延迟
+func GetTrafficSplitRuleStats(c *gin.Context) {
+	rule, ok := getTrafficSplitRuleParam(c)
+	if !ok {
+		return
+	}
+	canary, baseline := service.GetTrafficSplitStats(rule)
+	common.ApiSuccess(c, gin.H{
+		"rule":     newTrafficSplitRuleView(rule),
+		"canary":   canary,
+		"baseline": baseline,
+	})
+}
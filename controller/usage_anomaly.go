@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func parseUsageAnomalyFilter(c *gin.Context) model.UsageAnomalyFilter {
+	tokenId, _ := strconv.Atoi(c.Query("token_id"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return model.UsageAnomalyFilter{
+		TokenId:        tokenId,
+		Severity:       c.Query("severity"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+}
+
+func respondUsageAnomalies(c *gin.Context, filter model.UsageAnomalyFilter) {
+	pageInfo := common.GetPageQuery(c)
+	anomalies, total, err := model.GetUsageAnomalies(filter, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(anomalies)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetUserUsageAnomalies 当前用户的异常消耗记录
+func GetUserUsageAnomalies(c *gin.Context) {
+	filter := parseUsageAnomalyFilter(c)
+	filter.UserId = c.GetInt("id")
+	respondUsageAnomalies(c, filter)
+}
+
+// GetAllUsageAnomalies 全部用户的异常消耗记录，可按 user_id 过滤
+func GetAllUsageAnomalies(c *gin.Context) {
+	filter := parseUsageAnomalyFilter(c)
+	filter.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	respondUsageAnomalies(c, filter)
+}
+
+// GetUserUsageForecast 当前用户的本月消费预测，本月尚未生成预测时返回 null
+func GetUserUsageForecast(c *gin.Context) {
+	forecast, err := model.GetUserUsageForecast(c.GetInt("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		common.ApiSuccess(c, nil)
+		return
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, forecast)
+}
+
+// GetAllUsageForecasts 全部用户的本月消费预测，按预测月末消耗降序
+func GetAllUsageForecasts(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	forecasts, total, err := model.GetUsageForecasts(c.Query("username"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(forecasts)
+	common.ApiSuccess(c, pageInfo)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type usageExportRequest struct {
+	Format string `json:"format"`
+	model.LogExportFilter
+}
+
+type usageExportResponse struct {
+	TaskID      string                 `json:"task_id"`
+	Status      model.SystemTaskStatus `json:"status"`
+	Payload     any                    `json:"payload"`
+	State       any                    `json:"state"`
+	Result      any                    `json:"result"`
+	Error       string                 `json:"error"`
+	DownloadURL string                 `json:"download_url,omitempty"`
+	URLExpires  int64                  `json:"download_url_expires_at,omitempty"`
+	CreatedAt   int64                  `json:"created_at"`
+	UpdatedAt   int64                  `json:"updated_at"`
+}
+
+// CreateUsageExport 管理员按条件导出所有用户的日志
+func CreateUsageExport(c *gin.Context) {
+	var req usageExportRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	task, ok := startUsageExport(c, req, false)
+	if !ok {
+		return
+	}
+	recordManageAudit(c, "log.export", map[string]interface{}{
+		"format":          req.Format,
+		"start_timestamp": req.StartTimestamp,
+		"end_timestamp":   req.EndTimestamp,
+	})
+	common.ApiSuccess(c, newUsageExportResponse(task))
+}
+
+// CreateSelfUsageExport 用户导出自己的日志
+func CreateSelfUsageExport(c *gin.Context) {
+	var req usageExportRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	task, ok := startUsageExport(c, req, true)
+	if !ok {
+		return
+	}
+	common.ApiSuccess(c, newUsageExportResponse(task))
+}
+
+func startUsageExport(c *gin.Context, req usageExportRequest, selfService bool) (*model.SystemTask, bool) {
+	task, err := service.StartUsageExportTask(service.UsageExportPayload{
+		OwnerId:     c.GetInt("id"),
+		SelfService: selfService,
+		Format:      req.Format,
+		Filter:      req.LogExportFilter,
+	})
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	return task, true
+}
+
+// GetUsageExport 管理员查询任意导出任务的进度，完成后附带短期有效的下载地址
+func GetUsageExport(c *gin.Context) {
+	task, ok := findUsageExportTask(c)
+	if !ok {
+		return
+	}
+	common.ApiSuccess(c, newUsageExportResponse(task))
+}
+
+// GetSelfUsageExport 用户查询自己发起的导出任务
+func GetSelfUsageExport(c *gin.Context) {
+	task, ok := findUsageExportTask(c)
+	if !ok {
+		return
+	}
+	if ownerId, _ := service.UsageExportOwner(task); ownerId != c.GetInt("id") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "export not found",
+		})
+		return
+	}
+	common.ApiSuccess(c, newUsageExportResponse(task))
+}
+
+func findUsageExportTask(c *gin.Context) (*model.SystemTask, bool) {
+	task, err := model.GetSystemTaskByTaskID(c.Param("task_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	if task == nil || task.Type != model.SystemTaskTypeUsageExport {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "export not found",
+		})
+		return nil, false
+	}
+	return task, true
+}
+
+func newUsageExportResponse(task *model.SystemTask) usageExportResponse {
+	full := task.ToResponse()
+	resp := usageExportResponse{
+		TaskID:    full.TaskID,
+		Status:    full.Status,
+		Payload:   full.Payload,
+		State:     full.State,
+		Result:    full.Result,
+		Error:     full.Error,
+		CreatedAt: full.CreatedAt,
+		UpdatedAt: full.UpdatedAt,
+	}
+	if _, _, err := service.UsageExportFile(task); err == nil {
+		resp.DownloadURL, resp.URLExpires = service.UsageExportDownloadURL(task.TaskID, time.Now())
+	}
+	return resp
+}
+
+// DownloadUsageExport 通过签名地址下载导出文件，签名本身即凭证，无需登录
+func DownloadUsageExport(c *gin.Context) {
+	taskID := c.Query("task_id")
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if !service.VerifyUsageExportSignature(taskID, expires, c.Query("signature"), time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "invalid or expired download link",
+		})
+		return
+	}
+	task, err := model.GetSystemTaskByTaskID(taskID)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	path, result, err := service.UsageExportFile(task)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, service.ErrUsageExportNotReady) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, "usage-logs-"+task.TaskID+"."+result.Format)
+}
@@ -32,6 +32,15 @@ func GetAllQuotaDates(c *gin.Context) {
 	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
 	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
 	username := c.Query("username")
+	if label := c.Query("group_by_label"); label != "" {
+		respondLabelQuotaData(c, model.LabelUsageFilter{
+			Label:          label,
+			Username:       username,
+			StartTimestamp: startTimestamp,
+			EndTimestamp:   endTimestamp,
+		})
+		return
+	}
 	dates, err := model.GetAllQuotaDates(startTimestamp, endTimestamp, username)
 	if err != nil {
 		common.ApiError(c, err)
@@ -72,6 +81,15 @@ func GetUserQuotaDates(c *gin.Context) {
 		})
 		return
 	}
+	if label := c.Query("group_by_label"); label != "" {
+		respondLabelQuotaData(c, model.LabelUsageFilter{
+			Label:          label,
+			UserId:         userId,
+			StartTimestamp: startTimestamp,
+			EndTimestamp:   endTimestamp,
+		})
+		return
+	}
 	dates, err := model.GetQuotaDataByUserId(userId, startTimestamp, endTimestamp)
 	if err != nil {
 		common.ApiError(c, err)
@@ -129,3 +147,17 @@ func GetUserFlowQuotaDates(c *gin.Context) {
 	})
 	return
 }
+
+// respondLabelQuotaData 按标签取值与小时汇总消费日志，数据看板按成本分摊标签展示时使用
+func respondLabelQuotaData(c *gin.Context, filter model.LabelUsageFilter) {
+	dates, err := model.GetLabelQuotaData(filter)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    dates,
+	})
+}
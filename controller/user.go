@@ -15,6 +15,7 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
 	"github.com/QuantumNous/new-api/relaykit/dto"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/service/authz"
@@ -72,19 +73,28 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 检查是否启用2FA
+	if startTwoFALogin(&user, c) {
+		return
+	}
+
+	setupLogin(&user, c)
+}
+
+// startTwoFALogin 在用户启用了 2FA 时创建二次验证流程并写出响应，返回 true 表示
+// 登录需要等待 /api/user/login/2fa 完成，调用方不应再建立会话。
+func startTwoFALogin(user *model.User, c *gin.Context) bool {
 	twoFAEnabled, err := model.IsTwoFAEnabled(user.Id)
 	if err != nil {
 		common.SysLog(fmt.Sprintf("Login failed to load 2FA status for user %d: %v", user.Id, err))
 		common.ApiErrorI18n(c, i18n.MsgDatabaseError)
-		return
+		return true
 	}
 	if twoFAEnabled {
 		expiresAt := time.Now().Add(5 * time.Minute)
 		payload, err := common.Marshal(twoFALoginFlowPayload{AuthVersion: user.AuthVersion})
 		if err != nil {
 			common.ApiError(c, err)
-			return
+			return true
 		}
 		flowToken, _, err := model.CreateAuthFlow(model.AuthFlowCreate{
 			Purpose:   model.AuthFlowPurposeTwoFALogin,
@@ -94,7 +104,7 @@ func Login(c *gin.Context) {
 		})
 		if err != nil {
 			common.ApiError(c, err)
-			return
+			return true
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -106,10 +116,9 @@ func Login(c *gin.Context) {
 				"expires_at":  expiresAt.Unix(),
 			},
 		})
-		return
+		return true
 	}
-
-	setupLogin(&user, c)
+	return false
 }
 
 // loginMethodFromContext 根据请求路径推导登录方式，用于登录审计日志。
@@ -119,6 +128,8 @@ func loginMethodFromContext(c *gin.Context) string {
 		return "password"
 	case "/api/user/login/2fa":
 		return "2fa"
+	case "/api/user/login/ldap":
+		return "ldap"
 	case "/api/user/passkey/login/finish":
 		return "passkey"
 	case "/api/oauth/wechat":
@@ -299,7 +310,6 @@ func Register(c *gin.Context) {
 		token := model.Token{
 			UserId:             insertedUser.Id, // 使用插入后的用户ID
 			Name:               cleanUser.Username + "的初始令牌",
-			Key:                key,
 			CreatedTime:        common.GetTimestamp(),
 			AccessedTime:       common.GetTimestamp(),
 			ExpiredTime:        -1,     // 永不过期
@@ -307,6 +317,7 @@ func Register(c *gin.Context) {
 			UnlimitedQuota:     true,
 			ModelLimitsEnabled: false,
 		}
+		token.SetKey(key)
 		if setting.DefaultUseAutoGroup {
 			token.Group = "auto"
 		}
@@ -389,6 +400,12 @@ func GetUser(c *gin.Context) {
 		return
 	}
 	user.AdminPermissions = authz.Capabilities(user.Id, user.Role)
+	user.AuthzRole = authz.UserRoleKey(user.Id)
+	user.LockedFields, err = model.GetUserLockedFields(user.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -638,7 +655,7 @@ func GetUserModels(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
-	groups := service.GetUserUsableGroups(user.Group)
+	groups := service.GetUserUsableGroups(user.Group, user.GetUsableGroups()...)
 	group := c.Query("group")
 	var groupsToQuery []string
 	switch {
@@ -648,7 +665,7 @@ func GetUserModels(c *gin.Context) {
 		}
 	case group == "auto":
 		if _, ok := groups[group]; ok {
-			groupsToQuery = service.GetUserAutoGroup(user.Group)
+			groupsToQuery = service.GetUserAutoGroup(user.Group, user.GetUsableGroups()...)
 		}
 	default:
 		if _, ok := groups[group]; ok {
@@ -696,6 +713,10 @@ func UpdateUser(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgUserNoPermissionHigherLevel)
 		return
 	}
+	if updatedUser.Group != originUser.Group && model.IsUserFieldLocked(updatedUser.Id, oauth.ClaimFieldGroup) {
+		common.ApiErrorI18n(c, i18n.MsgUserFieldLockedByProvider, map[string]any{"Field": "group"})
+		return
+	}
 	if updatedUser.Password == "$I_LOVE_U" {
 		updatedUser.Password = "" // rollback to what it should be
 	}
@@ -1081,6 +1102,18 @@ type ManageRequest struct {
 	Mode   string `json:"mode"`
 }
 
+// manageUserActionPermission 返回 ManageUser 各操作所需的权限
+func manageUserActionPermission(action string) authz.Permission {
+	switch action {
+	case "enable", "disable":
+		return authz.UserOperate
+	case "add_quota":
+		return authz.UserQuota
+	default:
+		return authz.UserWrite
+	}
+}
+
 // ManageUser Only admin user can do this
 func ManageUser(c *gin.Context) {
 	var req ManageRequest
@@ -1090,6 +1123,10 @@ func ManageUser(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
 		return
 	}
+	if !authz.Can(c.GetInt("id"), c.GetInt("role"), manageUserActionPermission(req.Action)) {
+		common.ApiErrorI18n(c, i18n.MsgAuthInsufficientPrivilege)
+		return
+	}
 	user := model.User{
 		Id: req.Id,
 	}
@@ -1104,6 +1141,10 @@ func ManageUser(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgUserNoPermissionHigherLevel)
 		return
 	}
+	if (req.Action == "promote" || req.Action == "demote") && model.IsUserFieldLocked(user.Id, oauth.ClaimFieldRole) {
+		common.ApiErrorI18n(c, i18n.MsgUserFieldLockedByProvider, map[string]any{"Field": "role"})
+		return
+	}
 	switch req.Action {
 	case "disable":
 		user.Status = common.UserStatusDisabled
@@ -1395,6 +1436,10 @@ type UpdateUserSettingRequest struct {
 	GotifyUrl                        string  `json:"gotify_url,omitempty"`
 	GotifyToken                      string  `json:"gotify_token,omitempty"`
 	GotifyPriority                   int     `json:"gotify_priority,omitempty"`
+	ChatWebhookUrl                   string  `json:"chat_webhook_url,omitempty"`
+	ChatWebhookSecret                string  `json:"chat_webhook_secret,omitempty"`
+	TelegramBotToken                 string  `json:"telegram_bot_token,omitempty"`
+	TelegramChatId                   string  `json:"telegram_chat_id,omitempty"`
 	UpstreamModelUpdateNotifyEnabled *bool   `json:"upstream_model_update_notify_enabled,omitempty"`
 	AcceptUnsetModelRatioModel       bool    `json:"accept_unset_model_ratio_model"`
 	RecordIpLog                      bool    `json:"record_ip_log"`
@@ -1408,7 +1453,7 @@ func UpdateUserSetting(c *gin.Context) {
 	}
 
 	// 验证预警类型
-	if req.QuotaWarningType != dto.NotifyTypeEmail && req.QuotaWarningType != dto.NotifyTypeWebhook && req.QuotaWarningType != dto.NotifyTypeBark && req.QuotaWarningType != dto.NotifyTypeGotify {
+	if req.QuotaWarningType != dto.NotifyTypeEmail && req.QuotaWarningType != dto.NotifyTypeWebhook && req.QuotaWarningType != dto.NotifyTypeBark && req.QuotaWarningType != dto.NotifyTypeGotify && !service.IsChatNotifyType(req.QuotaWarningType) {
 		common.ApiErrorI18n(c, i18n.MsgSettingInvalidType)
 		return
 	}
@@ -1481,6 +1526,28 @@ func UpdateUserSetting(c *gin.Context) {
 		}
 	}
 
+	// 如果是Telegram类型，验证机器人令牌和会话ID
+	if req.QuotaWarningType == dto.NotifyTypeTelegram {
+		if strings.TrimSpace(req.TelegramBotToken) == "" || strings.TrimSpace(req.TelegramChatId) == "" {
+			common.ApiErrorI18n(c, i18n.MsgSettingTelegramConfigEmpty)
+			return
+		}
+	} else if service.IsChatNotifyType(req.QuotaWarningType) {
+		// Slack、Teams、Discord、飞书、钉钉均使用机器人Webhook地址
+		if req.ChatWebhookUrl == "" {
+			common.ApiErrorI18n(c, i18n.MsgSettingWebhookEmpty)
+			return
+		}
+		if _, err := url.ParseRequestURI(req.ChatWebhookUrl); err != nil {
+			common.ApiErrorI18n(c, i18n.MsgSettingWebhookInvalid)
+			return
+		}
+		if !strings.HasPrefix(req.ChatWebhookUrl, "https://") && !strings.HasPrefix(req.ChatWebhookUrl, "http://") {
+			common.ApiErrorI18n(c, i18n.MsgSettingUrlMustHttp)
+			return
+		}
+	}
+
 	userId := c.GetInt("id")
 	user, err := model.GetUserById(userId, true)
 	if err != nil {
@@ -1532,6 +1599,18 @@ func UpdateUserSetting(c *gin.Context) {
 		}
 	}
 
+	// 如果是Telegram类型，添加机器人配置到设置中
+	if req.QuotaWarningType == dto.NotifyTypeTelegram {
+		settings.TelegramBotToken = strings.TrimSpace(req.TelegramBotToken)
+		settings.TelegramChatId = strings.TrimSpace(req.TelegramChatId)
+	} else if service.IsChatNotifyType(req.QuotaWarningType) {
+		settings.ChatWebhookUrl = req.ChatWebhookUrl
+		// 签名密钥仅飞书和钉钉使用
+		if req.QuotaWarningType == dto.NotifyTypeFeishu || req.QuotaWarningType == dto.NotifyTypeDingTalk {
+			settings.ChatWebhookSecret = req.ChatWebhookSecret
+		}
+	}
+
 	// 更新用户设置
 	if err := model.UpdateUserSetting(user.Id, settings); err != nil {
 		common.ApiErrorI18n(c, i18n.MsgUpdateFailed)
@@ -33,6 +33,7 @@ func setupManageUserTestDB(t *testing.T) *gorm.DB {
 	model.DB, model.LOG_DB = db, db
 	require.NoError(t, db.AutoMigrate(
 		&model.User{}, &model.UserSession{}, &model.Log{}, &model.CasbinRule{}, &model.AuthzRole{},
+		&model.UserAccessLock{},
 	))
 
 	t.Cleanup(func() {
@@ -48,15 +49,20 @@ func setupManageUserTestDB(t *testing.T) *gorm.DB {
 }
 
 func performManageUserRequest(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return performManageUserRequestAs(t, 9999, common.RoleRootUser, body)
+}
+
+func performManageUserRequestAs(t *testing.T, operatorId int, operatorRole int, body string) *httptest.ResponseRecorder {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(recorder)
 	c.Request = httptest.NewRequest(http.MethodPost, "/api/user/manage", strings.NewReader(body))
 	c.Request.Header.Set("Content-Type", "application/json")
-	c.Set("id", 9999)
-	c.Set("role", common.RoleRootUser)
-	c.Set("username", "root-operator")
+	c.Set("id", operatorId)
+	c.Set("role", operatorRole)
+	c.Set("username", "operator")
 	ManageUser(c)
 	return recorder
 }
@@ -159,3 +165,33 @@ func TestManageUserDeleteReturnsImmediatelyAndUnknownActionFails(t *testing.T) {
 	assert.EqualValues(t, 1, unchanged.AuthVersion)
 	assert.Equal(t, common.UserStatusEnabled, unchanged.Status)
 }
+
+func TestManageUserChecksPermissionOfEachAction(t *testing.T) {
+	db := setupManageUserTestDB(t)
+	require.NoError(t, authz.Init(db))
+	_, err := authz.CreateCustomRole(authz.CustomRoleInput{
+		Key:    "support",
+		Grants: authz.PermissionsMap{authz.ResourceUser: {authz.ActionRead: true, authz.ActionQuota: true}},
+	})
+	require.NoError(t, err)
+	operator := model.User{Username: "support-operator", Password: "password", Role: common.RoleAdminUser, Status: common.UserStatusEnabled, Group: "default", AffCode: "support-operator"}
+	target := model.User{Username: "support-target", Password: "password", Role: common.RoleCommonUser, Status: common.UserStatusEnabled, Group: "default", Quota: 100, AffCode: "support-target"}
+	require.NoError(t, db.Create(&operator).Error)
+	require.NoError(t, db.Create(&target).Error)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return authz.AssignUserRoleInTx(tx, operator.Id, "support")
+	}))
+	require.NoError(t, authz.ReloadPolicy())
+
+	recorder := performManageUserRequestAs(t, operator.Id, common.RoleAdminUser, fmt.Sprintf(`{"id":%d,"action":"add_quota","mode":"override","value":500}`, target.Id))
+	assert.Contains(t, recorder.Body.String(), `"success":true`)
+	var quota int
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", target.Id).Select("quota").Scan(&quota).Error)
+	assert.Equal(t, 500, quota)
+
+	recorder = performManageUserRequestAs(t, operator.Id, common.RoleAdminUser, fmt.Sprintf(`{"id":%d,"action":"disable"}`, target.Id))
+	assert.Contains(t, recorder.Body.String(), `"success":false`)
+	var status int
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", target.Id).Select("status").Scan(&status).Error)
+	assert.Equal(t, common.UserStatusEnabled, status)
+}
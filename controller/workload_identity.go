@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RFC 8693 token exchange identifiers.
+const (
+	tokenExchangeGrantType     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT               = "urn:ietf:params:oauth:token-type:jwt"
+	tokenTypeIDToken           = "urn:ietf:params:oauth:token-type:id_token"
+	tokenTypeAccessTokenIssued = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// WorkloadIdentityIssuerRequest is the request structure for creating or
+// replacing a workload identity issuer
+type WorkloadIdentityIssuerRequest struct {
+	Name        string `json:"name"`
+	Issuer      string `json:"issuer"`
+	Audience    string `json:"audience"`
+	JwksURL     string `json:"jwks_url"`
+	StaticKeys  string `json:"static_keys"`
+	Rules       string `json:"rules"`
+	AllowDirect bool   `json:"allow_direct"`
+	TokenTTL    int    `json:"token_ttl"`
+	Enabled     bool   `json:"enabled"`
+}
+
+func (req *WorkloadIdentityIssuerRequest) apply(issuer *model.WorkloadIdentityIssuer) {
+	issuer.Name = req.Name
+	issuer.Issuer = req.Issuer
+	issuer.Audience = req.Audience
+	issuer.JwksURL = req.JwksURL
+	issuer.StaticKeys = req.StaticKeys
+	issuer.Rules = req.Rules
+	issuer.AllowDirect = req.AllowDirect
+	issuer.TokenTTL = req.TokenTTL
+	issuer.Enabled = req.Enabled
+}
+
+// GetWorkloadIdentityIssuers returns all workload identity issuers
+func GetWorkloadIdentityIssuers(c *gin.Context) {
+	issuers, err := model.GetAllWorkloadIdentityIssuers()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    issuers,
+	})
+}
+
+// CreateWorkloadIdentityIssuer registers a trusted workload identity issuer
+func CreateWorkloadIdentityIssuer(c *gin.Context) {
+	var req WorkloadIdentityIssuerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	issuer := &model.WorkloadIdentityIssuer{}
+	req.apply(issuer)
+	if err := oauth.ValidateWorkloadIssuer(issuer); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if err := model.CreateWorkloadIdentityIssuer(issuer); err != nil {
+		if errors.Is(err, model.ErrWorkloadIssuerTaken) {
+			common.ApiErrorMsg(c, "该 Issuer 已被注册")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    issuer,
+	})
+}
+
+// UpdateWorkloadIdentityIssuer replaces an issuer's configuration and revokes
+// the gateway keys it has issued
+func UpdateWorkloadIdentityIssuer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return
+	}
+	var req WorkloadIdentityIssuerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorMsg(c, "无效的请求参数: "+err.Error())
+		return
+	}
+	issuer, err := model.GetWorkloadIdentityIssuerById(id)
+	if err != nil {
+		common.ApiErrorMsg(c, "未找到该工作负载身份签发方")
+		return
+	}
+	oldJwksURL := issuer.JwksURL
+	req.apply(issuer)
+	if err := oauth.ValidateWorkloadIssuer(issuer); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	if err := model.UpdateWorkloadIdentityIssuer(issuer); err != nil {
+		if errors.Is(err, model.ErrWorkloadIssuerTaken) {
+			common.ApiErrorMsg(c, "该 Issuer 已被注册")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	oauth.InvalidateWorkloadJWKS(oldJwksURL)
+	oauth.InvalidateWorkloadJWKS(issuer.JwksURL)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    issuer,
+	})
+}
+
+// DeleteWorkloadIdentityIssuer deletes an issuer and revokes the gateway keys
+// it has issued
+func DeleteWorkloadIdentityIssuer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的 ID")
+		return
+	}
+	issuer, err := model.GetWorkloadIdentityIssuerById(id)
+	if err != nil {
+		common.ApiErrorMsg(c, "未找到该工作负载身份签发方")
+		return
+	}
+	if err := model.DeleteWorkloadIdentityIssuer(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	oauth.InvalidateWorkloadJWKS(issuer.JwksURL)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
+
+// ExchangeWorkloadToken exchanges a workload identity JWT for a short-lived
+// gateway key (RFC 8693 token exchange). The request is form encoded and
+// errors use the OAuth 2.0 error response format (RFC 6749 section 5.2).
+func ExchangeWorkloadToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if c.PostForm("grant_type") != tokenExchangeGrantType {
+		writeTokenExchangeError(c, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be "+tokenExchangeGrantType)
+		return
+	}
+	subjectToken := c.PostForm("subject_token")
+	subjectTokenType := c.PostForm("subject_token_type")
+	if subjectToken == "" || (subjectTokenType != tokenTypeJWT && subjectTokenType != tokenTypeIDToken) {
+		writeTokenExchangeError(c, http.StatusBadRequest, "invalid_request", "subject_token must be a JWT")
+		return
+	}
+	key, token, err := service.ExchangeWorkloadJWT(c, subjectToken, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, service.ErrWorkloadIdentityDenied) {
+			writeTokenExchangeError(c, http.StatusBadRequest, "invalid_grant", "subject_token is not accepted")
+			return
+		}
+		if errors.Is(err, model.ErrWorkloadQuotaExhausted) {
+			writeTokenExchangeError(c, http.StatusBadRequest, "invalid_grant", "the workload has used up its token quota")
+			return
+		}
+		common.SysError("failed to exchange workload identity token: " + err.Error())
+		writeTokenExchangeError(c, http.StatusInternalServerError, "server_error", "failed to issue token")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":      "sk-" + key,
+		"issued_token_type": tokenTypeAccessTokenIssued,
+		"token_type":        "Bearer",
+		"expires_in":        token.ExpiredTime - common.GetTimestamp(),
+	})
+}
+
+func writeTokenExchangeError(c *gin.Context, status int, code string, description string) {
+	c.JSON(status, gin.H{
+		"error":             code,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type workloadIdentityTestEnv struct {
+	issuer  *model.WorkloadIdentityIssuer
+	private ed25519.PrivateKey
+	userId  int
+}
+
+func setupWorkloadIdentityTest(t *testing.T) *workloadIdentityTestEnv {
+	t.Helper()
+	db := setupManageUserTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Token{}, &model.WorkloadIdentityIssuer{}))
+	// 令牌按 key 列查询，需要先按数据库类型初始化列名引用
+	t.Setenv("LOG_SQL_DSN", "")
+	require.NoError(t, model.InitLogDB())
+
+	user := &model.User{Username: "ci-bot", Password: "password-placeholder", Status: common.UserStatusEnabled, Group: "default"}
+	require.NoError(t, db.Create(user).Error)
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	issuer := &model.WorkloadIdentityIssuer{
+		Name:       "GitHub Actions",
+		Issuer:     "https://token.actions.example.com",
+		Audience:   "new-api",
+		StaticKeys: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Rules: `[{"when": {"conditions": [{"field": "repository", "op": "eq", "value": "acme/api"}]},
+			"user_id": ` + common.Interface2String(user.Id) + `, "model_limits": ["gpt-4o-mini"], "quota": 5000}]`,
+		AllowDirect: true,
+		TokenTTL:    600,
+		Enabled:     true,
+	}
+	require.NoError(t, model.CreateWorkloadIdentityIssuer(issuer))
+	return &workloadIdentityTestEnv{issuer: issuer, private: private, userId: user.Id}
+}
+
+func (env *workloadIdentityTestEnv) sign(t *testing.T, overrides jwt.MapClaims) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"iss":        env.issuer.Issuer,
+		"aud":        env.issuer.Audience,
+		"sub":        "repo:acme/api:ref:refs/heads/main",
+		"repository": "acme/api",
+		"iat":        time.Now().Unix(),
+		"exp":        time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range overrides {
+		claims[key] = value
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(env.private)
+	require.NoError(t, err)
+	return signed
+}
+
+func performWorkloadTokenExchange(t *testing.T, form url.Values) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/workload-identity/token", strings.NewReader(form.Encode()))
+	c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ExchangeWorkloadToken(c)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	return recorder, body
+}
+
+func workloadExchangeForm(subjectToken string) url.Values {
+	return url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {tokenTypeJWT},
+	}
+}
+
+func TestExchangeWorkloadTokenMintsScopedShortLivedKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	env := setupWorkloadIdentityTest(t)
+
+	recorder, body := performWorkloadTokenExchange(t, workloadExchangeForm(env.sign(t, nil)))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.InDelta(t, 600, body["expires_in"], 2, "the issuer token_ttl caps the key lifetime below the JWT exp")
+	accessToken, _ := body["access_token"].(string)
+	require.True(t, strings.HasPrefix(accessToken, "sk-"))
+
+	token, err := model.ValidateUserToken(strings.TrimPrefix(accessToken, "sk-"))
+	require.NoError(t, err)
+	assert.Equal(t, env.userId, token.UserId)
+	assert.Equal(t, env.issuer.Id, token.WorkloadIssuerId)
+	assert.Equal(t, 5000, token.RemainQuota)
+	assert.True(t, token.ModelLimitsEnabled)
+	assert.Equal(t, []string{"gpt-4o-mini"}, token.GetModelLimits())
+	assert.Equal(t, "GitHub Actions:repo:acme/api:ref:refs/heads/main", token.Name)
+
+	var count int64
+	require.NoError(t, model.LOG_DB.Model(&model.Log{}).
+		Where("user_id = ? AND type = ? AND other LIKE ?", env.userId, model.LogTypeManage, "%workload_identity.token_issue%").
+		Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	for name, form := range map[string]url.Values{
+		"no matching rule": workloadExchangeForm(env.sign(t, jwt.MapClaims{"repository": "acme/web"})),
+		"wrong audience":   workloadExchangeForm(env.sign(t, jwt.MapClaims{"aud": "other"})),
+		"unknown issuer":   workloadExchangeForm(env.sign(t, jwt.MapClaims{"iss": "https://unknown.example.com"})),
+	} {
+		recorder, body := performWorkloadTokenExchange(t, form)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, name)
+		assert.Equal(t, "invalid_grant", body["error"], name)
+	}
+
+	form := workloadExchangeForm(env.sign(t, nil))
+	form.Set("grant_type", "client_credentials")
+	_, body = performWorkloadTokenExchange(t, form)
+	assert.Equal(t, "unsupported_grant_type", body["error"])
+	form = workloadExchangeForm(env.sign(t, nil))
+	form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:saml2")
+	_, body = performWorkloadTokenExchange(t, form)
+	assert.Equal(t, "invalid_request", body["error"])
+}
+
+func TestWorkloadTokenExchangesShareTheRuleQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	env := setupWorkloadIdentityTest(t)
+	grantedQuota := func() int {
+		var total int
+		require.NoError(t, model.DB.Model(&model.Token{}).Where("workload_issuer_id = ?", env.issuer.Id).
+			Select("COALESCE(SUM(remain_quota + used_quota), 0)").Scan(&total).Error)
+		return total
+	}
+
+	recorder, body := performWorkloadTokenExchange(t, workloadExchangeForm(env.sign(t, nil)))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	first, err := model.ValidateUserToken(strings.TrimPrefix(body["access_token"].(string), "sk-"))
+	require.NoError(t, err)
+	assert.Equal(t, 5000, first.RemainQuota)
+
+	recorder, body = performWorkloadTokenExchange(t, workloadExchangeForm(env.sign(t, jwt.MapClaims{"jti": "second"})))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "exchanging again must not mint another full quota")
+	assert.Equal(t, "invalid_grant", body["error"])
+	_, err = service.AuthenticateWorkloadJWT(t.Context(), env.sign(t, jwt.MapClaims{"jti": "direct"}), "127.0.0.1")
+	assert.ErrorIs(t, err, model.ErrWorkloadQuotaExhausted, "direct use draws from the same budget")
+	assert.Equal(t, 5000, grantedQuota())
+
+	// 第一个令牌只领用 3000 时，再次交换只能得到剩余的 2000
+	require.NoError(t, model.DB.Model(&model.Token{}).Where("id = ?", first.Id).Update("remain_quota", 3000).Error)
+	recorder, body = performWorkloadTokenExchange(t, workloadExchangeForm(env.sign(t, jwt.MapClaims{"jti": "third"})))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	second, err := model.ValidateUserToken(strings.TrimPrefix(body["access_token"].(string), "sk-"))
+	require.NoError(t, err)
+	assert.Equal(t, 2000, second.RemainQuota)
+	assert.Equal(t, 5000, grantedQuota())
+
+	// 其它工作负载有各自的额度上限
+	recorder, _ = performWorkloadTokenExchange(t, workloadExchangeForm(env.sign(t, jwt.MapClaims{"sub": "repo:acme/api:ref:refs/heads/dev"})))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+}
+
+func TestWorkloadJWTDirectBearerReusesTokenUntilRevoked(t *testing.T) {
+	env := setupWorkloadIdentityTest(t)
+	raw := env.sign(t, jwt.MapClaims{"exp": time.Now().Add(5 * time.Minute).Unix()})
+	require.True(t, service.IsWorkloadJWT(raw))
+
+	token, err := service.AuthenticateWorkloadJWT(t.Context(), raw, "127.0.0.1")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, token.ExpiredTime, time.Now().Add(5*time.Minute).Unix(), "the JWT exp caps the key lifetime")
+	again, err := service.AuthenticateWorkloadJWT(t.Context(), raw, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, token.Id, again.Id, "later requests with the same JWT reuse its key and quota")
+
+	// 同一工作负载轮换 JWT 后沿用同一个令牌与剩余额度，并续期
+	require.NoError(t, model.DB.Model(&model.Token{}).Where("id = ?", token.Id).Update("remain_quota", 4000).Error)
+	rotated := env.sign(t, jwt.MapClaims{"exp": time.Now().Add(8 * time.Minute).Unix(), "jti": "rotated"})
+	renewed, err := service.AuthenticateWorkloadJWT(t.Context(), rotated, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, token.Id, renewed.Id, "a new JWT for the same subject reuses the workload's token")
+	assert.Equal(t, 4000, renewed.RemainQuota, "the rule quota is a per-workload cap, not per JWT")
+	assert.Greater(t, renewed.ExpiredTime, token.ExpiredTime)
+	other, err := service.AuthenticateWorkloadJWT(t.Context(), env.sign(t, jwt.MapClaims{"sub": "repo:acme/api:ref:refs/heads/dev"}), "127.0.0.1")
+	require.NoError(t, err)
+	assert.NotEqual(t, token.Id, other.Id, "different subjects get their own tokens")
+	var issued int64
+	require.NoError(t, model.DB.Model(&model.Token{}).Where("workload_issuer_id = ?", env.issuer.Id).Count(&issued).Error)
+	assert.EqualValues(t, 2, issued)
+
+	// 修改签发方会吊销已签发的令牌，关闭直连后同一 JWT 不能再直接使用
+	env.issuer.AllowDirect = false
+	require.NoError(t, model.UpdateWorkloadIdentityIssuer(env.issuer))
+	_, err = model.GetTokenById(token.Id)
+	assert.Error(t, err)
+	_, err = service.AuthenticateWorkloadJWT(t.Context(), raw, "127.0.0.1")
+	assert.ErrorIs(t, err, service.ErrWorkloadIdentityDenied)
+
+	// 过期的工作负载令牌由定时清理物理删除，普通令牌不受影响
+	expired := &model.Token{UserId: env.userId, Name: "expired", ExpiredTime: time.Now().Add(-time.Minute).Unix(), WorkloadIssuerId: env.issuer.Id}
+	expired.SetKey("expired-workload-key")
+	regular := &model.Token{UserId: env.userId, Name: "regular", ExpiredTime: time.Now().Add(-time.Minute).Unix()}
+	regular.SetKey("expired-regular-key")
+	require.NoError(t, expired.Insert())
+	require.NoError(t, regular.Insert())
+	require.NoError(t, model.DeleteExpiredWorkloadTokens(time.Now().Unix()))
+	var count int64
+	require.NoError(t, model.DB.Unscoped().Model(&model.Token{}).Where("id = ?", expired.Id).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, model.DB.Model(&model.Token{}).Where("id = ?", regular.Id).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
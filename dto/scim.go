@@ -0,0 +1,111 @@
+package dto
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// SCIM 2.0 (RFC 7643 / RFC 7644) 资源与消息结构
+const (
+	ScimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	ScimContentType = "application/scim+json"
+)
+
+// scimType 错误细分（RFC 7644 §3.12）
+const (
+	ScimErrorInvalidFilter = "invalidFilter"
+	ScimErrorInvalidSyntax = "invalidSyntax"
+	ScimErrorInvalidPath   = "invalidPath"
+	ScimErrorInvalidValue  = "invalidValue"
+	ScimErrorNoTarget      = "noTarget"
+	ScimErrorUniqueness    = "uniqueness"
+	ScimErrorMutability    = "mutability"
+)
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// ScimMemberRef 同时用于组成员（members）与用户所属组（groups）。
+type ScimMemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type ScimUser struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	ExternalId  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *ScimName        `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Emails      []ScimMultiValue `json:"emails,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+	Groups      []ScimMemberRef  `json:"groups,omitempty"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+type ScimGroup struct {
+	Schemas     []string        `json:"schemas"`
+	Id          string          `json:"id,omitempty"`
+	ExternalId  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []ScimMemberRef `json:"members,omitempty"`
+	Meta        *ScimMeta       `json:"meta,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewScimError(status int, scimType string, detail string) ScimError {
+	return ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
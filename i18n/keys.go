@@ -58,6 +58,7 @@ const (
 	MsgTokenAutoGroupsTooMany    = "token.auto_groups_too_many"
 	MsgTokenAutoGroupsDuplicate  = "token.auto_groups_duplicate"
 	MsgTokenAutoGroupsInvalid    = "token.auto_groups_invalid"
+	MsgTokenKeyNotRevealable     = "token.key_not_revealable"
 )
 
 // Redemption related messages
@@ -120,6 +121,7 @@ const (
 	MsgUserTelegramNotBound          = "user.telegram_not_bound"
 	MsgUserLinuxDOIdEmpty            = "user.linux_do_id_empty"
 	MsgUserQuotaChangeZero           = "user.quota_change_zero"
+	MsgUserFieldLockedByProvider     = "user.field_locked_by_provider"
 )
 
 // Quota related messages
@@ -183,6 +185,9 @@ const (
 	MsgChannelNoValidUpstream    = "channel.no_valid_upstream"
 	MsgChannelUpstreamSaturated  = "channel.upstream_saturated"
 	MsgChannelGetAvailableFailed = "channel.get_available_failed"
+	MsgChannelReplayBodyRequired = "channel.replay_body_required"
+	MsgChannelReplayLogNotFound  = "channel.replay_log_not_found"
+	MsgChannelReplayNoChannel    = "channel.replay_no_channel"
 )
 
 // Model related messages
@@ -243,17 +248,18 @@ const (
 
 // Setting related messages
 const (
-	MsgSettingInvalidType      = "setting.invalid_type"
-	MsgSettingWebhookEmpty     = "setting.webhook_empty"
-	MsgSettingWebhookInvalid   = "setting.webhook_invalid"
-	MsgSettingEmailInvalid     = "setting.email_invalid"
-	MsgSettingBarkUrlEmpty     = "setting.bark_url_empty"
-	MsgSettingBarkUrlInvalid   = "setting.bark_url_invalid"
-	MsgSettingGotifyUrlEmpty   = "setting.gotify_url_empty"
-	MsgSettingGotifyTokenEmpty = "setting.gotify_token_empty"
-	MsgSettingGotifyUrlInvalid = "setting.gotify_url_invalid"
-	MsgSettingUrlMustHttp      = "setting.url_must_http"
-	MsgSettingSaved            = "setting.saved"
+	MsgSettingInvalidType         = "setting.invalid_type"
+	MsgSettingWebhookEmpty        = "setting.webhook_empty"
+	MsgSettingWebhookInvalid      = "setting.webhook_invalid"
+	MsgSettingEmailInvalid        = "setting.email_invalid"
+	MsgSettingBarkUrlEmpty        = "setting.bark_url_empty"
+	MsgSettingBarkUrlInvalid      = "setting.bark_url_invalid"
+	MsgSettingGotifyUrlEmpty      = "setting.gotify_url_empty"
+	MsgSettingGotifyTokenEmpty    = "setting.gotify_token_empty"
+	MsgSettingGotifyUrlInvalid    = "setting.gotify_url_invalid"
+	MsgSettingTelegramConfigEmpty = "setting.telegram_config_empty"
+	MsgSettingUrlMustHttp         = "setting.url_must_http"
+	MsgSettingSaved               = "setting.saved"
 )
 
 // Deployment related messages (io.net)
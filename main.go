@@ -29,6 +29,7 @@ import (
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/QuantumNous/new-api/service/logsink"
 	_ "github.com/QuantumNous/new-api/setting/performance_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
@@ -143,6 +144,11 @@ func main() {
 		return a
 	}
 
+	// 消费、错误与任务计费日志外发到 Kafka / syslog / HTTP / S3，配置见 LOG_SINKS_CONFIG
+	if err := logsink.Init(); err != nil {
+		common.FatalLog("failed to initialize log sinks: " + err.Error())
+	}
+
 	// Register the periodic channel test, upstream model update, and async task
 	// polling (Midjourney / Suno / video) jobs as scheduled system tasks
 	// (DB-lease dedup across masters + run history), then start the runner that
@@ -215,6 +221,26 @@ func main() {
 		}
 	}()
 
+	// 可选的 TLS 监听，配置客户端 CA 后可使用 mTLS 客户端证书代替令牌密钥
+	tlsListener, err := common.LoadTLSListenerConfig()
+	if err != nil {
+		common.FatalLog("failed to load TLS listener config: " + err.Error())
+	}
+	var tlsSrv *http.Server
+	if tlsListener != nil {
+		tlsSrv = &http.Server{
+			Addr:      ":" + tlsListener.Port,
+			Handler:   server,
+			TLSConfig: tlsListener.TLSConfig,
+		}
+		go func() {
+			if err := tlsSrv.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				common.FatalLog("failed to start HTTPS server: " + err.Error())
+			}
+		}()
+		common.SysLog("HTTPS server listening on port " + tlsListener.Port)
+	}
+
 	time.Sleep(100 * time.Millisecond)
 
 	common.LogStartupSuccess(startTime, port)
@@ -231,6 +257,13 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		common.SysError(fmt.Sprintf("server forced to shutdown: %v", err))
 	}
+	if tlsSrv != nil {
+		if err := tlsSrv.Shutdown(ctx); err != nil {
+			common.SysError(fmt.Sprintf("HTTPS server forced to shutdown: %v", err))
+		}
+	}
+	// 请求已全部结束，投递队列中剩余的日志；超时未送达的批次落盘，下次启动补发
+	logsink.Shutdown(ctx)
 	// 内存中的看板数据保存入库，避免重启丢失未落库数据 (issue #5679)
 	if common.DataExportEnabled {
 		model.SaveQuotaDataCache()
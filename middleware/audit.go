@@ -56,6 +56,11 @@ var auditRouteActions = map[string]string{
 	"PUT /api/custom-oauth-provider/:id":    "custom_oauth.update",
 	"DELETE /api/custom-oauth-provider/:id": "custom_oauth.delete",
 
+	// 工作负载身份签发方（root）
+	"POST /api/workload-identity/issuer/":      "workload_identity.create",
+	"PUT /api/workload-identity/issuer/:id":    "workload_identity.update",
+	"DELETE /api/workload-identity/issuer/:id": "workload_identity.delete",
+
 	// 性能/缓存（root）
 	"DELETE /api/performance/disk_cache": "performance.clear_disk_cache",
 	"POST /api/performance/gc":           "performance.gc",
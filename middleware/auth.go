@@ -314,7 +314,9 @@ func TokenAuthReadOnly() func(c *gin.Context) {
 
 		// TokenAuthReadOnly must keep allowing other token states to query read-only
 		// data, such as token usage logs; only explicitly disabled tokens are denied.
-		if token.Status == common.TokenStatusDisabled {
+		// Tokens pinned to a client certificate still require it.
+		if token.Status == common.TokenStatusDisabled ||
+			(token.ClientCertRequired && !token.MatchesClientCert(common.ClientCertIdentities(common.VerifiedClientCertificate(c.Request.TLS)))) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": common.TranslateMessage(c, i18n.MsgTokenStatusUnavailable),
@@ -322,6 +324,20 @@ func TokenAuthReadOnly() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
+		if token.SigningRequired {
+			reason, err := verifyRequestSignature(c, token)
+			if err != nil {
+				reason = "读取请求体失败: " + err.Error()
+			}
+			if reason != "" {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"message": reason,
+				})
+				c.Abort()
+				return
+			}
+		}
 
 		userCache, err := model.GetUserCache(token.UserId)
 		if err != nil {
@@ -350,6 +366,8 @@ func TokenAuthReadOnly() func(c *gin.Context) {
 }
 
 func TokenAuth() func(c *gin.Context) {
+	// 隔离令牌的限速使用内存限流器，可重复初始化
+	inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
 	return func(c *gin.Context) {
 		// 先检测是否为ws
 		if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
@@ -393,6 +411,8 @@ func TokenAuth() func(c *gin.Context) {
 		if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
 			key = strings.TrimSpace(key[7:])
 		}
+		// 工作负载身份 JWT 整体作为凭据，不做 sk- 前缀与渠道后缀解析
+		workloadJWT := service.IsWorkloadJWT(key)
 		if key == "" || key == "midjourney-proxy" {
 			key = c.Request.Header.Get("mj-api-secret")
 			if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
@@ -401,12 +421,22 @@ func TokenAuth() func(c *gin.Context) {
 			key = strings.TrimPrefix(key, "sk-")
 			parts = strings.Split(key, "-")
 			key = parts[0]
-		} else {
+		} else if !workloadJWT {
 			key = strings.TrimPrefix(key, "sk-")
 			parts = strings.Split(key, "-")
 			key = parts[0]
 		}
-		token, err := model.ValidateUserToken(key)
+		// 未携带密钥时使用已校验的客户端证书（mTLS）鉴权
+		clientCertIdentities := common.ClientCertIdentities(common.VerifiedClientCertificate(c.Request.TLS))
+		var token *model.Token
+		var err error
+		if workloadJWT {
+			token, err = service.AuthenticateWorkloadJWT(c, key, c.ClientIP())
+		} else if key == "" && len(clientCertIdentities) > 0 {
+			token, err = model.ValidateClientCertToken(clientCertIdentities)
+		} else {
+			token, err = model.ValidateUserToken(key)
+		}
 		if token != nil {
 			id := c.GetInt("id")
 			if id == 0 {
@@ -424,6 +454,25 @@ func TokenAuth() func(c *gin.Context) {
 			}
 			return
 		}
+		if token.ClientCertRequired && !token.MatchesClientCert(clientCertIdentities) {
+			abortWithOpenAiMessage(c, http.StatusUnauthorized, "该令牌要求出示绑定的客户端证书", types.ErrorCodeAccessDenied)
+			return
+		}
+		if token.SigningRequired {
+			reason, err := verifyRequestSignature(c, token)
+			if err != nil {
+				if common.IsRequestBodyTooLargeError(err) {
+					abortWithOpenAiMessage(c, http.StatusRequestEntityTooLarge, err.Error())
+				} else {
+					abortWithOpenAiMessage(c, http.StatusBadRequest, "读取请求体失败: "+err.Error())
+				}
+				return
+			}
+			if reason != "" {
+				abortWithOpenAiMessage(c, http.StatusUnauthorized, reason, types.ErrorCodeAccessDenied)
+				return
+			}
+		}
 
 		allowIps := token.GetIpLimits()
 		if len(allowIps) > 0 {
@@ -454,13 +503,18 @@ func TokenAuth() func(c *gin.Context) {
 			return
 		}
 
+		// 仅对明文密钥鉴权做泄露检测，工作负载身份与客户端证书本身已绑定调用方
+		if !workloadJWT && key != "" {
+			service.ObserveTokenRequest(c, token)
+		}
+
 		userCache.WriteContext(c)
 
 		userGroup := userCache.Group
 		tokenGroup := token.Group
 		if tokenGroup != "" {
 			// check common.UserUsableGroups[userGroup]
-			if _, ok := service.GetUserUsableGroups(userGroup)[tokenGroup]; !ok {
+			if _, ok := service.GetUserUsableGroups(userGroup, userCache.GetUsableGroups()...)[tokenGroup]; !ok {
 				abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("无权访问 %s 分组", tokenGroup))
 				return
 			}
@@ -479,6 +533,9 @@ func TokenAuth() func(c *gin.Context) {
 		if err != nil {
 			return
 		}
+		if token.Quarantined && !applyTokenQuarantine(c, token) {
+			return
+		}
 		c.Next()
 	}
 }
@@ -503,6 +560,22 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	if token.EndUserId != "" {
+		common.SetContextKey(c, constant.ContextKeyTokenEndUserId, token.EndUserId)
+		common.SetContextKey(c, constant.ContextKeyEndUserId, token.EndUserId)
+	}
+	if token.ParentTokenId != 0 {
+		common.SetContextKey(c, constant.ContextKeyTokenParentId, token.ParentTokenId)
+	}
+	if token.EndUserHeader != "" {
+		common.SetContextKey(c, constant.ContextKeyTokenEndUserHeader, token.EndUserHeader)
+	}
+	if token.EndUserRateLimit > 0 {
+		common.SetContextKey(c, constant.ContextKeyTokenEndUserRateLimit, token.EndUserRateLimit)
+	}
+	if token.EndUserDailyQuota > 0 {
+		common.SetContextKey(c, constant.ContextKeyTokenEndUserDailyQuota, token.EndUserDailyQuota)
+	}
 	if token.AutoGroups != "" {
 		autoGroups, err := token.GetAutoGroups()
 		if err != nil {
@@ -513,6 +586,9 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 			common.SetContextKey(c, constant.ContextKeyTokenAutoGroups, autoGroups)
 		}
 	}
+	if err := setupLogLabels(c, token); err != nil {
+		return err
+	}
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
@@ -524,3 +600,23 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	return nil
 }
+
+// setupLogLabels 合并令牌标签与 X-NewAPI-Tags 请求头中的标签，写入上下文供使用日志记录
+func setupLogLabels(c *gin.Context, token *model.Token) error {
+	tokenLabels, err := token.GetLabels()
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to parse labels for token %d: %v", token.Id, err))
+	}
+	var requestLabels map[string]string
+	if c.Request != nil {
+		requestLabels, err = model.ParseRequestLabels(c.Request.Header.Get(model.LabelsHeader))
+		if err != nil {
+			abortWithOpenAiMessage(c, http.StatusBadRequest, err.Error())
+			return err
+		}
+	}
+	if labels := model.MergeLogLabels(tokenLabels, requestLabels); labels != "" {
+		common.SetContextKey(c, constant.ContextKeyLogLabels, labels)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testClientCertIdentity = "san:spiffe://mesh.local/ns/billing/sa/worker"
+
+func setupTokenAuthTest(t *testing.T) *model.User {
+	t.Helper()
+	previousDB := model.DB
+	previousLogDB := model.LOG_DB
+	previousType := common.MainDatabaseType()
+	previousRedis := common.RedisEnabled
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Token{}))
+	model.DB = db
+	common.SetMainDatabaseType(common.DatabaseTypeSQLite)
+	common.RedisEnabled = false
+	// 令牌按 key 列查询，需要先按数据库类型初始化列名引用
+	t.Setenv("LOG_SQL_DSN", "")
+	require.NoError(t, model.InitLogDB())
+	t.Cleanup(func() {
+		model.DB = previousDB
+		model.LOG_DB = previousLogDB
+		common.SetMainDatabaseType(previousType)
+		common.RedisEnabled = previousRedis
+	})
+
+	user := &model.User{
+		Username: "mtls-user", Password: "password-placeholder", Role: common.RoleCommonUser,
+		Status: common.UserStatusEnabled, Group: "default", AffCode: "middleware-aff-mtls",
+	}
+	require.NoError(t, db.Create(user).Error)
+	return user
+}
+
+func createClientCertToken(t *testing.T, userId int, key string, clientCert string, required bool) *model.Token {
+	t.Helper()
+	token := &model.Token{
+		UserId: userId, Name: key, Status: common.TokenStatusEnabled, ExpiredTime: -1,
+		UnlimitedQuota: true, ClientCert: clientCert, ClientCertRequired: required,
+	}
+	token.SetKey(key)
+	require.NoError(t, model.DB.Create(token).Error)
+	return token
+}
+
+func testClientCertConnectionState(t *testing.T, uri string) *tls.ConnectionState {
+	t.Helper()
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	leaf := &x509.Certificate{
+		Raw:     []byte("client-cert-" + uri),
+		Subject: pkix.Name{CommonName: "billing-worker"},
+		URIs:    []*url.URL{parsed},
+	}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+}
+
+func performClientCertRequest(key string, state *tls.ConnectionState) (*httptest.ResponseRecorder, int) {
+	router := gin.New()
+	tokenId := 0
+	router.GET("/v1/models", TokenAuth(), func(c *gin.Context) {
+		tokenId = c.GetInt("token_id")
+		c.Status(http.StatusOK)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer sk-"+key)
+	}
+	request.TLS = state
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder, tokenId
+}
+
+func TestTokenAuthAcceptsVerifiedClientCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := setupTokenAuthTest(t)
+	token := createClientCertToken(t, user.Id, "mtlsboundkey0001", testClientCertIdentity, false)
+
+	recorder, tokenId := performClientCertRequest("", testClientCertConnectionState(t, "spiffe://mesh.local/ns/billing/sa/worker"))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, token.Id, tokenId, "a verified client certificate authenticates without a bearer key")
+
+	recorder, _ = performClientCertRequest("", testClientCertConnectionState(t, "spiffe://mesh.local/ns/other/sa/worker"))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "certificates not bound to any token are rejected")
+
+	recorder, _ = performClientCertRequest("", &tls.ConnectionState{})
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "unverified connections do not authenticate")
+
+	recorder, tokenId = performClientCertRequest("mtlsboundkey0001", nil)
+	require.Equal(t, http.StatusOK, recorder.Code, "unpinned tokens keep working with their key alone")
+	assert.Equal(t, token.Id, tokenId)
+}
+
+func TestTokenAuthEnforcesPinnedClientCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := setupTokenAuthTest(t)
+	token := createClientCertToken(t, user.Id, "mtlspinnedkey001", testClientCertIdentity, true)
+
+	recorder, _ := performClientCertRequest("mtlspinnedkey001", nil)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "access_denied")
+
+	recorder, _ = performClientCertRequest("mtlspinnedkey001", testClientCertConnectionState(t, "spiffe://mesh.local/ns/other/sa/worker"))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "a different certificate does not satisfy the pin")
+
+	recorder, tokenId := performClientCertRequest("mtlspinnedkey001", testClientCertConnectionState(t, "spiffe://mesh.local/ns/billing/sa/worker"))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, token.Id, tokenId)
+}
+
+func TestClientCertOnlyRequestsIgnoreChildTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := setupTokenAuthTest(t)
+	// The child row is older than its parent so that lookup order alone cannot pick the parent
+	child := createClientCertToken(t, user.Id, "mtlschildkey0001", testClientCertIdentity, true)
+	parent := createClientCertToken(t, user.Id, "mtlsparentkey001", testClientCertIdentity, true)
+	require.NoError(t, model.DB.Model(child).Update("parent_token_id", parent.Id).Error)
+	state := testClientCertConnectionState(t, "spiffe://mesh.local/ns/billing/sa/worker")
+
+	recorder, tokenId := performClientCertRequest("", state)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, parent.Id, tokenId, "certificate-only requests resolve to the parent")
+
+	recorder, _ = performClientCertRequest("mtlschildkey0001", nil)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "the inherited pin applies to the child key")
+
+	recorder, tokenId = performClientCertRequest("mtlschildkey0001", state)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, child.Id, tokenId)
+}
@@ -33,6 +33,7 @@ type ModelRequest struct {
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var channel *model.Channel
+		var split *service.TrafficSplitAssignment
 		channelId, ok := common.GetContextKey(c, constant.ContextKeyTokenSpecificChannelId)
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
 		if err != nil {
@@ -93,7 +94,7 @@ func Distribute() func(c *gin.Context) {
 						return
 					}
 					if playgroundRequest.Group != "" {
-						if !service.GroupInUserUsableGroups(usingGroup, playgroundRequest.Group) && playgroundRequest.Group != usingGroup {
+						if !service.GroupInUserUsableGroups(usingGroup, playgroundRequest.Group, service.GetContextUserUsableGroups(c)...) && playgroundRequest.Group != usingGroup {
 							abortWithOpenAiMessage(c, http.StatusForbidden, i18n.T(c, i18n.MsgDistributorGroupAccessDenied))
 							return
 						}
@@ -102,33 +103,44 @@ func Distribute() func(c *gin.Context) {
 					}
 				}
 
-				if preferredChannelID, found := service.GetPreferredChannelByAffinity(c, modelRequest.Model, usingGroup); found {
-					affinityUsable := false
-					preferred, err := model.CacheGetChannel(preferredChannelID)
-					if err == nil && preferred != nil && preferred.Status == common.ChannelStatusEnabled &&
-						channelSupportsRequestPath(preferred, c.Request.URL.Path, modelRequest.Model) {
-						if usingGroup == "auto" {
-							userGroup := common.GetContextKeyString(c, constant.ContextKeyUserGroup)
-							autoGroups := service.GetRequestAutoGroups(c, userGroup)
-							for _, g := range autoGroups {
-								if model.IsChannelEnabledForGroupModel(g, modelRequest.Model, preferred.Id) {
-									selectGroup = g
-									common.SetContextKey(c, constant.ContextKeyAutoGroup, g)
-									channel = preferred
-									affinityUsable = true
-									service.MarkChannelAffinityUsed(c, g, preferred.Id)
-									break
+				split = service.AssignTrafficSplit(c, modelRequest.Model, usingGroup)
+				if split.IsCanary() && split.CanaryChannelId > 0 {
+					channel = selectTrafficSplitChannel(c, split, modelRequest.Model, usingGroup)
+					if channel == nil {
+						split.Fallback("canary channel unavailable")
+					}
+				}
+
+				// canary 渠道不参与渠道亲和，避免回滚后亲和缓存仍把用户带到 canary 渠道
+				if channel == nil {
+					if preferredChannelID, found := service.GetPreferredChannelByAffinity(c, modelRequest.Model, usingGroup); found {
+						affinityUsable := false
+						preferred, err := model.CacheGetChannel(preferredChannelID)
+						if err == nil && preferred != nil && preferred.Status == common.ChannelStatusEnabled &&
+							channelSupportsRequestPath(preferred, c.Request.URL.Path, modelRequest.Model) {
+							if usingGroup == "auto" {
+								userGroup := common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+								autoGroups := service.GetRequestAutoGroups(c, userGroup)
+								for _, g := range autoGroups {
+									if model.IsChannelEnabledForGroupModel(g, modelRequest.Model, preferred.Id) {
+										selectGroup = g
+										common.SetContextKey(c, constant.ContextKeyAutoGroup, g)
+										channel = preferred
+										affinityUsable = true
+										service.MarkChannelAffinityUsed(c, g, preferred.Id)
+										break
+									}
 								}
+							} else if model.IsChannelEnabledForGroupModel(usingGroup, modelRequest.Model, preferred.Id) {
+								channel = preferred
+								selectGroup = usingGroup
+								affinityUsable = true
+								service.MarkChannelAffinityUsed(c, usingGroup, preferred.Id)
 							}
-						} else if model.IsChannelEnabledForGroupModel(usingGroup, modelRequest.Model, preferred.Id) {
-							channel = preferred
-							selectGroup = usingGroup
-							affinityUsable = true
-							service.MarkChannelAffinityUsed(c, usingGroup, preferred.Id)
 						}
-					}
-					if !affinityUsable && !service.ShouldKeepChannelAffinityOnChannelDisabled() {
-						service.ClearCurrentChannelAffinityCache(c)
+						if !affinityUsable && !service.ShouldKeepChannelAffinityOnChannelDisabled() {
+							service.ClearCurrentChannelAffinityCache(c)
+						}
 					}
 				}
 
@@ -163,6 +175,7 @@ func Distribute() func(c *gin.Context) {
 		}
 		common.SetContextKey(c, constant.ContextKeyRequestStartTime, time.Now())
 		SetupContextForSelectedChannel(c, channel, modelRequest.Model)
+		service.ApplyTrafficSplitModelMapping(c, split, modelRequest.Model)
 		c.Next()
 		if channel != nil && c.Writer != nil && c.Writer.Status() < http.StatusBadRequest {
 			service.RecordChannelAffinity(c, channel.Id)
@@ -170,6 +183,34 @@ func Distribute() func(c *gin.Context) {
 	}
 }
 
+// selectTrafficSplitChannel 校验 canary 渠道能否服务本次请求，条件与渠道亲和相同；
+// 渠道的可用模型包含请求模型或 canary 模型均可。auto 分组时按顺序选出 canary 渠道所在的第一个分组
+func selectTrafficSplitChannel(c *gin.Context, split *service.TrafficSplitAssignment, modelName string, usingGroup string) *model.Channel {
+	canary, err := model.CacheGetChannel(split.CanaryChannelId)
+	if err != nil || canary == nil || canary.Status != common.ChannelStatusEnabled ||
+		!channelSupportsRequestPath(canary, c.Request.URL.Path, modelName) {
+		return nil
+	}
+	servesGroup := func(group string) bool {
+		return model.IsChannelEnabledForGroupModel(group, modelName, canary.Id) ||
+			(split.CanaryModel != "" && model.IsChannelEnabledForGroupModel(group, split.CanaryModel, canary.Id))
+	}
+	if usingGroup != "auto" {
+		if servesGroup(usingGroup) {
+			return canary
+		}
+		return nil
+	}
+	userGroup := common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+	for _, g := range service.GetRequestAutoGroups(c, userGroup) {
+		if servesGroup(g) {
+			common.SetContextKey(c, constant.ContextKeyAutoGroup, g)
+			return canary
+		}
+	}
+	return nil
+}
+
 // channelSupportsRequestPath reports whether a channel can serve the request path.
 // Only Advanced Custom (type 58) channels are path-checked; all other channel types
 // always pass. A type-58 channel is usable only when one of its routes matches.
@@ -336,6 +377,21 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 		if _, ok := c.Get("relay_mode"); !ok {
 			c.Set("relay_mode", relayMode)
 		}
+	} else if c.Request.URL.Path == "/v1/messages/batches" {
+		// Claude Message Batches：批次内所有请求共用一个模型，取第一条请求的模型选渠道
+		storage, err := common.GetBodyStorage(c)
+		if err != nil {
+			return nil, false, err
+		}
+		requestBody, err := storage.Bytes()
+		if err != nil {
+			return nil, false, err
+		}
+		if !gjson.ValidBytes(requestBody) {
+			return nil, false, errors.New(i18n.T(c, i18n.MsgDistributorInvalidRequest, map[string]any{"Error": "invalid JSON request body"}))
+		}
+		modelRequest.Model = gjson.GetBytes(requestBody, "requests.0.params.model").String()
+		c.Set("platform", string(constant.TaskPlatformClaudeBatch))
 	} else if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models/") || strings.HasPrefix(c.Request.URL.Path, "/v1/models/") {
 		// Gemini API 路径处理: /v1beta/models/gemini-2.0-flash:generateContent
 		relayMode := relayconstant.RelayModeGemini
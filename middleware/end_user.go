@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	maxEndUserIdLength           = 128
+	endUserRateLimitWindow int64 = 60
+)
+
+// endUserBodyFields 依次为 OpenAI 的 user、Claude 的 metadata.user_id 与 OpenAI 的 safety_identifier
+var endUserBodyFields = []string{"user", "metadata.user_id", "safety_identifier"}
+
+func redisEndUserRateLimitKey(scope int, endUserId string) string {
+	return fmt.Sprintf("%s:enduser:%d:%s", redisRateLimitNamespace, scope, endUserId)
+}
+
+// EndUserLimit 识别请求所属的终端用户，并执行令牌上配置的终端用户每分钟请求数与每日额度限制。
+// 必须在 TokenAuth 与 Distribute 之后使用，请求体已由 Distribute 缓存；未识别出终端用户的请求不受限制。
+func EndUserLimit() func(c *gin.Context) {
+	// It's safe to call multi times.
+	inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+	return func(c *gin.Context) {
+		endUserId := resolveEndUserId(c)
+		if endUserId == "" {
+			c.Next()
+			return
+		}
+		common.SetContextKey(c, constant.ContextKeyEndUserId, endUserId)
+		scope := model.EndUserLimitScope(c)
+
+		if limit := common.GetContextKeyInt(c, constant.ContextKeyTokenEndUserRateLimit); limit > 0 {
+			if !takeEndUserRequest(c, scope, endUserId, limit) {
+				return
+			}
+		}
+		if dailyQuota := common.GetContextKeyInt(c, constant.ContextKeyTokenEndUserDailyQuota); dailyQuota > 0 {
+			used, err := model.GetEndUserDailyQuota(scope, endUserId)
+			if err != nil {
+				logger.LogError(c.Request.Context(), fmt.Sprintf("end user daily quota check failed: %v", err))
+				abortWithOpenAiMessage(c, http.StatusInternalServerError, "end_user_quota_check_failed")
+				return
+			}
+			if used >= dailyQuota {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests,
+					fmt.Sprintf("终端用户 %s 已达到今日额度上限", endUserId), types.ErrorCodeEndUserQuotaExceeded)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func takeEndUserRequest(c *gin.Context, scope int, endUserId string, limit int) bool {
+	message := fmt.Sprintf("终端用户 %s 请求过于频繁：每分钟最多请求 %d 次", endUserId, limit)
+	if !common.RedisEnabled {
+		key := fmt.Sprintf("EU:%d:%s", scope, endUserId)
+		if !inMemoryRateLimiter.Request(key, limit, endUserRateLimitWindow) {
+			c.Header("Retry-After", fmt.Sprint(endUserRateLimitWindow))
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, message, types.ErrorCodeEndUserRateLimited)
+			return false
+		}
+		return true
+	}
+	allowed, _, ttlSeconds, err := redisFixedWindowTake(c.Request.Context(), redisEndUserRateLimitKey(scope, endUserId), limit, endUserRateLimitWindow)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("end user rate limit check failed: %v", err))
+		abortWithOpenAiMessage(c, http.StatusInternalServerError, "end_user_rate_limit_check_failed")
+		return false
+	}
+	if !allowed {
+		if ttlSeconds > 0 {
+			c.Header("Retry-After", fmt.Sprint(ttlSeconds))
+		}
+		abortWithOpenAiMessage(c, http.StatusTooManyRequests, message, types.ErrorCodeEndUserRateLimited)
+		return false
+	}
+	return true
+}
+
+// resolveEndUserId 子令牌绑定的终端用户优先，其次为令牌配置的请求头，最后读取 JSON 请求体中的标识字段
+func resolveEndUserId(c *gin.Context) string {
+	if endUserId := common.GetContextKeyString(c, constant.ContextKeyTokenEndUserId); endUserId != "" {
+		return endUserId
+	}
+	if header := common.GetContextKeyString(c, constant.ContextKeyTokenEndUserHeader); header != "" {
+		if endUserId := normalizeEndUserId(c.GetHeader(header)); endUserId != "" {
+			return endUserId
+		}
+	}
+	if !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+	return normalizeEndUserId(getEndUserIdFromJSONBody(c))
+}
+
+func getEndUserIdFromJSONBody(c *gin.Context) string {
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		if _, seekErr := storage.Seek(0, io.SeekStart); seekErr == nil {
+			c.Request.Body = io.NopCloser(storage)
+		}
+	}()
+	requestBody, err := storage.Bytes()
+	if err != nil || !gjson.ValidBytes(requestBody) {
+		return ""
+	}
+	for _, value := range gjson.GetManyBytes(requestBody, endUserBodyFields...) {
+		if value.Type == gjson.String && strings.TrimSpace(value.String()) != "" {
+			return value.String()
+		}
+	}
+	return ""
+}
+
+func normalizeEndUserId(endUserId string) string {
+	endUserId = strings.TrimSpace(endUserId)
+	for len(endUserId) > maxEndUserIdLength {
+		_, size := utf8.DecodeLastRuneInString(endUserId)
+		endUserId = endUserId[:len(endUserId)-size]
+	}
+	return endUserId
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type endUserTokenContext struct {
+	tokenId     int
+	parentId    int
+	boundUser   string
+	header      string
+	rateLimit   int
+	dailyQuota  int
+	seenEndUser *string
+}
+
+func newEndUserRouter(token endUserTokenContext) *gin.Engine {
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		common.SetContextKey(c, constant.ContextKeyTokenId, token.tokenId)
+		if token.parentId > 0 {
+			common.SetContextKey(c, constant.ContextKeyTokenParentId, token.parentId)
+		}
+		if token.boundUser != "" {
+			common.SetContextKey(c, constant.ContextKeyTokenEndUserId, token.boundUser)
+		}
+		if token.header != "" {
+			common.SetContextKey(c, constant.ContextKeyTokenEndUserHeader, token.header)
+		}
+		if token.rateLimit > 0 {
+			common.SetContextKey(c, constant.ContextKeyTokenEndUserRateLimit, token.rateLimit)
+		}
+		if token.dailyQuota > 0 {
+			common.SetContextKey(c, constant.ContextKeyTokenEndUserDailyQuota, token.dailyQuota)
+		}
+	}, EndUserLimit(), func(c *gin.Context) {
+		if token.seenEndUser != nil {
+			*token.seenEndUser = common.GetContextKeyString(c, constant.ContextKeyEndUserId)
+		}
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+func performEndUserRequest(router http.Handler, body string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestEndUserLimitResolvesEndUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var seen string
+	router := newEndUserRouter(endUserTokenContext{tokenId: 1, header: "X-End-User", seenEndUser: &seen})
+
+	body := `{"model":"gpt-4o-mini","user":"openai-user"}`
+	recorder := performEndUserRequest(router, body, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "openai-user", seen)
+	assert.Equal(t, body, recorder.Body.String(), "the request body stays readable for the relay")
+
+	performEndUserRequest(router, `{"metadata":{"user_id":"claude-user"}}`, nil)
+	assert.Equal(t, "claude-user", seen)
+
+	performEndUserRequest(router, body, http.Header{"X-End-User": {"header-user"}})
+	assert.Equal(t, "header-user", seen, "the configured header takes precedence over the body")
+
+	performEndUserRequest(router, `{"user":123}`, nil)
+	assert.Empty(t, seen)
+
+	bound := newEndUserRouter(endUserTokenContext{tokenId: 2, boundUser: "child-user", header: "X-End-User", seenEndUser: &seen})
+	performEndUserRequest(bound, body, http.Header{"X-End-User": {"header-user"}})
+	assert.Equal(t, "child-user", seen, "a child token's bound end user cannot be overridden")
+}
+
+func TestEndUserLimitEnforcesPerEndUserRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useRateLimitMiniRedis(t)
+
+	parent := newEndUserRouter(endUserTokenContext{tokenId: 10, rateLimit: 2})
+	child := newEndUserRouter(endUserTokenContext{tokenId: 11, parentId: 10, boundUser: "alice", rateLimit: 2})
+	alice := `{"user":"alice"}`
+
+	assert.Equal(t, http.StatusOK, performEndUserRequest(parent, alice, nil).Code)
+	assert.Equal(t, http.StatusOK, performEndUserRequest(child, `{}`, nil).Code)
+	limited := performEndUserRequest(parent, alice, nil)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code, "child tokens share their parent's end-user counters")
+	assert.Contains(t, limited.Body.String(), "end_user_rate_limited")
+	assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, performEndUserRequest(parent, `{"user":"bob"}`, nil).Code)
+	assert.Equal(t, http.StatusOK, performEndUserRequest(parent, `{}`, nil).Code, "requests without an end user are not limited")
+}
+
+func TestEndUserLimitEnforcesDailyQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	useRateLimitMiniRedis(t)
+
+	router := newEndUserRouter(endUserTokenContext{tokenId: 20, dailyQuota: 500})
+	require.NoError(t, model.IncreaseEndUserDailyQuota(20, "alice", 499))
+	assert.Equal(t, http.StatusOK, performEndUserRequest(router, `{"user":"alice"}`, nil).Code)
+
+	require.NoError(t, model.IncreaseEndUserDailyQuota(20, "alice", 1))
+	exceeded := performEndUserRequest(router, `{"user":"alice"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, exceeded.Code)
+	assert.Contains(t, exceeded.Body.String(), "end_user_quota_exceeded")
+	assert.Equal(t, http.StatusOK, performEndUserRequest(router, `{"user":"bob"}`, nil).Code)
+}
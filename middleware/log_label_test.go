@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTokenContextWithTags(t *testing.T, token *model.Token, tags string) (*gin.Context, *httptest.ResponseRecorder, error) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if tags != "" {
+		c.Request.Header.Set(model.LabelsHeader, tags)
+	}
+	return c, recorder, SetupContextForToken(c, token)
+}
+
+func TestSetupContextForTokenMergesRequestTags(t *testing.T) {
+	token := &model.Token{Id: 1, UserId: 1}
+	require.NoError(t, token.SetLabels(map[string]string{"team": "search"}))
+
+	c, _, err := setupTokenContextWithTags(t, token, "project=alpha, team=ads")
+	require.NoError(t, err)
+	assert.Equal(t, `{"project":"alpha","team":"search"}`, common.GetContextKeyString(c, constant.ContextKeyLogLabels))
+
+	c, _, err = setupTokenContextWithTags(t, &model.Token{Id: 2, UserId: 1}, "")
+	require.NoError(t, err)
+	_, exists := common.GetContextKey(c, constant.ContextKeyLogLabels)
+	assert.False(t, exists)
+}
+
+func TestSetupContextForTokenRejectsInvalidTags(t *testing.T) {
+	c, recorder, err := setupTokenContextWithTags(t, &model.Token{Id: 1, UserId: 1}, "project")
+	require.Error(t, err)
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), model.LabelsHeader)
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/reqsign"
+	"github.com/gin-gonic/gin"
+)
+
+// 签名时间戳与服务器时间的最大偏差；随机数在两倍偏差内不可重复使用
+const requestSignatureMaxSkew = 5 * time.Minute
+
+const requestSignatureNonceNamespace = "reqsign:nonce"
+
+const maxRequestSignatureNonceLength = 128
+
+var requestSignatureNonces = &inMemoryNonceCache{store: make(map[string]int64)}
+
+// inMemoryNonceCache 未启用 Redis 时的节点本地随机数缓存
+type inMemoryNonceCache struct {
+	mutex     sync.Mutex
+	store     map[string]int64
+	lastSweep int64
+}
+
+func (cache *inMemoryNonceCache) add(key string, now time.Time, ttl time.Duration) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	nowUnix := now.Unix()
+	if nowUnix-cache.lastSweep >= int64(ttl/time.Second) {
+		for k, expireAt := range cache.store {
+			if expireAt <= nowUnix {
+				delete(cache.store, k)
+			}
+		}
+		cache.lastSweep = nowUnix
+	}
+	if expireAt, ok := cache.store[key]; ok && expireAt > nowUnix {
+		return false
+	}
+	cache.store[key] = nowUnix + int64(ttl/time.Second)
+	return true
+}
+
+// verifyRequestSignature 校验 reqsign 签名头，返回拒绝原因；通过时返回空字符串
+func verifyRequestSignature(c *gin.Context, token *model.Token) (string, error) {
+	if token.SigningSecret == "" {
+		return "令牌尚未生成签名密钥", nil
+	}
+	timestampHeader := c.GetHeader(reqsign.HeaderTimestamp)
+	nonce := c.GetHeader(reqsign.HeaderNonce)
+	signature := c.GetHeader(reqsign.HeaderSignature)
+	if timestampHeader == "" || nonce == "" || signature == "" {
+		return "该令牌要求请求签名", nil
+	}
+	if len(nonce) > maxRequestSignatureNonceLength {
+		return "请求签名随机数过长", nil
+	}
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return "请求签名时间戳无效", nil
+	}
+	now := time.Now()
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > requestSignatureMaxSkew || skew < -requestSignatureMaxSkew {
+		return "请求签名已过期或时间戳偏差过大", nil
+	}
+	bodyHash, err := requestBodyHash(c)
+	if err != nil {
+		return "", err
+	}
+	stringToSign := reqsign.StringToSign(c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, bodyHash)
+	if !reqsign.Verify(token.SigningSecret, stringToSign, signature) {
+		return "请求签名无效", nil
+	}
+	// 随机数在签名校验通过后才占用，伪造的请求不能抢占合法客户端的随机数
+	fresh, err := takeRequestSignatureNonce(token.Id, nonce, now)
+	if err != nil {
+		return "", err
+	}
+	if !fresh {
+		return "请求签名随机数已被使用", nil
+	}
+	return "", nil
+}
+
+func requestBodyHash(c *gin.Context) (string, error) {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return reqsign.EmptyBodyHash, nil
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, storage); err != nil {
+		return "", err
+	}
+	if _, err := storage.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(storage)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func takeRequestSignatureNonce(tokenId int, nonce string, now time.Time) (bool, error) {
+	ttl := 2 * requestSignatureMaxSkew
+	key := fmt.Sprintf("%s:%d:%s", requestSignatureNonceNamespace, tokenId, nonce)
+	if common.RedisEnabled && common.RDB != nil {
+		return common.RDB.SetNX(context.Background(), key, 1, ttl).Result()
+	}
+	return requestSignatureNonces.add(key, now, ttl), nil
+}
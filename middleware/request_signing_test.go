@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/reqsign"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningSecret = "request-signing-secret"
+
+func newSignedRequest(t *testing.T, body string, timestamp time.Time, nonce string) *http.Request {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?trace=1", strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer sk-signedtokenkey01")
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(reqsign.HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(reqsign.HeaderNonce, nonce)
+	stringToSign := reqsign.StringToSign(http.MethodPost, "/v1/chat/completions?trace=1", timestamp.Unix(), nonce, reqsign.BodyHash([]byte(body)))
+	request.Header.Set(reqsign.HeaderSignature, reqsign.Sign(testSigningSecret, stringToSign))
+	return request
+}
+
+func TestTokenAuthVerifiesRequestSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := setupTokenAuthTest(t)
+	token := &model.Token{
+		UserId: user.Id, Name: "signed", Status: common.TokenStatusEnabled, ExpiredTime: -1,
+		UnlimitedQuota: true, SigningRequired: true, SigningSecret: testSigningSecret,
+	}
+	token.SetKey("signedtokenkey01")
+	require.NoError(t, model.DB.Create(token).Error)
+
+	router := gin.New()
+	router.POST("/v1/chat/completions", TokenAuth(), func(c *gin.Context) {
+		storage, err := common.GetBodyStorage(c)
+		require.NoError(t, err)
+		raw, err := storage.Bytes()
+		require.NoError(t, err)
+		c.String(http.StatusOK, string(raw))
+	})
+	perform := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	body := `{"model":"gpt-4o-mini"}`
+	now := time.Now()
+
+	recorder := perform(newSignedRequest(t, body, now, "nonce-1"))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, body, recorder.Body.String(), "the request body stays readable after hashing")
+
+	replayed := perform(newSignedRequest(t, body, now, "nonce-1"))
+	assert.Equal(t, http.StatusUnauthorized, replayed.Code, "a nonce cannot be reused")
+
+	tampered := newSignedRequest(t, body, now, "nonce-2")
+	tampered.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"model":"gpt-4o"}`)).Body
+	assert.Equal(t, http.StatusUnauthorized, perform(tampered).Code, "the body is covered by the signature")
+
+	stale := perform(newSignedRequest(t, body, now.Add(-10*time.Minute), "nonce-3"))
+	assert.Equal(t, http.StatusUnauthorized, stale.Code, "timestamps outside the skew window are rejected")
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?trace=1", strings.NewReader(body))
+	unsigned.Header.Set("Authorization", "Bearer sk-signedtokenkey01")
+	recorder = perform(unsigned)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "the bearer key alone is not enough")
+	assert.Contains(t, recorder.Body.String(), "access_denied")
+
+	assert.Equal(t, http.StatusOK, perform(newSignedRequest(t, body, now, "nonce-2")).Code,
+		"a nonce rejected for a bad signature stays usable")
+}
+
+func TestInMemoryNonceCacheExpiresEntries(t *testing.T) {
+	cache := &inMemoryNonceCache{store: make(map[string]int64)}
+	now := time.Unix(1700000000, 0)
+	assert.True(t, cache.add("a", now, time.Minute))
+	assert.False(t, cache.add("a", now.Add(30*time.Second), time.Minute))
+	assert.True(t, cache.add("a", now.Add(time.Minute), time.Minute))
+	assert.True(t, cache.add("b", now.Add(3*time.Minute), time.Minute))
+	assert.NotContains(t, cache.store, "a", "expired nonces are swept")
+}
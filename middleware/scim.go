@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScimAuth 校验 SCIM 客户端携带的 Bearer 密钥。SCIM 未启用（或未配置密钥）时
+// 接口整体返回 404，避免暴露开通入口。
+func ScimAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		settings := system_setting.GetSCIMSettings()
+		if !settings.IsSCIMEnabled() {
+			abortWithScimError(c, http.StatusNotFound, "SCIM provisioning is not enabled")
+			return
+		}
+		token, ok := authorizationToken(c.GetHeader("Authorization"))
+		secret := strings.TrimSpace(settings.BearerSecret)
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			abortWithScimError(c, http.StatusUnauthorized, "invalid SCIM bearer token")
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortWithScimError(c *gin.Context, status int, detail string) {
+	c.Header("Content-Type", dto.ScimContentType)
+	c.AbortWithStatusJSON(status, dto.NewScimError(status, "", detail))
+}
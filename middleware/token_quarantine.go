@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tokenQuarantineRateLimitWindow int64 = 60
+
+const tokenQuarantinedMessage = "该令牌疑似泄露，已被隔离，请在令牌管理中确认安全或更换密钥"
+
+func redisTokenQuarantineRateLimitKey(tokenId int) string {
+	return fmt.Sprintf("%s:quarantine:%d", redisRateLimitNamespace, tokenId)
+}
+
+// applyTokenQuarantine 隔离中的令牌只能调用配置允许的模型（与令牌自身的模型限制取交集），
+// 并按令牌执行每分钟请求数限制。必须在 SetupContextForToken 之后调用，返回 false 时请求已中止。
+func applyTokenQuarantine(c *gin.Context, token *model.Token) bool {
+	setting := operation_setting.GetTokenLeakSetting()
+	var tokenLimits map[string]bool
+	if token.ModelLimitsEnabled {
+		tokenLimits = token.GetModelLimitsMap()
+	}
+	allowed := make(map[string]bool)
+	for _, modelName := range setting.GetQuarantineModels() {
+		if tokenLimits == nil || tokenLimits[modelName] {
+			allowed[modelName] = true
+		}
+	}
+	if len(allowed) == 0 {
+		abortWithOpenAiMessage(c, http.StatusForbidden, tokenQuarantinedMessage, types.ErrorCodeTokenQuarantined)
+		return false
+	}
+	c.Set("token_model_limit_enabled", true)
+	c.Set("token_model_limit", allowed)
+	if setting.QuarantineRateLimit <= 0 {
+		return true
+	}
+	return takeQuarantinedTokenRequest(c, token.Id, setting.QuarantineRateLimit)
+}
+
+func takeQuarantinedTokenRequest(c *gin.Context, tokenId int, limit int) bool {
+	message := fmt.Sprintf("%s：隔离期间每分钟最多请求 %d 次", tokenQuarantinedMessage, limit)
+	if !common.RedisEnabled {
+		key := fmt.Sprintf("TQ:%d", tokenId)
+		if !inMemoryRateLimiter.Request(key, limit, tokenQuarantineRateLimitWindow) {
+			c.Header("Retry-After", fmt.Sprint(tokenQuarantineRateLimitWindow))
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests, message, types.ErrorCodeTokenQuarantined)
+			return false
+		}
+		return true
+	}
+	allowed, _, ttlSeconds, err := redisFixedWindowTake(c.Request.Context(), redisTokenQuarantineRateLimitKey(tokenId), limit, tokenQuarantineRateLimitWindow)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("token quarantine rate limit check failed: %v", err))
+		abortWithOpenAiMessage(c, http.StatusInternalServerError, "token_quarantine_rate_limit_check_failed")
+		return false
+	}
+	if !allowed {
+		if ttlSeconds > 0 {
+			c.Header("Retry-After", fmt.Sprint(ttlSeconds))
+		}
+		abortWithOpenAiMessage(c, http.StatusTooManyRequests, message, types.ErrorCodeTokenQuarantined)
+		return false
+	}
+	return true
+}
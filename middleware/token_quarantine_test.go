@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withTokenLeakSetting(t *testing.T, models []string, rateLimit int) {
+	t.Helper()
+	current := operation_setting.GetTokenLeakSetting()
+	original := *current
+	t.Cleanup(func() { *current = original })
+	current.QuarantineModels = models
+	current.QuarantineRateLimit = rateLimit
+}
+
+func performQuarantineRequest(key string) (*httptest.ResponseRecorder, map[string]bool) {
+	router := gin.New()
+	var modelLimits map[string]bool
+	router.GET("/v1/models", TokenAuth(), func(c *gin.Context) {
+		if c.GetBool("token_model_limit_enabled") {
+			modelLimits = c.MustGet("token_model_limit").(map[string]bool)
+		}
+		c.Status(http.StatusOK)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	request.Header.Set("Authorization", "Bearer sk-"+key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder, modelLimits
+}
+
+func TestTokenAuthRestrictsQuarantinedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := setupTokenAuthTest(t)
+	token := &model.Token{
+		UserId: user.Id, Name: "quarantined", Status: common.TokenStatusEnabled, ExpiredTime: -1,
+		UnlimitedQuota: true, ModelLimitsEnabled: true, ModelLimits: "gpt-4o,gpt-4o-mini", Quarantined: true,
+	}
+	token.SetKey("quarantinedkey01")
+	require.NoError(t, model.DB.Create(token).Error)
+
+	withTokenLeakSetting(t, nil, 0)
+	recorder, _ := performQuarantineRequest("quarantinedkey01")
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "no quarantine models blocks every request")
+	assert.Contains(t, recorder.Body.String(), "token_quarantined")
+
+	withTokenLeakSetting(t, []string{"gpt-4o-mini", " claude-haiku "}, 1)
+	recorder, modelLimits := performQuarantineRequest("quarantinedkey01")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, map[string]bool{"gpt-4o-mini": true}, modelLimits, "quarantine models are intersected with the token's own limits")
+
+	recorder, _ = performQuarantineRequest("quarantinedkey01")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+}
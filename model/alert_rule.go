@@ -0,0 +1,257 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	AlertRuleTypeBalancePercent = "balance_percent" // 剩余额度低于总额度（剩余+已用）的百分比
+	AlertRuleTypeSpend          = "spend"           // 自然日/自然周消耗超过阈值，可限定令牌或模型
+	AlertRuleTypeVelocity       = "velocity"        // 最近窗口消耗超过此前 24 个窗口平均值的倍数
+	AlertRuleTypeChannelError   = "channel_error"   // 窗口内渠道错误数超过阈值，仅管理员可用
+
+	AlertPeriodDay  = "day"
+	AlertPeriodWeek = "week"
+
+	MaxAlertRulesPerUser    = 20
+	MaxAlertTemplateLength  = 2000
+	maxAlertTitleLength     = 255
+	maxAlertNameLength      = 64
+	maxAlertWindowMinutes   = 1440
+	defaultVelocityWindow   = 60
+	defaultChannelErrWindow = 10
+)
+
+var ErrAlertRuleLimit = fmt.Errorf("at most %d alert rules are allowed per user", MaxAlertRulesPerUser)
+
+// AlertRule 用户自定义的额度/消耗告警规则，由 budget_alert 系统任务定期评估，
+// 通过用户通知设置中的方式发送。LastTriggerKey 记录最近一次触发所属的周期，
+// 同一周期内只发送一次。
+type AlertRule struct {
+	Id            int     `json:"id"`
+	UserId        int     `json:"user_id" gorm:"index"`
+	Name          string  `json:"name" gorm:"type:varchar(64)"`
+	Type          string  `json:"type" gorm:"type:varchar(32)"`
+	Enabled       bool    `json:"enabled" gorm:"index"`
+	Threshold     float64 `json:"threshold"`                           // 百分比、额度、倍数或错误次数，含义随 Type 变化
+	Period        string  `json:"period" gorm:"type:varchar(16)"`      // spend 规则的统计周期：day / week
+	WindowMinutes int     `json:"window_minutes"`                      // velocity / channel_error 的统计窗口
+	MinQuota      int     `json:"min_quota"`                           // velocity 规则忽略消耗低于该额度的窗口
+	TokenId       int     `json:"token_id"`                            // spend / velocity 限定令牌，0 表示全部
+	ModelName     string  `json:"model_name" gorm:"type:varchar(255)"` // spend / velocity 限定模型，空表示全部
+	ChannelId     int     `json:"channel_id"`                          // channel_error 限定渠道，0 表示全部
+	Title         string  `json:"title" gorm:"type:varchar(255)"`      // 标题模板，空则使用默认
+	Template      string  `json:"template" gorm:"type:text"`           // 正文模板，空则使用默认
+	// LastTriggerKey 最近一次触发的周期标识，余额类规则在恢复到阈值以上时清空
+	LastTriggerKey  string `json:"-" gorm:"type:varchar(64);default:''"`
+	LastTriggeredAt int64  `json:"last_triggered_at" gorm:"bigint"`
+	CreatedAt       int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt       int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// Normalize 校验规则并清理与类型无关的字段
+func (rule *AlertRule) Normalize() error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || utf8.RuneCountInString(rule.Name) > maxAlertNameLength {
+		return fmt.Errorf("alert rule name must be 1-%d characters", maxAlertNameLength)
+	}
+	if utf8.RuneCountInString(rule.Title) > maxAlertTitleLength {
+		return fmt.Errorf("alert title template must be at most %d characters", maxAlertTitleLength)
+	}
+	if utf8.RuneCountInString(rule.Template) > MaxAlertTemplateLength {
+		return fmt.Errorf("alert template must be at most %d characters", MaxAlertTemplateLength)
+	}
+	if rule.Threshold <= 0 {
+		return errors.New("alert threshold must be greater than 0")
+	}
+	scoped := false
+	windowed := false
+	switch rule.Type {
+	case AlertRuleTypeBalancePercent:
+		if rule.Threshold >= 100 {
+			return errors.New("balance percent threshold must be below 100")
+		}
+	case AlertRuleTypeSpend:
+		if rule.Period != AlertPeriodDay && rule.Period != AlertPeriodWeek {
+			return fmt.Errorf("spend period must be %q or %q", AlertPeriodDay, AlertPeriodWeek)
+		}
+		scoped = true
+	case AlertRuleTypeVelocity:
+		if rule.Threshold <= 1 {
+			return errors.New("velocity multiplier must be greater than 1")
+		}
+		if rule.WindowMinutes == 0 {
+			rule.WindowMinutes = defaultVelocityWindow
+		}
+		if rule.MinQuota < 0 {
+			return errors.New("min quota must not be negative")
+		}
+		scoped = true
+		windowed = true
+	case AlertRuleTypeChannelError:
+		if rule.WindowMinutes == 0 {
+			rule.WindowMinutes = defaultChannelErrWindow
+		}
+		if rule.ChannelId < 0 {
+			return errors.New("invalid channel id")
+		}
+		windowed = true
+	default:
+		return fmt.Errorf("unknown alert rule type %q", rule.Type)
+	}
+	if rule.Type != AlertRuleTypeSpend {
+		rule.Period = ""
+	}
+	if rule.Type != AlertRuleTypeVelocity {
+		rule.MinQuota = 0
+	}
+	if rule.Type != AlertRuleTypeChannelError {
+		rule.ChannelId = 0
+	}
+	if windowed {
+		if rule.WindowMinutes < 1 || rule.WindowMinutes > maxAlertWindowMinutes {
+			return fmt.Errorf("window must be 1-%d minutes", maxAlertWindowMinutes)
+		}
+	} else {
+		rule.WindowMinutes = 0
+	}
+	if scoped {
+		rule.ModelName = strings.TrimSpace(rule.ModelName)
+		if len(rule.ModelName) > 255 {
+			return errors.New("model name is too long")
+		}
+		if rule.TokenId < 0 {
+			return errors.New("invalid token id")
+		}
+	} else {
+		rule.TokenId = 0
+		rule.ModelName = ""
+	}
+	return nil
+}
+
+func GetUserAlertRules(userId int) ([]*AlertRule, error) {
+	var rules []*AlertRule
+	err := DB.Where("user_id = ?", userId).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func GetUserAlertRuleById(id int, userId int) (*AlertRule, error) {
+	var rule AlertRule
+	if err := DB.Where("id = ? AND user_id = ?", id, userId).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetEnabledAlertRules 返回全部启用的规则，供 budget_alert 任务评估
+func GetEnabledAlertRules() ([]*AlertRule, error) {
+	var rules []*AlertRule
+	err := DB.Where("enabled = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// HasEnabledAlertRules 没有启用的规则时 budget_alert 任务不调度
+func HasEnabledAlertRules() bool {
+	var id int64
+	err := DB.Model(&AlertRule{}).Where("enabled = ?", true).Limit(1).Pluck("id", &id).Error
+	return err == nil && id != 0
+}
+
+func (rule *AlertRule) Insert() error {
+	var count int64
+	if err := DB.Model(&AlertRule{}).Where("user_id = ?", rule.UserId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= MaxAlertRulesPerUser {
+		return ErrAlertRuleLimit
+	}
+	now := common.GetTimestamp()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.LastTriggerKey = ""
+	rule.LastTriggeredAt = 0
+	return DB.Create(rule).Error
+}
+
+// Update 保存规则配置；修改后清空触发记录，按新条件重新评估
+func (rule *AlertRule) Update() error {
+	rule.UpdatedAt = common.GetTimestamp()
+	rule.LastTriggerKey = ""
+	return DB.Model(rule).Select("name", "type", "enabled", "threshold", "period", "window_minutes", "min_quota",
+		"token_id", "model_name", "channel_id", "title", "template", "last_trigger_key", "updated_at").Updates(rule).Error
+}
+
+func DeleteUserAlertRule(id int, userId int) error {
+	result := DB.Where("id = ? AND user_id = ?", id, userId).Delete(&AlertRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("alert rule not found")
+	}
+	return nil
+}
+
+// ClaimAlertTrigger 以条件更新抢占某个周期的触发权，多个节点或多次评估同时命中时只有一方返回 true
+func ClaimAlertTrigger(id int, key string, now int64) (bool, error) {
+	result := DB.Model(&AlertRule{}).
+		Where("id = ? AND last_trigger_key <> ?", id, key).
+		Updates(map[string]interface{}{"last_trigger_key": key, "last_triggered_at": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ResetAlertTrigger 条件恢复后清空触发记录，下次越过阈值时重新告警
+func ResetAlertTrigger(id int) error {
+	return DB.Model(&AlertRule{}).Where("id = ? AND last_trigger_key <> ''", id).Update("last_trigger_key", "").Error
+}
+
+// AlertSpendFilter 告警规则统计消费日志的范围，区间为 [StartTimestamp, EndTimestamp)
+type AlertSpendFilter struct {
+	UserId         int
+	TokenId        int
+	ModelName      string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// SumAlertSpend 统计范围内消费日志的额度之和
+func SumAlertSpend(filter AlertSpendFilter) (int64, error) {
+	tx := LOG_DB.Table("logs").
+		Where("type = ? AND user_id = ?", LogTypeConsume, filter.UserId).
+		Where("created_at >= ? AND created_at < ?", filter.StartTimestamp, filter.EndTimestamp)
+	if filter.TokenId != 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("model_name = ?", filter.ModelName)
+	}
+	var total int64
+	err := tx.Select("COALESCE(SUM(quota), 0)").Scan(&total).Error
+	return total, err
+}
+
+// ChannelErrorCount 某渠道在窗口内的错误日志条数
+type ChannelErrorCount struct {
+	ChannelId int   `json:"channel_id" gorm:"column:channel_id"`
+	Count     int64 `json:"count" gorm:"column:error_count"`
+}
+
+// CountChannelErrors 按渠道统计 [start, end) 内的错误日志，channelId 为 0 时统计全部渠道。
+// 错误日志只有开启 ERROR_LOG_ENABLED 时才会记录。
+func CountChannelErrors(channelId int, start int64, end int64) ([]ChannelErrorCount, error) {
+	tx := LOG_DB.Table("logs").
+		Where("type = ? AND channel_id <> 0", LogTypeError).
+		Where("created_at >= ? AND created_at < ?", start, end)
+	if channelId != 0 {
+		tx = tx.Where("channel_id = ?", channelId)
+	}
+	var counts []ChannelErrorCount
+	err := tx.Select("channel_id, COUNT(*) error_count").Group("channel_id").Order("error_count DESC, channel_id").Scan(&counts).Error
+	return counts, err
+}
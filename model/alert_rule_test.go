@@ -0,0 +1,95 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRuleNormalize(t *testing.T) {
+	rule := &AlertRule{Name: " daily ", Type: AlertRuleTypeSpend, Threshold: 1000, Period: AlertPeriodDay, WindowMinutes: 30, ChannelId: 3, TokenId: 2}
+	require.NoError(t, rule.Normalize())
+	assert.Equal(t, "daily", rule.Name)
+	assert.Zero(t, rule.WindowMinutes)
+	assert.Zero(t, rule.ChannelId)
+	assert.Equal(t, 2, rule.TokenId)
+
+	rule = &AlertRule{Name: "spike", Type: AlertRuleTypeVelocity, Threshold: 3}
+	require.NoError(t, rule.Normalize())
+	assert.Equal(t, 60, rule.WindowMinutes)
+
+	for _, invalid := range []AlertRule{
+		{Name: "", Type: AlertRuleTypeSpend, Threshold: 1, Period: AlertPeriodDay},
+		{Name: "x", Type: AlertRuleTypeSpend, Threshold: 1, Period: "month"},
+		{Name: "x", Type: AlertRuleTypeBalancePercent, Threshold: 100},
+		{Name: "x", Type: AlertRuleTypeVelocity, Threshold: 1},
+		{Name: "x", Type: AlertRuleTypeChannelError, Threshold: 5, WindowMinutes: 2000},
+		{Name: "x", Type: "unknown", Threshold: 1},
+	} {
+		assert.Error(t, invalid.Normalize(), invalid)
+	}
+}
+
+func TestAlertRuleInsertLimitAndClaim(t *testing.T) {
+	truncateTables(t)
+
+	for i := 0; i < MaxAlertRulesPerUser; i++ {
+		require.NoError(t, (&AlertRule{UserId: 1, Name: "r", Type: AlertRuleTypeBalancePercent, Threshold: 10, Enabled: i == 0}).Insert())
+	}
+	assert.ErrorIs(t, (&AlertRule{UserId: 1, Name: "r", Type: AlertRuleTypeBalancePercent, Threshold: 10}).Insert(), ErrAlertRuleLimit)
+	assert.True(t, HasEnabledAlertRules())
+
+	rules, err := GetEnabledAlertRules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	id := rules[0].Id
+
+	claimed, err := ClaimAlertTrigger(id, "day:2024-01-01", 100)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = ClaimAlertTrigger(id, "day:2024-01-01", 101)
+	require.NoError(t, err)
+	assert.False(t, claimed, "same period must only fire once")
+	claimed, err = ClaimAlertTrigger(id, "day:2024-01-02", 102)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	require.NoError(t, ResetAlertTrigger(id))
+	claimed, err = ClaimAlertTrigger(id, "day:2024-01-02", 103)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestAlertRuleLogAggregates(t *testing.T) {
+	truncateTables(t)
+
+	seed := []Log{
+		{UserId: 1, TokenId: 5, ModelName: "gpt-4o", CreatedAt: 100, Type: LogTypeConsume, Quota: 10},
+		{UserId: 1, TokenId: 6, ModelName: "gpt-4o", CreatedAt: 150, Type: LogTypeConsume, Quota: 20},
+		{UserId: 1, TokenId: 5, ModelName: "claude", CreatedAt: 199, Type: LogTypeConsume, Quota: 40},
+		{UserId: 1, TokenId: 5, ModelName: "gpt-4o", CreatedAt: 200, Type: LogTypeConsume, Quota: 80},
+		{UserId: 2, TokenId: 7, ModelName: "gpt-4o", CreatedAt: 120, Type: LogTypeConsume, Quota: 160},
+		{UserId: 2, ChannelId: 3, CreatedAt: 120, Type: LogTypeError},
+		{UserId: 2, ChannelId: 3, CreatedAt: 130, Type: LogTypeError},
+		{UserId: 1, ChannelId: 4, CreatedAt: 140, Type: LogTypeError},
+		{UserId: 1, ChannelId: 4, CreatedAt: 300, Type: LogTypeError},
+	}
+	for i := range seed {
+		require.NoError(t, createLog(&seed[i]))
+	}
+
+	total, err := SumAlertSpend(AlertSpendFilter{UserId: 1, StartTimestamp: 100, EndTimestamp: 200})
+	require.NoError(t, err)
+	assert.EqualValues(t, 70, total)
+	total, err = SumAlertSpend(AlertSpendFilter{UserId: 1, TokenId: 5, ModelName: "gpt-4o", StartTimestamp: 0, EndTimestamp: 1000})
+	require.NoError(t, err)
+	assert.EqualValues(t, 90, total)
+
+	counts, err := CountChannelErrors(0, 100, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []ChannelErrorCount{{ChannelId: 3, Count: 2}, {ChannelId: 4, Count: 1}}, counts)
+	counts, err = CountChannelErrors(4, 0, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []ChannelErrorCount{{ChannelId: 4, Count: 2}}, counts)
+}
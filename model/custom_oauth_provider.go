@@ -61,6 +61,7 @@ type CustomOAuthProvider struct {
 	AuthStyle           int    `json:"auth_style" gorm:"default:0"`                    // 0=auto, 1=params, 2=header (Basic Auth)
 	AccessPolicy        string `json:"access_policy" gorm:"type:text"`                 // JSON policy for access control based on user info
 	AccessDeniedMessage string `json:"access_denied_message" gorm:"type:varchar(512)"` // Custom error message template when access is denied
+	ClaimMapping        string `json:"claim_mapping" gorm:"type:text"`                 // JSON rules mapping user info claims to group, usable groups, role and initial quota
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// 终端用户日额度计数保留两天，跨日后旧计数自然过期
+const endUserQuotaCounterTTL = 48 * time.Hour
+
+var endUserQuotaMemory = struct {
+	sync.Mutex
+	day    string
+	quotas map[string]int
+}{quotas: make(map[string]int)}
+
+// EndUserLimitScope 返回终端用户限制的归属令牌：子令牌与父令牌共享同一组终端用户计数
+func EndUserLimitScope(c *gin.Context) int {
+	if parentId := common.GetContextKeyInt(c, constant.ContextKeyTokenParentId); parentId > 0 {
+		return parentId
+	}
+	return common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+}
+
+func endUserQuotaDay(now time.Time) string {
+	return now.Format("20060102")
+}
+
+func endUserQuotaCounterKey(day string, scope int, endUserId string) string {
+	return fmt.Sprintf("enduser:quota:%s:%d:%s", day, scope, endUserId)
+}
+
+// GetEndUserDailyQuota 返回终端用户在指定令牌下当日已消耗的额度
+func GetEndUserDailyQuota(scope int, endUserId string) (int, error) {
+	key := endUserQuotaCounterKey(endUserQuotaDay(time.Now()), scope, endUserId)
+	if common.RedisEnabled {
+		quota, err := common.RDB.Get(context.Background(), key).Int()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return 0, err
+		}
+		return quota, nil
+	}
+	endUserQuotaMemory.Lock()
+	defer endUserQuotaMemory.Unlock()
+	if endUserQuotaMemory.day != endUserQuotaDay(time.Now()) {
+		return 0, nil
+	}
+	return endUserQuotaMemory.quotas[key], nil
+}
+
+// IncreaseEndUserDailyQuota 累加终端用户当日消耗的额度
+func IncreaseEndUserDailyQuota(scope int, endUserId string, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	day := endUserQuotaDay(time.Now())
+	key := endUserQuotaCounterKey(day, scope, endUserId)
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.TxPipeline()
+		pipe.IncrBy(ctx, key, int64(quota))
+		pipe.Expire(ctx, key, endUserQuotaCounterTTL)
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+	endUserQuotaMemory.Lock()
+	defer endUserQuotaMemory.Unlock()
+	if endUserQuotaMemory.day != day {
+		endUserQuotaMemory.day = day
+		endUserQuotaMemory.quotas = make(map[string]int)
+	}
+	endUserQuotaMemory.quotas[key] += quota
+	return nil
+}
+
+// recordEndUserQuota 仅在令牌配置了终端用户日额度时累计，避免为每个请求增加一次 Redis 写入
+func recordEndUserQuota(c *gin.Context, quota int) {
+	endUserId := common.GetContextKeyString(c, constant.ContextKeyEndUserId)
+	if endUserId == "" || common.GetContextKeyInt(c, constant.ContextKeyTokenEndUserDailyQuota) <= 0 {
+		return
+	}
+	if err := IncreaseEndUserDailyQuota(EndUserLimitScope(c), endUserId, quota); err != nil {
+		common.SysLog("failed to record end user daily quota: " + err.Error())
+	}
+}
+
+// EndUserUsage 按终端用户汇总的消费统计。聚合列使用与 logs 原列不同的别名，
+// 避免 ClickHouse 将 SUM(quota) 中的 quota 解析为同名别名
+type EndUserUsage struct {
+	EndUserId        string `json:"end_user_id" gorm:"column:end_user_id"`
+	Requests         int64  `json:"requests" gorm:"column:request_count"`
+	Quota            int64  `json:"quota" gorm:"column:total_quota"`
+	PromptTokens     int64  `json:"prompt_tokens" gorm:"column:total_prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens" gorm:"column:total_completion_tokens"`
+	LastUsedAt       int64  `json:"last_used_at" gorm:"column:last_used_at"`
+}
+
+// EndUserUsageFilter 终端用户汇总的查询条件，零值字段不参与过滤
+type EndUserUsageFilter struct {
+	UserId         int
+	Username       string
+	TokenId        int
+	TokenName      string
+	EndUserId      string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// GetEndUserUsage 按终端用户聚合消费日志，按消耗额度降序分页返回
+func GetEndUserUsage(filter EndUserUsageFilter, startIdx int, num int) (usages []*EndUserUsage, total int64, err error) {
+	tx := LOG_DB.Table("logs").Where("type = ? AND end_user_id <> ''", LogTypeConsume)
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "username", filter.Username); err != nil {
+		return nil, 0, err
+	}
+	if filter.TokenId != 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("token_name = ?", filter.TokenName)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "end_user_id", filter.EndUserId); err != nil {
+		return nil, 0, err
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+	}
+
+	if err = tx.Session(&gorm.Session{}).Select("COUNT(DISTINCT end_user_id)").Scan(&total).Error; err != nil {
+		common.SysError("failed to count end user usage: " + err.Error())
+		return nil, 0, errors.New("查询终端用户用量失败")
+	}
+	err = tx.Select("end_user_id, COUNT(*) request_count, COALESCE(SUM(quota), 0) total_quota, " +
+		"COALESCE(SUM(prompt_tokens), 0) total_prompt_tokens, COALESCE(SUM(completion_tokens), 0) total_completion_tokens, " +
+		"MAX(created_at) last_used_at").
+		Group("end_user_id").
+		Order("total_quota DESC, end_user_id").
+		Limit(num).Offset(startIdx).
+		Scan(&usages).Error
+	if err != nil {
+		common.SysError("failed to query end user usage: " + err.Error())
+		return nil, 0, errors.New("查询终端用户用量失败")
+	}
+	return usages, total, nil
+}
@@ -10,7 +10,10 @@ import (
 	"gorm.io/gorm/clause"
 )
 
-const ExternalIdentityProviderTelegram = "telegram"
+const (
+	ExternalIdentityProviderTelegram = "telegram"
+	ExternalIdentityProviderLDAP     = "ldap"
+)
 
 var ErrExternalIdentityAlreadyClaimed = errors.New("external identity is already claimed")
 
@@ -67,6 +70,14 @@ func ClaimExternalIdentityWithTx(tx *gorm.DB, provider, subject string, userId i
 	return nil
 }
 
+// GetUserIdByExternalIdentity returns the user owning a provider subject, or 0
+// when the subject has not been claimed.
+func GetUserIdByExternalIdentity(provider string, subject string) (int, error) {
+	var claim ExternalIdentityClaim
+	err := DB.Where("provider = ? AND subject = ?", provider, subject).Limit(1).Find(&claim).Error
+	return claim.UserId, err
+}
+
 func ReleaseExternalIdentityWithTx(tx *gorm.DB, provider string, userId int) error {
 	provider = strings.TrimSpace(provider)
 	if tx == nil || provider == "" || userId == 0 {
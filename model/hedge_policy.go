@@ -0,0 +1,138 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	MaxHedgePolicies          = 50
+	maxHedgePolicyNameLength  = 64
+	maxHedgePolicyDelayMs     = 10 * 60 * 1000
+	defaultHedgeFallbackDelay = 2000
+)
+
+var ErrHedgePolicyLimit = fmt.Errorf("at most %d hedge policies are allowed", MaxHedgePolicies)
+
+// HedgePolicy 对冲请求策略：命中策略的请求在首个渠道迟迟没有返回首字节时，
+// 把同一请求再发给另一个渠道，先返回的一方把响应写回客户端，另一方被取消。
+// 只有胜出的一方向用户计费；落选方已产生的用量可以记到指定的管理员账户上。
+type HedgePolicy struct {
+	Id                 int    `json:"id"`
+	Name               string `json:"name" gorm:"type:varchar(64)"`
+	Enabled            bool   `json:"enabled" gorm:"index"`
+	ModelName          string `json:"model_name" gorm:"type:varchar(255);default:''"` // 用户请求的原始模型名，空表示全部模型
+	Group              string `json:"group" gorm:"type:varchar(64);default:''"`       // 限定分组，空表示全部分组
+	DelayMs            int    `json:"delay_ms"`                                       // 等待首字节的毫秒数，0 表示使用该模型近期观测到的 p90 首字时间
+	FallbackDelayMs    int    `json:"fallback_delay_ms"`                              // DelayMs 为 0 且观测样本不足时使用的等待时间
+	LoserBillingUserId int    `json:"loser_billing_user_id"`                          // 落选方的部分用量记到该用户，0 表示不计费
+	CreatedAt          int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt          int64  `json:"updated_at" gorm:"bigint"`
+}
+
+// Normalize 校验策略配置并补全默认值
+func (policy *HedgePolicy) Normalize() error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" || utf8.RuneCountInString(policy.Name) > maxHedgePolicyNameLength {
+		return fmt.Errorf("hedge policy name must be 1-%d characters", maxHedgePolicyNameLength)
+	}
+	policy.ModelName = strings.TrimSpace(policy.ModelName)
+	if len(policy.ModelName) > 255 {
+		return errors.New("model name is too long")
+	}
+	policy.Group = strings.TrimSpace(policy.Group)
+	if len(policy.Group) > 64 {
+		return errors.New("group name is too long")
+	}
+	if policy.ModelName == "" && policy.Group == "" {
+		return errors.New("a model or a group is required")
+	}
+	if policy.DelayMs < 0 || policy.DelayMs > maxHedgePolicyDelayMs {
+		return fmt.Errorf("delay must be between 0 and %d ms", maxHedgePolicyDelayMs)
+	}
+	if policy.FallbackDelayMs < 0 || policy.FallbackDelayMs > maxHedgePolicyDelayMs {
+		return fmt.Errorf("fallback delay must be between 0 and %d ms", maxHedgePolicyDelayMs)
+	}
+	if policy.FallbackDelayMs == 0 {
+		policy.FallbackDelayMs = defaultHedgeFallbackDelay
+	}
+	if policy.LoserBillingUserId < 0 {
+		return errors.New("invalid loser billing user")
+	}
+	return nil
+}
+
+// Matches 判断请求的原始模型与分组是否命中策略
+func (policy *HedgePolicy) Matches(modelName string, group string) bool {
+	if policy.ModelName != "" && policy.ModelName != modelName {
+		return false
+	}
+	return policy.Group == "" || policy.Group == group
+}
+
+// Specificity 同时命中多条策略时优先使用更具体的一条：指定模型优先于只指定分组
+func (policy *HedgePolicy) Specificity() int {
+	score := 0
+	if policy.ModelName != "" {
+		score += 2
+	}
+	if policy.Group != "" {
+		score++
+	}
+	return score
+}
+
+func GetHedgePolicies() ([]*HedgePolicy, error) {
+	var policies []*HedgePolicy
+	err := DB.Order("id").Find(&policies).Error
+	return policies, err
+}
+
+func GetEnabledHedgePolicies() ([]*HedgePolicy, error) {
+	var policies []*HedgePolicy
+	err := DB.Where("enabled = ?", true).Order("id").Find(&policies).Error
+	return policies, err
+}
+
+func GetHedgePolicyById(id int) (*HedgePolicy, error) {
+	var policy HedgePolicy
+	if err := DB.Where("id = ?", id).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (policy *HedgePolicy) Insert() error {
+	var count int64
+	if err := DB.Model(&HedgePolicy{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= MaxHedgePolicies {
+		return ErrHedgePolicyLimit
+	}
+	now := common.GetTimestamp()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+	return DB.Create(policy).Error
+}
+
+func (policy *HedgePolicy) Update() error {
+	policy.UpdatedAt = common.GetTimestamp()
+	return DB.Model(policy).Select("name", "enabled", "model_name", "group", "delay_ms", "fallback_delay_ms",
+		"loser_billing_user_id", "updated_at").Updates(policy).Error
+}
+
+func DeleteHedgePolicy(id int) error {
+	result := DB.Where("id = ?", id).Delete(&HedgePolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("hedge policy not found")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgePolicyNormalizeAndMatch(t *testing.T) {
+	policy := &HedgePolicy{Name: " chat ", ModelName: " gpt-4o "}
+	require.NoError(t, policy.Normalize())
+	assert.Equal(t, "chat", policy.Name)
+	assert.Equal(t, "gpt-4o", policy.ModelName)
+	assert.Equal(t, defaultHedgeFallbackDelay, policy.FallbackDelayMs)
+	assert.True(t, policy.Matches("gpt-4o", "vip"))
+	assert.False(t, policy.Matches("gpt-4o-mini", "vip"))
+
+	groupOnly := &HedgePolicy{Name: "vip", Group: "vip", DelayMs: 800}
+	require.NoError(t, groupOnly.Normalize())
+	assert.True(t, groupOnly.Matches("any-model", "vip"))
+	assert.False(t, groupOnly.Matches("any-model", "default"))
+
+	both := &HedgePolicy{ModelName: "gpt-4o", Group: "vip"}
+	assert.Greater(t, both.Specificity(), policy.Specificity())
+	assert.Greater(t, policy.Specificity(), groupOnly.Specificity(), "a model policy wins over a group policy")
+
+	for _, invalid := range []HedgePolicy{
+		{Name: "", ModelName: "m"},
+		{Name: "x"},
+		{Name: "x", ModelName: "m", DelayMs: -1},
+		{Name: "x", ModelName: "m", DelayMs: maxHedgePolicyDelayMs + 1},
+		{Name: "x", ModelName: "m", FallbackDelayMs: -1},
+		{Name: "x", ModelName: "m", LoserBillingUserId: -1},
+	} {
+		assert.Error(t, invalid.Normalize(), invalid)
+	}
+}
+
+func TestHedgePolicyCRUD(t *testing.T) {
+	truncateTables(t)
+
+	policy := &HedgePolicy{Name: "chat", Enabled: true, ModelName: "gpt-4o", DelayMs: 500}
+	require.NoError(t, policy.Normalize())
+	require.NoError(t, policy.Insert())
+	disabled := &HedgePolicy{Name: "off", Group: "default"}
+	require.NoError(t, disabled.Normalize())
+	require.NoError(t, disabled.Insert())
+
+	enabled, err := GetEnabledHedgePolicies()
+	require.NoError(t, err)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, policy.Id, enabled[0].Id)
+	all, err := GetHedgePolicies()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	policy.Enabled = false
+	policy.DelayMs = 0
+	policy.LoserBillingUserId = 1
+	require.NoError(t, policy.Update())
+	stored, err := GetHedgePolicyById(policy.Id)
+	require.NoError(t, err)
+	assert.False(t, stored.Enabled)
+	assert.Zero(t, stored.DelayMs)
+	assert.Equal(t, 1, stored.LoserBillingUserId)
+
+	require.NoError(t, DeleteHedgePolicy(policy.Id))
+	assert.Error(t, DeleteHedgePolicy(policy.Id))
+	_, err = GetHedgePolicyById(policy.Id)
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/types"
 
@@ -77,6 +78,8 @@ type Log struct {
 	Ip                string `json:"ip" gorm:"index;default:''"`
 	RequestId         string `json:"request_id,omitempty" gorm:"type:varchar(64);index:idx_logs_request_id;default:''"`
 	UpstreamRequestId string `json:"upstream_request_id,omitempty" gorm:"type:varchar(128);index:idx_logs_upstream_request_id;default:''"`
+	EndUserId         string `json:"end_user_id,omitempty" gorm:"type:varchar(128);index;default:''"`
+	Labels            string `json:"labels,omitempty" gorm:"type:text"`
 	Other             string `json:"other"`
 }
 
@@ -98,9 +101,27 @@ func ensureLogRequestId(log *Log) {
 	}
 }
 
+// LogShipper 接收消费、错误与任务计费日志，由 service/logsink 在启动时注册，
+// 避免 model 依赖具体的投递实现。实现必须非阻塞，不能拖慢请求路径。
+var LogShipper func(log *Log)
+
+func shipLog(log *Log) {
+	if shipper := LogShipper; shipper != nil {
+		shipper(log)
+	}
+}
+
 func createLog(log *Log) error {
 	ensureLogRequestId(log)
-	return LOG_DB.Create(log).Error
+	if log.Labels == "" || common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		return LOG_DB.Create(log).Error
+	}
+	return LOG_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(log).Error; err != nil {
+			return err
+		}
+		return createLogLabels(tx, log)
+	})
 }
 
 func clickHouseLogOrder(prefix string) string {
@@ -141,6 +162,21 @@ func GetLogByTokenId(tokenId int) (logs []*Log, err error) {
 	return logs, err
 }
 
+// GetRelayLogByRequestId 按请求 ID 查找消费或错误日志，供管理员回放请求
+func GetRelayLogByRequestId(requestId string) (*Log, error) {
+	order := "created_at desc, id desc"
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		order = clickHouseLogOrder("")
+	}
+	var log Log
+	err := LOG_DB.Where("request_id = ? AND type IN ?", requestId, []int{LogTypeConsume, LogTypeError}).
+		Order(order).First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
 func RecordLog(userId int, logType int, content string) {
 	if logType == LogTypeConsume && !common.LogConsumeEnabled {
 		return
@@ -317,12 +353,15 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 		}(),
 		RequestId:         requestId,
 		UpstreamRequestId: upstreamRequestId,
+		EndUserId:         common.GetContextKeyString(c, constant.ContextKeyEndUserId),
+		Labels:            common.GetContextKeyString(c, constant.ContextKeyLogLabels),
 		Other:             otherStr,
 	}
 	err := createLog(log)
 	if err != nil {
 		logger.LogError(c, "failed to record log: "+err.Error())
 	}
+	shipLog(log)
 }
 
 type RecordConsumeLogParams struct {
@@ -341,6 +380,8 @@ type RecordConsumeLogParams struct {
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	// 终端用户日额度按实际消耗累计，不受消费日志开关影响
+	recordEndUserQuota(c, params.Quota)
 	if !common.LogConsumeEnabled {
 		return
 	}
@@ -381,12 +422,15 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 		}(),
 		RequestId:         requestId,
 		UpstreamRequestId: upstreamRequestId,
+		EndUserId:         common.GetContextKeyString(c, constant.ContextKeyEndUserId),
+		Labels:            common.GetContextKeyString(c, constant.ContextKeyLogLabels),
 		Other:             otherStr,
 	}
 	err := createLog(log)
 	if err != nil {
 		logger.LogError(c, "failed to record log: "+err.Error())
 	}
+	shipLog(log)
 	if common.DataExportEnabled {
 		LogQuotaData(QuotaDataLogParams{
 			UserID:    userId,
@@ -446,6 +490,7 @@ func RecordTaskBillingLog(params RecordTaskBillingLogParams) {
 	if err != nil {
 		common.SysLog("failed to record task billing log: " + err.Error())
 	}
+	shipLog(log)
 	if params.LogType == LogTypeConsume && common.DataExportEnabled {
 		nodeName := params.NodeName
 		if nodeName == "" {
@@ -465,7 +510,7 @@ func RecordTaskBillingLog(params RecordTaskBillingLogParams) {
 	}
 }
 
-func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string, startIdx int, num int, channel int, group string, requestId string, upstreamRequestId string) (logs []*Log, total int64, err error) {
+func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string, startIdx int, num int, channel int, group string, requestId string, upstreamRequestId string, endUserId string) (logs []*Log, total int64, err error) {
 	var tx *gorm.DB
 	if logType == LogTypeUnknown {
 		tx = LOG_DB
@@ -488,6 +533,9 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 	if upstreamRequestId != "" {
 		tx = tx.Where("logs.upstream_request_id = ?", upstreamRequestId)
 	}
+	if endUserId != "" {
+		tx = tx.Where("logs.end_user_id = ?", endUserId)
+	}
 	if startTimestamp != 0 {
 		tx = tx.Where("logs.created_at >= ?", startTimestamp)
 	}
@@ -561,7 +609,7 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 
 const logSearchCountLimit = 10000
 
-func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, tokenName string, startIdx int, num int, group string, requestId string, upstreamRequestId string) (logs []*Log, total int64, err error) {
+func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, tokenName string, startIdx int, num int, group string, requestId string, upstreamRequestId string, endUserId string) (logs []*Log, total int64, err error) {
 	var tx *gorm.DB
 	if logType == LogTypeUnknown {
 		tx = LOG_DB.Where("logs.user_id = ?", userId)
@@ -581,6 +629,9 @@ func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int
 	if upstreamRequestId != "" {
 		tx = tx.Where("logs.upstream_request_id = ?", upstreamRequestId)
 	}
+	if endUserId != "" {
+		tx = tx.Where("logs.end_user_id = ?", endUserId)
+	}
 	if startTimestamp != 0 {
 		tx = tx.Where("logs.created_at >= ?", startTimestamp)
 	}
@@ -733,5 +784,9 @@ func DeleteOldLogBatch(ctx context.Context, targetTimestamp int64, limit int) (i
 	if nil != result.Error {
 		return 0, result.Error
 	}
+	// 标签副本与日志行的 created_at 相同，每条日志最多 maxLogLabels 个标签
+	if err := LOG_DB.WithContext(ctx).Where("created_at < ?", targetTimestamp).Limit(limit * maxLogLabels).Delete(&LogLabel{}).Error; err != nil {
+		return 0, err
+	}
 	return result.RowsAffected, nil
 }
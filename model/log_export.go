@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// LogExportFilter 日志导出的过滤条件，零值字段不参与过滤
+type LogExportFilter struct {
+	LogType        int    `json:"type"`
+	StartTimestamp int64  `json:"start_timestamp"`
+	EndTimestamp   int64  `json:"end_timestamp"`
+	UserId         int    `json:"user_id,omitempty"`
+	Username       string `json:"username,omitempty"`
+	TokenId        int    `json:"token_id,omitempty"`
+	TokenName      string `json:"token_name,omitempty"`
+	ModelName      string `json:"model_name,omitempty"`
+	Group          string `json:"group,omitempty"`
+	ChannelId      int    `json:"channel,omitempty"`
+}
+
+// LogExportCursor 记录上一批最后一行的位置，按 (created_at, id) 递增翻页；
+// ClickHouse 日志表没有自增 id，改用与排序键一致的 (created_at, request_id)
+type LogExportCursor struct {
+	CreatedAt int64  `json:"created_at"`
+	Id        int    `json:"id"`
+	RequestId string `json:"request_id"`
+}
+
+func applyLogExportFilter(tx *gorm.DB, filter LogExportFilter) (*gorm.DB, error) {
+	var err error
+	if filter.LogType != LogTypeUnknown {
+		tx = tx.Where("logs.type = ?", filter.LogType)
+	}
+	if filter.UserId != 0 {
+		tx = tx.Where("logs.user_id = ?", filter.UserId)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "logs.username", filter.Username); err != nil {
+		return nil, err
+	}
+	if filter.TokenId != 0 {
+		tx = tx.Where("logs.token_id = ?", filter.TokenId)
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("logs.token_name = ?", filter.TokenName)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "logs.model_name", filter.ModelName); err != nil {
+		return nil, err
+	}
+	if filter.Group != "" {
+		tx = tx.Where("logs."+logGroupCol+" = ?", filter.Group)
+	}
+	if filter.ChannelId != 0 {
+		tx = tx.Where("logs.channel_id = ?", filter.ChannelId)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", filter.EndTimestamp)
+	}
+	return tx, nil
+}
+
+// CountExportLogs 统计满足导出条件的日志行数，用于汇报进度
+func CountExportLogs(ctx context.Context, filter LogExportFilter) (int64, error) {
+	tx, err := applyLogExportFilter(LOG_DB.WithContext(ctx).Model(&Log{}), filter)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// FindExportLogBatch 按时间正序返回 cursor 之后的一批日志。nil cursor 表示从头开始。
+// 按游标翻页而不是 OFFSET，导出一个月的日志时每批的查询代价保持不变。
+func FindExportLogBatch(ctx context.Context, filter LogExportFilter, cursor *LogExportCursor, limit int) ([]*Log, error) {
+	tx, err := applyLogExportFilter(LOG_DB.WithContext(ctx), filter)
+	if err != nil {
+		return nil, err
+	}
+	clickHouse := common.UsingLogDatabase(common.DatabaseTypeClickHouse)
+	if cursor != nil {
+		if clickHouse {
+			tx = tx.Where("(logs.created_at > ? OR (logs.created_at = ? AND logs.request_id > ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.RequestId)
+		} else {
+			tx = tx.Where("(logs.created_at > ? OR (logs.created_at = ? AND logs.id > ?))", cursor.CreatedAt, cursor.CreatedAt, cursor.Id)
+		}
+	}
+	order := "logs.created_at asc, logs.id asc"
+	if clickHouse {
+		order = "logs.created_at asc, logs.request_id asc"
+	}
+	var logs []*Log
+	if err := tx.Order(order).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// SanitizeUserExportLog 去掉普通用户不可见的字段，与 GetUserLogs 的展示保持一致
+func SanitizeUserExportLog(log *Log) {
+	formatUserLogs([]*Log{log}, 0)
+	log.Id = 0
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindExportLogBatchPagesByCursor(t *testing.T) {
+	truncateTables(t)
+
+	// Two rows share a timestamp so the cursor must break ties by id.
+	seed := []Log{
+		{UserId: 1, CreatedAt: 100, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 1},
+		{UserId: 1, CreatedAt: 200, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 2},
+		{UserId: 1, CreatedAt: 200, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 3},
+		{UserId: 2, CreatedAt: 250, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 4},
+		{UserId: 1, CreatedAt: 300, Type: LogTypeTopup, Quota: 5},
+		{UserId: 1, CreatedAt: 400, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 6},
+		{UserId: 1, CreatedAt: 900, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 7},
+	}
+	for i := range seed {
+		require.NoError(t, createLog(&seed[i]))
+	}
+
+	filter := LogExportFilter{
+		LogType:        LogTypeConsume,
+		StartTimestamp: 100,
+		EndTimestamp:   500,
+		UserId:         1,
+	}
+	total, err := CountExportLogs(context.Background(), filter)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+
+	var quotas []int
+	var cursor *LogExportCursor
+	for {
+		logs, err := FindExportLogBatch(context.Background(), filter, cursor, 2)
+		require.NoError(t, err)
+		if len(logs) == 0 {
+			break
+		}
+		for _, log := range logs {
+			quotas = append(quotas, log.Quota)
+		}
+		last := logs[len(logs)-1]
+		cursor = &LogExportCursor{CreatedAt: last.CreatedAt, Id: last.Id, RequestId: last.RequestId}
+	}
+	assert.Equal(t, []int{1, 2, 3, 6}, quotas)
+}
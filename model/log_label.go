@@ -0,0 +1,272 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// LabelsHeader 请求级成本分摊标签，格式为 k=v,k2=v2
+	LabelsHeader = "X-NewAPI-Tags"
+
+	MaxTokenLabels      = 10
+	MaxRequestLabels    = 10
+	MaxLabelKeyLength   = 63
+	MaxLabelValueLength = 128
+	// maxLogLabels 令牌标签与请求标签合并后的上限
+	maxLogLabels = MaxTokenLabels + MaxRequestLabels
+	// labelUsageLimit 按标签值汇总时最多返回的取值个数，按消耗额度降序截断
+	labelUsageLimit = 100
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// LogLabel 日志标签的规范化副本，仅用于 MySQL/PostgreSQL/SQLite 日志库按标签聚合时走索引。
+// ClickHouse 日志库直接使用 logs.label_map 列，不写入该表。
+type LogLabel struct {
+	Id         int    `json:"id"`
+	LogId      int    `json:"log_id" gorm:"index"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index;index:idx_log_labels_key_value,priority:3"`
+	LabelKey   string `json:"label_key" gorm:"type:varchar(64);index:idx_log_labels_key_value,priority:1"`
+	LabelValue string `json:"label_value" gorm:"type:varchar(128);index:idx_log_labels_key_value,priority:2"`
+}
+
+// ValidateLabelKey 标签名只允许字母、数字、下划线、点与连字符，且以字母或数字开头
+func ValidateLabelKey(key string) error {
+	if key == "" || len(key) > MaxLabelKeyLength || !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q: use up to %d letters, digits, '_', '.' or '-'", key, MaxLabelKeyLength)
+	}
+	return nil
+}
+
+// ValidateLabelValue 标签值不能为空，不能包含控制字符、逗号或等号，以便同样能通过请求头传递
+func ValidateLabelValue(key string, value string) error {
+	if value == "" || len(value) > MaxLabelValueLength || !utf8.ValidString(value) || value != strings.TrimSpace(value) {
+		return fmt.Errorf("invalid value for label %q: must be 1-%d bytes without surrounding spaces", key, MaxLabelValueLength)
+	}
+	if strings.ContainsAny(value, ",=") || strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return fmt.Errorf("invalid value for label %q: must not contain ',', '=' or control characters", key)
+	}
+	return nil
+}
+
+// ValidateLabels 校验一组标签，max 为允许的标签个数
+func ValidateLabels(labels map[string]string, max int) error {
+	if len(labels) > max {
+		return fmt.Errorf("too many labels: at most %d allowed", max)
+	}
+	for key, value := range labels {
+		if err := ValidateLabelKey(key); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseRequestLabels 解析 X-NewAPI-Tags 请求头，空项会被忽略，同名标签以最后一次出现为准
+func ParseRequestLabels(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=value", LabelsHeader, item)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := ValidateLabels(labels, MaxRequestLabels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// MergeLogLabels 合并令牌标签与请求标签并编码为写入日志的 JSON。
+// 令牌标签由令牌所有者配置，同名时覆盖请求标签，调用方无法借请求头改写成本归属。
+func MergeLogLabels(tokenLabels map[string]string, requestLabels map[string]string) string {
+	if len(tokenLabels) == 0 && len(requestLabels) == 0 {
+		return ""
+	}
+	merged := make(map[string]string, len(tokenLabels)+len(requestLabels))
+	for key, value := range requestLabels {
+		merged[key] = value
+	}
+	for key, value := range tokenLabels {
+		merged[key] = value
+	}
+	// encoding/json 按键排序输出，相同标签集合总是得到相同的字符串
+	data, err := common.Marshal(merged)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func decodeLogLabels(labels string) map[string]string {
+	if labels == "" {
+		return nil
+	}
+	var decoded map[string]string
+	if err := common.UnmarshalJsonStr(labels, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// createLogLabels 为非 ClickHouse 日志库写入标签副本，需在日志行写入后调用以获得 log.Id
+func createLogLabels(tx *gorm.DB, log *Log) error {
+	labels := decodeLogLabels(log.Labels)
+	if len(labels) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := make([]LogLabel, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, LogLabel{LogId: log.Id, CreatedAt: log.CreatedAt, LabelKey: key, LabelValue: labels[key]})
+	}
+	return tx.Create(&rows).Error
+}
+
+// LabelUsageFilter 按标签汇总的查询条件，Label 为分组使用的标签名，其余零值字段不参与过滤
+type LabelUsageFilter struct {
+	Label          string
+	UserId         int
+	Username       string
+	TokenName      string
+	ModelName      string
+	Group          string
+	ChannelId      int
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// LabelUsage 某个标签取值下的消费汇总，聚合列别名与 EndUserUsage 一致
+type LabelUsage struct {
+	Value            string `json:"value" gorm:"column:label_value"`
+	Requests         int64  `json:"requests" gorm:"column:request_count"`
+	Quota            int64  `json:"quota" gorm:"column:total_quota"`
+	PromptTokens     int64  `json:"prompt_tokens" gorm:"column:total_prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens" gorm:"column:total_completion_tokens"`
+}
+
+// LabelQuotaData 数据看板按标签取值与小时汇总的数据，字段含义与 QuotaData 相同
+type LabelQuotaData struct {
+	Label     string `json:"label" gorm:"column:label_value"`
+	CreatedAt int64  `json:"created_at" gorm:"column:bucket_at"`
+	Count     int64  `json:"count" gorm:"column:request_count"`
+	Quota     int64  `json:"quota" gorm:"column:total_quota"`
+	TokenUsed int64  `json:"token_used" gorm:"column:total_token_used"`
+}
+
+// labelUsageQuery 返回按标签取值分组的消费日志查询以及取值表达式。
+// SQL 日志库通过 log_labels 的 (label_key, label_value, created_at) 索引关联，ClickHouse 直接读取 label_map。
+func labelUsageQuery(filter LabelUsageFilter) (*gorm.DB, string, error) {
+	if err := ValidateLabelKey(filter.Label); err != nil {
+		return nil, "", err
+	}
+	var tx *gorm.DB
+	valueExpr := "log_labels.label_value"
+	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
+		// 标签名已通过校验，不含引号，可安全内联到 map 下标中
+		valueExpr = "label_map['" + filter.Label + "']"
+		tx = LOG_DB.Table("logs").Where("mapContains(label_map, ?)", filter.Label)
+	} else {
+		tx = LOG_DB.Table("logs").
+			Joins("JOIN log_labels ON log_labels.log_id = logs.id AND log_labels.label_key = ?", filter.Label)
+		if filter.StartTimestamp != 0 {
+			tx = tx.Where("log_labels.created_at >= ?", filter.StartTimestamp)
+		}
+		if filter.EndTimestamp != 0 {
+			tx = tx.Where("log_labels.created_at <= ?", filter.EndTimestamp)
+		}
+	}
+	tx = tx.Where("logs.type = ?", LogTypeConsume)
+	var err error
+	if filter.UserId != 0 {
+		tx = tx.Where("logs.user_id = ?", filter.UserId)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "logs.username", filter.Username); err != nil {
+		return nil, "", err
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("logs.token_name = ?", filter.TokenName)
+	}
+	if tx, err = applyExplicitLogTextFilter(tx, "logs.model_name", filter.ModelName); err != nil {
+		return nil, "", err
+	}
+	if filter.Group != "" {
+		tx = tx.Where("logs."+logGroupCol+" = ?", filter.Group)
+	}
+	if filter.ChannelId != 0 {
+		tx = tx.Where("logs.channel_id = ?", filter.ChannelId)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", filter.EndTimestamp)
+	}
+	return tx, valueExpr, nil
+}
+
+// GetLabelUsage 按标签取值汇总消费日志，未携带该标签的日志不计入
+func GetLabelUsage(filter LabelUsageFilter) ([]*LabelUsage, error) {
+	tx, valueExpr, err := labelUsageQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	var usages []*LabelUsage
+	err = tx.Select(valueExpr + " label_value, COUNT(*) request_count, COALESCE(SUM(logs.quota), 0) total_quota, " +
+		"COALESCE(SUM(logs.prompt_tokens), 0) total_prompt_tokens, COALESCE(SUM(logs.completion_tokens), 0) total_completion_tokens").
+		Group(valueExpr).
+		Order("total_quota DESC, label_value").
+		Limit(labelUsageLimit).
+		Scan(&usages).Error
+	if err != nil {
+		common.SysError("failed to query label usage: " + err.Error())
+		return nil, errors.New("查询标签用量失败")
+	}
+	return usages, nil
+}
+
+// GetLabelQuotaData 按标签取值与整点小时汇总消费日志，供数据看板按标签展示
+func GetLabelQuotaData(filter LabelUsageFilter) ([]*LabelQuotaData, error) {
+	tx, valueExpr, err := labelUsageQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	bucketExpr := "logs.created_at - logs.created_at % 3600"
+	var data []*LabelQuotaData
+	err = tx.Select(valueExpr + " label_value, " + bucketExpr + " bucket_at, COUNT(*) request_count, " +
+		"COALESCE(SUM(logs.quota), 0) total_quota, " +
+		"COALESCE(SUM(logs.prompt_tokens), 0) + COALESCE(SUM(logs.completion_tokens), 0) total_token_used").
+		Group(valueExpr + ", " + bucketExpr).
+		Order("bucket_at, label_value").
+		Scan(&data).Error
+	if err != nil {
+		common.SysError("failed to query label quota data: " + err.Error())
+		return nil, errors.New("查询标签用量失败")
+	}
+	return data, nil
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequestLabels(t *testing.T) {
+	labels, err := ParseRequestLabels(" project=alpha, ticket = INC-42 ,,project=beta")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"project": "beta", "ticket": "INC-42"}, labels)
+
+	labels, err = ParseRequestLabels("")
+	require.NoError(t, err)
+	assert.Nil(t, labels)
+
+	for _, header := range []string{"project", "=alpha", "project=", "pro ject=alpha", "project=a\tb"} {
+		_, err = ParseRequestLabels(header)
+		assert.Error(t, err, header)
+	}
+}
+
+func TestMergeLogLabelsTokenLabelsWin(t *testing.T) {
+	merged := MergeLogLabels(
+		map[string]string{"team": "search", "env": "prod"},
+		map[string]string{"team": "ads", "project": "alpha"},
+	)
+	assert.Equal(t, `{"env":"prod","project":"alpha","team":"search"}`, merged)
+	assert.Equal(t, "", MergeLogLabels(nil, nil))
+}
+
+func TestLabelUsageGroupsByLabelValue(t *testing.T) {
+	truncateTables(t)
+
+	seed := []Log{
+		{UserId: 1, CreatedAt: 3600, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 10, PromptTokens: 1, CompletionTokens: 2, Labels: `{"env":"prod","team":"search"}`},
+		{UserId: 1, CreatedAt: 3700, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 20, PromptTokens: 3, CompletionTokens: 4, Labels: `{"team":"search"}`},
+		{UserId: 1, CreatedAt: 7300, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 5, Labels: `{"team":"ads"}`},
+		{UserId: 2, CreatedAt: 3800, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 100, Labels: `{"team":"search"}`},
+		{UserId: 1, CreatedAt: 3900, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 1000},
+		{UserId: 1, CreatedAt: 4000, Type: LogTypeError, ModelName: "gpt-4o", Labels: `{"team":"search"}`},
+	}
+	for i := range seed {
+		require.NoError(t, createLog(&seed[i]))
+	}
+
+	usages, err := GetLabelUsage(LabelUsageFilter{Label: "team", UserId: 1, StartTimestamp: 1, EndTimestamp: 10000})
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+	assert.Equal(t, LabelUsage{Value: "search", Requests: 2, Quota: 30, PromptTokens: 4, CompletionTokens: 6}, *usages[0])
+	assert.Equal(t, LabelUsage{Value: "ads", Requests: 1, Quota: 5}, *usages[1])
+
+	data, err := GetLabelQuotaData(LabelUsageFilter{Label: "team", UserId: 1})
+	require.NoError(t, err)
+	require.Len(t, data, 2)
+	assert.Equal(t, LabelQuotaData{Label: "search", CreatedAt: 3600, Count: 2, Quota: 30, TokenUsed: 10}, *data[0])
+	assert.Equal(t, LabelQuotaData{Label: "ads", CreatedAt: 7200, Count: 1, Quota: 5}, *data[1])
+
+	_, err = GetLabelUsage(LabelUsageFilter{Label: "team'"})
+	assert.Error(t, err)
+
+	deleted, err := DeleteOldLogBatch(context.Background(), 5000, 100)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, deleted)
+	var remaining int64
+	require.NoError(t, LOG_DB.Model(&LogLabel{}).Count(&remaining).Error)
+	assert.EqualValues(t, 1, remaining)
+}
@@ -195,7 +195,7 @@ func InitDB() (err error) {
 		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(common.GetEnvOrDefault("SQL_MAX_LIFETIME", 60)))
 
 		if !common.IsMasterNode {
-			return nil
+			return initTokenKeySecret()
 		}
 		if common.UsingMainDatabase(common.DatabaseTypeMySQL) {
 			//_, _ = sqlDB.Exec("ALTER TABLE channels MODIFY model_mapping TEXT;") // TODO: delete this line when most users have upgraded
@@ -292,6 +292,17 @@ func migrateDB() error {
 		&SystemTaskLock{},
 		&CasbinRule{},
 		&AuthzRole{},
+		&ScimGroup{},
+		&ScimGroupMember{},
+		&UserAccessLock{},
+		&WorkloadIdentityIssuer{},
+		&AlertRule{},
+		&UsageAnomaly{},
+		&UsageForecast{},
+		&ShadowRule{},
+		&ShadowMetric{},
+		&TrafficSplitRule{},
+		&HedgePolicy{},
 	)
 	if err != nil {
 		return err
@@ -302,6 +313,12 @@ func migrateDB() error {
 	if err := InitializeExternalIdentityClaims(); err != nil {
 		return err
 	}
+	if err := initTokenKeySecret(); err != nil {
+		return err
+	}
+	if err := migrateTokenKeys(); err != nil {
+		return err
+	}
 	if common.UsingMainDatabase(common.DatabaseTypeSQLite) {
 		if err := ensureSubscriptionPlanTableSQLite(); err != nil {
 			return err
@@ -353,6 +370,17 @@ func migrateDBFast() error {
 		{&SystemInstance{}, "SystemInstance"},
 		{&SystemTask{}, "SystemTask"},
 		{&SystemTaskLock{}, "SystemTaskLock"},
+		{&ScimGroup{}, "ScimGroup"},
+		{&ScimGroupMember{}, "ScimGroupMember"},
+		{&UserAccessLock{}, "UserAccessLock"},
+		{&WorkloadIdentityIssuer{}, "WorkloadIdentityIssuer"},
+		{&AlertRule{}, "AlertRule"},
+		{&UsageAnomaly{}, "UsageAnomaly"},
+		{&UsageForecast{}, "UsageForecast"},
+		{&ShadowRule{}, "ShadowRule"},
+		{&ShadowMetric{}, "ShadowMetric"},
+		{&TrafficSplitRule{}, "TrafficSplitRule"},
+		{&HedgePolicy{}, "HedgePolicy"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -383,6 +411,12 @@ func migrateDBFast() error {
 	if err := InitializeExternalIdentityClaims(); err != nil {
 		return err
 	}
+	if err := initTokenKeySecret(); err != nil {
+		return err
+	}
+	if err := migrateTokenKeys(); err != nil {
+		return err
+	}
 	if common.UsingMainDatabase(common.DatabaseTypeSQLite) {
 		if err := ensureSubscriptionPlanTableSQLite(); err != nil {
 			return err
@@ -400,7 +434,7 @@ func migrateLOGDB() error {
 	if common.UsingLogDatabase(common.DatabaseTypeClickHouse) {
 		return migrateClickHouseLogDB()
 	}
-	return LOG_DB.AutoMigrate(&Log{})
+	return LOG_DB.AutoMigrate(&Log{}, &LogLabel{})
 }
 
 func migrateClickHouseLogDB() error {
@@ -408,6 +442,16 @@ func migrateClickHouseLogDB() error {
 	if err := LOG_DB.Exec(clickHouseLogCreateTableSQL(ttlDays)).Error; err != nil {
 		return err
 	}
+	// 已有的 logs 表不会被 CREATE IF NOT EXISTS 更新，新增列需要单独补齐
+	if err := LOG_DB.Exec("ALTER TABLE logs ADD COLUMN IF NOT EXISTS end_user_id String DEFAULT '' AFTER upstream_request_id").Error; err != nil {
+		return err
+	}
+	if err := LOG_DB.Exec("ALTER TABLE logs ADD COLUMN IF NOT EXISTS labels String DEFAULT '' AFTER end_user_id").Error; err != nil {
+		return err
+	}
+	if err := LOG_DB.Exec("ALTER TABLE logs ADD COLUMN IF NOT EXISTS label_map " + clickHouseLabelMapColumn + " AFTER labels").Error; err != nil {
+		return err
+	}
 	return syncClickHouseLogTTL(ttlDays)
 }
 
@@ -434,6 +478,10 @@ func clickHouseLogTTLClause(ttlDays int) string {
 	return "\nTTL " + expression
 }
 
+// clickHouseLabelMapColumn 由 labels 的 JSON 物化出的标签映射，按标签聚合时直接读取；
+// MATERIALIZED 列不出现在 SELECT * 中，写入时也无需提供
+const clickHouseLabelMapColumn = "Map(String, String) MATERIALIZED CAST(JSONExtractKeysAndValues(labels, 'String'), 'Map(String, String)')"
+
 func clickHouseLogCreateTableSQL(ttlDays int) string {
 	return fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS logs (
@@ -456,6 +504,9 @@ CREATE TABLE IF NOT EXISTS logs (
 	ip String DEFAULT '',
 	request_id String DEFAULT '',
 	upstream_request_id String DEFAULT '',
+	end_user_id String DEFAULT '',
+	labels String DEFAULT '',
+	label_map `+clickHouseLabelMapColumn+`,
 	other String DEFAULT ''
 )
 ENGINE = MergeTree()
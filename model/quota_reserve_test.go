@@ -30,12 +30,12 @@ func createReserveTestToken(t *testing.T, remainQuota int) Token {
 	t.Helper()
 	token := Token{
 		UserId:      1,
-		Key:         "reserve-token-" + common.GetRandomString(8),
 		Name:        "reserve-test",
 		Status:      common.TokenStatusEnabled,
 		ExpiredTime: -1,
 		RemainQuota: remainQuota,
 	}
+	token.SetKey("reserve-token-" + common.GetRandomString(8))
 	require.NoError(t, token.Insert())
 	return token
 }
@@ -175,7 +175,7 @@ func TestSynchronousReserveCompensatesCacheWhenPersistenceFails(t *testing.T) {
 	assert.Equal(t, 10, cached.Quota)
 
 	token := createReserveTestToken(t, 12)
-	_, err = GetTokenByKey(token.Key, true)
+	_, err = GetTokenByKeyHash(token.Key, true)
 	require.NoError(t, err)
 	require.NoError(t, DB.Delete(&token).Error)
 	reserved, err = TryReserveTokenQuota(token.Id, token.Key, 7, false)
@@ -193,7 +193,7 @@ func TestTokenCacheInitPreservesLiveQuotaAndFenceBlocksStaleSnapshot(t *testing.
 	server := useUserCacheMiniRedis(t)
 
 	token := createReserveTestToken(t, 100)
-	loaded, err := GetTokenByKey(token.Key, true)
+	loaded, err := GetTokenByKeyHash(token.Key, true)
 	require.NoError(t, err)
 	stale := *loaded
 
@@ -219,7 +219,7 @@ func TestTokenCacheInitPreservesLiveQuotaAndFenceBlocksStaleSnapshot(t *testing.
 
 	// fence 过期后可重新从数据库水合。
 	server.FastForward(time.Duration(tokenCacheFenceSeconds+1) * time.Second)
-	fresh, err := GetTokenByKey(token.Key, false)
+	fresh, err := GetTokenByKeyHash(token.Key, false)
 	require.NoError(t, err)
 	assert.Equal(t, 100, fresh.RemainQuota)
 	cached, err = cacheGetTokenByKey(token.Key)
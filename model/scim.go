@@ -0,0 +1,229 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SCIM 开通的用户身份复用 ExternalIdentityClaim：IdP 的 externalId 与
+// userName 各占一个 provider 槽位，唯一索引保证一个 IdP 身份只对应一个用户。
+// userName 可能是超出本地用户名长度的邮箱，因此单独保存而不写入 users.username。
+const (
+	ExternalIdentityProviderSCIM         = "scim"
+	ExternalIdentityProviderSCIMUserName = "scim_username"
+)
+
+var ErrScimGroupNameTaken = errors.New("scim group display name is already taken")
+
+// ScimGroup 是 IdP 推送的组，成员关系决定用户的分组与管理员角色。
+type ScimGroup struct {
+	Id          int    `json:"id"`
+	DisplayName string `json:"display_name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	ExternalId  string `json:"external_id" gorm:"type:varchar(128);index"`
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type ScimGroupMember struct {
+	GroupId int `json:"group_id" gorm:"primaryKey;autoIncrement:false"`
+	UserId  int `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// GetScimIdentity 返回用户的 SCIM externalId 与 userName，未开通过的用户返回空串。
+func GetScimIdentity(userId int) (externalId string, userName string, err error) {
+	var claims []ExternalIdentityClaim
+	err = DB.Where("user_id = ? AND provider IN ?", userId,
+		[]string{ExternalIdentityProviderSCIM, ExternalIdentityProviderSCIMUserName}).
+		Find(&claims).Error
+	for _, claim := range claims {
+		switch claim.Provider {
+		case ExternalIdentityProviderSCIM:
+			externalId = claim.Subject
+		case ExternalIdentityProviderSCIMUserName:
+			userName = claim.Subject
+		}
+	}
+	return externalId, userName, err
+}
+
+// SetScimIdentityWithTx 覆盖用户的 SCIM 身份；空值表示释放对应槽位。
+func SetScimIdentityWithTx(tx *gorm.DB, userId int, externalId string, userName string) error {
+	for provider, subject := range map[string]string{
+		ExternalIdentityProviderSCIM:         externalId,
+		ExternalIdentityProviderSCIMUserName: userName,
+	} {
+		if err := ReleaseExternalIdentityWithTx(tx, provider, userId); err != nil {
+			return err
+		}
+		if strings.TrimSpace(subject) == "" {
+			continue
+		}
+		if err := ClaimExternalIdentityWithTx(tx, provider, subject, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindUserIdsByScimUserName 查找 SCIM userName 对应的用户：已开通用户按其 SCIM userName
+// 匹配，尚未开通的本地用户按用户名匹配，便于 IdP 关联已有账号。
+func FindUserIdsByScimUserName(userName string) ([]int, error) {
+	var ids []int
+	claimedUserIds := DB.Model(&ExternalIdentityClaim{}).Select("user_id").
+		Where("provider = ?", ExternalIdentityProviderSCIMUserName)
+	err := DB.Model(&User{}).
+		Where("id IN (?) OR (username = ? AND id NOT IN (?))",
+			DB.Model(&ExternalIdentityClaim{}).Select("user_id").
+				Where("provider = ? AND subject = ?", ExternalIdentityProviderSCIMUserName, userName),
+			userName, claimedUserIds).
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ApplyScimUserWithTx 写入由 IdP 管理的资料（显示名、邮箱、状态）与 SCIM 身份，
+// 状态变化会推进 auth_version。调用方负责提交后的缓存发布与会话吊销。
+func ApplyScimUserWithTx(tx *gorm.DB, user *User, externalId string, userName string) error {
+	if err := SetScimIdentityWithTx(tx, user.Id, externalId, userName); err != nil {
+		return err
+	}
+	user.Email = NormalizeEmail(user.Email)
+	if err := ensureEmailAvailableWithTx(tx, user.Email, user.Id); err != nil {
+		return err
+	}
+	// UpdateWithTx 忽略零值，清空邮箱需要单独写入
+	if err := tx.Model(&User{}).Where("id = ?", user.Id).Update("email", user.Email).Error; err != nil {
+		return err
+	}
+	return user.UpdateWithTx(tx, false)
+}
+
+// ListScimUsers 分页列出用户，userIds 非 nil 时只返回其中的用户（用于过滤）。
+func ListScimUsers(userIds []int, offset int, limit int) (users []*User, total int64, err error) {
+	query := DB.Model(&User{})
+	if userIds != nil {
+		query = query.Where("id IN ?", userIds)
+	}
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+// ReleaseScimUserWithTx 解除被删除用户的 SCIM 身份与组成员关系，
+// 使 IdP 之后可以用同一身份重新开通。
+func ReleaseScimUserWithTx(tx *gorm.DB, userId int) error {
+	if err := SetScimIdentityWithTx(tx, userId, "", ""); err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userId).Delete(&ScimGroupMember{}).Error
+}
+
+// GetExistingUserIds 过滤掉不存在或已删除的用户 id。
+func GetExistingUserIds(userIds []int) ([]int, error) {
+	var ids []int
+	if len(userIds) == 0 {
+		return ids, nil
+	}
+	err := DB.Model(&User{}).Where("id IN ?", userIds).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func GetScimGroupById(id int) (*ScimGroup, error) {
+	group := &ScimGroup{}
+	if err := DB.First(group, id).Error; err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListScimGroups 分页列出 SCIM 组，column/value 非空时按该列精确过滤。
+func ListScimGroups(column string, value string, offset int, limit int) (groups []*ScimGroup, total int64, err error) {
+	query := DB.Model(&ScimGroup{})
+	switch column {
+	case "":
+	case "id", "display_name", "external_id":
+		query = query.Where(column+" = ?", value)
+	default:
+		return nil, 0, errors.New("unsupported scim group filter column")
+	}
+	if err = query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = query.Order("id").Offset(offset).Limit(limit).Find(&groups).Error
+	return groups, total, err
+}
+
+// GetScimGroupsByUserId 返回用户所属的 SCIM 组，按显示名排序以保证映射结果稳定。
+func GetScimGroupsByUserId(userId int) ([]*ScimGroup, error) {
+	var groups []*ScimGroup
+	err := DB.Where("id IN (?)", DB.Model(&ScimGroupMember{}).Select("group_id").Where("user_id = ?", userId)).
+		Order("display_name").Find(&groups).Error
+	return groups, err
+}
+
+// GetScimGroupMemberIds 返回组内仍存在的用户 id。
+func GetScimGroupMemberIds(groupId int) ([]int, error) {
+	var ids []int
+	err := DB.Model(&ScimGroupMember{}).
+		Where("group_id = ? AND user_id IN (?)", groupId, DB.Model(&User{}).Select("id")).
+		Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func (group *ScimGroup) InsertWithTx(tx *gorm.DB) error {
+	if err := ensureScimGroupNameAvailable(tx, group.DisplayName, 0); err != nil {
+		return err
+	}
+	return tx.Create(group).Error
+}
+
+func (group *ScimGroup) UpdateWithTx(tx *gorm.DB) error {
+	if err := ensureScimGroupNameAvailable(tx, group.DisplayName, group.Id); err != nil {
+		return err
+	}
+	return tx.Model(group).Select("display_name", "external_id").Updates(group).Error
+}
+
+func DeleteScimGroupWithTx(tx *gorm.DB, groupId int) error {
+	if err := tx.Where("group_id = ?", groupId).Delete(&ScimGroupMember{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&ScimGroup{}, groupId).Error
+}
+
+func ensureScimGroupNameAvailable(tx *gorm.DB, displayName string, excludeId int) error {
+	var count int64
+	if err := tx.Model(&ScimGroup{}).Where("display_name = ? AND id <> ?", displayName, excludeId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrScimGroupNameTaken
+	}
+	return nil
+}
+
+func AddScimGroupMembersWithTx(tx *gorm.DB, groupId int, userIds []int) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	members := make([]ScimGroupMember, 0, len(userIds))
+	for _, userId := range userIds {
+		members = append(members, ScimGroupMember{GroupId: groupId, UserId: userId})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+func RemoveScimGroupMembersWithTx(tx *gorm.DB, groupId int, userIds []int) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	return tx.Where("group_id = ? AND user_id IN ?", groupId, userIds).Delete(&ScimGroupMember{}).Error
+}
+
+func RemoveAllScimGroupMembersWithTx(tx *gorm.DB, groupId int) error {
+	return tx.Where("group_id = ?", groupId).Delete(&ScimGroupMember{}).Error
+}
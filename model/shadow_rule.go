@@ -0,0 +1,244 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxShadowRules          = 50
+	maxShadowRuleNameLength = 64
+	maxShadowErrorLength    = 255
+
+	// ShadowSimilarityNotComputed 规则未开启响应对比或任一方无可对比文本
+	ShadowSimilarityNotComputed = -1
+)
+
+var ErrShadowRuleLimit = fmt.Errorf("at most %d shadow rules are allowed", MaxShadowRules)
+
+// ShadowRule 影子流量规则：主请求成功返回后，按采样比例把请求异步复制到影子渠道，
+// 影子请求不影响客户端，也不向用户计费，其消耗累计到规则自身的管理员预算 UsedQuota，
+// 达到 BudgetQuota 后停止采样。
+type ShadowRule struct {
+	Id              int     `json:"id"`
+	Name            string  `json:"name" gorm:"type:varchar(64)"`
+	Enabled         bool    `json:"enabled" gorm:"index"`
+	ModelName       string  `json:"model_name" gorm:"type:varchar(255);default:''"` // 限定模型（用户请求的原始模型名），空表示全部
+	Group           string  `json:"group" gorm:"type:varchar(64);default:''"`       // 限定分组，空表示全部
+	ShadowChannelId int     `json:"shadow_channel_id"`
+	SampleRate      float64 `json:"sample_rate"`      // 采样百分比，(0, 100]
+	CompareResponse bool    `json:"compare_response"` // 是否缓存主响应并计算与影子响应的文本相似度
+	BudgetQuota     int     `json:"budget_quota"`     // 影子请求可消耗的额度上限
+	UsedQuota       int     `json:"used_quota"`
+	CreatedAt       int64   `json:"created_at" gorm:"bigint"`
+	UpdatedAt       int64   `json:"updated_at" gorm:"bigint"`
+}
+
+// Normalize 校验规则配置
+func (rule *ShadowRule) Normalize() error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || utf8.RuneCountInString(rule.Name) > maxShadowRuleNameLength {
+		return fmt.Errorf("shadow rule name must be 1-%d characters", maxShadowRuleNameLength)
+	}
+	rule.ModelName = strings.TrimSpace(rule.ModelName)
+	if len(rule.ModelName) > 255 {
+		return errors.New("model name is too long")
+	}
+	rule.Group = strings.TrimSpace(rule.Group)
+	if len(rule.Group) > 64 {
+		return errors.New("group name is too long")
+	}
+	if rule.ShadowChannelId <= 0 {
+		return errors.New("shadow channel is required")
+	}
+	if rule.SampleRate <= 0 || rule.SampleRate > 100 {
+		return errors.New("sample rate must be greater than 0 and at most 100")
+	}
+	if rule.BudgetQuota <= 0 {
+		return errors.New("budget quota must be greater than 0")
+	}
+	return nil
+}
+
+// Matches 判断请求的原始模型与分组是否命中规则
+func (rule *ShadowRule) Matches(modelName string, group string) bool {
+	if rule.ModelName != "" && rule.ModelName != modelName {
+		return false
+	}
+	if rule.Group != "" && rule.Group != group {
+		return false
+	}
+	return true
+}
+
+// BudgetExhausted 影子消耗达到预算后不再采样
+func (rule *ShadowRule) BudgetExhausted() bool {
+	return rule.UsedQuota >= rule.BudgetQuota
+}
+
+func GetShadowRules() ([]*ShadowRule, error) {
+	var rules []*ShadowRule
+	err := DB.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func GetShadowRuleById(id int) (*ShadowRule, error) {
+	var rule ShadowRule
+	if err := DB.Where("id = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetEnabledShadowRules 返回全部启用的规则，供转发链路按模型和分组匹配
+func GetEnabledShadowRules() ([]*ShadowRule, error) {
+	var rules []*ShadowRule
+	err := DB.Where("enabled = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (rule *ShadowRule) Insert() error {
+	var count int64
+	if err := DB.Model(&ShadowRule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= MaxShadowRules {
+		return ErrShadowRuleLimit
+	}
+	now := common.GetTimestamp()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.UsedQuota = 0
+	return DB.Create(rule).Error
+}
+
+// Update 保存规则配置；已用额度只由影子请求累计，不随配置修改
+func (rule *ShadowRule) Update() error {
+	rule.UpdatedAt = common.GetTimestamp()
+	return DB.Model(rule).Select("name", "enabled", "model_name", "group", "shadow_channel_id", "sample_rate",
+		"compare_response", "budget_quota", "updated_at").Updates(rule).Error
+}
+
+// ResetShadowRuleUsage 清零已用额度，用于预算周期重新开始
+func ResetShadowRuleUsage(id int) error {
+	return DB.Model(&ShadowRule{}).Where("id = ?", id).Update("used_quota", 0).Error
+}
+
+// DeleteShadowRule 删除规则及其影子指标
+func DeleteShadowRule(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&ShadowRule{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("shadow rule not found")
+		}
+		return tx.Where("rule_id = ?", id).Delete(&ShadowMetric{}).Error
+	})
+}
+
+// AddShadowRuleUsedQuota 原子累加影子请求的消耗
+func AddShadowRuleUsedQuota(id int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	return DB.Model(&ShadowRule{}).Where("id = ?", id).
+		Update("used_quota", gorm.Expr("used_quota + ?", quota)).Error
+}
+
+// ShadowMetric 一次影子请求与对应主请求的对比记录。主请求的用量来自其消费结算，
+// 影子请求失败时只记录状态码、错误与耗时。
+type ShadowMetric struct {
+	Id                      int     `json:"id"`
+	RuleId                  int     `json:"rule_id" gorm:"index:idx_shadow_metric_rule_time,priority:1"`
+	CreatedAt               int64   `json:"created_at" gorm:"bigint;index:idx_shadow_metric_rule_time,priority:2"`
+	RequestId               string  `json:"request_id" gorm:"type:varchar(64);default:''"`
+	ModelName               string  `json:"model_name" gorm:"type:varchar(255);default:''"`
+	Group                   string  `json:"group" gorm:"type:varchar(64);default:''"`
+	IsStream                bool    `json:"is_stream"`
+	PrimaryChannelId        int     `json:"primary_channel_id"`
+	PrimaryLatencyMs        int64   `json:"primary_latency_ms"`
+	PrimaryPromptTokens     int     `json:"primary_prompt_tokens"`
+	PrimaryCompletionTokens int     `json:"primary_completion_tokens"`
+	ShadowChannelId         int     `json:"shadow_channel_id"`
+	ShadowSuccess           bool    `json:"shadow_success"`
+	ShadowStatusCode        int     `json:"shadow_status_code"`
+	ShadowError             string  `json:"shadow_error" gorm:"type:varchar(255);default:''"`
+	ShadowLatencyMs         int64   `json:"shadow_latency_ms"`
+	ShadowPromptTokens      int     `json:"shadow_prompt_tokens"`
+	ShadowCompletionTokens  int     `json:"shadow_completion_tokens"`
+	ShadowQuota             int     `json:"shadow_quota"`
+	Similarity              float64 `json:"similarity"` // 0-1，ShadowSimilarityNotComputed 表示未计算
+}
+
+func RecordShadowMetric(metric *ShadowMetric) error {
+	if metric.CreatedAt == 0 {
+		metric.CreatedAt = common.GetTimestamp()
+	}
+	if utf8.RuneCountInString(metric.ShadowError) > maxShadowErrorLength {
+		metric.ShadowError = string([]rune(metric.ShadowError)[:maxShadowErrorLength])
+	}
+	return DB.Create(metric).Error
+}
+
+// GetShadowMetrics 按时间倒序分页返回规则的影子指标
+func GetShadowMetrics(ruleId int, startIdx int, num int) ([]*ShadowMetric, int64, error) {
+	var total int64
+	tx := DB.Model(&ShadowMetric{}).Where("rule_id = ?", ruleId)
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var metrics []*ShadowMetric
+	err := tx.Order("id desc").Limit(num).Offset(startIdx).Find(&metrics).Error
+	return metrics, total, err
+}
+
+// ShadowMetricSummary 规则在统计区间内的汇总。用量对比只统计影子请求成功的样本，
+// 相似度只统计已计算的样本。
+type ShadowMetricSummary struct {
+	Count                   int64   `json:"count" gorm:"column:sample_count"`
+	ShadowErrors            int64   `json:"shadow_errors" gorm:"column:shadow_errors"`
+	ErrorRate               float64 `json:"error_rate" gorm:"-"`
+	AvgPrimaryLatencyMs     float64 `json:"avg_primary_latency_ms" gorm:"column:avg_primary_latency"`
+	AvgShadowLatencyMs      float64 `json:"avg_shadow_latency_ms" gorm:"column:avg_shadow_latency"`
+	PrimaryPromptTokens     int64   `json:"primary_prompt_tokens" gorm:"column:primary_prompt_tokens"`
+	PrimaryCompletionTokens int64   `json:"primary_completion_tokens" gorm:"column:primary_completion_tokens"`
+	ShadowPromptTokens      int64   `json:"shadow_prompt_tokens" gorm:"column:shadow_prompt_tokens"`
+	ShadowCompletionTokens  int64   `json:"shadow_completion_tokens" gorm:"column:shadow_completion_tokens"`
+	ComparedCount           int64   `json:"compared_count" gorm:"column:compared_count"`
+	AvgSimilarity           float64 `json:"avg_similarity" gorm:"column:avg_similarity"`
+	ShadowQuota             int64   `json:"shadow_quota" gorm:"column:shadow_quota"`
+}
+
+// SummarizeShadowMetrics 汇总规则自 startTimestamp 起的影子指标，startTimestamp 为 0 时统计全部
+func SummarizeShadowMetrics(ruleId int, startTimestamp int64) (*ShadowMetricSummary, error) {
+	tx := DB.Model(&ShadowMetric{}).Where("rule_id = ?", ruleId)
+	if startTimestamp > 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	var summary ShadowMetricSummary
+	err := tx.Select(`COUNT(*) sample_count,
+		COALESCE(SUM(CASE WHEN shadow_success THEN 0 ELSE 1 END), 0) shadow_errors,
+		COALESCE(AVG(primary_latency_ms), 0) avg_primary_latency,
+		COALESCE(AVG(shadow_latency_ms), 0) avg_shadow_latency,
+		COALESCE(SUM(CASE WHEN shadow_success THEN primary_prompt_tokens ELSE 0 END), 0) primary_prompt_tokens,
+		COALESCE(SUM(CASE WHEN shadow_success THEN primary_completion_tokens ELSE 0 END), 0) primary_completion_tokens,
+		COALESCE(SUM(CASE WHEN shadow_success THEN shadow_prompt_tokens ELSE 0 END), 0) shadow_prompt_tokens,
+		COALESCE(SUM(CASE WHEN shadow_success THEN shadow_completion_tokens ELSE 0 END), 0) shadow_completion_tokens,
+		COALESCE(SUM(CASE WHEN similarity >= 0 THEN 1 ELSE 0 END), 0) compared_count,
+		COALESCE(AVG(CASE WHEN similarity >= 0 THEN similarity END), 0) avg_similarity,
+		COALESCE(SUM(shadow_quota), 0) shadow_quota`).Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	if summary.Count > 0 {
+		summary.ErrorRate = float64(summary.ShadowErrors) / float64(summary.Count)
+	}
+	return &summary, nil
+}
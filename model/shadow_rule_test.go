@@ -0,0 +1,83 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowRuleNormalizeAndMatch(t *testing.T) {
+	rule := &ShadowRule{Name: " canary ", ModelName: " gpt-4o ", ShadowChannelId: 3, SampleRate: 5, BudgetQuota: 1000}
+	require.NoError(t, rule.Normalize())
+	assert.Equal(t, "canary", rule.Name)
+	assert.True(t, rule.Matches("gpt-4o", "vip"))
+	assert.False(t, rule.Matches("gpt-4o-mini", "vip"))
+
+	rule.Group = "default"
+	assert.True(t, rule.Matches("gpt-4o", "default"))
+	assert.False(t, rule.Matches("gpt-4o", "vip"))
+
+	for _, invalid := range []ShadowRule{
+		{Name: "", ShadowChannelId: 1, SampleRate: 1, BudgetQuota: 1},
+		{Name: "x", SampleRate: 1, BudgetQuota: 1},
+		{Name: "x", ShadowChannelId: 1, SampleRate: 0, BudgetQuota: 1},
+		{Name: "x", ShadowChannelId: 1, SampleRate: 101, BudgetQuota: 1},
+		{Name: "x", ShadowChannelId: 1, SampleRate: 1},
+	} {
+		assert.Error(t, invalid.Normalize(), invalid)
+	}
+}
+
+func TestShadowRuleUsageAndMetrics(t *testing.T) {
+	truncateTables(t)
+
+	rule := &ShadowRule{Name: "canary", Enabled: true, Group: "default", ShadowChannelId: 2, SampleRate: 10, BudgetQuota: 100}
+	require.NoError(t, rule.Insert())
+	rule.Group = "vip"
+	rule.UsedQuota = 99
+	require.NoError(t, rule.Update())
+
+	require.NoError(t, AddShadowRuleUsedQuota(rule.Id, 60))
+	require.NoError(t, AddShadowRuleUsedQuota(rule.Id, 40))
+	stored, err := GetShadowRuleById(rule.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "vip", stored.Group)
+	assert.Equal(t, 100, stored.UsedQuota, "used quota is only changed by shadow usage")
+	assert.True(t, stored.BudgetExhausted())
+
+	require.NoError(t, RecordShadowMetric(&ShadowMetric{RuleId: rule.Id, CreatedAt: 100, PrimaryLatencyMs: 100, ShadowSuccess: true, ShadowLatencyMs: 300,
+		PrimaryPromptTokens: 10, PrimaryCompletionTokens: 20, ShadowPromptTokens: 12, ShadowCompletionTokens: 30, ShadowQuota: 60, Similarity: 0.5}))
+	require.NoError(t, RecordShadowMetric(&ShadowMetric{RuleId: rule.Id, CreatedAt: 200, PrimaryLatencyMs: 200, ShadowSuccess: true, ShadowLatencyMs: 100,
+		PrimaryPromptTokens: 10, PrimaryCompletionTokens: 20, ShadowPromptTokens: 12, ShadowCompletionTokens: 10, ShadowQuota: 40, Similarity: ShadowSimilarityNotComputed}))
+	require.NoError(t, RecordShadowMetric(&ShadowMetric{RuleId: rule.Id, CreatedAt: 300, PrimaryLatencyMs: 300, ShadowStatusCode: 500, ShadowError: "boom",
+		PrimaryPromptTokens: 10, PrimaryCompletionTokens: 20, Similarity: ShadowSimilarityNotComputed}))
+
+	summary, err := SummarizeShadowMetrics(rule.Id, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, summary.Count)
+	assert.EqualValues(t, 1, summary.ShadowErrors)
+	assert.InDelta(t, 1.0/3, summary.ErrorRate, 1e-9)
+	assert.InDelta(t, 200, summary.AvgPrimaryLatencyMs, 1e-9)
+	assert.EqualValues(t, 20, summary.PrimaryPromptTokens, "failed shadow samples are excluded from token comparison")
+	assert.EqualValues(t, 40, summary.ShadowCompletionTokens)
+	assert.EqualValues(t, 1, summary.ComparedCount)
+	assert.InDelta(t, 0.5, summary.AvgSimilarity, 1e-9)
+	assert.EqualValues(t, 100, summary.ShadowQuota)
+
+	summary, err = SummarizeShadowMetrics(rule.Id, 200)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, summary.Count)
+
+	metrics, total, err := GetShadowMetrics(rule.Id, 0, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "boom", metrics[0].ShadowError)
+
+	require.NoError(t, DeleteShadowRule(rule.Id))
+	_, total, err = GetShadowMetrics(rule.Id, 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total, "metrics are removed with their rule")
+	assert.Error(t, DeleteShadowRule(rule.Id))
+}
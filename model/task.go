@@ -178,8 +178,10 @@ func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo)
 	properties := Properties{}
 	privateData := TaskPrivateData{}
 	if relayInfo != nil && relayInfo.ChannelMeta != nil {
+		// 批次结果需要用提交时的 key 拉取，多 key 渠道下必须固定
 		if relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeGemini ||
-			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi {
+			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi ||
+			platform == constant.TaskPlatformClaudeBatch {
 			privateData.Key = relayInfo.ChannelMeta.ApiKey
 		}
 		if relayInfo.UpstreamModelName != "" {
@@ -294,11 +296,13 @@ func TaskGetAllTasks(startIdx int, num int, queryParams SyncTaskQueryParams) []*
 	return tasks
 }
 
-func GetTimedOutUnfinishedTasks(cutoffUnix int64, limit int) []*Task {
+// GetTimedOutUnfinishedTasks 获取超时未完成的任务。batchCutoffUnix 单独作用于 Claude Message Batches，上游批次最长运行 24 小时，需要更宽的超时窗口。
+func GetTimedOutUnfinishedTasks(cutoffUnix int64, batchCutoffUnix int64, limit int) []*Task {
 	var tasks []*Task
 	err := DB.Where("progress != ?", "100%").
 		Where("status NOT IN ?", []string{TaskStatusFailure, TaskStatusSuccess}).
-		Where("submit_time < ?", cutoffUnix).
+		Where("((platform IS NULL OR platform <> ?) AND submit_time < ?) OR (platform = ? AND submit_time < ?)",
+			constant.TaskPlatformClaudeBatch, cutoffUnix, constant.TaskPlatformClaudeBatch, batchCutoffUnix).
 		Order("submit_time").
 		Limit(limit).
 		Find(&tasks).Error
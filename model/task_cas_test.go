@@ -45,6 +45,7 @@ func TestMain(m *testing.M) {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&Log{},
+		&LogLabel{},
 		&Channel{},
 		&QuotaData{},
 		&Ability{},
@@ -53,10 +54,18 @@ func TestMain(m *testing.M) {
 		&SubscriptionOrder{},
 		&UserSubscription{},
 		&UserOAuthBinding{},
+		&UserAccessLock{},
 		&PerfMetric{},
 		&SystemInstance{},
 		&SystemTask{},
 		&SystemTaskLock{},
+		&AlertRule{},
+		&UsageAnomaly{},
+		&UsageForecast{},
+		&ShadowRule{},
+		&ShadowMetric{},
+		&TrafficSplitRule{},
+		&HedgePolicy{},
 	); err != nil {
 		panic("failed to migrate: " + err.Error())
 	}
@@ -76,8 +85,10 @@ func truncateTables(t *testing.T) {
 		DB.Exec("DELETE FROM two_fas")
 		DB.Exec("DELETE FROM tokens")
 		DB.Exec("DELETE FROM user_oauth_bindings")
+		DB.Exec("DELETE FROM user_access_locks")
 		DB.Exec("DELETE FROM users")
 		DB.Exec("DELETE FROM logs")
+		DB.Exec("DELETE FROM log_labels")
 		DB.Exec("DELETE FROM channels")
 		DB.Exec("DELETE FROM quota_data")
 		DB.Exec("DELETE FROM abilities")
@@ -89,6 +100,13 @@ func truncateTables(t *testing.T) {
 		DB.Exec("DELETE FROM system_instances")
 		DB.Exec("DELETE FROM system_task_locks")
 		DB.Exec("DELETE FROM system_tasks")
+		DB.Exec("DELETE FROM alert_rules")
+		DB.Exec("DELETE FROM usage_anomalies")
+		DB.Exec("DELETE FROM usage_forecasts")
+		DB.Exec("DELETE FROM shadow_rules")
+		DB.Exec("DELETE FROM shadow_metrics")
+		DB.Exec("DELETE FROM traffic_split_rules")
+		DB.Exec("DELETE FROM hedge_policies")
 	})
 }
 
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/bytedance/gopkg/util/gopool"
 	"gorm.io/gorm"
@@ -14,7 +15,9 @@ import (
 type Token struct {
 	Id                 int            `json:"id"`
 	UserId             int            `json:"user_id" gorm:"index"`
-	Key                string         `json:"key" gorm:"type:varchar(128);uniqueIndex"`
+	Key                string         `json:"key" gorm:"type:varchar(128);uniqueIndex"` // 密钥摘要，见 HashTokenKey
+	KeyPrefix          string         `json:"key_prefix" gorm:"type:varchar(16);default:''"`
+	PlainKey           string         `json:"-" gorm:"type:varchar(128);default:''"` // 仅 TOKEN_KEY_REVEAL_ENABLED 时保存
 	Status             int            `json:"status" gorm:"default:1"`
 	Name               string         `json:"name" gorm:"index" `
 	CreatedTime        int64          `json:"created_time" gorm:"bigint"`
@@ -29,6 +32,23 @@ type Token struct {
 	Group              string         `json:"group" gorm:"default:''"`
 	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
 	AutoGroups         string         `json:"-" gorm:"type:text"`
+	WorkloadIssuerId   int            `json:"workload_issuer_id" gorm:"index;default:0"`             // 由工作负载身份签发时的签发方 ID
+	WorkloadSubject    string         `json:"-" gorm:"type:varchar(64);index;default:''"`            // 工作负载 sub 的摘要，同一工作负载的令牌共用规则的额度上限
+	WorkloadDirect     bool           `json:"-" gorm:"default:false"`                                // 直连签发的令牌，同一工作负载轮换 JWT 时复用
+	ParentTokenId      int            `json:"parent_token_id" gorm:"index;default:0"`                // 子令牌的父令牌 ID
+	EndUserId          string         `json:"end_user_id" gorm:"type:varchar(128);default:''"`       // 子令牌绑定的终端用户标识，写入使用日志
+	EndUserHeader      string         `json:"end_user_header" gorm:"type:varchar(64);default:''"`    // 从该请求头读取终端用户标识，为空时只读取请求体
+	EndUserRateLimit   int            `json:"end_user_rate_limit" gorm:"default:0"`                  // 每个终端用户每分钟请求数，0 表示不限制
+	EndUserDailyQuota  int            `json:"end_user_daily_quota" gorm:"default:0"`                 // 每个终端用户每日额度上限，0 表示不限制
+	ClientCert         string         `json:"client_cert" gorm:"type:varchar(255);index;default:''"` // 绑定的客户端证书标识，见 common.ClientCertIdentities
+	ClientCertRequired bool           `json:"client_cert_required"`                                  // 为 true 时使用密钥调用也必须出示绑定的证书
+	SigningRequired    bool           `json:"signing_required"`                                      // 为 true 时请求必须携带 reqsign 签名
+	SigningSecret      string         `json:"-" gorm:"type:varchar(128);default:''"`                 // 请求签名密钥，仅在生成时返回一次
+	Labels             string         `json:"-" gorm:"type:text"`                                    // 成本分摊标签，JSON 对象，写入使用日志
+	LeakFlaggedAt      int64          `json:"leak_flagged_at" gorm:"bigint;default:0"`               // 疑似泄露的标记时间，0 表示未标记
+	LeakReason         string         `json:"leak_reason" gorm:"type:varchar(255);default:''"`       // 触发泄露标记的检测信号
+	Quarantined        bool           `json:"quarantined"`                                           // 隔离中的令牌只能以低速率调用少数模型
+	LeakConfirmedAt    int64          `json:"leak_confirmed_at" gorm:"bigint;default:0"`             // 所有者确认安全的时间，之后一段时间内重新学习访问特征
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -56,8 +76,34 @@ func (token *Token) SetAutoGroups(groups []string) error {
 	return nil
 }
 
+// GetLabels 返回令牌的成本分摊标签，未配置时返回 nil
+func (token *Token) GetLabels() (map[string]string, error) {
+	if token.Labels == "" {
+		return nil, nil
+	}
+	var labels map[string]string
+	if err := common.UnmarshalJsonStr(token.Labels, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (token *Token) SetLabels(labels map[string]string) error {
+	if len(labels) == 0 {
+		token.Labels = ""
+		return nil
+	}
+	data, err := common.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	token.Labels = string(data)
+	return nil
+}
+
 func (token *Token) Clean() {
 	token.Key = ""
+	token.PlainKey = ""
 }
 
 func MaskTokenKey(key string) string {
@@ -73,11 +119,18 @@ func MaskTokenKey(key string) string {
 	return key[:4] + "**********" + key[len(key)-4:]
 }
 
+// GetFullKey 返回可再次展示的明文密钥；未开启 TOKEN_KEY_REVEAL_ENABLED 时明文不落库，返回空串。
 func (token *Token) GetFullKey() string {
-	return token.Key
+	if !constant.TokenKeyRevealEnabled {
+		return ""
+	}
+	return token.PlainKey
 }
 
 func (token *Token) GetMaskedKey() string {
+	if token.KeyPrefix != "" {
+		return token.KeyPrefix + "**********"
+	}
 	return MaskTokenKey(token.Key)
 }
 
@@ -193,12 +246,17 @@ func SearchUserTokens(userId int, keyword string, token string, offset int, limi
 		}
 		baseQuery = baseQuery.Where("name LIKE ? ESCAPE '!'", keywordPattern)
 	}
+	// 库中只有摘要与前缀：完整密钥按摘要精确匹配，其余按可见前缀匹配
 	if token != "" {
-		tokenPattern, err := sanitizeLikePattern(token)
-		if err != nil {
-			return nil, 0, err
+		if !strings.Contains(token, "%") && len(token) > tokenKeyPrefixLength {
+			baseQuery = baseQuery.Where(commonKeyCol+" = ?", HashTokenKey(token))
+		} else {
+			tokenPattern, err := sanitizeLikePattern(token)
+			if err != nil {
+				return nil, 0, err
+			}
+			baseQuery = baseQuery.Where("key_prefix LIKE ? ESCAPE '!'", tokenPattern)
 		}
-		baseQuery = baseQuery.Where(commonKeyCol+" LIKE ? ESCAPE '!'", tokenPattern)
 	}
 
 	// 先查匹配总数（用于分页，受 maxTokens 上限保护，避免全表 COUNT）
@@ -223,32 +281,7 @@ func ValidateUserToken(key string) (token *Token, err error) {
 	}
 	token, err = GetTokenByKey(key, false)
 	if err == nil {
-		if token.Status == common.TokenStatusExhausted ||
-			token.Status == common.TokenStatusExpired ||
-			token.Status != common.TokenStatusEnabled {
-			return token, ErrTokenInvalid
-		}
-		if token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp() {
-			if !common.RedisEnabled {
-				token.Status = common.TokenStatusExpired
-				err := token.SelectUpdate()
-				if err != nil {
-					common.SysLog("failed to update token status" + err.Error())
-				}
-			}
-			return token, ErrTokenInvalid
-		}
-		if !token.UnlimitedQuota && token.RemainQuota <= 0 {
-			if !common.RedisEnabled {
-				token.Status = common.TokenStatusExhausted
-				err := token.SelectUpdate()
-				if err != nil {
-					common.SysLog("failed to update token status" + err.Error())
-				}
-			}
-			return token, ErrTokenInvalid
-		}
-		return token, nil
+		return token, ValidateTokenStatus(token)
 	}
 	common.SysLog("ValidateUserToken: failed to get token: " + err.Error())
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -257,6 +290,37 @@ func ValidateUserToken(key string) (token *Token, err error) {
 	return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
 }
 
+// ValidateTokenStatus 检查令牌是否可用（状态、过期时间、剩余额度），
+// 过期或耗尽时在未启用 Redis 的情况下顺带落库更新状态。
+func ValidateTokenStatus(token *Token) error {
+	if token.Status == common.TokenStatusExhausted ||
+		token.Status == common.TokenStatusExpired ||
+		token.Status != common.TokenStatusEnabled {
+		return ErrTokenInvalid
+	}
+	if token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp() {
+		if !common.RedisEnabled {
+			token.Status = common.TokenStatusExpired
+			err := token.SelectUpdate()
+			if err != nil {
+				common.SysLog("failed to update token status" + err.Error())
+			}
+		}
+		return ErrTokenInvalid
+	}
+	if !token.UnlimitedQuota && token.RemainQuota <= 0 {
+		if !common.RedisEnabled {
+			token.Status = common.TokenStatusExhausted
+			err := token.SelectUpdate()
+			if err != nil {
+				common.SysLog("failed to update token status" + err.Error())
+			}
+		}
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
 func GetTokenByIds(id int, userId int) (*Token, error) {
 	if id == 0 || userId == 0 {
 		return nil, errors.New("id 或 userId 为空！")
@@ -277,17 +341,27 @@ func GetTokenById(id int) (*Token, error) {
 	return &token, err
 }
 
-func GetTokenByKey(key string, fromDB bool) (token *Token, err error) {
+// GetTokenByKey 按客户端提交的明文密钥（不含 sk- 前缀）查找令牌
+func GetTokenByKey(key string, fromDB bool) (*Token, error) {
+	token, err := GetTokenByKeyHash(HashTokenKey(key), fromDB)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return getLegacyTokenByKey(key)
+	}
+	return token, err
+}
+
+// GetTokenByKeyHash 按密钥摘要（即 Token.Key）查找令牌，供已通过鉴权的内部调用使用
+func GetTokenByKeyHash(keyHash string, fromDB bool) (token *Token, err error) {
 	if !fromDB && common.RedisEnabled {
 		// Try Redis first
-		token, err := cacheGetTokenByKey(key)
+		token, err := cacheGetTokenByKey(keyHash)
 		if err == nil {
 			return token, nil
 		}
 		// Don't return error - fall through to DB
 	}
 	token = &Token{}
-	if err = DB.Where(commonKeyCol+" = ?", key).First(token).Error; err != nil {
+	if err = DB.Where(commonKeyCol+" = ?", keyHash).First(token).Error; err != nil {
 		return nil, err
 	}
 	if common.RedisEnabled {
@@ -313,7 +387,9 @@ func (token *Token) Update() (err error) {
 		common.SysLog("failed to invalidate token cache before update: " + cacheErr.Error())
 	}
 	return DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "auto_groups").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "auto_groups",
+		"end_user_header", "end_user_rate_limit", "end_user_daily_quota", "client_cert", "client_cert_required",
+		"signing_required", "signing_secret", "labels").Updates(token).Error
 }
 
 func (token *Token) SelectUpdate() (err error) {
@@ -371,7 +447,11 @@ func DeleteTokenById(id int, userId int) (err error) {
 	if err != nil {
 		return err
 	}
-	return token.Delete()
+	if err = token.Delete(); err != nil {
+		return err
+	}
+	// 子令牌不能在父令牌删除后继续使用
+	return RevokeChildTokens(token.Id)
 }
 
 func IncreaseTokenQuota(tokenId int, key string, quota int) (err error) {
@@ -467,12 +547,20 @@ func BatchDeleteTokens(ids []int, userId int) (int, error) {
 		return 0, err
 	}
 
+	deletedIds := make([]int, 0, len(tokens))
+	for _, token := range tokens {
+		deletedIds = append(deletedIds, token.Id)
+	}
+	if err := RevokeChildTokens(deletedIds...); err != nil {
+		return 0, err
+	}
+
 	return len(tokens), nil
 }
 
 func GetTokenKeysByIds(ids []int, userId int) ([]Token, error) {
 	var tokens []Token
-	err := DB.Select("id", commonKeyCol).
+	err := DB.Select("id", commonKeyCol, "plain_key").
 		Where("user_id = ? AND id IN (?)", userId, ids).
 		Find(&tokens).Error
 	return tokens, err
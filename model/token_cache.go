@@ -9,12 +9,13 @@ import (
 	"github.com/QuantumNous/new-api/common"
 )
 
-func getTokenCacheKey(key string) string {
-	return fmt.Sprintf("token:%s", common.GenerateHMAC(key))
+// 缓存以密钥摘要（Token.Key）为键，Redis 中同样不出现明文密钥
+func getTokenCacheKey(keyHash string) string {
+	return fmt.Sprintf("token:%s", keyHash)
 }
 
-func getTokenCacheFenceKey(key string) string {
-	return fmt.Sprintf("token:fence:%s", common.GenerateHMAC(key))
+func getTokenCacheFenceKey(keyHash string) string {
+	return fmt.Sprintf("token:fence:%s", keyHash)
 }
 
 func tokenCacheTTLSeconds() int {
@@ -66,7 +67,7 @@ if redis.call('EXISTS', KEYS[2]) == 1 then
   return 0
 end
 if redis.call('EXISTS', KEYS[1]) == 1 then
-  redis.call('EXPIRE', KEYS[1], ARGV[17])
+  redis.call('EXPIRE', KEYS[1], ARGV[30])
   return 2
 end
 redis.call('HSET', KEYS[1],
@@ -74,8 +75,14 @@ redis.call('HSET', KEYS[1],
   'CreatedTime', ARGV[5], 'AccessedTime', ARGV[6], 'ExpiredTime', ARGV[7],
   'UnlimitedQuota', ARGV[8], 'ModelLimitsEnabled', ARGV[9], 'ModelLimits', ARGV[10],
   'AllowIps', ARGV[11], 'Group', ARGV[12], 'CrossGroupRetry', ARGV[13],
-  'AutoGroups', ARGV[14], 'RemainQuota', ARGV[15], 'UsedQuota', ARGV[16])
-redis.call('EXPIRE', KEYS[1], ARGV[17])
+  'AutoGroups', ARGV[14], 'RemainQuota', ARGV[15], 'UsedQuota', ARGV[16],
+  'ParentTokenId', ARGV[17], 'EndUserId', ARGV[18], 'EndUserHeader', ARGV[19],
+  'EndUserRateLimit', ARGV[20], 'EndUserDailyQuota', ARGV[21],
+  'ClientCert', ARGV[22], 'ClientCertRequired', ARGV[23],
+  'SigningRequired', ARGV[24], 'SigningSecret', ARGV[25],
+  'Labels', ARGV[26], 'LeakFlaggedAt', ARGV[27], 'Quarantined', ARGV[28],
+  'LeakConfirmedAt', ARGV[29])
+redis.call('EXPIRE', KEYS[1], ARGV[30])
 return 1`
 
 	return common.RDB.Eval(context.Background(), script, []string{
@@ -86,22 +93,27 @@ return 1`
 		strconv.FormatBool(token.UnlimitedQuota), strconv.FormatBool(token.ModelLimitsEnabled),
 		token.ModelLimits, allowIps, token.Group, strconv.FormatBool(token.CrossGroupRetry),
 		token.AutoGroups, token.RemainQuota, token.UsedQuota,
-		tokenCacheTTLSeconds(),
+		token.ParentTokenId, token.EndUserId, token.EndUserHeader,
+		token.EndUserRateLimit, token.EndUserDailyQuota,
+		token.ClientCert, strconv.FormatBool(token.ClientCertRequired),
+		strconv.FormatBool(token.SigningRequired), token.SigningSecret,
+		token.Labels, token.LeakFlaggedAt, strconv.FormatBool(token.Quarantined),
+		token.LeakConfirmedAt, tokenCacheTTLSeconds(),
 	).Int()
 }
 
 // cacheGetTokenByKey 从缓存读取 token；不完整的哈希（如仅有配额字段）会被拒绝。
-func cacheGetTokenByKey(key string) (*Token, error) {
+func cacheGetTokenByKey(keyHash string) (*Token, error) {
 	if !common.RedisEnabled {
 		return nil, fmt.Errorf("redis is not enabled")
 	}
 	var token Token
-	if err := common.RedisHGetObj(getTokenCacheKey(key), &token); err != nil {
+	if err := common.RedisHGetObj(getTokenCacheKey(keyHash), &token); err != nil {
 		return nil, err
 	}
 	if token.Id <= 0 {
 		return nil, fmt.Errorf("token cache is incomplete")
 	}
-	token.Key = key
+	token.Key = keyHash
 	return &token, nil
 }
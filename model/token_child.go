@@ -0,0 +1,116 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+// ErrParentTokenQuotaInsufficient 表示父令牌剩余额度不足以划给子令牌
+var ErrParentTokenQuotaInsufficient = errors.New("parent token remaining quota is insufficient")
+
+const childTokenCleanupBatchSize = 500
+
+// CreateChildToken 在同一事务中从父令牌的剩余额度划出子令牌额度并写入子令牌。
+// 无限额度的父令牌不扣减额度。
+func CreateChildToken(parent *Token, child *Token) error {
+	child.ParentTokenId = parent.Id
+	if !parent.UnlimitedQuota {
+		// 写库前失效父令牌缓存，避免缓存中的剩余额度与数据库不一致
+		if cacheErr := invalidateTokenCacheForMutation(parent.Key); cacheErr != nil {
+			common.SysLog("failed to invalidate parent token cache before child token creation: " + cacheErr.Error())
+		}
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if !parent.UnlimitedQuota {
+			result := tx.Model(&Token{}).
+				Where("id = ? AND unlimited_quota = ? AND remain_quota >= ?", parent.Id, false, child.RemainQuota).
+				Updates(map[string]interface{}{
+					"remain_quota": gorm.Expr("remain_quota - ?", child.RemainQuota),
+					"used_quota":   gorm.Expr("used_quota + ?", child.RemainQuota),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrParentTokenQuotaInsufficient
+			}
+		}
+		return tx.Create(child).Error
+	})
+}
+
+// RevokeChildTokens 立即删除指定父令牌签发的全部子令牌，用于父令牌被删除或停用时
+func RevokeChildTokens(parentIds ...int) error {
+	if len(parentIds) == 0 {
+		return nil
+	}
+	return releaseChildTokens("parent_token_id IN ?", parentIds)
+}
+
+// DeleteExpiredChildTokens 清理已过期或已被用户删除的子令牌，并把未用完的额度退回父令牌
+func DeleteExpiredChildTokens(now int64) error {
+	return releaseChildTokens("parent_token_id > 0 AND ((expired_time <> -1 AND expired_time < ?) OR deleted_at IS NOT NULL)", now)
+}
+
+func releaseChildTokens(query string, args ...any) error {
+	for {
+		var children []Token
+		if err := DB.Unscoped().Where(query, args...).
+			Order("id").Limit(childTokenCleanupBatchSize).Find(&children).Error; err != nil {
+			return err
+		}
+		if len(children) == 0 {
+			return nil
+		}
+		// 剩余额度以鉴权使用的缓存余额为准，数据库中的值可能还未落盘最近的消耗
+		for i := range children {
+			if live, err := GetTokenByKeyHash(children[i].Key, false); err == nil {
+				children[i].RemainQuota = live.RemainQuota
+			}
+		}
+		if err := invalidateTokensCache(children); err != nil {
+			common.SysLog("failed to invalidate child token cache: " + err.Error())
+		}
+		for i := range children {
+			if err := releaseChildToken(&children[i]); err != nil {
+				return err
+			}
+		}
+		if len(children) < childTokenCleanupBatchSize {
+			return nil
+		}
+	}
+}
+
+// releaseChildToken 物理删除子令牌；父令牌仍存在且为有限额度时退回子令牌的剩余额度
+func releaseChildToken(child *Token) error {
+	refund := child.RemainQuota
+	var parent Token
+	if refund > 0 {
+		err := DB.Where("id = ?", child.ParentTokenId).First(&parent).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err != nil || parent.UnlimitedQuota {
+			refund = 0
+		} else if cacheErr := invalidateTokenCacheForMutation(parent.Key); cacheErr != nil {
+			common.SysLog("failed to invalidate parent token cache before child token refund: " + cacheErr.Error())
+		}
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("id = ?", child.Id).Delete(&Token{})
+		if result.Error != nil {
+			return result.Error
+		}
+		// 并发清理时只有真正删除子令牌的一方退回额度
+		if result.RowsAffected == 0 || refund <= 0 {
+			return nil
+		}
+		return tx.Model(&Token{}).Where("id = ?", parent.Id).Updates(map[string]interface{}{
+			"remain_quota": gorm.Expr("remain_quota + ?", refund),
+			"used_quota":   gorm.Expr("used_quota - ?", refund),
+		}).Error
+	})
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseChildTokenRefundsLiveCachedBalance(t *testing.T) {
+	truncateTables(t)
+	resetBatchUpdateTestState(t)
+	useUserCacheMiniRedis(t)
+
+	parent := &Token{UserId: 1, Name: "parent", Status: common.TokenStatusEnabled, ExpiredTime: -1, RemainQuota: 10000}
+	parent.SetKey("child-refund-parent")
+	require.NoError(t, DB.Create(parent).Error)
+	child := &Token{UserId: 1, Name: "child", Status: common.TokenStatusEnabled, ExpiredTime: -1, RemainQuota: 3000, ParentTokenId: parent.Id}
+	child.SetKey("child-refund-child")
+	require.NoError(t, CreateChildToken(parent, child))
+
+	// Usage has been pre-deducted in the cache but not yet flushed to the database
+	_, err := GetTokenByKeyHash(child.Key, false)
+	require.NoError(t, err)
+	result, err := cacheApplyTokenQuotaDelta(child.Id, child.Key, -2000)
+	require.NoError(t, err)
+	require.Equal(t, cacheQuotaOK, result)
+
+	require.NoError(t, DB.Model(child).Update("expired_time", time.Now().Add(-time.Minute).Unix()).Error)
+	require.NoError(t, DeleteExpiredChildTokens(time.Now().Unix()))
+
+	var reloaded Token
+	require.NoError(t, DB.First(&reloaded, parent.Id).Error)
+	assert.Equal(t, 8000, reloaded.RemainQuota, "only the live remaining balance goes back to the parent")
+	assert.Equal(t, 2000, reloaded.UsedQuota)
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ValidateClientCertToken 按已校验客户端证书的标识查找绑定的令牌并检查其状态。
+// 证书的多个标识同时命中不同令牌时，按 identities 的顺序（指纹优先）选取。
+// 子令牌沿用父令牌的证书，只作为密钥鉴权的附加条件，不参与按证书查找。
+func ValidateClientCertToken(identities []string) (*Token, error) {
+	if len(identities) == 0 {
+		return nil, ErrTokenNotProvided
+	}
+	var candidates []Token
+	if err := DB.Where("client_cert IN ? AND parent_token_id = 0", identities).Order("id").Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+	}
+	for _, identity := range identities {
+		for i := range candidates {
+			if candidates[i].ClientCert != identity {
+				continue
+			}
+			// 经由密钥摘要读取，与密钥鉴权共用缓存中的实时余额
+			token, err := GetTokenByKeyHash(candidates[i].Key, false)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrDatabase, err)
+			}
+			return token, ValidateTokenStatus(token)
+		}
+	}
+	return nil, ErrTokenInvalid
+}
+
+// MatchesClientCert 判断证书标识中是否包含令牌绑定的证书
+func (token *Token) MatchesClientCert(identities []string) bool {
+	return token.ClientCert != "" && slices.Contains(identities, token.ClientCert)
+}
+
+// IsClientCertBound 检查证书标识是否已绑定到其它未删除的令牌，沿用父令牌证书的子令牌不计入
+func IsClientCertBound(identity string, excludeTokenId int) (bool, error) {
+	var count int64
+	err := DB.Model(&Token{}).Where("client_cert = ? AND id <> ? AND parent_token_id = 0", identity, excludeTokenId).Count(&count).Error
+	return count > 0, err
+}
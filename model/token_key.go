@@ -0,0 +1,169 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 令牌密钥只以 HMAC-SHA256 摘要形式存储在 tokens.key 中，Redis 缓存同样以摘要为键；
+// 明文仅在创建时返回一次，控制台通过 key_prefix 辨认令牌。
+// 开启 TOKEN_KEY_REVEAL_ENABLED 时额外保存明文到 plain_key，供控制台再次查看。
+
+const (
+	tokenKeyPrefixLength     = 8
+	tokenKeyMigrateBatchSize = 500
+	tokenKeySecretOptionKey  = "TokenKeyHashSecret"
+)
+
+var tokenKeySecret string
+
+// 摘要密钥被改写后所有已有令牌都无法鉴权，因此不能通过系统设置接口修改
+var errTokenKeySecretReadOnly = errors.New("TokenKeyHashSecret 由系统生成，不能通过设置修改")
+
+// HashTokenKey 返回令牌明文密钥（不含 sk- 前缀）的摘要。
+func HashTokenKey(key string) string {
+	return common.GenerateHMACWithKey([]byte("token-key-v1:"+tokenKeySecret), key)
+}
+
+func tokenKeyPrefix(key string) string {
+	if len(key) <= tokenKeyPrefixLength {
+		return key
+	}
+	return key[:tokenKeyPrefixLength]
+}
+
+// SetKey 为令牌设置新的明文密钥，只保留摘要与可见前缀。
+func (token *Token) SetKey(key string) {
+	token.Key = HashTokenKey(key)
+	token.KeyPrefix = tokenKeyPrefix(key)
+	token.PlainKey = ""
+	if constant.TokenKeyRevealEnabled {
+		token.PlainKey = key
+	}
+}
+
+// initTokenKeySecret 确定摘要使用的服务端密钥，所有节点启动时调用。
+// 已持久化过密钥时始终沿用（后来才配置 SESSION_SECRET 也不会使令牌失效）；
+// 否则优先使用显式配置的 CryptoSecret，两者都没有时生成随机密钥并保存，
+// 避免每次重启随机生成的 CryptoSecret 使所有令牌失效。
+func initTokenKeySecret() error {
+	tokenKeySecret = common.CryptoSecret
+	if !DB.Migrator().HasTable(&Option{}) {
+		// 从节点先于主节点完成迁移启动时无法得知已持久化的密钥，继续运行会与其它节点算出不同的摘要
+		return errors.New("options table not found, start the master node first so that token keys are hashed with the shared secret")
+	}
+	var option Option
+	err := DB.Where(commonKeyCol+" = ?", tokenKeySecretOptionKey).First(&option).Error
+	if err == nil && option.Value != "" {
+		tokenKeySecret = option.Value
+		common.SysLog("token keys are hashed with the secret stored in the database; set CRYPTO_SECRET before first start to keep it out of the database")
+		return nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if common.CryptoSecretConfigured {
+		return nil
+	}
+
+	secret, err := common.GenerateRandomCharsKey(48)
+	if err != nil {
+		return err
+	}
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Option{Key: tokenKeySecretOptionKey, Value: secret}).Error; err != nil {
+		return err
+	}
+	// 并发启动的节点可能已抢先写入，以数据库中的值为准
+	if err := DB.Where(commonKeyCol+" = ?", tokenKeySecretOptionKey).First(&option).Error; err != nil {
+		return err
+	}
+	tokenKeySecret = option.Value
+	common.SysLog("CRYPTO_SECRET is not set, generated a token key hash secret and stored it in the database")
+	return nil
+}
+
+func legacyTokenKeyCondition(db *gorm.DB) *gorm.DB {
+	return db.Where("(key_prefix = '' OR key_prefix IS NULL) AND " + commonKeyCol + " <> ''")
+}
+
+// migrateTokenKeys 将迁移前以明文保存的令牌密钥原地替换为摘要，原密钥继续有效。
+// 可重复执行：已迁移的行带有 key_prefix，不会被再次处理。
+func migrateTokenKeys() error {
+	migrated := 0
+	lastId := 0
+	for {
+		var tokens []Token
+		err := legacyTokenKeyCondition(DB.Unscoped().Select("id", commonKeyCol)).
+			Where("id > ?", lastId).
+			Order("id").
+			Limit(tokenKeyMigrateBatchSize).
+			Find(&tokens).Error
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			break
+		}
+		for _, token := range tokens {
+			lastId = token.Id
+			ok, err := upgradeTokenKey(token.Id, token.Key)
+			if err != nil {
+				return fmt.Errorf("failed to hash key of token %d: %w", token.Id, err)
+			}
+			if ok {
+				migrated++
+			}
+		}
+	}
+	if migrated > 0 {
+		common.SysLog(fmt.Sprintf("hashed %d legacy token keys", migrated))
+	}
+
+	if !constant.TokenKeyRevealEnabled {
+		result := DB.Unscoped().Model(&Token{}).Where("plain_key <> ''").Update("plain_key", "")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			common.SysLog(fmt.Sprintf("TOKEN_KEY_REVEAL_ENABLED is off, cleared %d stored plaintext token keys", result.RowsAffected))
+		}
+	}
+	return nil
+}
+
+// upgradeTokenKey 以原明文为条件更新，避免与并发的升级重复处理。
+func upgradeTokenKey(id int, key string) (bool, error) {
+	updates := map[string]interface{}{
+		"key":        HashTokenKey(key),
+		"key_prefix": tokenKeyPrefix(key),
+	}
+	if constant.TokenKeyRevealEnabled {
+		updates["plain_key"] = key
+	}
+	result := DB.Unscoped().Model(&Token{}).
+		Where("id = ? AND "+commonKeyCol+" = ?", id, key).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// getLegacyTokenByKey 兼容尚未迁移（或由旧版本节点写入）的明文密钥，命中后原地升级为摘要。
+func getLegacyTokenByKey(key string) (*Token, error) {
+	if key == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	token := &Token{}
+	if err := legacyTokenKeyCondition(DB).Where(commonKeyCol+" = ?", key).First(token).Error; err != nil {
+		return nil, err
+	}
+	if _, err := upgradeTokenKey(token.Id, key); err != nil {
+		return nil, err
+	}
+	token.SetKey(key)
+	return token, nil
+}
@@ -0,0 +1,161 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func insertLegacyPlainKeyToken(t *testing.T, name string, rawKey string) Token {
+	t.Helper()
+	token := Token{
+		UserId:         1,
+		Name:           name,
+		Key:            rawKey,
+		Status:         common.TokenStatusEnabled,
+		ExpiredTime:    -1,
+		UnlimitedQuota: true,
+	}
+	require.NoError(t, DB.Create(&token).Error)
+	return token
+}
+
+func TestMigrateTokenKeysHashesLegacyKeysAndKeepsThemValid(t *testing.T) {
+	truncateTables(t)
+	legacyKey := "legacy0123456789abcdefghijklmnopqrstuvwxyzABCDEF"
+	legacy := insertLegacyPlainKeyToken(t, "legacy", legacyKey)
+
+	require.NoError(t, migrateTokenKeys())
+
+	var stored Token
+	require.NoError(t, DB.First(&stored, legacy.Id).Error)
+	assert.Equal(t, HashTokenKey(legacyKey), stored.Key)
+	assert.Equal(t, "legacy01", stored.KeyPrefix)
+	assert.Empty(t, stored.PlainKey)
+	assert.Equal(t, "legacy01**********", stored.GetMaskedKey())
+
+	token, err := ValidateUserToken(legacyKey)
+	require.NoError(t, err)
+	assert.Equal(t, legacy.Id, token.Id)
+
+	// 再次执行不会重复摘要
+	require.NoError(t, migrateTokenKeys())
+	require.NoError(t, DB.First(&stored, legacy.Id).Error)
+	assert.Equal(t, HashTokenKey(legacyKey), stored.Key)
+}
+
+func TestGetTokenByKeyUpgradesLegacyRowOnFirstUse(t *testing.T) {
+	truncateTables(t)
+	legacyKey := "late0123456789abcdefghijklmnopqrstuvwxyzABCDEFGH"
+	legacy := insertLegacyPlainKeyToken(t, "written-by-old-node", legacyKey)
+
+	token, err := GetTokenByKey(legacyKey, false)
+	require.NoError(t, err)
+	assert.Equal(t, legacy.Id, token.Id)
+	assert.Equal(t, HashTokenKey(legacyKey), token.Key)
+
+	var stored Token
+	require.NoError(t, DB.First(&stored, legacy.Id).Error)
+	assert.Equal(t, HashTokenKey(legacyKey), stored.Key)
+	assert.Equal(t, "late0123", stored.KeyPrefix)
+
+	_, err = GetTokenByKey(HashTokenKey(legacyKey), false)
+	assert.Error(t, err, "the stored hash must not work as a key")
+}
+
+func TestTokenKeyRevealModeStoresAndClearsPlainKey(t *testing.T) {
+	truncateTables(t)
+	originalReveal := constant.TokenKeyRevealEnabled
+	t.Cleanup(func() { constant.TokenKeyRevealEnabled = originalReveal })
+
+	constant.TokenKeyRevealEnabled = true
+	legacyKey := "grace0123456789abcdefghijklmnopqrstuvwxyzABCDEFG"
+	legacy := insertLegacyPlainKeyToken(t, "grace", legacyKey)
+	require.NoError(t, migrateTokenKeys())
+
+	var stored Token
+	require.NoError(t, DB.First(&stored, legacy.Id).Error)
+	assert.Equal(t, HashTokenKey(legacyKey), stored.Key)
+	assert.Equal(t, legacyKey, stored.GetFullKey())
+
+	constant.TokenKeyRevealEnabled = false
+	assert.Empty(t, stored.GetFullKey())
+	require.NoError(t, migrateTokenKeys())
+	require.NoError(t, DB.First(&stored, legacy.Id).Error)
+	assert.Empty(t, stored.PlainKey)
+}
+
+func TestSearchUserTokensMatchesPrefixOrFullKey(t *testing.T) {
+	truncateTables(t)
+	rawKey := "srch0123456789abcdefghijklmnopqrstuvwxyzABCDEFGH"
+	token := Token{UserId: 1, Name: "searchable", Status: common.TokenStatusEnabled, ExpiredTime: -1}
+	token.SetKey(rawKey)
+	require.NoError(t, token.Insert())
+
+	byFullKey, total, err := SearchUserTokens(1, "", "sk-"+rawKey, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, token.Id, byFullKey[0].Id)
+
+	byPrefix, total, err := SearchUserTokens(1, "", "srch%", 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.Equal(t, token.Id, byPrefix[0].Id)
+
+	_, total, err = SearchUserTokens(1, "", "srch0123456789abcdefghijklmnopqrstuvwxyzABCDEFGX", 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func TestInitTokenKeySecretPersistsGeneratedSecret(t *testing.T) {
+	require.NoError(t, DB.AutoMigrate(&Option{}))
+	originalSecret := tokenKeySecret
+	originalConfigured := common.CryptoSecretConfigured
+	t.Cleanup(func() {
+		tokenKeySecret = originalSecret
+		common.CryptoSecretConfigured = originalConfigured
+		DB.Where(commonKeyCol+" = ?", tokenKeySecretOptionKey).Delete(&Option{})
+	})
+
+	common.CryptoSecretConfigured = false
+	require.NoError(t, initTokenKeySecret())
+	generated := tokenKeySecret
+	assert.NotEqual(t, common.CryptoSecret, generated)
+
+	// 之后才配置 CRYPTO_SECRET 也继续沿用已持久化的密钥，已有令牌不失效
+	common.CryptoSecretConfigured = true
+	require.NoError(t, initTokenKeySecret())
+	assert.Equal(t, generated, tokenKeySecret)
+}
+
+func TestTokenKeySecretCannotBeChangedThroughOptions(t *testing.T) {
+	require.NoError(t, DB.AutoMigrate(&Option{}))
+	t.Cleanup(func() {
+		DB.Where(commonKeyCol+" = ?", tokenKeySecretOptionKey).Delete(&Option{})
+	})
+
+	assert.ErrorIs(t, UpdateOption(tokenKeySecretOptionKey, "attacker-chosen"), errTokenKeySecretReadOnly)
+	assert.ErrorIs(t, UpdateOptionsBulk(map[string]string{tokenKeySecretOptionKey: "attacker-chosen"}), errTokenKeySecretReadOnly)
+	var count int64
+	require.NoError(t, DB.Model(&Option{}).Where(commonKeyCol+" = ?", tokenKeySecretOptionKey).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestInitTokenKeySecretFailsWithoutOptionsTable(t *testing.T) {
+	originalDB := DB
+	originalSecret := tokenKeySecret
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	DB = db
+	t.Cleanup(func() {
+		DB = originalDB
+		tokenKeySecret = originalSecret
+	})
+
+	assert.Error(t, initTokenKeySecret(), "a node must not fall back to a per-process secret")
+}
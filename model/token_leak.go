@@ -0,0 +1,81 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// FlagTokenLeak 将令牌标记为疑似泄露，quarantine 为 true 时同时进入隔离并吊销其签发的子令牌，
+// 避免持有泄露密钥的人借子令牌绕开隔离。仅在令牌尚未被标记时生效，返回是否由本次调用完成标记，
+// 多个节点同时检测到同一令牌时只有一个会通知所有者。
+func FlagTokenLeak(token *Token, reason string, quarantine bool, now int64) (bool, error) {
+	if cacheErr := invalidateTokenCacheForMutation(token.Key); cacheErr != nil {
+		common.SysLog("failed to invalidate token cache before leak flag: " + cacheErr.Error())
+	}
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	result := DB.Model(&Token{}).Where("id = ? AND leak_flagged_at = ?", token.Id, 0).
+		Updates(map[string]interface{}{
+			"leak_flagged_at": now,
+			"leak_reason":     reason,
+			"quarantined":     quarantine,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.LeakFlaggedAt = now
+	token.LeakReason = reason
+	token.Quarantined = quarantine
+	if quarantine {
+		if err := RevokeChildTokens(token.Id); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// ClearTokenLeak 所有者确认令牌未泄露：解除标记与隔离，并记录确认时间
+func ClearTokenLeak(token *Token, now int64) error {
+	if cacheErr := invalidateTokenCacheForMutation(token.Key); cacheErr != nil {
+		common.SysLog("failed to invalidate token cache before leak confirmation: " + cacheErr.Error())
+	}
+	err := DB.Model(token).Select("leak_flagged_at", "leak_reason", "quarantined", "leak_confirmed_at").
+		Updates(&Token{LeakConfirmedAt: now}).Error
+	if err != nil {
+		return err
+	}
+	token.LeakFlaggedAt = 0
+	token.LeakReason = ""
+	token.Quarantined = false
+	token.LeakConfirmedAt = now
+	return nil
+}
+
+// RotateTokenKey 为令牌更换密钥，保留名称、额度、模型限制等全部设置。
+// 旧密钥立即失效，泄露标记与隔离一并解除，旧密钥签发的子令牌全部吊销。
+func RotateTokenKey(token *Token, newKey string) error {
+	if cacheErr := invalidateTokenCacheForMutation(token.Key); cacheErr != nil {
+		common.SysLog("failed to invalidate token cache before key rotation: " + cacheErr.Error())
+	}
+	token.SetKey(newKey)
+	err := DB.Model(&Token{}).Where("id = ?", token.Id).Updates(map[string]interface{}{
+		"key":               token.Key,
+		"key_prefix":        token.KeyPrefix,
+		"plain_key":         token.PlainKey,
+		"leak_flagged_at":   0,
+		"leak_reason":       "",
+		"quarantined":       false,
+		"leak_confirmed_at": 0,
+	}).Error
+	if err != nil {
+		return err
+	}
+	token.LeakFlaggedAt = 0
+	token.LeakReason = ""
+	token.Quarantined = false
+	token.LeakConfirmedAt = 0
+	return RevokeChildTokens(token.Id)
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createLeakTestToken(t *testing.T, key string) *Token {
+	t.Helper()
+	token := &Token{UserId: 1, Name: "leak", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true, ModelLimits: "gpt-4o"}
+	token.SetKey(key)
+	require.NoError(t, DB.Create(token).Error)
+	return token
+}
+
+func TestFlagTokenLeakOnlyOnce(t *testing.T) {
+	truncateTables(t)
+	token := createLeakTestToken(t, "leak-flag-key")
+
+	flagged, err := FlagTokenLeak(token, "new_country: DE", true, 100)
+	require.NoError(t, err)
+	assert.True(t, flagged)
+
+	stale := *token
+	stale.LeakFlaggedAt = 0
+	flagged, err = FlagTokenLeak(&stale, "distinct_ip: 40 IPs in 10 minutes", false, 200)
+	require.NoError(t, err)
+	assert.False(t, flagged, "a token that is already flagged keeps its first reason")
+
+	stored, err := GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, stored.LeakFlaggedAt)
+	assert.Equal(t, "new_country: DE", stored.LeakReason)
+	assert.True(t, stored.Quarantined)
+
+	require.NoError(t, ClearTokenLeak(stored, 300))
+	stored, err = GetTokenById(token.Id)
+	require.NoError(t, err)
+	assert.Zero(t, stored.LeakFlaggedAt)
+	assert.Empty(t, stored.LeakReason)
+	assert.False(t, stored.Quarantined)
+	assert.EqualValues(t, 300, stored.LeakConfirmedAt)
+}
+
+func TestFlagTokenLeakRevokesChildrenWhenQuarantined(t *testing.T) {
+	truncateTables(t)
+	quarantined := createLeakTestToken(t, "leak-quarantine-key")
+	flaggedOnly := createLeakTestToken(t, "leak-flag-only-key")
+	quarantinedChild := &Token{UserId: 1, Name: "child", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}
+	quarantinedChild.SetKey("leak-quarantine-child")
+	require.NoError(t, CreateChildToken(quarantined, quarantinedChild))
+	flaggedChild := &Token{UserId: 1, Name: "child", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}
+	flaggedChild.SetKey("leak-flag-only-child")
+	require.NoError(t, CreateChildToken(flaggedOnly, flaggedChild))
+
+	_, err := FlagTokenLeak(quarantined, "new_country: DE", true, 100)
+	require.NoError(t, err)
+	_, err = FlagTokenLeak(flaggedOnly, "new_country: DE", false, 100)
+	require.NoError(t, err)
+
+	_, err = GetTokenById(quarantinedChild.Id)
+	assert.Error(t, err, "children of a quarantined token are revoked")
+	_, err = GetTokenById(flaggedChild.Id)
+	assert.NoError(t, err, "flagging without quarantine keeps the children")
+}
+
+func TestRotateTokenKeyKeepsSettings(t *testing.T) {
+	truncateTables(t)
+	token := createLeakTestToken(t, "leak-rotate-old")
+	_, err := FlagTokenLeak(token, "new_origin: https://evil.example", true, 100)
+	require.NoError(t, err)
+	child := &Token{UserId: 1, Name: "child", Status: common.TokenStatusEnabled, ExpiredTime: -1, UnlimitedQuota: true}
+	child.SetKey("leak-rotate-child")
+	require.NoError(t, CreateChildToken(token, child))
+
+	require.NoError(t, RotateTokenKey(token, "leak-rotate-new"))
+
+	_, err = GetTokenByKey("leak-rotate-old", true)
+	assert.Error(t, err)
+	rotated, err := GetTokenByKey("leak-rotate-new", true)
+	require.NoError(t, err)
+	assert.Equal(t, token.Id, rotated.Id)
+	assert.Equal(t, "leak", rotated.Name)
+	assert.Equal(t, "gpt-4o", rotated.ModelLimits)
+	assert.Equal(t, "leak-rot", rotated.KeyPrefix)
+	assert.Zero(t, rotated.LeakFlaggedAt)
+	assert.False(t, rotated.Quarantined)
+	_, err = GetTokenById(child.Id)
+	assert.Error(t, err, "child tokens issued under the old key are revoked")
+}
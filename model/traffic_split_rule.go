@@ -0,0 +1,248 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	MaxTrafficSplitRules          = 50
+	maxTrafficSplitRuleNameLength = 64
+	maxTrafficSplitReasonLength   = 255
+	maxTrafficSplitRampSteps      = 20
+	defaultTrafficSplitMinSamples = 20
+
+	TrafficSplitHashUser  = "user"
+	TrafficSplitHashToken = "token"
+
+	TrafficSplitStatusActive     = "active"
+	TrafficSplitStatusRolledBack = "rolled_back"
+)
+
+var ErrTrafficSplitRuleLimit = fmt.Errorf("at most %d traffic split rules are allowed", MaxTrafficSplitRules)
+
+// TrafficSplitRule 灰度分流规则：对指定模型按用户或令牌哈希稳定地切出一部分请求走 canary 分支，
+// canary 分支可以指定渠道、改写上游模型名，或两者同时使用。放量比例按 RampSteps 逐档随时间提升，
+// canary 的错误率或 p95 延迟相对 perf_metrics 基线超出阈值时自动回滚，回滚后全部请求回到正常选路。
+type TrafficSplitRule struct {
+	Id                   int     `json:"id"`
+	Name                 string  `json:"name" gorm:"type:varchar(64)"`
+	Enabled              bool    `json:"enabled" gorm:"index"`
+	ModelName            string  `json:"model_name" gorm:"type:varchar(255);index"`        // 用户请求的原始模型名
+	Group                string  `json:"group" gorm:"type:varchar(64);default:''"`         // 限定分组，空表示全部
+	CanaryChannelId      int     `json:"canary_channel_id"`                                // canary 分支使用的渠道，0 表示沿用正常选路
+	CanaryModel          string  `json:"canary_model" gorm:"type:varchar(255);default:''"` // canary 分支发往上游的模型名，空表示不改写
+	HashKey              string  `json:"hash_key" gorm:"type:varchar(16);default:'user'"`  // 分桶依据：user 或 token
+	RampSteps            string  `json:"ramp_steps" gorm:"type:varchar(255)"`              // 逐档放量百分比，如 "5,25,50,100"
+	RampIntervalMinutes  int     `json:"ramp_interval_minutes"`                            // 每档持续分钟数，0 表示停留在第一档
+	MaxErrorRateIncrease float64 `json:"max_error_rate_increase"`                          // canary 错误率最多高出基线的百分点，0 表示不检查
+	MaxLatencyIncrease   float64 `json:"max_latency_increase"`                             // canary p95 延迟最多高出基线的百分比，0 表示不检查
+	MinSamples           int     `json:"min_samples"`                                      // canary 与基线样本数都达到后才评估回滚
+	Status               string  `json:"status" gorm:"type:varchar(16);default:'active'"`
+	RollbackReason       string  `json:"rollback_reason" gorm:"type:varchar(255);default:''"`
+	RampStartedAt        int64   `json:"ramp_started_at" gorm:"bigint"`
+	RolledBackAt         int64   `json:"rolled_back_at" gorm:"bigint"`
+	CreatedAt            int64   `json:"created_at" gorm:"bigint"`
+	UpdatedAt            int64   `json:"updated_at" gorm:"bigint"`
+}
+
+// Normalize 校验规则配置并补全默认值
+func (rule *TrafficSplitRule) Normalize() error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || utf8.RuneCountInString(rule.Name) > maxTrafficSplitRuleNameLength {
+		return fmt.Errorf("traffic split rule name must be 1-%d characters", maxTrafficSplitRuleNameLength)
+	}
+	rule.ModelName = strings.TrimSpace(rule.ModelName)
+	if rule.ModelName == "" || len(rule.ModelName) > 255 {
+		return errors.New("model name is required and must be at most 255 characters")
+	}
+	rule.Group = strings.TrimSpace(rule.Group)
+	if len(rule.Group) > 64 {
+		return errors.New("group name is too long")
+	}
+	rule.CanaryModel = strings.TrimSpace(rule.CanaryModel)
+	if len(rule.CanaryModel) > 255 {
+		return errors.New("canary model name is too long")
+	}
+	if rule.CanaryChannelId < 0 {
+		return errors.New("invalid canary channel")
+	}
+	if rule.CanaryChannelId == 0 && (rule.CanaryModel == "" || rule.CanaryModel == rule.ModelName) {
+		return errors.New("a canary channel or a different canary model is required")
+	}
+	switch rule.HashKey {
+	case "":
+		rule.HashKey = TrafficSplitHashUser
+	case TrafficSplitHashUser, TrafficSplitHashToken:
+	default:
+		return errors.New("hash key must be user or token")
+	}
+	steps, err := ParseTrafficSplitRampSteps(rule.RampSteps)
+	if err != nil {
+		return err
+	}
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = strconv.FormatFloat(step, 'f', -1, 64)
+	}
+	rule.RampSteps = strings.Join(parts, ",")
+	if rule.RampIntervalMinutes < 0 {
+		return errors.New("ramp interval must not be negative")
+	}
+	if rule.MaxErrorRateIncrease < 0 || rule.MaxErrorRateIncrease > 100 {
+		return errors.New("error rate threshold must be between 0 and 100")
+	}
+	if rule.MaxLatencyIncrease < 0 {
+		return errors.New("latency threshold must not be negative")
+	}
+	if rule.MinSamples <= 0 {
+		rule.MinSamples = defaultTrafficSplitMinSamples
+	}
+	return nil
+}
+
+// ParseTrafficSplitRampSteps 解析逗号分隔的放量百分比，每档在 (0, 100] 内且不递减
+func ParseTrafficSplitRampSteps(raw string) ([]float64, error) {
+	var steps []float64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		step, err := strconv.ParseFloat(part, 64)
+		if err != nil || step <= 0 || step > 100 {
+			return nil, fmt.Errorf("invalid ramp step %q: must be a percentage greater than 0 and at most 100", part)
+		}
+		if len(steps) > 0 && step < steps[len(steps)-1] {
+			return nil, errors.New("ramp steps must not decrease")
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("at least one ramp step is required")
+	}
+	if len(steps) > maxTrafficSplitRampSteps {
+		return nil, fmt.Errorf("at most %d ramp steps are allowed", maxTrafficSplitRampSteps)
+	}
+	return steps, nil
+}
+
+// Matches 判断请求的原始模型与分组是否命中规则
+func (rule *TrafficSplitRule) Matches(modelName string, group string) bool {
+	if rule.ModelName != modelName {
+		return false
+	}
+	return rule.Group == "" || rule.Group == group
+}
+
+// CurrentPercent 返回 now 时刻 canary 分支的放量百分比。放量档位只由开始时间推算，
+// 各节点无需同步状态；已回滚的规则返回 0。
+func (rule *TrafficSplitRule) CurrentPercent(now int64) float64 {
+	if rule.Status != TrafficSplitStatusActive {
+		return 0
+	}
+	steps, err := ParseTrafficSplitRampSteps(rule.RampSteps)
+	if err != nil {
+		return 0
+	}
+	idx := 0
+	if rule.RampIntervalMinutes > 0 && now > rule.RampStartedAt {
+		idx = int((now - rule.RampStartedAt) / int64(rule.RampIntervalMinutes*60))
+	}
+	if idx >= len(steps) {
+		idx = len(steps) - 1
+	}
+	return steps[idx]
+}
+
+func GetTrafficSplitRules() ([]*TrafficSplitRule, error) {
+	var rules []*TrafficSplitRule
+	err := DB.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func GetTrafficSplitRuleById(id int) (*TrafficSplitRule, error) {
+	var rule TrafficSplitRule
+	if err := DB.Where("id = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetActiveTrafficSplitRules 返回启用且未回滚的规则，供转发链路匹配
+func GetActiveTrafficSplitRules() ([]*TrafficSplitRule, error) {
+	var rules []*TrafficSplitRule
+	err := DB.Where("enabled = ? AND status = ?", true, TrafficSplitStatusActive).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// Insert 创建规则，并从当前时间开始按第一档放量
+func (rule *TrafficSplitRule) Insert() error {
+	var count int64
+	if err := DB.Model(&TrafficSplitRule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= MaxTrafficSplitRules {
+		return ErrTrafficSplitRuleLimit
+	}
+	now := common.GetTimestamp()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.Status = TrafficSplitStatusActive
+	rule.RollbackReason = ""
+	rule.RampStartedAt = now
+	rule.RolledBackAt = 0
+	return DB.Create(rule).Error
+}
+
+// Update 保存规则配置；放量进度与回滚状态只能通过 RestartTrafficSplitRule 与自动回滚修改
+func (rule *TrafficSplitRule) Update() error {
+	rule.UpdatedAt = common.GetTimestamp()
+	return DB.Model(rule).Select("name", "enabled", "model_name", "group", "canary_channel_id", "canary_model",
+		"hash_key", "ramp_steps", "ramp_interval_minutes", "max_error_rate_increase", "max_latency_increase",
+		"min_samples", "updated_at").Updates(rule).Error
+}
+
+// RestartTrafficSplitRule 清除回滚状态，从第一档重新放量
+func RestartTrafficSplitRule(id int) error {
+	now := common.GetTimestamp()
+	return DB.Model(&TrafficSplitRule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          TrafficSplitStatusActive,
+		"rollback_reason": "",
+		"ramp_started_at": now,
+		"rolled_back_at":  0,
+		"updated_at":      now,
+	}).Error
+}
+
+// RollbackTrafficSplitRule 把仍在放量中的规则标记为已回滚，返回本次调用是否完成了回滚，
+// 多个节点同时判定时只有一个会成功
+func RollbackTrafficSplitRule(id int, reason string) (bool, error) {
+	if utf8.RuneCountInString(reason) > maxTrafficSplitReasonLength {
+		reason = string([]rune(reason)[:maxTrafficSplitReasonLength])
+	}
+	now := common.GetTimestamp()
+	result := DB.Model(&TrafficSplitRule{}).Where("id = ? AND status = ?", id, TrafficSplitStatusActive).Updates(map[string]interface{}{
+		"status":          TrafficSplitStatusRolledBack,
+		"rollback_reason": reason,
+		"rolled_back_at":  now,
+		"updated_at":      now,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func DeleteTrafficSplitRule(id int) error {
+	result := DB.Where("id = ?", id).Delete(&TrafficSplitRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("traffic split rule not found")
+	}
+	return nil
+}
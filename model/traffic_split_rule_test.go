@@ -0,0 +1,103 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficSplitRuleNormalize(t *testing.T) {
+	rule := &TrafficSplitRule{Name: " canary ", ModelName: " gpt-4o ", CanaryChannelId: 3, RampSteps: " 5, 25 ,50,100"}
+	require.NoError(t, rule.Normalize())
+	assert.Equal(t, "canary", rule.Name)
+	assert.Equal(t, "gpt-4o", rule.ModelName)
+	assert.Equal(t, "5,25,50,100", rule.RampSteps)
+	assert.Equal(t, TrafficSplitHashUser, rule.HashKey)
+	assert.Equal(t, defaultTrafficSplitMinSamples, rule.MinSamples)
+	assert.True(t, rule.Matches("gpt-4o", "vip"))
+	assert.False(t, rule.Matches("gpt-4o-mini", "vip"))
+
+	modelOnly := &TrafficSplitRule{Name: "x", ModelName: "gpt-4o", CanaryModel: "gpt-4o-2026", RampSteps: "0.5", HashKey: TrafficSplitHashToken}
+	require.NoError(t, modelOnly.Normalize())
+
+	for _, invalid := range []TrafficSplitRule{
+		{Name: "", ModelName: "m", CanaryChannelId: 1, RampSteps: "5"},
+		{Name: "x", CanaryChannelId: 1, RampSteps: "5"},
+		{Name: "x", ModelName: "m", RampSteps: "5"},
+		{Name: "x", ModelName: "m", CanaryModel: "m", RampSteps: "5"},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: ""},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: "0,5"},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: "50,10"},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: "5,101"},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: "5", HashKey: "ip"},
+		{Name: "x", ModelName: "m", CanaryChannelId: 1, RampSteps: "5", MaxErrorRateIncrease: -1},
+	} {
+		assert.Error(t, invalid.Normalize(), invalid)
+	}
+}
+
+func TestTrafficSplitRuleCurrentPercent(t *testing.T) {
+	rule := &TrafficSplitRule{Status: TrafficSplitStatusActive, RampSteps: "5,25,100", RampIntervalMinutes: 10, RampStartedAt: 1000}
+	assert.Equal(t, 5.0, rule.CurrentPercent(1000))
+	assert.Equal(t, 5.0, rule.CurrentPercent(1000+599))
+	assert.Equal(t, 25.0, rule.CurrentPercent(1000+600))
+	assert.Equal(t, 100.0, rule.CurrentPercent(1000+1200))
+	assert.Equal(t, 100.0, rule.CurrentPercent(1000+100000), "the last step is kept")
+
+	rule.RampIntervalMinutes = 0
+	assert.Equal(t, 5.0, rule.CurrentPercent(1000+100000), "without an interval the first step is kept")
+
+	rule.Status = TrafficSplitStatusRolledBack
+	assert.Zero(t, rule.CurrentPercent(1000))
+}
+
+func TestTrafficSplitRuleRollbackAndRestart(t *testing.T) {
+	truncateTables(t)
+
+	rule := &TrafficSplitRule{Name: "canary", Enabled: true, ModelName: "gpt-4o", CanaryChannelId: 2, RampSteps: "5,50"}
+	require.NoError(t, rule.Normalize())
+	require.NoError(t, rule.Insert())
+	disabled := &TrafficSplitRule{Name: "off", ModelName: "gpt-4o", CanaryChannelId: 2, RampSteps: "5"}
+	require.NoError(t, disabled.Normalize())
+	require.NoError(t, disabled.Insert())
+
+	active, err := GetActiveTrafficSplitRules()
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, rule.Id, active[0].Id)
+	assert.Equal(t, TrafficSplitStatusActive, active[0].Status)
+	assert.Positive(t, active[0].RampStartedAt)
+
+	rolledBack, err := RollbackTrafficSplitRule(rule.Id, "error rate too high")
+	require.NoError(t, err)
+	assert.True(t, rolledBack)
+	rolledBack, err = RollbackTrafficSplitRule(rule.Id, "again")
+	require.NoError(t, err)
+	assert.False(t, rolledBack, "only one node completes the rollback")
+
+	stored, err := GetTrafficSplitRuleById(rule.Id)
+	require.NoError(t, err)
+	assert.Equal(t, TrafficSplitStatusRolledBack, stored.Status)
+	assert.Equal(t, "error rate too high", stored.RollbackReason)
+	assert.Positive(t, stored.RolledBackAt)
+
+	stored.RampSteps = "10"
+	require.NoError(t, stored.Update())
+	stored, err = GetTrafficSplitRuleById(rule.Id)
+	require.NoError(t, err)
+	assert.Equal(t, TrafficSplitStatusRolledBack, stored.Status, "editing the rule keeps the rollback state")
+	active, err = GetActiveTrafficSplitRules()
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	require.NoError(t, RestartTrafficSplitRule(rule.Id))
+	stored, err = GetTrafficSplitRuleById(rule.Id)
+	require.NoError(t, err)
+	assert.Equal(t, TrafficSplitStatusActive, stored.Status)
+	assert.Empty(t, stored.RollbackReason)
+	assert.Zero(t, stored.RolledBackAt)
+
+	require.NoError(t, DeleteTrafficSplitRule(rule.Id))
+	assert.Error(t, DeleteTrafficSplitRule(rule.Id))
+}
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AnomalySeverityWarning  = "warning"
+	AnomalySeverityHigh     = "high"
+	AnomalySeverityCritical = "critical"
+
+	AnomalyActionTokenSuspended = "token_suspended"
+)
+
+// AnomalySeverityRank 返回严重级别的排序值，未知级别（包括空字符串）为 0
+func AnomalySeverityRank(severity string) int {
+	switch severity {
+	case AnomalySeverityWarning:
+		return 1
+	case AnomalySeverityHigh:
+		return 2
+	case AnomalySeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// UsageAnomaly 某个用户或令牌在某小时内的异常消耗，由 usage_anomaly 系统任务根据 quota_data 小时数据写入。
+// TokenId 为 0 表示用户维度（该用户全部令牌合计）。同一序列同一小时只保留一条，级别只升不降。
+type UsageAnomaly struct {
+	Id        int     `json:"id"`
+	UserId    int     `json:"user_id" gorm:"uniqueIndex:idx_usage_anomaly_series,priority:1"`
+	TokenId   int     `json:"token_id" gorm:"uniqueIndex:idx_usage_anomaly_series,priority:2"`
+	HourStart int64   `json:"hour_start" gorm:"bigint;uniqueIndex:idx_usage_anomaly_series,priority:3;index"`
+	Username  string  `json:"username" gorm:"type:varchar(64);default:''"`
+	TokenName string  `json:"token_name" gorm:"type:varchar(255);default:''"`
+	Quota     int64   `json:"quota" gorm:"bigint"`
+	Expected  float64 `json:"expected"` // 基线：历史均值与同一小时季节均值中的较大者
+	StdDev    float64 `json:"std_dev"`
+	ZScore    float64 `json:"z_score"`
+	Ratio     float64 `json:"ratio"` // 实际消耗 / 基线
+	Severity  string  `json:"severity" gorm:"type:varchar(16);index"`
+	Action    string  `json:"action" gorm:"type:varchar(32);default:''"` // 已执行的处置，例如 token_suspended
+	CreatedAt int64   `json:"created_at" gorm:"bigint"`
+	UpdatedAt int64   `json:"updated_at" gorm:"bigint"`
+}
+
+// UpsertUsageAnomaly 写入或刷新一条异常记录。返回 true 表示新发现的异常或级别升高，调用方据此决定是否通知或处置；
+// 已存在且级别未升高时只刷新统计值。
+func UpsertUsageAnomaly(anomaly *UsageAnomaly) (bool, error) {
+	now := common.GetTimestamp()
+	var existing UsageAnomaly
+	err := DB.Where("user_id = ? AND token_id = ? AND hour_start = ?", anomaly.UserId, anomaly.TokenId, anomaly.HourStart).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		anomaly.CreatedAt = now
+		anomaly.UpdatedAt = now
+		result := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(anomaly)
+		return result.RowsAffected == 1, result.Error
+	}
+	if err != nil {
+		return false, err
+	}
+	upgraded := AnomalySeverityRank(anomaly.Severity) > AnomalySeverityRank(existing.Severity)
+	if !upgraded {
+		anomaly.Severity = existing.Severity
+	}
+	anomaly.Id = existing.Id
+	anomaly.Action = existing.Action
+	anomaly.CreatedAt = existing.CreatedAt
+	anomaly.UpdatedAt = now
+	err = DB.Model(&UsageAnomaly{}).Where("id = ?", existing.Id).Updates(map[string]interface{}{
+		"quota":      anomaly.Quota,
+		"expected":   anomaly.Expected,
+		"std_dev":    anomaly.StdDev,
+		"z_score":    anomaly.ZScore,
+		"ratio":      anomaly.Ratio,
+		"severity":   anomaly.Severity,
+		"updated_at": now,
+	}).Error
+	return upgraded && err == nil, err
+}
+
+func SetUsageAnomalyAction(id int, action string) error {
+	return DB.Model(&UsageAnomaly{}).Where("id = ?", id).Update("action", action).Error
+}
+
+// UsageAnomalyFilter 查询异常记录的条件，零值表示不限制
+type UsageAnomalyFilter struct {
+	UserId         int
+	TokenId        int
+	Severity       string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func GetUsageAnomalies(filter UsageAnomalyFilter, startIdx int, num int) ([]*UsageAnomaly, int64, error) {
+	tx := DB.Model(&UsageAnomaly{})
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TokenId != 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.Severity != "" {
+		tx = tx.Where("severity = ?", filter.Severity)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("hour_start >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("hour_start <= ?", filter.EndTimestamp)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var anomalies []*UsageAnomaly
+	err := tx.Order("hour_start DESC, id DESC").Limit(num).Offset(startIdx).Find(&anomalies).Error
+	return anomalies, total, err
+}
+
+// UsageHourlyQuota 某用户某令牌在某小时的消耗合计
+type UsageHourlyQuota struct {
+	UserId    int   `gorm:"column:user_id"`
+	TokenId   int   `gorm:"column:token_id"`
+	CreatedAt int64 `gorm:"column:created_at"`
+	Quota     int64 `gorm:"column:quota"`
+}
+
+// GetUsageActiveUserIds 返回 [start, end) 内至少有一个小时合计消耗不低于 minQuota 的用户
+func GetUsageActiveUserIds(start int64, end int64, minQuota int) ([]int, error) {
+	var ids []int
+	err := DB.Table("quota_data").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("user_id, created_at").
+		Having("SUM(quota) >= ?", minQuota).
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]struct{}, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	return unique, nil
+}
+
+// GetUsageHourlyQuotas 按用户、令牌、小时汇总 [start, end) 内的消耗
+func GetUsageHourlyQuotas(userIds []int, start int64, end int64) ([]UsageHourlyQuota, error) {
+	var rows []UsageHourlyQuota
+	if len(userIds) == 0 {
+		return rows, nil
+	}
+	err := DB.Table("quota_data").
+		Select("user_id, token_id, created_at, SUM(quota) AS quota").
+		Where("user_id IN ? AND created_at >= ? AND created_at < ?", userIds, start, end).
+		Group("user_id, token_id, created_at").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// UsageQuotaTotal 某用户在时间范围内的消耗合计
+type UsageQuotaTotal struct {
+	UserId int   `gorm:"column:user_id"`
+	Quota  int64 `gorm:"column:quota"`
+}
+
+// SumUsageQuotaByUser 按用户汇总 [start, end) 内的消耗，只返回有消耗的用户
+func SumUsageQuotaByUser(start int64, end int64) ([]UsageQuotaTotal, error) {
+	var rows []UsageQuotaTotal
+	err := DB.Table("quota_data").
+		Select("user_id, SUM(quota) AS quota").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("user_id").
+		Having("SUM(quota) > 0").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// UsageForecast 用户本月消费预测，由 usage_anomaly 任务刷新，每个用户一条
+type UsageForecast struct {
+	Id                 int     `json:"id"`
+	UserId             int     `json:"user_id" gorm:"uniqueIndex"`
+	Username           string  `json:"username" gorm:"type:varchar(64);default:''"`
+	MonthStart         int64   `json:"month_start" gorm:"bigint;index"`
+	SpentToDate        int64   `json:"spent_to_date" gorm:"bigint"`
+	DailyRate          float64 `json:"daily_rate"` // 最近 7 天（本月不足 7 天时按已过天数）的日均消耗
+	ProjectedMonthEnd  int64   `json:"projected_month_end" gorm:"bigint"`
+	Balance            int64   `json:"balance" gorm:"bigint"`
+	DaysUntilExhausted float64 `json:"days_until_exhausted"` // 按日均消耗余额可用天数，-1 表示无消耗
+	UpdatedAt          int64   `json:"updated_at" gorm:"bigint"`
+}
+
+func UpsertUsageForecast(forecast *UsageForecast) error {
+	forecast.UpdatedAt = common.GetTimestamp()
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"username", "month_start", "spent_to_date", "daily_rate",
+			"projected_month_end", "balance", "days_until_exhausted", "updated_at",
+		}),
+	}).Create(forecast).Error
+}
+
+// DeleteStaleUsageForecasts 删除不属于当前月份的预测，例如上月有消耗而本月没有的用户
+func DeleteStaleUsageForecasts(monthStart int64) error {
+	return DB.Where("month_start <> ?", monthStart).Delete(&UsageForecast{}).Error
+}
+
+func GetUserUsageForecast(userId int) (*UsageForecast, error) {
+	var forecast UsageForecast
+	if err := DB.Where("user_id = ?", userId).First(&forecast).Error; err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+// GetUsageForecasts 按预测月末消耗从高到低返回，username 非空时精确匹配
+func GetUsageForecasts(username string, startIdx int, num int) ([]*UsageForecast, int64, error) {
+	tx := DB.Model(&UsageForecast{})
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var forecasts []*UsageForecast
+	err := tx.Order("projected_month_end DESC, id").Limit(num).Offset(startIdx).Find(&forecasts).Error
+	return forecasts, total, err
+}
+
+// GetUsageForecastUsers 批量读取生成预测所需的用户名和余额
+func GetUsageForecastUsers(ids []int) ([]*User, error) {
+	var users []*User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := DB.Select("id", "username", "quota").Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertUsageAnomalyOnlyReportsUpgrades(t *testing.T) {
+	truncateTables(t)
+
+	anomaly := &UsageAnomaly{UserId: 1, TokenId: 2, HourStart: 3600, Quota: 100, Severity: AnomalySeverityHigh}
+	fresh, err := UpsertUsageAnomaly(anomaly)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	require.NoError(t, SetUsageAnomalyAction(anomaly.Id, AnomalyActionTokenSuspended))
+
+	lower := &UsageAnomaly{UserId: 1, TokenId: 2, HourStart: 3600, Quota: 150, Severity: AnomalySeverityWarning}
+	fresh, err = UpsertUsageAnomaly(lower)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+	assert.Equal(t, AnomalySeverityHigh, lower.Severity, "severity never decreases")
+	assert.Equal(t, AnomalyActionTokenSuspended, lower.Action)
+
+	higher := &UsageAnomaly{UserId: 1, TokenId: 2, HourStart: 3600, Quota: 500, Severity: AnomalySeverityCritical}
+	fresh, err = UpsertUsageAnomaly(higher)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	other := &UsageAnomaly{UserId: 1, HourStart: 3600, Quota: 500, Severity: AnomalySeverityWarning}
+	_, err = UpsertUsageAnomaly(other)
+	require.NoError(t, err)
+
+	anomalies, total, err := GetUsageAnomalies(UsageAnomalyFilter{UserId: 1, TokenId: 2}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	assert.EqualValues(t, 500, anomalies[0].Quota)
+	assert.Equal(t, AnomalySeverityCritical, anomalies[0].Severity)
+
+	_, total, err = GetUsageAnomalies(UsageAnomalyFilter{Severity: AnomalySeverityWarning}, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
+
+func TestUsageForecastUpsertAndStaleCleanup(t *testing.T) {
+	truncateTables(t)
+
+	require.NoError(t, UpsertUsageForecast(&UsageForecast{UserId: 1, Username: "a", MonthStart: 100, ProjectedMonthEnd: 10}))
+	require.NoError(t, UpsertUsageForecast(&UsageForecast{UserId: 2, Username: "b", MonthStart: 200, ProjectedMonthEnd: 20}))
+	require.NoError(t, UpsertUsageForecast(&UsageForecast{UserId: 1, Username: "a", MonthStart: 200, ProjectedMonthEnd: 30}))
+
+	forecasts, total, err := GetUsageForecasts("", 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	assert.Equal(t, 1, forecasts[0].UserId)
+	assert.EqualValues(t, 30, forecasts[0].ProjectedMonthEnd)
+
+	require.NoError(t, UpsertUsageForecast(&UsageForecast{UserId: 3, Username: "c", MonthStart: 100}))
+	require.NoError(t, DeleteStaleUsageForecasts(200))
+	_, err = GetUserUsageForecast(3)
+	assert.Error(t, err)
+	_, total, err = GetUsageForecasts("b", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
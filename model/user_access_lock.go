@@ -0,0 +1,101 @@
+package model
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserAccessLock 记录由身份源（OIDC / 自定义 OAuth 的 claim 映射）托管并锁定的用户字段。
+// 被锁定的字段只随登录时的映射结果变化，后台不能手动修改，避免与身份源漂移。
+// 每个用户只保留最近一次带映射登录的身份源写入的锁。
+type UserAccessLock struct {
+	UserId    int    `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Source    string `json:"source" gorm:"type:varchar(64);not null;default:''"`
+	Fields    string `json:"fields" gorm:"type:varchar(128);not null;default:''"`
+	UpdatedAt int64  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// GetUserLockedFields 返回用户被身份源锁定的字段，未锁定时返回空切片。
+func GetUserLockedFields(userId int) ([]string, error) {
+	var lock UserAccessLock
+	if err := DB.Where("user_id = ?", userId).Limit(1).Find(&lock).Error; err != nil {
+		return nil, err
+	}
+	return splitCommaList(lock.Fields), nil
+}
+
+// IsUserFieldLocked 判断字段是否被身份源锁定；查询失败按锁定处理（fail-closed）。
+func IsUserFieldLocked(userId int, field string) bool {
+	fields, err := GetUserLockedFields(userId)
+	if err != nil {
+		return true
+	}
+	for _, locked := range fields {
+		if locked == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SetUserLockedFields 用 source 的锁定字段替换用户当前的锁，fields 为空时解除锁定。
+func SetUserLockedFields(userId int, source string, fields []string) error {
+	if len(fields) == 0 {
+		return DB.Where("user_id = ?", userId).Delete(&UserAccessLock{}).Error
+	}
+	lock := UserAccessLock{
+		UserId: userId,
+		Source: source,
+		Fields: strings.Join(fields, ","),
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"source", "fields", "updated_at"}),
+	}).Create(&lock).Error
+}
+
+// GetUsableGroups 返回身份源授予用户的额外可用分组。
+func (user *User) GetUsableGroups() []string {
+	return SplitUserUsableGroups(user.UsableGroups)
+}
+
+// SplitUserUsableGroups 解析 users.usable_groups 的逗号分隔存储格式。
+func SplitUserUsableGroups(value string) []string {
+	return splitCommaList(value)
+}
+
+// UpdateUsableGroupsWithTx 写入用户的额外可用分组。可用分组与分组一样决定令牌能
+// 访问哪些分组，变更时推进 auth_version，让缓存与会话按新的授权重新生效。
+func (user *User) UpdateUsableGroupsWithTx(tx *gorm.DB, groups []string) error {
+	value := strings.Join(groups, ",")
+	var current User
+	if err := tx.Select("id", "usable_groups").First(&current, user.Id).Error; err != nil {
+		return err
+	}
+	if current.UsableGroups == value {
+		user.UsableGroups = value
+		return nil
+	}
+	authVersion, err := IncrementUserAuthVersionWithTx(tx, user.Id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&User{}).Where("id = ?", user.Id).Update("usable_groups", value).Error; err != nil {
+		return err
+	}
+	user.UsableGroups = value
+	user.AuthVersion = authVersion
+	return nil
+}
+
+func splitCommaList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
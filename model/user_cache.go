@@ -11,23 +11,25 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const userCacheSchemaVersion = 2
+const userCacheSchemaVersion = 3
 
 type UserBase struct {
-	Id          int    `json:"id"`
-	Group       string `json:"group"`
-	Email       string `json:"email"`
-	Quota       int    `json:"quota"`
-	Status      int    `json:"status"`
-	Role        int    `json:"role"`
-	Username    string `json:"username"`
-	Setting     string `json:"setting"`
-	AuthVersion int64  `json:"-"`
-	CacheSchema int    `json:"-"`
+	Id           int    `json:"id"`
+	Group        string `json:"group"`
+	UsableGroups string `json:"usable_groups"`
+	Email        string `json:"email"`
+	Quota        int    `json:"quota"`
+	Status       int    `json:"status"`
+	Role         int    `json:"role"`
+	Username     string `json:"username"`
+	Setting      string `json:"setting"`
+	AuthVersion  int64  `json:"-"`
+	CacheSchema  int    `json:"-"`
 }
 
 func (user *UserBase) WriteContext(c *gin.Context) {
 	common.SetContextKey(c, constant.ContextKeyUserGroup, user.Group)
+	common.SetContextKey(c, constant.ContextKeyUserUsableGroups, user.GetUsableGroups())
 	common.SetContextKey(c, constant.ContextKeyUserQuota, user.Quota)
 	common.SetContextKey(c, constant.ContextKeyUserStatus, user.Status)
 	common.SetContextKey(c, constant.ContextKeyUserEmail, user.Email)
@@ -46,6 +48,12 @@ func (user *UserBase) GetSetting() dto.UserSetting {
 	return setting
 }
 
+// GetUsableGroups returns the extra groups granted to this user on top of the
+// group-derived usable groups.
+func (user *UserBase) GetUsableGroups() []string {
+	return SplitUserUsableGroups(user.UsableGroups)
+}
+
 // getUserCacheKey returns the key for user cache
 func getUserCacheKey(userId int) string {
 	return fmt.Sprintf("user:%d", userId)
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+// WorkloadIdentityIssuer 是受信任的工作负载身份签发方（CI、Kubernetes 等签发的 OIDC JWT）。
+// 签名通过 JwksURL 或 StaticKeys 校验，Rules 按 claims 把 JWT 映射到用户与令牌模板；
+// 由此签发的网关令牌记录 WorkloadIssuerId，过期后自动清理，停用或修改签发方时一并吊销。
+type WorkloadIdentityIssuer struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name" gorm:"type:varchar(64);not null"`
+	Issuer      string `json:"issuer" gorm:"type:varchar(255);not null;uniqueIndex"` // 与 JWT 的 iss 精确匹配
+	Audience    string `json:"audience" gorm:"type:varchar(255);not null"`           // JWT 的 aud 必须包含该值
+	JwksURL     string `json:"jwks_url" gorm:"type:varchar(512);default:''"`
+	StaticKeys  string `json:"static_keys" gorm:"type:text"`  // JWKS JSON 或 PEM 公钥，配置后不再请求 JwksURL
+	Rules       string `json:"rules" gorm:"type:text"`        // JSON 规则，见 oauth.ParseWorkloadRules
+	AllowDirect bool   `json:"allow_direct"`                  // 允许直接以 JWT 作为中转请求的 Bearer 凭据
+	TokenTTL    int    `json:"token_ttl" gorm:"default:3600"` // 签发令牌的最长有效期（秒），不超过 JWT 本身的 exp
+	Enabled     bool   `json:"enabled" gorm:"default:false"`
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+const workloadTokenCleanupBatchSize = 500
+
+var ErrWorkloadIssuerTaken = errors.New("workload identity issuer is already registered")
+
+// ErrWorkloadQuotaExhausted 表示工作负载名下的令牌已领完规则的额度上限。
+var ErrWorkloadQuotaExhausted = errors.New("workload token quota is exhausted")
+
+func GetAllWorkloadIdentityIssuers() ([]*WorkloadIdentityIssuer, error) {
+	var issuers []*WorkloadIdentityIssuer
+	err := DB.Order("id asc").Find(&issuers).Error
+	return issuers, err
+}
+
+func GetWorkloadIdentityIssuerById(id int) (*WorkloadIdentityIssuer, error) {
+	var issuer WorkloadIdentityIssuer
+	if err := DB.First(&issuer, id).Error; err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+// GetEnabledWorkloadIdentityIssuer 按 JWT 的 iss 查找已启用的签发方。
+func GetEnabledWorkloadIdentityIssuer(iss string) (*WorkloadIdentityIssuer, error) {
+	var issuer WorkloadIdentityIssuer
+	if err := DB.Where("issuer = ? AND enabled = ?", iss, true).First(&issuer).Error; err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func isWorkloadIssuerTaken(iss string, excludeId int) bool {
+	var count int64
+	DB.Model(&WorkloadIdentityIssuer{}).Where("issuer = ? AND id <> ?", iss, excludeId).Count(&count)
+	return count > 0
+}
+
+func CreateWorkloadIdentityIssuer(issuer *WorkloadIdentityIssuer) error {
+	issuer.Issuer = strings.TrimSpace(issuer.Issuer)
+	if isWorkloadIssuerTaken(issuer.Issuer, 0) {
+		return ErrWorkloadIssuerTaken
+	}
+	return DB.Create(issuer).Error
+}
+
+// UpdateWorkloadIdentityIssuer 保存签发方配置并吊销其已签发的令牌：
+// 规则、模板或信任的密钥变化后，旧令牌不应继续沿用旧的授权。
+func UpdateWorkloadIdentityIssuer(issuer *WorkloadIdentityIssuer) error {
+	issuer.Issuer = strings.TrimSpace(issuer.Issuer)
+	if isWorkloadIssuerTaken(issuer.Issuer, issuer.Id) {
+		return ErrWorkloadIssuerTaken
+	}
+	if err := DB.Save(issuer).Error; err != nil {
+		return err
+	}
+	return RevokeWorkloadIssuerTokens(issuer.Id)
+}
+
+func DeleteWorkloadIdentityIssuer(id int) error {
+	if err := DB.Delete(&WorkloadIdentityIssuer{}, id).Error; err != nil {
+		return err
+	}
+	return RevokeWorkloadIssuerTokens(id)
+}
+
+// RevokeWorkloadIssuerTokens 删除签发方签发的全部令牌，并清理其 Redis 缓存。
+func RevokeWorkloadIssuerTokens(issuerId int) error {
+	if issuerId <= 0 {
+		return nil
+	}
+	return deleteWorkloadTokens("workload_issuer_id = ?", issuerId)
+}
+
+// GetWorkloadDirectToken 查找签发方以直连方式为某个工作负载签发给指定用户的令牌。
+func GetWorkloadDirectToken(issuerId int, userId int, subjectHash string) (*Token, error) {
+	var token Token
+	err := DB.Where("workload_issuer_id = ? AND user_id = ? AND workload_subject = ? AND workload_direct = ?", issuerId, userId, subjectHash, true).
+		Order("id").First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateWorkloadToken 在工作负载的额度上限内签发令牌：同一签发方下归属同一用户、sub 相同的现存令牌
+// （直连与交换共用）已发放的额度合计不超过 quotaCap，新令牌只获得尚未发放的部分，
+// 已无剩余时返回 ErrWorkloadQuotaExhausted。令牌被清理或删除后其额度重新计入可发放部分。
+func CreateWorkloadToken(token *Token, quotaCap int) error {
+	if token.UnlimitedQuota {
+		return token.Insert()
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定签发方，同一签发方的并发签发依次计算剩余额度
+		if err := lockForUpdate(tx).Select("id").Where("id = ?", token.WorkloadIssuerId).
+			First(&WorkloadIdentityIssuer{}).Error; err != nil {
+			return err
+		}
+		var granted int64
+		if err := tx.Model(&Token{}).
+			Where("workload_issuer_id = ? AND user_id = ? AND workload_subject = ?", token.WorkloadIssuerId, token.UserId, token.WorkloadSubject).
+			Select("COALESCE(SUM(remain_quota + used_quota), 0)").Scan(&granted).Error; err != nil {
+			return err
+		}
+		remaining := int64(quotaCap) - granted
+		if remaining <= 0 {
+			return ErrWorkloadQuotaExhausted
+		}
+		token.RemainQuota = int(remaining)
+		return tx.Create(token).Error
+	})
+}
+
+// RenewWorkloadDirectToken 在工作负载出示新的 JWT 后把令牌有效期延长到 expiredTime（不会缩短），
+// 并重新启用已标记为过期的令牌；剩余额度保持不变。
+func RenewWorkloadDirectToken(token *Token, expiredTime int64) error {
+	if expiredTime <= token.ExpiredTime && token.Status != common.TokenStatusExpired {
+		return nil
+	}
+	if expiredTime > token.ExpiredTime {
+		token.ExpiredTime = expiredTime
+	}
+	if token.Status == common.TokenStatusExpired {
+		token.Status = common.TokenStatusEnabled
+	}
+	if err := invalidateTokenCacheForMutation(token.Key); err != nil {
+		common.SysLog("failed to invalidate workload token cache before renew: " + err.Error())
+	}
+	return DB.Model(token).Select("expired_time", "status").Updates(token).Error
+}
+
+// DeleteExpiredWorkloadTokens 清理已过期的工作负载令牌。过期后不会再被使用（直连令牌
+// 在工作负载出示新 JWT 时会重新签发），直接物理删除，避免令牌表随 CI 任务数量无限增长。
+func DeleteExpiredWorkloadTokens(now int64) error {
+	return deleteWorkloadTokens("workload_issuer_id > 0 AND expired_time <> -1 AND expired_time < ?", now)
+}
+
+func deleteWorkloadTokens(query string, args ...any) error {
+	for {
+		var tokens []Token
+		if err := DB.Unscoped().Where(query, args...).
+			Order("id").Limit(workloadTokenCleanupBatchSize).Find(&tokens).Error; err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+		if err := invalidateTokensCache(tokens); err != nil {
+			common.SysLog("failed to invalidate workload token cache: " + err.Error())
+		}
+		ids := make([]int, 0, len(tokens))
+		for _, token := range tokens {
+			ids = append(ids, token.Id)
+		}
+		if err := DB.Unscoped().Where("id IN ?", ids).Delete(&Token{}).Error; err != nil {
+			return err
+		}
+		if len(tokens) < workloadTokenCleanupBatchSize {
+			return nil
+		}
+	}
+}
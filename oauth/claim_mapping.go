@@ -0,0 +1,228 @@
+package oauth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/samber/lo"
+)
+
+// Claim mapping targets. Only group and role can be locked: usable groups are
+// never edited by admins, and quota is applied once when the user is created.
+const (
+	ClaimFieldGroup        = "group"
+	ClaimFieldUsableGroups = "usable_groups"
+	ClaimFieldRole         = "role"
+	ClaimFieldQuota        = "quota"
+
+	ClaimRoleAdmin = "admin"
+	ClaimRoleUser  = "user"
+)
+
+// ExtraClaimsKey is the OAuthUser.Extra key holding the raw claims JSON the
+// claim mapping is evaluated against.
+const ExtraClaimsKey = "claims"
+
+var lockableClaimFields = []string{ClaimFieldGroup, ClaimFieldRole}
+
+// ClaimMapping derives a user's group, usable groups, role and initial quota
+// from the claims of an OIDC / custom OAuth login. Rule conditions use the
+// access policy syntax (gjson paths with eq/in/contains/... operators) and are
+// re-evaluated on every login.
+type ClaimMapping struct {
+	Rules []ClaimMappingRule `json:"rules"`
+	// DefaultGroup is used when no matching rule sets a group. Setting it makes
+	// the group managed even if no rule mentions one.
+	DefaultGroup string `json:"default_group,omitempty"`
+	// LockedFields lists managed fields admins may not change by hand.
+	LockedFields []string `json:"locked_fields,omitempty"`
+}
+
+// ClaimMappingRule applies its targets when When matches. An empty When
+// matches every login.
+type ClaimMappingRule struct {
+	When         accessPolicy `json:"when"`
+	Group        string       `json:"group,omitempty"`
+	UsableGroups []string     `json:"usable_groups,omitempty"`
+	Role         string       `json:"role,omitempty"`
+	Quota        *int         `json:"quota,omitempty"`
+}
+
+// ClaimMappingResult is the outcome of evaluating a mapping. Empty Group/Role
+// and nil UsableGroups mean the mapping does not manage that field; a non-nil
+// empty UsableGroups clears the user's extra groups.
+type ClaimMappingResult struct {
+	Group        string
+	UsableGroups []string
+	Role         string
+	Quota        *int
+	LockedFields []string
+}
+
+// ParseClaimMapping parses and validates a claim mapping. An empty string
+// yields a nil mapping.
+func ParseClaimMapping(raw string) (*ClaimMapping, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var mapping ClaimMapping
+	if err := common.UnmarshalJsonStr(raw, &mapping); err != nil {
+		return nil, errors.New("claim mapping must be valid JSON")
+	}
+	if err := validateClaimMapping(&mapping); err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func validateClaimMapping(mapping *ClaimMapping) error {
+	mapping.DefaultGroup = strings.TrimSpace(mapping.DefaultGroup)
+	for index := range mapping.Rules {
+		rule := &mapping.Rules[index]
+		if len(rule.When.Conditions) > 0 || len(rule.When.Groups) > 0 {
+			if err := validateAccessPolicy(&rule.When); err != nil {
+				return fmt.Errorf("rules[%d].when: %w", index, err)
+			}
+		}
+		rule.Group = strings.TrimSpace(rule.Group)
+		rule.Role = strings.ToLower(strings.TrimSpace(rule.Role))
+		if rule.Role != "" && rule.Role != ClaimRoleAdmin && rule.Role != ClaimRoleUser {
+			return fmt.Errorf("rules[%d].role must be %q or %q", index, ClaimRoleAdmin, ClaimRoleUser)
+		}
+		if rule.Quota != nil && *rule.Quota < 0 {
+			return fmt.Errorf("rules[%d].quota must not be negative", index)
+		}
+		for groupIndex, group := range rule.UsableGroups {
+			if rule.UsableGroups[groupIndex] = strings.TrimSpace(group); rule.UsableGroups[groupIndex] == "" {
+				return fmt.Errorf("rules[%d].usable_groups[%d] is empty", index, groupIndex)
+			}
+		}
+		if rule.Group == "" && len(rule.UsableGroups) == 0 && rule.Role == "" && rule.Quota == nil {
+			return fmt.Errorf("rules[%d] must set group, usable_groups, role or quota", index)
+		}
+	}
+	for index, field := range mapping.LockedFields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !lo.Contains(lockableClaimFields, field) {
+			return fmt.Errorf("locked_fields[%d] is unsupported: %s", index, field)
+		}
+		mapping.LockedFields[index] = field
+	}
+	return nil
+}
+
+// Manages reports whether the mapping controls the given field.
+func (m *ClaimMapping) Manages(field string) bool {
+	if m == nil {
+		return false
+	}
+	if field == ClaimFieldGroup && m.DefaultGroup != "" {
+		return true
+	}
+	for _, rule := range m.Rules {
+		switch field {
+		case ClaimFieldGroup:
+			if rule.Group != "" {
+				return true
+			}
+		case ClaimFieldUsableGroups:
+			if len(rule.UsableGroups) > 0 {
+				return true
+			}
+		case ClaimFieldRole:
+			if rule.Role != "" {
+				return true
+			}
+		case ClaimFieldQuota:
+			if rule.Quota != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Evaluate applies the rules to the claims JSON. The group comes from the first
+// matching rule that sets one (then DefaultGroup, then "default"); usable groups
+// are the union over matching rules; the role is admin when any matching rule
+// grants it; the quota comes from the first matching rule that sets one.
+func (m *ClaimMapping) Evaluate(claims string) ClaimMappingResult {
+	var result ClaimMappingResult
+	if m == nil {
+		return result
+	}
+	if m.Manages(ClaimFieldUsableGroups) {
+		result.UsableGroups = []string{}
+	}
+	if m.Manages(ClaimFieldRole) {
+		result.Role = ClaimRoleUser
+	}
+	for _, rule := range m.Rules {
+		if matched, _ := evaluateAccessPolicy(claims, &rule.When); !matched {
+			continue
+		}
+		if result.Group == "" && rule.Group != "" {
+			result.Group = rule.Group
+		}
+		for _, group := range rule.UsableGroups {
+			if !lo.Contains(result.UsableGroups, group) {
+				result.UsableGroups = append(result.UsableGroups, group)
+			}
+		}
+		if rule.Role == ClaimRoleAdmin {
+			result.Role = ClaimRoleAdmin
+		}
+		if result.Quota == nil && rule.Quota != nil {
+			quota := *rule.Quota
+			result.Quota = &quota
+		}
+	}
+	if result.Group == "" && m.Manages(ClaimFieldGroup) {
+		result.Group = m.DefaultGroup
+		if result.Group == "" {
+			result.Group = "default"
+		}
+	}
+	for _, field := range m.LockedFields {
+		if m.Manages(field) {
+			result.LockedFields = append(result.LockedFields, field)
+		}
+	}
+	return result
+}
+
+// mergeIDTokenClaims overlays the userinfo response on the ID token payload so
+// mappings can use claims that only one of them carries (many IdPs put groups
+// in the ID token only). The ID token signature is not verified: it is only
+// read here, after being received directly from the token endpoint over TLS
+// (OIDC Core 3.1.3.7), and never used to establish the identity itself.
+func mergeIDTokenClaims(userInfo string, idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return userInfo
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return userInfo
+	}
+	claims := map[string]any{}
+	if err := common.Unmarshal(payload, &claims); err != nil {
+		return userInfo
+	}
+	info := map[string]any{}
+	if err := common.UnmarshalJsonStr(userInfo, &info); err != nil {
+		return userInfo
+	}
+	for key, value := range info {
+		claims[key] = value
+	}
+	merged, err := common.Marshal(claims)
+	if err != nil {
+		return userInfo
+	}
+	return string(merged)
+}
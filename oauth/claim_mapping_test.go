@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testClaimMapping = `{
+	"rules": [
+		{"when": {"conditions": [{"field": "groups", "op": "contains", "value": "platform-admins"}]}, "role": "admin"},
+		{"when": {"conditions": [{"field": "department", "op": "eq", "value": "research"}]}, "group": "vip", "usable_groups": ["gpu"], "quota": 500000},
+		{"when": {"conditions": [{"field": "groups", "op": "contains", "value": "engineering"}]}, "group": "svip", "usable_groups": ["gpu", "batch"]},
+		{"when": {"conditions": [{"field": "org.tier", "op": "in", "value": ["gold", "platinum"]}]}, "usable_groups": ["priority"]}
+	],
+	"default_group": "default",
+	"locked_fields": ["group", "ROLE"]
+}`
+
+func TestParseClaimMapping(t *testing.T) {
+	mapping, err := ParseClaimMapping("  ")
+	require.NoError(t, err)
+	assert.Nil(t, mapping)
+
+	mapping, err = ParseClaimMapping(testClaimMapping)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ClaimFieldGroup, ClaimFieldRole}, mapping.LockedFields)
+	assert.True(t, mapping.Manages(ClaimFieldUsableGroups))
+	assert.True(t, mapping.Manages(ClaimFieldQuota))
+
+	for _, raw := range []string{
+		`{"rules": [{"when": {"conditions": [{"field": "groups", "op": "matches", "value": "x"}]}, "group": "vip"}]}`,
+		`{"rules": [{"role": "root"}]}`,
+		`{"rules": [{"quota": -1}]}`,
+		`{"rules": [{"when": {"conditions": [{"field": "groups", "op": "exists"}]}}]}`,
+		`{"rules": [{"usable_groups": [" "]}]}`,
+		`{"locked_fields": ["quota"]}`,
+		`not json`,
+	} {
+		_, err := ParseClaimMapping(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestClaimMappingEvaluate(t *testing.T) {
+	mapping, err := ParseClaimMapping(testClaimMapping)
+	require.NoError(t, err)
+
+	result := mapping.Evaluate(`{"groups": ["engineering", "platform-admins"], "department": "research", "org": {"tier": "gold"}}`)
+	assert.Equal(t, "vip", result.Group, "the first matching rule that sets a group wins")
+	assert.Equal(t, []string{"gpu", "batch", "priority"}, result.UsableGroups)
+	assert.Equal(t, ClaimRoleAdmin, result.Role)
+	require.NotNil(t, result.Quota)
+	assert.Equal(t, 500000, *result.Quota)
+	assert.Equal(t, []string{ClaimFieldGroup, ClaimFieldRole}, result.LockedFields)
+
+	result = mapping.Evaluate(`{"groups": ["sales"]}`)
+	assert.Equal(t, "default", result.Group)
+	assert.NotNil(t, result.UsableGroups)
+	assert.Empty(t, result.UsableGroups, "managed usable groups are cleared when no rule matches")
+	assert.Equal(t, ClaimRoleUser, result.Role)
+	assert.Nil(t, result.Quota)
+
+	groupOnly, err := ParseClaimMapping(`{"rules": [{"when": {"conditions": [{"field": "groups", "op": "contains", "value": "engineering"}]}, "group": "svip"}], "locked_fields": ["role"]}`)
+	require.NoError(t, err)
+	result = groupOnly.Evaluate(`{}`)
+	assert.Equal(t, "default", result.Group)
+	assert.Nil(t, result.UsableGroups)
+	assert.Empty(t, result.Role)
+	assert.Empty(t, result.LockedFields, "unmanaged fields are never locked")
+}
+
+func TestMergeIDTokenClaims(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "alice", "groups": ["engineering"], "email": "old@example.com"}`))
+	idToken := "eyJhbGciOiJSUzI1NiJ9." + payload + ".signature"
+
+	merged := mergeIDTokenClaims(`{"sub": "alice", "email": "alice@example.com"}`, idToken)
+	mapping, err := ParseClaimMapping(`{"rules": [{"when": {"conditions": [{"field": "groups", "op": "contains", "value": "engineering"}, {"field": "email", "op": "eq", "value": "alice@example.com"}]}, "group": "svip"}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, "svip", mapping.Evaluate(merged).Group, "userinfo overrides the ID token and ID-token-only claims are kept")
+
+	assert.Equal(t, `{"sub": "alice"}`, mergeIDTokenClaims(`{"sub": "alice"}`, ""))
+	assert.Equal(t, `{"sub": "alice"}`, mergeIDTokenClaims(`{"sub": "alice"}`, "a.!!.c"))
+}
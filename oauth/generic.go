@@ -285,7 +285,8 @@ func (p *GenericOAuthProvider) GetUserInfo(ctx context.Context, token *OAuthToke
 		DisplayName:    displayName,
 		Email:          email,
 		Extra: map[string]any{
-			"provider": p.config.Slug,
+			"provider":     p.config.Slug,
+			ExtraClaimsKey: mergeIDTokenClaims(bodyStr, token.IDToken),
 		},
 	}, nil
 }
@@ -317,6 +318,11 @@ func (p *GenericOAuthProvider) ProviderUserIDColumn() string {
 	return ""
 }
 
+// ClaimMapping returns the provider's claim mapping configuration.
+func (p *GenericOAuthProvider) ClaimMapping() string {
+	return p.config.ClaimMapping
+}
+
 // GetProviderId returns the provider ID for binding purposes
 func (p *GenericOAuthProvider) GetProviderId() int {
 	return p.config.Id
@@ -0,0 +1,282 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ldapTimeout = 5 * time.Second
+
+// ldapSubjectMaxLength matches external_identity_claims.subject.
+const ldapSubjectMaxLength = 128
+
+var (
+	// ErrLDAPInvalidCredentials covers unknown users, ambiguous filters and wrong
+	// passwords alike so the login endpoint cannot be used to enumerate accounts.
+	ErrLDAPInvalidCredentials = errors.New("ldap: invalid credentials")
+	ErrLDAPNotConfigured      = errors.New("ldap: server url, base dn and a user filter containing %s are required")
+)
+
+// LDAPProvider authenticates users directly against an LDAP / Active Directory
+// server with the bind + search pattern. It receives the user's password
+// instead of an authorization code, so it is not part of the OAuth callback
+// registry; controller.LDAPLogin drives it and links accounts through
+// external identity claims.
+type LDAPProvider struct{}
+
+func (p *LDAPProvider) GetName() string {
+	return system_setting.GetLDAPSettings().GetEffectiveDisplayName()
+}
+
+func (p *LDAPProvider) IsEnabled() bool {
+	return system_setting.GetLDAPSettings().Enabled
+}
+
+func (p *LDAPProvider) GetProviderPrefix() string {
+	return "ldap_"
+}
+
+// Authenticate looks the user up with the service account, then binds as the
+// found entry to verify the password. On success the returned user carries the
+// entry's group memberships in Extra["groups"] and its DN in Extra["dn"].
+func (p *LDAPProvider) Authenticate(ctx context.Context, settings *system_setting.LDAPSettings, username string, password string) (*OAuthUser, error) {
+	username = strings.TrimSpace(username)
+	// 空密码会被服务器当作匿名绑定并返回成功，必须在这里拒绝
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	conn, err := p.connect(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := p.searchUser(ctx, conn, settings, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			logger.LogDebug(ctx, "[LDAP] Authenticate: bind rejected for dn=%s", entry.DN)
+			return nil, ErrLDAPInvalidCredentials
+		}
+		logger.LogError(ctx, fmt.Sprintf("[LDAP] Authenticate bind error: %s", err.Error()))
+		return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, map[string]any{"Provider": settings.GetEffectiveDisplayName()}, err.Error())
+	}
+	return ldapEntryToUser(settings, entry, username)
+}
+
+// TestConnection checks that the server is reachable and the service account
+// can bind. When username is non-empty it also runs the user search and
+// returns the mapped entry, without verifying any user password.
+func (p *LDAPProvider) TestConnection(ctx context.Context, settings *system_setting.LDAPSettings, username string) (*OAuthUser, error) {
+	conn, err := p.connect(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, nil
+	}
+	entry, err := p.searchUser(ctx, conn, settings, username)
+	if err != nil {
+		return nil, err
+	}
+	return ldapEntryToUser(settings, entry, username)
+}
+
+// connect dials the server, upgrades with StartTLS when configured and binds
+// as the service account (or stays anonymous when no bind DN is set).
+func (p *LDAPProvider) connect(ctx context.Context, settings *system_setting.LDAPSettings) (*ldap.Conn, error) {
+	if strings.TrimSpace(settings.ServerURL) == "" || strings.TrimSpace(settings.BaseDN) == "" ||
+		!strings.Contains(settings.UserFilter, "%s") {
+		return nil, ErrLDAPNotConfigured
+	}
+	serverURL, err := url.Parse(strings.TrimSpace(settings.ServerURL))
+	if err != nil || (serverURL.Scheme != "ldap" && serverURL.Scheme != "ldaps") {
+		return nil, fmt.Errorf("ldap: server url must start with ldap:// or ldaps://")
+	}
+	providerParams := map[string]any{"Provider": settings.GetEffectiveDisplayName()}
+	tlsConfig := &tls.Config{
+		ServerName:         serverURL.Hostname(),
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+
+	logger.LogDebug(ctx, "[LDAP] connect: server=%s, start_tls=%t", serverURL.Redacted(), settings.StartTLS)
+	conn, err := ldap.DialURL(serverURL.String(),
+		ldap.DialWithDialer(&net.Dialer{Timeout: ldapTimeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("[LDAP] connect error: %s", err.Error()))
+		return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, providerParams, err.Error())
+	}
+	conn.SetTimeout(ldapTimeout)
+
+	if settings.StartTLS && serverURL.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			logger.LogError(ctx, fmt.Sprintf("[LDAP] StartTLS error: %s", err.Error()))
+			return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, providerParams, err.Error())
+		}
+	}
+
+	if bindDN := strings.TrimSpace(settings.BindDN); bindDN != "" {
+		if err := conn.Bind(bindDN, settings.BindSecret); err != nil {
+			conn.Close()
+			logger.LogError(ctx, fmt.Sprintf("[LDAP] service bind error: %s", err.Error()))
+			return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthConnectFailed, providerParams, err.Error())
+		}
+	}
+	return conn, nil
+}
+
+func (p *LDAPProvider) searchUser(ctx context.Context, conn *ldap.Conn, settings *system_setting.LDAPSettings, username string) (*ldap.Entry, error) {
+	filter := strings.ReplaceAll(settings.UserFilter, "%s", ldap.EscapeFilter(username))
+	attributes := []string{}
+	for _, attr := range []string{
+		settings.IdentityAttribute,
+		settings.UsernameAttribute,
+		settings.EmailAttribute,
+		settings.DisplayNameAttribute,
+		settings.GroupMembershipAttr,
+	} {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			attributes = append(attributes, attr)
+		}
+	}
+	// 只取两条即可区分「唯一命中」与「过滤条件不唯一」
+	request := ldap.NewSearchRequest(
+		strings.TrimSpace(settings.BaseDN),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(ldapTimeout/time.Second), false,
+		filter, attributes, nil,
+	)
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		logger.LogError(ctx, fmt.Sprintf("[LDAP] search error: %s", err.Error()))
+		return nil, NewOAuthErrorWithRaw(i18n.MsgOAuthGetUserErr, nil, err.Error())
+	}
+	if result == nil || len(result.Entries) != 1 {
+		count := 0
+		if result != nil {
+			count = len(result.Entries)
+		}
+		logger.LogDebug(ctx, "[LDAP] search: filter=%s matched %d entries", filter, count)
+		return nil, ErrLDAPInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+func ldapEntryToUser(settings *system_setting.LDAPSettings, entry *ldap.Entry, loginName string) (*OAuthUser, error) {
+	user := &OAuthUser{
+		ProviderUserID: ldapSubject(entry, strings.TrimSpace(settings.IdentityAttribute)),
+		Username:       loginName,
+		Extra: map[string]any{
+			"dn":     entry.DN,
+			"groups": []string{},
+		},
+	}
+	if attr := strings.TrimSpace(settings.UsernameAttribute); attr != "" {
+		if value := strings.TrimSpace(entry.GetAttributeValue(attr)); value != "" {
+			user.Username = value
+		}
+	}
+	if attr := strings.TrimSpace(settings.EmailAttribute); attr != "" {
+		user.Email = strings.TrimSpace(entry.GetAttributeValue(attr))
+	}
+	if attr := strings.TrimSpace(settings.DisplayNameAttribute); attr != "" {
+		user.DisplayName = strings.TrimSpace(entry.GetAttributeValue(attr))
+	}
+	if attr := strings.TrimSpace(settings.GroupMembershipAttr); attr != "" {
+		user.Extra["groups"] = entry.GetAttributeValues(attr)
+	}
+	if user.ProviderUserID == "" {
+		return nil, NewOAuthError(i18n.MsgOAuthUserInfoEmpty, map[string]any{"Provider": settings.GetEffectiveDisplayName()})
+	}
+	return user, nil
+}
+
+// ldapSubject returns the stable identifier used to link the entry to a local
+// account. Binary identifiers such as AD objectGUID are hex encoded; when the
+// attribute is missing the normalized DN is used, hashed if it would not fit
+// into the claim column.
+func ldapSubject(entry *ldap.Entry, attr string) string {
+	if attr != "" {
+		raw := entry.GetRawAttributeValue(attr)
+		if len(raw) > 0 {
+			if isPrintableLDAPValue(raw) {
+				return limitLDAPSubject(string(raw))
+			}
+			return limitLDAPSubject(hex.EncodeToString(raw))
+		}
+	}
+	dn := strings.ToLower(entry.DN)
+	if parsed, err := ldap.ParseDN(entry.DN); err == nil {
+		dn = strings.ToLower(parsed.String())
+	}
+	if dn == "" {
+		return ""
+	}
+	return limitLDAPSubject("dn:" + dn)
+}
+
+func limitLDAPSubject(subject string) string {
+	if len(subject) <= ldapSubjectMaxLength {
+		return subject
+	}
+	sum := sha256.Sum256([]byte(subject))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func isPrintableLDAPValue(raw []byte) bool {
+	if !utf8.Valid(raw) {
+		return false
+	}
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// LDAPGroupMapping returns the mapping value configured for a group DN. Keys
+// match either the full DN or the value of its first RDN (e.g. "Admins" for
+// "CN=Admins,OU=Groups,DC=corp,DC=example"), both case-insensitively.
+func LDAPGroupMapping(mapping map[string]string, groupDN string) string {
+	if len(mapping) == 0 {
+		return ""
+	}
+	candidates := []string{groupDN}
+	if parsed, err := ldap.ParseDN(groupDN); err == nil && len(parsed.RDNs) > 0 && len(parsed.RDNs[0].Attributes) > 0 {
+		candidates = append(candidates, parsed.String(), parsed.RDNs[0].Attributes[0].Value)
+	}
+	for _, candidate := range candidates {
+		for key, value := range mapping {
+			if strings.EqualFold(strings.TrimSpace(key), strings.TrimSpace(candidate)) {
+				if value = strings.TrimSpace(value); value != "" {
+					return value
+				}
+			}
+		}
+	}
+	return ""
+}
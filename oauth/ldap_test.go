@@ -0,0 +1,124 @@
+package oauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/oauth/ldaptest"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ldapTestServiceDN = "cn=service,dc=corp,dc=example"
+	ldapTestAliceDN   = "CN=Alice Liddell,OU=People,DC=corp,DC=example"
+)
+
+func newLDAPTestServer(t *testing.T) (*ldaptest.Server, *system_setting.LDAPSettings) {
+	t.Helper()
+	server := ldaptest.NewServer(
+		ldaptest.Entry{DN: ldapTestServiceDN, Password: "service-secret"},
+		ldaptest.Entry{
+			DN:       ldapTestAliceDN,
+			Password: "wonderland",
+			Attributes: map[string][]string{
+				"sAMAccountName": {"alice"},
+				"mail":           {"Alice@Corp.Example"},
+				"displayName":    {"Alice Liddell"},
+				"objectGUID":     {string([]byte{0x10, 0x00, 0xfe, 0x01})},
+				"memberOf": {
+					"CN=Engineering,OU=Groups,DC=corp,DC=example",
+					"CN=Admins,OU=Groups,DC=corp,DC=example",
+				},
+			},
+		},
+		ldaptest.Entry{DN: "CN=Dup One,OU=People,DC=corp,DC=example", Password: "x", Attributes: map[string][]string{"sAMAccountName": {"dup"}}},
+		ldaptest.Entry{DN: "CN=Dup Two,OU=People,DC=corp,DC=example", Password: "x", Attributes: map[string][]string{"sAMAccountName": {"dup"}}},
+	)
+	t.Cleanup(server.Close)
+	return server, &system_setting.LDAPSettings{
+		ServerURL:            server.URL,
+		BindDN:               ldapTestServiceDN,
+		BindSecret:           "service-secret",
+		BaseDN:               "DC=corp,DC=example",
+		UserFilter:           "(&(sAMAccountName=%s)(!(disabled=true)))",
+		IdentityAttribute:    "objectGUID",
+		UsernameAttribute:    "sAMAccountName",
+		EmailAttribute:       "mail",
+		DisplayNameAttribute: "displayName",
+		GroupMembershipAttr:  "memberOf",
+	}
+}
+
+func TestLDAPProvider_Authenticate(t *testing.T) {
+	server, settings := newLDAPTestServer(t)
+	p := &LDAPProvider{}
+
+	user, err := p.Authenticate(context.Background(), settings, "alice", "wonderland")
+	require.NoError(t, err)
+	assert.Equal(t, "1000fe01", user.ProviderUserID)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, "Alice@Corp.Example", user.Email)
+	assert.Equal(t, "Alice Liddell", user.DisplayName)
+	assert.Equal(t, ldapTestAliceDN, user.Extra["dn"])
+	assert.Len(t, user.Extra["groups"], 2)
+	assert.Equal(t, []string{ldapTestServiceDN, ldapTestAliceDN}, server.Binds())
+
+	_, err = p.Authenticate(context.Background(), settings, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrLDAPInvalidCredentials)
+	_, err = p.Authenticate(context.Background(), settings, "alice", "")
+	assert.ErrorIs(t, err, ErrLDAPInvalidCredentials)
+	_, err = p.Authenticate(context.Background(), settings, "nobody", "wonderland")
+	assert.ErrorIs(t, err, ErrLDAPInvalidCredentials)
+	_, err = p.Authenticate(context.Background(), settings, "dup", "x")
+	assert.ErrorIs(t, err, ErrLDAPInvalidCredentials, "an ambiguous filter must not log anyone in")
+	// 过滤器注入：* 被转义后不会匹配任意条目
+	_, err = p.Authenticate(context.Background(), settings, "*", "wonderland")
+	assert.ErrorIs(t, err, ErrLDAPInvalidCredentials)
+}
+
+func TestLDAPProvider_TestConnection(t *testing.T) {
+	_, settings := newLDAPTestServer(t)
+	p := &LDAPProvider{}
+
+	user, err := p.TestConnection(context.Background(), settings, "")
+	require.NoError(t, err)
+	assert.Nil(t, user)
+
+	user, err = p.TestConnection(context.Background(), settings, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+
+	broken := *settings
+	broken.BindSecret = "wrong"
+	_, err = p.TestConnection(context.Background(), &broken, "")
+	var oauthErr *OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.NotEmpty(t, oauthErr.RawError)
+
+	unconfigured := *settings
+	unconfigured.UserFilter = "(uid=alice)"
+	_, err = p.TestConnection(context.Background(), &unconfigured, "")
+	assert.ErrorIs(t, err, ErrLDAPNotConfigured)
+}
+
+func TestLDAPSubjectFallsBackToDN(t *testing.T) {
+	_, settings := newLDAPTestServer(t)
+	settings.IdentityAttribute = "entryUUID"
+
+	user, err := (&LDAPProvider{}).Authenticate(context.Background(), settings, "alice", "wonderland")
+	require.NoError(t, err)
+	assert.Equal(t, "dn:cn=alice liddell,ou=people,dc=corp,dc=example", user.ProviderUserID)
+}
+
+func TestLDAPGroupMapping(t *testing.T) {
+	mapping := map[string]string{
+		"admins": "admin",
+		"cn=engineering,ou=groups,dc=corp,dc=example": "vip",
+	}
+	assert.Equal(t, "admin", LDAPGroupMapping(mapping, "CN=Admins,OU=Groups,DC=corp,DC=example"))
+	assert.Equal(t, "vip", LDAPGroupMapping(mapping, "CN=Engineering,OU=Groups,DC=corp,DC=example"))
+	assert.Empty(t, LDAPGroupMapping(mapping, "CN=Sales,OU=Groups,DC=corp,DC=example"))
+	assert.Empty(t, LDAPGroupMapping(nil, "CN=Admins,OU=Groups,DC=corp,DC=example"))
+}
@@ -0,0 +1,300 @@
+// Package ldaptest provides an in-process stand-in for an OpenLDAP / Active
+// Directory server, in the spirit of net/http/httptest. It speaks just enough
+// of LDAPv3 for the login provider: simple bind, subtree search with
+// and/or/not/equality/presence filters, a size limit, and unbind.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+const (
+	appBindRequest      = 0
+	appBindResponse     = 1
+	appUnbindRequest    = 2
+	appSearchRequest    = 3
+	appSearchResultItem = 4
+	appSearchResultDone = 5
+	appExtendedRequest  = 23
+	appExtendedResponse = 24
+
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+)
+
+// Entry is a directory object. Entries with a Password can be bound to;
+// attribute names are matched case-insensitively, and values may hold raw
+// bytes (e.g. an objectGUID).
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server is a running stand-in directory listening on a loopback port.
+type Server struct {
+	// URL is the ldap:// URL of the server.
+	URL string
+	// AllowAnonymousSearch lets unauthenticated connections search.
+	AllowAnonymousSearch bool
+
+	listener net.Listener
+	entries  []Entry
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	binds    []string
+}
+
+// NewServer starts a server holding the given entries.
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: failed to listen: " + err.Error())
+	}
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the listener, drops open connections and waits for their
+// handlers to return.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Binds returns the DNs of every successful bind, in order.
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				_ = conn.Close()
+			}()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	bound := false
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		op := packet.Children[1]
+		switch op.Tag {
+		case appBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			writeResult(conn, messageID, appBindResponse, code)
+		case appSearchRequest:
+			if !bound && !s.AllowAnonymousSearch {
+				writeResult(conn, messageID, appSearchResultDone, resultInsufficientAccess)
+				continue
+			}
+			s.search(conn, messageID, op)
+		case appUnbindRequest:
+			return
+		case appExtendedRequest:
+			// StartTLS 等扩展操作不受支持
+			writeResult(conn, messageID, appExtendedResponse, resultProtocolError)
+		default:
+			return
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocolError
+	}
+	dn := packetString(op.Children[1])
+	password := packetString(op.Children[2])
+	if password == "" {
+		return resultInvalidCredentials
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			s.mu.Lock()
+			s.binds = append(s.binds, entry.DN)
+			s.mu.Unlock()
+			return resultSuccess
+		}
+	}
+	return resultInvalidCredentials
+}
+
+func (s *Server) search(conn net.Conn, messageID any, op *ber.Packet) {
+	if len(op.Children) < 8 {
+		writeResult(conn, messageID, appSearchResultDone, resultProtocolError)
+		return
+	}
+	baseDN := strings.ToLower(packetString(op.Children[0]))
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	var requested []string
+	for _, attr := range op.Children[7].Children {
+		requested = append(requested, packetString(attr))
+	}
+
+	sent := 0
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), baseDN) || !matchFilter(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && int64(sent) >= sizeLimit {
+			writeResult(conn, messageID, appSearchResultDone, resultSizeLimitExceeded)
+			return
+		}
+		writeEntry(conn, messageID, entry, requested)
+		sent++
+	}
+	writeResult(conn, messageID, appSearchResultDone, resultSuccess)
+}
+
+func matchFilter(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(filter.Children) == 1 && !matchFilter(entry, filter.Children[0])
+	case 3: // equalityMatch
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := packetString(filter.Children[1])
+		for _, value := range attributeValues(entry, packetString(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		return strings.EqualFold(packetString(filter), "objectClass") ||
+			len(attributeValues(entry, packetString(filter))) > 0
+	default:
+		return false
+	}
+}
+
+func attributeValues(entry Entry, name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func packetString(packet *ber.Packet) string {
+	if value, ok := packet.Value.(string); ok {
+		return value
+	}
+	if packet.Data != nil {
+		return packet.Data.String()
+	}
+	return ""
+}
+
+func envelope(messageID any) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	return packet
+}
+
+func writeResult(conn net.Conn, messageID any, tag ber.Tag, code int) {
+	packet := envelope(messageID)
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	packet.AppendChild(result)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func writeEntry(conn net.Conn, messageID any, entry Entry, requested []string) {
+	packet := envelope(messageID)
+	item := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchResultItem, nil, "Search Result Entry")
+	item.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for attr, values := range entry.Attributes {
+		if !isRequested(attr, requested) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	item.AppendChild(attributes)
+	packet.AppendChild(item)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func isRequested(attr string, requested []string) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	for _, name := range requested {
+		if name == "*" || strings.EqualFold(name, attr) {
+			return true
+		}
+	}
+	return false
+}
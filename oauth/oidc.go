@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -137,8 +138,13 @@ func (p *OIDCProvider) GetUserInfo(ctx context.Context, token *OAuthToken) (*OAu
 		return nil, NewOAuthError(i18n.MsgOAuthGetUserErr, nil)
 	}
 
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] GetUserInfo read body error: %s", err.Error()))
+		return nil, err
+	}
 	var oidcUser oidcUser
-	err = json.NewDecoder(res.Body).Decode(&oidcUser)
+	err = json.Unmarshal(body, &oidcUser)
 	if err != nil {
 		logger.LogError(ctx, fmt.Sprintf("[OAuth-OIDC] GetUserInfo decode error: %s", err.Error()))
 		return nil, err
@@ -156,6 +162,9 @@ func (p *OIDCProvider) GetUserInfo(ctx context.Context, token *OAuthToken) (*OAu
 		Username:       oidcUser.PreferredUsername,
 		DisplayName:    oidcUser.Name,
 		Email:          oidcUser.Email,
+		Extra: map[string]any{
+			ExtraClaimsKey: mergeIDTokenClaims(string(body), token.IDToken),
+		},
 	}, nil
 }
 
@@ -180,3 +189,8 @@ func (p *OIDCProvider) GetProviderPrefix() string {
 func (p *OIDCProvider) ProviderUserIDColumn() string {
 	return "oidc_id"
 }
+
+// ClaimMapping returns the oidc.claim_mapping setting.
+func (p *OIDCProvider) ClaimMapping() string {
+	return system_setting.GetOIDCSettings().ClaimMapping
+}
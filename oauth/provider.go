@@ -40,3 +40,10 @@ type Provider interface {
 	// (e.g. the user_oauth_bindings table) return an empty string.
 	ProviderUserIDColumn() string
 }
+
+// ClaimMappingSource is implemented by providers whose logins can drive the
+// local user's group, usable groups and role through a claim mapping.
+type ClaimMappingSource interface {
+	// ClaimMapping returns the raw claim mapping JSON; empty disables mapping.
+	ClaimMapping() string
+}
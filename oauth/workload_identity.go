@@ -0,0 +1,452 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Workload identity lets services authenticate with short-lived OIDC JWTs
+// issued by their platform (CI, Kubernetes service accounts, ...) instead of
+// static keys. A JWT is accepted when its iss names an enabled issuer, its
+// signature verifies against that issuer's keys, its aud contains the issuer's
+// audience, and one of the issuer's rules matches its claims.
+const (
+	DefaultWorkloadTokenTTL = 3600
+	MinWorkloadTokenTTL     = 60
+	MaxWorkloadTokenTTL     = 24 * 3600
+
+	workloadJWKSCacheTTL = 10 * time.Minute
+	// workloadJWKSRefreshFloor bounds refetches triggered by unknown key IDs or
+	// failed fetches, so forged tokens cannot make us hammer the JWKS endpoint.
+	workloadJWKSRefreshFloor = time.Minute
+	workloadJWKSMaxBytes     = 1 << 20
+	workloadJWKSFetchTimeout = 10 * time.Second
+	workloadJWTLeeway        = 30 * time.Second
+)
+
+// WorkloadJWKSHTTPClient returns the client used to fetch issuer JWKS. The
+// service package points it at the shared outbound client (oauth cannot import
+// service); a nil client falls back to http.DefaultClient.
+var WorkloadJWKSHTTPClient = func() *http.Client { return nil }
+
+var workloadJWTMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+var errUnsupportedJWK = errors.New("unsupported key type")
+
+// WorkloadRule maps a verified workload JWT to the user its gateway keys
+// belong to and the token template they are minted from. An empty When
+// matches every JWT of the issuer.
+type WorkloadRule struct {
+	When           accessPolicy `json:"when"`
+	UserId         int          `json:"user_id"`
+	Group          string       `json:"group,omitempty"`
+	ModelLimits    []string     `json:"model_limits,omitempty"`
+	Quota          int          `json:"quota,omitempty"`
+	UnlimitedQuota bool         `json:"unlimited_quota,omitempty"`
+}
+
+// WorkloadIdentity is a verified workload JWT.
+type WorkloadIdentity struct {
+	Subject   string
+	ExpiresAt time.Time
+	// Claims is the JSON claim set the issuer's rules are evaluated against.
+	Claims string
+}
+
+// ParseWorkloadRules parses and validates an issuer's rule list.
+func ParseWorkloadRules(raw string) ([]WorkloadRule, error) {
+	var rules []WorkloadRule
+	if err := common.UnmarshalJsonStr(strings.TrimSpace(raw), &rules); err != nil {
+		return nil, errors.New("rules must be a JSON array")
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("at least one rule is required")
+	}
+	for index := range rules {
+		rule := &rules[index]
+		if len(rule.When.Conditions) > 0 || len(rule.When.Groups) > 0 {
+			if err := validateAccessPolicy(&rule.When); err != nil {
+				return nil, fmt.Errorf("rules[%d].when: %w", index, err)
+			}
+		}
+		if rule.UserId <= 0 {
+			return nil, fmt.Errorf("rules[%d].user_id is required", index)
+		}
+		rule.Group = strings.TrimSpace(rule.Group)
+		models := make([]string, 0, len(rule.ModelLimits))
+		for _, name := range rule.ModelLimits {
+			if name = strings.TrimSpace(name); name != "" {
+				models = append(models, name)
+			}
+		}
+		rule.ModelLimits = models
+		if rule.Quota < 0 {
+			return nil, fmt.Errorf("rules[%d].quota must not be negative", index)
+		}
+		if rule.Quota == 0 && !rule.UnlimitedQuota {
+			return nil, fmt.Errorf("rules[%d] must set quota or unlimited_quota", index)
+		}
+	}
+	return rules, nil
+}
+
+// MatchWorkloadRule returns the first rule whose condition matches the claims.
+func MatchWorkloadRule(rules []WorkloadRule, claims string) (*WorkloadRule, bool) {
+	for index := range rules {
+		if matched, _ := evaluateAccessPolicy(claims, &rules[index].When); matched {
+			return &rules[index], true
+		}
+	}
+	return nil, false
+}
+
+// ValidateWorkloadIssuer normalizes and validates an issuer before it is saved.
+func ValidateWorkloadIssuer(issuer *model.WorkloadIdentityIssuer) error {
+	issuer.Name = strings.TrimSpace(issuer.Name)
+	issuer.Issuer = strings.TrimSpace(issuer.Issuer)
+	issuer.Audience = strings.TrimSpace(issuer.Audience)
+	issuer.JwksURL = strings.TrimSpace(issuer.JwksURL)
+	issuer.StaticKeys = strings.TrimSpace(issuer.StaticKeys)
+	if issuer.Name == "" {
+		return errors.New("name is required")
+	}
+	if issuer.Issuer == "" {
+		return errors.New("issuer is required")
+	}
+	// Without an audience any JWT the platform issues for other services
+	// would be accepted here.
+	if issuer.Audience == "" {
+		return errors.New("audience is required")
+	}
+	if issuer.StaticKeys != "" {
+		if _, err := parseWorkloadKeys(issuer.StaticKeys); err != nil {
+			return fmt.Errorf("static_keys is invalid: %w", err)
+		}
+	} else {
+		parsed, err := url.Parse(issuer.JwksURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return errors.New("jwks_url must be an http(s) URL when static_keys is empty")
+		}
+	}
+	if issuer.TokenTTL == 0 {
+		issuer.TokenTTL = DefaultWorkloadTokenTTL
+	}
+	if issuer.TokenTTL < MinWorkloadTokenTTL || issuer.TokenTTL > MaxWorkloadTokenTTL {
+		return fmt.Errorf("token_ttl must be between %d and %d seconds", MinWorkloadTokenTTL, MaxWorkloadTokenTTL)
+	}
+	if _, err := ParseWorkloadRules(issuer.Rules); err != nil {
+		return fmt.Errorf("rules is invalid: %w", err)
+	}
+	return nil
+}
+
+// WorkloadJWTIssuer returns the unverified iss claim of raw, or false when raw
+// is not a JWT. It is only used to pick the issuer whose keys verify the token.
+func WorkloadJWTIssuer(raw string) (string, bool) {
+	if strings.Count(raw, ".") != 2 || !strings.HasPrefix(raw, "eyJ") {
+		return "", false
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, claims); err != nil {
+		return "", false
+	}
+	iss, err := claims.GetIssuer()
+	if err != nil || iss == "" {
+		return "", false
+	}
+	return iss, true
+}
+
+// VerifyWorkloadJWT verifies the signature, issuer, audience and lifetime of
+// raw against the issuer's keys.
+func VerifyWorkloadJWT(ctx context.Context, issuer *model.WorkloadIdentityIssuer, raw string) (*WorkloadIdentity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(workloadJWTMethods),
+		jwt.WithIssuer(issuer.Issuer),
+		jwt.WithAudience(issuer.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(workloadJWTLeeway),
+	)
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return workloadVerificationKeys(ctx, issuer, kid)
+	}); err != nil {
+		return nil, err
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, errors.New("token has no expiration time")
+	}
+	payload, err := common.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkloadIdentity{Subject: subject, ExpiresAt: expiresAt.Time, Claims: string(payload)}, nil
+}
+
+func workloadVerificationKeys(ctx context.Context, issuer *model.WorkloadIdentityIssuer, kid string) (jwt.VerificationKeySet, error) {
+	var keys []workloadKey
+	var err error
+	if strings.TrimSpace(issuer.StaticKeys) != "" {
+		keys, err = parseWorkloadKeys(issuer.StaticKeys)
+	} else {
+		keys, err = workloadJWKS(ctx, issuer.JwksURL, false)
+		if err == nil && kid != "" && !hasWorkloadKeyID(keys, kid) {
+			// The issuer may have rotated its keys since we last fetched them.
+			keys, err = workloadJWKS(ctx, issuer.JwksURL, true)
+		}
+	}
+	set := jwt.VerificationKeySet{}
+	if err != nil {
+		return set, err
+	}
+	for _, key := range keys {
+		if kid == "" || key.kid == "" || key.kid == kid {
+			set.Keys = append(set.Keys, key.key)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return set, fmt.Errorf("no verification key matches kid %q", kid)
+	}
+	return set, nil
+}
+
+type workloadKey struct {
+	kid string
+	key crypto.PublicKey
+}
+
+func hasWorkloadKeyID(keys []workloadKey, kid string) bool {
+	for _, key := range keys {
+		if key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWorkloadKeys accepts a JWKS document or PEM encoded public keys and
+// certificates.
+func parseWorkloadKeys(raw string) ([]workloadKey, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "{") {
+		return parseJWKS([]byte(raw))
+	}
+	keys := make([]workloadKey, 0)
+	rest := []byte(raw)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, workloadKey{key: key})
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, workloadKey{key: cert.PublicKey})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("expected a JWKS document or PEM public keys")
+	}
+	return keys, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(raw []byte) ([]workloadKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := common.Unmarshal(raw, &set); err != nil {
+		return nil, errors.New("JWKS must be valid JSON")
+	}
+	keys := make([]workloadKey, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if errors.Is(err, errUnsupportedJWK) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		keys = append(keys, workloadKey{kid: jwk.Kid, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil || len(n) == 0 {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedJWK
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, errUnsupportedJWK
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errUnsupportedJWK
+}
+
+type workloadJWKSEntry struct {
+	mu        sync.Mutex
+	keys      []workloadKey
+	err       error
+	fetchedAt time.Time // last successful fetch
+	checkedAt time.Time // last fetch attempt
+}
+
+var workloadJWKSCache sync.Map // JWKS URL -> *workloadJWKSEntry
+
+// workloadJWKS returns the keys published at jwksURL. Keys are cached for
+// workloadJWKSCacheTTL; force refetches them early (e.g. for an unknown kid),
+// but never more than once per workloadJWKSRefreshFloor. When a refetch fails
+// the previously fetched keys keep being used.
+func workloadJWKS(ctx context.Context, jwksURL string, force bool) ([]workloadKey, error) {
+	value, _ := workloadJWKSCache.LoadOrStore(jwksURL, &workloadJWKSEntry{})
+	entry := value.(*workloadJWKSEntry)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	now := time.Now()
+	due := entry.keys == nil || force || now.Sub(entry.fetchedAt) >= workloadJWKSCacheTTL
+	if due && now.Sub(entry.checkedAt) >= workloadJWKSRefreshFloor {
+		entry.checkedAt = now
+		keys, err := fetchWorkloadJWKS(ctx, jwksURL)
+		if err == nil {
+			entry.keys, entry.err, entry.fetchedAt = keys, nil, now
+		} else {
+			entry.err = err
+			if entry.keys != nil {
+				common.SysError(fmt.Sprintf("failed to refresh workload identity JWKS %s, using cached keys: %v", jwksURL, err))
+			}
+		}
+	}
+	if entry.keys == nil {
+		return nil, entry.err
+	}
+	return entry.keys, nil
+}
+
+// InvalidateWorkloadJWKS drops the cached keys of jwksURL.
+func InvalidateWorkloadJWKS(jwksURL string) {
+	workloadJWKSCache.Delete(jwksURL)
+}
+
+func fetchWorkloadJWKS(ctx context.Context, jwksURL string) ([]workloadKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, workloadJWKSFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := WorkloadJWKSHTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %s", res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, workloadJWKSMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(body)
+}
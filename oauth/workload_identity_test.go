@@ -0,0 +1,249 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWorkloadRules = `[
+	{"when": {"conditions": [{"field": "repository", "op": "eq", "value": "acme/api"}]}, "user_id": 7, "group": "ci", "model_limits": ["gpt-4o-mini", " "], "quota": 5000},
+	{"user_id": 8, "unlimited_quota": true}
+]`
+
+func rsaJWK(kid string, key *rsa.PublicKey) string {
+	return fmt.Sprintf(`{"kty":"RSA","kid":%q,"use":"sig","n":%q,"e":%q}`, kid,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+}
+
+func signWorkloadJWT(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func workloadClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":        "https://ci.example.com",
+		"aud":        "new-api",
+		"sub":        "repo:acme/api:ref:refs/heads/main",
+		"repository": "acme/api",
+		"iat":        now.Unix(),
+		"exp":        now.Add(10 * time.Minute).Unix(),
+	}
+	for key, value := range overrides {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestParseWorkloadRules(t *testing.T) {
+	rules, err := ParseWorkloadRules(testWorkloadRules)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, []string{"gpt-4o-mini"}, rules[0].ModelLimits)
+
+	rule, ok := MatchWorkloadRule(rules, `{"repository": "acme/api"}`)
+	require.True(t, ok)
+	assert.Equal(t, 7, rule.UserId)
+	rule, ok = MatchWorkloadRule(rules, `{"repository": "acme/web"}`)
+	require.True(t, ok)
+	assert.Equal(t, 8, rule.UserId, "a rule without conditions matches every token")
+
+	for _, raw := range []string{
+		``,
+		`[]`,
+		`{"user_id": 1}`,
+		`[{"quota": 10}]`,
+		`[{"user_id": 1}]`,
+		`[{"user_id": 1, "quota": -1, "unlimited_quota": true}]`,
+		`[{"when": {"conditions": [{"field": "sub", "op": "matches", "value": "x"}]}, "user_id": 1, "quota": 10}]`,
+	} {
+		_, err := ParseWorkloadRules(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestParseWorkloadKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecBytes, err := ecKey.PublicKey.Bytes()
+	require.NoError(t, err)
+
+	jwks := fmt.Sprintf(`{"keys": [%s,
+		{"kty":"EC","kid":"ec","crv":"P-256","x":%q,"y":%q},
+		{"kty":"OKP","kid":"ed","crv":"Ed25519","x":%q},
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},
+		{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}
+	]}`, rsaJWK("rsa", &rsaKey.PublicKey),
+		base64.RawURLEncoding.EncodeToString(ecBytes[1:33]),
+		base64.RawURLEncoding.EncodeToString(ecBytes[33:]),
+		base64.RawURLEncoding.EncodeToString(edPublic))
+	keys, err := parseWorkloadKeys(jwks)
+	require.NoError(t, err)
+	require.Len(t, keys, 3, "encryption and symmetric keys are skipped")
+	assert.True(t, rsaKey.PublicKey.Equal(keys[0].key))
+	assert.True(t, ecKey.PublicKey.Equal(keys[1].key))
+	assert.True(t, edPublic.Equal(keys[2].key))
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	keys, err = parseWorkloadKeys(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, rsaKey.PublicKey.Equal(keys[0].key))
+
+	for _, raw := range []string{
+		`not a key`,
+		`{"keys": []}`,
+		`{"keys": [{"kty":"EC","crv":"P-256","x":"AAAA","y":"AAAA"}]}`,
+	} {
+		_, err := parseWorkloadKeys(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestVerifyWorkloadJWTWithStaticKeys(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	issuer := &model.WorkloadIdentityIssuer{
+		Name: "CI", Issuer: "https://ci.example.com", Audience: "new-api",
+		StaticKeys: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		Rules:      testWorkloadRules,
+	}
+	require.NoError(t, ValidateWorkloadIssuer(issuer))
+	assert.Equal(t, DefaultWorkloadTokenTTL, issuer.TokenTTL)
+
+	raw := signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(nil))
+	iss, ok := WorkloadJWTIssuer(raw)
+	require.True(t, ok)
+	assert.Equal(t, issuer.Issuer, iss)
+	identity, err := VerifyWorkloadJWT(context.Background(), issuer, raw)
+	require.NoError(t, err)
+	assert.Equal(t, "repo:acme/api:ref:refs/heads/main", identity.Subject)
+	assert.Contains(t, identity.Claims, `"repository":"acme/api"`)
+
+	_, otherPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	for name, token := range map[string]string{
+		"wrong audience": signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(jwt.MapClaims{"aud": "other"})),
+		"wrong issuer":   signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+		"expired":        signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":      signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(jwt.MapClaims{"exp": nil})),
+		"no subject":     signWorkloadJWT(t, jwt.SigningMethodEdDSA, private, "", workloadClaims(jwt.MapClaims{"sub": ""})),
+		"foreign key":    signWorkloadJWT(t, jwt.SigningMethodEdDSA, otherPrivate, "", workloadClaims(nil)),
+		"hmac":           signWorkloadJWT(t, jwt.SigningMethodHS256, der, "", workloadClaims(nil)),
+	} {
+		_, err := VerifyWorkloadJWT(context.Background(), issuer, token)
+		assert.Error(t, err, name)
+	}
+
+	_, ok = WorkloadJWTIssuer("sk-abcdef")
+	assert.False(t, ok)
+}
+
+func TestVerifyWorkloadJWTRefreshesRotatedJWKS(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if rotated.Load() {
+			_, _ = fmt.Fprintf(w, `{"keys": [%s]}`, rsaJWK("new", &newKey.PublicKey))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"keys": [%s]}`, rsaJWK("old", &oldKey.PublicKey))
+	}))
+	defer server.Close()
+	defer InvalidateWorkloadJWKS(server.URL)
+
+	issuer := &model.WorkloadIdentityIssuer{
+		Name: "CI", Issuer: "https://ci.example.com", Audience: "new-api", JwksURL: server.URL, Rules: testWorkloadRules,
+	}
+	require.NoError(t, ValidateWorkloadIssuer(issuer))
+	ctx := context.Background()
+
+	_, err = VerifyWorkloadJWT(ctx, issuer, signWorkloadJWT(t, jwt.SigningMethodRS256, oldKey, "old", workloadClaims(nil)))
+	require.NoError(t, err)
+	_, err = VerifyWorkloadJWT(ctx, issuer, signWorkloadJWT(t, jwt.SigningMethodRS256, oldKey, "old", workloadClaims(nil)))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, fetches.Load(), "keys are served from cache")
+
+	rotated.Store(true)
+	newToken := signWorkloadJWT(t, jwt.SigningMethodRS256, newKey, "new", workloadClaims(nil))
+	_, err = VerifyWorkloadJWT(ctx, issuer, newToken)
+	assert.Error(t, err, "unknown key IDs cannot force a refetch more than once per refresh floor")
+	assert.EqualValues(t, 1, fetches.Load())
+
+	value, ok := workloadJWKSCache.Load(server.URL)
+	require.True(t, ok)
+	entry := value.(*workloadJWKSEntry)
+	entry.mu.Lock()
+	entry.checkedAt = entry.checkedAt.Add(-workloadJWKSRefreshFloor)
+	entry.mu.Unlock()
+	_, err = VerifyWorkloadJWT(ctx, issuer, newToken)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, fetches.Load())
+}
+
+type countingRoundTripper struct {
+	calls atomic.Int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.calls.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFetchWorkloadJWKSUsesInjectedClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"keys": [%s]}`, rsaJWK("k1", &key.PublicKey))
+	}))
+	defer server.Close()
+
+	transport := &countingRoundTripper{}
+	previous := WorkloadJWKSHTTPClient
+	WorkloadJWKSHTTPClient = func() *http.Client { return &http.Client{Transport: transport} }
+	t.Cleanup(func() { WorkloadJWKSHTTPClient = previous })
+
+	keys, err := fetchWorkloadJWKS(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.EqualValues(t, 1, transport.calls.Load(), "JWKS are fetched through the shared outbound client")
+}
@@ -0,0 +1,161 @@
+package perfmetrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// baselineWindowSize bounds the recent outcomes kept per model for baseline
+// comparisons. Hour buckets only hold averages, so percentiles come from here.
+const baselineWindowSize = 1000
+
+var (
+	baselineWindows sync.Map
+	ttftWindows     sync.Map
+)
+
+// WindowStats summarizes the outcomes recorded in a Window since a given time.
+type WindowStats struct {
+	Samples      int     `json:"samples"`
+	ErrorRate    float64 `json:"error_rate"`
+	P95LatencyMs int64   `json:"p95_latency_ms"`
+}
+
+type windowEntry struct {
+	at        int64
+	latencyMs int64
+	success   bool
+}
+
+// Window is a fixed-size ring of recent request outcomes on this node.
+type Window struct {
+	mu      sync.Mutex
+	entries []windowEntry
+	next    int
+}
+
+func NewWindow(size int) *Window {
+	if size <= 0 {
+		size = baselineWindowSize
+	}
+	return &Window{entries: make([]windowEntry, 0, size)}
+}
+
+func (w *Window) Add(at time.Time, latencyMs int64, success bool) {
+	entry := windowEntry{at: at.UnixMilli(), latencyMs: latencyMs, success: success}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.entries) < cap(w.entries) {
+		w.entries = append(w.entries, entry)
+		return
+	}
+	w.entries[w.next] = entry
+	w.next = (w.next + 1) % len(w.entries)
+}
+
+// Stats returns the error rate (percent) and the p95 latency of successful
+// requests among the outcomes recorded at or after since.
+func (w *Window) Stats(since time.Time) WindowStats {
+	sinceMs := since.UnixMilli()
+	w.mu.Lock()
+	latencies := make([]int64, 0, len(w.entries))
+	stats := WindowStats{}
+	failures := 0
+	for _, entry := range w.entries {
+		if entry.at < sinceMs {
+			continue
+		}
+		stats.Samples++
+		if entry.success {
+			latencies = append(latencies, entry.latencyMs)
+		} else {
+			failures++
+		}
+	}
+	w.mu.Unlock()
+	if stats.Samples == 0 {
+		return stats
+	}
+	stats.ErrorRate = float64(failures) / float64(stats.Samples) * 100
+	stats.P95LatencyMs = percentile(latencies, 0.95)
+	return stats
+}
+
+// SuccessPercentile returns the p-th percentile latency of successful requests
+// recorded at or after since, together with the number of those requests.
+func (w *Window) SuccessPercentile(since time.Time, p float64) (int64, int) {
+	sinceMs := since.UnixMilli()
+	w.mu.Lock()
+	latencies := make([]int64, 0, len(w.entries))
+	for _, entry := range w.entries {
+		if entry.at >= sinceMs && entry.success {
+			latencies = append(latencies, entry.latencyMs)
+		}
+	}
+	w.mu.Unlock()
+	return percentile(latencies, p), len(latencies)
+}
+
+func percentile(values []int64, p float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	idx := int(math.Ceil(float64(len(values))*p)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(values) {
+		idx = len(values) - 1
+	}
+	return values[idx]
+}
+
+func recordBaseline(sample Sample) {
+	if sample.Canary {
+		return
+	}
+	actual, _ := baselineWindows.LoadOrStore(sample.Model, NewWindow(baselineWindowSize))
+	now := time.Now()
+	actual.(*Window).Add(now, sample.LatencyMs, sample.Success)
+	if sample.HasTtft && sample.Success && sample.TtftMs >= 0 {
+		ttft, _ := ttftWindows.LoadOrStore(sample.Model, NewWindow(baselineWindowSize))
+		ttft.(*Window).Add(now, sample.TtftMs, true)
+	}
+}
+
+// RecentBaseline returns the outcomes of the latest non-canary requests for a
+// model on this node since the given time. It is empty while perf metrics are
+// disabled.
+func RecentBaseline(modelName string, since time.Time) WindowStats {
+	actual, ok := baselineWindows.Load(modelName)
+	if !ok {
+		return WindowStats{}
+	}
+	return actual.(*Window).Stats(since)
+}
+
+// RecentLatencyPercentile returns the p-th percentile latency of the latest
+// successful non-canary requests for a model on this node since the given
+// time, and how many requests it is based on.
+func RecentLatencyPercentile(modelName string, since time.Time, p float64) (int64, int) {
+	actual, ok := baselineWindows.Load(modelName)
+	if !ok {
+		return 0, 0
+	}
+	return actual.(*Window).SuccessPercentile(since, p)
+}
+
+// RecentTtftPercentile is RecentLatencyPercentile for the time to first token
+// of successful streaming requests.
+func RecentTtftPercentile(modelName string, since time.Time, p float64) (int64, int) {
+	actual, ok := ttftWindows.Load(modelName)
+	if !ok {
+		return 0, 0
+	}
+	return actual.(*Window).SuccessPercentile(since, p)
+}
@@ -51,6 +51,7 @@ func RecordRelaySample(info *relaycommon.RelayInfo, success bool, outputTokens i
 		Success:      success,
 		OutputTokens: outputTokens,
 		GenerationMs: generationMs,
+		Canary:       info.TrafficSplitCanary,
 	})
 }
 
@@ -74,6 +75,7 @@ func Record(sample Sample) {
 	actual, _ := hotBuckets.LoadOrStore(key, &atomicBucket{})
 	actual.(*atomicBucket).add(sample)
 	recordRedis(key, sample)
+	recordBaseline(sample)
 }
 
 func Query(params QueryParams) (QueryResult, error) {
@@ -16,6 +16,9 @@ type Sample struct {
 	Success      bool
 	OutputTokens int64
 	GenerationMs int64
+	// Canary marks requests served by a traffic-split canary arm. They are
+	// aggregated as usual but kept out of the per-model baseline window.
+	Canary bool
 }
 
 type QueryParams struct {
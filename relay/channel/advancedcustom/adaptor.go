@@ -10,7 +10,9 @@ import (
 
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/relay/channel"
+	"github.com/QuantumNous/new-api/relay/channel/aws"
 	"github.com/QuantumNous/new-api/relay/channel/claude"
+	"github.com/QuantumNous/new-api/relay/channel/cohere"
 	"github.com/QuantumNous/new-api/relay/channel/gemini"
 	"github.com/QuantumNous/new-api/relay/channel/openai"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
@@ -56,7 +58,9 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	switch converter {
 	case relayconvert.ConverterOpenAIChatToClaudeMessages,
 		relayconvert.ConverterOpenAIChatToOpenAIResponses,
-		relayconvert.ConverterOpenAIChatToGeminiContent:
+		relayconvert.ConverterOpenAIChatToGeminiContent,
+		relayconvert.ConverterOpenAIChatToBedrockConverse,
+		relayconvert.ConverterOpenAIChatToCohereChat:
 		result, err := service.ConvertRequestByID(c, info, converter, request)
 		if err != nil {
 			return nil, err
@@ -278,6 +282,9 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, header *http.Header, info *
 	if shouldApplyClaudeHeaders(a.converter, info) {
 		applyClaudeHeaders(c, header, info)
 	}
+	if shouldUseConverseStreamURL(a.converter, info) {
+		header.Set("Accept", "application/vnd.amazon.eventstream")
+	}
 
 	return nil
 }
@@ -318,6 +325,16 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		return a.geminiAdaptor.DoResponse(c, resp, info)
 	case relayconvert.ConverterOpenAIResponsesToGemini:
 		return a.geminiAdaptor.DoResponse(c, resp, info)
+	case relayconvert.ConverterOpenAIChatToBedrockConverse:
+		if info.IsStream {
+			return aws.ConverseStreamHandler(c, info, resp)
+		}
+		return aws.ConverseHandler(c, info, resp)
+	case relayconvert.ConverterOpenAIChatToCohereChat:
+		if info.IsStream {
+			return cohere.CohereChatStreamHandler(c, info, resp)
+		}
+		return cohere.CohereChatHandler(c, info, resp)
 	case relayconvert.ConverterOpenAIChatToOpenAIResponses:
 		if info.IsStream {
 			return openai.OaiResponsesToChatStreamHandler(c, info, resp)
@@ -416,6 +433,9 @@ func buildRouteURL(route dto.AdvancedCustomRoute, converter string, info *relayc
 	if shouldUseGeminiStreamURL(converter, info) {
 		useGeminiStreamGenerateContentURL(parsedURL)
 	}
+	if shouldUseConverseStreamURL(converter, info) {
+		useConverseStreamURL(parsedURL)
+	}
 	if info != nil && info.RelayMode == relayconstant.RelayModeRealtime {
 		switch parsedURL.Scheme {
 		case "https":
@@ -504,6 +524,17 @@ func useGeminiStreamGenerateContentURL(parsedURL *url.URL) {
 	}
 }
 
+func shouldUseConverseStreamURL(converter string, info *relaycommon.RelayInfo) bool {
+	return info != nil && info.IsStream && converter == relayconvert.ConverterOpenAIChatToBedrockConverse
+}
+
+func useConverseStreamURL(parsedURL *url.URL) {
+	if strings.HasSuffix(parsedURL.Path, "/converse") {
+		parsedURL.Path += "-stream"
+		parsedURL.RawPath = ""
+	}
+}
+
 func shouldApplyClaudeHeaders(converter string, info *relaycommon.RelayInfo) bool {
 	return converter == relayconvert.ConverterOpenAIChatToClaudeMessages ||
 		(converter == relayconvert.ConverterNone && info != nil && info.RelayFormat == types.RelayFormatClaude)
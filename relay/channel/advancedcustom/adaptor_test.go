@@ -16,6 +16,8 @@ import (
 	"github.com/QuantumNous/new-api/relaykit/relayconvert"
 	"github.com/QuantumNous/new-api/relaykit/types"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream/eventstreamapi"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -829,6 +831,140 @@ func TestAdaptorConvertsGeminiRequestToOpenAIChatUpstream(t *testing.T) {
 	assert.Equal(t, "user", chatReq.Messages[0].Role)
 }
 
+func TestAdaptorRelaysOpenAIChatThroughBedrockConverseUpstream(t *testing.T) {
+	adaptor := &Adaptor{}
+	info := advancedCustomRelayInfo(&dto.AdvancedCustomConfig{
+		Routes: []dto.AdvancedCustomRoute{
+			{
+				IncomingPath: "/v1/chat/completions",
+				UpstreamPath: "https://bedrock-runtime.us-east-1.amazonaws.com/model/{model}/converse",
+				Converter:    relayconvert.ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+	})
+	info.UpstreamModelName = "amazon.nova-pro-v1:0"
+	info.IsStream = true
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	converted, err := adaptor.ConvertOpenAIRequest(c, info, &dto.GeneralOpenAIRequest{
+		Model: "amazon.nova-pro-v1:0",
+		Messages: []dto.Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "hello"},
+		},
+	})
+	require.NoError(t, err)
+	bedrockReq, ok := converted.(*dto.BedrockConverseRequest)
+	require.True(t, ok)
+	require.Len(t, bedrockReq.System, 1)
+	require.Len(t, bedrockReq.Messages, 1)
+	assert.Equal(t, "user", bedrockReq.Messages[0].Role)
+
+	requestURL, err := adaptor.GetRequestURL(info)
+	require.NoError(t, err)
+	parsedURL, err := url.Parse(requestURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/model/amazon.nova-pro-v1:0/converse-stream", parsedURL.Path)
+
+	header := http.Header{}
+	require.NoError(t, adaptor.SetupRequestHeader(c, &header, info))
+	assert.Equal(t, "application/vnd.amazon.eventstream", header.Get("Accept"))
+
+	var body bytes.Buffer
+	for _, event := range []struct {
+		eventType string
+		payload   string
+	}{
+		{"messageStart", `{"role":"assistant"}`},
+		{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"hello"}}`},
+		{"contentBlockStop", `{"contentBlockIndex":0}`},
+		{"messageStop", `{"stopReason":"end_turn"}`},
+		{"metadata", `{"usage":{"inputTokens":2,"outputTokens":3,"totalTokens":5},"metrics":{"latencyMs":10}}`},
+	} {
+		require.NoError(t, eventstream.NewEncoder().Encode(&body, eventstream.Message{
+			Headers: eventstream.Headers{
+				{Name: eventstreamapi.MessageTypeHeader, Value: eventstream.StringValue(eventstreamapi.EventMessageType)},
+				{Name: eventstreamapi.EventTypeHeader, Value: eventstream.StringValue(event.eventType)},
+			},
+			Payload: []byte(event.payload),
+		}))
+	}
+
+	usage, newAPIError := adaptor.DoResponse(c, &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(&body),
+	}, info)
+	require.Nil(t, newAPIError)
+	chatUsage, ok := usage.(*dto.Usage)
+	require.True(t, ok)
+	assert.Equal(t, 2, chatUsage.PromptTokens)
+	assert.Equal(t, 3, chatUsage.CompletionTokens)
+
+	got := recorder.Body.String()
+	assert.Contains(t, got, `"object":"chat.completion.chunk"`)
+	assert.Contains(t, got, `"content":"hello"`)
+	assert.Contains(t, got, `"finish_reason":"stop"`)
+	assert.Contains(t, got, "data: [DONE]")
+}
+
+func TestAdaptorRelaysOpenAIChatThroughCohereChatUpstream(t *testing.T) {
+	adaptor := &Adaptor{}
+	info := advancedCustomRelayInfo(&dto.AdvancedCustomConfig{
+		Routes: []dto.AdvancedCustomRoute{
+			{
+				IncomingPath: "/v1/chat/completions",
+				UpstreamPath: "/v2/chat",
+				Converter:    relayconvert.ConverterOpenAIChatToCohereChat,
+			},
+		},
+	})
+	info.UpstreamModelName = "command-a-03-2025"
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	converted, err := adaptor.ConvertOpenAIRequest(c, info, &dto.GeneralOpenAIRequest{
+		Model: "command-a-03-2025",
+		Messages: []dto.Message{
+			{Role: "user", Content: "hello"},
+		},
+	})
+	require.NoError(t, err)
+	cohereReq, ok := converted.(*dto.CohereChatRequest)
+	require.True(t, ok)
+	assert.Equal(t, "command-a-03-2025", cohereReq.Model)
+	require.Len(t, cohereReq.Messages, 1)
+	assert.Equal(t, "user", cohereReq.Messages[0].Role)
+
+	requestURL, err := adaptor.GetRequestURL(info)
+	require.NoError(t, err)
+	assert.Equal(t, "https://fallback.example/v2/chat", requestURL)
+
+	body := `{"id":"resp_1","finish_reason":"COMPLETE","message":{"role":"assistant","content":[{"type":"text","text":"hello"}]},"usage":{"billed_units":{"input_tokens":2,"output_tokens":3},"tokens":{"input_tokens":2,"output_tokens":3}}}`
+	usage, newAPIError := adaptor.DoResponse(c, &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}, info)
+	require.Nil(t, newAPIError)
+	chatUsage, ok := usage.(*dto.Usage)
+	require.True(t, ok)
+	assert.Equal(t, 2, chatUsage.PromptTokens)
+	assert.Equal(t, 3, chatUsage.CompletionTokens)
+
+	got := recorder.Body.String()
+	assert.Contains(t, got, `"object":"chat.completion"`)
+	assert.Contains(t, got, `"content":"hello"`)
+	assert.Contains(t, got, `"finish_reason":"stop"`)
+	assert.NotContains(t, got, `"billed_units"`)
+}
+
 func advancedCustomRelayInfo(config *dto.AdvancedCustomConfig) *relaycommon.RelayInfo {
 	return &relaycommon.RelayInfo{
 		RelayFormat:     types.RelayFormatOpenAI,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/QuantumNous/new-api/relay/channel"
@@ -33,12 +34,17 @@ type Adaptor struct {
 	IsNova     bool
 }
 
-func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
-	//TODO implement me
+func (a *Adaptor) ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error) {
+	if a.ClientMode == ClientModeApiKey {
+		return convertConverseRequest(c, info, request)
+	}
 	return nil, errors.New("not implemented")
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	if a.ClientMode == ClientModeApiKey {
+		return convertConverseRequest(c, info, request)
+	}
 	for i, message := range request.Messages {
 		updated := false
 		if !message.IsStringContent() {
@@ -86,17 +92,28 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 }
 
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+	// API Key 模式走 Converse HTTP 接口，请求转换阶段就需要知道模式
+	if info.ChannelOtherSettings.AwsKeyType == dto.AwsKeyTypeApiKey {
+		a.ClientMode = ClientModeApiKey
+	} else {
+		a.ClientMode = ClientModeAKSK
+	}
 }
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	if info.ChannelOtherSettings.AwsKeyType == dto.AwsKeyTypeApiKey {
-		awsModelId := getAwsModelID(info.UpstreamModelName)
 		a.ClientMode = ClientModeApiKey
 		awsSecret := strings.Split(info.ApiKey, "|")
 		if len(awsSecret) != 2 {
 			return "", errors.New("invalid aws api key, should be in format of <api-key>|<region>")
 		}
-		return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/converse", awsModelId, awsSecret[1]), nil
+		region := awsSecret[1]
+		action := "converse"
+		if info.IsStream {
+			action = "converse-stream"
+		}
+		awsModelId := ResolveModelID(info.UpstreamModelName, region)
+		return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/%s", region, url.PathEscape(awsModelId), action), nil
 	} else {
 		a.ClientMode = ClientModeAKSK
 		return "", nil
@@ -104,10 +121,18 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 }
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
-	claude.CommonClaudeHeadersOperation(c, req, info)
 	if a.ClientMode == ClientModeApiKey {
-		req.Set("Authorization", "Bearer "+info.ApiKey)
+		channel.SetupApiRequestHeader(info, c, req)
+		req.Set("Content-Type", "application/json")
+		if info.IsStream {
+			req.Set("Accept", "application/vnd.amazon.eventstream")
+		} else {
+			req.Set("Accept", "application/json")
+		}
+		req.Set("Authorization", "Bearer "+strings.Split(info.ApiKey, "|")[0])
+		return nil
 	}
+	claude.CommonClaudeHeadersOperation(c, req, info)
 	return nil
 }
 
@@ -115,6 +140,9 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if request == nil {
 		return nil, errors.New("request is nil")
 	}
+	if a.ClientMode == ClientModeApiKey {
+		return convertConverseRequest(c, info, request)
+	}
 	// 检查是否为Nova模型
 	if isNovaModel(request.Model) {
 		novaReq := convertToNovaRequest(request)
@@ -145,7 +173,9 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
-	// TODO implement me
+	if a.ClientMode == ClientModeApiKey {
+		return convertConverseRequest(c, info, &request)
+	}
 	return nil, errors.New("not implemented")
 }
 
@@ -159,8 +189,11 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
 	if a.ClientMode == ClientModeApiKey {
-		claudeAdaptor := claude.Adaptor{}
-		usage, err = claudeAdaptor.DoResponse(c, resp, info)
+		if info.IsStream {
+			usage, err = ConverseStreamHandler(c, info, resp)
+		} else {
+			usage, err = ConverseHandler(c, info, resp)
+		}
 	} else {
 		if a.IsNova {
 			err, usage = handleNovaRequest(c, info, a)
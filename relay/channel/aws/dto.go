@@ -12,6 +12,9 @@ import (
 	"github.com/QuantumNous/new-api/relaykit/dto"
 )
 
+// AnthropicVersion 是 Bedrock 上 Claude 请求体中的 anthropic_version
+const AnthropicVersion = "bedrock-2023-05-31"
+
 type AwsClaudeRequest struct {
 	// AnthropicVersion should be "bedrock-2023-05-31"
 	AnthropicVersion  string              `json:"anthropic_version"`
@@ -37,7 +40,7 @@ func formatRequest(requestBody io.Reader, requestHeader http.Header) (*AwsClaude
 	if err != nil {
 		return nil, err
 	}
-	awsClaudeRequest.AnthropicVersion = "bedrock-2023-05-31"
+	awsClaudeRequest.AnthropicVersion = AnthropicVersion
 
 	// check header anthropic-beta
 	anthropicBetaValues := requestHeader.Get("anthropic-beta")
@@ -101,13 +101,7 @@ func doAwsClientRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor,
 	a.AwsClient = awsCli
 
 	// 获取对应的AWS模型ID
-	awsModelId := getAwsModelID(info.UpstreamModelName)
-
-	awsRegionPrefix := getAwsRegionPrefix(awsCli.Options().Region)
-	canCrossRegion := awsModelCanCrossRegion(awsModelId, awsRegionPrefix)
-	if canCrossRegion {
-		awsModelId = awsModelCrossRegion(awsModelId, awsRegionPrefix)
-	}
+	awsModelId := ResolveModelID(info.UpstreamModelName, awsCli.Options().Region)
 
 	// init empty request.header
 	requestHeader := http.Header{}
@@ -219,6 +213,16 @@ func awsModelCrossRegion(awsModelId, awsRegionPrefix string) string {
 	return modelPrefix + "." + awsModelId
 }
 
+// ResolveModelID 返回模型在指定区域使用的 Bedrock 模型 ID，支持跨区域推理的模型改用对应的推理配置文件
+func ResolveModelID(requestModel, region string) string {
+	awsModelId := getAwsModelID(requestModel)
+	awsRegionPrefix := getAwsRegionPrefix(region)
+	if awsModelCanCrossRegion(awsModelId, awsRegionPrefix) {
+		return awsModelCrossRegion(awsModelId, awsRegionPrefix)
+	}
+	return awsModelId
+}
+
 func getAwsModelID(requestModel string) string {
 	if awsModelIDName, ok := awsModelIDMap[requestModel]; ok {
 		return awsModelIDName
@@ -456,3 +456,55 @@ func TestAwsStreamHandlerStopsAtClientCancellationAndKeepsPartialBillingUsage(t
 		t.Fatal("upstream producer did not observe the closed stream")
 	}
 }
+
+func TestApiKeyModeRelaysThroughConverse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c := newAwsTestContext(recorder, context.Background())
+	info := &relaycommon.RelayInfo{
+		RelayFormat:     relaytypes.RelayFormatClaude,
+		OriginModelName: "claude-3-5-haiku-20241022",
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ApiKey:            "bedrock-api-key|us-east-1",
+			UpstreamModelName: "claude-3-5-haiku-20241022",
+			ChannelOtherSettings: dto.ChannelOtherSettings{
+				AwsKeyType: dto.AwsKeyTypeApiKey,
+			},
+		},
+	}
+	adaptor := &Adaptor{}
+	adaptor.Init(info)
+
+	converted, err := adaptor.ConvertClaudeRequest(c, info, &dto.ClaudeRequest{
+		Model:    "claude-3-5-haiku-20241022",
+		Messages: []dto.ClaudeMessage{{Role: "user", Content: "hello"}},
+	})
+	require.NoError(t, err)
+	converseReq, ok := converted.(*dto.BedrockConverseRequest)
+	require.True(t, ok)
+	require.Len(t, converseReq.Messages, 1)
+
+	requestURL, err := adaptor.GetRequestURL(info)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bedrock-runtime.us-east-1.amazonaws.com/model/us.anthropic.claude-3-5-haiku-20241022-v1:0/converse", requestURL)
+
+	header := http.Header{}
+	require.NoError(t, adaptor.SetupRequestHeader(c, &header, info))
+	assert.Equal(t, "Bearer bedrock-api-key", header.Get("Authorization"))
+	assert.Equal(t, "application/json", header.Get("Accept"))
+
+	body := `{"output":{"message":{"role":"assistant","content":[{"text":"hi there"}]}},"stopReason":"end_turn","usage":{"inputTokens":4,"outputTokens":2,"totalTokens":6}}`
+	usage, apiErr := adaptor.DoResponse(c, &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}, info)
+	require.Nil(t, apiErr)
+	converseUsage, ok := usage.(*dto.Usage)
+	require.True(t, ok)
+	assert.Equal(t, 4, converseUsage.PromptTokens)
+	assert.Equal(t, 2, converseUsage.CompletionTokens)
+	assert.Contains(t, recorder.Body.String(), `"type":"message"`)
+	assert.Contains(t, recorder.Body.String(), `"text":"hi there"`)
+	assert.Contains(t, recorder.Body.String(), `"stop_reason":"end_turn"`)
+}
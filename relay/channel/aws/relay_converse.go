@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream/eventstreamapi"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// convertConverseRequest 经转换器注册表将任意入站请求转换为 Bedrock Converse 请求
+func convertConverseRequest(c *gin.Context, info *relaycommon.RelayInfo, request any) (any, error) {
+	result, err := service.ConvertRequest(c, info, types.RelayFormatBedrockConverse, request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert request to bedrock converse request")
+	}
+	return result.Value, nil
+}
+
+// ConverseHandler 处理 Converse 非流式响应，转换为客户端请求的格式
+func ConverseHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	var converseResp dto.BedrockConverseResponse
+	if err := common.Unmarshal(body, &converseResp); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	return channel.WriteConvertedResponse(c, info, resp, &converseResp)
+}
+
+// ConverseStreamHandler 解码 ConverseStream 的 AWS event stream 帧，逐个事件转换为客户端请求的格式
+func ConverseStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	stream, err := channel.NewConvertedStream(c, info, types.RelayFormatBedrockConverse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+
+	decoder := eventstream.NewDecoder()
+	var payloadBuf []byte
+	for {
+		msg, err := decoder.Decode(resp.Body, payloadBuf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, types.NewOpenAIError(errors.Wrap(err, "decode converse stream"), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		payloadBuf = msg.Payload[:0]
+
+		switch eventStreamHeader(msg, eventstreamapi.MessageTypeHeader) {
+		case eventstreamapi.EventMessageType:
+		case eventstreamapi.ExceptionMessageType:
+			return nil, newConverseStreamError(eventStreamHeader(msg, eventstreamapi.ExceptionTypeHeader), msg.Payload)
+		case eventstreamapi.ErrorMessageType:
+			return nil, newConverseStreamError(eventStreamHeader(msg, eventstreamapi.ErrorCodeHeader), []byte(eventStreamHeader(msg, eventstreamapi.ErrorMessageHeader)))
+		default:
+			continue
+		}
+
+		event, err := decodeConverseStreamEvent(eventStreamHeader(msg, eventstreamapi.EventTypeHeader), msg.Payload)
+		if err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		if event == nil {
+			continue
+		}
+		info.SetFirstResponseTime()
+		if err := stream.Send(event); err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+		}
+	}
+
+	usage, err := stream.Finish()
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+	return usage, nil
+}
+
+// decodeConverseStreamEvent 按 :event-type 头把事件载荷放入对应字段，未知事件返回 nil
+func decodeConverseStreamEvent(eventType string, payload []byte) (*dto.BedrockConverseStreamEvent, error) {
+	event := &dto.BedrockConverseStreamEvent{}
+	var target any
+	switch eventType {
+	case "messageStart":
+		event.MessageStart = &dto.BedrockMessageStartEvent{}
+		target = event.MessageStart
+	case "contentBlockStart":
+		event.ContentBlockStart = &dto.BedrockContentBlockStartEvent{}
+		target = event.ContentBlockStart
+	case "contentBlockDelta":
+		event.ContentBlockDelta = &dto.BedrockContentBlockDeltaEvent{}
+		target = event.ContentBlockDelta
+	case "contentBlockStop":
+		event.ContentBlockStop = &dto.BedrockContentBlockStopEvent{}
+		target = event.ContentBlockStop
+	case "messageStop":
+		event.MessageStop = &dto.BedrockMessageStopEvent{}
+		target = event.MessageStop
+	case "metadata":
+		event.Metadata = &dto.BedrockStreamMetadataEvent{}
+		target = event.Metadata
+	default:
+		return nil, nil
+	}
+	if err := common.Unmarshal(payload, target); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal converse stream %s event", eventType)
+	}
+	return event, nil
+}
+
+func eventStreamHeader(msg eventstream.Message, name string) string {
+	if value := msg.Headers.Get(name); value != nil {
+		return value.String()
+	}
+	return ""
+}
+
+func newConverseStreamError(errorType string, payload []byte) *types.NewAPIError {
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := string(payload)
+	if err := common.Unmarshal(payload, &body); err == nil && body.Message != "" {
+		message = body.Message
+	}
+	return types.NewOpenAIError(fmt.Errorf("converse stream %s: %s", errorType, message), types.ErrorCodeAwsInvokeError, http.StatusInternalServerError)
+}
@@ -2,12 +2,14 @@ package cohere
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/relaykit/dto"
@@ -18,40 +20,20 @@ import (
 	"github.com/samber/lo"
 )
 
-func requestOpenAI2Cohere(textRequest dto.GeneralOpenAIRequest) *CohereRequest {
-	cohereReq := CohereRequest{
-		Model:       textRequest.Model,
-		ChatHistory: []ChatHistory{},
-		Message:     "",
-		Stream:      lo.FromPtrOr(textRequest.Stream, false),
-		MaxTokens:   textRequest.GetMaxTokens(),
+// convertCohereChatRequest 经转换器注册表将入站请求转换为 Cohere Chat v2 请求，并应用全局安全模式设置
+func convertCohereChatRequest(c *gin.Context, info *relaycommon.RelayInfo, request any) (any, error) {
+	result, err := service.ConvertRequest(c, info, types.RelayFormatCohereChat, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request to cohere chat request: %w", err)
+	}
+	cohereReq, ok := result.Value.(*dto.CohereChatRequest)
+	if !ok {
+		return nil, fmt.Errorf("expected Cohere chat request, got %T", result.Value)
 	}
 	if common.CohereSafetySetting != "NONE" {
 		cohereReq.SafetyMode = common.CohereSafetySetting
 	}
-	if cohereReq.MaxTokens == 0 {
-		cohereReq.MaxTokens = 4000
-	}
-	for _, msg := range textRequest.Messages {
-		if msg.Role == "user" {
-			cohereReq.Message = msg.StringContent()
-		} else {
-			var role string
-			if msg.Role == "assistant" {
-				role = "CHATBOT"
-			} else if msg.Role == "system" {
-				role = "SYSTEM"
-			} else {
-				role = "USER"
-			}
-			cohereReq.ChatHistory = append(cohereReq.ChatHistory, ChatHistory{
-				Role:    role,
-				Message: msg.StringContent(),
-			})
-		}
-	}
-
-	return &cohereReq
+	return cohereReq, nil
 }
 
 func requestConvertRerank2Cohere(rerankRequest dto.RerankRequest) *CohereRerankRequest {
@@ -69,151 +51,55 @@ func requestConvertRerank2Cohere(rerankRequest dto.RerankRequest) *CohereRerankR
 	return &cohereReq
 }
 
-func stopReasonCohere2OpenAI(reason string) string {
-	switch reason {
-	case "COMPLETE":
-		return "stop"
-	case "MAX_TOKENS":
-		return "max_tokens"
-	default:
-		return reason
-	}
-}
+// CohereChatHandler 处理 Cohere Chat v2 非流式响应，转换为客户端请求的格式
+func CohereChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
 
-func cohereStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
-	responseId := helper.GetResponseID(c)
-	createdTime := common.GetTimestamp()
-	usage := &dto.Usage{}
-	responseText := ""
-	scanner := helper.NewStreamScanner(resp.Body)
-	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-		if i := strings.Index(string(data), "\n"); i >= 0 {
-			return i + 1, data[0:i], nil
-		}
-		if atEOF {
-			return len(data), data, nil
-		}
-		return 0, nil, nil
-	})
-	dataChan := make(chan string)
-	stopChan := make(chan bool)
-	go func() {
-		for scanner.Scan() {
-			data := scanner.Text()
-			dataChan <- data
-		}
-		if err := scanner.Err(); err != nil {
-			common.SysLog("error reading stream: " + err.Error())
-		}
-		stopChan <- true
-	}()
-	helper.SetEventStreamHeaders(c)
-	isFirst := true
-	c.Stream(func(w io.Writer) bool {
-		select {
-		case data := <-dataChan:
-			if isFirst {
-				isFirst = false
-				info.FirstResponseTime = time.Now()
-			}
-			data = strings.TrimSuffix(data, "\r")
-			var cohereResp CohereResponse
-			err := json.Unmarshal([]byte(data), &cohereResp)
-			if err != nil {
-				common.SysLog("error unmarshalling stream response: " + err.Error())
-				return true
-			}
-			var openaiResp dto.ChatCompletionsStreamResponse
-			openaiResp.Id = responseId
-			openaiResp.Created = createdTime
-			openaiResp.Object = "chat.completion.chunk"
-			openaiResp.Model = info.UpstreamModelName
-			if cohereResp.IsFinished {
-				finishReason := stopReasonCohere2OpenAI(cohereResp.FinishReason)
-				openaiResp.Choices = []dto.ChatCompletionsStreamResponseChoice{
-					{
-						Delta:        dto.ChatCompletionsStreamResponseChoiceDelta{},
-						Index:        0,
-						FinishReason: &finishReason,
-					},
-				}
-				if cohereResp.Response != nil {
-					usage.PromptTokens = cohereResp.Response.Meta.BilledUnits.InputTokens
-					usage.CompletionTokens = cohereResp.Response.Meta.BilledUnits.OutputTokens
-				}
-			} else {
-				openaiResp.Choices = []dto.ChatCompletionsStreamResponseChoice{
-					{
-						Delta: dto.ChatCompletionsStreamResponseChoiceDelta{
-							Role:    "assistant",
-							Content: &cohereResp.Text,
-						},
-						Index: 0,
-					},
-				}
-				responseText += cohereResp.Text
-			}
-			jsonStr, err := json.Marshal(openaiResp)
-			if err != nil {
-				common.SysLog("error marshalling stream response: " + err.Error())
-				return true
-			}
-			c.Render(-1, common.CustomEvent{Data: "data: " + string(jsonStr)})
-			return true
-		case <-stopChan:
-			c.Render(-1, common.CustomEvent{Data: "data: [DONE]"})
-			return false
-		}
-	})
-	if usage.PromptTokens == 0 {
-		usage = service.ResponseText2Usage(c, responseText, info.UpstreamModelName, info.GetEstimatePromptTokens())
-	}
-	return usage, nil
-}
-
-func cohereHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
-	createdTime := common.GetTimestamp()
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
 	}
-	service.CloseResponseBodyGracefully(resp)
-	var cohereResp CohereResponseResult
-	err = json.Unmarshal(responseBody, &cohereResp)
-	if err != nil {
+	var cohereResp dto.CohereChatResponse
+	if err := common.Unmarshal(responseBody, &cohereResp); err != nil {
 		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
 	}
-	usage := dto.Usage{}
-	usage.PromptTokens = cohereResp.Meta.BilledUnits.InputTokens
-	usage.CompletionTokens = cohereResp.Meta.BilledUnits.OutputTokens
-	usage.TotalTokens = cohereResp.Meta.BilledUnits.InputTokens + cohereResp.Meta.BilledUnits.OutputTokens
+	return channel.WriteConvertedResponse(c, info, resp, &cohereResp)
+}
 
-	var openaiResp dto.TextResponse
-	openaiResp.Id = cohereResp.ResponseId
-	openaiResp.Created = createdTime
-	openaiResp.Object = "chat.completion"
-	openaiResp.Model = info.UpstreamModelName
-	openaiResp.Usage = usage
+// CohereChatStreamHandler 逐个转换 Cohere Chat v2 的 SSE 事件为客户端请求的格式
+func CohereChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	stream, err := channel.NewConvertedStream(c, info, types.RelayFormatCohereChat)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
 
-	openaiResp.Choices = []dto.OpenAITextResponseChoice{
-		{
-			Index:        0,
-			Message:      dto.Message{Content: cohereResp.Text, Role: "assistant"},
-			FinishReason: stopReasonCohere2OpenAI(cohereResp.FinishReason),
-		},
+	var streamErr *types.NewAPIError
+	helper.StreamScannerHandler(c, resp, info, func(data string, sr *helper.StreamResult) {
+		var event dto.CohereChatStreamEvent
+		if err := common.UnmarshalJsonStr(data, &event); err != nil {
+			logger.LogError(c, "failed to unmarshal cohere stream event: "+err.Error())
+			sr.Error(err)
+			return
+		}
+		if event.Delta != nil && event.Delta.Error != "" {
+			streamErr = types.NewOpenAIError(errors.New(event.Delta.Error), types.ErrorCodeBadResponse, http.StatusInternalServerError)
+			sr.Stop(streamErr)
+			return
+		}
+		if err := stream.Send(&event); err != nil {
+			streamErr = types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+			sr.Stop(streamErr)
+		}
+	})
+	if streamErr != nil {
+		return nil, streamErr
 	}
 
-	jsonResponse, err := json.Marshal(openaiResp)
+	usage, err := stream.Finish()
 	if err != nil {
-		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
 	}
-	c.Writer.Header().Set("Content-Type", "application/json")
-	c.Writer.WriteHeader(resp.StatusCode)
-	_, _ = c.Writer.Write(jsonResponse)
-	return &usage, nil
+	return usage, nil
 }
 
 func cohereRerankHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.Usage, *types.NewAPIError) {
@@ -0,0 +1,132 @@
+package channel
+
+import (
+	"fmt"
+	"net/http"
+
+	common2 "github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WriteConvertedResponse 将已解析的上游非流式响应经转换器注册表转换为客户端请求的格式后写回
+func WriteConvertedResponse(c *gin.Context, info *common.RelayInfo, resp *http.Response, upstream any) (*dto.Usage, *types.NewAPIError) {
+	result, err := relayconvert.ConvertResponse(c, info, info.RelayFormat, upstream)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	usage := result.Usage
+	if usage == nil || usage.TotalTokens == 0 {
+		usage = service.ResponseText2Usage(c, "", info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+	responseBody, err := common2.Marshal(result.Value)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeJsonMarshalFailed, http.StatusInternalServerError)
+	}
+	service.IOCopyBytesGracefully(c, resp, responseBody)
+	return usage, nil
+}
+
+// ConvertedStream 将上游流式事件经转换器注册表逐个转换为客户端请求的格式并写出
+type ConvertedStream struct {
+	c     *gin.Context
+	info  *common.RelayInfo
+	state *relayconvert.ResponseStreamState
+}
+
+func NewConvertedStream(c *gin.Context, info *common.RelayInfo, from types.RelayFormat) (*ConvertedStream, error) {
+	state, err := relayconvert.NewResponseStreamState(from, info.RelayFormat, relayconvert.ResponseStreamOptions{
+		ID:      helper.GetResponseID(c),
+		Model:   info.UpstreamModelName,
+		Created: common2.GetTimestamp(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info.RelayFormat == types.RelayFormatClaude && info.ClaudeConvertInfo == nil {
+		info.ClaudeConvertInfo = &common.ClaudeConvertInfo{LastMessagesType: common.LastMessageTypeNone}
+	}
+	helper.SetEventStreamHeaders(c)
+	return &ConvertedStream{c: c, info: info, state: state}, nil
+}
+
+// Send 转换并写出一个上游流式事件
+func (s *ConvertedStream) Send(event any) error {
+	results, err := relayconvert.ConvertStreamResponseChunk(s.c, s.info, s.state, event)
+	if err != nil {
+		return err
+	}
+	return s.write(results)
+}
+
+// Finish 写出转换器缓存的收尾事件并返回本次流的用量，上游未返回用量时按输出文本估算
+func (s *ConvertedStream) Finish() (*dto.Usage, error) {
+	usage := s.state.Usage()
+	if usage == nil || usage.TotalTokens == 0 {
+		usage = service.ResponseText2Usage(s.c, s.state.UsageText(), s.info.UpstreamModelName, s.info.GetEstimatePromptTokens())
+		s.state.SetUsage(usage)
+	}
+	if s.info.RelayFormat == types.RelayFormatClaude && s.info.ClaudeConvertInfo != nil {
+		s.info.ClaudeConvertInfo.Usage = usage
+	}
+	results, err := relayconvert.FinalizeStreamResponse(s.c, s.info, s.state)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.write(results); err != nil {
+		return nil, err
+	}
+	if s.info.RelayFormat == types.RelayFormatOpenAI {
+		helper.Done(s.c)
+	}
+	return usage, nil
+}
+
+func (s *ConvertedStream) write(results []relayconvert.ResponseResult) error {
+	for _, result := range results {
+		if err := writeConvertedStreamValue(s.c, result.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeConvertedStreamValue(c *gin.Context, value any) error {
+	switch v := value.(type) {
+	case dto.ChatCompletionsStreamResponse:
+		return writeConvertedStreamValue(c, &v)
+	case *dto.ChatCompletionsStreamResponse:
+		if v == nil || (len(v.Choices) == 0 && v.Usage == nil) {
+			return nil
+		}
+		return helper.ObjectData(c, v)
+	case dto.ClaudeResponse:
+		return helper.ClaudeData(c, v)
+	case *dto.ClaudeResponse:
+		if v == nil {
+			return nil
+		}
+		return helper.ClaudeData(c, *v)
+	case dto.GeminiChatResponse:
+		return helper.ObjectData(c, &v)
+	case *dto.GeminiChatResponse:
+		if v == nil {
+			return nil
+		}
+		return helper.ObjectData(c, v)
+	case relayconvert.ChatToResponsesStreamEvent:
+		data, err := common2.Marshal(v.Payload)
+		if err != nil {
+			return err
+		}
+		return helper.ResponseChunkData(c, dto.ResponsesStreamResponse{Type: v.Type}, string(data))
+	default:
+		return fmt.Errorf("unsupported converted stream response type %T", value)
+	}
+}
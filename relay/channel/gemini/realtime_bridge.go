@@ -0,0 +1,567 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+)
+
+// OpenAI Realtime 默认的 pcm16 为 24kHz 单声道，Gemini Live 可接受声明采样率的 PCM 输入，输出同为 24kHz PCM。
+const geminiLiveInputAudioMimeType = "audio/pcm;rate=24000"
+
+// openAIRealtimeVoices 是 OpenAI 内置音色，Gemini 不认识这些名称，遇到时使用 Gemini 默认音色。
+var openAIRealtimeVoices = map[string]struct{}{
+	"alloy": {}, "ash": {}, "ballad": {}, "coral": {}, "echo": {},
+	"fable": {}, "onyx": {}, "nova": {}, "sage": {}, "shimmer": {}, "verse": {},
+	"marin": {}, "cedar": {},
+}
+
+// realtimeBridge translates between the OpenAI Realtime event protocol spoken
+// by the client and the Gemini Live BidiGenerateContent protocol spoken by
+// the upstream. It holds no connections so it can be driven directly in tests;
+// callers must serialise access.
+type realtimeBridge struct {
+	model   string
+	session dto.RealtimeSession
+
+	setupSent    bool
+	manualTurns  bool
+	activityOpen bool
+	pendingTurn  bool
+	// Gemini continues generating on its own after a tool response, so the
+	// response.create that OpenAI clients send next must not start a new turn.
+	skipResponseCreate bool
+	callNames          map[string]string
+
+	response        *dto.RealtimeResponse
+	messageItem     *dto.RealtimeItem
+	hasAudio        bool
+	text            strings.Builder
+	transcript      strings.Builder
+	inputTranscript strings.Builder
+	usage           *dto.RealtimeUsage
+}
+
+func newRealtimeBridge(model string) *realtimeBridge {
+	return &realtimeBridge{
+		model: model,
+		session: dto.RealtimeSession{
+			Modalities:        []string{"text", "audio"},
+			InputAudioFormat:  "pcm16",
+			OutputAudioFormat: "pcm16",
+			TurnDetection:     map[string]any{"type": "server_vad"},
+		},
+		callNames: make(map[string]string),
+	}
+}
+
+func (b *realtimeBridge) sessionCreatedEvent() dto.RealtimeEvent {
+	session := b.session
+	return dto.RealtimeEvent{
+		EventId: realtimeEventId(),
+		Type:    dto.RealtimeEventTypeSessionCreated,
+		Session: &session,
+	}
+}
+
+// handleClientMessage decodes one client event and returns it together with
+// the Gemini messages to send upstream and the events to answer the client
+// with directly.
+func (b *realtimeBridge) handleClientMessage(message []byte) (*dto.RealtimeEvent, []dto.GeminiLiveClientMessage, []dto.RealtimeEvent, error) {
+	event := &dto.RealtimeEvent{}
+	if err := common.Unmarshal(message, event); err != nil {
+		return nil, nil, nil, err
+	}
+	if event.Type == dto.RealtimeEventTypeSessionUpdate {
+		// turn_detection: null 表示手动轮次，需要与未携带该字段区分
+		var raw struct {
+			Session struct {
+				TurnDetection json.RawMessage `json:"turn_detection"`
+			} `json:"session"`
+		}
+		_ = common.Unmarshal(message, &raw)
+		return event, nil, b.updateSession(event.Session, raw.Session.TurnDetection != nil), nil
+	}
+	upstream, events := b.handleClientEvent(event)
+	return event, upstream, events, nil
+}
+
+func (b *realtimeBridge) handleClientEvent(event *dto.RealtimeEvent) ([]dto.GeminiLiveClientMessage, []dto.RealtimeEvent) {
+	switch event.Type {
+	case dto.RealtimeEventInputAudioBufferAppend:
+		upstream := b.ensureSetup()
+		if b.manualTurns && !b.activityOpen {
+			b.activityOpen = true
+			upstream = append(upstream, dto.GeminiLiveClientMessage{
+				RealtimeInput: &dto.GeminiLiveRealtimeInput{ActivityStart: &struct{}{}},
+			})
+		}
+		upstream = append(upstream, dto.GeminiLiveClientMessage{
+			RealtimeInput: &dto.GeminiLiveRealtimeInput{
+				Audio: &dto.GeminiInlineData{MimeType: geminiLiveInputAudioMimeType, Data: event.Audio},
+			},
+		})
+		return upstream, nil
+	case dto.RealtimeEventInputAudioBufferCommit:
+		var upstream []dto.GeminiLiveClientMessage
+		if b.activityOpen {
+			b.activityOpen = false
+			upstream = append(upstream, dto.GeminiLiveClientMessage{
+				RealtimeInput: &dto.GeminiLiveRealtimeInput{ActivityEnd: &struct{}{}},
+			})
+		}
+		return upstream, []dto.RealtimeEvent{{
+			EventId: realtimeEventId(),
+			Type:    dto.RealtimeEventInputAudioBufferCommitted,
+			ItemId:  realtimeItemId(),
+		}}
+	case dto.RealtimeEventInputAudioBufferClear:
+		// 已发送给 Gemini 的音频无法撤回，这里只确认客户端缓冲区已清空
+		return nil, []dto.RealtimeEvent{{
+			EventId: realtimeEventId(),
+			Type:    dto.RealtimeEventInputAudioBufferCleared,
+		}}
+	case dto.RealtimeEventTypeConversationCreate:
+		return b.createItem(event.Item)
+	case dto.RealtimeEventTypeResponseCreate:
+		if b.skipResponseCreate {
+			b.skipResponseCreate = false
+			return nil, nil
+		}
+		upstream := b.ensureSetup()
+		if b.activityOpen {
+			b.activityOpen = false
+			upstream = append(upstream, dto.GeminiLiveClientMessage{
+				RealtimeInput: &dto.GeminiLiveRealtimeInput{ActivityEnd: &struct{}{}},
+			})
+			return upstream, nil
+		}
+		if b.pendingTurn {
+			b.pendingTurn = false
+			upstream = append(upstream, dto.GeminiLiveClientMessage{
+				ClientContent: &dto.GeminiLiveClientContent{TurnComplete: true},
+			})
+		}
+		return upstream, nil
+	}
+	// response.cancel 等事件 Gemini Live 没有对应能力，直接忽略
+	return nil, nil
+}
+
+func (b *realtimeBridge) updateSession(session *dto.RealtimeSession, turnDetectionSet bool) []dto.RealtimeEvent {
+	if b.setupSent {
+		return []dto.RealtimeEvent{realtimeErrorEvent("session_update_not_supported",
+			"Gemini Live cannot change the session after the conversation has started")}
+	}
+	if session != nil {
+		if len(session.Modalities) > 0 {
+			b.session.Modalities = session.Modalities
+		}
+		b.session.Instructions = common.GetStringIfEmpty(session.Instructions, b.session.Instructions)
+		b.session.Voice = common.GetStringIfEmpty(session.Voice, b.session.Voice)
+		b.session.InputAudioFormat = common.GetStringIfEmpty(session.InputAudioFormat, b.session.InputAudioFormat)
+		b.session.OutputAudioFormat = common.GetStringIfEmpty(session.OutputAudioFormat, b.session.OutputAudioFormat)
+		b.session.InputAudioTranscription = session.InputAudioTranscription
+		if turnDetectionSet {
+			b.session.TurnDetection = session.TurnDetection
+		}
+		if session.Tools != nil {
+			b.session.Tools = session.Tools
+		}
+		b.session.ToolChoice = common.GetStringIfEmpty(session.ToolChoice, b.session.ToolChoice)
+		if session.Temperature != 0 {
+			b.session.Temperature = session.Temperature
+		}
+	}
+	current := b.session
+	return []dto.RealtimeEvent{{
+		EventId: realtimeEventId(),
+		Type:    dto.RealtimeEventTypeSessionUpdated,
+		Session: &current,
+	}}
+}
+
+func (b *realtimeBridge) ensureSetup() []dto.GeminiLiveClientMessage {
+	if b.setupSent {
+		return nil
+	}
+	b.setupSent = true
+	b.manualTurns = b.session.TurnDetection == nil
+
+	setup := &dto.GeminiLiveSetup{
+		Model:            "models/" + b.model,
+		GenerationConfig: &dto.GeminiChatGenerationConfig{},
+	}
+	if b.hasModality("audio") {
+		// 音频模式下由 Gemini 转写输出音频，用于 response.audio_transcript.delta
+		setup.GenerationConfig.ResponseModalities = []string{"AUDIO"}
+		setup.OutputAudioTranscription = &struct{}{}
+		if _, isOpenAIVoice := openAIRealtimeVoices[b.session.Voice]; b.session.Voice != "" && !isOpenAIVoice {
+			speechConfig, _ := common.Marshal(map[string]any{
+				"voiceConfig": map[string]any{
+					"prebuiltVoiceConfig": map[string]any{"voiceName": b.session.Voice},
+				},
+			})
+			setup.GenerationConfig.SpeechConfig = speechConfig
+		}
+	} else {
+		setup.GenerationConfig.ResponseModalities = []string{"TEXT"}
+	}
+	if b.session.Temperature != 0 {
+		temperature := b.session.Temperature
+		setup.GenerationConfig.Temperature = &temperature
+	}
+	if b.session.Instructions != "" {
+		setup.SystemInstruction = &dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{{Text: b.session.Instructions}},
+		}
+	}
+	if b.session.InputAudioTranscription.Model != "" {
+		setup.InputAudioTranscription = &struct{}{}
+	}
+	if b.manualTurns {
+		setup.RealtimeInputConfig = &dto.GeminiLiveRealtimeInputConfig{
+			AutomaticActivityDetection: &dto.GeminiLiveAutomaticActivityDetection{Disabled: true},
+		}
+	}
+	if b.session.ToolChoice != "none" {
+		declarations := make([]map[string]any, 0, len(b.session.Tools))
+		for _, tool := range b.session.Tools {
+			if tool.Type != "" && tool.Type != "function" {
+				continue
+			}
+			declaration := map[string]any{"name": tool.Name}
+			if tool.Description != "" {
+				declaration["description"] = tool.Description
+			}
+			if tool.Parameters != nil {
+				declaration["parametersJsonSchema"] = tool.Parameters
+			}
+			declarations = append(declarations, declaration)
+		}
+		if len(declarations) > 0 {
+			setup.Tools = []dto.GeminiChatTool{{FunctionDeclarations: declarations}}
+		}
+	}
+	return []dto.GeminiLiveClientMessage{{Setup: setup}}
+}
+
+func (b *realtimeBridge) createItem(item *dto.RealtimeItem) ([]dto.GeminiLiveClientMessage, []dto.RealtimeEvent) {
+	if item == nil {
+		return nil, []dto.RealtimeEvent{realtimeErrorEvent("invalid_request_error", "item is required")}
+	}
+	created := *item
+	if created.Id == "" {
+		created.Id = realtimeItemId()
+	}
+	ack := []dto.RealtimeEvent{{
+		EventId: realtimeEventId(),
+		Type:    dto.RealtimeEventConversationItemCreated,
+		Item:    &created,
+	}}
+
+	upstream := b.ensureSetup()
+	switch item.Type {
+	case "function_call_output":
+		response := map[string]any{}
+		if err := common.UnmarshalJsonStr(item.Output, &response); err != nil || len(response) == 0 {
+			response = map[string]any{"output": item.Output}
+		}
+		b.skipResponseCreate = true
+		upstream = append(upstream, dto.GeminiLiveClientMessage{
+			ToolResponse: &dto.GeminiLiveToolResponse{
+				FunctionResponses: []dto.GeminiLiveFunctionResponse{{
+					Id:       item.CallId,
+					Name:     b.callNames[item.CallId],
+					Response: response,
+				}},
+			},
+		})
+	case "message", "":
+		var parts []dto.GeminiPart
+		for _, content := range item.Content {
+			text := content.Text
+			if text == "" {
+				text = content.Transcript
+			}
+			if text != "" {
+				parts = append(parts, dto.GeminiPart{Text: text})
+			}
+		}
+		if len(parts) == 0 {
+			return upstream, ack
+		}
+		role := "user"
+		if item.Role == "assistant" {
+			role = "model"
+		}
+		b.pendingTurn = true
+		upstream = append(upstream, dto.GeminiLiveClientMessage{
+			ClientContent: &dto.GeminiLiveClientContent{
+				Turns: []dto.GeminiChatContent{{Role: role, Parts: parts}},
+			},
+		})
+	}
+	return upstream, ack
+}
+
+// handleServerMessage converts one Gemini Live message into client events.
+// A response.done event is emitted per finished turn; its usage is nil when
+// Gemini did not report usage for the turn.
+func (b *realtimeBridge) handleServerMessage(message *dto.GeminiLiveServerMessage) []dto.RealtimeEvent {
+	if message.UsageMetadata != nil {
+		b.usage = realtimeUsageFromGeminiLive(message.UsageMetadata)
+	}
+	var events []dto.RealtimeEvent
+
+	if toolCall := message.ToolCall; toolCall != nil && len(toolCall.FunctionCalls) > 0 {
+		events = append(events, b.startResponse()...)
+		events = append(events, b.closeMessageItem()...)
+		for _, call := range toolCall.FunctionCalls {
+			callId := common.GetStringIfEmpty(call.Id, "call_"+common.GetRandomString(24))
+			b.callNames[callId] = call.Name
+			arguments := "{}"
+			if len(call.Args) > 0 {
+				if data, err := common.Marshal(call.Args); err == nil {
+					arguments = string(data)
+				}
+			}
+			item := dto.RealtimeItem{
+				Id:        realtimeItemId(),
+				Type:      "function_call",
+				Status:    "completed",
+				Name:      &call.Name,
+				CallId:    callId,
+				Arguments: arguments,
+			}
+			b.response.Output = append(b.response.Output, item)
+			events = append(events,
+				dto.RealtimeEvent{EventId: realtimeEventId(), Type: dto.RealtimeEventResponseOutputItemAdded, ResponseId: b.response.Id, Item: &item},
+				dto.RealtimeEvent{EventId: realtimeEventId(), Type: dto.RealtimeEventResponseFunctionCallArgumentsDone, ResponseId: b.response.Id, ItemId: item.Id, CallId: callId, Name: call.Name, Arguments: arguments},
+				dto.RealtimeEvent{EventId: realtimeEventId(), Type: dto.RealtimeEventResponseOutputItemDone, ResponseId: b.response.Id, Item: &item},
+			)
+		}
+		// OpenAI 在函数调用后结束本次 response，等待客户端提交结果
+		events = append(events, b.finishResponse("completed")...)
+	}
+
+	content := message.ServerContent
+	if content == nil {
+		return events
+	}
+	if content.InputTranscription != nil {
+		b.inputTranscript.WriteString(content.InputTranscription.Text)
+	}
+	if content.Interrupted {
+		events = append(events, dto.RealtimeEvent{
+			EventId: realtimeEventId(),
+			Type:    dto.RealtimeEventInputAudioBufferSpeechStarted,
+		})
+		if b.response != nil {
+			events = append(events, b.finishResponse("cancelled")...)
+		}
+	}
+	if content.ModelTurn != nil {
+		for _, part := range content.ModelTurn.Parts {
+			if part.Thought {
+				continue
+			}
+			if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/") {
+				events = append(events, b.startMessageItem()...)
+				b.hasAudio = true
+				events = append(events, dto.RealtimeEvent{
+					EventId:    realtimeEventId(),
+					Type:       dto.RealtimeEventResponseAudioDelta,
+					ResponseId: b.response.Id,
+					ItemId:     b.messageItem.Id,
+					Delta:      part.InlineData.Data,
+				})
+			} else if part.Text != "" {
+				events = append(events, b.startMessageItem()...)
+				b.text.WriteString(part.Text)
+				events = append(events, dto.RealtimeEvent{
+					EventId:    realtimeEventId(),
+					Type:       dto.RealtimeEventResponseTextDelta,
+					ResponseId: b.response.Id,
+					ItemId:     b.messageItem.Id,
+					Delta:      part.Text,
+				})
+			}
+		}
+	}
+	if content.OutputTranscription != nil && content.OutputTranscription.Text != "" {
+		events = append(events, b.startMessageItem()...)
+		b.transcript.WriteString(content.OutputTranscription.Text)
+		events = append(events, dto.RealtimeEvent{
+			EventId:    realtimeEventId(),
+			Type:       dto.RealtimeEventResponseAudioTranscriptionDelta,
+			ResponseId: b.response.Id,
+			ItemId:     b.messageItem.Id,
+			Delta:      content.OutputTranscription.Text,
+		})
+	}
+	if content.TurnComplete {
+		if b.inputTranscript.Len() > 0 {
+			events = append(events, dto.RealtimeEvent{
+				EventId:    realtimeEventId(),
+				Type:       dto.RealtimeEventInputAudioTranscriptionCompleted,
+				ItemId:     realtimeItemId(),
+				Transcript: b.inputTranscript.String(),
+			})
+			b.inputTranscript.Reset()
+		}
+		if b.response != nil {
+			events = append(events, b.finishResponse("completed")...)
+		}
+	}
+	return events
+}
+
+// takeUsage returns usage reported after the last response.done, if any.
+func (b *realtimeBridge) takeUsage() *dto.RealtimeUsage {
+	usage := b.usage
+	b.usage = nil
+	return usage
+}
+
+func (b *realtimeBridge) hasModality(modality string) bool {
+	for _, m := range b.session.Modalities {
+		if m == modality {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *realtimeBridge) startResponse() []dto.RealtimeEvent {
+	if b.response != nil {
+		return nil
+	}
+	b.response = &dto.RealtimeResponse{
+		Id:     "resp_" + common.GetRandomString(24),
+		Object: "realtime.response",
+		Status: "in_progress",
+	}
+	created := *b.response
+	return []dto.RealtimeEvent{{
+		EventId:  realtimeEventId(),
+		Type:     dto.RealtimeEventTypeResponseCreated,
+		Response: &created,
+	}}
+}
+
+func (b *realtimeBridge) startMessageItem() []dto.RealtimeEvent {
+	events := b.startResponse()
+	if b.messageItem != nil {
+		return events
+	}
+	b.messageItem = &dto.RealtimeItem{
+		Id:     realtimeItemId(),
+		Type:   "message",
+		Status: "in_progress",
+		Role:   "assistant",
+	}
+	item := *b.messageItem
+	return append(events, dto.RealtimeEvent{
+		EventId:    realtimeEventId(),
+		Type:       dto.RealtimeEventResponseOutputItemAdded,
+		ResponseId: b.response.Id,
+		Item:       &item,
+	})
+}
+
+func (b *realtimeBridge) closeMessageItem() []dto.RealtimeEvent {
+	if b.messageItem == nil {
+		return nil
+	}
+	var events []dto.RealtimeEvent
+	item := *b.messageItem
+	item.Status = "completed"
+	if b.hasAudio {
+		item.Content = append(item.Content, dto.RealtimeContent{Type: "audio", Transcript: b.transcript.String()})
+		events = append(events, dto.RealtimeEvent{
+			EventId:    realtimeEventId(),
+			Type:       dto.RealtimeEventResponseAudioDone,
+			ResponseId: b.response.Id,
+			ItemId:     item.Id,
+		})
+	}
+	if b.text.Len() > 0 {
+		item.Content = append(item.Content, dto.RealtimeContent{Type: "text", Text: b.text.String()})
+	}
+	b.response.Output = append(b.response.Output, item)
+	events = append(events, dto.RealtimeEvent{
+		EventId:    realtimeEventId(),
+		Type:       dto.RealtimeEventResponseOutputItemDone,
+		ResponseId: b.response.Id,
+		Item:       &item,
+	})
+	b.messageItem = nil
+	b.hasAudio = false
+	b.text.Reset()
+	b.transcript.Reset()
+	return events
+}
+
+func (b *realtimeBridge) finishResponse(status string) []dto.RealtimeEvent {
+	if b.response == nil {
+		return nil
+	}
+	events := b.closeMessageItem()
+	done := *b.response
+	done.Status = status
+	done.Usage = b.takeUsage()
+	b.response = nil
+	return append(events, dto.RealtimeEvent{
+		EventId:  realtimeEventId(),
+		Type:     dto.RealtimeEventTypeResponseDone,
+		Response: &done,
+	})
+}
+
+func realtimeUsageFromGeminiLive(metadata *dto.GeminiLiveUsageMetadata) *dto.RealtimeUsage {
+	usage := &dto.RealtimeUsage{
+		InputTokens:  metadata.PromptTokenCount + metadata.ToolUsePromptTokenCount,
+		OutputTokens: metadata.ResponseTokenCount + metadata.ThoughtsTokenCount,
+	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	usage.InputTokenDetails.CachedTokens = metadata.CachedContentTokenCount
+	for _, detail := range metadata.PromptTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.InputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	for _, detail := range metadata.ResponseTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.OutputTokenDetails.AudioTokens += detail.TokenCount
+		}
+	}
+	// 未按模态细分的部分（文本、图像、工具与思考）按文本计费
+	usage.InputTokenDetails.TextTokens = usage.InputTokens - usage.InputTokenDetails.AudioTokens
+	usage.OutputTokenDetails.TextTokens = usage.OutputTokens - usage.OutputTokenDetails.AudioTokens
+	return usage
+}
+
+func realtimeErrorEvent(code string, message string) dto.RealtimeEvent {
+	return dto.RealtimeEvent{
+		EventId: realtimeEventId(),
+		Type:    dto.RealtimeEventTypeError,
+		Error: &types.OpenAIError{
+			Message: message,
+			Type:    "invalid_request_error",
+			Code:    code,
+		},
+	}
+}
+
+func realtimeEventId() string {
+	return fmt.Sprintf("event_%s", common.GetRandomString(20))
+}
+
+func realtimeItemId() string {
+	return fmt.Sprintf("item_%s", common.GetRandomString(20))
+}
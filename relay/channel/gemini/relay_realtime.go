@@ -0,0 +1,221 @@
+package gemini
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// GeminiRealtimeHandler 将客户端的 OpenAI Realtime 会话桥接到 Gemini Live，按轮次预扣费。
+// Gemini 未返回 usageMetadata 时，使用本地估算的文本/音频 token 计费。
+func GeminiRealtimeHandler(c *gin.Context, info *relaycommon.RelayInfo) (*types.NewAPIError, *dto.RealtimeUsage) {
+	if info == nil || info.ClientWs == nil || info.TargetWs == nil {
+		return types.NewError(fmt.Errorf("invalid websocket connection"), types.ErrorCodeBadResponse), nil
+	}
+
+	info.IsStream = true
+	clientConn := info.ClientWs
+	targetConn := info.TargetWs
+
+	clientClosed := make(chan struct{})
+	targetClosed := make(chan struct{})
+	errChan := make(chan error, 2)
+
+	var (
+		mu         sync.Mutex
+		clientMu   sync.Mutex
+		bridge     = newRealtimeBridge(info.UpstreamModelName)
+		localUsage = &dto.RealtimeUsage{}
+		sumUsage   = &dto.RealtimeUsage{}
+	)
+
+	writeClient := func(events []dto.RealtimeEvent) error {
+		clientMu.Lock()
+		defer clientMu.Unlock()
+		for i := range events {
+			if err := helper.WssObject(c, clientConn, events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// settle 在 mu 内调用：统计发往客户端的事件，遇到 response.done 时按轮次扣费
+	settle := func(events []dto.RealtimeEvent) error {
+		for i := range events {
+			event := &events[i]
+			if event.Type == dto.RealtimeEventTypeResponseDone && event.Response != nil {
+				if event.Response.Usage == nil {
+					event.Response.Usage = localUsage
+				}
+				info.IsFirstRequest = false
+				usage := event.Response.Usage
+				localUsage = &dto.RealtimeUsage{}
+				if err := consumeRealtimeUsage(c, info, usage, sumUsage); err != nil {
+					return err
+				}
+				continue
+			}
+			textToken, audioToken, err := service.CountTokenRealtime(info, *event, info.UpstreamModelName)
+			if err != nil {
+				return err
+			}
+			localUsage.TotalTokens += textToken + audioToken
+			switch event.Type {
+			case dto.RealtimeEventResponseAudioDelta, dto.RealtimeEventResponseAudioTranscriptionDelta:
+				localUsage.OutputTokens += textToken + audioToken
+				localUsage.OutputTokenDetails.TextTokens += textToken
+				localUsage.OutputTokenDetails.AudioTokens += audioToken
+			default:
+				localUsage.InputTokens += textToken + audioToken
+				localUsage.InputTokenDetails.TextTokens += textToken
+				localUsage.InputTokenDetails.AudioTokens += audioToken
+			}
+		}
+		return nil
+	}
+
+	mu.Lock()
+	created := []dto.RealtimeEvent{bridge.sessionCreatedEvent()}
+	mu.Unlock()
+	if err := writeClient(created); err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponse), nil
+	}
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in client reader: %v", r)
+			}
+		}()
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := clientConn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from client: %v", err)
+					}
+					close(clientClosed)
+					return
+				}
+
+				mu.Lock()
+				event, upstream, replies, err := bridge.handleClientMessage(message)
+				if err == nil {
+					if event.Type == dto.RealtimeEventTypeSessionUpdate && event.Session != nil && event.Session.Tools != nil {
+						info.RealtimeTools = event.Session.Tools
+					}
+					err = settle([]dto.RealtimeEvent{*event})
+				}
+				if err == nil {
+					err = settle(replies)
+				}
+				mu.Unlock()
+				if err != nil {
+					errChan <- fmt.Errorf("error handling client event: %v", err)
+					return
+				}
+
+				for _, upstreamMessage := range upstream {
+					if err := helper.WssObject(c, targetConn, upstreamMessage); err != nil {
+						errChan <- fmt.Errorf("error writing to target: %v", err)
+						return
+					}
+				}
+				if err := writeClient(replies); err != nil {
+					errChan <- fmt.Errorf("error writing to client: %v", err)
+					return
+				}
+			}
+		}
+	})
+
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic in target reader: %v", r)
+			}
+		}()
+		for {
+			select {
+			case <-c.Done():
+				return
+			default:
+				_, message, err := targetConn.ReadMessage()
+				if err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						errChan <- fmt.Errorf("error reading from target: %v", err)
+					}
+					close(targetClosed)
+					return
+				}
+				info.SetFirstResponseTime()
+				serverMessage := &dto.GeminiLiveServerMessage{}
+				if err := common.Unmarshal(message, serverMessage); err != nil {
+					errChan <- fmt.Errorf("error unmarshalling message: %v", err)
+					return
+				}
+				if serverMessage.GoAway != nil {
+					logger.LogWarn(c, fmt.Sprintf("gemini live session will close, time left: %s", serverMessage.GoAway.TimeLeft))
+				}
+
+				mu.Lock()
+				events := bridge.handleServerMessage(serverMessage)
+				err = settle(events)
+				mu.Unlock()
+				if err != nil {
+					errChan <- fmt.Errorf("error consume usage: %v", err)
+					return
+				}
+				if err := writeClient(events); err != nil {
+					errChan <- fmt.Errorf("error writing to client: %v", err)
+					return
+				}
+			}
+		}
+	})
+
+	select {
+	case <-clientClosed:
+	case <-targetClosed:
+	case err := <-errChan:
+		logger.LogError(c, "gemini realtime error: "+err.Error())
+	case <-c.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 未结束的轮次：优先使用 Gemini 已上报的用量，否则使用本地估算
+	if usage := bridge.takeUsage(); usage != nil {
+		_ = consumeRealtimeUsage(c, info, usage, sumUsage)
+	} else if localUsage.TotalTokens != 0 {
+		_ = consumeRealtimeUsage(c, info, localUsage, sumUsage)
+	}
+	return nil, sumUsage
+}
+
+func consumeRealtimeUsage(c *gin.Context, info *relaycommon.RelayInfo, usage *dto.RealtimeUsage, totalUsage *dto.RealtimeUsage) error {
+	totalUsage.TotalTokens += usage.TotalTokens
+	totalUsage.InputTokens += usage.InputTokens
+	totalUsage.OutputTokens += usage.OutputTokens
+	totalUsage.InputTokenDetails.CachedTokens += usage.InputTokenDetails.CachedTokens
+	totalUsage.InputTokenDetails.TextTokens += usage.InputTokenDetails.TextTokens
+	totalUsage.InputTokenDetails.AudioTokens += usage.InputTokenDetails.AudioTokens
+	totalUsage.OutputTokenDetails.TextTokens += usage.OutputTokenDetails.TextTokens
+	totalUsage.OutputTokenDetails.AudioTokens += usage.OutputTokenDetails.AudioTokens
+	return service.PreWssConsumeQuota(c, info, usage)
+}
@@ -0,0 +1,314 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bridgeClientMessage(t *testing.T, bridge *realtimeBridge, raw string) ([]dto.GeminiLiveClientMessage, []dto.RealtimeEvent) {
+	t.Helper()
+	_, upstream, replies, err := bridge.handleClientMessage([]byte(raw))
+	require.NoError(t, err)
+	return upstream, replies
+}
+
+func realtimeEventTypes(events []dto.RealtimeEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestRealtimeBridgeTextTurn(t *testing.T) {
+	bridge := newRealtimeBridge("gemini-live-test")
+
+	upstream, replies := bridgeClientMessage(t, bridge, `{"type":"session.update","session":{"modalities":["text"],"instructions":"be brief","tools":[{"type":"function","name":"lookup","description":"Look up","parameters":{"type":"object"}}]}}`)
+	assert.Empty(t, upstream)
+	require.Len(t, replies, 1)
+	assert.Equal(t, dto.RealtimeEventTypeSessionUpdated, replies[0].Type)
+
+	upstream, replies = bridgeClientMessage(t, bridge, `{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}}`)
+	require.Len(t, upstream, 2)
+	setup := upstream[0].Setup
+	require.NotNil(t, setup)
+	assert.Equal(t, "models/gemini-live-test", setup.Model)
+	assert.Equal(t, []string{"TEXT"}, setup.GenerationConfig.ResponseModalities)
+	assert.Equal(t, "be brief", setup.SystemInstruction.Parts[0].Text)
+	assert.Nil(t, setup.RealtimeInputConfig, "turn detection was not changed, so VAD stays on")
+	require.Len(t, setup.Tools, 1)
+	require.NotNil(t, upstream[1].ClientContent)
+	assert.Equal(t, "hi", upstream[1].ClientContent.Turns[0].Parts[0].Text)
+	assert.False(t, upstream[1].ClientContent.TurnComplete)
+	assert.Equal(t, []string{dto.RealtimeEventConversationItemCreated}, realtimeEventTypes(replies))
+
+	upstream, _ = bridgeClientMessage(t, bridge, `{"type":"response.create"}`)
+	require.Len(t, upstream, 1)
+	assert.True(t, upstream[0].ClientContent.TurnComplete)
+
+	_, replies = bridgeClientMessage(t, bridge, `{"type":"session.update","session":{"instructions":"changed"}}`)
+	assert.Equal(t, []string{dto.RealtimeEventTypeError}, realtimeEventTypes(replies))
+
+	events := bridge.handleServerMessage(&dto.GeminiLiveServerMessage{
+		ServerContent: &dto.GeminiLiveServerContent{
+			ModelTurn: &dto.GeminiChatContent{Parts: []dto.GeminiPart{{Text: "hello"}}},
+		},
+	})
+	assert.Equal(t, []string{
+		dto.RealtimeEventTypeResponseCreated,
+		dto.RealtimeEventResponseOutputItemAdded,
+		dto.RealtimeEventResponseTextDelta,
+	}, realtimeEventTypes(events))
+
+	events = bridge.handleServerMessage(&dto.GeminiLiveServerMessage{
+		ServerContent: &dto.GeminiLiveServerContent{TurnComplete: true},
+		UsageMetadata: &dto.GeminiLiveUsageMetadata{
+			PromptTokenCount:      12,
+			ResponseTokenCount:    3,
+			TotalTokenCount:       15,
+			PromptTokensDetails:   []dto.GeminiPromptTokensDetails{{Modality: "TEXT", TokenCount: 12}},
+			ResponseTokensDetails: []dto.GeminiPromptTokensDetails{{Modality: "TEXT", TokenCount: 3}},
+		},
+	})
+	assert.Equal(t, []string{
+		dto.RealtimeEventResponseOutputItemDone,
+		dto.RealtimeEventTypeResponseDone,
+	}, realtimeEventTypes(events))
+	done := events[1].Response
+	assert.Equal(t, "completed", done.Status)
+	require.Len(t, done.Output, 1)
+	assert.Equal(t, "hello", done.Output[0].Content[0].Text)
+	require.NotNil(t, done.Usage)
+	assert.Equal(t, 12, done.Usage.InputTokenDetails.TextTokens)
+	assert.Equal(t, 3, done.Usage.OutputTokenDetails.TextTokens)
+	assert.Nil(t, bridge.takeUsage())
+}
+
+func TestRealtimeBridgeManualAudioTurnAndToolCall(t *testing.T) {
+	bridge := newRealtimeBridge("gemini-live-test")
+
+	bridgeClientMessage(t, bridge, `{"type":"session.update","session":{"turn_detection":null,"voice":"alloy"}}`)
+	upstream, _ := bridgeClientMessage(t, bridge, `{"type":"input_audio_buffer.append","audio":"AAAA"}`)
+	require.Len(t, upstream, 3)
+	setup := upstream[0].Setup
+	require.NotNil(t, setup)
+	assert.Equal(t, []string{"AUDIO"}, setup.GenerationConfig.ResponseModalities)
+	assert.Empty(t, setup.GenerationConfig.SpeechConfig, "OpenAI voice names fall back to the Gemini default voice")
+	require.NotNil(t, setup.RealtimeInputConfig)
+	assert.True(t, setup.RealtimeInputConfig.AutomaticActivityDetection.Disabled)
+	assert.NotNil(t, upstream[1].RealtimeInput.ActivityStart)
+	assert.Equal(t, "AAAA", upstream[2].RealtimeInput.Audio.Data)
+	assert.Equal(t, geminiLiveInputAudioMimeType, upstream[2].RealtimeInput.Audio.MimeType)
+
+	upstream, replies := bridgeClientMessage(t, bridge, `{"type":"input_audio_buffer.commit"}`)
+	require.Len(t, upstream, 1)
+	assert.NotNil(t, upstream[0].RealtimeInput.ActivityEnd)
+	assert.Equal(t, []string{dto.RealtimeEventInputAudioBufferCommitted}, realtimeEventTypes(replies))
+	upstream, _ = bridgeClientMessage(t, bridge, `{"type":"response.create"}`)
+	assert.Empty(t, upstream, "activityEnd already started generation")
+
+	events := bridge.handleServerMessage(&dto.GeminiLiveServerMessage{
+		ToolCall: &dto.GeminiLiveToolCall{FunctionCalls: []dto.GeminiLiveFunctionCall{
+			{Id: "fc_1", Name: "lookup", Args: map[string]any{"q": "x"}},
+		}},
+	})
+	assert.Equal(t, []string{
+		dto.RealtimeEventTypeResponseCreated,
+		dto.RealtimeEventResponseOutputItemAdded,
+		dto.RealtimeEventResponseFunctionCallArgumentsDone,
+		dto.RealtimeEventResponseOutputItemDone,
+		dto.RealtimeEventTypeResponseDone,
+	}, realtimeEventTypes(events))
+	assert.Equal(t, "fc_1", events[2].CallId)
+	assert.Equal(t, `{"q":"x"}`, events[2].Arguments)
+	assert.Nil(t, events[4].Response.Usage)
+
+	upstream, _ = bridgeClientMessage(t, bridge, `{"type":"conversation.item.create","item":{"type":"function_call_output","call_id":"fc_1","output":"{\"answer\":42}"}}`)
+	require.Len(t, upstream, 1)
+	functionResponse := upstream[0].ToolResponse.FunctionResponses[0]
+	assert.Equal(t, "fc_1", functionResponse.Id)
+	assert.Equal(t, "lookup", functionResponse.Name)
+	assert.Equal(t, float64(42), functionResponse.Response["answer"])
+	upstream, _ = bridgeClientMessage(t, bridge, `{"type":"response.create"}`)
+	assert.Empty(t, upstream, "Gemini continues on its own after a tool response")
+
+	events = bridge.handleServerMessage(&dto.GeminiLiveServerMessage{
+		ServerContent: &dto.GeminiLiveServerContent{
+			ModelTurn:           &dto.GeminiChatContent{Parts: []dto.GeminiPart{{InlineData: &dto.GeminiInlineData{MimeType: "audio/pcm;rate=24000", Data: "BBBB"}}}},
+			OutputTranscription: &dto.GeminiLiveTranscription{Text: "forty two"},
+		},
+	})
+	assert.Equal(t, []string{
+		dto.RealtimeEventTypeResponseCreated,
+		dto.RealtimeEventResponseOutputItemAdded,
+		dto.RealtimeEventResponseAudioDelta,
+		dto.RealtimeEventResponseAudioTranscriptionDelta,
+	}, realtimeEventTypes(events))
+
+	events = bridge.handleServerMessage(&dto.GeminiLiveServerMessage{
+		ServerContent: &dto.GeminiLiveServerContent{Interrupted: true},
+	})
+	assert.Equal(t, []string{
+		dto.RealtimeEventInputAudioBufferSpeechStarted,
+		dto.RealtimeEventResponseAudioDone,
+		dto.RealtimeEventResponseOutputItemDone,
+		dto.RealtimeEventTypeResponseDone,
+	}, realtimeEventTypes(events))
+	assert.Equal(t, "cancelled", events[3].Response.Status)
+	assert.Equal(t, "forty two", events[3].Response.Output[0].Content[0].Transcript)
+}
+
+func TestRealtimeUsageFromGeminiLiveSplitsModalities(t *testing.T) {
+	usage := realtimeUsageFromGeminiLive(&dto.GeminiLiveUsageMetadata{
+		PromptTokenCount:        100,
+		CachedContentTokenCount: 10,
+		ResponseTokenCount:      40,
+		ThoughtsTokenCount:      5,
+		PromptTokensDetails: []dto.GeminiPromptTokensDetails{
+			{Modality: "AUDIO", TokenCount: 80},
+			{Modality: "TEXT", TokenCount: 20},
+		},
+		ResponseTokensDetails: []dto.GeminiPromptTokensDetails{{Modality: "AUDIO", TokenCount: 40}},
+	})
+
+	assert.Equal(t, 100, usage.InputTokens)
+	assert.Equal(t, 45, usage.OutputTokens)
+	assert.Equal(t, 145, usage.TotalTokens)
+	assert.Equal(t, 80, usage.InputTokenDetails.AudioTokens)
+	assert.Equal(t, 20, usage.InputTokenDetails.TextTokens)
+	assert.Equal(t, 10, usage.InputTokenDetails.CachedTokens)
+	assert.Equal(t, 40, usage.OutputTokenDetails.AudioTokens)
+	assert.Equal(t, 5, usage.OutputTokenDetails.TextTokens)
+}
+
+// TestGeminiRealtimeHandlerBridgesLocalStandIn drives the handler between a
+// real client WebSocket and a local stand-in for the Gemini Live endpoint.
+func TestGeminiRealtimeHandlerBridgesLocalStandIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upgrader := websocket.Upgrader{}
+
+	upstreamReceived := make(chan dto.GeminiLiveClientMessage, 8)
+	geminiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var message dto.GeminiLiveClientMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+			upstreamReceived <- message
+			switch {
+			case message.Setup != nil:
+				_ = conn.WriteJSON(map[string]any{"setupComplete": map[string]any{}})
+			case message.ClientContent != nil && message.ClientContent.TurnComplete:
+				_ = conn.WriteJSON(map[string]any{"serverContent": map[string]any{
+					"modelTurn": map[string]any{"parts": []any{map[string]any{"inlineData": map[string]any{"mimeType": "audio/pcm;rate=24000", "data": "AAAA"}}}},
+				}})
+				_ = conn.WriteJSON(map[string]any{
+					"serverContent": map[string]any{"turnComplete": true},
+					"usageMetadata": map[string]any{
+						"promptTokenCount":      7,
+						"responseTokenCount":    9,
+						"totalTokenCount":       16,
+						"promptTokensDetails":   []any{map[string]any{"modality": "TEXT", "tokenCount": 7}},
+						"responseTokensDetails": []any{map[string]any{"modality": "AUDIO", "tokenCount": 9}},
+					},
+				})
+			}
+		}
+	}))
+	defer geminiServer.Close()
+
+	result := make(chan *dto.RealtimeUsage, 1)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientWs, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer clientWs.Close()
+		targetWs, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(geminiServer.URL, "http"), nil)
+		if err != nil {
+			return
+		}
+		defer targetWs.Close()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = r
+		info := &relaycommon.RelayInfo{
+			ClientWs:          clientWs,
+			TargetWs:          targetWs,
+			UsePrice:          true,
+			InputAudioFormat:  "pcm16",
+			OutputAudioFormat: "pcm16",
+			ChannelMeta: &relaycommon.ChannelMeta{
+				UpstreamModelName: "gemini-live-test",
+			},
+		}
+		apiErr, usage := GeminiRealtimeHandler(c, info)
+		if apiErr == nil {
+			result <- usage
+		}
+	}))
+	defer proxyServer.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(proxyServer.URL, "http"), nil)
+	require.NoError(t, err)
+
+	readEvent := func() dto.RealtimeEvent {
+		t.Helper()
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, data, err := client.ReadMessage()
+		require.NoError(t, err)
+		var event dto.RealtimeEvent
+		require.NoError(t, common.Unmarshal(data, &event))
+		return event
+	}
+
+	assert.Equal(t, dto.RealtimeEventTypeSessionCreated, readEvent().Type)
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}}`)))
+	assert.Equal(t, dto.RealtimeEventConversationItemCreated, readEvent().Type)
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.create"}`)))
+
+	var done dto.RealtimeEvent
+	var audioDeltas int
+	for done.Type != dto.RealtimeEventTypeResponseDone {
+		done = readEvent()
+		if done.Type == dto.RealtimeEventResponseAudioDelta {
+			audioDeltas++
+			assert.Equal(t, "AAAA", done.Delta)
+		}
+	}
+	assert.Equal(t, 1, audioDeltas)
+	require.NotNil(t, done.Response.Usage)
+	assert.Equal(t, 9, done.Response.Usage.OutputTokenDetails.AudioTokens)
+
+	setup := <-upstreamReceived
+	require.NotNil(t, setup.Setup)
+	assert.Equal(t, "models/gemini-live-test", setup.Setup.Model)
+
+	require.NoError(t, client.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	_ = client.Close()
+
+	select {
+	case usage := <-result:
+		require.NotNil(t, usage)
+		assert.Equal(t, 7, usage.InputTokens)
+		assert.Equal(t, 9, usage.OutputTokens)
+	case <-time.After(5 * time.Second):
+		t.Fatal("realtime handler did not return")
+	}
+}
@@ -0,0 +1,444 @@
+package claudebatch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	taskcommon "github.com/QuantumNous/new-api/relay/channel/task/taskcommon"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	contextKeyBatchRequest = "claude_batch_request"
+
+	defaultAnthropicVersion = "2023-06-01"
+)
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	taskcommon.BaseBilling
+	ChannelType int
+	apiKey      string
+	baseURL     string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	a.apiKey = info.ApiKey
+}
+
+// ParseCreateRequest 解析并校验批次请求。批次内所有请求必须使用同一个模型，
+// 这样整个批次可以固定在一个渠道上计费和轮询。
+func ParseCreateRequest(body []byte) (*CreateRequest, error) {
+	var req CreateRequest
+	if err := common.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Requests) == 0 {
+		return nil, fmt.Errorf("requests must contain at least one item")
+	}
+	if len(req.Requests) > MaxBatchRequests {
+		return nil, fmt.Errorf("requests must contain at most %d items", MaxBatchRequests)
+	}
+	modelName := ""
+	seen := make(map[string]struct{}, len(req.Requests))
+	for i, item := range req.Requests {
+		if item.CustomID == "" {
+			return nil, fmt.Errorf("requests.%d.custom_id is required", i)
+		}
+		if _, ok := seen[item.CustomID]; ok {
+			return nil, fmt.Errorf("requests.%d.custom_id %q is duplicated", i, item.CustomID)
+		}
+		seen[item.CustomID] = struct{}{}
+		var params requestParams
+		if err := common.Unmarshal(item.Params, &params); err != nil {
+			return nil, fmt.Errorf("requests.%d.params is invalid: %w", i, err)
+		}
+		if params.Model == "" {
+			return nil, fmt.Errorf("requests.%d.params.model is required", i)
+		}
+		if params.Stream {
+			return nil, fmt.Errorf("requests.%d.params.stream is not supported in message batches", i)
+		}
+		if modelName == "" {
+			modelName = params.Model
+		} else if params.Model != modelName {
+			return nil, fmt.Errorf("all requests in a batch must use the same model, got %q and %q", modelName, params.Model)
+		}
+	}
+	return &req, nil
+}
+
+func getBatchRequest(c *gin.Context) (*CreateRequest, error) {
+	if v, ok := c.Get(contextKeyBatchRequest); ok {
+		if req, ok := v.(*CreateRequest); ok {
+			return req, nil
+		}
+	}
+	return nil, fmt.Errorf("batch request not found in context")
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
+	if err := validateBatchChannel(info); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_channel_type", http.StatusBadRequest)
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+	}
+	body, err := storage.Bytes()
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+	}
+	req, err := ParseCreateRequest(body)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	c.Set(contextKeyBatchRequest, req)
+	info.Action = constant.TaskActionMessageBatch
+	return nil
+}
+
+// EstimateBilling 按次计费时按请求数预扣；按量计费时用输入估算 + max_tokens 预扣，
+// 两者都乘以批次折扣。
+func (a *TaskAdaptor) EstimateBilling(c *gin.Context, info *relaycommon.RelayInfo) map[string]float64 {
+	req, err := getBatchRequest(c)
+	if err != nil {
+		return nil
+	}
+	discount := model_setting.GetClaudeSettings().GetBatchDiscountRatio()
+	if info.PriceData.UsePrice {
+		return map[string]float64{
+			"batch_requests": float64(len(req.Requests)),
+			"batch_discount": discount,
+		}
+	}
+	if info.PriceData.FreeModel {
+		return nil
+	}
+	completionRatio := ratio_setting.GetCompletionRatio(info.OriginModelName)
+	tokens := 0.0
+	for _, item := range req.Requests {
+		var params requestParams
+		if err := common.Unmarshal(item.Params, &params); err != nil {
+			continue
+		}
+		input := string(params.System) + string(params.Messages) + string(params.Tools)
+		tokens += float64(service.EstimateTokenByModel(info.OriginModelName, input))
+		tokens += float64(params.MaxTokens) * completionRatio
+	}
+	// 按量计费的基础额度只是模型倍率的一半，这里替换为整批的估算额度
+	quota, _ := common.QuotaFromFloatChecked(tokens * info.PriceData.ModelRatio * info.PriceData.GroupRatioInfo.GroupRatio)
+	info.PriceData.Quota = quota
+	return map[string]float64{
+		"batch_discount": discount,
+	}
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/v1/messages/batches", a.baseURL), nil
+}
+
+// BuildRequestHeader sets required headers.
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	setAnthropicHeaders(req.Header, a.apiKey, c.Request.Header)
+	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// setAnthropicHeaders 设置上游鉴权与版本头，并透传客户端的 anthropic-version / anthropic-beta。
+func setAnthropicHeaders(header http.Header, apiKey string, clientHeader http.Header) {
+	header.Set("x-api-key", apiKey)
+	version := defaultAnthropicVersion
+	if clientHeader != nil {
+		if v := clientHeader.Get("anthropic-version"); v != "" {
+			version = v
+		}
+		if beta := clientHeader.Get("anthropic-beta"); beta != "" {
+			header.Set("anthropic-beta", beta)
+		}
+	}
+	header.Set("anthropic-version", version)
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	req, err := getBatchRequest(c)
+	if err != nil {
+		return nil, err
+	}
+	// Vertex / Bedrock 的批处理以 JSONL 文件作为输入，由 DoRequest 上传到 GCS / S3
+	switch info.ChannelType {
+	case constant.ChannelTypeVertexAi:
+		body, err := buildVertexBatchInput(req)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(body), nil
+	case constant.ChannelTypeAws:
+		body, err := buildBedrockBatchInput(req, c.Request.Header)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(body), nil
+	}
+	upstream := CreateRequest{Requests: make([]RequestItem, len(req.Requests))}
+	for i, item := range req.Requests {
+		params, err := sjson.SetBytes(item.Params, "model", info.UpstreamModelName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "set model for request %s failed", item.CustomID)
+		}
+		upstream.Requests[i] = RequestItem{CustomID: item.CustomID, Params: params}
+	}
+	body, err := common.Marshal(upstream)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal batch request failed")
+	}
+	return bytes.NewReader(body), nil
+}
+
+// DoRequest 在 Anthropic 渠道上直接创建批次，在 Vertex / Bedrock 渠道上创建对应的批处理作业。
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	switch info.ChannelType {
+	case constant.ChannelTypeVertexAi:
+		input, err := io.ReadAll(requestBody)
+		if err != nil {
+			return nil, err
+		}
+		return submitVertexBatch(c, info, input)
+	case constant.ChannelTypeAws:
+		req, err := getBatchRequest(c)
+		if err != nil {
+			return nil, err
+		}
+		input, err := io.ReadAll(requestBody)
+		if err != nil {
+			return nil, err
+		}
+		return submitBedrockBatch(c, info, req, input)
+	}
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+// DoResponse handles upstream response, returns the upstream batch id.
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	_ = resp.Body.Close()
+
+	var batch MessageBatch
+	if err := common.Unmarshal(responseBody, &batch); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrapf(err, "body: %s", responseBody), "unmarshal_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	if batch.ID == "" {
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("batch id is empty"), "invalid_response", http.StatusInternalServerError)
+		return
+	}
+
+	publicBody, err := ToPublicBatch(responseBody, info.PublicTaskID)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "rewrite_response_failed", http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", publicBody)
+	return batch.ID, responseBody, nil
+}
+
+// ToPublicBatch 将上游批次对象中的 id 与 results_url 替换为网关侧的公开地址，
+// 避免把上游 ID 和地址暴露给客户端。
+func ToPublicBatch(data []byte, publicID string) ([]byte, error) {
+	var batch MessageBatch
+	if err := common.Unmarshal(data, &batch); err != nil {
+		return nil, errors.Wrap(err, "unmarshal batch failed")
+	}
+	out, err := sjson.SetBytes(data, "id", publicID)
+	if err != nil {
+		return nil, errors.Wrap(err, "set id failed")
+	}
+	if batch.ResultsURL != nil && *batch.ResultsURL != "" {
+		out, err = sjson.SetBytes(out, "results_url", PublicResultsURL(publicID))
+		if err != nil {
+			return nil, errors.Wrap(err, "set results_url failed")
+		}
+	}
+	return out, nil
+}
+
+// PublicResultsURL 返回网关侧的批次结果下载地址。
+func PublicResultsURL(publicID string) string {
+	return fmt.Sprintf("%s/v1/messages/batches/%s/results", system_setting.ServerAddress, publicID)
+}
+
+// NewUpstreamRequest 构造指向上游批次接口的请求，path 为 /v1/messages/batches 之后的部分。
+func NewUpstreamRequest(method, baseURL, key, upstreamID, path string, clientHeader http.Header) (*http.Request, error) {
+	uri := fmt.Sprintf("%s/v1/messages/batches/%s%s", baseURL, upstreamID, path)
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAnthropicHeaders(req.Header, key, clientHeader)
+	return req, nil
+}
+
+// FetchTask fetch batch status
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	channelType, _ := body["channel_type"].(int)
+	u := &Upstream{ChannelType: channelType, BaseURL: baseUrl, Key: key, Proxy: proxy}
+	return DoUpstreamRequest(context.Background(), u, taskID, UpstreamPathRetrieve, nil)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return nil
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var batch MessageBatch
+	if err := common.Unmarshal(respBody, &batch); err != nil {
+		return nil, errors.Wrap(err, "unmarshal batch failed")
+	}
+
+	taskResult := relaycommon.TaskInfo{
+		Code: 0,
+	}
+	counts := batch.RequestCounts
+	total := counts.Processing + counts.Succeeded + counts.Errored + counts.Canceled + counts.Expired
+
+	switch batch.ProcessingStatus {
+	case ProcessingStatusInProgress, ProcessingStatusCanceling:
+		taskResult.Status = model.TaskStatusInProgress
+		if total > 0 {
+			// 进度上限 99%，100% 会被视为终态
+			progress := min((total-counts.Processing)*100/total, 99)
+			taskResult.Progress = fmt.Sprintf("%d%%", progress)
+		}
+	case ProcessingStatusEnded:
+		if counts.Succeeded > 0 {
+			taskResult.Status = model.TaskStatusSuccess
+		} else {
+			taskResult.Status = model.TaskStatusFailure
+			taskResult.Reason = fmt.Sprintf("no request succeeded (errored: %d, canceled: %d, expired: %d)",
+				counts.Errored, counts.Canceled, counts.Expired)
+		}
+	default:
+	}
+	return &taskResult, nil
+}
+
+// AdjustBillingOnComplete 批次结束后逐行读取结果，按每个成功请求的实际用量
+// 与提交时的倍率快照重新计算额度，并乘以批次折扣。
+func (a *TaskAdaptor) AdjustBillingOnComplete(task *model.Task, taskResult *relaycommon.TaskInfo) int {
+	bc := task.PrivateData.BillingContext
+	if bc == nil || taskResult.Status != model.TaskStatusSuccess {
+		return 0
+	}
+	ch, err := model.CacheGetChannel(task.ChannelId)
+	if err != nil {
+		common.SysError(fmt.Sprintf("claude batch %s: get channel failed: %s", task.TaskID, err.Error()))
+		return 0
+	}
+	resp, err := DoUpstreamRequest(context.Background(), NewUpstream(ch, task), task.GetUpstreamTaskID(), UpstreamPathResults, nil)
+	if err != nil {
+		common.SysError(fmt.Sprintf("claude batch %s: fetch results failed: %s", task.TaskID, err.Error()))
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		common.SysError(fmt.Sprintf("claude batch %s: fetch results returned status %d", task.TaskID, resp.StatusCode))
+		return 0
+	}
+	quota, err := CalculateResultsQuota(resp.Body, bc)
+	if err != nil {
+		common.SysError(fmt.Sprintf("claude batch %s: parse results failed: %s", task.TaskID, err.Error()))
+		return 0
+	}
+	return quota
+}
+
+// SettlesPerCallBilling 按次计费时预扣的是全部请求条数，完成后按成功条数结算
+func (a *TaskAdaptor) SettlesPerCallBilling() bool {
+	return true
+}
+
+// CalculateResultsQuota 汇总 JSONL 结果中所有成功请求的额度。
+// 按次计费：modelPrice × QuotaPerUnit × groupRatio × 成功条数 × batch_discount，与同步中转的按次价格一致；
+// 按量计费：(input + cache_read × cacheRatio + cache_creation × createCacheRatio + output × completionRatio)
+// × modelRatio × groupRatio × batch_discount。
+func CalculateResultsQuota(results io.Reader, bc *model.TaskBillingContext) (int, error) {
+	modelName := bc.OriginModelName
+	completionRatio := ratio_setting.GetCompletionRatio(modelName)
+	cacheRatio, _ := ratio_setting.GetCacheRatio(modelName)
+	createCacheRatio, _ := ratio_setting.GetCreateCacheRatio(modelName)
+	discount, ok := bc.OtherRatios["batch_discount"]
+	if !ok {
+		discount = 1
+	}
+
+	succeeded := 0
+	tokens := 0.0
+	scanner := bufio.NewScanner(results)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var result ResultLine
+		if err := common.Unmarshal(line, &result); err != nil {
+			return 0, err
+		}
+		if result.Result.Type != ResultTypeSucceeded {
+			continue
+		}
+		succeeded++
+		if result.Result.Message == nil || result.Result.Message.Usage == nil {
+			continue
+		}
+		usage := result.Result.Message.Usage
+		tokens += float64(usage.InputTokens) +
+			float64(usage.CacheReadInputTokens)*cacheRatio +
+			float64(usage.CacheCreationInputTokens)*createCacheRatio +
+			float64(usage.OutputTokens)*completionRatio
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	// 按量计费的 ModelPrice 记为 -1
+	if bc.ModelPrice >= 0 {
+		quota, _ := common.QuotaFromFloatChecked(bc.ModelPrice * common.QuotaPerUnit * bc.GroupRatio * float64(succeeded) * discount)
+		return quota, nil
+	}
+	quota, _ := common.QuotaFromFloatChecked(tokens * bc.ModelRatio * bc.GroupRatio * discount)
+	return quota, nil
+}
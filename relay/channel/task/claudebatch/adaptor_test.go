@@ -0,0 +1,124 @@
+package claudebatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseCreateRequestValidatesItems(t *testing.T) {
+	req, err := ParseCreateRequest([]byte(`{"requests":[
+		{"custom_id":"a","params":{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[]}},
+		{"custom_id":"b","params":{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[]}}
+	]}`))
+	require.NoError(t, err)
+	assert.Len(t, req.Requests, 2)
+
+	cases := map[string]string{
+		"empty":          `{"requests":[]}`,
+		"missing id":     `{"requests":[{"params":{"model":"m"}}]}`,
+		"duplicate id":   `{"requests":[{"custom_id":"a","params":{"model":"m"}},{"custom_id":"a","params":{"model":"m"}}]}`,
+		"missing model":  `{"requests":[{"custom_id":"a","params":{}}]}`,
+		"stream":         `{"requests":[{"custom_id":"a","params":{"model":"m","stream":true}}]}`,
+		"mixed models":   `{"requests":[{"custom_id":"a","params":{"model":"m1"}},{"custom_id":"b","params":{"model":"m2"}}]}`,
+		"invalid params": `{"requests":[{"custom_id":"a","params":"x"}]}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCreateRequest([]byte(body))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestToPublicBatchRewritesIDAndResultsURL(t *testing.T) {
+	previous := system_setting.ServerAddress
+	system_setting.ServerAddress = "https://gateway.example.com"
+	t.Cleanup(func() { system_setting.ServerAddress = previous })
+
+	upstream := []byte(`{"id":"msgbatch_upstream","type":"message_batch","processing_status":"ended","results_url":"https://api.anthropic.com/v1/messages/batches/msgbatch_upstream/results"}`)
+	out, err := ToPublicBatch(upstream, "task_public")
+	require.NoError(t, err)
+	assert.Equal(t, "task_public", gjson.GetBytes(out, "id").String())
+	assert.Equal(t, "https://gateway.example.com/v1/messages/batches/task_public/results", gjson.GetBytes(out, "results_url").String())
+	assert.NotContains(t, string(out), "msgbatch_upstream")
+
+	pending := []byte(`{"id":"msgbatch_upstream","processing_status":"in_progress","results_url":null}`)
+	out, err = ToPublicBatch(pending, "task_public")
+	require.NoError(t, err)
+	assert.Equal(t, gjson.Null, gjson.GetBytes(out, "results_url").Type)
+}
+
+func TestParseTaskResultMapsProcessingStatus(t *testing.T) {
+	a := &TaskAdaptor{}
+
+	res, err := a.ParseTaskResult([]byte(`{"processing_status":"in_progress","request_counts":{"processing":3,"succeeded":1}}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusInProgress, res.Status)
+	assert.Equal(t, "25%", res.Progress)
+
+	res, err = a.ParseTaskResult([]byte(`{"processing_status":"ended","request_counts":{"succeeded":1,"errored":2}}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusSuccess, res.Status)
+
+	res, err = a.ParseTaskResult([]byte(`{"processing_status":"ended","request_counts":{"errored":1,"expired":1}}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskStatusFailure, res.Status)
+	assert.Contains(t, res.Reason, "errored: 1")
+}
+
+func TestCalculateResultsQuotaUsesSucceededUsageAndDiscount(t *testing.T) {
+	modelName := "claude-batch-unit-test"
+	results := strings.Join([]string{
+		`{"custom_id":"a","result":{"type":"succeeded","message":{"model":"m","usage":{"input_tokens":100,"output_tokens":10,"cache_read_input_tokens":40,"cache_creation_input_tokens":20}}}}`,
+		`{"custom_id":"b","result":{"type":"errored","error":{"type":"invalid_request_error"}}}`,
+		``,
+		`{"custom_id":"c","result":{"type":"succeeded","message":{"model":"m","usage":{"input_tokens":50,"output_tokens":5}}}}`,
+	}, "\n")
+	bc := &model.TaskBillingContext{
+		ModelPrice:      -1,
+		ModelRatio:      2,
+		GroupRatio:      1.5,
+		OriginModelName: modelName,
+		OtherRatios:     map[string]float64{"batch_discount": 0.5},
+	}
+
+	quota, err := CalculateResultsQuota(strings.NewReader(results), bc)
+	require.NoError(t, err)
+
+	completionRatio := ratio_setting.GetCompletionRatio(modelName)
+	cacheRatio, _ := ratio_setting.GetCacheRatio(modelName)
+	createCacheRatio, _ := ratio_setting.GetCreateCacheRatio(modelName)
+	tokens := 150 + 40*cacheRatio + 20*createCacheRatio + 15*completionRatio
+	expected, _ := common.QuotaFromFloatChecked(tokens * 2 * 1.5 * 0.5)
+	assert.Equal(t, expected, quota)
+}
+
+func TestCalculateResultsQuotaUsesModelPricePerSucceededRequest(t *testing.T) {
+	results := strings.Join([]string{
+		`{"custom_id":"a","result":{"type":"succeeded","message":{"model":"m","usage":{"input_tokens":100,"output_tokens":10}}}}`,
+		`{"custom_id":"b","result":{"type":"errored","error":{"type":"invalid_request_error"}}}`,
+		`{"custom_id":"c","result":{"type":"expired"}}`,
+		`{"custom_id":"d","result":{"type":"succeeded","message":{"model":"m","usage":{"input_tokens":50,"output_tokens":5}}}}`,
+	}, "\n")
+	bc := &model.TaskBillingContext{
+		ModelPrice:      0.02,
+		GroupRatio:      1.5,
+		OriginModelName: "claude-batch-price-test",
+		OtherRatios:     map[string]float64{"batch_requests": 4, "batch_discount": 0.5},
+		PerCallBilling:  true,
+	}
+
+	quota, err := CalculateResultsQuota(strings.NewReader(results), bc)
+	require.NoError(t, err)
+
+	expected, _ := common.QuotaFromFloatChecked(0.02 * common.QuotaPerUnit * 1.5 * 2 * 0.5)
+	assert.Equal(t, expected, quota)
+}
@@ -0,0 +1,539 @@
+package claudebatch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	awschannel "github.com/QuantumNous/new-api/relay/channel/aws"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+// bedrockEndpoint / s3Endpoint 返回 Bedrock 控制面与 S3 存储桶的地址，测试中替换为本地服务
+var (
+	bedrockEndpoint = func(region string) string {
+		return fmt.Sprintf("https://bedrock.%s.amazonaws.com", region)
+	}
+	s3Endpoint = func(bucket, region string) string {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	}
+)
+
+// Bedrock 批量推理作业状态
+const (
+	bedrockJobStatusStopping           = "Stopping"
+	bedrockJobStatusCompleted          = "Completed"
+	bedrockJobStatusPartiallyCompleted = "PartiallyCompleted"
+	bedrockJobStatusFailed             = "Failed"
+	bedrockJobStatusStopped            = "Stopped"
+	bedrockJobStatusExpired            = "Expired"
+)
+
+const (
+	// bedrockCustomIDsObject 保存 recordId 序号到 custom_id 的映射，Bedrock 的 recordId 只能是 11 位字母数字
+	bedrockCustomIDsObject = "custom_ids.json"
+	// bedrockRequestCountMeta 记录批次请求数的 S3 对象元数据，作业运行期间 Bedrock 不返回请求计数
+	bedrockRequestCountMeta = "X-Amz-Meta-Request-Count"
+	bedrockManifestObject   = "manifest.json.out"
+	bedrockJobTimeoutHours  = 24
+)
+
+type bedrockCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+}
+
+// parseBedrockKey 解析 AccessKey|SecretAccessKey|Region 形式的渠道密钥，批量推理作业不支持 API Key
+func parseBedrockKey(key string) (*bedrockCredentials, error) {
+	parts := strings.Split(key, "|")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, errors.New("message batches on Bedrock need an AccessKey|SecretAccessKey|Region key")
+	}
+	return &bedrockCredentials{AccessKeyID: parts[0], SecretAccessKey: parts[1], Region: parts[2]}, nil
+}
+
+// bedrockBatchInputLine 是 Bedrock 批量推理输入中的一行
+type bedrockBatchInputLine struct {
+	RecordID   string          `json:"recordId"`
+	ModelInput json.RawMessage `json:"modelInput"`
+}
+
+// bedrockBatchResultLine 是 Bedrock 批量推理输出中的一行，失败的请求带有 error
+type bedrockBatchResultLine struct {
+	RecordID    string          `json:"recordId"`
+	ModelOutput json.RawMessage `json:"modelOutput"`
+	Error       *struct {
+		ErrorCode    int    `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"error"`
+}
+
+type bedrockManifest struct {
+	TotalRecordCount     int `json:"totalRecordCount"`
+	ProcessedRecordCount int `json:"processedRecordCount"`
+	SuccessRecordCount   int `json:"successRecordCount"`
+	ErrorRecordCount     int `json:"errorRecordCount"`
+}
+
+type bedrockBatchJob struct {
+	JobArn          string `json:"jobArn"`
+	Status          string `json:"status"`
+	Message         string `json:"message"`
+	SubmitTime      string `json:"submitTime"`
+	EndTime         string `json:"endTime"`
+	InputDataConfig struct {
+		S3InputDataConfig struct {
+			S3Uri string `json:"s3Uri"`
+		} `json:"s3InputDataConfig"`
+	} `json:"inputDataConfig"`
+	OutputDataConfig struct {
+		S3OutputDataConfig struct {
+			S3Uri string `json:"s3Uri"`
+		} `json:"s3OutputDataConfig"`
+	} `json:"outputDataConfig"`
+}
+
+func (job *bedrockBatchJob) ended() bool {
+	switch job.Status {
+	case bedrockJobStatusCompleted, bedrockJobStatusPartiallyCompleted, bedrockJobStatusFailed, bedrockJobStatusStopped, bedrockJobStatusExpired:
+		return true
+	}
+	return false
+}
+
+// outputDir 返回作业输出所在的 S3 目录，Bedrock 会在输出位置下按作业 ID 建立子目录
+func (job *bedrockBatchJob) outputDir() string {
+	jobID := job.JobArn[strings.LastIndex(job.JobArn, "/")+1:]
+	return strings.TrimRight(job.OutputDataConfig.S3OutputDataConfig.S3Uri, "/") + "/" + jobID
+}
+
+// toMessageBatch 把 Bedrock 作业转换为 Anthropic 的批次对象。total 为提交的请求数，
+// manifest 为作业结束后的统计，作业失败时可能不存在。
+func (job *bedrockBatchJob) toMessageBatch(total int, manifest *bedrockManifest) *MessageBatch {
+	batch := &MessageBatch{
+		ID:               job.JobArn,
+		Type:             "message_batch",
+		ProcessingStatus: ProcessingStatusInProgress,
+		CreatedAt:        job.SubmitTime,
+	}
+	if !job.ended() {
+		if job.Status == bedrockJobStatusStopping {
+			batch.ProcessingStatus = ProcessingStatusCanceling
+		}
+		batch.RequestCounts.Processing = total
+		return batch
+	}
+	batch.ProcessingStatus = ProcessingStatusEnded
+	if job.EndTime != "" {
+		batch.EndedAt = &job.EndTime
+	}
+	unprocessed := total
+	if manifest != nil {
+		batch.RequestCounts.Succeeded = manifest.SuccessRecordCount
+		batch.RequestCounts.Errored = manifest.ErrorRecordCount
+		unprocessed = max(total-manifest.SuccessRecordCount-manifest.ErrorRecordCount, 0)
+		resultsURL := job.outputDir()
+		batch.ResultsURL = &resultsURL
+	}
+	switch job.Status {
+	case bedrockJobStatusStopped:
+		batch.RequestCounts.Canceled = unprocessed
+	case bedrockJobStatusExpired:
+		batch.RequestCounts.Expired = unprocessed
+	default:
+		batch.RequestCounts.Errored += unprocessed
+	}
+	return batch
+}
+
+// storageDir 返回 s3:// 对象所在的目录。path.Dir 会把 s3:// 折叠为 s3:/，不能直接使用
+func storageDir(uri string) string {
+	return uri[:max(strings.LastIndex(uri, "/"), 0)]
+}
+
+// bedrockRecordID 返回第 i 个请求的 recordId
+func bedrockRecordID(i int) string {
+	return fmt.Sprintf("R%010d", i)
+}
+
+// buildBedrockBatchInput 生成 Bedrock 批量推理的 JSONL 输入：请求体去掉 model 并带上 anthropic_version，
+// 客户端的 anthropic-beta 写入请求体
+func buildBedrockBatchInput(req *CreateRequest, clientHeader http.Header) ([]byte, error) {
+	var betas []string
+	if clientHeader != nil {
+		for _, beta := range strings.Split(clientHeader.Get("anthropic-beta"), ",") {
+			if beta = strings.TrimSpace(beta); beta != "" {
+				betas = append(betas, beta)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	for i, item := range req.Requests {
+		params, err := sjson.DeleteBytes(item.Params, "model")
+		if err != nil {
+			return nil, errors.Wrapf(err, "remove model for request %s failed", item.CustomID)
+		}
+		params, err = sjson.SetBytes(params, "anthropic_version", awschannel.AnthropicVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "set anthropic_version for request %s failed", item.CustomID)
+		}
+		if len(betas) > 0 {
+			params, err = sjson.SetBytes(params, "anthropic_beta", betas)
+			if err != nil {
+				return nil, errors.Wrapf(err, "set anthropic_beta for request %s failed", item.CustomID)
+			}
+		}
+		line, err := common.Marshal(bedrockBatchInputLine{RecordID: bedrockRecordID(i), ModelInput: params})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// bedrockClient 以 SigV4 签名请求 Bedrock 控制面与 S3
+type bedrockClient struct {
+	client *http.Client
+	creds  *bedrockCredentials
+}
+
+func (b *bedrockClient) do(ctx context.Context, signingName, method, uri string, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		// S3 的对象路径只编码一次，其余服务按 SigV4 规范再编码一次
+		o.DisableURIPathEscaping = signingName == "s3"
+	})
+	credentials := aws.Credentials{AccessKeyID: b.creds.AccessKeyID, SecretAccessKey: b.creds.SecretAccessKey}
+	if err := signer.SignHTTP(ctx, credentials, req, payloadHash, signingName, b.creds.Region, time.Now()); err != nil {
+		return nil, errors.Wrap(err, "sign aws request failed")
+	}
+	return b.client.Do(req)
+}
+
+func (b *bedrockClient) objectURL(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return s3Endpoint(bucket, b.creds.Region) + "/" + strings.Join(segments, "/")
+}
+
+func (b *bedrockClient) putObject(ctx context.Context, bucket, key string, data []byte, header http.Header) error {
+	resp, err := b.do(ctx, "s3", http.MethodPut, b.objectURL(bucket, key), header, data)
+	if err != nil {
+		return err
+	}
+	if err := readAwsResponse(resp, nil); err != nil {
+		return fmt.Errorf("upload s3://%s/%s failed: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// getObject 读取 S3 对象，对象不存在时返回 nil
+func (b *bedrockClient) getObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, "s3", http.MethodGet, b.objectURL(bucket, key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		_ = resp.Body.Close()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("read s3://%s/%s failed: %w", bucket, key, readAwsResponse(resp, nil))
+}
+
+func (b *bedrockClient) readObjectJSON(ctx context.Context, uri string, v any) (bool, error) {
+	bucket, key, err := parseStorageURI(uri, "s3")
+	if err != nil {
+		return false, err
+	}
+	body, err := b.getObject(ctx, bucket, key)
+	if err != nil || body == nil {
+		return false, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return false, err
+	}
+	return true, common.Unmarshal(data, v)
+}
+
+// requestCount 读取提交时写入 custom_ids.json 元数据中的请求数
+func (b *bedrockClient) requestCount(ctx context.Context, inputDir string) (int, error) {
+	bucket, prefix, err := parseStorageURI(inputDir, "s3")
+	if err != nil {
+		return 0, err
+	}
+	key := path.Join(prefix, bedrockCustomIDsObject)
+	resp, err := b.do(ctx, "s3", http.MethodHead, b.objectURL(bucket, key), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("head s3://%s/%s returned status %d", bucket, key, resp.StatusCode)
+	}
+	return strconv.Atoi(resp.Header.Get(bedrockRequestCountMeta))
+}
+
+// readAwsResponse 读取成功响应的 JSON，非 2xx 时返回包含响应内容的错误
+func readAwsResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	if v == nil {
+		return nil
+	}
+	return common.Unmarshal(body, v)
+}
+
+func (b *bedrockClient) jobURL(jobArn string) string {
+	return bedrockEndpoint(b.creds.Region) + "/model-invocation-job/" + url.PathEscape(jobArn)
+}
+
+// getJob 查询作业，上游返回非 200 时原样返回响应
+func (b *bedrockClient) getJob(ctx context.Context, jobArn string) (*bedrockBatchJob, *http.Response, error) {
+	resp, err := b.do(ctx, "bedrock", http.MethodGet, b.jobURL(jobArn), nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp, nil
+	}
+	var job bedrockBatchJob
+	if err := readAwsResponse(resp, &job); err != nil {
+		return nil, nil, err
+	}
+	if job.JobArn == "" {
+		job.JobArn = jobArn
+	}
+	return &job, nil, nil
+}
+
+// messageBatch 汇总作业状态与请求计数
+func (b *bedrockClient) messageBatch(ctx context.Context, job *bedrockBatchJob) (*MessageBatch, error) {
+	total, err := b.requestCount(ctx, storageDir(job.InputDataConfig.S3InputDataConfig.S3Uri))
+	if err != nil {
+		return nil, err
+	}
+	if !job.ended() {
+		return job.toMessageBatch(total, nil), nil
+	}
+	var manifest bedrockManifest
+	found, err := b.readObjectJSON(ctx, job.outputDir()+"/"+bedrockManifestObject, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if job.Status != bedrockJobStatusFailed {
+			return nil, fmt.Errorf("bedrock batch job %s has no manifest", job.JobArn)
+		}
+		return job.toMessageBatch(total, nil), nil
+	}
+	return job.toMessageBatch(total, &manifest), nil
+}
+
+// submitBedrockBatch 把批次输入与 custom_id 映射写入 S3，再创建 Bedrock 批量推理作业
+func submitBedrockBatch(c *gin.Context, info *relaycommon.RelayInfo, req *CreateRequest, input []byte) (*http.Response, error) {
+	ctx := c.Request.Context()
+	creds, err := parseBedrockKey(info.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	bucket, prefix, err := parseStorageURI(info.ChannelOtherSettings.BatchStorageURI, "s3")
+	if err != nil {
+		return nil, err
+	}
+	client, err := service.GetHttpClientWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	b := &bedrockClient{client: client, creds: creds}
+
+	customIDs := make([]string, len(req.Requests))
+	for i, item := range req.Requests {
+		customIDs[i] = item.CustomID
+	}
+	ids, err := common.Marshal(customIDs)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Join(prefix, info.PublicTaskID)
+	meta := http.Header{bedrockRequestCountMeta: []string{strconv.Itoa(len(customIDs))}}
+	if err := b.putObject(ctx, bucket, path.Join(dir, bedrockCustomIDsObject), ids, meta); err != nil {
+		return nil, err
+	}
+	inputObject := path.Join(dir, batchInputObject)
+	if err := b.putObject(ctx, bucket, inputObject, input, nil); err != nil {
+		return nil, err
+	}
+
+	job := map[string]any{
+		"jobName": strings.ReplaceAll(info.PublicTaskID, "_", "-"),
+		"roleArn": strings.TrimSpace(info.ChannelOtherSettings.BatchRoleArn),
+		"modelId": awschannel.ResolveModelID(info.UpstreamModelName, creds.Region),
+		"inputDataConfig": map[string]any{
+			"s3InputDataConfig": map[string]any{
+				"s3Uri":         fmt.Sprintf("s3://%s/%s", bucket, inputObject),
+				"s3InputFormat": "JSONL",
+			},
+		},
+		"outputDataConfig": map[string]any{
+			"s3OutputDataConfig": map[string]any{
+				"s3Uri": fmt.Sprintf("s3://%s/%s/", bucket, path.Join(dir, batchOutputDir)),
+			},
+		},
+		"timeoutDurationInHours": bedrockJobTimeoutHours,
+	}
+	body, err := common.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	resp, err := b.do(ctx, "bedrock", http.MethodPost, bedrockEndpoint(creds.Region)+"/model-invocation-job", header, body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	var created struct {
+		JobArn string `json:"jobArn"`
+	}
+	if err := readAwsResponse(resp, &created); err != nil {
+		return nil, err
+	}
+	if created.JobArn == "" {
+		return nil, fmt.Errorf("bedrock batch job arn is empty")
+	}
+	return newBatchResponse(&MessageBatch{
+		ID:               created.JobArn,
+		Type:             "message_batch",
+		ProcessingStatus: ProcessingStatusInProgress,
+		RequestCounts:    RequestCounts{Processing: len(customIDs)},
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// doBedrockBatchRequest 查询、停止 Bedrock 批量推理作业，或读取其 S3 输出
+func doBedrockBatchRequest(ctx context.Context, client *http.Client, u *Upstream, jobArn, action string) (*http.Response, error) {
+	creds, err := parseBedrockKey(u.Key)
+	if err != nil {
+		return nil, err
+	}
+	b := &bedrockClient{client: client, creds: creds}
+	if action == UpstreamPathCancel {
+		resp, err := b.do(ctx, "bedrock", http.MethodPost, b.jobURL(jobArn)+"/stop", nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+	}
+	job, errResp, err := b.getJob(ctx, jobArn)
+	if err != nil || errResp != nil {
+		return errResp, err
+	}
+	if action == UpstreamPathResults {
+		return b.results(ctx, job)
+	}
+	batch, err := b.messageBatch(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return newBatchResponse(batch)
+}
+
+// results 按 custom_id 映射转换作业输出文件中的每一行
+func (b *bedrockClient) results(ctx context.Context, job *bedrockBatchJob) (*http.Response, error) {
+	if !job.ended() {
+		return nil, fmt.Errorf("bedrock batch job %s has not ended yet", job.JobArn)
+	}
+	inputURI := job.InputDataConfig.S3InputDataConfig.S3Uri
+	var customIDs []string
+	found, err := b.readObjectJSON(ctx, storageDir(inputURI)+"/"+bedrockCustomIDsObject, &customIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("custom ids of bedrock batch job %s not found", job.JobArn)
+	}
+	bucket, key, err := parseStorageURI(job.outputDir()+"/"+path.Base(inputURI)+".out", "s3")
+	if err != nil {
+		return nil, err
+	}
+	body, err := b.getObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("output of bedrock batch job %s not found", job.JobArn)
+	}
+	return newResultsResponse(func(w io.Writer) error {
+		defer body.Close()
+		return convertResultLines(body, w, func(line []byte) ([]byte, error) {
+			return convertBedrockResultLine(line, customIDs)
+		})
+	}), nil
+}
+
+func convertBedrockResultLine(line []byte, customIDs []string) ([]byte, error) {
+	var result bedrockBatchResultLine
+	if err := common.Unmarshal(line, &result); err != nil {
+		return nil, err
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(result.RecordID, "R"))
+	if err != nil || index < 0 || index >= len(customIDs) {
+		return nil, fmt.Errorf("unknown bedrock record id %q", result.RecordID)
+	}
+	customID := customIDs[index]
+	if result.Error == nil && common.GetJsonType(result.ModelOutput) == "object" {
+		return newSucceededResultLine(customID, result.ModelOutput)
+	}
+	message := "no output was returned for this request"
+	if result.Error != nil && result.Error.ErrorMessage != "" {
+		message = result.Error.ErrorMessage
+	}
+	return newErroredResultLine(customID, message)
+}
@@ -0,0 +1,82 @@
+package claudebatch
+
+import (
+	"encoding/json"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+)
+
+const (
+	ChannelName = "claude-message-batches"
+
+	// MaxBatchRequests mirrors Anthropic's per-batch request limit.
+	MaxBatchRequests = 100000
+
+	ProcessingStatusInProgress = "in_progress"
+	ProcessingStatusCanceling  = "canceling"
+	ProcessingStatusEnded      = "ended"
+
+	ResultTypeSucceeded = "succeeded"
+	ResultTypeErrored   = "errored"
+	ResultTypeCanceled  = "canceled"
+	ResultTypeExpired   = "expired"
+)
+
+// CreateRequest is the body of POST /v1/messages/batches.
+type CreateRequest struct {
+	Requests []RequestItem `json:"requests"`
+}
+
+type RequestItem struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// requestParams holds the fields of a batched Messages request that the
+// gateway inspects for validation and pre-charge estimation.
+type requestParams struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	System    json.RawMessage `json:"system,omitempty"`
+	Messages  json.RawMessage `json:"messages"`
+	Tools     json.RawMessage `json:"tools,omitempty"`
+	Stream    bool            `json:"stream,omitempty"`
+}
+
+// MessageBatch is the Anthropic message batch object.
+type MessageBatch struct {
+	ID                string        `json:"id"`
+	Type              string        `json:"type"`
+	ProcessingStatus  string        `json:"processing_status"`
+	RequestCounts     RequestCounts `json:"request_counts"`
+	EndedAt           *string       `json:"ended_at"`
+	CreatedAt         string        `json:"created_at"`
+	ExpiresAt         string        `json:"expires_at"`
+	ArchivedAt        *string       `json:"archived_at"`
+	CancelInitiatedAt *string       `json:"cancel_initiated_at"`
+	ResultsURL        *string       `json:"results_url"`
+}
+
+type RequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// ResultLine is one line of the JSONL results file.
+type ResultLine struct {
+	CustomID string       `json:"custom_id"`
+	Result   ResultDetail `json:"result"`
+}
+
+type ResultDetail struct {
+	Type    string         `json:"type"`
+	Message *ResultMessage `json:"message,omitempty"`
+}
+
+type ResultMessage struct {
+	Model string           `json:"model"`
+	Usage *dto.ClaudeUsage `json:"usage,omitempty"`
+}
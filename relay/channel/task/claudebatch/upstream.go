@@ -0,0 +1,220 @@
+package claudebatch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	vertexcore "github.com/QuantumNous/new-api/relay/channel/vertex"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// 上游批次接口在 /v1/messages/batches/:id 之后的路径
+const (
+	UpstreamPathRetrieve = ""
+	UpstreamPathCancel   = "/cancel"
+	UpstreamPathResults  = "/results"
+)
+
+// batchInputObject / batchOutputDir 是单个批次在 GCS / S3 中的输入文件名与输出目录名
+const (
+	batchInputObject = "input.jsonl"
+	batchOutputDir   = "output"
+)
+
+// Upstream 是批次所在渠道的连接信息。批次提交后，查询、取消与读取结果都固定使用提交时的渠道与 key。
+type Upstream struct {
+	ChannelType int
+	BaseURL     string
+	Key         string
+	Proxy       string
+}
+
+// NewUpstream 按任务记录的渠道与 key 构造上游连接信息
+func NewUpstream(ch *model.Channel, task *model.Task) *Upstream {
+	baseURL := ch.GetBaseURL()
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[ch.Type]
+	}
+	key := ch.Key
+	if task.PrivateData.Key != "" {
+		key = task.PrivateData.Key
+	}
+	return &Upstream{
+		ChannelType: ch.Type,
+		BaseURL:     baseURL,
+		Key:         key,
+		Proxy:       ch.GetSetting().Proxy,
+	}
+}
+
+// DoUpstreamRequest 请求上游批次的查询、取消或结果接口。Vertex 批量预测作业与 Bedrock 批量推理作业
+// 会被转换为 Anthropic 的批次对象与 JSONL 结果，调用方无需区分渠道类型。
+func DoUpstreamRequest(ctx context.Context, u *Upstream, upstreamID, path string, clientHeader http.Header) (*http.Response, error) {
+	client, err := service.GetHttpClientWithProxy(u.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	switch u.ChannelType {
+	case constant.ChannelTypeVertexAi:
+		return doVertexBatchRequest(ctx, client, u, upstreamID, path)
+	case constant.ChannelTypeAws:
+		return doBedrockBatchRequest(ctx, client, u, upstreamID, path)
+	}
+	method := http.MethodGet
+	if path == UpstreamPathCancel {
+		method = http.MethodPost
+	}
+	req, err := NewUpstreamRequest(method, u.BaseURL, u.Key, upstreamID, path, clientHeader)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req.WithContext(ctx))
+}
+
+// validateBatchChannel 检查选中的渠道能否承载消息批处理。
+// Vertex / Bedrock 的批处理以 GCS / S3 中的 JSONL 文件作为输入输出，需要在渠道设置中配置存储位置。
+func validateBatchChannel(info *relaycommon.RelayInfo) error {
+	settings := info.ChannelOtherSettings
+	switch info.ChannelType {
+	case constant.ChannelTypeAnthropic:
+		return nil
+	case constant.ChannelTypeVertexAi:
+		if settings.VertexKeyType == dto.VertexKeyTypeAPIKey {
+			return errors.New("message batches on Vertex AI need a service account JSON key")
+		}
+		if _, _, err := parseStorageURI(settings.BatchStorageURI, "gs"); err != nil {
+			return err
+		}
+		if region := vertexcore.GetModelRegion(info.ApiVersion, info.OriginModelName); region == "" || region == "global" {
+			return fmt.Errorf("message batches on Vertex AI need a regional location for model %s", info.OriginModelName)
+		}
+		return nil
+	case constant.ChannelTypeAws:
+		if settings.AwsKeyType == dto.AwsKeyTypeApiKey {
+			return errors.New("message batches on Bedrock need an AccessKey|SecretAccessKey|Region key")
+		}
+		if _, err := parseBedrockKey(info.ApiKey); err != nil {
+			return err
+		}
+		if _, _, err := parseStorageURI(settings.BatchStorageURI, "s3"); err != nil {
+			return err
+		}
+		if strings.TrimSpace(settings.BatchRoleArn) == "" {
+			return errors.New("message batches on Bedrock need batch_role_arn in the channel settings")
+		}
+		return nil
+	}
+	return errors.New("message batches are only supported on Anthropic, Vertex AI and AWS Bedrock channels")
+}
+
+// parseStorageURI 解析 gs://bucket/prefix 或 s3://bucket/prefix 形式的存储位置
+func parseStorageURI(uri, scheme string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(uri), scheme+"://")
+	if ok {
+		bucket, prefix, _ = strings.Cut(rest, "/")
+	}
+	if bucket == "" {
+		return "", "", fmt.Errorf("message batches need batch_storage_uri set to a %s://bucket/prefix location in the channel settings", scheme)
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// newBatchResponse 把转换得到的批次对象包装为上游响应，供提交、轮询与查询接口统一处理
+func newBatchResponse(batch *MessageBatch) (*http.Response, error) {
+	body, err := common.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// newResultsResponse 以流的方式返回 write 写出的 JSONL 结果，写出失败时读取方会收到该错误
+func newResultsResponse(write func(w io.Writer) error) *http.Response {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/x-jsonl"}},
+		Body:       pr,
+	}
+}
+
+// convertResultLines 逐行读取上游输出文件，转换为 Anthropic 结果行后写出
+func convertResultLines(r io.Reader, w io.Writer, convert func(line []byte) ([]byte, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		converted, err := convert(line)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(converted, '\n')); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// convertedResultLine 是由 Vertex / Bedrock 输出转换得到的 Anthropic 结果行
+type convertedResultLine struct {
+	CustomID string                `json:"custom_id"`
+	Result   convertedResultDetail `json:"result"`
+}
+
+type convertedResultDetail struct {
+	Type    string          `json:"type"`
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   *resultError    `json:"error,omitempty"`
+}
+
+type resultError struct {
+	Type  string            `json:"type"`
+	Error resultErrorDetail `json:"error"`
+}
+
+type resultErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func newSucceededResultLine(customID string, message json.RawMessage) ([]byte, error) {
+	return common.Marshal(convertedResultLine{
+		CustomID: customID,
+		Result:   convertedResultDetail{Type: ResultTypeSucceeded, Message: message},
+	})
+}
+
+func newErroredResultLine(customID, message string) ([]byte, error) {
+	return common.Marshal(convertedResultLine{
+		CustomID: customID,
+		Result: convertedResultDetail{
+			Type: ResultTypeErrored,
+			Error: &resultError{
+				Type:  "error",
+				Error: resultErrorDetail{Type: "api_error", Message: message},
+			},
+		},
+	})
+}
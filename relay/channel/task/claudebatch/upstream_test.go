@@ -0,0 +1,290 @@
+package claudebatch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+const testBatchRequest = `{"requests":[
+	{"custom_id":"first","params":{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}},
+	{"custom_id":"second","params":{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"yo"}]}}
+]}`
+
+func TestValidateBatchChannel(t *testing.T) {
+	newInfo := func(channelType int, key string, settings dto.ChannelOtherSettings) *relaycommon.RelayInfo {
+		return &relaycommon.RelayInfo{
+			OriginModelName: "claude-sonnet-4-5",
+			ChannelMeta: &relaycommon.ChannelMeta{
+				ChannelType:          channelType,
+				ApiKey:               key,
+				ApiVersion:           "us-east5",
+				ChannelOtherSettings: settings,
+			},
+		}
+	}
+
+	assert.NoError(t, validateBatchChannel(newInfo(constant.ChannelTypeAnthropic, "sk", dto.ChannelOtherSettings{})))
+	assert.Error(t, validateBatchChannel(newInfo(constant.ChannelTypeOpenAI, "sk", dto.ChannelOtherSettings{})))
+
+	vertex := dto.ChannelOtherSettings{BatchStorageURI: "gs://bucket/batches"}
+	assert.NoError(t, validateBatchChannel(newInfo(constant.ChannelTypeVertexAi, "{}", vertex)))
+	assert.Error(t, validateBatchChannel(newInfo(constant.ChannelTypeVertexAi, "{}", dto.ChannelOtherSettings{BatchStorageURI: "s3://bucket"})))
+	apiKey := vertex
+	apiKey.VertexKeyType = dto.VertexKeyTypeAPIKey
+	assert.Error(t, validateBatchChannel(newInfo(constant.ChannelTypeVertexAi, "key", apiKey)))
+	global := newInfo(constant.ChannelTypeVertexAi, "{}", vertex)
+	global.ApiVersion = "global"
+	assert.Error(t, validateBatchChannel(global))
+
+	bedrock := dto.ChannelOtherSettings{BatchStorageURI: "s3://bucket/batches", BatchRoleArn: "arn:aws:iam::1:role/batch"}
+	assert.NoError(t, validateBatchChannel(newInfo(constant.ChannelTypeAws, "ak|sk|us-east-1", bedrock)))
+	assert.Error(t, validateBatchChannel(newInfo(constant.ChannelTypeAws, "key|us-east-1", bedrock)))
+	assert.Error(t, validateBatchChannel(newInfo(constant.ChannelTypeAws, "ak|sk|us-east-1", dto.ChannelOtherSettings{BatchStorageURI: "s3://bucket"})))
+}
+
+func TestBuildVertexBatchInput(t *testing.T) {
+	req, err := ParseCreateRequest([]byte(testBatchRequest))
+	require.NoError(t, err)
+	input, err := buildVertexBatchInput(req)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(input)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "first", gjson.Get(lines[0], "custom_id").String())
+	assert.Equal(t, "vertex-2023-10-16", gjson.Get(lines[0], "request.anthropic_version").String())
+	assert.False(t, gjson.Get(lines[0], "request.model").Exists())
+	assert.Equal(t, "hi", gjson.Get(lines[0], "request.messages.0.content").String())
+}
+
+func TestVertexBatchJobToMessageBatch(t *testing.T) {
+	running := &vertexBatchJob{Name: "projects/p/locations/us-east5/batchPredictionJobs/1", State: "JOB_STATE_RUNNING"}
+	batch := running.toMessageBatch()
+	assert.Equal(t, ProcessingStatusInProgress, batch.ProcessingStatus)
+	assert.Nil(t, batch.ResultsURL)
+
+	var cancelled vertexBatchJob
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name":"projects/p/locations/us-east5/batchPredictionJobs/1",
+		"state":"JOB_STATE_CANCELLED",
+		"endTime":"2026-01-01T00:00:00Z",
+		"completionStats":{"successfulCount":"3","failedCount":"1","incompleteCount":"2"},
+		"outputInfo":{"gcsOutputDirectory":"gs://bucket/batches/task/output/prediction-1"}
+	}`), &cancelled))
+	batch = cancelled.toMessageBatch()
+	assert.Equal(t, ProcessingStatusEnded, batch.ProcessingStatus)
+	assert.Equal(t, RequestCounts{Succeeded: 3, Errored: 1, Canceled: 2}, batch.RequestCounts)
+	require.NotNil(t, batch.ResultsURL)
+	require.NotNil(t, batch.EndedAt)
+}
+
+func TestVertexBatchResultsConvertsOutputFiles(t *testing.T) {
+	objects := map[string]string{
+		"batches/task/output/prediction-1/000000000000.jsonl": `{"custom_id":"first","request":{},"response":{"id":"msg_1","type":"message","usage":{"input_tokens":3,"output_tokens":5}},"status":""}` + "\n",
+		"batches/task/output/prediction-1/000000000001.jsonl": `{"custom_id":"second","request":{},"response":null,"status":"Bad Request: invalid max_tokens"}` + "\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/projects/"):
+			_, _ = io.WriteString(w, `{"name":"projects/p/locations/us-east5/batchPredictionJobs/1","state":"JOB_STATE_SUCCEEDED",
+				"outputInfo":{"gcsOutputDirectory":"gs://bucket/batches/task/output/prediction-1"}}`)
+		case r.URL.Path == "/storage/v1/b/bucket/o":
+			assert.Equal(t, "batches/task/output/prediction-1/", r.URL.Query().Get("prefix"))
+			_, _ = io.WriteString(w, `{"items":[
+				{"name":"batches/task/output/prediction-1/000000000001.jsonl"},
+				{"name":"batches/task/output/prediction-1/000000000000.jsonl"}
+			]}`)
+		case strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"):
+			object := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/")
+			_, _ = io.WriteString(w, objects[object])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	previous := gcsBaseURL
+	gcsBaseURL = server.URL
+	t.Cleanup(func() { gcsBaseURL = previous })
+
+	jobURL, err := vertexJobURL(server.URL, "projects/p/locations/us-east5/batchPredictionJobs/1")
+	require.NoError(t, err)
+	resp, err := vertexBatchResults(context.Background(), server.Client(), "token", jobURL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "first", gjson.Get(lines[0], "custom_id").String())
+	assert.Equal(t, ResultTypeSucceeded, gjson.Get(lines[0], "result.type").String())
+	assert.Equal(t, int64(5), gjson.Get(lines[0], "result.message.usage.output_tokens").Int())
+	assert.Equal(t, "second", gjson.Get(lines[1], "custom_id").String())
+	assert.Equal(t, ResultTypeErrored, gjson.Get(lines[1], "result.type").String())
+	assert.Equal(t, "Bad Request: invalid max_tokens", gjson.Get(lines[1], "result.error.error.message").String())
+}
+
+// fakeBedrock 模拟 Bedrock 批量推理控制面与 S3
+type fakeBedrock struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	meta    map[string]string
+	job     map[string]any
+	stopped bool
+}
+
+func newFakeBedrock(t *testing.T) *fakeBedrock {
+	f := &fakeBedrock{objects: map[string][]byte{}, meta: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(f.serve(t)))
+	t.Cleanup(server.Close)
+	previousBedrock, previousS3 := bedrockEndpoint, s3Endpoint
+	bedrockEndpoint = func(region string) string { return server.URL + "/bedrock" }
+	s3Endpoint = func(bucket, region string) string { return server.URL + "/s3/" + bucket }
+	t.Cleanup(func() { bedrockEndpoint, s3Endpoint = previousBedrock, previousS3 })
+	return f
+}
+
+func (f *fakeBedrock) serve(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		assert.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=ak/")
+		assert.NotEmpty(t, r.Header.Get("X-Amz-Content-Sha256"))
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/s3/"):
+			key := strings.TrimPrefix(r.URL.Path, "/s3/")
+			switch r.Method {
+			case http.MethodPut:
+				f.objects[key] = body
+				f.meta[key] = r.Header.Get(bedrockRequestCountMeta)
+			case http.MethodHead, http.MethodGet:
+				data, ok := f.objects[key]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set(bedrockRequestCountMeta, f.meta[key])
+				if r.Method == http.MethodGet {
+					_, _ = w.Write(data)
+				}
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/bedrock/model-invocation-job":
+			require.NoError(t, json.Unmarshal(body, &f.job))
+			_, _ = io.WriteString(w, `{"jobArn":"arn:aws:bedrock:us-east-1:1:model-invocation-job/job1"}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/stop"):
+			f.stopped = true
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/bedrock/model-invocation-job/"):
+			status := "InProgress"
+			if f.stopped {
+				status = "Stopped"
+			}
+			job := map[string]any{
+				"jobArn":           "arn:aws:bedrock:us-east-1:1:model-invocation-job/job1",
+				"status":           status,
+				"submitTime":       "2026-01-01T00:00:00Z",
+				"inputDataConfig":  f.job["inputDataConfig"],
+				"outputDataConfig": f.job["outputDataConfig"],
+			}
+			if f.stopped {
+				job["endTime"] = "2026-01-01T01:00:00Z"
+			}
+			data, _ := json.Marshal(job)
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestBedrockBatchSubmitPollAndResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f := newFakeBedrock(t)
+
+	req, err := ParseCreateRequest([]byte(testBatchRequest))
+	require.NoError(t, err)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages/batches", nil)
+	c.Request.Header.Set("anthropic-beta", "context-1m-2025-08-07")
+	info := &relaycommon.RelayInfo{
+		TaskRelayInfo: &relaycommon.TaskRelayInfo{PublicTaskID: "task_abc"},
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelType:       constant.ChannelTypeAws,
+			ApiKey:            "ak|sk|us-east-1",
+			UpstreamModelName: "claude-sonnet-4-5",
+			ChannelOtherSettings: dto.ChannelOtherSettings{
+				BatchStorageURI: "s3://bucket/batches/",
+				BatchRoleArn:    "arn:aws:iam::1:role/batch",
+			},
+		},
+	}
+	input, err := buildBedrockBatchInput(req, c.Request.Header)
+	require.NoError(t, err)
+	resp, err := submitBedrockBatch(c, info, req, input)
+	require.NoError(t, err)
+	var created MessageBatch
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "arn:aws:bedrock:us-east-1:1:model-invocation-job/job1", created.ID)
+	assert.Equal(t, 2, created.RequestCounts.Processing)
+
+	assert.Equal(t, "task-abc", f.job["jobName"])
+	assert.Equal(t, "s3://bucket/batches/task_abc/input.jsonl", gjson.GetBytes(mustJSON(t, f.job), "inputDataConfig.s3InputDataConfig.s3Uri").String())
+	assert.JSONEq(t, `["first","second"]`, string(f.objects["bucket/batches/task_abc/custom_ids.json"]))
+	lines := strings.Split(strings.TrimSpace(string(f.objects["bucket/batches/task_abc/input.jsonl"])), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "R0000000001", gjson.Get(lines[1], "recordId").String())
+	assert.Equal(t, "bedrock-2023-05-31", gjson.Get(lines[1], "modelInput.anthropic_version").String())
+	assert.Equal(t, "context-1m-2025-08-07", gjson.Get(lines[1], "modelInput.anthropic_beta.0").String())
+	assert.False(t, gjson.Get(lines[1], "modelInput.model").Exists())
+
+	u := &Upstream{ChannelType: constant.ChannelTypeAws, Key: "ak|sk|us-east-1"}
+	a := &TaskAdaptor{}
+	resp, err = DoUpstreamRequest(context.Background(), u, created.ID, UpstreamPathRetrieve, nil)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	taskInfo, err := a.ParseTaskResult(body)
+	require.NoError(t, err)
+	assert.Equal(t, "0%", taskInfo.Progress)
+
+	// 停止后一条成功、一条失败，其余视为取消
+	f.objects["bucket/batches/task_abc/output/job1/manifest.json.out"] = []byte(`{"totalRecordCount":2,"processedRecordCount":1,"successRecordCount":1,"errorRecordCount":0}`)
+	f.objects["bucket/batches/task_abc/output/job1/input.jsonl.out"] = []byte(
+		`{"recordId":"R0000000000","modelInput":{},"modelOutput":{"id":"msg_1","type":"message","usage":{"input_tokens":3,"output_tokens":5}}}` + "\n" +
+			`{"recordId":"R0000000001","modelInput":{},"error":{"errorCode":400,"errorMessage":"max_tokens is too large"}}` + "\n")
+	resp, err = DoUpstreamRequest(context.Background(), u, created.ID, UpstreamPathCancel, nil)
+	require.NoError(t, err)
+	var stopped MessageBatch
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stopped))
+	assert.Equal(t, ProcessingStatusEnded, stopped.ProcessingStatus)
+	assert.Equal(t, RequestCounts{Succeeded: 1, Canceled: 1}, stopped.RequestCounts)
+
+	resp, err = DoUpstreamRequest(context.Background(), u, created.ID, UpstreamPathResults, nil)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	results := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, results, 2)
+	assert.Equal(t, "first", gjson.Get(results[0], "custom_id").String())
+	assert.Equal(t, ResultTypeSucceeded, gjson.Get(results[0], "result.type").String())
+	assert.Equal(t, "second", gjson.Get(results[1], "custom_id").String())
+	assert.Equal(t, "max_tokens is too large", gjson.Get(results[1], "result.error.error.message").String())
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,388 @@
+package claudebatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	vertexcore "github.com/QuantumNous/new-api/relay/channel/vertex"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+// gcsBaseURL 是 Cloud Storage JSON API 的地址，测试中替换为本地服务
+var gcsBaseURL = "https://storage.googleapis.com"
+
+// Vertex 批量预测作业状态
+const (
+	vertexJobStateSucceeded          = "JOB_STATE_SUCCEEDED"
+	vertexJobStatePartiallySucceeded = "JOB_STATE_PARTIALLY_SUCCEEDED"
+	vertexJobStateFailed             = "JOB_STATE_FAILED"
+	vertexJobStateCancelling         = "JOB_STATE_CANCELLING"
+	vertexJobStateCancelled          = "JOB_STATE_CANCELLED"
+	vertexJobStateExpired            = "JOB_STATE_EXPIRED"
+)
+
+// vertexBatchInputLine 是 Vertex 批量预测输入中的一行
+type vertexBatchInputLine struct {
+	CustomID string          `json:"custom_id"`
+	Request  json.RawMessage `json:"request"`
+}
+
+// vertexBatchResultLine 是 Vertex 批量预测输出中的一行，失败的请求没有 response，错误信息在 status 中
+type vertexBatchResultLine struct {
+	CustomID string          `json:"custom_id"`
+	Response json.RawMessage `json:"response"`
+	Status   string          `json:"status"`
+}
+
+type vertexBatchJob struct {
+	Name            string `json:"name"`
+	State           string `json:"state"`
+	CreateTime      string `json:"createTime"`
+	EndTime         string `json:"endTime"`
+	CompletionStats *struct {
+		SuccessfulCount int64 `json:"successfulCount,string"`
+		FailedCount     int64 `json:"failedCount,string"`
+		IncompleteCount int64 `json:"incompleteCount,string"`
+	} `json:"completionStats,omitempty"`
+	OutputInfo *struct {
+		GcsOutputDirectory string `json:"gcsOutputDirectory"`
+	} `json:"outputInfo,omitempty"`
+}
+
+// toMessageBatch 把 Vertex 作业转换为 Anthropic 的批次对象
+func (job *vertexBatchJob) toMessageBatch() *MessageBatch {
+	batch := &MessageBatch{
+		ID:               job.Name,
+		Type:             "message_batch",
+		ProcessingStatus: ProcessingStatusInProgress,
+		CreatedAt:        job.CreateTime,
+	}
+	var incomplete int
+	if stats := job.CompletionStats; stats != nil {
+		batch.RequestCounts.Succeeded = int(stats.SuccessfulCount)
+		batch.RequestCounts.Errored = int(stats.FailedCount)
+		incomplete = int(stats.IncompleteCount)
+	}
+	switch job.State {
+	case vertexJobStateCancelling:
+		batch.ProcessingStatus = ProcessingStatusCanceling
+		batch.RequestCounts.Processing = incomplete
+	case vertexJobStateSucceeded, vertexJobStatePartiallySucceeded, vertexJobStateFailed, vertexJobStateCancelled, vertexJobStateExpired:
+		batch.ProcessingStatus = ProcessingStatusEnded
+		if job.EndTime != "" {
+			batch.EndedAt = &job.EndTime
+		}
+		switch job.State {
+		case vertexJobStateCancelled:
+			batch.RequestCounts.Canceled = incomplete
+		case vertexJobStateExpired:
+			batch.RequestCounts.Expired = incomplete
+		default:
+			batch.RequestCounts.Errored += incomplete
+		}
+		if job.OutputInfo != nil && job.OutputInfo.GcsOutputDirectory != "" {
+			batch.ResultsURL = &job.OutputInfo.GcsOutputDirectory
+		}
+	default:
+		batch.RequestCounts.Processing = incomplete
+	}
+	return batch
+}
+
+// buildVertexBatchInput 生成 Vertex 批量预测的 JSONL 输入：custom_id 保持不变，请求体去掉 model 并带上 anthropic_version
+func buildVertexBatchInput(req *CreateRequest) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, item := range req.Requests {
+		params, err := sjson.DeleteBytes(item.Params, "model")
+		if err != nil {
+			return nil, errors.Wrapf(err, "remove model for request %s failed", item.CustomID)
+		}
+		params, err = sjson.SetBytes(params, "anthropic_version", vertexcore.AnthropicVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "set anthropic_version for request %s failed", item.CustomID)
+		}
+		line, err := common.Marshal(vertexBatchInputLine{CustomID: item.CustomID, Request: params})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func vertexAccessToken(key, proxy string) (*vertexcore.Credentials, string, error) {
+	adc := &vertexcore.Credentials{}
+	if err := common.Unmarshal([]byte(key), adc); err != nil {
+		return nil, "", fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	token, err := vertexcore.AcquireAccessToken(*adc, proxy)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to acquire access token: %w", err)
+	}
+	return adc, token, nil
+}
+
+func doGoogleRequest(ctx context.Context, client *http.Client, token, method, uri, contentType string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return client.Do(req)
+}
+
+// readGoogleResponse 读取成功响应的 JSON，非 2xx 时返回包含响应内容的错误
+func readGoogleResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, body)
+	}
+	if v == nil {
+		return nil
+	}
+	return common.Unmarshal(body, v)
+}
+
+func uploadGCSObject(ctx context.Context, client *http.Client, token, bucket, object string, data []byte) error {
+	uri := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", gcsBaseURL, url.PathEscape(bucket), url.QueryEscape(object))
+	resp, err := doGoogleRequest(ctx, client, token, http.MethodPost, uri, "application/jsonl", data)
+	if err != nil {
+		return err
+	}
+	if err := readGoogleResponse(resp, nil); err != nil {
+		return fmt.Errorf("upload gs://%s/%s failed: %w", bucket, object, err)
+	}
+	return nil
+}
+
+func listGCSObjects(ctx context.Context, client *http.Client, token, bucket, prefix string) ([]string, error) {
+	var names []string
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": []string{prefix}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		uri := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", gcsBaseURL, url.PathEscape(bucket), query.Encode())
+		resp, err := doGoogleRequest(ctx, client, token, http.MethodGet, uri, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := readGoogleResponse(resp, &page); err != nil {
+			return nil, fmt.Errorf("list gs://%s/%s failed: %w", bucket, prefix, err)
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func openGCSObject(ctx context.Context, client *http.Client, token, bucket, object string) (io.ReadCloser, error) {
+	uri := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", gcsBaseURL, url.PathEscape(bucket), url.PathEscape(object))
+	resp, err := doGoogleRequest(ctx, client, token, http.MethodGet, uri, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("read gs://%s/%s failed: %w", bucket, object, readGoogleResponse(resp, nil))
+	}
+	return resp.Body, nil
+}
+
+// vertexJobURL 返回批量预测作业的地址，作业名形如 projects/{project}/locations/{region}/batchPredictionJobs/{id}
+func vertexJobURL(baseURL, name string) (string, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 6 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "batchPredictionJobs" {
+		return "", fmt.Errorf("invalid vertex batch prediction job name %q", name)
+	}
+	return vertexcore.BuildAPIBaseURL(baseURL, vertexcore.DefaultAPIVersion, "", parts[3]) + "/" + name, nil
+}
+
+// vertexJobResponse 把作业响应转换为 Anthropic 批次对象，上游错误原样返回
+func vertexJobResponse(resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	var job vertexBatchJob
+	if err := readGoogleResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	if job.Name == "" {
+		return nil, fmt.Errorf("vertex batch prediction job name is empty")
+	}
+	return newBatchResponse(job.toMessageBatch())
+}
+
+// submitVertexBatch 把批次输入写入 GCS，再以 Claude 模型创建 Vertex 批量预测作业
+func submitVertexBatch(c *gin.Context, info *relaycommon.RelayInfo, input []byte) (*http.Response, error) {
+	ctx := c.Request.Context()
+	bucket, prefix, err := parseStorageURI(info.ChannelOtherSettings.BatchStorageURI, "gs")
+	if err != nil {
+		return nil, err
+	}
+	client, err := service.GetHttpClientWithProxy(info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	adc, token, err := vertexAccessToken(info.ApiKey, info.ChannelSetting.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Join(prefix, info.PublicTaskID)
+	inputObject := path.Join(dir, batchInputObject)
+	if err := uploadGCSObject(ctx, client, token, bucket, inputObject, input); err != nil {
+		return nil, err
+	}
+
+	job := map[string]any{
+		"displayName": info.PublicTaskID,
+		"model":       "publishers/anthropic/models/" + vertexcore.ClaudeModelName(info.UpstreamModelName),
+		"inputConfig": map[string]any{
+			"instancesFormat": "jsonl",
+			"gcsSource":       map[string]any{"uris": []string{fmt.Sprintf("gs://%s/%s", bucket, inputObject)}},
+		},
+		"outputConfig": map[string]any{
+			"predictionsFormat": "jsonl",
+			"gcsDestination":    map[string]any{"outputUriPrefix": fmt.Sprintf("gs://%s/%s", bucket, path.Join(dir, batchOutputDir))},
+		},
+	}
+	body, err := common.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	region := vertexcore.GetModelRegion(info.ApiVersion, info.OriginModelName)
+	uri := vertexcore.BuildAPIBaseURL(info.ChannelBaseUrl, vertexcore.DefaultAPIVersion, adc.ProjectID, region) + "/batchPredictionJobs"
+	resp, err := doGoogleRequest(ctx, client, token, http.MethodPost, uri, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+	return vertexJobResponse(resp)
+}
+
+// doVertexBatchRequest 查询、取消 Vertex 批量预测作业，或读取其 GCS 输出
+func doVertexBatchRequest(ctx context.Context, client *http.Client, u *Upstream, name, action string) (*http.Response, error) {
+	_, token, err := vertexAccessToken(u.Key, u.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	jobURL, err := vertexJobURL(u.BaseURL, name)
+	if err != nil {
+		return nil, err
+	}
+	switch action {
+	case UpstreamPathCancel:
+		resp, err := doGoogleRequest(ctx, client, token, http.MethodPost, jobURL+":cancel", "application/json", []byte("{}"))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+	case UpstreamPathResults:
+		return vertexBatchResults(ctx, client, token, jobURL)
+	}
+	resp, err := doGoogleRequest(ctx, client, token, http.MethodGet, jobURL, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return vertexJobResponse(resp)
+}
+
+// vertexBatchResults 依次读取作业输出目录下的 JSONL 文件并转换为 Anthropic 结果行
+func vertexBatchResults(ctx context.Context, client *http.Client, token, jobURL string) (*http.Response, error) {
+	resp, err := doGoogleRequest(ctx, client, token, http.MethodGet, jobURL, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	var job vertexBatchJob
+	if err := readGoogleResponse(resp, &job); err != nil {
+		return nil, err
+	}
+	if job.OutputInfo == nil || job.OutputInfo.GcsOutputDirectory == "" {
+		return nil, fmt.Errorf("vertex batch prediction job %s has no output yet", job.Name)
+	}
+	bucket, prefix, err := parseStorageURI(job.OutputInfo.GcsOutputDirectory, "gs")
+	if err != nil {
+		return nil, err
+	}
+	names, err := listGCSObjects(ctx, client, token, bucket, prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasSuffix(name, ".jsonl") {
+			objects = append(objects, name)
+		}
+	}
+	sort.Strings(objects)
+	return newResultsResponse(func(w io.Writer) error {
+		for _, object := range objects {
+			body, err := openGCSObject(ctx, client, token, bucket, object)
+			if err != nil {
+				return err
+			}
+			err = convertResultLines(body, w, convertVertexResultLine)
+			_ = body.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}), nil
+}
+
+func convertVertexResultLine(line []byte) ([]byte, error) {
+	var result vertexBatchResultLine
+	if err := common.Unmarshal(line, &result); err != nil {
+		return nil, err
+	}
+	if result.Status == "" && common.GetJsonType(result.Response) == "object" {
+		return newSucceededResultLine(result.CustomID, result.Response)
+	}
+	message := result.Status
+	if message == "" {
+		message = "no response was returned for this request"
+	}
+	return newErroredResultLine(result.CustomID, message)
+}
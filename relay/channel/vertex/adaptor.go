@@ -48,7 +48,16 @@ var claudeModelMap = map[string]string{
 	"claude-opus-4-8":            "claude-opus-4-8",
 }
 
-const anthropicVersion = "vertex-2023-10-16"
+// AnthropicVersion 是 Vertex 上 Claude 请求体中的 anthropic_version
+const AnthropicVersion = "vertex-2023-10-16"
+
+// ClaudeModelName 返回 Claude 模型在 Vertex 上的模型名
+func ClaudeModelName(modelName string) string {
+	if v, ok := claudeModelMap[modelName]; ok {
+		return v
+	}
+	return modelName
+}
 
 type Adaptor struct {
 	RequestMode        int
@@ -99,7 +108,7 @@ func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayIn
 	} else {
 		c.Set("request_model", request.Model)
 	}
-	vertexClaudeReq := copyRequest(request, anthropicVersion)
+	vertexClaudeReq := copyRequest(request, AnthropicVersion)
 	return vertexClaudeReq, nil
 }
 
@@ -201,11 +210,7 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 		} else {
 			suffix = "rawPredict"
 		}
-		model := info.UpstreamModelName
-		if v, ok := claudeModelMap[info.UpstreamModelName]; ok {
-			model = v
-		}
-		return a.getRequestUrl(info, model, suffix)
+		return a.getRequestUrl(info, ClaudeModelName(info.UpstreamModelName), suffix)
 	} else if a.RequestMode == RequestModeOpenSource {
 		return a.getRequestUrl(info, "", "")
 	}
@@ -297,7 +302,7 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 		if !ok {
 			return nil, fmt.Errorf("expected Anthropic Messages request, got %T", result.Value)
 		}
-		vertexClaudeReq := copyRequest(claudeReq, anthropicVersion)
+		vertexClaudeReq := copyRequest(claudeReq, AnthropicVersion)
 		c.Set("request_model", claudeReq.Model)
 		info.UpstreamModelName = claudeReq.Model
 		return vertexClaudeReq, nil
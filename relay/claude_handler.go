@@ -110,6 +110,7 @@ func ClaudeHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		if effort := request.GetEfforts(); effort != "" {
 			info.SetReasoningEffort(effort)
 		}
+		service.PreprocessClaudeRequestImages(c, info, request)
 	}
 
 	if info.ChannelSetting.SystemPrompt != "" {
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync/atomic"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+)
+
+// HedgeArm 对冲请求中的一路尝试。两路中先写出响应的一方胜出，另一方被标记为落选并取消，
+// 落选方即使拿到了部分用量也不向用户结算。
+type HedgeArm struct {
+	Hedge bool // false 为首个渠道的尝试，true 为延迟后发出的对冲尝试
+	// LoserBillingUserId 落选方的部分用量记到该用户，0 表示不计费
+	LoserBillingUserId int
+	ctx                context.Context
+	lost               atomic.Bool
+}
+
+// NewHedgeArm 创建一路尝试，ctx 在该路落选时被取消，上游请求绑定到它上面
+func NewHedgeArm(ctx context.Context, hedge bool, loserBillingUserId int) *HedgeArm {
+	return &HedgeArm{Hedge: hedge, LoserBillingUserId: loserBillingUserId, ctx: ctx}
+}
+
+// Context 返回该路尝试的 context，nil 安全
+func (a *HedgeArm) Context() context.Context {
+	if a == nil {
+		return nil
+	}
+	return a.ctx
+}
+
+// MarkLost 标记该路尝试落选
+func (a *HedgeArm) MarkLost() {
+	a.lost.Store(true)
+}
+
+// Lost 判断该路尝试是否落选，nil 安全
+func (a *HedgeArm) Lost() bool {
+	return a != nil && a.lost.Load()
+}
+
+// CloneForHedge 为对冲尝试复制一份 RelayInfo：两路尝试在不同 goroutine 中并发执行，
+// 转发过程中会被修改的字段各自持有一份，计费会话仍然共用，由胜出方结算。
+// 渠道信息在对冲尝试选定渠道后由 InitChannelMeta 重新生成。
+func (info *RelayInfo) CloneForHedge(request dto.Request) *RelayInfo {
+	clone := *info
+	clone.Request = request
+	clone.ChannelMeta = nil
+	clone.StreamStatus = nil
+	clone.LastError = nil
+	clone.convOptions = nil
+	clone.TrafficSplitCanary = false
+	clone.HedgeArm = nil
+	clone.RequestHeaders = maps.Clone(info.RequestHeaders)
+	clone.RuntimeHeadersOverride = maps.Clone(info.RuntimeHeadersOverride)
+	clone.ParamOverrideAudit = slices.Clone(info.ParamOverrideAudit)
+	clone.RequestConversionChain = slices.Clone(info.RequestConversionChain)
+	if info.ClaudeConvertInfo != nil {
+		claudeInfo := *info.ClaudeConvertInfo
+		if claudeInfo.Usage != nil {
+			usage := *claudeInfo.Usage
+			claudeInfo.Usage = &usage
+		}
+		clone.ClaudeConvertInfo = &claudeInfo
+	}
+	if info.ResponsesUsageInfo != nil {
+		tools := make(map[string]*BuildInToolInfo, len(info.ResponsesUsageInfo.BuiltInTools))
+		for name, tool := range info.ResponsesUsageInfo.BuiltInTools {
+			if tool != nil {
+				copied := *tool
+				tool = &copied
+			}
+			tools[name] = tool
+		}
+		clone.ResponsesUsageInfo = &ResponsesUsageInfo{BuiltInTools: tools}
+	}
+	if info.RerankerInfo != nil {
+		rerankerInfo := *info.RerankerInfo
+		clone.RerankerInfo = &rerankerInfo
+	}
+	return &clone
+}
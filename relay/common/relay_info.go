@@ -82,7 +82,7 @@ type TokenCountMeta struct {
 
 type RelayInfo struct {
 	TokenId           int
-	TokenKey          string
+	TokenKey          string // 令牌密钥摘要（model.Token.Key），用于缓存与计费
 	TokenGroup        string
 	UserId            int
 	UsingGroup        string // 使用的分组，当auto跨分组重试时，会变动
@@ -144,6 +144,8 @@ type RelayInfo struct {
 	IsClaudeBetaQuery                     bool // /v1/messages?beta=true
 	IsChannelTest                         bool // channel test request
 	RetryIndex                            int
+	TrafficSplitCanary                    bool      // 当前尝试走灰度分流的 canary 分支，不计入 perf_metrics 的基线
+	HedgeArm                              *HedgeArm // 对冲请求中当前尝试所在的分支，落选分支不向用户计费
 	LastError                             *types.NewAPIError
 	RuntimeHeadersOverride                map[string]interface{}
 	UseRuntimeHeadersOverride             bool
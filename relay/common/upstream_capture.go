@@ -0,0 +1,218 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUpstreamCaptureBytes caps how much of each request/response body is kept.
+const maxUpstreamCaptureBytes = 1 << 20
+
+const upstreamCaptureMask = "***masked***"
+
+// UpstreamCapture records the HTTP exchange with the upstream for one relay.
+// It is only attached by debugging tools such as channel replay; regular relay
+// requests never pay for the copy.
+type UpstreamCapture struct {
+	mu             sync.Mutex
+	method         string
+	url            string
+	requestHeader  http.Header
+	requestBody    []byte
+	statusCode     int
+	responseHeader http.Header
+	responseBody   bytes.Buffer
+	truncated      bool
+}
+
+// UpstreamCaptureSnapshot is the masked, JSON-friendly view of an UpstreamCapture.
+type UpstreamCaptureSnapshot struct {
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body"`
+	StatusCode      int               `json:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body"`
+	Truncated       bool              `json:"truncated,omitempty"`
+}
+
+// AttachUpstreamCapture enables capturing of the upstream exchange for c.
+func AttachUpstreamCapture(c *gin.Context) *UpstreamCapture {
+	capture := &UpstreamCapture{}
+	common.SetContextKey(c, constant.ContextKeyUpstreamCapture, capture)
+	return capture
+}
+
+func getUpstreamCapture(c *gin.Context) *UpstreamCapture {
+	if c == nil {
+		return nil
+	}
+	capture, _ := common.GetContextKeyType[*UpstreamCapture](c, constant.ContextKeyUpstreamCapture)
+	return capture
+}
+
+// CaptureUpstreamRequest records the final request right before it is sent,
+// i.e. after param and header overrides have been applied.
+func CaptureUpstreamRequest(c *gin.Context, req *http.Request) {
+	capture := getUpstreamCapture(c)
+	if capture == nil || req == nil {
+		return
+	}
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	capture.method = req.Method
+	if req.URL != nil {
+		capture.url = req.URL.String()
+	}
+	capture.requestHeader = req.Header.Clone()
+	if req.Host != "" && req.URL != nil && req.Host != req.URL.Host {
+		capture.requestHeader.Set("Host", req.Host)
+	}
+	if req.GetBody == nil {
+		return
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	defer func() { _ = body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(body, maxUpstreamCaptureBytes+1))
+	if len(data) > maxUpstreamCaptureBytes {
+		data = data[:maxUpstreamCaptureBytes]
+		capture.truncated = true
+	}
+	capture.requestBody = data
+}
+
+// CaptureUpstreamResponse records the response status and headers and tees
+// the raw body as the adaptor reads it.
+func CaptureUpstreamResponse(c *gin.Context, resp *http.Response) {
+	capture := getUpstreamCapture(c)
+	if capture == nil || resp == nil {
+		return
+	}
+	capture.mu.Lock()
+	capture.statusCode = resp.StatusCode
+	capture.responseHeader = resp.Header.Clone()
+	capture.mu.Unlock()
+	if resp.Body != nil {
+		resp.Body = &upstreamCaptureBody{ReadCloser: resp.Body, capture: capture}
+	}
+}
+
+type upstreamCaptureBody struct {
+	io.ReadCloser
+	capture *UpstreamCapture
+}
+
+func (b *upstreamCaptureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.capture.appendResponse(p[:n])
+	}
+	return n, err
+}
+
+func (u *UpstreamCapture) appendResponse(data []byte) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	remaining := maxUpstreamCaptureBytes - u.responseBody.Len()
+	if remaining <= 0 {
+		u.truncated = true
+		return
+	}
+	if len(data) > remaining {
+		data = data[:remaining]
+		u.truncated = true
+	}
+	u.responseBody.Write(data)
+}
+
+// Captured reports whether an upstream request was recorded. Adaptors that
+// talk to the upstream through an SDK (e.g. AWS Bedrock) bypass the hook.
+func (u *UpstreamCapture) Captured() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.method != ""
+}
+
+// Snapshot returns the capture with credentials masked: sensitive headers and
+// URL query parameters are redacted, and every occurrence of the given
+// secrets (typically the channel key) is replaced in the URL, headers and bodies.
+func (u *UpstreamCapture) Snapshot(secrets ...string) UpstreamCaptureSnapshot {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	replacer := newSecretReplacer(secrets)
+	return UpstreamCaptureSnapshot{
+		Method:          u.method,
+		URL:             replacer.Replace(SanitizeURLForLog(u.url)),
+		RequestHeaders:  maskCapturedHeaders(u.requestHeader, replacer),
+		RequestBody:     replacer.Replace(string(u.requestBody)),
+		StatusCode:      u.statusCode,
+		ResponseHeaders: maskCapturedHeaders(u.responseHeader, replacer),
+		ResponseBody:    replacer.Replace(u.responseBody.String()),
+		Truncated:       u.truncated,
+	}
+}
+
+// MaskUpstreamSecrets replaces every occurrence of the given secrets in text.
+func MaskUpstreamSecrets(text string, secrets ...string) string {
+	return newSecretReplacer(secrets).Replace(text)
+}
+
+func newSecretReplacer(secrets []string) *strings.Replacer {
+	var parts []string
+	for _, secret := range secrets {
+		// Multi-part keys (e.g. AK|SK) are masked piece by piece; very short
+		// pieces would also match unrelated content, so they are skipped.
+		for _, part := range strings.Split(secret, "|") {
+			part = strings.TrimSpace(part)
+			if len(part) >= 8 {
+				parts = append(parts, part)
+			}
+		}
+	}
+	// Longer secrets first so a key sharing a prefix with another is fully masked.
+	sort.SliceStable(parts, func(i, j int) bool { return len(parts[i]) > len(parts[j]) })
+	pairs := make([]string, 0, len(parts)*2)
+	for _, part := range parts {
+		pairs = append(pairs, part, upstreamCaptureMask)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func maskCapturedHeaders(header http.Header, replacer *strings.Replacer) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(header))
+	for name, values := range header {
+		if isSensitiveHeaderName(name) {
+			masked[name] = upstreamCaptureMask
+			continue
+		}
+		masked[name] = replacer.Replace(strings.Join(values, ", "))
+	}
+	return masked
+}
+
+func isSensitiveHeaderName(name string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if isSensitiveURLQueryKey(normalized) {
+		return true
+	}
+	return strings.Contains(normalized, "key") ||
+		strings.Contains(normalized, "auth") ||
+		strings.Contains(normalized, "cookie") ||
+		strings.Contains(normalized, "credential")
+}
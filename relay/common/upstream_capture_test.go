@@ -0,0 +1,50 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamCaptureMasksSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	capture := AttachUpstreamCapture(c)
+
+	req, err := http.NewRequest(http.MethodPost, "https://upstream.example/v1beta/models/gemini:generateContent?key=AIza-secret-value&alt=sse", bytes.NewBufferString(`{"api_key":"ak-secret-value"}`))
+	require.NoError(t, err)
+	req.Header.Set("X-Goog-Api-Key", "AIza-secret-value")
+	req.Header.Set("X-Trace", "ak-secret-value")
+	req.Header.Set("Content-Type", "application/json")
+	CaptureUpstreamRequest(c, req)
+
+	resp := &http.Response{StatusCode: http.StatusBadRequest, Header: http.Header{"Set-Cookie": {"session=1"}}, Body: io.NopCloser(bytes.NewBufferString(`{"error":"bad key ak-secret-value"}`))}
+	CaptureUpstreamResponse(c, resp)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.True(t, capture.Captured())
+	snapshot := capture.Snapshot("AIza-secret-value", "ak-secret-value|sk")
+	assert.Equal(t, "https://upstream.example/v1beta/models/gemini:generateContent?alt=sse&key=%2A%2A%2Amasked%2A%2A%2A", snapshot.URL)
+	assert.Equal(t, "***masked***", snapshot.RequestHeaders["X-Goog-Api-Key"])
+	assert.Equal(t, "***masked***", snapshot.RequestHeaders["X-Trace"], "secret values are masked in any header")
+	assert.Equal(t, "application/json", snapshot.RequestHeaders["Content-Type"])
+	assert.Equal(t, `{"api_key":"***masked***"}`, snapshot.RequestBody)
+	assert.Equal(t, http.StatusBadRequest, snapshot.StatusCode)
+	assert.Equal(t, "***masked***", snapshot.ResponseHeaders["Set-Cookie"])
+	assert.Equal(t, `{"error":"bad key ***masked***"}`, snapshot.ResponseBody)
+}
+
+func TestUpstreamCaptureIsOptIn(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	body := io.NopCloser(bytes.NewBufferString("ok"))
+	resp := &http.Response{StatusCode: http.StatusOK, Body: body}
+	CaptureUpstreamResponse(c, resp)
+	assert.Equal(t, body, resp.Body, "responses are not wrapped without an attached capture")
+}
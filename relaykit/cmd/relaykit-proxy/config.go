@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/relaykit/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultListen           = ":8080"
+	defaultUpstreamTimeout  = 5 * time.Minute
+	defaultMaxMediaBytes    = 20 << 20
+	defaultAnthropicVersion = "2023-06-01"
+)
+
+// Config is the sidecar configuration loaded from YAML. Values of the form
+// ${ENV} are expanded before parsing so keys can stay out of the file.
+type Config struct {
+	Listen    string           `yaml:"listen"`
+	Upstreams []UpstreamConfig `yaml:"upstreams"`
+	Routes    []RouteConfig    `yaml:"routes"`
+	Media     MediaConfig      `yaml:"media"`
+}
+
+// UpstreamConfig describes one upstream endpoint. When APIKey is empty the
+// client's own key is forwarded to the upstream.
+type UpstreamConfig struct {
+	Name    string            `yaml:"name"`
+	BaseURL string            `yaml:"base_url"`
+	APIKey  string            `yaml:"api_key"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// RouteConfig binds an inbound path and protocol to an upstream protocol.
+// Gemini inbound routes should use a subtree path such as /v1beta/models/,
+// the model and action are read from the last path segment.
+type RouteConfig struct {
+	Path     string            `yaml:"path"`
+	Inbound  types.RelayFormat `yaml:"inbound"`
+	Outbound types.RelayFormat `yaml:"outbound"`
+	Upstream string            `yaml:"upstream"`
+	// ModelMap rewrites client model names to upstream model names.
+	ModelMap map[string]string `yaml:"model_map"`
+	// DefaultMaxTokens is injected when converting to Claude Messages and
+	// the client did not send max_tokens.
+	DefaultMaxTokens int `yaml:"default_max_tokens"`
+}
+
+// MediaConfig controls how image URLs are inlined when a conversion needs
+// base64 data (e.g. OpenAI image_url to Gemini inlineData).
+type MediaConfig struct {
+	FetchURLs bool  `yaml:"fetch_urls"`
+	MaxBytes  int64 `yaml:"max_bytes"`
+}
+
+var supportedFormats = []types.RelayFormat{
+	types.RelayFormatOpenAI,
+	types.RelayFormatOpenAIResponses,
+	types.RelayFormatClaude,
+	types.RelayFormatGemini,
+}
+
+func isSupportedFormat(format types.RelayFormat) bool {
+	for _, f := range supportedFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) normalize() error {
+	if c.Listen == "" {
+		c.Listen = defaultListen
+	}
+	if c.Media.MaxBytes <= 0 {
+		c.Media.MaxBytes = defaultMaxMediaBytes
+	}
+	if len(c.Upstreams) == 0 {
+		return errors.New("config: at least one upstream is required")
+	}
+	if len(c.Routes) == 0 {
+		return errors.New("config: at least one route is required")
+	}
+	names := make(map[string]struct{}, len(c.Upstreams))
+	for i := range c.Upstreams {
+		u := &c.Upstreams[i]
+		if u.Name == "" {
+			return fmt.Errorf("config: upstreams[%d].name is required", i)
+		}
+		if _, ok := names[u.Name]; ok {
+			return fmt.Errorf("config: upstream %q is duplicated", u.Name)
+		}
+		names[u.Name] = struct{}{}
+		u.BaseURL = strings.TrimRight(u.BaseURL, "/")
+		if u.BaseURL == "" {
+			return fmt.Errorf("config: upstream %q base_url is required", u.Name)
+		}
+		if u.Timeout <= 0 {
+			u.Timeout = defaultUpstreamTimeout
+		}
+	}
+	paths := make(map[string]struct{}, len(c.Routes))
+	for i := range c.Routes {
+		r := &c.Routes[i]
+		if !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("config: routes[%d].path must start with /", i)
+		}
+		if _, ok := paths[r.Path]; ok {
+			return fmt.Errorf("config: route %q is duplicated", r.Path)
+		}
+		paths[r.Path] = struct{}{}
+		if !isSupportedFormat(r.Inbound) {
+			return fmt.Errorf("config: route %q has unsupported inbound format %q", r.Path, r.Inbound)
+		}
+		if !isSupportedFormat(r.Outbound) {
+			return fmt.Errorf("config: route %q has unsupported outbound format %q", r.Path, r.Outbound)
+		}
+		if _, ok := names[r.Upstream]; !ok {
+			return fmt.Errorf("config: route %q references unknown upstream %q", r.Path, r.Upstream)
+		}
+	}
+	return nil
+}
+
+func (c *Config) upstream(name string) *UpstreamConfig {
+	for i := range c.Upstreams {
+		if c.Upstreams[i].Name == name {
+			return &c.Upstreams[i]
+		}
+	}
+	return nil
+}
+
+func (r *RouteConfig) mapModel(model string) string {
+	if mapped, ok := r.ModelMap[model]; ok && mapped != "" {
+		return mapped
+	}
+	return model
+}
@@ -0,0 +1,47 @@
+// Command relaykit-proxy is a standalone protocol-translation proxy built on
+// relaykit. Each configured route accepts one protocol (OpenAI Chat,
+// OpenAI Responses, Claude Messages or Gemini) and forwards the request to an
+// upstream speaking another, converting request, response and stream.
+//
+// Usage:
+//
+//	relaykit-proxy -config config.yaml
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/relaykit/relayconvert"
+)
+
+func logf(format string, args ...any) {
+	log.Printf(format, args...)
+}
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "path to the YAML config file")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	relayconvert.SetMediaResolver(newMediaResolver(cfg.Media))
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           NewServer(cfg),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	for _, route := range cfg.Routes {
+		logf("route %s: %s -> %s (upstream %s)", route.Path, route.Inbound, route.Outbound, route.Upstream)
+	}
+	logf("relaykit-proxy listening on %s", cfg.Listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("serve: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/relaykit/relayconvert"
+	"github.com/QuantumNous/new-api/relaykit/types"
+)
+
+const mediaFetchTimeout = 30 * time.Second
+
+// newMediaResolver returns the resolver used when a conversion needs inline
+// base64 data. Remote URLs are only downloaded when media.fetch_urls is set.
+func newMediaResolver(cfg MediaConfig) relayconvert.MediaResolver {
+	client := &http.Client{Timeout: mediaFetchTimeout}
+	return relayconvert.MediaResolver{
+		GetBase64Data: func(c context.Context, source types.FileSource, reason ...string) (string, string, error) {
+			if source == nil {
+				return "", "", errors.New("media source is nil")
+			}
+			if !source.IsURL() {
+				data := source.GetRawData()
+				if b, ok := source.(*types.Base64Source); ok && b.MimeType != "" && !strings.HasPrefix(data, "data:") {
+					return data, b.MimeType, nil
+				}
+				mimeType, data, err := decodeBase64FileData(data)
+				return data, mimeType, err
+			}
+			url := source.GetRawData()
+			if strings.HasPrefix(url, "data:") {
+				mimeType, data, err := decodeBase64FileData(url)
+				return data, mimeType, err
+			}
+			if !cfg.FetchURLs {
+				return "", "", fmt.Errorf("fetching media url is disabled: %s", source.GetIdentifier())
+			}
+			return fetchMedia(c, client, url, cfg.MaxBytes)
+		},
+		DecodeBase64FileData: decodeBase64FileData,
+	}
+}
+
+func fetchMedia(c context.Context, client *http.Client, url string, maxBytes int64) (string, string, error) {
+	req, err := http.NewRequestWithContext(c, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("fetch media: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("fetch media: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return "", "", fmt.Errorf("fetch media: size exceeds %d bytes", maxBytes)
+	}
+	mimeType := resp.Header.Get("Content-Type")
+	if idx := strings.Index(mimeType, ";"); idx >= 0 {
+		mimeType = mimeType[:idx]
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+	return base64.StdEncoding.EncodeToString(data), mimeType, nil
+}
+
+// decodeBase64FileData accepts either a data URL or bare base64 and returns
+// the mime type and the base64 payload.
+func decodeBase64FileData(s string) (string, string, error) {
+	if header, payload, ok := strings.Cut(s, ","); ok && strings.HasPrefix(header, "data:") {
+		mimeType := strings.TrimPrefix(header, "data:")
+		if idx := strings.Index(mimeType, ";"); idx >= 0 {
+			mimeType = mimeType[:idx]
+		}
+		if mimeType == "" {
+			mimeType = sniffBase64(payload)
+		}
+		return mimeType, payload, nil
+	}
+	return sniffBase64(s), s, nil
+}
+
+func sniffBase64(s string) string {
+	prefix := s
+	if len(prefix) > 64 {
+		prefix = prefix[:64]
+	}
+	data, err := base64.StdEncoding.DecodeString(prefix[:len(prefix)/4*4])
+	if err != nil || len(data) == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(data)
+}
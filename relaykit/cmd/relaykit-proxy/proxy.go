@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert/convmeta"
+	"github.com/QuantumNous/new-api/relaykit/types"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	maxRequestBodyBytes    = 64 << 20
+	defaultRouteMaxTokens  = 8192
+	geminiActionGenerate   = "generateContent"
+	geminiActionStreamGen  = "streamGenerateContent"
+	contentTypeJSON        = "application/json"
+	contentTypeEventStream = "text/event-stream"
+)
+
+// Server translates requests between protocols according to the configured
+// routes and forwards them to the matching upstream.
+type Server struct {
+	cfg     *Config
+	mux     *http.ServeMux
+	clients map[string]*http.Client
+}
+
+func NewServer(cfg *Config) *Server {
+	s := &Server{
+		cfg:     cfg,
+		mux:     http.NewServeMux(),
+		clients: make(map[string]*http.Client, len(cfg.Upstreams)),
+	}
+	for i := range cfg.Upstreams {
+		u := &cfg.Upstreams[i]
+		s.clients[u.Name] = &http.Client{Timeout: u.Timeout}
+	}
+	for i := range cfg.Routes {
+		route := &cfg.Routes[i]
+		s.mux.Handle("POST "+route.Path, s.routeHandler(route, cfg.upstream(route.Upstream)))
+	}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// inboundRequest is the parsed client request.
+type inboundRequest struct {
+	raw     []byte
+	request any
+	model   string
+	stream  bool
+	// includeUsage mirrors OpenAI stream_options.include_usage.
+	includeUsage bool
+}
+
+func (s *Server) routeHandler(route *RouteConfig, upstream *UpstreamConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := upstream.APIKey
+		if key == "" {
+			key = clientAPIKey(r)
+			if key == "" {
+				writeError(w, route.Inbound, http.StatusUnauthorized, "missing api key")
+				return
+			}
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusBadRequest, "read request body failed: "+err.Error())
+			return
+		}
+		in, err := parseInbound(route.Inbound, r.URL.Path, body)
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusBadRequest, err.Error())
+			return
+		}
+		upstreamModel := route.mapModel(in.model)
+		meta := &convmeta.Values{
+			OriginModelName:     in.model,
+			UpstreamModelName:   upstreamModel,
+			ChannelMetaAttached: true,
+			IsStream:            in.stream,
+			Options:             route.convOptions(),
+		}
+
+		payload, err := buildUpstreamBody(r.Context(), meta, route, in, upstreamModel)
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusBadRequest, err.Error())
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, upstreamURL(upstream.BaseURL, route.Outbound, upstreamModel, in.stream), bytes.NewReader(payload))
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusInternalServerError, err.Error())
+			return
+		}
+		setUpstreamHeaders(req, r, route.Outbound, upstream, key)
+
+		client := s.clients[upstream.Name]
+		if in.stream {
+			// 流式响应的总时长不受 client timeout 限制，由客户端连接决定
+			client = &http.Client{Transport: client.Transport}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusBadGateway, "upstream request failed: "+err.Error())
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			copyResponse(w, resp)
+			return
+		}
+		if route.Inbound == route.Outbound {
+			copyResponse(w, resp)
+			return
+		}
+		if in.stream {
+			if err := streamResponse(r.Context(), w, resp.Body, meta, route, in); err != nil {
+				logf("route %s: stream conversion failed: %v", route.Path, err)
+			}
+			return
+		}
+		out, err := convertResponseBody(r.Context(), meta, route, resp.Body)
+		if err != nil {
+			writeError(w, route.Inbound, http.StatusBadGateway, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(out)
+	}
+}
+
+func (r *RouteConfig) convOptions() *convmeta.Options {
+	maxTokens := r.DefaultMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultRouteMaxTokens
+	}
+	return &convmeta.Options{
+		Claude: convmeta.ClaudeOptions{
+			DefaultMaxTokens: func(string) int { return maxTokens },
+		},
+	}
+}
+
+// parseInbound decodes the client body into the DTO for the inbound format.
+func parseInbound(format types.RelayFormat, path string, body []byte) (*inboundRequest, error) {
+	in := &inboundRequest{raw: body}
+	switch format {
+	case types.RelayFormatOpenAI:
+		req := &dto.GeneralOpenAIRequest{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		in.request, in.model = req, req.Model
+		in.stream = req.Stream != nil && *req.Stream
+		in.includeUsage = req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+	case types.RelayFormatOpenAIResponses:
+		req := &dto.OpenAIResponsesRequest{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		in.request, in.model = req, req.Model
+		in.stream = req.Stream != nil && *req.Stream
+	case types.RelayFormatClaude:
+		req := &dto.ClaudeRequest{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		in.request, in.model = req, req.Model
+		in.stream = req.Stream != nil && *req.Stream
+	case types.RelayFormatGemini:
+		model, action, err := parseGeminiPath(path)
+		if err != nil {
+			return nil, err
+		}
+		req := &dto.GeminiChatRequest{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		in.request, in.model = req, model
+		in.stream = action == geminiActionStreamGen
+	default:
+		return nil, fmt.Errorf("unsupported inbound format %s", format)
+	}
+	if in.model == "" {
+		return nil, errors.New("model is required")
+	}
+	return in, nil
+}
+
+// parseGeminiPath extracts model and action from .../models/{model}:{action}.
+func parseGeminiPath(path string) (string, string, error) {
+	segment := path[strings.LastIndex(path, "/")+1:]
+	model, action, ok := strings.Cut(segment, ":")
+	if !ok || model == "" {
+		return "", "", fmt.Errorf("invalid gemini path %q, expected models/{model}:{action}", path)
+	}
+	if action != geminiActionGenerate && action != geminiActionStreamGen {
+		return "", "", fmt.Errorf("unsupported gemini action %q", action)
+	}
+	return model, action, nil
+}
+
+func buildUpstreamBody(ctx context.Context, meta *convmeta.Values, route *RouteConfig, in *inboundRequest, upstreamModel string) ([]byte, error) {
+	var payload []byte
+	if route.Inbound == route.Outbound {
+		payload = in.raw
+	} else {
+		setRequestModel(in.request, upstreamModel)
+		result, err := relayconvert.ConvertRequest(ctx, meta, route.Outbound, in.request)
+		if err != nil {
+			return nil, fmt.Errorf("convert request: %w", err)
+		}
+		payload, err = json.Marshal(result.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if route.Outbound == types.RelayFormatGemini {
+		// Gemini 的模型与是否流式都在 URL 中
+		return payload, nil
+	}
+	var err error
+	if payload, err = sjson.SetBytes(payload, "model", upstreamModel); err != nil {
+		return nil, err
+	}
+	if in.stream {
+		if payload, err = sjson.SetBytes(payload, "stream", true); err != nil {
+			return nil, err
+		}
+		if route.Outbound == types.RelayFormatOpenAI && route.Inbound != types.RelayFormatOpenAI {
+			// 其他协议的流式响应都带 usage，需要上游回传
+			if payload, err = sjson.SetBytes(payload, "stream_options.include_usage", true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return payload, nil
+}
+
+func setRequestModel(request any, model string) {
+	switch req := request.(type) {
+	case *dto.GeneralOpenAIRequest:
+		req.Model = model
+	case *dto.OpenAIResponsesRequest:
+		req.Model = model
+	case *dto.ClaudeRequest:
+		req.Model = model
+	}
+}
+
+func upstreamURL(baseURL string, format types.RelayFormat, model string, stream bool) string {
+	switch format {
+	case types.RelayFormatOpenAIResponses:
+		return baseURL + "/v1/responses"
+	case types.RelayFormatClaude:
+		return baseURL + "/v1/messages"
+	case types.RelayFormatGemini:
+		if stream {
+			return fmt.Sprintf("%s/v1beta/models/%s:%s?alt=sse", baseURL, model, geminiActionStreamGen)
+		}
+		return fmt.Sprintf("%s/v1beta/models/%s:%s", baseURL, model, geminiActionGenerate)
+	default:
+		return baseURL + "/v1/chat/completions"
+	}
+}
+
+// clientAPIKey reads the caller's key from any of the headers the four
+// protocols use, so passthrough works regardless of the inbound format.
+func clientAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if key := r.Header.Get("x-api-key"); key != "" {
+		return key
+	}
+	if key := r.Header.Get("x-goog-api-key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("key")
+}
+
+func setUpstreamHeaders(req *http.Request, client *http.Request, format types.RelayFormat, upstream *UpstreamConfig, key string) {
+	req.Header.Set("Content-Type", contentTypeJSON)
+	switch format {
+	case types.RelayFormatClaude:
+		req.Header.Set("x-api-key", key)
+		version := client.Header.Get("anthropic-version")
+		if version == "" {
+			version = defaultAnthropicVersion
+		}
+		req.Header.Set("anthropic-version", version)
+		if beta := client.Header.Get("anthropic-beta"); beta != "" {
+			req.Header.Set("anthropic-beta", beta)
+		}
+	case types.RelayFormatGemini:
+		req.Header.Set("x-goog-api-key", key)
+	default:
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	for k, v := range upstream.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	for _, h := range []string{"Content-Type", "Cache-Control", "Retry-After"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func convertResponseBody(ctx context.Context, meta *convmeta.Values, route *RouteConfig, body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("read upstream response: %w", err)
+	}
+	var response any
+	switch route.Outbound {
+	case types.RelayFormatOpenAIResponses:
+		response = &dto.OpenAIResponsesResponse{}
+	case types.RelayFormatClaude:
+		response = &dto.ClaudeResponse{}
+	case types.RelayFormatGemini:
+		response = &dto.GeminiChatResponse{}
+	default:
+		response = &dto.OpenAITextResponse{}
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("decode upstream response: %w", err)
+	}
+	result, err := relayconvert.ConvertResponse(ctx, meta, route.Inbound, response)
+	if err != nil {
+		return nil, fmt.Errorf("convert response: %w", err)
+	}
+	return json.Marshal(result.Value)
+}
+
+// writeError writes a local error in the error shape of the inbound protocol.
+func writeError(w http.ResponseWriter, format types.RelayFormat, status int, message string) {
+	var body any
+	switch format {
+	case types.RelayFormatClaude:
+		body = map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "invalid_request_error", "message": message},
+		}
+	case types.RelayFormatGemini:
+		body = map[string]any{
+			"error": map[string]any{"code": status, "message": message, "status": http.StatusText(status)},
+		}
+	default:
+		body = map[string]any{
+			"error": map[string]any{"type": "invalid_request_error", "message": message},
+		}
+	}
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// eventName returns the SSE event name for formats that use named events.
+func eventName(format types.RelayFormat, data []byte) string {
+	if format != types.RelayFormatClaude && format != types.RelayFormatOpenAIResponses {
+		return ""
+	}
+	return gjson.GetBytes(data, "type").String()
+}
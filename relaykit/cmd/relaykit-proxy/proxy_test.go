@@ -0,0 +1,284 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newUpstream(t *testing.T, contentType, response string) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.path = r.URL.RequestURI()
+		captured.header = r.Header.Clone()
+		captured.body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+func newTestServer(t *testing.T, yaml string) *httptest.Server {
+	t.Helper()
+	cfg, err := ParseConfig([]byte(yaml))
+	require.NoError(t, err)
+	srv := httptest.NewServer(NewServer(cfg))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, url string, header map[string]string, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, data
+}
+
+func TestOpenAIToClaudeNonStream(t *testing.T) {
+	upstream, captured := newUpstream(t, "application/json", `{
+		"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",
+		"content":[{"type":"text","text":"hello"}],
+		"stop_reason":"end_turn","usage":{"input_tokens":7,"output_tokens":3}
+	}`)
+	proxy := newTestServer(t, `
+upstreams:
+  - name: anthropic
+    base_url: `+upstream.URL+`/
+    api_key: sk-static
+routes:
+  - path: /v1/chat/completions
+    inbound: openai
+    outbound: claude
+    upstream: anthropic
+    model_map:
+      gpt-4o: claude-sonnet-4-5
+    default_max_tokens: 1024
+`)
+
+	resp, body := post(t, proxy.URL+"/v1/chat/completions", map[string]string{"Authorization": "Bearer sk-client"},
+		`{"model":"gpt-4o","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hi"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	assert.Equal(t, "/v1/messages", captured.path)
+	assert.Equal(t, "sk-static", captured.header.Get("x-api-key"))
+	assert.Equal(t, defaultAnthropicVersion, captured.header.Get("anthropic-version"))
+	assert.Empty(t, captured.header.Get("Authorization"))
+	assert.Equal(t, "claude-sonnet-4-5", gjson.GetBytes(captured.body, "model").String())
+	assert.Equal(t, int64(1024), gjson.GetBytes(captured.body, "max_tokens").Int())
+	assert.False(t, gjson.GetBytes(captured.body, "stream").Bool())
+
+	assert.Equal(t, "chat.completion", gjson.GetBytes(body, "object").String())
+	assert.Equal(t, "hello", gjson.GetBytes(body, "choices.0.message.content").String())
+	assert.Equal(t, int64(10), gjson.GetBytes(body, "usage.total_tokens").Int())
+}
+
+func TestOpenAIToClaudeStream(t *testing.T) {
+	events := strings.Join([]string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":5,"output_tokens":0}}}`,
+		``,
+		`event: ping`,
+		`data: {"type":"ping"}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		``,
+		`event: content_block_stop`,
+		`data: {"type":"content_block_stop","index":0}`,
+		``,
+		`event: message_delta`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		``,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+		``,
+	}, "\n")
+	upstream, captured := newUpstream(t, "text/event-stream", events)
+	proxy := newTestServer(t, `
+upstreams:
+  - name: anthropic
+    base_url: `+upstream.URL+`
+routes:
+  - path: /v1/chat/completions
+    inbound: openai
+    outbound: claude
+    upstream: anthropic
+`)
+
+	resp, body := post(t, proxy.URL+"/v1/chat/completions", map[string]string{"Authorization": "Bearer sk-client"},
+		`{"model":"claude-sonnet-4-5","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// 未配置静态 key 时透传客户端 key
+	assert.Equal(t, "sk-client", captured.header.Get("x-api-key"))
+	assert.True(t, gjson.GetBytes(captured.body, "stream").Bool())
+
+	var text strings.Builder
+	var finish string
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.NotEmpty(t, lines)
+	assert.Equal(t, "data: [DONE]", lines[len(lines)-1])
+	for _, line := range lines {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		assert.Equal(t, "chat.completion.chunk", gjson.Get(data, "object").String())
+		text.WriteString(gjson.Get(data, "choices.0.delta.content").String())
+		if reason := gjson.Get(data, "choices.0.finish_reason").String(); reason != "" {
+			finish = reason
+		}
+	}
+	assert.Equal(t, "Hello", text.String())
+	assert.Equal(t, "stop", finish)
+}
+
+func TestGeminiInboundToOpenAI(t *testing.T) {
+	upstream, captured := newUpstream(t, "application/json", `{
+		"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",
+		"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+		"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}
+	}`)
+	proxy := newTestServer(t, `
+upstreams:
+  - name: openai
+    base_url: `+upstream.URL+`
+    headers:
+      OpenAI-Organization: org-test
+routes:
+  - path: /v1beta/models/
+    inbound: gemini
+    outbound: openai
+    upstream: openai
+`)
+
+	resp, body := post(t, proxy.URL+"/v1beta/models/gpt-4o:generateContent", map[string]string{"x-goog-api-key": "sk-client"},
+		`{"contents":[{"role":"user","parts":[{"text":"ping"}]}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	assert.Equal(t, "/v1/chat/completions", captured.path)
+	assert.Equal(t, "Bearer sk-client", captured.header.Get("Authorization"))
+	assert.Equal(t, "org-test", captured.header.Get("OpenAI-Organization"))
+	assert.Equal(t, "gpt-4o", gjson.GetBytes(captured.body, "model").String())
+	assert.Equal(t, "pong", gjson.GetBytes(body, "candidates.0.content.parts.0.text").String())
+}
+
+func TestRouteErrors(t *testing.T) {
+	upstream, _ := newUpstream(t, "application/json", `{}`)
+	proxy := newTestServer(t, `
+upstreams:
+  - name: anthropic
+    base_url: `+upstream.URL+`
+routes:
+  - path: /v1/messages
+    inbound: claude
+    outbound: openai
+    upstream: anthropic
+  - path: /v1beta/models/
+    inbound: gemini
+    outbound: openai
+    upstream: anthropic
+`)
+
+	resp, body := post(t, proxy.URL+"/v1/messages", nil, `{"model":"m","max_tokens":1,"messages":[]}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "error", gjson.GetBytes(body, "type").String())
+
+	resp, body = post(t, proxy.URL+"/v1/messages", map[string]string{"x-api-key": "k"}, `{"max_tokens":1}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, gjson.GetBytes(body, "error.message").String(), "model is required")
+
+	resp, body = post(t, proxy.URL+"/v1beta/models/gpt-4o:countTokens", map[string]string{"x-goog-api-key": "k"}, `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, int64(http.StatusBadRequest), gjson.GetBytes(body, "error.code").Int())
+}
+
+func TestUpstreamErrorPassthrough(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	}))
+	t.Cleanup(upstream.Close)
+	proxy := newTestServer(t, `
+upstreams:
+  - name: anthropic
+    base_url: `+upstream.URL+`
+    api_key: k
+routes:
+  - path: /v1/chat/completions
+    inbound: openai
+    outbound: claude
+    upstream: anthropic
+`)
+
+	resp, body := post(t, proxy.URL+"/v1/chat/completions", nil, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "rate_limit_error", gjson.GetBytes(body, "error.type").String())
+}
+
+func TestParseConfigValidation(t *testing.T) {
+	t.Setenv("RELAYKIT_TEST_KEY", "sk-env")
+	cfg, err := ParseConfig([]byte(`
+upstreams:
+  - name: a
+    base_url: https://api.example.com/
+    api_key: ${RELAYKIT_TEST_KEY}
+    timeout: 30s
+routes:
+  - path: /v1/chat/completions
+    inbound: openai
+    outbound: openai_responses
+    upstream: a
+`))
+	require.NoError(t, err)
+	assert.Equal(t, defaultListen, cfg.Listen)
+	assert.Equal(t, "https://api.example.com", cfg.Upstreams[0].BaseURL)
+	assert.Equal(t, "sk-env", cfg.Upstreams[0].APIKey)
+	assert.Equal(t, int64(defaultMaxMediaBytes), cfg.Media.MaxBytes)
+
+	cases := map[string]string{
+		"no upstream":      `routes: [{path: /a, inbound: openai, outbound: claude, upstream: a}]`,
+		"no route":         `upstreams: [{name: a, base_url: http://x}]`,
+		"unknown upstream": `{upstreams: [{name: a, base_url: http://x}], routes: [{path: /a, inbound: openai, outbound: claude, upstream: b}]}`,
+		"bad format":       `{upstreams: [{name: a, base_url: http://x}], routes: [{path: /a, inbound: openai, outbound: bedrock, upstream: a}]}`,
+		"relative path":    `{upstreams: [{name: a, base_url: http://x}], routes: [{path: a, inbound: openai, outbound: claude, upstream: a}]}`,
+		"duplicate name":   `{upstreams: [{name: a, base_url: http://x}, {name: a, base_url: http://y}], routes: [{path: /a, inbound: openai, outbound: claude, upstream: a}]}`,
+		"missing base_url": `{upstreams: [{name: a}], routes: [{path: /a, inbound: openai, outbound: claude, upstream: a}]}`,
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert/convmeta"
+	"github.com/QuantumNous/new-api/relaykit/types"
+
+	"github.com/google/uuid"
+)
+
+const maxStreamLineBytes = 16 << 20
+
+// sseWriter writes converted chunks to the client in the SSE framing of the
+// inbound protocol.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	format  types.RelayFormat
+}
+
+func (s *sseWriter) writeEvent(event string, data []byte) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteString("\n")
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	if _, err := io.WriteString(s.w, b.String()); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+func (s *sseWriter) writeResults(results []relayconvert.ResponseResult) error {
+	for _, result := range results {
+		if event, ok := result.Value.(relayconvert.ChatToResponsesStreamEvent); ok {
+			data, err := json.Marshal(event.Payload)
+			if err != nil {
+				return err
+			}
+			if err := s.writeEvent(event.Type, data); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.Marshal(result.Value)
+		if err != nil {
+			return err
+		}
+		if err := s.writeEvent(eventName(s.format, data), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamResponse converts an upstream SSE stream chunk by chunk. Once the
+// response headers are sent errors can only be logged.
+func streamResponse(ctx context.Context, w http.ResponseWriter, body io.Reader, meta *convmeta.Values, route *RouteConfig, in *inboundRequest) error {
+	state, err := relayconvert.NewResponseStreamState(route.Outbound, route.Inbound, relayconvert.ResponseStreamOptions{
+		ID:           streamID(route.Inbound),
+		Model:        in.model,
+		Created:      time.Now().Unix(),
+		IncludeUsage: in.includeUsage || route.Inbound != types.RelayFormatOpenAI,
+	})
+	if err != nil {
+		writeError(w, route.Inbound, http.StatusBadGateway, err.Error())
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	out := &sseWriter{w: w, flusher: flusher, format: route.Inbound}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		chunk, err := decodeStreamChunk(route.Outbound, data)
+		if err != nil {
+			return err
+		}
+		if chunk == nil {
+			continue
+		}
+		results, err := relayconvert.ConvertStreamResponseChunk(ctx, meta, state, chunk)
+		if err != nil {
+			return err
+		}
+		if err := out.writeResults(results); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	results, err := relayconvert.FinalizeStreamResponse(ctx, meta, state)
+	if err != nil {
+		return err
+	}
+	if err := out.writeResults(results); err != nil {
+		return err
+	}
+	if route.Inbound == types.RelayFormatOpenAI {
+		_, err = io.WriteString(w, "data: [DONE]\n\n")
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return err
+}
+
+// decodeStreamChunk decodes one upstream data line into the stream DTO of
+// the outbound protocol. Claude ping events carry no content and are dropped.
+func decodeStreamChunk(format types.RelayFormat, data string) (any, error) {
+	var chunk any
+	switch format {
+	case types.RelayFormatOpenAIResponses:
+		chunk = &dto.ResponsesStreamResponse{}
+	case types.RelayFormatClaude:
+		if strings.Contains(data, `"type":"ping"`) {
+			return nil, nil
+		}
+		chunk = &dto.ClaudeResponse{}
+	case types.RelayFormatGemini:
+		chunk = &dto.GeminiChatResponse{}
+	default:
+		chunk = &dto.ChatCompletionsStreamResponse{}
+	}
+	if err := json.Unmarshal([]byte(data), chunk); err != nil {
+		return nil, fmt.Errorf("decode upstream stream chunk: %w", err)
+	}
+	return chunk, nil
+}
+
+func streamID(format types.RelayFormat) string {
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")
+	switch format {
+	case types.RelayFormatOpenAIResponses:
+		return "resp_" + id
+	case types.RelayFormatClaude:
+		return "msg_" + id
+	default:
+		return "chatcmpl-" + id
+	}
+}
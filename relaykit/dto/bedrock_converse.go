@@ -0,0 +1,218 @@
+package dto
+
+import "encoding/json"
+
+// BedrockConverseRequest is the body of the AWS Bedrock Converse and
+// ConverseStream APIs. The model id and stream mode are carried by the
+// request path (/model/{modelId}/converse[-stream]), not by the body.
+type BedrockConverseRequest struct {
+	Messages                          []BedrockMessage        `json:"messages"`
+	System                            []BedrockSystemBlock    `json:"system,omitempty"`
+	InferenceConfig                   *BedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig                        *BedrockToolConfig      `json:"toolConfig,omitempty"`
+	AdditionalModelRequestFields      json.RawMessage         `json:"additionalModelRequestFields,omitempty"`
+	AdditionalModelResponseFieldPaths []string                `json:"additionalModelResponseFieldPaths,omitempty"`
+	RequestMetadata                   map[string]string       `json:"requestMetadata,omitempty"`
+}
+
+type BedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []BedrockContentBlock `json:"content"`
+}
+
+// BedrockContentBlock is a union; exactly one field is set.
+type BedrockContentBlock struct {
+	Text             *string                       `json:"text,omitempty"`
+	Image            *BedrockImageBlock            `json:"image,omitempty"`
+	Document         *BedrockDocumentBlock         `json:"document,omitempty"`
+	ToolUse          *BedrockToolUseBlock          `json:"toolUse,omitempty"`
+	ToolResult       *BedrockToolResultBlock       `json:"toolResult,omitempty"`
+	ReasoningContent *BedrockReasoningContentBlock `json:"reasoningContent,omitempty"`
+	CachePoint       *BedrockCachePointBlock       `json:"cachePoint,omitempty"`
+}
+
+type BedrockSystemBlock struct {
+	Text       *string                 `json:"text,omitempty"`
+	CachePoint *BedrockCachePointBlock `json:"cachePoint,omitempty"`
+}
+
+type BedrockCachePointBlock struct {
+	Type string `json:"type"`
+}
+
+type BedrockImageBlock struct {
+	// Format is one of png, jpeg, gif, webp.
+	Format string             `json:"format"`
+	Source BedrockBytesSource `json:"source"`
+}
+
+type BedrockDocumentBlock struct {
+	// Format is one of pdf, csv, doc, docx, xls, xlsx, html, txt, md.
+	Format string             `json:"format"`
+	Name   string             `json:"name"`
+	Source BedrockBytesSource `json:"source"`
+}
+
+// BedrockBytesSource carries base64-encoded bytes, as in the JSON wire format.
+type BedrockBytesSource struct {
+	Bytes string `json:"bytes"`
+}
+
+type BedrockToolUseBlock struct {
+	ToolUseId string `json:"toolUseId"`
+	Name      string `json:"name"`
+	Input     any    `json:"input"`
+}
+
+type BedrockToolResultBlock struct {
+	ToolUseId string                     `json:"toolUseId"`
+	Content   []BedrockToolResultContent `json:"content"`
+	// Status is success or error.
+	Status string `json:"status,omitempty"`
+}
+
+type BedrockToolResultContent struct {
+	Text     *string               `json:"text,omitempty"`
+	Json     any                   `json:"json,omitempty"`
+	Image    *BedrockImageBlock    `json:"image,omitempty"`
+	Document *BedrockDocumentBlock `json:"document,omitempty"`
+}
+
+type BedrockReasoningContentBlock struct {
+	ReasoningText   *BedrockReasoningText `json:"reasoningText,omitempty"`
+	RedactedContent string                `json:"redactedContent,omitempty"`
+}
+
+type BedrockReasoningText struct {
+	Text      string `json:"text"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type BedrockInferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type BedrockToolConfig struct {
+	Tools      []BedrockTool      `json:"tools"`
+	ToolChoice *BedrockToolChoice `json:"toolChoice,omitempty"`
+}
+
+type BedrockTool struct {
+	ToolSpec   *BedrockToolSpec        `json:"toolSpec,omitempty"`
+	CachePoint *BedrockCachePointBlock `json:"cachePoint,omitempty"`
+}
+
+type BedrockToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema BedrockToolInputSchema `json:"inputSchema"`
+}
+
+type BedrockToolInputSchema struct {
+	Json any `json:"json"`
+}
+
+// BedrockToolChoice is a union of auto, any and tool.
+type BedrockToolChoice struct {
+	Auto *struct{}                  `json:"auto,omitempty"`
+	Any  *struct{}                  `json:"any,omitempty"`
+	Tool *BedrockSpecificToolChoice `json:"tool,omitempty"`
+}
+
+type BedrockSpecificToolChoice struct {
+	Name string `json:"name"`
+}
+
+type BedrockConverseResponse struct {
+	Output                        BedrockConverseOutput `json:"output"`
+	StopReason                    string                `json:"stopReason"`
+	Usage                         *BedrockUsage         `json:"usage,omitempty"`
+	Metrics                       *BedrockMetrics       `json:"metrics,omitempty"`
+	AdditionalModelResponseFields json.RawMessage       `json:"additionalModelResponseFields,omitempty"`
+}
+
+type BedrockConverseOutput struct {
+	Message *BedrockMessage `json:"message,omitempty"`
+}
+
+// BedrockUsage reports input tokens excluding cache reads and writes, like
+// Anthropic Messages.
+type BedrockUsage struct {
+	InputTokens           int `json:"inputTokens"`
+	OutputTokens          int `json:"outputTokens"`
+	TotalTokens           int `json:"totalTokens"`
+	CacheReadInputTokens  int `json:"cacheReadInputTokens,omitempty"`
+	CacheWriteInputTokens int `json:"cacheWriteInputTokens,omitempty"`
+}
+
+type BedrockMetrics struct {
+	LatencyMs int64 `json:"latencyMs"`
+}
+
+// BedrockConverseStreamEvent is one ConverseStream event. The AWS event
+// stream frames each event with an :event-type header; in JSON form the
+// event is keyed by that type, so exactly one field is set.
+type BedrockConverseStreamEvent struct {
+	MessageStart      *BedrockMessageStartEvent      `json:"messageStart,omitempty"`
+	ContentBlockStart *BedrockContentBlockStartEvent `json:"contentBlockStart,omitempty"`
+	ContentBlockDelta *BedrockContentBlockDeltaEvent `json:"contentBlockDelta,omitempty"`
+	ContentBlockStop  *BedrockContentBlockStopEvent  `json:"contentBlockStop,omitempty"`
+	MessageStop       *BedrockMessageStopEvent       `json:"messageStop,omitempty"`
+	Metadata          *BedrockStreamMetadataEvent    `json:"metadata,omitempty"`
+}
+
+type BedrockMessageStartEvent struct {
+	Role string `json:"role"`
+}
+
+type BedrockContentBlockStartEvent struct {
+	ContentBlockIndex int                      `json:"contentBlockIndex"`
+	Start             BedrockContentBlockStart `json:"start"`
+}
+
+type BedrockContentBlockStart struct {
+	ToolUse *BedrockToolUseBlockStart `json:"toolUse,omitempty"`
+}
+
+type BedrockToolUseBlockStart struct {
+	ToolUseId string `json:"toolUseId"`
+	Name      string `json:"name"`
+}
+
+type BedrockContentBlockDeltaEvent struct {
+	ContentBlockIndex int                      `json:"contentBlockIndex"`
+	Delta             BedrockContentBlockDelta `json:"delta"`
+}
+
+type BedrockContentBlockDelta struct {
+	Text             *string                    `json:"text,omitempty"`
+	ToolUse          *BedrockToolUseBlockDelta  `json:"toolUse,omitempty"`
+	ReasoningContent *BedrockReasoningTextDelta `json:"reasoningContent,omitempty"`
+}
+
+type BedrockToolUseBlockDelta struct {
+	Input string `json:"input"`
+}
+
+type BedrockReasoningTextDelta struct {
+	Text            *string `json:"text,omitempty"`
+	Signature       *string `json:"signature,omitempty"`
+	RedactedContent string  `json:"redactedContent,omitempty"`
+}
+
+type BedrockContentBlockStopEvent struct {
+	ContentBlockIndex int `json:"contentBlockIndex"`
+}
+
+type BedrockMessageStopEvent struct {
+	StopReason                    string          `json:"stopReason"`
+	AdditionalModelResponseFields json.RawMessage `json:"additionalModelResponseFields,omitempty"`
+}
+
+type BedrockStreamMetadataEvent struct {
+	Usage   *BedrockUsage   `json:"usage,omitempty"`
+	Metrics *BedrockMetrics `json:"metrics,omitempty"`
+}
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"fmt"
+	"math"
 	"net/url"
 	"regexp"
 	"strings"
@@ -23,6 +24,9 @@ type ChannelSettings struct {
 	// HTTP2ConnectionShards spreads HTTP/2 traffic across N independent transports
 	// (1-8). Zero/unset means 1. Ignored when HTTPProtocol is "http1".
 	HTTP2ConnectionShards int `json:"http2_connection_shards,omitempty"`
+	// ImagePreprocess downscales/re-encodes request images before they are
+	// converted and sent upstream. Nil falls back to the global model policy.
+	ImagePreprocess *ImagePreprocessPolicy `json:"image_preprocess,omitempty"`
 }
 
 const (
@@ -51,6 +55,112 @@ func (s *ChannelSettings) ValidateHTTPTransport() error {
 	return nil
 }
 
+// ValidateImagePreprocess validates save-time image preprocessing settings.
+func (s *ChannelSettings) ValidateImagePreprocess() error {
+	if s == nil {
+		return nil
+	}
+	return s.ImagePreprocess.Validate()
+}
+
+const (
+	ImagePreprocessFormatKeep = ""
+	ImagePreprocessFormatJPEG = "jpeg"
+	ImagePreprocessFormatWebP = "webp"
+	ImagePreprocessFormatPNG  = "png"
+
+	DefaultImagePreprocessQuality = 85
+)
+
+// ImagePreprocessPolicy describes how request images are shrunk before relay.
+// ModelPatterns are regular expressions; empty means every model.
+// It covers chat completions, Claude messages, Responses and Gemini native
+// requests. Images referenced by provider file ids or URIs (Responses
+// file_id, Gemini fileData) and pass-through request bodies are forwarded
+// untouched.
+type ImagePreprocessPolicy struct {
+	Enabled       bool     `json:"enabled"`
+	ModelPatterns []string `json:"model_patterns,omitempty"`
+	MaxLongEdge   int      `json:"max_long_edge,omitempty"`
+	MaxPixels     int      `json:"max_pixels,omitempty"`
+	// Format is the target encoding: "" keeps the source format, otherwise
+	// "jpeg", "webp" (lossless) or "png".
+	Format string `json:"format,omitempty"`
+	// Quality is the JPEG quality (1-100). Zero uses DefaultImagePreprocessQuality.
+	Quality int `json:"quality,omitempty"`
+}
+
+// Validate validates save-time image preprocessing settings.
+func (p *ImagePreprocessPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Format)) {
+	case ImagePreprocessFormatKeep, ImagePreprocessFormatJPEG, ImagePreprocessFormatWebP, ImagePreprocessFormatPNG:
+	default:
+		return fmt.Errorf("invalid image_preprocess format: %s", p.Format)
+	}
+	if p.MaxLongEdge < 0 {
+		return fmt.Errorf("invalid image_preprocess max_long_edge: %d", p.MaxLongEdge)
+	}
+	if p.MaxPixels < 0 {
+		return fmt.Errorf("invalid image_preprocess max_pixels: %d", p.MaxPixels)
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("invalid image_preprocess quality: %d", p.Quality)
+	}
+	for _, pattern := range p.ModelPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid image_preprocess model pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// TargetFormat returns the normalized output format for a source format.
+func (p *ImagePreprocessPolicy) TargetFormat(sourceFormat string) string {
+	format := strings.ToLower(strings.TrimSpace(p.Format))
+	if format == ImagePreprocessFormatKeep {
+		format = strings.ToLower(sourceFormat)
+	}
+	return format
+}
+
+// GetQuality returns the effective JPEG quality.
+func (p *ImagePreprocessPolicy) GetQuality() int {
+	if p.Quality <= 0 || p.Quality > 100 {
+		return DefaultImagePreprocessQuality
+	}
+	return p.Quality
+}
+
+// TargetSize returns the dimensions an image of width x height is resized to.
+// The aspect ratio is preserved; ok is false when no downscaling is needed.
+func (p *ImagePreprocessPolicy) TargetSize(width, height int) (int, int, bool) {
+	if p == nil || width <= 0 || height <= 0 {
+		return width, height, false
+	}
+	scale := 1.0
+	if p.MaxLongEdge > 0 {
+		longEdge := max(width, height)
+		if longEdge > p.MaxLongEdge {
+			scale = float64(p.MaxLongEdge) / float64(longEdge)
+		}
+	}
+	if p.MaxPixels > 0 {
+		pixels := float64(width) * float64(height) * scale * scale
+		if pixels > float64(p.MaxPixels) {
+			scale *= math.Sqrt(float64(p.MaxPixels) / pixels)
+		}
+	}
+	if scale >= 1 {
+		return width, height, false
+	}
+	newWidth := max(1, int(math.Floor(float64(width)*scale)))
+	newHeight := max(1, int(math.Floor(float64(height)*scale)))
+	return newWidth, newHeight, true
+}
+
 type VertexKeyType string
 
 const (
@@ -78,6 +188,8 @@ type ChannelOtherSettings struct {
 	AllowIncludeObfuscation               bool                  `json:"allow_include_obfuscation,omitempty"`  // 是否允许 stream_options.include_obfuscation 透传（默认过滤以避免关闭流混淆保护）
 	DisableTaskPollingSleep               bool                  `json:"disable_task_polling_sleep,omitempty"` // 是否跳过异步任务轮询间隔
 	AwsKeyType                            AwsKeyType            `json:"aws_key_type,omitempty"`
+	BatchStorageURI                       string                `json:"batch_storage_uri,omitempty"`                          // Vertex / Bedrock 消息批处理的输入输出位置，gs://bucket/prefix 或 s3://bucket/prefix
+	BatchRoleArn                          string                `json:"batch_role_arn,omitempty"`                             // Bedrock 批处理作业读写 S3 使用的服务角色 ARN
 	UpstreamModelUpdateCheckEnabled       bool                  `json:"upstream_model_update_check_enabled,omitempty"`        // 是否检测上游模型更新
 	UpstreamModelUpdateAutoSyncEnabled    bool                  `json:"upstream_model_update_auto_sync_enabled,omitempty"`    // 是否自动同步上游模型更新
 	UpstreamModelUpdateLastCheckTime      int64                 `json:"upstream_model_update_last_check_time,omitempty"`      // 上次检测时间
@@ -103,6 +215,8 @@ const (
 	advancedCustomConverterOpenAIResponsesToGemini     = "openai_responses_to_gemini_generate_content"
 	advancedCustomConverterGeminiContentToOpenAIChat   = "gemini_generate_content_to_openai_chat_completions"
 	advancedCustomConverterOpenAIChatToGeminiContent   = "openai_chat_completions_to_gemini_generate_content"
+	advancedCustomConverterOpenAIChatToBedrockConverse = "openai_chat_completions_to_bedrock_converse"
+	advancedCustomConverterOpenAIChatToCohereChat      = "openai_chat_completions_to_cohere_chat"
 )
 
 const (
@@ -360,7 +474,9 @@ func IsAdvancedCustomConverterAllowed(converter string) bool {
 		advancedCustomConverterOpenAIResponsesToOpenAIChat,
 		advancedCustomConverterOpenAIResponsesToGemini,
 		advancedCustomConverterGeminiContentToOpenAIChat,
-		advancedCustomConverterOpenAIChatToGeminiContent:
+		advancedCustomConverterOpenAIChatToGeminiContent,
+		advancedCustomConverterOpenAIChatToBedrockConverse,
+		advancedCustomConverterOpenAIChatToCohereChat:
 		return true
 	default:
 		return false
@@ -552,7 +668,9 @@ func validateAdvancedCustomConverterPath(index int, incomingPath string, convert
 		}
 	case advancedCustomConverterOpenAIChatToClaudeMessages,
 		advancedCustomConverterOpenAIChatToOpenAIResponses,
-		advancedCustomConverterOpenAIChatToGeminiContent:
+		advancedCustomConverterOpenAIChatToGeminiContent,
+		advancedCustomConverterOpenAIChatToBedrockConverse,
+		advancedCustomConverterOpenAIChatToCohereChat:
 		if incomingPath == "/v1/chat/completions" {
 			return nil
 		}
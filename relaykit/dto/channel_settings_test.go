@@ -59,6 +59,47 @@ func TestAdvancedCustomValidateResponsesToChatConverterPath(t *testing.T) {
 	}
 }
 
+func TestAdvancedCustomValidateBedrockAndCohereConverterPaths(t *testing.T) {
+	tests := []struct {
+		name         string
+		converter    string
+		upstreamPath string
+	}{
+		{name: "bedrock converse", converter: advancedCustomConverterOpenAIChatToBedrockConverse, upstreamPath: "/model/{model}/converse"},
+		{name: "cohere chat", converter: advancedCustomConverterOpenAIChatToCohereChat, upstreamPath: "/v2/chat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.True(t, IsAdvancedCustomConverterAllowed(tt.converter))
+
+			valid := &AdvancedCustomConfig{
+				Routes: []AdvancedCustomRoute{
+					{
+						IncomingPath: "/v1/chat/completions",
+						UpstreamPath: tt.upstreamPath,
+						Converter:    tt.converter,
+					},
+				},
+			}
+			require.NoError(t, valid.Validate())
+
+			invalid := &AdvancedCustomConfig{
+				Routes: []AdvancedCustomRoute{
+					{
+						IncomingPath: "/v1/responses",
+						UpstreamPath: tt.upstreamPath,
+						Converter:    tt.converter,
+					},
+				},
+			}
+			err := invalid.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "converter does not match incoming_path")
+		})
+	}
+}
+
 func TestAdvancedCustomValidateModelListRouteConstraints(t *testing.T) {
 	valid := &AdvancedCustomConfig{
 		Routes: []AdvancedCustomRoute{
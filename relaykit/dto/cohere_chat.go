@@ -0,0 +1,162 @@
+package dto
+
+import "encoding/json"
+
+// CohereChatRequest is the body of Cohere's v2 Chat API (POST /v2/chat).
+type CohereChatRequest struct {
+	Model            string                `json:"model"`
+	Messages         []CohereMessage       `json:"messages"`
+	Tools            []CohereTool          `json:"tools,omitempty"`
+	Documents        json.RawMessage       `json:"documents,omitempty"`
+	Stream           *bool                 `json:"stream,omitempty"`
+	MaxTokens        *int                  `json:"max_tokens,omitempty"`
+	Temperature      *float64              `json:"temperature,omitempty"`
+	P                *float64              `json:"p,omitempty"`
+	K                *int                  `json:"k,omitempty"`
+	StopSequences    []string              `json:"stop_sequences,omitempty"`
+	Seed             *int                  `json:"seed,omitempty"`
+	FrequencyPenalty *float64              `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64              `json:"presence_penalty,omitempty"`
+	ResponseFormat   *CohereResponseFormat `json:"response_format,omitempty"`
+	// ToolChoice is REQUIRED or NONE; omitted means the model decides.
+	ToolChoice  string          `json:"tool_choice,omitempty"`
+	StrictTools *bool           `json:"strict_tools,omitempty"`
+	SafetyMode  string          `json:"safety_mode,omitempty"`
+	Thinking    *CohereThinking `json:"thinking,omitempty"`
+}
+
+// CohereMessage is a chat message. Content is a string or []CohereContent.
+type CohereMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content,omitempty"`
+	ToolCalls  []CohereToolCall `json:"tool_calls,omitempty"`
+	ToolPlan   string           `json:"tool_plan,omitempty"`
+	ToolCallId string           `json:"tool_call_id,omitempty"`
+}
+
+// ParseContent returns the content as a list of typed items, wrapping plain
+// string content in a single text item.
+func (m *CohereMessage) ParseContent() []CohereContent {
+	switch content := m.Content.(type) {
+	case nil:
+		return nil
+	case string:
+		if content == "" {
+			return nil
+		}
+		return []CohereContent{{Type: "text", Text: content}}
+	case []CohereContent:
+		return content
+	default:
+		data, err := json.Marshal(content)
+		if err != nil {
+			return nil
+		}
+		var items []CohereContent
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil
+		}
+		return items
+	}
+}
+
+type CohereContent struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Thinking string          `json:"thinking,omitempty"`
+	ImageUrl *CohereImageUrl `json:"image_url,omitempty"`
+	Document json.RawMessage `json:"document,omitempty"`
+}
+
+type CohereImageUrl struct {
+	Url    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type CohereToolCall struct {
+	Id       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function CohereFunctionCall `json:"function"`
+}
+
+type CohereFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type CohereTool struct {
+	Type     string             `json:"type"`
+	Function CohereToolFunction `json:"function"`
+}
+
+type CohereToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type CohereResponseFormat struct {
+	// Type is text or json_object.
+	Type       string `json:"type"`
+	JsonSchema any    `json:"json_schema,omitempty"`
+}
+
+type CohereThinking struct {
+	// Type is enabled or disabled.
+	Type        string `json:"type"`
+	TokenBudget *int   `json:"token_budget,omitempty"`
+}
+
+type CohereChatResponse struct {
+	Id           string                 `json:"id"`
+	FinishReason string                 `json:"finish_reason"`
+	Message      *CohereResponseMessage `json:"message,omitempty"`
+	Usage        *CohereUsage           `json:"usage,omitempty"`
+}
+
+type CohereResponseMessage struct {
+	Role      string           `json:"role"`
+	Content   []CohereContent  `json:"content,omitempty"`
+	ToolPlan  string           `json:"tool_plan,omitempty"`
+	ToolCalls []CohereToolCall `json:"tool_calls,omitempty"`
+	Citations json.RawMessage  `json:"citations,omitempty"`
+}
+
+// CohereUsage reports both billed units and raw token counts; the API
+// returns them as JSON numbers that may carry a fractional part.
+type CohereUsage struct {
+	BilledUnits  *CohereUsageUnits `json:"billed_units,omitempty"`
+	Tokens       *CohereUsageUnits `json:"tokens,omitempty"`
+	CachedTokens float64           `json:"cached_tokens,omitempty"`
+}
+
+type CohereUsageUnits struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+// CohereChatStreamEvent is one v2 chat stream event, discriminated by Type:
+// message-start, content-start, content-delta, content-end, tool-plan-delta,
+// tool-call-start, tool-call-delta, tool-call-end, citation-start,
+// citation-end and message-end.
+type CohereChatStreamEvent struct {
+	Type  string             `json:"type"`
+	Id    string             `json:"id,omitempty"`
+	Index *int               `json:"index,omitempty"`
+	Delta *CohereStreamDelta `json:"delta,omitempty"`
+}
+
+type CohereStreamDelta struct {
+	Message      *CohereStreamMessage `json:"message,omitempty"`
+	FinishReason string               `json:"finish_reason,omitempty"`
+	Usage        *CohereUsage         `json:"usage,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+type CohereStreamMessage struct {
+	Role      string          `json:"role,omitempty"`
+	Content   *CohereContent  `json:"content,omitempty"`
+	ToolPlan  string          `json:"tool_plan,omitempty"`
+	ToolCalls *CohereToolCall `json:"tool_calls,omitempty"`
+	Citations json.RawMessage `json:"citations,omitempty"`
+}
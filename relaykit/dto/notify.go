@@ -13,6 +13,10 @@ const (
 	NotifyTypeQuotaExceed   = "quota_exceed"
 	NotifyTypeChannelUpdate = "channel_update"
 	NotifyTypeChannelTest   = "channel_test"
+	NotifyTypeBudgetAlert   = "budget_alert"
+	NotifyTypeUsageAnomaly  = "usage_anomaly"
+	NotifyTypeTokenLeak     = "token_leak"
+	NotifyTypeTrafficSplit  = "traffic_split"
 )
 
 func NewNotify(t string, title string, content string, values []interface{}) Notify {
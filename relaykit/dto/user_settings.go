@@ -10,6 +10,10 @@ type UserSetting struct {
 	GotifyUrl                        string  `json:"gotify_url,omitempty"`                           // GotifyUrl Gotify服务器地址
 	GotifyToken                      string  `json:"gotify_token,omitempty"`                         // GotifyToken Gotify应用令牌
 	GotifyPriority                   int     `json:"gotify_priority"`                                // GotifyPriority Gotify消息优先级
+	ChatWebhookUrl                   string  `json:"chat_webhook_url,omitempty"`                     // ChatWebhookUrl Slack/Teams/Discord/飞书/钉钉机器人 Webhook 地址
+	ChatWebhookSecret                string  `json:"chat_webhook_secret,omitempty"`                  // ChatWebhookSecret 飞书/钉钉机器人签名密钥
+	TelegramBotToken                 string  `json:"telegram_bot_token,omitempty"`                   // TelegramBotToken Telegram 机器人令牌
+	TelegramChatId                   string  `json:"telegram_chat_id,omitempty"`                     // TelegramChatId Telegram 会话 ID
 	UpstreamModelUpdateNotifyEnabled bool    `json:"upstream_model_update_notify_enabled,omitempty"` // 是否接收上游模型更新定时检测通知（仅管理员）
 	AcceptUnsetRatioModel            bool    `json:"accept_unset_model_ratio_model,omitempty"`       // AcceptUnsetRatioModel 是否接受未设置价格的模型
 	RecordIpLog                      bool    `json:"record_ip_log,omitempty"`                        // 是否记录请求和错误日志IP
@@ -19,8 +23,14 @@ type UserSetting struct {
 }
 
 var (
-	NotifyTypeEmail   = "email"   // Email 邮件
-	NotifyTypeWebhook = "webhook" // Webhook
-	NotifyTypeBark    = "bark"    // Bark 推送
-	NotifyTypeGotify  = "gotify"  // Gotify 推送
+	NotifyTypeEmail    = "email"    // Email 邮件
+	NotifyTypeWebhook  = "webhook"  // Webhook
+	NotifyTypeBark     = "bark"     // Bark 推送
+	NotifyTypeGotify   = "gotify"   // Gotify 推送
+	NotifyTypeSlack    = "slack"    // Slack Incoming Webhook
+	NotifyTypeTeams    = "teams"    // Microsoft Teams Workflows Webhook
+	NotifyTypeDiscord  = "discord"  // Discord Webhook
+	NotifyTypeTelegram = "telegram" // Telegram 机器人
+	NotifyTypeFeishu   = "feishu"   // 飞书/Lark 自定义机器人
+	NotifyTypeDingTalk = "dingtalk" // 钉钉自定义机器人
 )
@@ -39,3 +39,61 @@ func OpenAIFinishReasonToClaudeStopReason(finishReason string) string {
 		return finishReason
 	}
 }
+
+func BedrockStopReasonToOpenAIFinishReason(stopReason string) string {
+	switch strings.ToLower(stopReason) {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens", "model_context_window_exceeded":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "guardrail_intervened", "content_filtered":
+		return types.FinishReasonContentFilter
+	default:
+		return stopReason
+	}
+}
+
+func OpenAIFinishReasonToBedrockStopReason(finishReason string) string {
+	switch strings.ToLower(finishReason) {
+	case "stop":
+		return "end_turn"
+	case "length", "max_tokens":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	case types.FinishReasonContentFilter:
+		return "content_filtered"
+	default:
+		return finishReason
+	}
+}
+
+func CohereFinishReasonToOpenAIFinishReason(finishReason string) string {
+	switch strings.ToUpper(finishReason) {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
+	default:
+		return finishReason
+	}
+}
+
+func OpenAIFinishReasonToCohereFinishReason(finishReason string) string {
+	switch strings.ToLower(finishReason) {
+	case "stop":
+		return "COMPLETE"
+	case "length", "max_tokens":
+		return "MAX_TOKENS"
+	case "tool_calls":
+		return "TOOL_CALL"
+	case types.FinishReasonContentFilter:
+		return "ERROR"
+	default:
+		return finishReason
+	}
+}
@@ -17,6 +17,10 @@ func GuessRelayFormatFromRequest(req any) (types.RelayFormat, bool) {
 		return types.RelayFormatClaude, true
 	case *dto.GeminiChatRequest, dto.GeminiChatRequest:
 		return types.RelayFormatGemini, true
+	case *dto.BedrockConverseRequest, dto.BedrockConverseRequest:
+		return types.RelayFormatBedrockConverse, true
+	case *dto.CohereChatRequest, dto.CohereChatRequest:
+		return types.RelayFormatCohereChat, true
 	case *dto.EmbeddingRequest, dto.EmbeddingRequest:
 		return types.RelayFormatEmbedding, true
 	case *dto.RerankRequest, dto.RerankRequest:
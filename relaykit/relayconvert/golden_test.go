@@ -173,11 +173,51 @@ func fixtureRequests() map[types.RelayFormat]any {
 		"tools": [{"type": "function", "name": "get_weather", "description": "Get weather by city", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}}]
 	}`, responses)
 
+	bedrock := &dto.BedrockConverseRequest{}
+	mustUnmarshalFixture(`{
+		"system": [{"text": "You are a helpful assistant."}],
+		"messages": [
+			{"role": "user", "content": [
+				{"text": "What is in this image?"},
+				{"image": {"format": "png", "source": {"bytes": "aGVsbG8="}}}
+			]},
+			{"role": "assistant", "content": [
+				{"reasoningContent": {"reasoningText": {"text": "Let me look.", "signature": "sig"}}},
+				{"toolUse": {"toolUseId": "tooluse_abc", "name": "get_weather", "input": {"city": "Paris"}}}
+			]},
+			{"role": "user", "content": [{"toolResult": {"toolUseId": "tooluse_abc", "content": [{"text": "15 degrees"}]}}]}
+		],
+		"inferenceConfig": {"maxTokens": 1024, "temperature": 0.7},
+		"toolConfig": {
+			"tools": [{"toolSpec": {"name": "get_weather", "description": "Get weather by city", "inputSchema": {"json": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}}}}],
+			"toolChoice": {"auto": {}}
+		}
+	}`, bedrock)
+
+	cohere := &dto.CohereChatRequest{}
+	mustUnmarshalFixture(`{
+		"model": "command-test",
+		"stream": true,
+		"max_tokens": 1024,
+		"messages": [
+			{"role": "system", "content": "You are a helpful assistant."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is in this image?"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}
+			]},
+			{"role": "assistant", "tool_plan": "I will check the weather.", "tool_calls": [{"id": "call_abc", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_abc", "content": "15 degrees"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "description": "Get weather by city", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}}}]
+	}`, cohere)
+
 	return map[types.RelayFormat]any{
 		types.RelayFormatOpenAI:          openai,
 		types.RelayFormatClaude:          claude,
 		types.RelayFormatGemini:          gemini,
 		types.RelayFormatOpenAIResponses: responses,
+		types.RelayFormatBedrockConverse: bedrock,
+		types.RelayFormatCohereChat:      cohere,
 	}
 }
 
@@ -254,11 +294,38 @@ func fixtureResponses() map[types.RelayFormat]any {
 		"usage": {"input_tokens": 10, "output_tokens": 5, "total_tokens": 15}
 	}`, responses)
 
+	bedrock := &dto.BedrockConverseResponse{}
+	mustUnmarshalFixture(`{
+		"output": {"message": {"role": "assistant", "content": [
+			{"reasoningContent": {"reasoningText": {"text": "Deep thought.", "signature": "sig"}}},
+			{"text": "The answer is 42."},
+			{"toolUse": {"toolUseId": "tooluse_abc", "name": "get_weather", "input": {"city": "Paris"}}}
+		]}},
+		"stopReason": "tool_use",
+		"usage": {"inputTokens": 5, "outputTokens": 5, "totalTokens": 15, "cacheReadInputTokens": 3, "cacheWriteInputTokens": 2},
+		"metrics": {"latencyMs": 120}
+	}`, bedrock)
+
+	cohere := &dto.CohereChatResponse{}
+	mustUnmarshalFixture(`{
+		"id": "cohere_fixed",
+		"finish_reason": "TOOL_CALL",
+		"message": {
+			"role": "assistant",
+			"tool_plan": "Deep thought.",
+			"content": [{"type": "text", "text": "The answer is 42."}],
+			"tool_calls": [{"id": "call_abc", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}]
+		},
+		"usage": {"billed_units": {"input_tokens": 10, "output_tokens": 5}, "tokens": {"input_tokens": 12, "output_tokens": 5}, "cached_tokens": 3}
+	}`, cohere)
+
 	return map[types.RelayFormat]any{
 		types.RelayFormatOpenAI:          openai,
 		types.RelayFormatClaude:          claude,
 		types.RelayFormatGemini:          gemini,
 		types.RelayFormatOpenAIResponses: responses,
+		types.RelayFormatBedrockConverse: bedrock,
+		types.RelayFormatCohereChat:      cohere,
 	}
 }
 
@@ -290,6 +357,22 @@ func fixtureStreamChunks() map[types.RelayFormat][]any {
 			responsesStreamChunk(`{"type":"response.output_text.delta","delta":" world"}`),
 			responsesStreamChunk(`{"type":"response.completed","response":{"id":"resp_fixed","object":"response","status":"completed","model":"gpt-test","usage":{"input_tokens":4,"output_tokens":2,"total_tokens":6}}}`),
 		},
+		types.RelayFormatBedrockConverse: {
+			bedrockStreamChunk(`{"messageStart":{"role":"assistant"}}`),
+			bedrockStreamChunk(`{"contentBlockDelta":{"contentBlockIndex":0,"delta":{"text":"Hello"}}}`),
+			bedrockStreamChunk(`{"contentBlockDelta":{"contentBlockIndex":0,"delta":{"text":" world"}}}`),
+			bedrockStreamChunk(`{"contentBlockStop":{"contentBlockIndex":0}}`),
+			bedrockStreamChunk(`{"messageStop":{"stopReason":"end_turn"}}`),
+			bedrockStreamChunk(`{"metadata":{"usage":{"inputTokens":4,"outputTokens":2,"totalTokens":6},"metrics":{"latencyMs":80}}}`),
+		},
+		types.RelayFormatCohereChat: {
+			cohereStreamChunk(`{"type":"message-start","id":"cohere_fixed","delta":{"message":{"role":"assistant"}}}`),
+			cohereStreamChunk(`{"type":"content-start","index":0,"delta":{"message":{"content":{"type":"text","text":""}}}}`),
+			cohereStreamChunk(`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hello"}}}}`),
+			cohereStreamChunk(`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":" world"}}}}`),
+			cohereStreamChunk(`{"type":"content-end","index":0}`),
+			cohereStreamChunk(`{"type":"message-end","delta":{"finish_reason":"COMPLETE","usage":{"billed_units":{"input_tokens":4,"output_tokens":2},"tokens":{"input_tokens":4,"output_tokens":2}}}}`),
+		},
 	}
 }
 
@@ -303,6 +386,8 @@ func allFormats() []types.RelayFormat {
 		types.RelayFormatClaude,
 		types.RelayFormatGemini,
 		types.RelayFormatOpenAIResponses,
+		types.RelayFormatBedrockConverse,
+		types.RelayFormatCohereChat,
 	}
 }
 
@@ -438,6 +523,30 @@ func deepCopyFixture(t *testing.T, v any) any {
 		out := &dto.ResponsesStreamResponse{}
 		require.NoError(t, json.Unmarshal(data, out))
 		return out
+	case *dto.BedrockConverseRequest:
+		out := &dto.BedrockConverseRequest{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
+	case *dto.CohereChatRequest:
+		out := &dto.CohereChatRequest{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
+	case *dto.BedrockConverseResponse:
+		out := &dto.BedrockConverseResponse{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
+	case *dto.CohereChatResponse:
+		out := &dto.CohereChatResponse{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
+	case *dto.BedrockConverseStreamEvent:
+		out := &dto.BedrockConverseStreamEvent{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
+	case *dto.CohereChatStreamEvent:
+		out := &dto.CohereChatStreamEvent{}
+		require.NoError(t, json.Unmarshal(data, out))
+		return out
 	default:
 		t.Fatalf("deepCopyFixture: unsupported fixture type %T", v)
 		return nil
@@ -468,6 +577,18 @@ func responsesStreamChunk(raw string) *dto.ResponsesStreamResponse {
 	return &r
 }
 
+func bedrockStreamChunk(raw string) *dto.BedrockConverseStreamEvent {
+	var r dto.BedrockConverseStreamEvent
+	mustUnmarshalFixture(raw, &r)
+	return &r
+}
+
+func cohereStreamChunk(raw string) *dto.CohereChatStreamEvent {
+	var r dto.CohereChatStreamEvent
+	mustUnmarshalFixture(raw, &r)
+	return &r
+}
+
 func mustUnmarshalFixture(raw string, out any) {
 	if err := json.Unmarshal([]byte(raw), out); err != nil {
 		panic(fmt.Sprintf("bad fixture JSON: %v", err))
@@ -0,0 +1,219 @@
+package bedrockconverse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert/convmeta"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert/internal/jsonutil"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+)
+
+// BedrockConverseRequestToOpenAIChat converts a Converse request body. The
+// body carries neither model nor stream mode, so both come from the relay
+// meta, as with Gemini.
+func BedrockConverseRequestToOpenAIChat(bedrockRequest *dto.BedrockConverseRequest, info convmeta.Meta) (*dto.GeneralOpenAIRequest, error) {
+	isStream := false
+	if info != nil {
+		isStream = info.GetIsStream()
+	}
+	openAIRequest := &dto.GeneralOpenAIRequest{
+		Model:  convmeta.UpstreamModelName(info),
+		Stream: kitutil.GetPointer(isStream),
+	}
+
+	messages := make([]dto.Message, 0, len(bedrockRequest.Messages)+1)
+	if system := bedrockSystemText(bedrockRequest.System); system != "" {
+		messages = append(messages, dto.Message{Role: "system", Content: system})
+	}
+	for _, bedrockMessage := range bedrockRequest.Messages {
+		messages = append(messages, bedrockMessageToOpenAI(bedrockMessage)...)
+	}
+	openAIRequest.Messages = messages
+
+	if config := bedrockRequest.InferenceConfig; config != nil {
+		if config.MaxTokens != nil && *config.MaxTokens > 0 {
+			openAIRequest.MaxTokens = kitutil.GetPointer(uint(*config.MaxTokens))
+		}
+		openAIRequest.Temperature = config.Temperature
+		openAIRequest.TopP = config.TopP
+		if len(config.StopSequences) > 0 {
+			openAIRequest.Stop = config.StopSequences
+		}
+	}
+
+	if len(bedrockRequest.AdditionalModelRequestFields) > 0 {
+		var additional struct {
+			TopK *int `json:"top_k"`
+		}
+		if err := kitutil.Unmarshal(bedrockRequest.AdditionalModelRequestFields, &additional); err == nil && additional.TopK != nil {
+			openAIRequest.TopK = additional.TopK
+		}
+	}
+
+	if toolConfig := bedrockRequest.ToolConfig; toolConfig != nil {
+		tools := make([]dto.ToolCallRequest, 0, len(toolConfig.Tools))
+		for _, tool := range toolConfig.Tools {
+			if tool.ToolSpec == nil {
+				continue
+			}
+			tools = append(tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        tool.ToolSpec.Name,
+					Description: tool.ToolSpec.Description,
+					Parameters:  tool.ToolSpec.InputSchema.Json,
+				},
+			})
+		}
+		if len(tools) > 0 {
+			openAIRequest.Tools = tools
+			openAIRequest.ToolChoice = bedrockToolChoiceToOpenAI(toolConfig.ToolChoice)
+		}
+	}
+
+	return openAIRequest, nil
+}
+
+func bedrockSystemText(blocks []dto.BedrockSystemBlock) string {
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Text != nil && *block.Text != "" {
+			texts = append(texts, *block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// bedrockMessageToOpenAI splits tool results out of a user turn into
+// separate tool messages, placed first so they directly follow the
+// assistant tool calls they answer.
+func bedrockMessageToOpenAI(bedrockMessage dto.BedrockMessage) []dto.Message {
+	var (
+		messages      []dto.Message
+		mediaContents []dto.MediaContent
+		toolCalls     []dto.ToolCallRequest
+		reasoning     strings.Builder
+	)
+	for i, block := range bedrockMessage.Content {
+		switch {
+		case block.Text != nil:
+			if *block.Text != "" {
+				mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: *block.Text})
+			}
+		case block.Image != nil:
+			mimeType := "image/" + block.Image.Format
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeImageURL,
+				ImageUrl: &dto.MessageImageUrl{
+					Url:      fmt.Sprintf("data:%s;base64,%s", mimeType, block.Image.Source.Bytes),
+					Detail:   "auto",
+					MimeType: mimeType,
+				},
+			})
+		case block.Document != nil:
+			name := block.Document.Name
+			if name == "" {
+				name = fmt.Sprintf("document-%d", i+1)
+			}
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeFile,
+				File: &dto.MessageFile{
+					FileName: fmt.Sprintf("%s.%s", name, block.Document.Format),
+					FileData: fmt.Sprintf("data:%s;base64,%s", bedrockDocumentMimeType(block.Document.Format), block.Document.Source.Bytes),
+				},
+			})
+		case block.ToolUse != nil:
+			toolCalls = append(toolCalls, dto.ToolCallRequest{
+				ID:   block.ToolUse.ToolUseId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      block.ToolUse.Name,
+					Arguments: jsonutil.ToJSONString(block.ToolUse.Input),
+				},
+			})
+		case block.ToolResult != nil:
+			toolMessage := dto.Message{
+				Role:       "tool",
+				ToolCallId: block.ToolResult.ToolUseId,
+			}
+			toolMessage.SetStringContent(bedrockToolResultText(block.ToolResult))
+			messages = append(messages, toolMessage)
+		case block.ReasoningContent != nil:
+			if block.ReasoningContent.ReasoningText != nil {
+				reasoning.WriteString(block.ReasoningContent.ReasoningText.Text)
+			}
+		}
+	}
+
+	message := dto.Message{Role: bedrockMessage.Role}
+	if len(toolCalls) > 0 {
+		message.SetToolCalls(toolCalls)
+	}
+	if reasoning.Len() > 0 {
+		message.ReasoningContent = kitutil.GetPointer(reasoning.String())
+	}
+	if len(mediaContents) == 1 && mediaContents[0].Type == dto.ContentTypeText {
+		message.SetStringContent(mediaContents[0].Text)
+	} else if len(mediaContents) > 0 {
+		message.SetMediaContent(mediaContents)
+	}
+	if len(mediaContents) > 0 || len(toolCalls) > 0 {
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func bedrockToolResultText(result *dto.BedrockToolResultBlock) string {
+	parts := make([]string, 0, len(result.Content))
+	for _, content := range result.Content {
+		if content.Text != nil {
+			parts = append(parts, *content.Text)
+		} else if content.Json != nil {
+			parts = append(parts, jsonutil.ToJSONString(content.Json))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func bedrockToolChoiceToOpenAI(choice *dto.BedrockToolChoice) any {
+	if choice == nil {
+		return nil
+	}
+	switch {
+	case choice.Any != nil:
+		return "required"
+	case choice.Tool != nil:
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": choice.Tool.Name},
+		}
+	case choice.Auto != nil:
+		return "auto"
+	}
+	return nil
+}
+
+func bedrockDocumentMimeType(format string) string {
+	switch format {
+	case "pdf":
+		return "application/pdf"
+	case "csv":
+		return "text/csv"
+	case "doc":
+		return "application/msword"
+	case "docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case "xls":
+		return "application/vnd.ms-excel"
+	case "xlsx":
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case "html":
+		return "text/html"
+	case "md":
+		return "text/markdown"
+	default:
+		return "text/plain"
+	}
+}
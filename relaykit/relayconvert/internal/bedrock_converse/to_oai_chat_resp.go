@@ -0,0 +1,222 @@
+package bedrockconverse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/reasonmap"
+	"github.com/QuantumNous/new-api/relaykit/relayconvert/internal/jsonutil"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+	"github.com/QuantumNous/new-api/relaykit/types"
+)
+
+// UsageFromBedrockUsage maps Converse usage onto OpenAI semantics: Bedrock
+// reports inputTokens without cache reads and writes, so both are folded
+// back into prompt_tokens and broken out in the prompt token details.
+func UsageFromBedrockUsage(usage *dto.BedrockUsage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	promptTokens := usage.InputTokens + usage.CacheReadInputTokens + usage.CacheWriteInputTokens
+	totalTokens := usage.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = promptTokens + usage.OutputTokens
+	}
+	mapped := &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      totalTokens,
+		InputTokens:      promptTokens,
+		OutputTokens:     usage.OutputTokens,
+	}
+	mapped.PromptTokensDetails.CachedTokens = usage.CacheReadInputTokens
+	mapped.PromptTokensDetails.CachedCreationTokens = usage.CacheWriteInputTokens
+	mapped.BillingUsage = dto.NewOpenAIChatBillingUsage(mapped)
+	return mapped
+}
+
+func ResponseBedrockConverse2OpenAI(id string, created int64, response *dto.BedrockConverseResponse) *dto.OpenAITextResponse {
+	choice := dto.OpenAITextResponseChoice{
+		Message: dto.Message{
+			Role:    "assistant",
+			Content: "",
+		},
+		FinishReason: reasonmap.BedrockStopReasonToOpenAIFinishReason(response.StopReason),
+	}
+	if choice.FinishReason == "" {
+		choice.FinishReason = types.FinishReasonStop
+	}
+
+	if message := response.Output.Message; message != nil {
+		var (
+			content   strings.Builder
+			reasoning strings.Builder
+			toolCalls []dto.ToolCallResponse
+		)
+		for _, block := range message.Content {
+			switch {
+			case block.Text != nil:
+				content.WriteString(*block.Text)
+			case block.ReasoningContent != nil && block.ReasoningContent.ReasoningText != nil:
+				reasoning.WriteString(block.ReasoningContent.ReasoningText.Text)
+			case block.ToolUse != nil:
+				toolCalls = append(toolCalls, dto.ToolCallResponse{
+					ID:   block.ToolUse.ToolUseId,
+					Type: "function",
+					Function: dto.FunctionResponse{
+						Name:      block.ToolUse.Name,
+						Arguments: jsonutil.ToJSONString(block.ToolUse.Input),
+					},
+				})
+			}
+		}
+		choice.Message.SetStringContent(content.String())
+		if reasoning.Len() > 0 {
+			choice.Message.ReasoningContent = kitutil.GetPointer(reasoning.String())
+		}
+		if len(toolCalls) > 0 {
+			choice.Message.SetToolCalls(toolCalls)
+			choice.FinishReason = types.FinishReasonToolCalls
+		}
+	}
+
+	openAIResponse := &dto.OpenAITextResponse{
+		Id:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Choices: []dto.OpenAITextResponseChoice{choice},
+	}
+	if usage := UsageFromBedrockUsage(response.Usage); usage != nil {
+		openAIResponse.Usage = *usage
+	}
+	return openAIResponse
+}
+
+// BedrockToChatStreamState converts ConverseStream events. Bedrock sends the
+// stop reason in messageStop and usage in the trailing metadata event, so the
+// finish chunk is held until metadata arrives or the stream is finalized.
+type BedrockToChatStreamState struct {
+	id            string
+	created       int64
+	toolIndexes   map[int]int
+	stopReason    string
+	finishEmitted bool
+	latestUsage   *dto.Usage
+}
+
+func NewBedrockToChatStreamState(id string, created int64) *BedrockToChatStreamState {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		id = fmt.Sprintf("chatcmpl-%s", kitutil.GetUUID())
+	}
+	if created == 0 {
+		created = kitutil.GetTimestamp()
+	}
+	return &BedrockToChatStreamState{id: id, created: created, toolIndexes: make(map[int]int)}
+}
+
+func (s *BedrockToChatStreamState) ConvertEvent(event *dto.BedrockConverseStreamEvent, model string) []*dto.ChatCompletionsStreamResponse {
+	if s == nil || event == nil {
+		return nil
+	}
+	switch {
+	case event.MessageStart != nil:
+		return []*dto.ChatCompletionsStreamResponse{s.chunk(model, dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"})}
+	case event.ContentBlockStart != nil:
+		toolUse := event.ContentBlockStart.Start.ToolUse
+		if toolUse == nil {
+			return nil
+		}
+		index := len(s.toolIndexes)
+		s.toolIndexes[event.ContentBlockStart.ContentBlockIndex] = index
+		toolCall := dto.ToolCallResponse{
+			ID:   toolUse.ToolUseId,
+			Type: "function",
+			Function: dto.FunctionResponse{
+				Name: toolUse.Name,
+			},
+		}
+		toolCall.SetIndex(index)
+		return []*dto.ChatCompletionsStreamResponse{s.chunk(model, dto.ChatCompletionsStreamResponseChoiceDelta{
+			ToolCalls: []dto.ToolCallResponse{toolCall},
+		})}
+	case event.ContentBlockDelta != nil:
+		delta := event.ContentBlockDelta.Delta
+		var chatDelta dto.ChatCompletionsStreamResponseChoiceDelta
+		switch {
+		case delta.Text != nil:
+			chatDelta.SetContentString(*delta.Text)
+		case delta.ReasoningContent != nil && delta.ReasoningContent.Text != nil:
+			chatDelta.SetReasoningContent(*delta.ReasoningContent.Text)
+		case delta.ToolUse != nil:
+			index, ok := s.toolIndexes[event.ContentBlockDelta.ContentBlockIndex]
+			if !ok {
+				return nil
+			}
+			toolCall := dto.ToolCallResponse{
+				Function: dto.FunctionResponse{
+					Arguments: delta.ToolUse.Input,
+				},
+			}
+			toolCall.SetIndex(index)
+			chatDelta.ToolCalls = []dto.ToolCallResponse{toolCall}
+		default:
+			return nil
+		}
+		return []*dto.ChatCompletionsStreamResponse{s.chunk(model, chatDelta)}
+	case event.MessageStop != nil:
+		s.stopReason = event.MessageStop.StopReason
+		return nil
+	case event.Metadata != nil:
+		if usage := UsageFromBedrockUsage(event.Metadata.Usage); usage != nil {
+			s.latestUsage = usage
+		}
+		if s.finishEmitted {
+			return nil
+		}
+		return []*dto.ChatCompletionsStreamResponse{s.terminalChunk(model)}
+	}
+	return nil
+}
+
+func (s *BedrockToChatStreamState) Finalize(model string) []*dto.ChatCompletionsStreamResponse {
+	if s == nil || s.finishEmitted {
+		return nil
+	}
+	return []*dto.ChatCompletionsStreamResponse{s.terminalChunk(model)}
+}
+
+func (s *BedrockToChatStreamState) Usage() *dto.Usage {
+	if s == nil {
+		return nil
+	}
+	return s.latestUsage
+}
+
+func (s *BedrockToChatStreamState) chunk(model string, delta dto.ChatCompletionsStreamResponseChoiceDelta) *dto.ChatCompletionsStreamResponse {
+	return &dto.ChatCompletionsStreamResponse{
+		Id:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   model,
+		Choices: []dto.ChatCompletionsStreamResponseChoice{
+			{Delta: delta},
+		},
+	}
+}
+
+func (s *BedrockToChatStreamState) terminalChunk(model string) *dto.ChatCompletionsStreamResponse {
+	finishReason := reasonmap.BedrockStopReasonToOpenAIFinishReason(s.stopReason)
+	if finishReason == "" {
+		finishReason = types.FinishReasonStop
+		if len(s.toolIndexes) > 0 {
+			finishReason = types.FinishReasonToolCalls
+		}
+	}
+	s.finishEmitted = true
+	response := s.chunk(model, dto.ChatCompletionsStreamResponseChoiceDelta{})
+	response.Choices[0].FinishReason = &finishReason
+	response.Usage = s.latestUsage
+	return response
+}
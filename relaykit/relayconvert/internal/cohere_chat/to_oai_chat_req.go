@@ -0,0 +1,135 @@
+package coherechat
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+)
+
+func CohereChatRequestToOpenAIChat(cohereRequest *dto.CohereChatRequest) (*dto.GeneralOpenAIRequest, error) {
+	openAIRequest := &dto.GeneralOpenAIRequest{
+		Model:            cohereRequest.Model,
+		Temperature:      cohereRequest.Temperature,
+		TopP:             cohereRequest.P,
+		TopK:             cohereRequest.K,
+		FrequencyPenalty: cohereRequest.FrequencyPenalty,
+		PresencePenalty:  cohereRequest.PresencePenalty,
+	}
+	if cohereRequest.Stream != nil {
+		openAIRequest.Stream = kitutil.GetPointer(*cohereRequest.Stream)
+	}
+	if cohereRequest.MaxTokens != nil && *cohereRequest.MaxTokens > 0 {
+		openAIRequest.MaxTokens = kitutil.GetPointer(uint(*cohereRequest.MaxTokens))
+	}
+	if len(cohereRequest.StopSequences) > 0 {
+		openAIRequest.Stop = cohereRequest.StopSequences
+	}
+	if cohereRequest.Seed != nil {
+		openAIRequest.Seed = kitutil.GetPointer(float64(*cohereRequest.Seed))
+	}
+
+	messages := make([]dto.Message, 0, len(cohereRequest.Messages))
+	for _, cohereMessage := range cohereRequest.Messages {
+		messages = append(messages, cohereMessageToOpenAI(cohereMessage))
+	}
+	openAIRequest.Messages = messages
+
+	if len(cohereRequest.Tools) > 0 {
+		tools := make([]dto.ToolCallRequest, 0, len(cohereRequest.Tools))
+		for _, tool := range cohereRequest.Tools {
+			tools = append(tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        tool.Function.Name,
+					Description: tool.Function.Description,
+					Parameters:  tool.Function.Parameters,
+				},
+			})
+		}
+		openAIRequest.Tools = tools
+	}
+	switch strings.ToUpper(cohereRequest.ToolChoice) {
+	case "REQUIRED":
+		openAIRequest.ToolChoice = "required"
+	case "NONE":
+		openAIRequest.ToolChoice = "none"
+	}
+
+	if format := cohereRequest.ResponseFormat; format != nil && format.Type == "json_object" {
+		if format.JsonSchema == nil {
+			openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: "json_object"}
+		} else {
+			schema, err := kitutil.Marshal(dto.FormatJsonSchema{
+				Name:   "response",
+				Schema: format.JsonSchema,
+			})
+			if err != nil {
+				return nil, err
+			}
+			openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: "json_schema", JsonSchema: schema}
+		}
+	}
+
+	return openAIRequest, nil
+}
+
+func cohereMessageToOpenAI(cohereMessage dto.CohereMessage) dto.Message {
+	message := dto.Message{
+		Role:       cohereMessage.Role,
+		ToolCallId: cohereMessage.ToolCallId,
+	}
+
+	var (
+		mediaContents []dto.MediaContent
+		reasoning     strings.Builder
+	)
+	for _, item := range cohereMessage.ParseContent() {
+		switch item.Type {
+		case "text":
+			mediaContents = append(mediaContents, dto.MediaContent{Type: dto.ContentTypeText, Text: item.Text})
+		case "image_url":
+			if item.ImageUrl != nil {
+				mediaContents = append(mediaContents, dto.MediaContent{
+					Type: dto.ContentTypeImageURL,
+					ImageUrl: &dto.MessageImageUrl{
+						Url:    item.ImageUrl.Url,
+						Detail: item.ImageUrl.Detail,
+					},
+				})
+			}
+		case "thinking":
+			reasoning.WriteString(item.Thinking)
+		}
+	}
+	if reasoning.Len() == 0 && cohereMessage.ToolPlan != "" {
+		reasoning.WriteString(cohereMessage.ToolPlan)
+	}
+	if reasoning.Len() > 0 {
+		message.ReasoningContent = kitutil.GetPointer(reasoning.String())
+	}
+
+	if len(cohereMessage.ToolCalls) > 0 {
+		toolCalls := make([]dto.ToolCallRequest, 0, len(cohereMessage.ToolCalls))
+		for _, toolCall := range cohereMessage.ToolCalls {
+			toolCalls = append(toolCalls, dto.ToolCallRequest{
+				ID:   toolCall.Id,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				},
+			})
+		}
+		message.SetToolCalls(toolCalls)
+	}
+
+	if len(mediaContents) == 1 && mediaContents[0].Type == dto.ContentTypeText {
+		message.SetStringContent(mediaContents[0].Text)
+	} else if len(mediaContents) > 0 {
+		message.SetMediaContent(mediaContents)
+	} else {
+		message.SetStringContent("")
+	}
+	return message
+}
@@ -0,0 +1,246 @@
+package coherechat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/reasonmap"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+	"github.com/QuantumNous/new-api/relaykit/types"
+)
+
+// UsageFromCohereUsage prefers billed units, which are what Cohere charges
+// for, and falls back to raw token counts.
+func UsageFromCohereUsage(usage *dto.CohereUsage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	units := usage.BilledUnits
+	if units == nil || (units.InputTokens == 0 && units.OutputTokens == 0) {
+		units = usage.Tokens
+	}
+	if units == nil {
+		return nil
+	}
+	promptTokens := int(units.InputTokens)
+	completionTokens := int(units.OutputTokens)
+	mapped := &dto.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+		InputTokens:      promptTokens,
+		OutputTokens:     completionTokens,
+	}
+	mapped.PromptTokensDetails.CachedTokens = int(usage.CachedTokens)
+	mapped.BillingUsage = dto.NewOpenAIChatBillingUsage(mapped)
+	return mapped
+}
+
+func ResponseCohereChat2OpenAI(id string, created int64, response *dto.CohereChatResponse) *dto.OpenAITextResponse {
+	if response.Id != "" {
+		id = response.Id
+	}
+	choice := dto.OpenAITextResponseChoice{
+		Message: dto.Message{
+			Role:    "assistant",
+			Content: "",
+		},
+		FinishReason: reasonmap.CohereFinishReasonToOpenAIFinishReason(response.FinishReason),
+	}
+	if choice.FinishReason == "" {
+		choice.FinishReason = types.FinishReasonStop
+	}
+
+	if message := response.Message; message != nil {
+		var (
+			content   strings.Builder
+			reasoning strings.Builder
+		)
+		for _, item := range message.Content {
+			switch item.Type {
+			case "text":
+				content.WriteString(item.Text)
+			case "thinking":
+				reasoning.WriteString(item.Thinking)
+			}
+		}
+		if reasoning.Len() == 0 && message.ToolPlan != "" {
+			reasoning.WriteString(message.ToolPlan)
+		}
+		choice.Message.SetStringContent(content.String())
+		if reasoning.Len() > 0 {
+			choice.Message.ReasoningContent = kitutil.GetPointer(reasoning.String())
+		}
+		if len(message.ToolCalls) > 0 {
+			toolCalls := make([]dto.ToolCallResponse, 0, len(message.ToolCalls))
+			for _, toolCall := range message.ToolCalls {
+				toolCalls = append(toolCalls, dto.ToolCallResponse{
+					ID:   toolCall.Id,
+					Type: "function",
+					Function: dto.FunctionResponse{
+						Name:      toolCall.Function.Name,
+						Arguments: toolCall.Function.Arguments,
+					},
+				})
+			}
+			choice.Message.SetToolCalls(toolCalls)
+			choice.FinishReason = types.FinishReasonToolCalls
+		}
+	}
+
+	openAIResponse := &dto.OpenAITextResponse{
+		Id:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Choices: []dto.OpenAITextResponseChoice{choice},
+	}
+	if usage := UsageFromCohereUsage(response.Usage); usage != nil {
+		openAIResponse.Usage = *usage
+	}
+	return openAIResponse
+}
+
+// CohereToChatStreamState converts v2 chat stream events. Content items and
+// tool calls are indexed separately upstream; the state tracks the type of
+// each content index so thinking deltas become reasoning deltas.
+type CohereToChatStreamState struct {
+	id            string
+	created       int64
+	contentTypes  map[int]string
+	sawToolCall   bool
+	finishEmitted bool
+	latestUsage   *dto.Usage
+}
+
+func NewCohereToChatStreamState(id string, created int64) *CohereToChatStreamState {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		id = fmt.Sprintf("chatcmpl-%s", kitutil.GetUUID())
+	}
+	if created == 0 {
+		created = kitutil.GetTimestamp()
+	}
+	return &CohereToChatStreamState{id: id, created: created, contentTypes: make(map[int]string)}
+}
+
+func (s *CohereToChatStreamState) ConvertEvent(event *dto.CohereChatStreamEvent, model string) []*dto.ChatCompletionsStreamResponse {
+	if s == nil || event == nil {
+		return nil
+	}
+	index := 0
+	if event.Index != nil {
+		index = *event.Index
+	}
+	var message *dto.CohereStreamMessage
+	if event.Delta != nil {
+		message = event.Delta.Message
+	}
+
+	var delta dto.ChatCompletionsStreamResponseChoiceDelta
+	switch event.Type {
+	case "message-start":
+		delta.Role = "assistant"
+	case "content-start":
+		if message == nil || message.Content == nil {
+			return nil
+		}
+		s.contentTypes[index] = message.Content.Type
+		if message.Content.Text == "" && message.Content.Thinking == "" {
+			return nil
+		}
+		s.applyContent(&delta, index, message.Content)
+	case "content-delta":
+		if message == nil || message.Content == nil {
+			return nil
+		}
+		s.applyContent(&delta, index, message.Content)
+	case "tool-plan-delta":
+		if message == nil || message.ToolPlan == "" {
+			return nil
+		}
+		delta.SetReasoningContent(message.ToolPlan)
+	case "tool-call-start", "tool-call-delta":
+		if message == nil || message.ToolCalls == nil {
+			return nil
+		}
+		s.sawToolCall = true
+		toolCall := dto.ToolCallResponse{
+			Function: dto.FunctionResponse{
+				Arguments: message.ToolCalls.Function.Arguments,
+			},
+		}
+		if event.Type == "tool-call-start" {
+			toolCall.ID = message.ToolCalls.Id
+			toolCall.Type = "function"
+			toolCall.Function.Name = message.ToolCalls.Function.Name
+		}
+		toolCall.SetIndex(index)
+		delta.ToolCalls = []dto.ToolCallResponse{toolCall}
+	case "message-end":
+		var finishReason string
+		if event.Delta != nil {
+			finishReason = event.Delta.FinishReason
+			if usage := UsageFromCohereUsage(event.Delta.Usage); usage != nil {
+				s.latestUsage = usage
+			}
+		}
+		return []*dto.ChatCompletionsStreamResponse{s.terminalChunk(model, finishReason)}
+	default:
+		return nil
+	}
+	return []*dto.ChatCompletionsStreamResponse{s.chunk(model, delta)}
+}
+
+func (s *CohereToChatStreamState) Finalize(model string) []*dto.ChatCompletionsStreamResponse {
+	if s == nil || s.finishEmitted {
+		return nil
+	}
+	return []*dto.ChatCompletionsStreamResponse{s.terminalChunk(model, "")}
+}
+
+func (s *CohereToChatStreamState) Usage() *dto.Usage {
+	if s == nil {
+		return nil
+	}
+	return s.latestUsage
+}
+
+func (s *CohereToChatStreamState) applyContent(delta *dto.ChatCompletionsStreamResponseChoiceDelta, index int, content *dto.CohereContent) {
+	contentType := content.Type
+	if contentType == "" {
+		contentType = s.contentTypes[index]
+	}
+	if contentType == "thinking" || content.Thinking != "" {
+		delta.SetReasoningContent(content.Thinking)
+		return
+	}
+	delta.SetContentString(content.Text)
+}
+
+func (s *CohereToChatStreamState) chunk(model string, delta dto.ChatCompletionsStreamResponseChoiceDelta) *dto.ChatCompletionsStreamResponse {
+	return &dto.ChatCompletionsStreamResponse{
+		Id:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   model,
+		Choices: []dto.ChatCompletionsStreamResponseChoice{
+			{Delta: delta},
+		},
+	}
+}
+
+func (s *CohereToChatStreamState) terminalChunk(model string, cohereFinishReason string) *dto.ChatCompletionsStreamResponse {
+	finishReason := reasonmap.CohereFinishReasonToOpenAIFinishReason(cohereFinishReason)
+	if finishReason == "" {
+		finishReason = types.FinishReasonStop
+		if s.sawToolCall {
+			finishReason = types.FinishReasonToolCalls
+		}
+	}
+	s.finishEmitted = true
+	response := s.chunk(model, dto.ChatCompletionsStreamResponseChoiceDelta{})
+	response.Choices[0].FinishReason = &finishReason
+	response.Usage = s.latestUsage
+	return response
+}
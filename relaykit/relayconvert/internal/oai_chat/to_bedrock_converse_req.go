@@ -0,0 +1,246 @@
+package oaichat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	relaymedia "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/media"
+	sharedclaude "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/shared/claude"
+	sharedgemini "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/shared/gemini"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+)
+
+// OpenAIChatRequestToBedrockConverse builds a Converse request body. Bedrock
+// requires strictly alternating user/assistant turns that start with a user
+// turn, so tool results are folded into user turns and consecutive messages
+// with the same role are merged.
+func OpenAIChatRequestToBedrockConverse(c context.Context, textRequest dto.GeneralOpenAIRequest) (*dto.BedrockConverseRequest, error) {
+	bedrockRequest := &dto.BedrockConverseRequest{}
+
+	inferenceConfig := &dto.BedrockInferenceConfig{
+		Temperature:   textRequest.Temperature,
+		TopP:          textRequest.TopP,
+		StopSequences: sharedgemini.ParseStopSequences(textRequest.Stop),
+	}
+	if maxTokens := textRequest.GetMaxTokens(); maxTokens > 0 {
+		inferenceConfig.MaxTokens = kitutil.GetPointer(int(maxTokens))
+	}
+	if inferenceConfig.MaxTokens != nil || inferenceConfig.Temperature != nil || inferenceConfig.TopP != nil || len(inferenceConfig.StopSequences) > 0 {
+		bedrockRequest.InferenceConfig = inferenceConfig
+	}
+	if textRequest.TopK != nil {
+		additional, err := kitutil.Marshal(map[string]any{"top_k": *textRequest.TopK})
+		if err != nil {
+			return nil, err
+		}
+		bedrockRequest.AdditionalModelRequestFields = additional
+	}
+
+	tools := make([]dto.BedrockTool, 0, len(textRequest.Tools))
+	for _, tool := range textRequest.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		tools = append(tools, dto.BedrockTool{
+			ToolSpec: &dto.BedrockToolSpec{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: dto.BedrockToolInputSchema{
+					Json: sharedclaude.FunctionParametersToInputSchema(tool.Function.Parameters),
+				},
+			},
+		})
+	}
+	if len(tools) > 0 {
+		bedrockRequest.ToolConfig = &dto.BedrockToolConfig{
+			Tools:      tools,
+			ToolChoice: openAIToolChoiceToBedrock(textRequest.ToolChoice),
+		}
+	}
+
+	messages := make([]dto.BedrockMessage, 0, len(textRequest.Messages))
+	appendBlocks := func(role string, blocks []dto.BedrockContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if len(messages) > 0 && messages[len(messages)-1].Role == role {
+			messages[len(messages)-1].Content = append(messages[len(messages)-1].Content, blocks...)
+			return
+		}
+		messages = append(messages, dto.BedrockMessage{Role: role, Content: blocks})
+	}
+
+	for _, message := range textRequest.Messages {
+		switch message.Role {
+		case "system", "developer":
+			for _, text := range openAIMessageTexts(message) {
+				bedrockRequest.System = append(bedrockRequest.System, dto.BedrockSystemBlock{Text: kitutil.GetPointer(text)})
+			}
+		case "tool":
+			text := message.StringContent()
+			appendBlocks("user", []dto.BedrockContentBlock{{
+				ToolResult: &dto.BedrockToolResultBlock{
+					ToolUseId: message.ToolCallId,
+					Content:   []dto.BedrockToolResultContent{{Text: kitutil.GetPointer(text)}},
+				},
+			}})
+		case "assistant":
+			blocks, err := openAIContentToBedrockBlocks(c, message)
+			if err != nil {
+				return nil, err
+			}
+			for _, toolCall := range message.ParseToolCalls() {
+				input := make(map[string]any)
+				if args := toolCall.Function.Arguments; args != "" {
+					if err := kitutil.Unmarshal([]byte(args), &input); err != nil {
+						kitutil.LogInfo("tool call function arguments is not a map[string]any: " + args)
+					}
+				}
+				blocks = append(blocks, dto.BedrockContentBlock{
+					ToolUse: &dto.BedrockToolUseBlock{
+						ToolUseId: toolCall.ID,
+						Name:      toolCall.Function.Name,
+						Input:     input,
+					},
+				})
+			}
+			if len(messages) == 0 {
+				appendBlocks("user", []dto.BedrockContentBlock{{Text: kitutil.GetPointer("...")}})
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			blocks, err := openAIContentToBedrockBlocks(c, message)
+			if err != nil {
+				return nil, err
+			}
+			if len(blocks) == 0 {
+				blocks = []dto.BedrockContentBlock{{Text: kitutil.GetPointer("...")}}
+			}
+			appendBlocks("user", blocks)
+		}
+	}
+	bedrockRequest.Messages = messages
+	return bedrockRequest, nil
+}
+
+func openAIMessageTexts(message dto.Message) []string {
+	if message.IsStringContent() {
+		if text := message.StringContent(); text != "" {
+			return []string{text}
+		}
+		return nil
+	}
+	var texts []string
+	for _, content := range message.ParseContent() {
+		if content.Type == dto.ContentTypeText && content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return texts
+}
+
+func openAIContentToBedrockBlocks(c context.Context, message dto.Message) ([]dto.BedrockContentBlock, error) {
+	if message.IsStringContent() {
+		if text := message.StringContent(); text != "" {
+			return []dto.BedrockContentBlock{{Text: kitutil.GetPointer(text)}}, nil
+		}
+		return nil, nil
+	}
+	var blocks []dto.BedrockContentBlock
+	for i, content := range message.ParseContent() {
+		if content.Type == dto.ContentTypeText {
+			if content.Text != "" {
+				blocks = append(blocks, dto.BedrockContentBlock{Text: kitutil.GetPointer(content.Text)})
+			}
+			continue
+		}
+		source := content.ToFileSource()
+		if source == nil {
+			continue
+		}
+		base64Data, mimeType, err := relaymedia.ResolveBase64Data(c, source, "formatting media for Bedrock")
+		if err != nil {
+			return nil, fmt.Errorf("get file data failed: %s", err.Error())
+		}
+		if format, ok := bedrockImageFormat(mimeType); ok {
+			blocks = append(blocks, dto.BedrockContentBlock{
+				Image: &dto.BedrockImageBlock{
+					Format: format,
+					Source: dto.BedrockBytesSource{Bytes: base64Data},
+				},
+			})
+			continue
+		}
+		if format, ok := bedrockDocumentFormat(mimeType); ok {
+			blocks = append(blocks, dto.BedrockContentBlock{
+				Document: &dto.BedrockDocumentBlock{
+					Format: format,
+					Name:   fmt.Sprintf("document-%d", i+1),
+					Source: dto.BedrockBytesSource{Bytes: base64Data},
+				},
+			})
+		}
+	}
+	return blocks, nil
+}
+
+func bedrockImageFormat(mimeType string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "image/png":
+		return "png", true
+	case "image/jpeg", "image/jpg":
+		return "jpeg", true
+	case "image/gif":
+		return "gif", true
+	case "image/webp":
+		return "webp", true
+	}
+	return "", false
+}
+
+func bedrockDocumentFormat(mimeType string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(mimeType)) {
+	case "application/pdf":
+		return "pdf", true
+	case "text/csv":
+		return "csv", true
+	case "application/msword":
+		return "doc", true
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return "docx", true
+	case "application/vnd.ms-excel":
+		return "xls", true
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return "xlsx", true
+	case "text/html":
+		return "html", true
+	case "text/markdown":
+		return "md", true
+	case "text/plain":
+		return "txt", true
+	}
+	return "", false
+}
+
+// openAIToolChoiceToBedrock maps tool_choice; Bedrock has no "none", so it
+// falls back to the default (auto) like an omitted choice.
+func openAIToolChoiceToBedrock(toolChoice any) *dto.BedrockToolChoice {
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "auto":
+			return &dto.BedrockToolChoice{Auto: &struct{}{}}
+		case "required":
+			return &dto.BedrockToolChoice{Any: &struct{}{}}
+		}
+	case map[string]any:
+		if function, ok := choice["function"].(map[string]any); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return &dto.BedrockToolChoice{Tool: &dto.BedrockSpecificToolChoice{Name: name}}
+			}
+		}
+	}
+	return nil
+}
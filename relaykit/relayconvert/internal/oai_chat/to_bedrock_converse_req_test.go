@@ -0,0 +1,45 @@
+package oaichat
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIChatRequestToBedrockConverseMergesTurns(t *testing.T) {
+	req := dto.GeneralOpenAIRequest{
+		Model: "gpt-test",
+		Messages: []dto.Message{
+			{Role: "system", Content: "system rules"},
+			assistantMessageWithTool("", "call_1", "lookup", `{"q":"x"}`),
+			{Role: "tool", ToolCallId: "call_1", Content: "tool result"},
+			{Role: "user", Content: "thanks"},
+			{Role: "user", Content: "and more"},
+		},
+	}
+
+	got, err := OpenAIChatRequestToBedrockConverse(nil, req)
+	require.NoError(t, err)
+
+	require.Len(t, got.System, 1)
+	assert.Equal(t, "system rules", *got.System[0].Text)
+	require.Len(t, got.Messages, 3)
+
+	assert.Equal(t, "user", got.Messages[0].Role)
+	require.Len(t, got.Messages[0].Content, 1)
+	assert.Equal(t, "...", *got.Messages[0].Content[0].Text)
+
+	assert.Equal(t, "assistant", got.Messages[1].Role)
+	require.Len(t, got.Messages[1].Content, 1)
+	require.NotNil(t, got.Messages[1].Content[0].ToolUse)
+	assert.Equal(t, map[string]any{"q": "x"}, got.Messages[1].Content[0].ToolUse.Input)
+
+	assert.Equal(t, "user", got.Messages[2].Role)
+	require.Len(t, got.Messages[2].Content, 3)
+	require.NotNil(t, got.Messages[2].Content[0].ToolResult)
+	assert.Equal(t, "call_1", got.Messages[2].Content[0].ToolResult.ToolUseId)
+	assert.Equal(t, "thanks", *got.Messages[2].Content[1].Text)
+	assert.Equal(t, "and more", *got.Messages[2].Content[2].Text)
+}
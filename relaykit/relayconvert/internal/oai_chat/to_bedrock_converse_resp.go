@@ -0,0 +1,236 @@
+package oaichat
+
+import (
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/reasonmap"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+)
+
+// BedrockUsageFromChatUsage splits OpenAI prompt tokens back into Bedrock's
+// uncached input, cache read and cache write counts.
+func BedrockUsageFromChatUsage(usage *dto.Usage) *dto.BedrockUsage {
+	if usage == nil {
+		return nil
+	}
+	cacheRead := usage.PromptTokensDetails.CachedTokens
+	cacheWrite := usage.PromptTokensDetails.CacheCreationTokensTotal()
+	inputTokens := usage.PromptTokens - cacheRead - cacheWrite
+	if inputTokens < 0 {
+		inputTokens = 0
+	}
+	totalTokens := usage.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return &dto.BedrockUsage{
+		InputTokens:           inputTokens,
+		OutputTokens:          usage.CompletionTokens,
+		TotalTokens:           totalTokens,
+		CacheReadInputTokens:  cacheRead,
+		CacheWriteInputTokens: cacheWrite,
+	}
+}
+
+func ResponseOpenAI2BedrockConverse(openAIResponse *dto.OpenAITextResponse) *dto.BedrockConverseResponse {
+	message := &dto.BedrockMessage{
+		Role:    "assistant",
+		Content: []dto.BedrockContentBlock{},
+	}
+	stopReason := "end_turn"
+	if len(openAIResponse.Choices) > 0 {
+		choice := openAIResponse.Choices[0]
+		if reasoning := choice.Message.GetReasoningContent(); reasoning != "" {
+			message.Content = append(message.Content, dto.BedrockContentBlock{
+				ReasoningContent: &dto.BedrockReasoningContentBlock{
+					ReasoningText: &dto.BedrockReasoningText{Text: reasoning},
+				},
+			})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			message.Content = append(message.Content, dto.BedrockContentBlock{Text: kitutil.GetPointer(text)})
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			input := make(map[string]any)
+			if args := toolCall.Function.Arguments; args != "" {
+				if err := kitutil.Unmarshal([]byte(args), &input); err != nil {
+					kitutil.LogInfo("tool call function arguments is not a map[string]any: " + args)
+				}
+			}
+			message.Content = append(message.Content, dto.BedrockContentBlock{
+				ToolUse: &dto.BedrockToolUseBlock{
+					ToolUseId: toolCall.ID,
+					Name:      toolCall.Function.Name,
+					Input:     input,
+				},
+			})
+		}
+		if choice.FinishReason != "" {
+			stopReason = reasonmap.OpenAIFinishReasonToBedrockStopReason(choice.FinishReason)
+		}
+	}
+	return &dto.BedrockConverseResponse{
+		Output:     dto.BedrockConverseOutput{Message: message},
+		StopReason: stopReason,
+		Usage:      BedrockUsageFromChatUsage(&openAIResponse.Usage),
+		Metrics:    &dto.BedrockMetrics{},
+	}
+}
+
+// ChatToBedrockStreamState converts chat completion chunks to ConverseStream
+// events. Text and reasoning deltas share an implicit block that is closed
+// whenever the delta kind changes; tool calls get their own blocks with an
+// explicit contentBlockStart. The metadata event is emitted on finalize
+// because chat usage may arrive after the finish chunk.
+type ChatToBedrockStreamState struct {
+	started     bool
+	stopped     bool
+	blockIndex  int
+	blockKind   string
+	blockOpen   bool
+	toolBlocks  map[int]int
+	latestUsage *dto.Usage
+}
+
+const (
+	bedrockBlockText      = "text"
+	bedrockBlockReasoning = "reasoning"
+	bedrockBlockToolUse   = "tool_use"
+)
+
+func NewChatToBedrockStreamState() *ChatToBedrockStreamState {
+	return &ChatToBedrockStreamState{toolBlocks: make(map[int]int)}
+}
+
+func (s *ChatToBedrockStreamState) ConvertChunk(chunk *dto.ChatCompletionsStreamResponse) []*dto.BedrockConverseStreamEvent {
+	if s == nil || chunk == nil {
+		return nil
+	}
+	if chunk.Usage != nil {
+		s.latestUsage = chunk.Usage
+	}
+	var events []*dto.BedrockConverseStreamEvent
+	if !s.started {
+		s.started = true
+		events = append(events, &dto.BedrockConverseStreamEvent{
+			MessageStart: &dto.BedrockMessageStartEvent{Role: "assistant"},
+		})
+	}
+	if len(chunk.Choices) == 0 || s.stopped {
+		return events
+	}
+	choice := chunk.Choices[0]
+
+	if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+		events = append(events, s.openBlock(bedrockBlockReasoning)...)
+		events = append(events, s.delta(dto.BedrockContentBlockDelta{
+			ReasoningContent: &dto.BedrockReasoningTextDelta{Text: kitutil.GetPointer(reasoning)},
+		}))
+	}
+	if text := choice.Delta.GetContentString(); text != "" {
+		events = append(events, s.openBlock(bedrockBlockText)...)
+		events = append(events, s.delta(dto.BedrockContentBlockDelta{Text: kitutil.GetPointer(text)}))
+	}
+	for _, toolCall := range choice.Delta.ToolCalls {
+		toolIndex := 0
+		if toolCall.Index != nil {
+			toolIndex = *toolCall.Index
+		}
+		blockIndex, known := s.toolBlocks[toolIndex]
+		if !known {
+			events = append(events, s.closeBlock()...)
+			blockIndex = s.blockIndex
+			s.toolBlocks[toolIndex] = blockIndex
+			s.blockKind = bedrockBlockToolUse
+			s.blockOpen = true
+			events = append(events, &dto.BedrockConverseStreamEvent{
+				ContentBlockStart: &dto.BedrockContentBlockStartEvent{
+					ContentBlockIndex: blockIndex,
+					Start: dto.BedrockContentBlockStart{
+						ToolUse: &dto.BedrockToolUseBlockStart{
+							ToolUseId: toolCall.ID,
+							Name:      toolCall.Function.Name,
+						},
+					},
+				},
+			})
+		}
+		if toolCall.Function.Arguments != "" {
+			events = append(events, &dto.BedrockConverseStreamEvent{
+				ContentBlockDelta: &dto.BedrockContentBlockDeltaEvent{
+					ContentBlockIndex: blockIndex,
+					Delta: dto.BedrockContentBlockDelta{
+						ToolUse: &dto.BedrockToolUseBlockDelta{Input: toolCall.Function.Arguments},
+					},
+				},
+			})
+		}
+	}
+
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		events = append(events, s.stop(reasonmap.OpenAIFinishReasonToBedrockStopReason(*choice.FinishReason))...)
+	}
+	return events
+}
+
+func (s *ChatToBedrockStreamState) Finalize() []*dto.BedrockConverseStreamEvent {
+	if s == nil {
+		return nil
+	}
+	var events []*dto.BedrockConverseStreamEvent
+	if !s.stopped {
+		events = append(events, s.stop("end_turn")...)
+	}
+	events = append(events, &dto.BedrockConverseStreamEvent{
+		Metadata: &dto.BedrockStreamMetadataEvent{
+			Usage:   BedrockUsageFromChatUsage(s.latestUsage),
+			Metrics: &dto.BedrockMetrics{},
+		},
+	})
+	return events
+}
+
+func (s *ChatToBedrockStreamState) Usage() *dto.Usage {
+	if s == nil {
+		return nil
+	}
+	return s.latestUsage
+}
+
+func (s *ChatToBedrockStreamState) openBlock(kind string) []*dto.BedrockConverseStreamEvent {
+	if s.blockOpen && s.blockKind == kind {
+		return nil
+	}
+	events := s.closeBlock()
+	s.blockKind = kind
+	s.blockOpen = true
+	return events
+}
+
+func (s *ChatToBedrockStreamState) closeBlock() []*dto.BedrockConverseStreamEvent {
+	if !s.blockOpen {
+		return nil
+	}
+	event := &dto.BedrockConverseStreamEvent{
+		ContentBlockStop: &dto.BedrockContentBlockStopEvent{ContentBlockIndex: s.blockIndex},
+	}
+	s.blockOpen = false
+	s.blockIndex++
+	return []*dto.BedrockConverseStreamEvent{event}
+}
+
+func (s *ChatToBedrockStreamState) delta(delta dto.BedrockContentBlockDelta) *dto.BedrockConverseStreamEvent {
+	return &dto.BedrockConverseStreamEvent{
+		ContentBlockDelta: &dto.BedrockContentBlockDeltaEvent{
+			ContentBlockIndex: s.blockIndex,
+			Delta:             delta,
+		},
+	}
+}
+
+func (s *ChatToBedrockStreamState) stop(stopReason string) []*dto.BedrockConverseStreamEvent {
+	events := s.closeBlock()
+	s.stopped = true
+	return append(events, &dto.BedrockConverseStreamEvent{
+		MessageStop: &dto.BedrockMessageStopEvent{StopReason: stopReason},
+	})
+}
@@ -0,0 +1,138 @@
+package oaichat
+
+import (
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	sharedgemini "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/shared/gemini"
+	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
+)
+
+// OpenAIChatRequestToCohereChat builds a Cohere v2 chat request. Cohere
+// accepts image URLs (remote or data URLs) as-is, so media is not resolved.
+func OpenAIChatRequestToCohereChat(textRequest dto.GeneralOpenAIRequest) (*dto.CohereChatRequest, error) {
+	cohereRequest := &dto.CohereChatRequest{
+		Model:            textRequest.Model,
+		Temperature:      textRequest.Temperature,
+		P:                textRequest.TopP,
+		K:                textRequest.TopK,
+		StopSequences:    sharedgemini.ParseStopSequences(textRequest.Stop),
+		FrequencyPenalty: textRequest.FrequencyPenalty,
+		PresencePenalty:  textRequest.PresencePenalty,
+	}
+	if textRequest.IsStream(nil) {
+		cohereRequest.Stream = kitutil.GetPointer(true)
+	}
+	if maxTokens := textRequest.GetMaxTokens(); maxTokens > 0 {
+		cohereRequest.MaxTokens = kitutil.GetPointer(int(maxTokens))
+	}
+	if textRequest.Seed != nil {
+		cohereRequest.Seed = kitutil.GetPointer(int(*textRequest.Seed))
+	}
+
+	for _, tool := range textRequest.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		cohereRequest.Tools = append(cohereRequest.Tools, dto.CohereTool{
+			Type: "function",
+			Function: dto.CohereToolFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+	switch choice := textRequest.ToolChoice.(type) {
+	case string:
+		switch choice {
+		case "required":
+			cohereRequest.ToolChoice = "REQUIRED"
+		case "none":
+			cohereRequest.ToolChoice = "NONE"
+		}
+	case map[string]any:
+		// Cohere cannot force a specific tool; requiring a tool call is the
+		// closest equivalent.
+		cohereRequest.ToolChoice = "REQUIRED"
+	}
+
+	if format := textRequest.ResponseFormat; format != nil {
+		switch format.Type {
+		case "json_object":
+			cohereRequest.ResponseFormat = &dto.CohereResponseFormat{Type: "json_object"}
+		case "json_schema":
+			cohereRequest.ResponseFormat = &dto.CohereResponseFormat{Type: "json_object"}
+			var schema dto.FormatJsonSchema
+			if len(format.JsonSchema) > 0 {
+				if err := kitutil.Unmarshal(format.JsonSchema, &schema); err != nil {
+					return nil, err
+				}
+			}
+			cohereRequest.ResponseFormat.JsonSchema = schema.Schema
+		}
+	}
+
+	if textRequest.ReasoningEffort != "" && textRequest.ReasoningEffort != "none" {
+		cohereRequest.Thinking = &dto.CohereThinking{Type: "enabled"}
+	}
+
+	messages := make([]dto.CohereMessage, 0, len(textRequest.Messages))
+	for _, message := range textRequest.Messages {
+		messages = append(messages, openAIMessageToCohere(message))
+	}
+	cohereRequest.Messages = messages
+	return cohereRequest, nil
+}
+
+func openAIMessageToCohere(message dto.Message) dto.CohereMessage {
+	role := message.Role
+	if role == "developer" {
+		role = "system"
+	}
+	cohereMessage := dto.CohereMessage{Role: role}
+
+	switch role {
+	case "tool":
+		cohereMessage.ToolCallId = message.ToolCallId
+		cohereMessage.Content = message.StringContent()
+		return cohereMessage
+	case "assistant":
+		for _, toolCall := range message.ParseToolCalls() {
+			cohereMessage.ToolCalls = append(cohereMessage.ToolCalls, dto.CohereToolCall{
+				Id:   toolCall.ID,
+				Type: "function",
+				Function: dto.CohereFunctionCall{
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				},
+			})
+		}
+		if len(cohereMessage.ToolCalls) > 0 {
+			cohereMessage.ToolPlan = message.GetReasoningContent()
+		}
+	}
+
+	if message.IsStringContent() {
+		if text := message.StringContent(); text != "" {
+			cohereMessage.Content = text
+		}
+		return cohereMessage
+	}
+	var items []dto.CohereContent
+	for _, content := range message.ParseContent() {
+		switch content.Type {
+		case dto.ContentTypeText:
+			items = append(items, dto.CohereContent{Type: "text", Text: content.Text})
+		case dto.ContentTypeImageURL:
+			if image := content.GetImageMedia(); image != nil && image.Url != "" {
+				items = append(items, dto.CohereContent{
+					Type:     "image_url",
+					ImageUrl: &dto.CohereImageUrl{Url: image.Url, Detail: image.Detail},
+				})
+			}
+		}
+	}
+	if len(items) > 0 {
+		cohereMessage.Content = items
+	}
+	return cohereMessage
+}
@@ -0,0 +1,238 @@
+package oaichat
+
+import (
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/reasonmap"
+)
+
+func CohereUsageFromChatUsage(usage *dto.Usage) *dto.CohereUsage {
+	if usage == nil {
+		return nil
+	}
+	units := &dto.CohereUsageUnits{
+		InputTokens:  float64(usage.PromptTokens),
+		OutputTokens: float64(usage.CompletionTokens),
+	}
+	return &dto.CohereUsage{
+		BilledUnits:  units,
+		Tokens:       &dto.CohereUsageUnits{InputTokens: units.InputTokens, OutputTokens: units.OutputTokens},
+		CachedTokens: float64(usage.PromptTokensDetails.CachedTokens),
+	}
+}
+
+func ResponseOpenAI2CohereChat(openAIResponse *dto.OpenAITextResponse) *dto.CohereChatResponse {
+	message := &dto.CohereResponseMessage{Role: "assistant"}
+	finishReason := "COMPLETE"
+	if len(openAIResponse.Choices) > 0 {
+		choice := openAIResponse.Choices[0]
+		reasoning := choice.Message.GetReasoningContent()
+		toolCalls := choice.Message.ParseToolCalls()
+		if reasoning != "" {
+			if len(toolCalls) > 0 {
+				message.ToolPlan = reasoning
+			} else {
+				message.Content = append(message.Content, dto.CohereContent{Type: "thinking", Thinking: reasoning})
+			}
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			message.Content = append(message.Content, dto.CohereContent{Type: "text", Text: text})
+		}
+		for _, toolCall := range toolCalls {
+			message.ToolCalls = append(message.ToolCalls, dto.CohereToolCall{
+				Id:   toolCall.ID,
+				Type: "function",
+				Function: dto.CohereFunctionCall{
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				},
+			})
+		}
+		if choice.FinishReason != "" {
+			finishReason = reasonmap.OpenAIFinishReasonToCohereFinishReason(choice.FinishReason)
+		}
+	}
+	return &dto.CohereChatResponse{
+		Id:           openAIResponse.Id,
+		FinishReason: finishReason,
+		Message:      message,
+		Usage:        CohereUsageFromChatUsage(&openAIResponse.Usage),
+	}
+}
+
+// ChatToCohereStreamState converts chat completion chunks to Cohere v2
+// stream events. Content items and tool calls keep separate index counters
+// as Cohere does. message-end carries usage, so it is emitted on finalize.
+type ChatToCohereStreamState struct {
+	id           string
+	started      bool
+	contentIndex int
+	contentType  string
+	contentOpen  bool
+	toolIndex    int
+	toolOpen     bool
+	toolIndexes  map[int]int
+	finishReason string
+	latestUsage  *dto.Usage
+}
+
+func NewChatToCohereStreamState(id string) *ChatToCohereStreamState {
+	return &ChatToCohereStreamState{id: id, toolIndexes: make(map[int]int)}
+}
+
+func (s *ChatToCohereStreamState) ConvertChunk(chunk *dto.ChatCompletionsStreamResponse) []*dto.CohereChatStreamEvent {
+	if s == nil || chunk == nil {
+		return nil
+	}
+	if chunk.Usage != nil {
+		s.latestUsage = chunk.Usage
+	}
+	var events []*dto.CohereChatStreamEvent
+	if !s.started {
+		s.started = true
+		if s.id == "" {
+			s.id = chunk.Id
+		}
+		events = append(events, &dto.CohereChatStreamEvent{
+			Type: "message-start",
+			Id:   s.id,
+			Delta: &dto.CohereStreamDelta{
+				Message: &dto.CohereStreamMessage{Role: "assistant"},
+			},
+		})
+	}
+	if len(chunk.Choices) == 0 {
+		return events
+	}
+	choice := chunk.Choices[0]
+
+	if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+		events = append(events, s.contentDelta("thinking", dto.CohereContent{Type: "thinking", Thinking: reasoning})...)
+	}
+	if text := choice.Delta.GetContentString(); text != "" {
+		events = append(events, s.contentDelta("text", dto.CohereContent{Type: "text", Text: text})...)
+	}
+	for _, toolCall := range choice.Delta.ToolCalls {
+		chatIndex := 0
+		if toolCall.Index != nil {
+			chatIndex = *toolCall.Index
+		}
+		index, known := s.toolIndexes[chatIndex]
+		if !known {
+			events = append(events, s.closeContent()...)
+			events = append(events, s.closeTool()...)
+			index = s.toolIndex
+			s.toolIndexes[chatIndex] = index
+			s.toolOpen = true
+			events = append(events, &dto.CohereChatStreamEvent{
+				Type:  "tool-call-start",
+				Index: &index,
+				Delta: &dto.CohereStreamDelta{
+					Message: &dto.CohereStreamMessage{
+						ToolCalls: &dto.CohereToolCall{
+							Id:   toolCall.ID,
+							Type: "function",
+							Function: dto.CohereFunctionCall{
+								Name:      toolCall.Function.Name,
+								Arguments: toolCall.Function.Arguments,
+							},
+						},
+					},
+				},
+			})
+			continue
+		}
+		if toolCall.Function.Arguments != "" {
+			events = append(events, &dto.CohereChatStreamEvent{
+				Type:  "tool-call-delta",
+				Index: &index,
+				Delta: &dto.CohereStreamDelta{
+					Message: &dto.CohereStreamMessage{
+						ToolCalls: &dto.CohereToolCall{
+							Function: dto.CohereFunctionCall{Arguments: toolCall.Function.Arguments},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		s.finishReason = reasonmap.OpenAIFinishReasonToCohereFinishReason(*choice.FinishReason)
+		events = append(events, s.closeContent()...)
+		events = append(events, s.closeTool()...)
+	}
+	return events
+}
+
+func (s *ChatToCohereStreamState) Finalize() []*dto.CohereChatStreamEvent {
+	if s == nil {
+		return nil
+	}
+	events := s.closeContent()
+	events = append(events, s.closeTool()...)
+	finishReason := s.finishReason
+	if finishReason == "" {
+		finishReason = "COMPLETE"
+	}
+	return append(events, &dto.CohereChatStreamEvent{
+		Type: "message-end",
+		Delta: &dto.CohereStreamDelta{
+			FinishReason: finishReason,
+			Usage:        CohereUsageFromChatUsage(s.latestUsage),
+		},
+	})
+}
+
+func (s *ChatToCohereStreamState) Usage() *dto.Usage {
+	if s == nil {
+		return nil
+	}
+	return s.latestUsage
+}
+
+func (s *ChatToCohereStreamState) contentDelta(contentType string, content dto.CohereContent) []*dto.CohereChatStreamEvent {
+	var events []*dto.CohereChatStreamEvent
+	if !s.contentOpen || s.contentType != contentType {
+		events = append(events, s.closeContent()...)
+		events = append(events, s.closeTool()...)
+		s.contentOpen = true
+		s.contentType = contentType
+		index := s.contentIndex
+		start := dto.CohereContent{Type: contentType}
+		events = append(events, &dto.CohereChatStreamEvent{
+			Type:  "content-start",
+			Index: &index,
+			Delta: &dto.CohereStreamDelta{
+				Message: &dto.CohereStreamMessage{Content: &start},
+			},
+		})
+	}
+	index := s.contentIndex
+	return append(events, &dto.CohereChatStreamEvent{
+		Type:  "content-delta",
+		Index: &index,
+		Delta: &dto.CohereStreamDelta{
+			Message: &dto.CohereStreamMessage{Content: &content},
+		},
+	})
+}
+
+func (s *ChatToCohereStreamState) closeContent() []*dto.CohereChatStreamEvent {
+	if !s.contentOpen {
+		return nil
+	}
+	index := s.contentIndex
+	s.contentOpen = false
+	s.contentIndex++
+	return []*dto.CohereChatStreamEvent{{Type: "content-end", Index: &index}}
+}
+
+func (s *ChatToCohereStreamState) closeTool() []*dto.CohereChatStreamEvent {
+	if !s.toolOpen {
+		return nil
+	}
+	index := s.toolIndex
+	s.toolOpen = false
+	s.toolIndex++
+	return []*dto.CohereChatStreamEvent{{Type: "tool-call-end", Index: &index}}
+}
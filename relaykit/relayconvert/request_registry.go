@@ -10,7 +10,9 @@ import (
 	"context"
 	"github.com/QuantumNous/new-api/relaykit/dto"
 	"github.com/QuantumNous/new-api/relaykit/relayconvert/convmeta"
+	bedrockconverse "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/bedrock_converse"
 	claudemessages "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/claude_messages"
+	coherechat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/cohere_chat"
 	geminichat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/gemini_chat"
 	oaichat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/oai_chat"
 	oairesponses "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/oai_responses"
@@ -69,6 +71,21 @@ const (
 	requestConverterGeminiToClaude    = "gemini_generate_content_to_claude_messages"
 	requestConverterGeminiToResponses = "gemini_generate_content_to_openai_responses"
 	requestConverterResponsesToClaude = "openai_responses_to_claude_messages"
+
+	requestConverterBedrockToClaude    = "bedrock_converse_to_claude_messages"
+	requestConverterBedrockToGemini    = "bedrock_converse_to_gemini_generate_content"
+	requestConverterBedrockToResponses = "bedrock_converse_to_openai_responses"
+	requestConverterBedrockToCohere    = "bedrock_converse_to_cohere_chat"
+	requestConverterClaudeToBedrock    = "claude_messages_to_bedrock_converse"
+	requestConverterGeminiToBedrock    = "gemini_generate_content_to_bedrock_converse"
+	requestConverterResponsesToBedrock = "openai_responses_to_bedrock_converse"
+	requestConverterCohereToBedrock    = "cohere_chat_to_bedrock_converse"
+	requestConverterCohereToClaude     = "cohere_chat_to_claude_messages"
+	requestConverterCohereToGemini     = "cohere_chat_to_gemini_generate_content"
+	requestConverterCohereToResponses  = "cohere_chat_to_openai_responses"
+	requestConverterClaudeToCohere     = "claude_messages_to_cohere_chat"
+	requestConverterGeminiToCohere     = "gemini_generate_content_to_cohere_chat"
+	requestConverterResponsesToCohere  = "openai_responses_to_cohere_chat"
 )
 
 const (
@@ -80,6 +97,10 @@ const (
 	ConverterOpenAIResponsesToGemini     = "openai_responses_to_gemini_generate_content"
 	ConverterGeminiContentToOpenAIChat   = "gemini_generate_content_to_openai_chat_completions"
 	ConverterOpenAIChatToGeminiContent   = "openai_chat_completions_to_gemini_generate_content"
+	ConverterOpenAIChatToBedrockConverse = "openai_chat_completions_to_bedrock_converse"
+	ConverterBedrockConverseToOpenAIChat = "bedrock_converse_to_openai_chat_completions"
+	ConverterOpenAIChatToCohereChat      = "openai_chat_completions_to_cohere_chat"
+	ConverterCohereChatToOpenAIChat      = "cohere_chat_to_openai_chat_completions"
 )
 
 func registerBuiltinRequestConverter(spec RequestConverterSpec) {
@@ -497,3 +518,55 @@ func convertResponsesRequestToChat(_ context.Context, _ convmeta.Meta, request a
 	}
 	return oairesponses.ResponsesRequestToChatCompletionsRequest(responsesRequest)
 }
+
+func convertBedrockRequestToOpenAI(_ context.Context, info convmeta.Meta, request any) (any, error) {
+	bedrockRequest, ok := request.(*dto.BedrockConverseRequest)
+	if !ok {
+		if value, ok := request.(dto.BedrockConverseRequest); ok {
+			bedrockRequest = &value
+		}
+	}
+	if bedrockRequest == nil {
+		return nil, fmt.Errorf("expected Bedrock Converse request, got %T", request)
+	}
+	return bedrockconverse.BedrockConverseRequestToOpenAIChat(bedrockRequest, info)
+}
+
+func convertOpenAIRequestToBedrock(c context.Context, _ convmeta.Meta, request any) (any, error) {
+	openAIRequest, ok := request.(*dto.GeneralOpenAIRequest)
+	if !ok {
+		if value, ok := request.(dto.GeneralOpenAIRequest); ok {
+			openAIRequest = &value
+		}
+	}
+	if openAIRequest == nil {
+		return nil, fmt.Errorf("expected OpenAI chat completions request, got %T", request)
+	}
+	return oaichat.OpenAIChatRequestToBedrockConverse(c, *openAIRequest)
+}
+
+func convertCohereRequestToOpenAI(_ context.Context, _ convmeta.Meta, request any) (any, error) {
+	cohereRequest, ok := request.(*dto.CohereChatRequest)
+	if !ok {
+		if value, ok := request.(dto.CohereChatRequest); ok {
+			cohereRequest = &value
+		}
+	}
+	if cohereRequest == nil {
+		return nil, fmt.Errorf("expected Cohere chat request, got %T", request)
+	}
+	return coherechat.CohereChatRequestToOpenAIChat(cohereRequest)
+}
+
+func convertOpenAIRequestToCohere(_ context.Context, _ convmeta.Meta, request any) (any, error) {
+	openAIRequest, ok := request.(*dto.GeneralOpenAIRequest)
+	if !ok {
+		if value, ok := request.(dto.GeneralOpenAIRequest); ok {
+			openAIRequest = &value
+		}
+	}
+	if openAIRequest == nil {
+		return nil, fmt.Errorf("expected OpenAI chat completions request, got %T", request)
+	}
+	return oaichat.OpenAIChatRequestToCohereChat(*openAIRequest)
+}
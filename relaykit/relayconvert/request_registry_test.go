@@ -27,6 +27,10 @@ func TestRequestConverterRegistryListsSupportedTextConverters(t *testing.T) {
 		{converter: ConverterOpenAIChatToGeminiContent, from: types.RelayFormatOpenAI, to: types.RelayFormatGemini, quality: RequestConverterQualityFair, advancedCustom: true},
 		{converter: ConverterOpenAIChatToOpenAIResponses, from: types.RelayFormatOpenAI, to: types.RelayFormatOpenAIResponses, quality: RequestConverterQualityGood, advancedCustom: true},
 		{converter: ConverterOpenAIResponsesToOpenAIChat, from: types.RelayFormatOpenAIResponses, to: types.RelayFormatOpenAI, quality: RequestConverterQualityGood, advancedCustom: true},
+		{converter: ConverterBedrockConverseToOpenAIChat, from: types.RelayFormatBedrockConverse, to: types.RelayFormatOpenAI, quality: RequestConverterQualityFair},
+		{converter: ConverterOpenAIChatToBedrockConverse, from: types.RelayFormatOpenAI, to: types.RelayFormatBedrockConverse, quality: RequestConverterQualityFair, advancedCustom: true},
+		{converter: ConverterCohereChatToOpenAIChat, from: types.RelayFormatCohereChat, to: types.RelayFormatOpenAI, quality: RequestConverterQualityFair},
+		{converter: ConverterOpenAIChatToCohereChat, from: types.RelayFormatOpenAI, to: types.RelayFormatCohereChat, quality: RequestConverterQualityFair, advancedCustom: true},
 		{
 			converter: requestConverterClaudeToGemini,
 			from:      types.RelayFormatClaude,
@@ -80,6 +84,146 @@ func TestRequestConverterRegistryListsSupportedTextConverters(t *testing.T) {
 			quality:        RequestConverterQualityFair,
 			advancedCustom: true,
 		},
+		{
+			converter: requestConverterBedrockToClaude,
+			from:      types.RelayFormatBedrockConverse,
+			to:        types.RelayFormatClaude,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+		},
+		{
+			converter: requestConverterBedrockToGemini,
+			from:      types.RelayFormatBedrockConverse,
+			to:        types.RelayFormatGemini,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+		},
+		{
+			converter: requestConverterBedrockToResponses,
+			from:      types.RelayFormatBedrockConverse,
+			to:        types.RelayFormatOpenAIResponses,
+			quality:   RequestConverterQualityFair,
+			stepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+		},
+		{
+			converter: requestConverterBedrockToCohere,
+			from:      types.RelayFormatBedrockConverse,
+			to:        types.RelayFormatCohereChat,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		{
+			converter: requestConverterClaudeToBedrock,
+			from:      types.RelayFormatClaude,
+			to:        types.RelayFormatBedrockConverse,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		{
+			converter: requestConverterGeminiToBedrock,
+			from:      types.RelayFormatGemini,
+			to:        types.RelayFormatBedrockConverse,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		{
+			converter: requestConverterResponsesToBedrock,
+			from:      types.RelayFormatOpenAIResponses,
+			to:        types.RelayFormatBedrockConverse,
+			quality:   RequestConverterQualityFair,
+			stepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		{
+			converter: requestConverterCohereToBedrock,
+			from:      types.RelayFormatCohereChat,
+			to:        types.RelayFormatBedrockConverse,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		{
+			converter: requestConverterCohereToClaude,
+			from:      types.RelayFormatCohereChat,
+			to:        types.RelayFormatClaude,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+		},
+		{
+			converter: requestConverterCohereToGemini,
+			from:      types.RelayFormatCohereChat,
+			to:        types.RelayFormatGemini,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+		},
+		{
+			converter: requestConverterCohereToResponses,
+			from:      types.RelayFormatCohereChat,
+			to:        types.RelayFormatOpenAIResponses,
+			quality:   RequestConverterQualityFair,
+			stepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+		},
+		{
+			converter: requestConverterClaudeToCohere,
+			from:      types.RelayFormatClaude,
+			to:        types.RelayFormatCohereChat,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		{
+			converter: requestConverterGeminiToCohere,
+			from:      types.RelayFormatGemini,
+			to:        types.RelayFormatCohereChat,
+			quality:   RequestConverterQualityDiscouraged,
+			stepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		{
+			converter: requestConverterResponsesToCohere,
+			from:      types.RelayFormatOpenAIResponses,
+			to:        types.RelayFormatCohereChat,
+			quality:   RequestConverterQualityFair,
+			stepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
 	}
 
 	require.Len(t, requestConverters, len(tests))
@@ -10,6 +10,8 @@ import (
 
 	"github.com/QuantumNous/new-api/relaykit/dto"
 	"github.com/QuantumNous/new-api/relaykit/relayconvert/convmeta"
+	bedrockconverse "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/bedrock_converse"
+	coherechat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/cohere_chat"
 	geminichat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/gemini_chat"
 	oaichat "github.com/QuantumNous/new-api/relaykit/relayconvert/internal/oai_chat"
 	kitutil "github.com/QuantumNous/new-api/relaykit/relayconvert/kitutil"
@@ -95,6 +97,10 @@ const (
 	ResponseConverterOAIChatToGeminiChat     = "oai_chat_to_gemini_chat_resp"
 	ResponseConverterClaudeMessagesToOAIChat = "claude_messages_to_oai_chat_resp"
 	ResponseConverterGeminiChatToOAIChat     = "gemini_chat_to_oai_chat_resp"
+	ResponseConverterOAIChatToBedrock        = "oai_chat_to_bedrock_converse_resp"
+	ResponseConverterBedrockToOAIChat        = "bedrock_converse_to_oai_chat_resp"
+	ResponseConverterOAIChatToCohereChat     = "oai_chat_to_cohere_chat_resp"
+	ResponseConverterCohereChatToOAIChat     = "cohere_chat_to_oai_chat_resp"
 
 	responseConverterClaudeToGemini    = "claude_messages_to_gemini_chat_resp"
 	responseConverterClaudeToResponses = "claude_messages_to_oai_responses_resp"
@@ -102,6 +108,21 @@ const (
 	responseConverterGeminiToResponses = "gemini_chat_to_oai_responses_resp"
 	responseConverterResponsesToClaude = "oai_responses_to_claude_messages_resp"
 	responseConverterResponsesToGemini = "oai_responses_to_gemini_chat_resp"
+
+	responseConverterBedrockToClaude    = "bedrock_converse_to_claude_messages_resp"
+	responseConverterBedrockToGemini    = "bedrock_converse_to_gemini_chat_resp"
+	responseConverterBedrockToResponses = "bedrock_converse_to_oai_responses_resp"
+	responseConverterBedrockToCohere    = "bedrock_converse_to_cohere_chat_resp"
+	responseConverterClaudeToBedrock    = "claude_messages_to_bedrock_converse_resp"
+	responseConverterGeminiToBedrock    = "gemini_chat_to_bedrock_converse_resp"
+	responseConverterResponsesToBedrock = "oai_responses_to_bedrock_converse_resp"
+	responseConverterCohereToBedrock    = "cohere_chat_to_bedrock_converse_resp"
+	responseConverterCohereToClaude     = "cohere_chat_to_claude_messages_resp"
+	responseConverterCohereToGemini     = "cohere_chat_to_gemini_chat_resp"
+	responseConverterCohereToResponses  = "cohere_chat_to_oai_responses_resp"
+	responseConverterClaudeToCohere     = "claude_messages_to_cohere_chat_resp"
+	responseConverterGeminiToCohere     = "gemini_chat_to_cohere_chat_resp"
+	responseConverterResponsesToCohere  = "oai_responses_to_cohere_chat_resp"
 )
 
 var (
@@ -718,6 +739,10 @@ func inferResponseRelayFormat(response any) (types.RelayFormat, error) {
 		return types.RelayFormatClaude, nil
 	case *dto.GeminiChatResponse, dto.GeminiChatResponse:
 		return types.RelayFormatGemini, nil
+	case *dto.BedrockConverseResponse, dto.BedrockConverseResponse, *dto.BedrockConverseStreamEvent, dto.BedrockConverseStreamEvent:
+		return types.RelayFormatBedrockConverse, nil
+	case *dto.CohereChatResponse, dto.CohereChatResponse, *dto.CohereChatStreamEvent, dto.CohereChatStreamEvent:
+		return types.RelayFormatCohereChat, nil
 	default:
 		return "", fmt.Errorf("unsupported response type %T", response)
 	}
@@ -774,11 +799,41 @@ func canonicalUsageFromResponse(response any) *dto.Usage {
 		return UsageFromGeminiMetadata(resp.GetUsageMetadata(), 0)
 	case dto.GeminiChatResponse:
 		return UsageFromGeminiMetadata(resp.GetUsageMetadata(), 0)
+	case *dto.BedrockConverseResponse:
+		return bedrockconverse.UsageFromBedrockUsage(resp.Usage)
+	case dto.BedrockConverseResponse:
+		return bedrockconverse.UsageFromBedrockUsage(resp.Usage)
+	case *dto.BedrockConverseStreamEvent:
+		return usageFromBedrockStreamEvent(resp)
+	case dto.BedrockConverseStreamEvent:
+		return usageFromBedrockStreamEvent(&resp)
+	case *dto.CohereChatResponse:
+		return coherechat.UsageFromCohereUsage(resp.Usage)
+	case dto.CohereChatResponse:
+		return coherechat.UsageFromCohereUsage(resp.Usage)
+	case *dto.CohereChatStreamEvent:
+		return usageFromCohereStreamEvent(resp)
+	case dto.CohereChatStreamEvent:
+		return usageFromCohereStreamEvent(&resp)
 	default:
 		return nil
 	}
 }
 
+func usageFromBedrockStreamEvent(event *dto.BedrockConverseStreamEvent) *dto.Usage {
+	if event == nil || event.Metadata == nil {
+		return nil
+	}
+	return bedrockconverse.UsageFromBedrockUsage(event.Metadata.Usage)
+}
+
+func usageFromCohereStreamEvent(event *dto.CohereChatStreamEvent) *dto.Usage {
+	if event == nil || event.Delta == nil {
+		return nil
+	}
+	return coherechat.UsageFromCohereUsage(event.Delta.Usage)
+}
+
 func usageFromClaudeResponse(resp *dto.ClaudeResponse) *dto.Usage {
 	if resp == nil {
 		return nil
@@ -1023,6 +1078,166 @@ func convertGeminiChatStreamResponseToOAIChat(_ context.Context, info convmeta.M
 	return openAIResponse, usage, nil
 }
 
+func convertBedrockResponseToOAIChat(_ context.Context, info convmeta.Meta, response any) (any, *dto.Usage, error) {
+	bedrockResponse, err := asBedrockConverseResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	openAIResponse := bedrockconverse.ResponseBedrockConverse2OpenAI(fmt.Sprintf("chatcmpl-%s", kitutil.GetUUID()), kitutil.GetTimestamp(), bedrockResponse)
+	if info != nil && info.HasChannelMeta() {
+		openAIResponse.Model = info.GetUpstreamModelName()
+	}
+	return openAIResponse, bedrockconverse.UsageFromBedrockUsage(bedrockResponse.Usage), nil
+}
+
+func newBedrockToOAIChatStreamState(options ResponseStreamOptions) any {
+	return bedrockconverse.NewBedrockToChatStreamState(options.ID, options.Created)
+}
+
+func convertBedrockStreamResponseToOAIChat(_ context.Context, info convmeta.Meta, response any, state any) ([]any, *dto.Usage, error) {
+	event, err := asBedrockConverseStreamEvent(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamState, ok := state.(*bedrockconverse.BedrockToChatStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("Bedrock Converse to OAI chat stream state is required")
+	}
+	model := ""
+	if info != nil && info.HasChannelMeta() {
+		model = info.GetUpstreamModelName()
+	}
+	responses := streamState.ConvertEvent(event, model)
+	return streamValuesFromAny(responses), usageFromBedrockStreamEvent(event), nil
+}
+
+func finalizeBedrockStreamResponseToOAIChat(_ context.Context, info convmeta.Meta, state any) ([]any, *dto.Usage, error) {
+	streamState, ok := state.(*bedrockconverse.BedrockToChatStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("Bedrock Converse to OAI chat stream state is required")
+	}
+	model := ""
+	if info != nil && info.HasChannelMeta() {
+		model = info.GetUpstreamModelName()
+	}
+	responses := streamState.Finalize(model)
+	return streamValuesFromAny(responses), streamState.Usage(), nil
+}
+
+func convertOAIChatResponseToBedrock(_ context.Context, _ convmeta.Meta, response any) (any, *dto.Usage, error) {
+	chatResponse, err := asOAIChatResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oaichat.ResponseOpenAI2BedrockConverse(chatResponse), UsageFromChatUsage(&chatResponse.Usage), nil
+}
+
+func newOAIChatToBedrockStreamState(_ ResponseStreamOptions) any {
+	return oaichat.NewChatToBedrockStreamState()
+}
+
+func convertOAIChatStreamResponseToBedrock(_ context.Context, _ convmeta.Meta, response any, state any) ([]any, *dto.Usage, error) {
+	chatResponse, err := asOAIChatStreamResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamState, ok := state.(*oaichat.ChatToBedrockStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("OAI chat to Bedrock Converse stream state is required")
+	}
+	events := streamState.ConvertChunk(chatResponse)
+	return streamValuesFromAny(events), canonicalUsageFromResponse(chatResponse), nil
+}
+
+func finalizeOAIChatStreamResponseToBedrock(_ context.Context, _ convmeta.Meta, state any) ([]any, *dto.Usage, error) {
+	streamState, ok := state.(*oaichat.ChatToBedrockStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("OAI chat to Bedrock Converse stream state is required")
+	}
+	events := streamState.Finalize()
+	return streamValuesFromAny(events), streamState.Usage(), nil
+}
+
+func convertCohereResponseToOAIChat(_ context.Context, info convmeta.Meta, response any) (any, *dto.Usage, error) {
+	cohereResponse, err := asCohereChatResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	openAIResponse := coherechat.ResponseCohereChat2OpenAI(fmt.Sprintf("chatcmpl-%s", kitutil.GetUUID()), kitutil.GetTimestamp(), cohereResponse)
+	if info != nil && info.HasChannelMeta() {
+		openAIResponse.Model = info.GetUpstreamModelName()
+	}
+	return openAIResponse, coherechat.UsageFromCohereUsage(cohereResponse.Usage), nil
+}
+
+func newCohereToOAIChatStreamState(options ResponseStreamOptions) any {
+	return coherechat.NewCohereToChatStreamState(options.ID, options.Created)
+}
+
+func convertCohereStreamResponseToOAIChat(_ context.Context, info convmeta.Meta, response any, state any) ([]any, *dto.Usage, error) {
+	event, err := asCohereChatStreamEvent(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamState, ok := state.(*coherechat.CohereToChatStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("Cohere chat to OAI chat stream state is required")
+	}
+	model := ""
+	if info != nil && info.HasChannelMeta() {
+		model = info.GetUpstreamModelName()
+	}
+	responses := streamState.ConvertEvent(event, model)
+	return streamValuesFromAny(responses), usageFromCohereStreamEvent(event), nil
+}
+
+func finalizeCohereStreamResponseToOAIChat(_ context.Context, info convmeta.Meta, state any) ([]any, *dto.Usage, error) {
+	streamState, ok := state.(*coherechat.CohereToChatStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("Cohere chat to OAI chat stream state is required")
+	}
+	model := ""
+	if info != nil && info.HasChannelMeta() {
+		model = info.GetUpstreamModelName()
+	}
+	responses := streamState.Finalize(model)
+	return streamValuesFromAny(responses), streamState.Usage(), nil
+}
+
+func convertOAIChatResponseToCohere(_ context.Context, _ convmeta.Meta, response any) (any, *dto.Usage, error) {
+	chatResponse, err := asOAIChatResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oaichat.ResponseOpenAI2CohereChat(chatResponse), UsageFromChatUsage(&chatResponse.Usage), nil
+}
+
+func newOAIChatToCohereStreamState(options ResponseStreamOptions) any {
+	return oaichat.NewChatToCohereStreamState(options.ID)
+}
+
+func convertOAIChatStreamResponseToCohere(_ context.Context, _ convmeta.Meta, response any, state any) ([]any, *dto.Usage, error) {
+	chatResponse, err := asOAIChatStreamResponse(response)
+	if err != nil {
+		return nil, nil, err
+	}
+	streamState, ok := state.(*oaichat.ChatToCohereStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("OAI chat to Cohere chat stream state is required")
+	}
+	events := streamState.ConvertChunk(chatResponse)
+	return streamValuesFromAny(events), canonicalUsageFromResponse(chatResponse), nil
+}
+
+func finalizeOAIChatStreamResponseToCohere(_ context.Context, _ convmeta.Meta, state any) ([]any, *dto.Usage, error) {
+	streamState, ok := state.(*oaichat.ChatToCohereStreamState)
+	if !ok || streamState == nil {
+		return nil, nil, errors.New("OAI chat to Cohere chat stream state is required")
+	}
+	events := streamState.Finalize()
+	return streamValuesFromAny(events), streamState.Usage(), nil
+}
+
 func fallbackPromptTokens(info convmeta.Meta) int {
 	if info == nil {
 		return 0
@@ -1095,3 +1310,47 @@ func asGeminiChatResponse(response any) (*dto.GeminiChatResponse, error) {
 		return nil, fmt.Errorf("expected Gemini chat response, got %T", response)
 	}
 }
+
+func asBedrockConverseResponse(response any) (*dto.BedrockConverseResponse, error) {
+	switch resp := response.(type) {
+	case *dto.BedrockConverseResponse:
+		return resp, nil
+	case dto.BedrockConverseResponse:
+		return &resp, nil
+	default:
+		return nil, fmt.Errorf("expected Bedrock Converse response, got %T", response)
+	}
+}
+
+func asBedrockConverseStreamEvent(response any) (*dto.BedrockConverseStreamEvent, error) {
+	switch resp := response.(type) {
+	case *dto.BedrockConverseStreamEvent:
+		return resp, nil
+	case dto.BedrockConverseStreamEvent:
+		return &resp, nil
+	default:
+		return nil, fmt.Errorf("expected Bedrock ConverseStream event, got %T", response)
+	}
+}
+
+func asCohereChatResponse(response any) (*dto.CohereChatResponse, error) {
+	switch resp := response.(type) {
+	case *dto.CohereChatResponse:
+		return resp, nil
+	case dto.CohereChatResponse:
+		return &resp, nil
+	default:
+		return nil, fmt.Errorf("expected Cohere chat response, got %T", response)
+	}
+}
+
+func asCohereChatStreamEvent(response any) (*dto.CohereChatStreamEvent, error) {
+	switch resp := response.(type) {
+	case *dto.CohereChatStreamEvent:
+		return resp, nil
+	case dto.CohereChatStreamEvent:
+		return &resp, nil
+	default:
+		return nil, fmt.Errorf("expected Cohere chat stream event, got %T", response)
+	}
+}
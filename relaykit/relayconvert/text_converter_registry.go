@@ -139,6 +139,70 @@ var builtinTextConverters = []TextConverterSpec{
 			Aliases:            []string{ResponseConverterOAIResponsesToOAIChat},
 		},
 	},
+	{
+		ID:      ConverterBedrockConverseToOpenAIChat,
+		From:    types.RelayFormatBedrockConverse,
+		To:      types.RelayFormatOpenAI,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			Convert: convertBedrockRequestToOpenAI,
+		},
+		Resp: TextResponseSide{
+			Convert:            convertBedrockResponseToOAIChat,
+			NewStreamState:     newBedrockToOAIChatStreamState,
+			ConvertStreamChunk: convertBedrockStreamResponseToOAIChat,
+			FinalizeStream:     finalizeBedrockStreamResponseToOAIChat,
+			Aliases:            []string{ResponseConverterBedrockToOAIChat},
+		},
+	},
+	{
+		ID:      ConverterOpenAIChatToBedrockConverse,
+		From:    types.RelayFormatOpenAI,
+		To:      types.RelayFormatBedrockConverse,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			Convert: convertOpenAIRequestToBedrock,
+		},
+		Resp: TextResponseSide{
+			Convert:            convertOAIChatResponseToBedrock,
+			NewStreamState:     newOAIChatToBedrockStreamState,
+			ConvertStreamChunk: convertOAIChatStreamResponseToBedrock,
+			FinalizeStream:     finalizeOAIChatStreamResponseToBedrock,
+			Aliases:            []string{ResponseConverterOAIChatToBedrock},
+		},
+	},
+	{
+		ID:      ConverterCohereChatToOpenAIChat,
+		From:    types.RelayFormatCohereChat,
+		To:      types.RelayFormatOpenAI,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			Convert: convertCohereRequestToOpenAI,
+		},
+		Resp: TextResponseSide{
+			Convert:            convertCohereResponseToOAIChat,
+			NewStreamState:     newCohereToOAIChatStreamState,
+			ConvertStreamChunk: convertCohereStreamResponseToOAIChat,
+			FinalizeStream:     finalizeCohereStreamResponseToOAIChat,
+			Aliases:            []string{ResponseConverterCohereChatToOAIChat},
+		},
+	},
+	{
+		ID:      ConverterOpenAIChatToCohereChat,
+		From:    types.RelayFormatOpenAI,
+		To:      types.RelayFormatCohereChat,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			Convert: convertOpenAIRequestToCohere,
+		},
+		Resp: TextResponseSide{
+			Convert:            convertOAIChatResponseToCohere,
+			NewStreamState:     newOAIChatToCohereStreamState,
+			ConvertStreamChunk: convertOAIChatStreamResponseToCohere,
+			FinalizeStream:     finalizeOAIChatStreamResponseToCohere,
+			Aliases:            []string{ResponseConverterOAIChatToCohereChat},
+		},
+	},
 	{
 		ID:      requestConverterClaudeToGemini,
 		From:    types.RelayFormatClaude,
@@ -247,6 +311,272 @@ var builtinTextConverters = []TextConverterSpec{
 			Aliases: []string{responseConverterResponsesToGemini},
 		},
 	},
+	{
+		ID:      requestConverterBedrockToClaude,
+		From:    types.RelayFormatBedrockConverse,
+		To:      types.RelayFormatClaude,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			Aliases: []string{responseConverterBedrockToClaude},
+		},
+	},
+	{
+		ID:      requestConverterBedrockToGemini,
+		From:    types.RelayFormatBedrockConverse,
+		To:      types.RelayFormatGemini,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			Aliases: []string{responseConverterBedrockToGemini},
+		},
+	},
+	{
+		ID:      requestConverterBedrockToResponses,
+		From:    types.RelayFormatBedrockConverse,
+		To:      types.RelayFormatOpenAIResponses,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			Aliases: []string{responseConverterBedrockToResponses},
+		},
+	},
+	{
+		ID:      requestConverterBedrockToCohere,
+		From:    types.RelayFormatBedrockConverse,
+		To:      types.RelayFormatCohereChat,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			Aliases: []string{responseConverterBedrockToCohere},
+		},
+	},
+	{
+		ID:      requestConverterClaudeToBedrock,
+		From:    types.RelayFormatClaude,
+		To:      types.RelayFormatBedrockConverse,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			Aliases: []string{responseConverterClaudeToBedrock},
+		},
+	},
+	{
+		ID:      requestConverterGeminiToBedrock,
+		From:    types.RelayFormatGemini,
+		To:      types.RelayFormatBedrockConverse,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			Aliases: []string{responseConverterGeminiToBedrock},
+		},
+	},
+	{
+		ID:      requestConverterResponsesToBedrock,
+		From:    types.RelayFormatOpenAIResponses,
+		To:      types.RelayFormatBedrockConverse,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			Aliases: []string{responseConverterResponsesToBedrock},
+		},
+	},
+	{
+		ID:      requestConverterCohereToBedrock,
+		From:    types.RelayFormatCohereChat,
+		To:      types.RelayFormatBedrockConverse,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			Aliases: []string{responseConverterCohereToBedrock},
+		},
+	},
+	{
+		ID:      requestConverterCohereToClaude,
+		From:    types.RelayFormatCohereChat,
+		To:      types.RelayFormatClaude,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			Aliases: []string{responseConverterCohereToClaude},
+		},
+	},
+	{
+		ID:      requestConverterCohereToGemini,
+		From:    types.RelayFormatCohereChat,
+		To:      types.RelayFormatGemini,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			Aliases: []string{responseConverterCohereToGemini},
+		},
+	},
+	{
+		ID:      requestConverterCohereToResponses,
+		From:    types.RelayFormatCohereChat,
+		To:      types.RelayFormatOpenAIResponses,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			Aliases: []string{responseConverterCohereToResponses},
+		},
+	},
+	{
+		ID:      requestConverterClaudeToCohere,
+		From:    types.RelayFormatClaude,
+		To:      types.RelayFormatCohereChat,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			Aliases: []string{responseConverterClaudeToCohere},
+		},
+	},
+	{
+		ID:      requestConverterGeminiToCohere,
+		From:    types.RelayFormatGemini,
+		To:      types.RelayFormatCohereChat,
+		Quality: TextConverterQualityDiscouraged,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			Aliases: []string{responseConverterGeminiToCohere},
+		},
+	},
+	{
+		ID:      requestConverterResponsesToCohere,
+		From:    types.RelayFormatOpenAIResponses,
+		To:      types.RelayFormatCohereChat,
+		Quality: TextConverterQualityFair,
+		Req: TextRequestSide{
+			StepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+		},
+		Resp: TextResponseSide{
+			StepConverters: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			Aliases: []string{responseConverterResponsesToCohere},
+		},
+	},
 }
 
 func init() {
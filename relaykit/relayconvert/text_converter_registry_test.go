@@ -27,6 +27,10 @@ func TestLookupBuiltinTextConverters(t *testing.T) {
 		{id: ConverterOpenAIChatToGeminiContent, from: types.RelayFormatOpenAI, to: types.RelayFormatGemini, quality: TextConverterQualityFair, reqDirect: true, respDirect: true, respAlias: ResponseConverterOAIChatToGeminiChat},
 		{id: ConverterOpenAIChatToOpenAIResponses, from: types.RelayFormatOpenAI, to: types.RelayFormatOpenAIResponses, quality: TextConverterQualityGood, reqDirect: true, respDirect: true, respAlias: ResponseConverterOAIChatToOAIResponses, streamDirect: true},
 		{id: ConverterOpenAIResponsesToOpenAIChat, from: types.RelayFormatOpenAIResponses, to: types.RelayFormatOpenAI, quality: TextConverterQualityGood, reqDirect: true, respDirect: true, respAlias: ResponseConverterOAIResponsesToOAIChat, streamDirect: true},
+		{id: ConverterBedrockConverseToOpenAIChat, from: types.RelayFormatBedrockConverse, to: types.RelayFormatOpenAI, quality: TextConverterQualityFair, reqDirect: true, respDirect: true, respAlias: ResponseConverterBedrockToOAIChat, streamDirect: true},
+		{id: ConverterOpenAIChatToBedrockConverse, from: types.RelayFormatOpenAI, to: types.RelayFormatBedrockConverse, quality: TextConverterQualityFair, reqDirect: true, respDirect: true, respAlias: ResponseConverterOAIChatToBedrock, streamDirect: true},
+		{id: ConverterCohereChatToOpenAIChat, from: types.RelayFormatCohereChat, to: types.RelayFormatOpenAI, quality: TextConverterQualityFair, reqDirect: true, respDirect: true, respAlias: ResponseConverterCohereChatToOAIChat, streamDirect: true},
+		{id: ConverterOpenAIChatToCohereChat, from: types.RelayFormatOpenAI, to: types.RelayFormatCohereChat, quality: TextConverterQualityFair, reqDirect: true, respDirect: true, respAlias: ResponseConverterOAIChatToCohereChat, streamDirect: true},
 		{
 			id:      requestConverterClaudeToGemini,
 			from:    types.RelayFormatClaude,
@@ -111,6 +115,216 @@ func TestLookupBuiltinTextConverters(t *testing.T) {
 			},
 			respAlias: responseConverterResponsesToGemini,
 		},
+		{
+			id:      requestConverterBedrockToClaude,
+			from:    types.RelayFormatBedrockConverse,
+			to:      types.RelayFormatClaude,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			respSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			respAlias: responseConverterBedrockToClaude,
+		},
+		{
+			id:      requestConverterBedrockToGemini,
+			from:    types.RelayFormatBedrockConverse,
+			to:      types.RelayFormatGemini,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			respSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			respAlias: responseConverterBedrockToGemini,
+		},
+		{
+			id:      requestConverterBedrockToResponses,
+			from:    types.RelayFormatBedrockConverse,
+			to:      types.RelayFormatOpenAIResponses,
+			quality: TextConverterQualityFair,
+			reqSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			respSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			respAlias: responseConverterBedrockToResponses,
+		},
+		{
+			id:      requestConverterBedrockToCohere,
+			from:    types.RelayFormatBedrockConverse,
+			to:      types.RelayFormatCohereChat,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respSteps: []string{
+				ConverterBedrockConverseToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respAlias: responseConverterBedrockToCohere,
+		},
+		{
+			id:      requestConverterClaudeToBedrock,
+			from:    types.RelayFormatClaude,
+			to:      types.RelayFormatBedrockConverse,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respSteps: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respAlias: responseConverterClaudeToBedrock,
+		},
+		{
+			id:      requestConverterGeminiToBedrock,
+			from:    types.RelayFormatGemini,
+			to:      types.RelayFormatBedrockConverse,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respSteps: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respAlias: responseConverterGeminiToBedrock,
+		},
+		{
+			id:      requestConverterResponsesToBedrock,
+			from:    types.RelayFormatOpenAIResponses,
+			to:      types.RelayFormatBedrockConverse,
+			quality: TextConverterQualityFair,
+			reqSteps: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respSteps: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respAlias: responseConverterResponsesToBedrock,
+		},
+		{
+			id:      requestConverterCohereToBedrock,
+			from:    types.RelayFormatCohereChat,
+			to:      types.RelayFormatBedrockConverse,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToBedrockConverse,
+			},
+			respAlias: responseConverterCohereToBedrock,
+		},
+		{
+			id:      requestConverterCohereToClaude,
+			from:    types.RelayFormatCohereChat,
+			to:      types.RelayFormatClaude,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			respSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToClaudeMessages,
+			},
+			respAlias: responseConverterCohereToClaude,
+		},
+		{
+			id:      requestConverterCohereToGemini,
+			from:    types.RelayFormatCohereChat,
+			to:      types.RelayFormatGemini,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			respSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToGeminiContent,
+			},
+			respAlias: responseConverterCohereToGemini,
+		},
+		{
+			id:      requestConverterCohereToResponses,
+			from:    types.RelayFormatCohereChat,
+			to:      types.RelayFormatOpenAIResponses,
+			quality: TextConverterQualityFair,
+			reqSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			respSteps: []string{
+				ConverterCohereChatToOpenAIChat,
+				ConverterOpenAIChatToOpenAIResponses,
+			},
+			respAlias: responseConverterCohereToResponses,
+		},
+		{
+			id:      requestConverterClaudeToCohere,
+			from:    types.RelayFormatClaude,
+			to:      types.RelayFormatCohereChat,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respSteps: []string{
+				ConverterClaudeMessagesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respAlias: responseConverterClaudeToCohere,
+		},
+		{
+			id:      requestConverterGeminiToCohere,
+			from:    types.RelayFormatGemini,
+			to:      types.RelayFormatCohereChat,
+			quality: TextConverterQualityDiscouraged,
+			reqSteps: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respSteps: []string{
+				ConverterGeminiContentToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respAlias: responseConverterGeminiToCohere,
+		},
+		{
+			id:      requestConverterResponsesToCohere,
+			from:    types.RelayFormatOpenAIResponses,
+			to:      types.RelayFormatCohereChat,
+			quality: TextConverterQualityFair,
+			reqSteps: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respSteps: []string{
+				ConverterOpenAIResponsesToOpenAIChat,
+				ConverterOpenAIChatToCohereChat,
+			},
+			respAlias: responseConverterResponsesToCohere,
+		},
 	}
 
 	require.Len(t, textConverters, len(tests))
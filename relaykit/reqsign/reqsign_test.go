@@ -0,0 +1,75 @@
+package reqsign
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verifyTestRequest(t *testing.T, r *http.Request, secret string) bool {
+	t.Helper()
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	return Verify(secret, StringToSign(r.Method, r.URL.RequestURI(), timestamp, r.Header.Get(HeaderNonce), BodyHash(body)), r.Header.Get(HeaderSignature))
+}
+
+func TestStringToSignAndSign(t *testing.T) {
+	assert.Equal(t, EmptyBodyHash, BodyHash(nil))
+	stringToSign := StringToSign("post", "/v1/chat/completions?stream=true", 1700000000, "abc", BodyHash([]byte(`{"model":"x"}`)))
+	assert.Equal(t, "POST\n/v1/chat/completions?stream=true\n1700000000\nabc\n"+BodyHash([]byte(`{"model":"x"}`)), stringToSign)
+
+	signature := Sign("secret", stringToSign)
+	assert.Len(t, signature, 64)
+	assert.True(t, Verify("secret", stringToSign, strings.ToUpper(signature)))
+	assert.False(t, Verify("other", stringToSign, signature))
+	assert.False(t, Verify("secret", stringToSign+"x", signature))
+}
+
+func TestSignRequestKeepsBodyReadable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://gateway.local/v1/chat/completions?x=1", strings.NewReader(`{"model":"gpt-4o-mini"}`))
+	now := time.Unix(1700000000, 0)
+	require.NoError(t, signRequestAt(req, "secret", now))
+	assert.Equal(t, "1700000000", req.Header.Get(HeaderTimestamp))
+	assert.Len(t, req.Header.Get(HeaderNonce), 32)
+	assert.True(t, verifyTestRequest(t, req, "secret"))
+
+	req = httptest.NewRequest(http.MethodGet, "http://gateway.local/v1/models", nil)
+	require.NoError(t, SignRequest(req, "secret"))
+	assert.True(t, verifyTestRequest(t, req, "secret"))
+
+	assert.Error(t, SignRequest(req, ""))
+}
+
+func TestTransportSignsEveryRequest(t *testing.T) {
+	nonces := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifyTestRequest(t, r, "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		nonces[r.Header.Get(HeaderNonce)] = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Secret: "secret"}}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/embeddings", strings.NewReader(`{"input":"hi"}`))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Empty(t, req.Header.Get(HeaderSignature), "the caller's request is not modified")
+	}
+	assert.Len(t, nonces, 2, "each request carries a fresh nonce")
+}
@@ -7,6 +7,8 @@ const (
 	RelayFormatClaude                                = "claude"
 	RelayFormatGemini                                = "gemini"
 	RelayFormatOpenAIResponses                       = "openai_responses"
+	RelayFormatBedrockConverse                       = "bedrock_converse"
+	RelayFormatCohereChat                            = "cohere_chat"
 	RelayFormatOpenAIResponsesCompaction             = "openai_responses_compaction"
 	RelayFormatOpenAIAlphaSearch                     = "openai_alpha_search"
 	RelayFormatOpenAIAudio                           = "openai_audio"
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/gin-gonic/gin"
+)
+
+// Admin console routes guarded by fine-grained authz permissions. Routes that
+// were root only (system settings, system tasks) keep working for root, which is
+// a superuser role, and can now be delegated to admins with an explicit grant.
+
+func registerUserAdminRoutes(userRoute *gin.RouterGroup) {
+	adminRoute := userRoute.Group("/")
+	adminRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(adminRoute, userAdminPermissionRoutes)
+}
+
+func registerSubscriptionAdminRoutes(apiRouter *gin.RouterGroup) {
+	subscriptionAdminRoute := apiRouter.Group("/subscription/admin")
+	subscriptionAdminRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(subscriptionAdminRoute, subscriptionAdminPermissionRoutes)
+}
+
+func registerOptionRoutes(apiRouter *gin.RouterGroup) {
+	optionRoute := apiRouter.Group("/option")
+	optionRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(optionRoute, optionPermissionRoutes)
+}
+
+func registerRedemptionRoutes(apiRouter *gin.RouterGroup) {
+	redemptionRoute := apiRouter.Group("/redemption")
+	redemptionRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(redemptionRoute, redemptionPermissionRoutes)
+}
+
+func registerSystemTaskRoutes(apiRouter *gin.RouterGroup) {
+	systemTaskRoute := apiRouter.Group("/system-task")
+	systemTaskRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(systemTaskRoute, systemTaskPermissionRoutes)
+}
+
+// registerModelMetaRoutes mounts model metadata, vendor and prefill group management.
+func registerModelMetaRoutes(apiRouter *gin.RouterGroup) {
+	adminRoute := apiRouter.Group("")
+	adminRoute.Use(middleware.AdminAuth())
+	handlePermissionRoutes(adminRoute, modelMetaPermissionRoutes)
+}
+
+// registerUsageLogAdminRoutes mounts the all-user log, usage data and task
+// views. Their groups also hold self-service routes, so AdminAuth is attached
+// per route instead of per group.
+func registerUsageLogAdminRoutes(apiRouter *gin.RouterGroup) {
+	handlePermissionRoutes(apiRouter, usageLogPermissionRoutes, middleware.AdminAuth())
+}
+
+var userAdminPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/", permission: authz.UserRead, handler: controller.GetAllUsers},
+	{method: http.MethodGet, path: "/topup", permission: authz.TopUpRead, handler: controller.GetAllTopUps},
+	{method: http.MethodPost, path: "/topup/complete", permission: authz.TopUpWrite, handler: controller.AdminCompleteTopUp},
+	{method: http.MethodGet, path: "/search", permission: authz.UserRead, handler: controller.SearchUsers},
+	{method: http.MethodGet, path: "/:id/oauth/bindings", permission: authz.UserRead, handler: controller.GetUserOAuthBindingsByAdmin},
+	{method: http.MethodDelete, path: "/:id/oauth/bindings/:provider_id", permission: authz.UserOperate, handler: controller.UnbindCustomOAuthByAdmin},
+	{method: http.MethodDelete, path: "/:id/bindings/:binding_type", permission: authz.UserOperate, handler: controller.AdminClearUserBinding},
+	{method: http.MethodGet, path: "/:id", permission: authz.UserRead, handler: controller.GetUser},
+	{method: http.MethodPost, path: "/", permission: authz.UserWrite, handler: controller.CreateUser},
+	// ManageUser checks the permission of each action (operate, quota, write) itself
+	{method: http.MethodPost, path: "/manage", permission: authz.UserRead, handler: controller.ManageUser},
+	{method: http.MethodPut, path: "/", permission: authz.UserWrite, handler: controller.UpdateUser},
+	{method: http.MethodDelete, path: "/:id", permission: authz.UserWrite, handler: controller.DeleteUser},
+	{method: http.MethodDelete, path: "/:id/reset_passkey", permission: authz.UserOperate, handler: controller.AdminResetPasskey},
+	{method: http.MethodGet, path: "/2fa/stats", permission: authz.UserRead, handler: controller.Admin2FAStats},
+	{method: http.MethodDelete, path: "/:id/2fa", permission: authz.UserOperate, handler: controller.AdminDisable2FA},
+	{method: http.MethodGet, path: "/:id/tokens", permission: authz.TokenRead, handler: controller.AdminGetUserTokens},
+	{method: http.MethodPost, path: "/:id/tokens/:token_id/status", permission: authz.TokenOperate, handler: controller.AdminUpdateUserTokenStatus},
+}
+
+var subscriptionAdminPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/plans", permission: authz.SubscriptionRead, handler: controller.AdminListSubscriptionPlans},
+	{method: http.MethodPost, path: "/plans", permission: authz.SubscriptionWrite, handler: controller.AdminCreateSubscriptionPlan},
+	{method: http.MethodPut, path: "/plans/:id", permission: authz.SubscriptionWrite, handler: controller.AdminUpdateSubscriptionPlan},
+	{method: http.MethodPatch, path: "/plans/:id", permission: authz.SubscriptionWrite, handler: controller.AdminUpdateSubscriptionPlanStatus},
+	{method: http.MethodPost, path: "/bind", permission: authz.SubscriptionWrite, handler: controller.AdminBindSubscription},
+	{method: http.MethodPost, path: "/plans/:id/subscriptions/reset", permission: authz.SubscriptionWrite, handler: controller.AdminResetPlanSubscriptions},
+	{method: http.MethodGet, path: "/users/:id/subscriptions", permission: authz.SubscriptionRead, handler: controller.AdminListUserSubscriptions},
+	{method: http.MethodPost, path: "/users/:id/subscriptions", permission: authz.SubscriptionWrite, handler: controller.AdminCreateUserSubscription},
+	{method: http.MethodPost, path: "/users/:id/subscriptions/reset", permission: authz.SubscriptionWrite, handler: controller.AdminResetUserSubscriptionsByPlan},
+	{method: http.MethodPost, path: "/user_subscriptions/:id/invalidate", permission: authz.SubscriptionWrite, handler: controller.AdminInvalidateUserSubscription},
+	{method: http.MethodDelete, path: "/user_subscriptions/:id", permission: authz.SubscriptionWrite, handler: controller.AdminDeleteUserSubscription},
+}
+
+var optionPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/", permission: authz.OptionRead, handler: controller.GetOptions},
+	{method: http.MethodPut, path: "/", permission: authz.OptionWrite, handler: controller.UpdateOption},
+	{method: http.MethodPost, path: "/payment_compliance", permission: authz.OptionWrite, handler: controller.ConfirmPaymentCompliance},
+	{method: http.MethodGet, path: "/channel_affinity_cache", permission: authz.OptionRead, handler: controller.GetChannelAffinityCacheStats},
+	{method: http.MethodDelete, path: "/channel_affinity_cache", permission: authz.OptionWrite, handler: controller.ClearChannelAffinityCache},
+	{method: http.MethodPost, path: "/rest_model_ratio", permission: authz.OptionWrite, handler: controller.ResetModelRatio},
+	{method: http.MethodPost, path: "/ldap/test", permission: authz.OptionWrite, handler: controller.TestLDAPConnection},
+	{method: http.MethodGet, path: "/waffo-pancake/catalog", permission: authz.OptionRead, handler: controller.ListWaffoPancakeCatalog},
+	{method: http.MethodPost, path: "/waffo-pancake/pair", permission: authz.OptionWrite, handler: controller.CreateWaffoPancakePair},
+	{method: http.MethodPost, path: "/waffo-pancake/save", permission: authz.OptionWrite, handler: controller.SaveWaffoPancake},
+	{method: http.MethodPost, path: "/waffo-pancake/subscription-product", permission: authz.OptionWrite, handler: controller.CreateWaffoPancakeSubscriptionProduct},
+	{method: http.MethodGet, path: "/waffo-pancake/subscription-product-options", permission: authz.OptionRead, handler: controller.ListWaffoPancakeSubscriptionProductOptions},
+}
+
+var redemptionPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/", permission: authz.RedemptionRead, handler: controller.GetAllRedemptions},
+	{method: http.MethodGet, path: "/search", permission: authz.RedemptionRead, handler: controller.SearchRedemptions},
+	{method: http.MethodGet, path: "/:id", permission: authz.RedemptionRead, handler: controller.GetRedemption},
+	{method: http.MethodPost, path: "/", permission: authz.RedemptionWrite, handler: controller.AddRedemption},
+	{method: http.MethodPut, path: "/", permission: authz.RedemptionWrite, handler: controller.UpdateRedemption},
+	{method: http.MethodDelete, path: "/invalid", permission: authz.RedemptionWrite, handler: controller.DeleteInvalidRedemption},
+	{method: http.MethodDelete, path: "/:id", permission: authz.RedemptionWrite, handler: controller.DeleteRedemption},
+}
+
+var systemTaskPermissionRoutes = []permissionRoute{
+	{method: http.MethodPost, path: "/log-cleanup", permission: authz.SystemTaskWrite, handler: controller.CreateLogCleanupSystemTask},
+	{method: http.MethodGet, path: "/list", permission: authz.SystemTaskRead, handler: controller.ListSystemTasks},
+	{method: http.MethodGet, path: "/current", permission: authz.SystemTaskRead, handler: controller.GetCurrentSystemTask},
+	{method: http.MethodGet, path: "/:task_id", permission: authz.SystemTaskRead, handler: controller.GetSystemTask},
+}
+
+var modelMetaPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/prefill_group/", permission: authz.ModelRead, handler: controller.GetPrefillGroups},
+	{method: http.MethodPost, path: "/prefill_group/", permission: authz.ModelWrite, handler: controller.CreatePrefillGroup},
+	{method: http.MethodPut, path: "/prefill_group/", permission: authz.ModelWrite, handler: controller.UpdatePrefillGroup},
+	{method: http.MethodDelete, path: "/prefill_group/:id", permission: authz.ModelWrite, handler: controller.DeletePrefillGroup},
+	{method: http.MethodGet, path: "/vendors/", permission: authz.ModelRead, handler: controller.GetAllVendors},
+	{method: http.MethodGet, path: "/vendors/search", permission: authz.ModelRead, handler: controller.SearchVendors},
+	{method: http.MethodGet, path: "/vendors/:id", permission: authz.ModelRead, handler: controller.GetVendorMeta},
+	{method: http.MethodPost, path: "/vendors/", permission: authz.ModelWrite, handler: controller.CreateVendorMeta},
+	{method: http.MethodPut, path: "/vendors/", permission: authz.ModelWrite, handler: controller.UpdateVendorMeta},
+	{method: http.MethodDelete, path: "/vendors/:id", permission: authz.ModelWrite, handler: controller.DeleteVendorMeta},
+	{method: http.MethodGet, path: "/models/sync_upstream/preview", permission: authz.ModelRead, handler: controller.SyncUpstreamPreview},
+	{method: http.MethodPost, path: "/models/sync_upstream", permission: authz.ModelWrite, handler: controller.SyncUpstreamModels},
+	{method: http.MethodGet, path: "/models/missing", permission: authz.ModelRead, handler: controller.GetMissingModels},
+	{method: http.MethodGet, path: "/models/", permission: authz.ModelRead, handler: controller.GetAllModelsMeta},
+	{method: http.MethodGet, path: "/models/search", permission: authz.ModelRead, handler: controller.SearchModelsMeta},
+	{method: http.MethodGet, path: "/models/:id", permission: authz.ModelRead, handler: controller.GetModelMeta},
+	{method: http.MethodPost, path: "/models/", permission: authz.ModelWrite, handler: controller.CreateModelMeta},
+	{method: http.MethodPut, path: "/models/", permission: authz.ModelWrite, handler: controller.UpdateModelMeta},
+	{method: http.MethodDelete, path: "/models/:id", permission: authz.ModelWrite, handler: controller.DeleteModelMeta},
+}
+
+var usageLogPermissionRoutes = []permissionRoute{
+	{method: http.MethodGet, path: "/log/", permission: authz.LogRead, handler: controller.GetAllLogs},
+	{method: http.MethodGet, path: "/log/stat", permission: authz.LogRead, handler: controller.GetLogsStat},
+	{method: http.MethodGet, path: "/log/end_user", permission: authz.LogRead, handler: controller.GetAllEndUserUsage},
+	{method: http.MethodGet, path: "/log/channel_affinity_usage_cache", permission: authz.LogRead, handler: controller.GetChannelAffinityUsageCacheStats},
+	{method: http.MethodGet, path: "/log/search", permission: authz.LogRead, handler: controller.SearchAllLogs},
+	{method: http.MethodPost, path: "/log/export", permission: authz.LogExport, handler: controller.CreateUsageExport},
+	{method: http.MethodGet, path: "/log/export/:task_id", permission: authz.LogExport, handler: controller.GetUsageExport},
+	{method: http.MethodGet, path: "/data/", permission: authz.LogRead, handler: controller.GetAllQuotaDates},
+	{method: http.MethodGet, path: "/data/users", permission: authz.LogRead, handler: controller.GetQuotaDatesByUser},
+	{method: http.MethodGet, path: "/data/flow", permission: authz.LogRead, handler: controller.GetAllFlowQuotaDates},
+	{method: http.MethodGet, path: "/data/anomaly", permission: authz.LogRead, handler: controller.GetAllUsageAnomalies},
+	{method: http.MethodGet, path: "/data/forecast", permission: authz.LogRead, handler: controller.GetAllUsageForecasts},
+	{method: http.MethodGet, path: "/mj/", permission: authz.LogRead, handler: controller.GetAllMidjourney},
+	{method: http.MethodGet, path: "/task/", permission: authz.LogRead, handler: controller.GetAllTask},
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/service/authz"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRoutesRegisterWithoutConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	api := engine.Group("/api")
+
+	require.NotPanics(t, func() {
+		registerUserAdminRoutes(api.Group("/user"))
+		registerSubscriptionAdminRoutes(api)
+		registerOptionRoutes(api)
+		registerRedemptionRoutes(api)
+		registerUsageLogAdminRoutes(api)
+		registerSystemTaskRoutes(api)
+		registerModelMetaRoutes(api)
+		registerAuthzRoutes(api)
+	})
+}
+
+func TestSupportRoutesUseSeparatePermissions(t *testing.T) {
+	assertRoutePermission(t, userAdminPermissionRoutes, http.MethodGet, "/:id", authz.UserRead, controller.GetUser)
+	assertRoutePermission(t, userAdminPermissionRoutes, http.MethodPut, "/", authz.UserWrite, controller.UpdateUser)
+	assertRoutePermission(t, userAdminPermissionRoutes, http.MethodPost, "/topup/complete", authz.TopUpWrite, controller.AdminCompleteTopUp)
+	assertRoutePermission(t, userAdminPermissionRoutes, http.MethodGet, "/:id/tokens", authz.TokenRead, controller.AdminGetUserTokens)
+	assertRoutePermission(t, userAdminPermissionRoutes, http.MethodPost, "/:id/tokens/:token_id/status", authz.TokenOperate, controller.AdminUpdateUserTokenStatus)
+	assertRoutePermission(t, usageLogPermissionRoutes, http.MethodGet, "/log/", authz.LogRead, controller.GetAllLogs)
+	assertRoutePermission(t, usageLogPermissionRoutes, http.MethodGet, "/data/users", authz.LogRead, controller.GetQuotaDatesByUser)
+	assertRoutePermission(t, usageLogPermissionRoutes, http.MethodGet, "/data/anomaly", authz.LogRead, controller.GetAllUsageAnomalies)
+	assertRoutePermission(t, usageLogPermissionRoutes, http.MethodPost, "/log/export", authz.LogExport, controller.CreateUsageExport)
+	assertRoutePermission(t, redemptionPermissionRoutes, http.MethodPost, "/", authz.RedemptionWrite, controller.AddRedemption)
+	assertRoutePermission(t, subscriptionAdminPermissionRoutes, http.MethodPost, "/bind", authz.SubscriptionWrite, controller.AdminBindSubscription)
+	assertRoutePermission(t, modelMetaPermissionRoutes, http.MethodPost, "/models/sync_upstream", authz.ModelWrite, controller.SyncUpstreamModels)
+	assertRoutePermission(t, optionPermissionRoutes, http.MethodPut, "/", authz.OptionWrite, controller.UpdateOption)
+	assertRoutePermission(t, systemTaskPermissionRoutes, http.MethodPost, "/log-cleanup", authz.SystemTaskWrite, controller.CreateLogCleanupSystemTask)
+}
+
+func assertRoutePermission(t *testing.T, routes []permissionRoute, method string, path string, permission authz.Permission, handler any) {
+	t.Helper()
+	for _, route := range routes {
+		if route.method == method && route.path == path {
+			assert.Equal(t, permission, route.permission)
+			assert.Equal(t, reflect.ValueOf(handler).Pointer(), reflect.ValueOf(route.handler).Pointer())
+			return
+		}
+	}
+	t.Fatalf("route %s %s not found", method, path)
+}
@@ -9,11 +9,21 @@ import (
 
 // registerAuthzRoutes mounts the authorization API under its own /authz
 // namespace. GET /authz/catalog returns the permission schema (resources,
-// actions, and role baselines) used by the client permission editor.
+// actions, and role baselines) used by the client permission editor. Custom
+// roles and their assignment to admin users are managed by root only.
 func registerAuthzRoutes(apiRouter *gin.RouterGroup) {
 	authzRoute := apiRouter.Group("/authz")
 	authzRoute.Use(middleware.AdminAuth())
 	{
 		authzRoute.GET("/catalog", controller.GetPermissionCatalog)
 	}
+	rootRoute := authzRoute.Group("")
+	rootRoute.Use(middleware.RootAuth())
+	{
+		rootRoute.GET("/roles", controller.GetAuthzRoles)
+		rootRoute.POST("/roles", controller.CreateAuthzRole)
+		rootRoute.PUT("/roles/:key", controller.UpdateAuthzRole)
+		rootRoute.DELETE("/roles/:key", controller.DeleteAuthzRole)
+		rootRoute.PUT("/users/:id/role", controller.SetUserAuthzRole)
+	}
 }
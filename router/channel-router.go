@@ -28,11 +28,17 @@ func registerChannelRoutes(apiRouter *gin.RouterGroup) {
 		controller.GetChannelKey,
 	)
 
-	for _, route := range channelPermissionRoutes {
-		channelRoute.Handle(route.method, route.path,
-			middleware.RequirePermission(route.permission),
-			route.handler,
-		)
+	handlePermissionRoutes(channelRoute, channelPermissionRoutes)
+}
+
+// handlePermissionRoutes mounts each route behind RequirePermission. Extra
+// middlewares (such as AdminAuth on groups shared with self-service routes)
+// run before the permission check.
+func handlePermissionRoutes(group *gin.RouterGroup, routes []permissionRoute, middlewares ...gin.HandlerFunc) {
+	for _, route := range routes {
+		handlers := append([]gin.HandlerFunc{}, middlewares...)
+		handlers = append(handlers, middleware.RequirePermission(route.permission), route.handler)
+		group.Handle(route.method, route.path, handlers...)
 	}
 }
 
@@ -45,6 +51,24 @@ var channelPermissionRoutes = []permissionRoute{
 	{method: http.MethodGet, path: "/:id", permission: authz.ChannelRead, handler: controller.GetChannel},
 	{method: http.MethodGet, path: "/test", permission: authz.ChannelOperate, handler: controller.TestAllChannels},
 	{method: http.MethodGet, path: "/test/:id", permission: authz.ChannelOperate, handler: controller.TestChannel},
+	{method: http.MethodPost, path: "/replay", permission: authz.ChannelOperate, handler: controller.ReplayChannelRequest},
+	{method: http.MethodGet, path: "/shadow_rules", permission: authz.ChannelRead, handler: controller.GetShadowRules},
+	{method: http.MethodPost, path: "/shadow_rules", permission: authz.ChannelWrite, handler: controller.CreateShadowRule},
+	{method: http.MethodPut, path: "/shadow_rules/:id", permission: authz.ChannelWrite, handler: controller.UpdateShadowRule},
+	{method: http.MethodDelete, path: "/shadow_rules/:id", permission: authz.ChannelWrite, handler: controller.DeleteShadowRule},
+	{method: http.MethodPost, path: "/shadow_rules/:id/reset_usage", permission: authz.ChannelWrite, handler: controller.ResetShadowRuleUsage},
+	{method: http.MethodGet, path: "/shadow_rules/:id/metrics", permission: authz.ChannelRead, handler: controller.GetShadowRuleMetrics},
+	{method: http.MethodGet, path: "/shadow_rules/:id/summary", permission: authz.ChannelRead, handler: controller.GetShadowRuleSummary},
+	{method: http.MethodGet, path: "/traffic_splits", permission: authz.ChannelRead, handler: controller.GetTrafficSplitRules},
+	{method: http.MethodPost, path: "/traffic_splits", permission: authz.ChannelWrite, handler: controller.CreateTrafficSplitRule},
+	{method: http.MethodPut, path: "/traffic_splits/:id", permission: authz.ChannelWrite, handler: controller.UpdateTrafficSplitRule},
+	{method: http.MethodDelete, path: "/traffic_splits/:id", permission: authz.ChannelWrite, handler: controller.DeleteTrafficSplitRule},
+	{method: http.MethodPost, path: "/traffic_splits/:id/restart", permission: authz.ChannelWrite, handler: controller.RestartTrafficSplitRule},
+	{method: http.MethodGet, path: "/traffic_splits/:id/stats", permission: authz.ChannelRead, handler: controller.GetTrafficSplitRuleStats},
+	{method: http.MethodGet, path: "/hedge_policies", permission: authz.ChannelRead, handler: controller.GetHedgePolicies},
+	{method: http.MethodPost, path: "/hedge_policies", permission: authz.ChannelWrite, handler: controller.CreateHedgePolicy},
+	{method: http.MethodPut, path: "/hedge_policies/:id", permission: authz.ChannelWrite, handler: controller.UpdateHedgePolicy},
+	{method: http.MethodDelete, path: "/hedge_policies/:id", permission: authz.ChannelWrite, handler: controller.DeleteHedgePolicy},
 	{method: http.MethodGet, path: "/update_balance", permission: authz.ChannelOperate, handler: controller.UpdateAllChannelsBalance},
 	{method: http.MethodGet, path: "/update_balance/:id", permission: authz.ChannelOperate, handler: controller.UpdateChannelBalance},
 	{method: http.MethodPost, path: "/", permission: authz.ChannelSensitiveWrite, handler: controller.AddChannel},
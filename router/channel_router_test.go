@@ -18,6 +18,34 @@ func TestChannelStatusRoutesUseOperatePermission(t *testing.T) {
 	assertChannelRoutePermission(t, http.MethodPut, "/", authz.ChannelWrite, controller.UpdateChannel)
 }
 
+func TestChannelReplayRouteUsesOperatePermission(t *testing.T) {
+	assertChannelRoutePermission(t, http.MethodPost, "/replay", authz.ChannelOperate, controller.ReplayChannelRequest)
+}
+
+func TestShadowRuleRoutesPermissions(t *testing.T) {
+	assertChannelRoutePermission(t, http.MethodGet, "/shadow_rules", authz.ChannelRead, controller.GetShadowRules)
+	assertChannelRoutePermission(t, http.MethodPost, "/shadow_rules", authz.ChannelWrite, controller.CreateShadowRule)
+	assertChannelRoutePermission(t, http.MethodPut, "/shadow_rules/:id", authz.ChannelWrite, controller.UpdateShadowRule)
+	assertChannelRoutePermission(t, http.MethodDelete, "/shadow_rules/:id", authz.ChannelWrite, controller.DeleteShadowRule)
+	assertChannelRoutePermission(t, http.MethodGet, "/shadow_rules/:id/summary", authz.ChannelRead, controller.GetShadowRuleSummary)
+}
+
+func TestTrafficSplitRoutesPermissions(t *testing.T) {
+	assertChannelRoutePermission(t, http.MethodGet, "/traffic_splits", authz.ChannelRead, controller.GetTrafficSplitRules)
+	assertChannelRoutePermission(t, http.MethodPost, "/traffic_splits", authz.ChannelWrite, controller.CreateTrafficSplitRule)
+	assertChannelRoutePermission(t, http.MethodPut, "/traffic_splits/:id", authz.ChannelWrite, controller.UpdateTrafficSplitRule)
+	assertChannelRoutePermission(t, http.MethodDelete, "/traffic_splits/:id", authz.ChannelWrite, controller.DeleteTrafficSplitRule)
+	assertChannelRoutePermission(t, http.MethodPost, "/traffic_splits/:id/restart", authz.ChannelWrite, controller.RestartTrafficSplitRule)
+	assertChannelRoutePermission(t, http.MethodGet, "/traffic_splits/:id/stats", authz.ChannelRead, controller.GetTrafficSplitRuleStats)
+}
+
+func TestHedgePolicyRoutesPermissions(t *testing.T) {
+	assertChannelRoutePermission(t, http.MethodGet, "/hedge_policies", authz.ChannelRead, controller.GetHedgePolicies)
+	assertChannelRoutePermission(t, http.MethodPost, "/hedge_policies", authz.ChannelWrite, controller.CreateHedgePolicy)
+	assertChannelRoutePermission(t, http.MethodPut, "/hedge_policies/:id", authz.ChannelWrite, controller.UpdateHedgePolicy)
+	assertChannelRoutePermission(t, http.MethodDelete, "/hedge_policies/:id", authz.ChannelWrite, controller.DeleteHedgePolicy)
+}
+
 func TestChannelDeleteRoutesUseSensitiveWritePermission(t *testing.T) {
 	assertChannelRoutePermission(t, http.MethodDelete, "/:id", authz.ChannelSensitiveWrite, controller.DeleteChannel)
 	assertChannelRoutePermission(t, http.MethodPost, "/batch", authz.ChannelSensitiveWrite, controller.DeleteChannelBatch)
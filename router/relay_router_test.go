@@ -11,8 +11,10 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestListModelsSupportsOpenAIAndGeminiAuthentication(t *testing.T) {
@@ -105,6 +107,10 @@ func setupRelayRouterTestDB(t *testing.T) {
 	common.SQLitePath = fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
 	common.SetDatabaseTypes(common.DatabaseTypeSQLite, common.DatabaseTypeSQLite)
 	require.NoError(t, os.Setenv("SQL_DSN", "local"))
+	// Non-master nodes expect the master to have created the options table already
+	seedDB, err := gorm.Open(sqlite.Open(common.SQLitePath), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, seedDB.AutoMigrate(&model.Option{}))
 	require.NoError(t, model.InitDB())
 	model.LOG_DB = model.DB
 	require.NoError(t, model.DB.AutoMigrate(&model.User{}, &model.Token{}, &model.Ability{}))
@@ -113,6 +119,9 @@ func setupRelayRouterTestDB(t *testing.T) {
 		if sqlDB, err := model.DB.DB(); err == nil {
 			_ = sqlDB.Close()
 		}
+		if sqlDB, err := seedDB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
 		common.IsMasterNode = originalIsMasterNode
 		common.RedisEnabled = originalRedisEnabled
 		common.SQLitePath = originalSQLitePath
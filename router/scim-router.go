@@ -0,0 +1,36 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/controller"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetScimRouter mounts the SCIM 2.0 provisioning API at the RFC 7644 base
+// path identity providers expect. It lives outside /api because SCIM clients
+// authenticate with the static scim.bearer_secret instead of a user session.
+func SetScimRouter(router *gin.Engine) {
+	scimRouter := router.Group("/scim/v2")
+	scimRouter.Use(middleware.RouteTag("scim"))
+	scimRouter.Use(middleware.BodyStorageCleanup())
+	scimRouter.Use(middleware.GlobalAPIRateLimit())
+	scimRouter.Use(middleware.ScimAuth())
+	{
+		scimRouter.GET("/ServiceProviderConfig", controller.ScimServiceProviderConfig)
+
+		scimRouter.GET("/Users", controller.ScimListUsers)
+		scimRouter.POST("/Users", controller.ScimCreateUser)
+		scimRouter.GET("/Users/:id", controller.ScimGetUser)
+		scimRouter.PUT("/Users/:id", controller.ScimReplaceUser)
+		scimRouter.PATCH("/Users/:id", controller.ScimPatchUser)
+		scimRouter.DELETE("/Users/:id", controller.ScimDeleteUser)
+
+		scimRouter.GET("/Groups", controller.ScimListGroups)
+		scimRouter.POST("/Groups", controller.ScimCreateGroup)
+		scimRouter.GET("/Groups/:id", controller.ScimGetGroup)
+		scimRouter.PUT("/Groups/:id", controller.ScimReplaceGroup)
+		scimRouter.PATCH("/Groups/:id", controller.ScimPatchGroup)
+		scimRouter.DELETE("/Groups/:id", controller.ScimDeleteGroup)
+	}
+}
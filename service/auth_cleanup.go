@@ -10,8 +10,9 @@ import (
 
 const authArtifactCleanupInterval = time.Hour
 
-// StartAuthArtifactCleanup removes expired dashboard Sessions and old
-// one-time authentication flows. Only the master instance performs cleanup.
+// StartAuthArtifactCleanup removes expired dashboard Sessions, old one-time
+// authentication flows, expired workload identity gateway keys and expired
+// child tokens. Only the master instance performs cleanup.
 func StartAuthArtifactCleanup() {
 	if !common.IsMasterNode {
 		return
@@ -48,4 +49,10 @@ func cleanupAuthArtifacts() {
 	if err := model.DeleteExpiredAuthFlows(now); err != nil {
 		common.SysError("failed to delete expired authentication flows: " + err.Error())
 	}
+	if err := model.DeleteExpiredWorkloadTokens(now.Unix()); err != nil {
+		common.SysError("failed to delete expired workload identity tokens: " + err.Error())
+	}
+	if err := model.DeleteExpiredChildTokens(now.Unix()); err != nil {
+		common.SysError("failed to delete expired child tokens: " + err.Error())
+	}
 }
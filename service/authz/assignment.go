@@ -2,19 +2,27 @@ package authz
 
 import "github.com/QuantumNous/new-api/common"
 
-// resolveSubjectRoles returns the role keys assigned to a subject. The mapping
-// is derived from the caller's system role.
+// resolveSubjectRoles returns the role keys assigned to a subject. Root maps to
+// the root role; an admin maps to its enabled custom role when one is assigned
+// and to the built-in admin role otherwise.
 var resolveSubjectRoles = func(userID int, systemRole int) []string {
 	switch {
 	case systemRole >= common.RoleRootUser:
 		return []string{BuiltInRoleRoot}
 	case systemRole >= common.RoleAdminUser:
-		return []string{BuiltInRoleAdmin}
+		return []string{baselineRoleKey(userID)}
 	default:
 		return nil
 	}
 }
 
-// managedRoleKey is the role whose baseline per-user overrides are expressed
-// relative to.
-const managedRoleKey = BuiltInRoleAdmin
+// baselineRoleKey is the role whose grants a user's per-user overrides are
+// expressed relative to. A disabled custom role falls back to the admin role.
+func baselineRoleKey(userID int) string {
+	if roleKey := UserRoleKey(userID); roleKey != "" {
+		if role, ok := customRole(roleKey); ok && role.Enabled {
+			return roleKey
+		}
+	}
+	return BuiltInRoleAdmin
+}
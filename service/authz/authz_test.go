@@ -51,6 +51,15 @@ func TestInitSeedsBuiltInRolesAndPoliciesOnce(t *testing.T) {
 	assert.True(t, Can(2, common.RoleAdminUser, ChannelWrite))
 	assert.False(t, Can(2, common.RoleAdminUser, ChannelSensitiveWrite))
 	assert.False(t, Can(3, common.RoleCommonUser, ChannelRead))
+
+	// Areas that were admin-only keep the admin baseline; root-only areas must
+	// be granted explicitly.
+	assert.True(t, Can(2, common.RoleAdminUser, UserQuota))
+	assert.True(t, Can(2, common.RoleAdminUser, LogRead))
+	assert.True(t, Can(2, common.RoleAdminUser, TopUpWrite))
+	assert.False(t, Can(2, common.RoleAdminUser, OptionRead))
+	assert.False(t, Can(2, common.RoleAdminUser, SystemTaskWrite))
+	assert.True(t, Can(1, common.RoleRootUser, OptionWrite))
 }
 
 func TestInitOnSlaveOnlyLoadsPolicies(t *testing.T) {
@@ -97,15 +106,13 @@ func TestSetUserPermissionsStoresOnlyOverrides(t *testing.T) {
 
 	assert.True(t, Can(42, common.RoleAdminUser, ChannelSensitiveWrite))
 	assert.False(t, Can(42, common.RoleAdminUser, ChannelWrite))
-	assert.Equal(t, PermissionsMap{
-		ResourceChannel: {
-			ActionRead:           true,
-			ActionOperate:        true,
-			ActionWrite:          false,
-			ActionSensitiveWrite: true,
-			ActionSecretView:     false,
-		},
-	}, ExplicitUserPermissions(42))
+	assert.Equal(t, map[string]bool{
+		ActionRead:           true,
+		ActionOperate:        true,
+		ActionWrite:          false,
+		ActionSensitiveWrite: true,
+		ActionSecretView:     false,
+	}, ExplicitUserPermissions(42)[ResourceChannel])
 	assert.Equal(t, PermissionsMap{
 		ResourceChannel: {
 			ActionSensitiveWrite: true,
@@ -125,15 +132,13 @@ func TestSetUserPermissionsStoresOnlyOverrides(t *testing.T) {
 		ActionSecretView:     false,
 	}}))
 	assert.False(t, Can(42, common.RoleAdminUser, ChannelSensitiveWrite))
-	assert.Equal(t, PermissionsMap{
-		ResourceChannel: {
-			ActionRead:           true,
-			ActionOperate:        true,
-			ActionWrite:          true,
-			ActionSensitiveWrite: false,
-			ActionSecretView:     false,
-		},
-	}, ExplicitUserPermissions(42))
+	assert.Equal(t, map[string]bool{
+		ActionRead:           true,
+		ActionOperate:        true,
+		ActionWrite:          true,
+		ActionSensitiveWrite: false,
+		ActionSecretView:     false,
+	}, ExplicitUserPermissions(42)[ResourceChannel])
 	assert.Empty(t, ExplicitUserOverrides(42))
 }
 
@@ -227,3 +232,83 @@ func TestCapabilitiesUseCatalogShape(t *testing.T) {
 	assert.False(t, capabilities[ResourceChannel][ActionSensitiveWrite])
 	assert.False(t, capabilities[ResourceChannel][ActionSecretView])
 }
+
+func TestCustomRoleReplacesAdminBaseline(t *testing.T) {
+	db := newAuthzTestDB(t)
+	require.NoError(t, Init(db))
+
+	_, err := CreateCustomRole(CustomRoleInput{Key: BuiltInRoleAdmin})
+	assert.ErrorIs(t, err, ErrRoleExists)
+	_, err = CreateCustomRole(CustomRoleInput{Key: "Support Staff"})
+	assert.ErrorIs(t, err, ErrInvalidRoleKey)
+
+	role, err := CreateCustomRole(CustomRoleInput{
+		Key:  "support",
+		Name: "Support",
+		Grants: PermissionsMap{
+			ResourceUser: {ActionRead: true, ActionQuota: true, ActionWrite: false},
+			ResourceLog:  {ActionRead: true},
+			"unknown":    {ActionRead: true},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, role.Enabled)
+	assert.True(t, role.Grants[ResourceUser][ActionQuota])
+	assert.False(t, role.Grants[ResourceChannel][ActionRead])
+	_, err = CreateCustomRole(CustomRoleInput{Key: "support"})
+	assert.ErrorIs(t, err, ErrRoleExists)
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return AssignUserRoleInTx(tx, 77, "support")
+	}))
+	require.NoError(t, ReloadPolicy())
+	assert.Equal(t, "support", UserRoleKey(77))
+
+	assert.True(t, Can(77, common.RoleAdminUser, LogRead))
+	assert.True(t, Can(77, common.RoleAdminUser, UserQuota))
+	assert.False(t, Can(77, common.RoleAdminUser, UserWrite))
+	assert.False(t, Can(77, common.RoleAdminUser, ChannelRead))
+	assert.True(t, Can(78, common.RoleAdminUser, ChannelRead), "unassigned admins keep the admin baseline")
+
+	// Overrides are stored relative to the assigned role.
+	require.NoError(t, SetUserPermissions(77, PermissionsMap{ResourceChannel: {ActionRead: true, ActionWrite: false}}))
+	assert.Equal(t, PermissionsMap{ResourceChannel: {ActionRead: true}}, ExplicitUserOverrides(77))
+	assert.True(t, Can(77, common.RoleAdminUser, ChannelRead))
+
+	disabled := false
+	_, err = UpdateCustomRole("support", CustomRoleInput{Name: "Support", Enabled: &disabled})
+	require.NoError(t, err)
+	assert.True(t, Can(77, common.RoleAdminUser, UserWrite), "a disabled role falls back to the admin baseline")
+	_, err = UpdateCustomRole(BuiltInRoleAdmin, CustomRoleInput{})
+	assert.ErrorIs(t, err, ErrBuiltInRole)
+
+	require.NoError(t, DeleteCustomRole("support"))
+	assert.Empty(t, UserRoleKey(77))
+	assert.ErrorIs(t, DeleteCustomRole("support"), ErrRoleNotFound)
+	for _, descriptor := range Roles() {
+		assert.NotEqual(t, "support", descriptor.Key)
+	}
+	var count int64
+	require.NoError(t, db.Model(&model.CasbinRule{}).Where("v0 = ? OR v1 = ?", RoleSubject("support"), RoleSubject("support")).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestClearUserAuthorizationRemovesRoleAssignment(t *testing.T) {
+	db := newAuthzTestDB(t)
+	require.NoError(t, Init(db))
+	_, err := CreateCustomRole(CustomRoleInput{Key: "auditor", Grants: PermissionsMap{ResourceLog: {ActionRead: true}}})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		if err := AssignUserRoleInTx(tx, 91, "auditor"); err != nil {
+			return err
+		}
+		return ClearUserAuthorizationInTx(tx, 91)
+	}))
+	require.NoError(t, ReloadPolicy())
+	assert.Empty(t, UserRoleKey(91))
+
+	assert.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return AssignUserRoleInTx(tx, 91, "missing")
+	}), ErrRoleNotFound)
+}
@@ -0,0 +1,323 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// customRoleSort places custom roles after the built-in roles.
+const customRoleSort = 100
+
+var customRoleKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+var (
+	ErrRoleNotFound   = errors.New("authorization role not found")
+	ErrRoleExists     = errors.New("authorization role already exists")
+	ErrBuiltInRole    = errors.New("built-in authorization roles cannot be changed")
+	ErrInvalidRoleKey = errors.New("role key must start with a lowercase letter and contain only lowercase letters, digits, '_' or '-' (at most 64 characters)")
+)
+
+var (
+	customRolesMu sync.RWMutex
+	customRoles   = map[string]model.AuthzRole{}
+)
+
+// CustomRoleInput is the editable part of a custom role. A nil Enabled keeps
+// the current state (new roles are enabled); a nil Grants keeps the current
+// grants.
+type CustomRoleInput struct {
+	Key         string         `json:"key"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Enabled     *bool          `json:"enabled"`
+	Grants      PermissionsMap `json:"grants"`
+}
+
+func currentPolicyDB() *gorm.DB {
+	enforcerMu.RLock()
+	defer enforcerMu.RUnlock()
+	return policyDB
+}
+
+// loadCustomRoles refreshes the in-memory snapshot of custom roles. Like the
+// policy snapshot it is reloaded by ReloadPolicy on every node.
+func loadCustomRoles(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("authz enforcer is not initialized")
+	}
+	var rows []model.AuthzRole
+	if err := db.Where("built_in = ?", false).Find(&rows).Error; err != nil {
+		return err
+	}
+	roles := make(map[string]model.AuthzRole, len(rows))
+	for _, row := range rows {
+		roles[row.Key] = row
+	}
+	customRolesMu.Lock()
+	customRoles = roles
+	customRolesMu.Unlock()
+	return nil
+}
+
+func customRole(roleKey string) (model.AuthzRole, bool) {
+	customRolesMu.RLock()
+	defer customRolesMu.RUnlock()
+	role, ok := customRoles[roleKey]
+	return role, ok
+}
+
+func sortedCustomRoles() []model.AuthzRole {
+	customRolesMu.RLock()
+	roles := make([]model.AuthzRole, 0, len(customRoles))
+	for _, role := range customRoles {
+		roles = append(roles, role)
+	}
+	customRolesMu.RUnlock()
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].Sort != roles[j].Sort {
+			return roles[i].Sort < roles[j].Sort
+		}
+		return roles[i].Key < roles[j].Key
+	})
+	return roles
+}
+
+func customRoleDescriptor(role model.AuthzRole) RoleDescriptor {
+	grants := make(PermissionsMap, len(registry))
+	e := currentEnforcer()
+	for _, resource := range registry {
+		actions := make(map[string]bool, len(resource.Actions))
+		for _, action := range resource.Actions {
+			actions[action.Action] = e != nil && roleBaselineAllows(e, role.Key, Permission{
+				Resource: resource.Resource,
+				Action:   action.Action,
+			})
+		}
+		grants[resource.Resource] = actions
+	}
+	return RoleDescriptor{
+		Key:         role.Key,
+		Name:        role.Name,
+		Description: role.Description,
+		BuiltIn:     false,
+		Enabled:     role.Enabled,
+		Grants:      grants,
+	}
+}
+
+// CustomRole returns the descriptor of a custom role.
+func CustomRole(roleKey string) (RoleDescriptor, bool) {
+	role, ok := customRole(roleKey)
+	if !ok {
+		return RoleDescriptor{}, false
+	}
+	return customRoleDescriptor(role), true
+}
+
+// CreateCustomRole stores a new custom role and its grants.
+func CreateCustomRole(input CustomRoleInput) (RoleDescriptor, error) {
+	key := strings.TrimSpace(input.Key)
+	if !customRoleKeyPattern.MatchString(key) {
+		return RoleDescriptor{}, ErrInvalidRoleKey
+	}
+	if _, ok := roleSpec(key); ok {
+		return RoleDescriptor{}, ErrRoleExists
+	}
+	name, err := customRoleName(input.Name, key)
+	if err != nil {
+		return RoleDescriptor{}, err
+	}
+	db := currentPolicyDB()
+	if db == nil {
+		return RoleDescriptor{}, fmt.Errorf("authz enforcer is not initialized")
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.AuthzRole{}).Where(&model.AuthzRole{Key: key}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrRoleExists
+		}
+		role := model.AuthzRole{
+			Key:         key,
+			Name:        name,
+			Description: strings.TrimSpace(input.Description),
+			BuiltIn:     false,
+			Enabled:     input.Enabled == nil || *input.Enabled,
+			Sort:        customRoleSort,
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return replaceRoleGrantsInTx(tx, key, input.Grants)
+	})
+	if err != nil {
+		return RoleDescriptor{}, err
+	}
+	return reloadCustomRole(key)
+}
+
+// UpdateCustomRole changes the name, description, enabled state and grants of
+// a custom role.
+func UpdateCustomRole(roleKey string, input CustomRoleInput) (RoleDescriptor, error) {
+	if _, ok := roleSpec(roleKey); ok {
+		return RoleDescriptor{}, ErrBuiltInRole
+	}
+	db := currentPolicyDB()
+	if db == nil {
+		return RoleDescriptor{}, fmt.Errorf("authz enforcer is not initialized")
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		role, err := findCustomRoleInTx(tx, roleKey)
+		if err != nil {
+			return err
+		}
+		if role.Name, err = customRoleName(input.Name, role.Key); err != nil {
+			return err
+		}
+		role.Description = strings.TrimSpace(input.Description)
+		if input.Enabled != nil {
+			role.Enabled = *input.Enabled
+		}
+		if err := tx.Model(&role).Select("name", "description", "enabled", "updated_at").Updates(&role).Error; err != nil {
+			return err
+		}
+		if input.Grants == nil {
+			return nil
+		}
+		return replaceRoleGrantsInTx(tx, role.Key, input.Grants)
+	})
+	if err != nil {
+		return RoleDescriptor{}, err
+	}
+	return reloadCustomRole(roleKey)
+}
+
+// DeleteCustomRole removes a custom role, its grants and its assignments.
+// Users assigned to it fall back to the built-in admin baseline.
+func DeleteCustomRole(roleKey string) error {
+	if _, ok := roleSpec(roleKey); ok {
+		return ErrBuiltInRole
+	}
+	db := currentPolicyDB()
+	if db == nil {
+		return fmt.Errorf("authz enforcer is not initialized")
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		role, err := findCustomRoleInTx(tx, roleKey)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("ptype = ? AND v0 = ?", "p", RoleSubject(roleKey)).Delete(&model.CasbinRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("ptype = ? AND v1 = ?", "g", RoleSubject(roleKey)).Delete(&model.CasbinRule{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return ReloadPolicy()
+}
+
+// AssignUserRoleInTx assigns a custom role to a user, replacing any previous
+// assignment. An empty roleKey returns the user to the built-in admin baseline.
+// Callers reload the policy after the transaction commits.
+func AssignUserRoleInTx(tx *gorm.DB, userID int, roleKey string) error {
+	if err := tx.Where("ptype = ? AND v0 = ?", "g", UserSubject(userID)).Delete(&model.CasbinRule{}).Error; err != nil {
+		return err
+	}
+	if roleKey == "" {
+		return nil
+	}
+	if _, err := findCustomRoleInTx(tx, roleKey); err != nil {
+		return err
+	}
+	rule := newRule("g", []string{UserSubject(userID), RoleSubject(roleKey)})
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rule).Error
+}
+
+// UserRoleKey returns the custom role assigned to a user, or an empty string.
+func UserRoleKey(userID int) string {
+	e := currentEnforcer()
+	if e == nil {
+		return ""
+	}
+	rules, err := e.GetFilteredGroupingPolicy(0, UserSubject(userID))
+	if err != nil {
+		return ""
+	}
+	for _, rule := range rules {
+		if len(rule) >= 2 && strings.HasPrefix(rule[1], "role:") {
+			return strings.TrimPrefix(rule[1], "role:")
+		}
+	}
+	return ""
+}
+
+func customRoleName(name string, key string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = key
+	}
+	if len([]rune(name)) > 100 {
+		return "", fmt.Errorf("role name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+func findCustomRoleInTx(tx *gorm.DB, roleKey string) (model.AuthzRole, error) {
+	var role model.AuthzRole
+	result := tx.Where(&model.AuthzRole{Key: roleKey}).Limit(1).Find(&role)
+	if result.Error != nil {
+		return role, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return role, ErrRoleNotFound
+	}
+	if role.BuiltIn {
+		return role, ErrBuiltInRole
+	}
+	return role, nil
+}
+
+func replaceRoleGrantsInTx(tx *gorm.DB, roleKey string, grants PermissionsMap) error {
+	if err := tx.Where("ptype = ? AND v0 = ?", "p", RoleSubject(roleKey)).Delete(&model.CasbinRule{}).Error; err != nil {
+		return err
+	}
+	rules := make([]model.CasbinRule, 0)
+	for resource, actions := range grants {
+		for action, allowed := range actions {
+			if !allowed || !isKnownPermission(Permission{Resource: resource, Action: action}) {
+				continue
+			}
+			rules = append(rules, newRule("p", []string{RoleSubject(roleKey), resource, action, EffectAllow}))
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rules).Error
+}
+
+func reloadCustomRole(roleKey string) (RoleDescriptor, error) {
+	if err := ReloadPolicy(); err != nil {
+		return RoleDescriptor{}, err
+	}
+	role, ok := CustomRole(roleKey)
+	if !ok {
+		return RoleDescriptor{}, ErrRoleNotFound
+	}
+	return role, nil
+}
@@ -30,7 +30,7 @@ func SetUserPermissions(userID int, permissions PermissionsMap) error {
 		if _, err := e.RemoveFilteredPolicy(0, UserSubject(userID), resource); err != nil {
 			return err
 		}
-		for _, policy := range userOverridePolicies(e, resource, actions) {
+		for _, policy := range userOverridePolicies(e, baselineRoleKey(userID), resource, actions) {
 			if _, err := e.AddPolicy(UserSubject(userID), policy.Resource, policy.Action, policy.Effect); err != nil {
 				return err
 			}
@@ -52,7 +52,7 @@ func SetUserPermissionsInTx(tx *gorm.DB, userID int, permissions PermissionsMap)
 		if err := tx.Where("ptype = ? AND v0 = ? AND v1 = ?", "p", UserSubject(userID), resource).Delete(&model.CasbinRule{}).Error; err != nil {
 			return err
 		}
-		policies := userOverridePolicies(e, resource, actions)
+		policies := userOverridePolicies(e, baselineRoleKey(userID), resource, actions)
 		if len(policies) == 0 {
 			continue
 		}
@@ -90,16 +90,24 @@ func ClearUserPermissionsInTx(tx *gorm.DB, userID int) error {
 	return nil
 }
 
+// ClearUserAuthorization removes the user's overrides and custom role assignment.
 func ClearUserAuthorization(userID int) error {
-	return ClearUserPermissions(userID)
+	if err := ClearUserPermissions(userID); err != nil {
+		return err
+	}
+	_, err := currentEnforcer().RemoveFilteredGroupingPolicy(0, UserSubject(userID))
+	return err
 }
 
 func ClearUserAuthorizationInTx(tx *gorm.DB, userID int) error {
-	return ClearUserPermissionsInTx(tx, userID)
+	if err := ClearUserPermissionsInTx(tx, userID); err != nil {
+		return err
+	}
+	return tx.Where("ptype = ? AND v0 = ?", "g", UserSubject(userID)).Delete(&model.CasbinRule{}).Error
 }
 
 // ExplicitUserPermissions returns the effective permission matrix for the
-// managed role plus any per-user overrides.
+// user's baseline role plus any per-user overrides.
 func ExplicitUserPermissions(userID int) PermissionsMap {
 	return Capabilities(userID, common.RoleAdminUser)
 }
@@ -133,9 +141,9 @@ func ExplicitUserOverrides(userID int) PermissionsMap {
 	return result
 }
 
-// userOverridePolicies returns the override entries that differ from the managed
-// role baseline; entries matching the baseline are omitted.
-func userOverridePolicies(e *casbin.SyncedEnforcer, resource string, actions map[string]bool) []overridePolicy {
+// userOverridePolicies returns the override entries that differ from the
+// baseline role's grants; entries matching the baseline are omitted.
+func userOverridePolicies(e *casbin.SyncedEnforcer, baselineRole string, resource string, actions map[string]bool) []overridePolicy {
 	overrides := make([]overridePolicy, 0, len(actions))
 	for _, action := range catalogActions(resource) {
 		desired, ok := actions[action.Action]
@@ -143,7 +151,7 @@ func userOverridePolicies(e *casbin.SyncedEnforcer, resource string, actions map
 			continue
 		}
 		permission := Permission{Resource: resource, Action: action.Action}
-		if desired == roleBaselineAllows(e, managedRoleKey, permission) {
+		if desired == roleBaselineAllows(e, baselineRole, permission) {
 			continue
 		}
 		effect := EffectDeny
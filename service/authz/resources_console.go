@@ -0,0 +1,223 @@
+package authz
+
+const (
+	ResourceUser         = "user"
+	ResourceToken        = "token"
+	ResourceLog          = "log"
+	ResourceRedemption   = "redemption"
+	ResourceTopUp        = "topup"
+	ResourceSubscription = "subscription"
+	ResourceModel        = "model"
+	ResourceOption       = "option"
+	ResourceSystemTask   = "system_task"
+
+	ActionQuota  = "quota"
+	ActionExport = "export"
+)
+
+var (
+	UserRead    = Permission{Resource: ResourceUser, Action: ActionRead}
+	UserOperate = Permission{Resource: ResourceUser, Action: ActionOperate}
+	UserQuota   = Permission{Resource: ResourceUser, Action: ActionQuota}
+	UserWrite   = Permission{Resource: ResourceUser, Action: ActionWrite}
+
+	TokenRead    = Permission{Resource: ResourceToken, Action: ActionRead}
+	TokenOperate = Permission{Resource: ResourceToken, Action: ActionOperate}
+
+	LogRead   = Permission{Resource: ResourceLog, Action: ActionRead}
+	LogExport = Permission{Resource: ResourceLog, Action: ActionExport}
+
+	RedemptionRead  = Permission{Resource: ResourceRedemption, Action: ActionRead}
+	RedemptionWrite = Permission{Resource: ResourceRedemption, Action: ActionWrite}
+
+	TopUpRead  = Permission{Resource: ResourceTopUp, Action: ActionRead}
+	TopUpWrite = Permission{Resource: ResourceTopUp, Action: ActionWrite}
+
+	SubscriptionRead  = Permission{Resource: ResourceSubscription, Action: ActionRead}
+	SubscriptionWrite = Permission{Resource: ResourceSubscription, Action: ActionWrite}
+
+	ModelRead  = Permission{Resource: ResourceModel, Action: ActionRead}
+	ModelWrite = Permission{Resource: ResourceModel, Action: ActionWrite}
+
+	OptionRead  = Permission{Resource: ResourceOption, Action: ActionRead}
+	OptionWrite = Permission{Resource: ResourceOption, Action: ActionWrite}
+
+	SystemTaskRead  = Permission{Resource: ResourceSystemTask, Action: ActionRead}
+	SystemTaskWrite = Permission{Resource: ResourceSystemTask, Action: ActionWrite}
+)
+
+// The admin role keeps every console area it could reach before these
+// resources were registered. System settings and system tasks used to be root
+// only, so they have no default role and must be granted explicitly.
+func init() {
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceUser,
+		LabelKey: "User Management",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read users",
+				DescriptionKey: "View user lists, details, bindings, and two-factor statistics.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionOperate,
+				LabelKey:       "Operate users",
+				DescriptionKey: "Enable or disable users and reset their passkeys, two-factor authentication, or account bindings.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionQuota,
+				LabelKey:       "Adjust user quota",
+				DescriptionKey: "Add, subtract, or override a user's quota.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Edit users",
+				DescriptionKey: "Create, edit, delete, promote, or demote users.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceToken,
+		LabelKey: "Token Management",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read user tokens",
+				DescriptionKey: "View the tokens of other users with masked keys.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionOperate,
+				LabelKey:       "Operate user tokens",
+				DescriptionKey: "Enable or disable the tokens of other users.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceLog,
+		LabelKey: "Usage Logs",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read all logs",
+				DescriptionKey: "View usage logs, statistics, drawing and task records of all users.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionExport,
+				LabelKey:       "Export all logs",
+				DescriptionKey: "Export the logs of all users as CSV, JSONL, or Parquet files.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceRedemption,
+		LabelKey: "Redemption Codes",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read redemption codes",
+				DescriptionKey: "View redemption code lists and details.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Edit redemption codes",
+				DescriptionKey: "Create, edit, and delete redemption codes.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceTopUp,
+		LabelKey: "Top-up Orders",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read top-up orders",
+				DescriptionKey: "View the top-up orders of all users.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Complete top-up orders",
+				DescriptionKey: "Manually mark pending top-up orders as paid.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceSubscription,
+		LabelKey: "Subscription Management",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read subscriptions",
+				DescriptionKey: "View subscription plans and user subscriptions.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Edit subscriptions",
+				DescriptionKey: "Edit plans and grant, reset, invalidate, or delete user subscriptions.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceModel,
+		LabelKey: "Model Metadata",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read model metadata",
+				DescriptionKey: "View model metadata, vendors, and prefill groups.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Edit model metadata",
+				DescriptionKey: "Edit model metadata, vendors, and prefill groups, or sync them from upstream.",
+				DefaultRoles:   []string{BuiltInRoleAdmin},
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceOption,
+		LabelKey: "System Settings",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read system settings",
+				DescriptionKey: "View system settings without secrets.",
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Edit system settings",
+				DescriptionKey: "Change system settings, including payment and login configuration.",
+			},
+		},
+	})
+	RegisterResource(ResourceDefinition{
+		Resource: ResourceSystemTask,
+		LabelKey: "System Tasks",
+		Actions: []ActionDefinition{
+			{
+				Action:         ActionRead,
+				LabelKey:       "Read system tasks",
+				DescriptionKey: "View background system tasks and their progress.",
+			},
+			{
+				Action:         ActionWrite,
+				LabelKey:       "Start system tasks",
+				DescriptionKey: "Start background maintenance tasks such as log cleanup.",
+			},
+		},
+	})
+}
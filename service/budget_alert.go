@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+)
+
+// velocity 规则以此前多少个窗口的平均消耗作为基线
+const budgetAlertBaselineWindows = 24
+
+var budgetAlertDefaultTitles = map[string]string{
+	model.AlertRuleTypeBalancePercent: "额度告警：{{rule_name}}",
+	model.AlertRuleTypeSpend:          "消费告警：{{rule_name}}",
+	model.AlertRuleTypeVelocity:       "消费突增告警：{{rule_name}}",
+	model.AlertRuleTypeChannelError:   "渠道错误告警：{{rule_name}}",
+}
+
+var budgetAlertDefaultTemplates = map[string]string{
+	model.AlertRuleTypeBalancePercent: "您的剩余额度为 {{balance}}，占总额度的 {{current}}，已低于设定的 {{threshold}}，请及时充值。",
+	model.AlertRuleTypeSpend:          "{{period}}内消耗 {{current}}，已超过设定的 {{threshold}}。\n令牌：{{token_name}}\n模型：{{model_name}}",
+	model.AlertRuleTypeVelocity:       "最近 {{period}} 消耗 {{current}}，为此前平均值 {{baseline}} 的 {{threshold}} 倍以上。\n令牌：{{token_name}}\n模型：{{model_name}}",
+	model.AlertRuleTypeChannelError:   "最近 {{period}} 内以下渠道错误次数达到 {{threshold}} 次：\n{{channels}}",
+}
+
+// budgetAlertHandler 每分钟评估一次全部启用的告警规则，没有启用的规则时不调度
+type budgetAlertHandler struct{}
+
+func (budgetAlertHandler) Type() string { return model.SystemTaskTypeBudgetAlert }
+
+func (budgetAlertHandler) Enabled() bool { return model.HasEnabledAlertRules() }
+
+func (budgetAlertHandler) Interval() time.Duration { return time.Minute }
+
+func (budgetAlertHandler) NewPayload() any { return nil }
+
+func (budgetAlertHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	summary, err := RunBudgetAlertsOnce(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() != nil {
+			logSystemTaskLockError(ctx, task, model.ErrSystemTaskLockLost)
+			return
+		}
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, summary, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+func init() {
+	RegisterSystemTaskHandler(budgetAlertHandler{})
+}
+
+type BudgetAlertSummary struct {
+	Evaluated int `json:"evaluated"`
+	Triggered int `json:"triggered"`
+	Failed    int `json:"failed"`
+}
+
+// budgetAlertEvent 一次命中的告警，Key 标识所属周期，同一周期只发送一次
+type budgetAlertEvent struct {
+	Key    string
+	Values map[string]string
+}
+
+// RunBudgetAlertsOnce 评估全部启用的规则并发送命中的告警
+func RunBudgetAlertsOnce(ctx context.Context, now time.Time) (*BudgetAlertSummary, error) {
+	rules, err := model.GetEnabledAlertRules()
+	if err != nil {
+		return nil, err
+	}
+	summary := &BudgetAlertSummary{}
+	users := map[int]*model.User{}
+	for _, rule := range rules {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		user, ok := users[rule.UserId]
+		if !ok {
+			user, err = model.GetUserById(rule.UserId, true)
+			if err != nil {
+				user = nil
+			}
+			users[rule.UserId] = user
+		}
+		if user == nil || user.Status != common.UserStatusEnabled {
+			continue
+		}
+		if rule.Type == model.AlertRuleTypeChannelError && user.Role < common.RoleAdminUser {
+			continue
+		}
+		summary.Evaluated++
+		event, err := evaluateAlertRule(rule, user, now)
+		if err != nil {
+			summary.Failed++
+			common.SysLog(fmt.Sprintf("failed to evaluate alert rule %d: %s", rule.Id, err.Error()))
+			continue
+		}
+		if event == nil {
+			continue
+		}
+		sent, err := fireAlertRule(rule, user, event, now)
+		if err != nil {
+			summary.Failed++
+			common.SysLog(fmt.Sprintf("failed to send alert rule %d to user %d: %s", rule.Id, rule.UserId, err.Error()))
+			continue
+		}
+		if sent {
+			summary.Triggered++
+		}
+	}
+	return summary, nil
+}
+
+// evaluateAlertRule 返回 nil 表示未命中
+func evaluateAlertRule(rule *model.AlertRule, user *model.User, now time.Time) (*budgetAlertEvent, error) {
+	values := map[string]string{
+		"rule_name":  rule.Name,
+		"username":   user.Username,
+		"time":       now.Format("2006-01-02 15:04:05"),
+		"token_name": "全部",
+		"model_name": "全部",
+	}
+	if rule.ModelName != "" {
+		values["model_name"] = rule.ModelName
+	}
+	if rule.TokenId != 0 {
+		values["token_name"] = fmt.Sprintf("#%d", rule.TokenId)
+		if token, err := model.GetTokenByIds(rule.TokenId, rule.UserId); err == nil {
+			values["token_name"] = token.Name
+		}
+	}
+
+	switch rule.Type {
+	case model.AlertRuleTypeBalancePercent:
+		total := user.Quota + user.UsedQuota
+		if total <= 0 {
+			return nil, nil
+		}
+		percent := float64(user.Quota) * 100 / float64(total)
+		if percent >= rule.Threshold {
+			// 额度恢复后重新布防，下次跌破阈值时再次告警
+			return nil, model.ResetAlertTrigger(rule.Id)
+		}
+		values["current"] = fmt.Sprintf("%.2f%%", percent)
+		values["threshold"] = formatAlertNumber(rule.Threshold) + "%"
+		values["balance"] = logger.FormatQuota(user.Quota)
+		return &budgetAlertEvent{Key: "low", Values: values}, nil
+	case model.AlertRuleTypeSpend:
+		start, key, label := alertPeriodStart(rule.Period, now)
+		spent, err := model.SumAlertSpend(alertSpendFilter(rule, start.Unix(), now.Unix()+1))
+		if err != nil {
+			return nil, err
+		}
+		if float64(spent) < rule.Threshold {
+			return nil, nil
+		}
+		values["period"] = label
+		values["current"] = logger.FormatQuota(int(spent))
+		values["threshold"] = logger.FormatQuota(int(rule.Threshold))
+		return &budgetAlertEvent{Key: key, Values: values}, nil
+	case model.AlertRuleTypeVelocity:
+		window := int64(rule.WindowMinutes) * 60
+		end := now.Unix() + 1
+		current, err := model.SumAlertSpend(alertSpendFilter(rule, end-window, end))
+		if err != nil {
+			return nil, err
+		}
+		if current <= 0 || current < int64(rule.MinQuota) {
+			return nil, nil
+		}
+		previous, err := model.SumAlertSpend(alertSpendFilter(rule, end-window*(budgetAlertBaselineWindows+1), end-window))
+		if err != nil {
+			return nil, err
+		}
+		baseline := float64(previous) / budgetAlertBaselineWindows
+		// 没有历史消耗时只能依靠 MinQuota 过滤，未设置则不告警，避免每次恢复使用都触发
+		if baseline == 0 && rule.MinQuota == 0 {
+			return nil, nil
+		}
+		if float64(current) <= baseline*rule.Threshold {
+			return nil, nil
+		}
+		values["period"] = fmt.Sprintf("%d 分钟", rule.WindowMinutes)
+		values["current"] = logger.FormatQuota(int(current))
+		values["baseline"] = logger.FormatQuota(int(baseline))
+		values["threshold"] = formatAlertNumber(rule.Threshold)
+		return &budgetAlertEvent{Key: alertWindowKey(now, window), Values: values}, nil
+	case model.AlertRuleTypeChannelError:
+		window := int64(rule.WindowMinutes) * 60
+		end := now.Unix() + 1
+		counts, err := model.CountChannelErrors(rule.ChannelId, end-window, end)
+		if err != nil {
+			return nil, err
+		}
+		var lines []string
+		for _, count := range counts {
+			if float64(count.Count) < rule.Threshold {
+				continue
+			}
+			name := ""
+			if channel, err := model.GetChannelById(count.ChannelId, false); err == nil {
+				name = channel.Name
+			}
+			lines = append(lines, fmt.Sprintf("#%d %s：%d 次", count.ChannelId, name, count.Count))
+		}
+		if len(lines) == 0 {
+			return nil, nil
+		}
+		values["period"] = fmt.Sprintf("%d 分钟", rule.WindowMinutes)
+		values["threshold"] = formatAlertNumber(rule.Threshold)
+		values["current"] = strconv.Itoa(len(lines))
+		values["channels"] = strings.Join(lines, "\n")
+		return &budgetAlertEvent{Key: alertWindowKey(now, window), Values: values}, nil
+	}
+	return nil, fmt.Errorf("unknown alert rule type %q", rule.Type)
+}
+
+func alertSpendFilter(rule *model.AlertRule, start int64, end int64) model.AlertSpendFilter {
+	return model.AlertSpendFilter{
+		UserId:         rule.UserId,
+		TokenId:        rule.TokenId,
+		ModelName:      rule.ModelName,
+		StartTimestamp: start,
+		EndTimestamp:   end,
+	}
+}
+
+// alertPeriodStart 返回自然日/自然周（周一开始）的起点、周期标识和展示文本，按服务器时区计算
+func alertPeriodStart(period string, now time.Time) (time.Time, string, string) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == model.AlertPeriodWeek {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		return start, "week:" + start.Format("2006-01-02"), "本周"
+	}
+	return start, "day:" + start.Format("2006-01-02"), "今日"
+}
+
+func alertWindowKey(now time.Time, window int64) string {
+	return "window:" + strconv.FormatInt(now.Unix()/window, 10)
+}
+
+func formatAlertNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// renderAlertTemplate 替换模板中的 {{占位符}}，未知占位符原样保留
+func renderAlertTemplate(template string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// buildAlertNotify 渲染规则的标题和正文；模板为纯文本，邮件需转义并保留换行
+func buildAlertNotify(rule *model.AlertRule, notifyType string, values map[string]string) dto.Notify {
+	title := rule.Title
+	if title == "" {
+		title = budgetAlertDefaultTitles[rule.Type]
+	}
+	template := rule.Template
+	if template == "" {
+		template = budgetAlertDefaultTemplates[rule.Type]
+	}
+	content := renderAlertTemplate(template, values)
+	if notifyType == "" || notifyType == dto.NotifyTypeEmail {
+		content = strings.ReplaceAll(html.EscapeString(content), "\n", "<br/>")
+	}
+	return dto.NewNotify(dto.NotifyTypeBudgetAlert, renderAlertTemplate(title, values), content, nil)
+}
+
+// fireAlertRule 先按规则做频率限制，再抢占本周期的触发权，成功后发送
+func fireAlertRule(rule *model.AlertRule, user *model.User, event *budgetAlertEvent, now time.Time) (bool, error) {
+	if rule.LastTriggerKey == event.Key {
+		// 本周期已发送，不占用频率限制计数
+		return false, nil
+	}
+	canSend, err := CheckNotificationLimit(rule.UserId, fmt.Sprintf("%s:%d", dto.NotifyTypeBudgetAlert, rule.Id))
+	if err != nil || !canSend {
+		return false, err
+	}
+	claimed, err := model.ClaimAlertTrigger(rule.Id, event.Key, now.Unix())
+	if err != nil || !claimed {
+		return false, err
+	}
+	userSetting := user.GetSetting()
+	notify := buildAlertNotify(rule, userSetting.NotifyType, event.Values)
+	return true, sendUserNotify(user.Id, user.Email, userSetting, notify)
+}
+
+// SendAlertRuleTest 使用示例数据发送一条测试告警，受通知频率限制
+func SendAlertRuleTest(rule *model.AlertRule, user *model.User) error {
+	values := map[string]string{
+		"rule_name":  rule.Name,
+		"username":   user.Username,
+		"time":       time.Now().Format("2006-01-02 15:04:05"),
+		"token_name": "全部",
+		"model_name": "全部",
+		"period":     "今日",
+		"current":    logger.FormatQuota(int(common.QuotaPerUnit)),
+		"baseline":   logger.FormatQuota(int(common.QuotaPerUnit / 10)),
+		"threshold":  formatAlertNumber(rule.Threshold),
+		"balance":    logger.FormatQuota(user.Quota),
+		"channels":   "#1 example：10 次",
+	}
+	if rule.ModelName != "" {
+		values["model_name"] = rule.ModelName
+	}
+	userSetting := user.GetSetting()
+	notify := buildAlertNotify(rule, userSetting.NotifyType, values)
+	notify.Type = dto.NotifyTypeBudgetAlert + "_test"
+	notify.Title = "[测试] " + notify.Title
+	return NotifyUser(user.Id, user.Email, userSetting, notify)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+type capturedNotify struct {
+	Path  string
+	Query string
+	Body  string
+}
+
+// newNotifyTestServer disables SSRF protection so notifications may reach the
+// local test server and records every request it receives.
+func newNotifyTestServer(t *testing.T, response string) (*httptest.Server, func() []capturedNotify) {
+	t.Helper()
+	fetchSetting := system_setting.GetFetchSetting()
+	originalFetchSetting := *fetchSetting
+	originalHTTPClient := httpClient
+	originalProtectedClient := ssrfProtectedHTTPClient
+	t.Cleanup(func() {
+		*fetchSetting = originalFetchSetting
+		httpClient = originalHTTPClient
+		ssrfProtectedHTTPClient = originalProtectedClient
+	})
+	fetchSetting.EnableSSRFProtection = false
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+
+	var mu sync.Mutex
+	var requests []capturedNotify
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, capturedNotify{Path: r.URL.Path, Query: r.URL.RawQuery, Body: string(body)})
+		mu.Unlock()
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []capturedNotify {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedNotify(nil), requests...)
+	}
+}
+
+func TestSendChatNotifyPayloads(t *testing.T) {
+	server, captured := newNotifyTestServer(t, `{"ok":true,"code":0,"errcode":0}`)
+	originalTelegramAPIBase := telegramAPIBase
+	telegramAPIBase = server.URL
+	t.Cleanup(func() { telegramAPIBase = originalTelegramAPIBase })
+
+	notify := dto.NewNotify(dto.NotifyTypeBudgetAlert, "Budget", "spent {{value}}", []interface{}{"$5"})
+	setting := dto.UserSetting{ChatWebhookUrl: server.URL + "/hook", ChatWebhookSecret: "s3cret", TelegramBotToken: "123:abc", TelegramChatId: "-100"}
+	for _, notifyType := range []string{dto.NotifyTypeSlack, dto.NotifyTypeTeams, dto.NotifyTypeDiscord, dto.NotifyTypeTelegram, dto.NotifyTypeFeishu, dto.NotifyTypeDingTalk} {
+		require.NoError(t, sendChatNotify(notifyType, setting, notify), notifyType)
+	}
+
+	requests := captured()
+	require.Len(t, requests, 6)
+	assert.Equal(t, "*Budget*\nspent $5", gjson.Get(requests[0].Body, "text").String())
+	assert.Equal(t, "spent $5", gjson.Get(requests[1].Body, "attachments.0.content.body.1.text").String())
+	assert.Equal(t, "Budget", gjson.Get(requests[2].Body, "embeds.0.title").String())
+	assert.Equal(t, "/bot123:abc/sendMessage", requests[3].Path)
+	assert.Equal(t, "-100", gjson.Get(requests[3].Body, "chat_id").String())
+	assert.Equal(t, "Budget\nspent $5", gjson.Get(requests[4].Body, "content.text").String())
+	assert.NotEmpty(t, gjson.Get(requests[4].Body, "sign").String())
+	assert.Contains(t, requests[5].Query, "sign=")
+	assert.Equal(t, "Budget\nspent $5", gjson.Get(requests[5].Body, "text.content").String())
+}
+
+func TestSendChatNotifyChecksResponseCode(t *testing.T) {
+	server, _ := newNotifyTestServer(t, `{"code":19021,"msg":"sign match fail","errcode":310000,"errmsg":"sign not match","ok":false,"description":"chat not found"}`)
+	originalTelegramAPIBase := telegramAPIBase
+	telegramAPIBase = server.URL
+	t.Cleanup(func() { telegramAPIBase = originalTelegramAPIBase })
+
+	setting := dto.UserSetting{ChatWebhookUrl: server.URL, TelegramBotToken: "123:abc", TelegramChatId: "1"}
+	notify := dto.NewNotify(dto.NotifyTypeBudgetAlert, "t", "c", nil)
+	assert.ErrorContains(t, sendChatNotify(dto.NotifyTypeFeishu, setting, notify), "19021")
+	assert.ErrorContains(t, sendChatNotify(dto.NotifyTypeDingTalk, setting, notify), "310000")
+	assert.ErrorContains(t, sendChatNotify(dto.NotifyTypeTelegram, setting, notify), "chat not found")
+	assert.NoError(t, sendChatNotify(dto.NotifyTypeSlack, setting, notify))
+}
+
+func TestRunBudgetAlertsOnceFiresOncePerPeriod(t *testing.T) {
+	truncate(t)
+	server, captured := newNotifyTestServer(t, "ok")
+	originalLimit := constant.NotifyLimitCount
+	constant.NotifyLimitCount = 2
+	t.Cleanup(func() { constant.NotifyLimitCount = originalLimit })
+
+	user := &model.User{Id: 9101, Username: "alert_user", Quota: 100, UsedQuota: 900, Status: common.UserStatusEnabled}
+	user.SetSetting(dto.UserSetting{NotifyType: dto.NotifyTypeWebhook, WebhookUrl: server.URL})
+	require.NoError(t, model.DB.Create(user).Error)
+
+	now := time.Now()
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: user.Id, CreatedAt: now.Unix() - 1, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 500}).Error)
+	rules := []*model.AlertRule{
+		{UserId: user.Id, Name: "low balance", Type: model.AlertRuleTypeBalancePercent, Threshold: 20, Enabled: true},
+		{UserId: user.Id, Name: "daily", Type: model.AlertRuleTypeSpend, Period: model.AlertPeriodDay, Threshold: 400, ModelName: "gpt-4o", Enabled: true,
+			Title: "{{rule_name}} for {{username}}", Template: "{{current}} over {{threshold}} on {{model_name}}"},
+		{UserId: user.Id, Name: "not reached", Type: model.AlertRuleTypeSpend, Period: model.AlertPeriodWeek, Threshold: 1000, Enabled: true},
+		{UserId: user.Id, Name: "admin only", Type: model.AlertRuleTypeChannelError, Threshold: 1, WindowMinutes: 10, Enabled: true},
+	}
+	for _, rule := range rules {
+		require.NoError(t, rule.Insert())
+	}
+
+	summary, err := RunBudgetAlertsOnce(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, BudgetAlertSummary{Evaluated: 3, Triggered: 2}, *summary)
+
+	requests := captured()
+	require.Len(t, requests, 2)
+	assert.Equal(t, dto.NotifyTypeBudgetAlert, gjson.Get(requests[0].Body, "type").String())
+	assert.Contains(t, gjson.Get(requests[0].Body, "content").String(), "10.00%")
+	assert.Equal(t, "daily for alert_user", gjson.Get(requests[1].Body, "title").String())
+	assert.True(t, strings.HasSuffix(gjson.Get(requests[1].Body, "content").String(), "on gpt-4o"))
+
+	summary, err = RunBudgetAlertsOnce(context.Background(), now)
+	require.NoError(t, err)
+	assert.Zero(t, summary.Triggered)
+	assert.Len(t, captured(), 2)
+}
+
+func TestEvaluateVelocityRule(t *testing.T) {
+	truncate(t)
+	user := &model.User{Id: 9102, Username: "velocity_user", Status: common.UserStatusEnabled}
+	require.NoError(t, model.DB.Create(user).Error)
+
+	now := time.Unix(1700000000, 0)
+	// 24 previous one-hour windows spent 240 in total, so the baseline is 10.
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: user.Id, CreatedAt: now.Unix() - 5*3600, Type: model.LogTypeConsume, Quota: 240}).Error)
+	require.NoError(t, model.LOG_DB.Create(&model.Log{UserId: user.Id, CreatedAt: now.Unix() - 60, Type: model.LogTypeConsume, Quota: 35}).Error)
+
+	rule := &model.AlertRule{Id: 1, UserId: user.Id, Name: "spike", Type: model.AlertRuleTypeVelocity, Threshold: 3}
+	require.NoError(t, rule.Normalize())
+	event, err := evaluateAlertRule(rule, user, now)
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, alertWindowKey(now, 3600), event.Key)
+
+	rule.Threshold = 4
+	event, err = evaluateAlertRule(rule, user, now)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func TestAlertPeriodStartWeekBeginsOnMonday(t *testing.T) {
+	sunday := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	start, key, _ := alertPeriodStart(model.AlertPeriodWeek, sunday)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, "week:2024-03-04", key)
+
+	_, key, _ = alertPeriodStart(model.AlertPeriodDay, sunday)
+	assert.Equal(t, "day:2024-03-10", key)
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func GetUserUsableGroups(userGroup string) map[string]string {
+// GetUserUsableGroups 返回用户可用的分组。extraGroups 是身份源 claim 映射授予该用户的
+// 额外可用分组（users.usable_groups），在全局与分组特殊设置之后追加。
+func GetUserUsableGroups(userGroup string, extraGroups ...string) map[string]string {
 	groupsCopy := setting.GetUserUsableGroupsCopy()
 	if userGroup != "" {
 		specialSettings, b := ratio_setting.GetGroupRatioSetting().GroupSpecialUsableGroup.Get(userGroup)
@@ -37,27 +39,42 @@ func GetUserUsableGroups(userGroup string) map[string]string {
 			groupsCopy[userGroup] = "用户分组"
 		}
 	}
+	for _, group := range extraGroups {
+		if _, ok := groupsCopy[group]; !ok {
+			groupsCopy[group] = "身份源授予"
+		}
+	}
 	return groupsCopy
 }
 
-func GroupInUserUsableGroups(userGroup, groupName string) bool {
-	_, ok := GetUserUsableGroups(userGroup)[groupName]
+// GetContextUserUsableGroups 返回认证中间件写入上下文的用户额外可用分组。
+func GetContextUserUsableGroups(c *gin.Context) []string {
+	if value, ok := common.GetContextKey(c, constant.ContextKeyUserUsableGroups); ok {
+		if groups, ok := value.([]string); ok {
+			return groups
+		}
+	}
+	return nil
+}
+
+func GroupInUserUsableGroups(userGroup, groupName string, extraGroups ...string) bool {
+	_, ok := GetUserUsableGroups(userGroup, extraGroups...)[groupName]
 	return ok
 }
 
-func IsUserSelectableGroup(userGroup, groupName string) bool {
+func IsUserSelectableGroup(userGroup, groupName string, extraGroups ...string) bool {
 	if groupName == "" || groupName == "auto" {
 		return false
 	}
-	return GroupInUserUsableGroups(userGroup, groupName) && ratio_setting.ContainsGroupRatio(groupName)
+	return GroupInUserUsableGroups(userGroup, groupName, extraGroups...) && ratio_setting.ContainsGroupRatio(groupName)
 }
 
 // GetUserAutoGroup 根据用户分组获取自动分组设置
-func GetUserAutoGroup(userGroup string) []string {
+func GetUserAutoGroup(userGroup string, extraGroups ...string) []string {
 	autoGroups := make([]string, 0)
 	seen := make(map[string]struct{})
 	for _, group := range setting.GetAutoGroups() {
-		if !IsUserSelectableGroup(userGroup, group) {
+		if !IsUserSelectableGroup(userGroup, group, extraGroups...) {
 			continue
 		}
 		if _, ok := seen[group]; ok {
@@ -71,12 +88,12 @@ func GetUserAutoGroup(userGroup string) []string {
 
 // FilterUserTokenAutoGroups applies current permissions before the current
 // per-token limit. It intentionally does not fall back to the global Auto list.
-func FilterUserTokenAutoGroups(userGroup string, groups []string) []string {
+func FilterUserTokenAutoGroups(userGroup string, groups []string, extraGroups ...string) []string {
 	maxCount := setting.GetMaxTokenAutoGroups()
 	filtered := make([]string, 0, min(len(groups), maxCount))
 	seen := make(map[string]struct{})
 	for _, group := range groups {
-		if !IsUserSelectableGroup(userGroup, group) {
+		if !IsUserSelectableGroup(userGroup, group, extraGroups...) {
 			continue
 		}
 		if _, ok := seen[group]; ok {
@@ -95,15 +112,16 @@ func FilterUserTokenAutoGroups(userGroup string, groups []string) []string {
 // The absence of the context value means that the token inherits the complete
 // global Auto list; a present (even empty) value is an explicit token snapshot.
 func GetRequestAutoGroups(c *gin.Context, userGroup string) []string {
+	extraGroups := GetContextUserUsableGroups(c)
 	value, ok := common.GetContextKey(c, constant.ContextKeyTokenAutoGroups)
 	if !ok {
-		return GetUserAutoGroup(userGroup)
+		return GetUserAutoGroup(userGroup, extraGroups...)
 	}
 	groups, ok := value.([]string)
 	if !ok {
 		return []string{}
 	}
-	return FilterUserTokenAutoGroups(userGroup, groups)
+	return FilterUserTokenAutoGroups(userGroup, groups, extraGroups...)
 }
 
 // GetGroupsEnabledModels 按 groups 顺序获取各分组启用的模型并去重
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 对冲请求：命中策略的请求在首个渠道超过等待时间仍没有写出响应时，再把同一请求发给另一个渠道，
+// 先写出响应的一方胜出，另一方被取消。这里负责策略匹配、等待时间、对冲渠道选择与落选方计费，
+// 两路尝试的竞速由 controller 完成。
+
+const (
+	hedgeCacheTTL = 30 * time.Second
+	// hedgeObserveWindow 观测首字时间只使用这段时间内的样本
+	hedgeObserveWindow      = 30 * time.Minute
+	hedgeObservedPercentile = 0.9
+	hedgeMinObservedSamples = 20
+	// hedgeMinDelay 观测值过小时的下限，避免几乎每个请求都被对冲
+	hedgeMinDelay = 50 * time.Millisecond
+	// hedgeChannelPicks 每个优先级上随机挑选对冲渠道的次数
+	hedgeChannelPicks = 3
+
+	HedgeDelaySourceFixed    = "fixed"
+	HedgeDelaySourceObserved = "observed_p90"
+	HedgeDelaySourceFallback = "fallback"
+
+	HedgeWinnerPrimary = "primary"
+	HedgeWinnerHedge   = "hedge"
+)
+
+var (
+	hedgeMu        sync.Mutex
+	hedgePolicies  []*model.HedgePolicy
+	hedgeExpiresAt time.Time
+)
+
+// InvalidateHedgePolicyCache 策略增删改后调用，下次请求时重新加载
+func InvalidateHedgePolicyCache() {
+	hedgeMu.Lock()
+	hedgeExpiresAt = time.Time{}
+	hedgeMu.Unlock()
+}
+
+func loadHedgePoliciesLocked(now time.Time) []*model.HedgePolicy {
+	if now.Before(hedgeExpiresAt) {
+		return hedgePolicies
+	}
+	policies, err := model.GetEnabledHedgePolicies()
+	if err != nil {
+		common.SysError("failed to load hedge policies: " + err.Error())
+		hedgeExpiresAt = now.Add(hedgeCacheTTL)
+		return hedgePolicies
+	}
+	hedgePolicies = policies
+	hedgeExpiresAt = now.Add(hedgeCacheTTL)
+	return hedgePolicies
+}
+
+// MatchHedgePolicy 返回命中模型与分组的策略副本，多条命中时取最具体的一条，同样具体时取 id 较小者
+func MatchHedgePolicy(modelName string, group string) *model.HedgePolicy {
+	hedgeMu.Lock()
+	defer hedgeMu.Unlock()
+	var matched *model.HedgePolicy
+	for _, policy := range loadHedgePoliciesLocked(time.Now()) {
+		if !policy.Matches(modelName, group) {
+			continue
+		}
+		if matched == nil || policy.Specificity() > matched.Specificity() {
+			matched = policy
+		}
+	}
+	if matched == nil {
+		return nil
+	}
+	copied := *matched
+	return &copied
+}
+
+// HedgeDelay 返回发出对冲请求前等待首字节的时间及其来源。策略未固定等待时间时，
+// 流式请求使用本节点近期观测到的 p90 首字时间，非流式请求使用 p90 总耗时；
+// 样本不足（包括关闭了性能指标）时使用策略的兜底等待时间。
+func HedgeDelay(policy *model.HedgePolicy, modelName string, isStream bool) (time.Duration, string) {
+	if policy.DelayMs > 0 {
+		return time.Duration(policy.DelayMs) * time.Millisecond, HedgeDelaySourceFixed
+	}
+	since := time.Now().Add(-hedgeObserveWindow)
+	var observedMs int64
+	var samples int
+	if isStream {
+		observedMs, samples = perfmetrics.RecentTtftPercentile(modelName, since, hedgeObservedPercentile)
+	} else {
+		observedMs, samples = perfmetrics.RecentLatencyPercentile(modelName, since, hedgeObservedPercentile)
+	}
+	if samples < hedgeMinObservedSamples {
+		return time.Duration(policy.FallbackDelayMs) * time.Millisecond, HedgeDelaySourceFallback
+	}
+	return max(time.Duration(observedMs)*time.Millisecond, hedgeMinDelay), HedgeDelaySourceObserved
+}
+
+// SelectHedgeChannel 按正常选路的优先级为对冲尝试挑选一个不同于首个渠道的渠道，没有可用渠道时返回 nil
+func SelectHedgeChannel(group string, modelName string, requestPath string, excludeChannelId int) *model.Channel {
+	for retry := 0; retry <= common.RetryTimes; retry++ {
+		for i := 0; i < hedgeChannelPicks; i++ {
+			channel, err := model.GetRandomSatisfiedChannel(group, modelName, retry, requestPath)
+			if err != nil || channel == nil {
+				break
+			}
+			if channel.Id != excludeChannelId {
+				return channel
+			}
+		}
+	}
+	return nil
+}
+
+// HedgeOutcome 一次对冲请求的过程，写入日志 admin_info.hedge。两路尝试并发读写，字段只通过方法访问。
+type HedgeOutcome struct {
+	mu               sync.Mutex
+	policyId         int
+	policyName       string
+	delayMs          int64
+	delaySource      string
+	primaryChannelId int
+	hedgeChannelId   int
+	winner           string
+}
+
+func NewHedgeOutcome(policy *model.HedgePolicy, delay time.Duration, delaySource string, primaryChannelId int) *HedgeOutcome {
+	return &HedgeOutcome{
+		policyId:         policy.Id,
+		policyName:       policy.Name,
+		delayMs:          delay.Milliseconds(),
+		delaySource:      delaySource,
+		primaryChannelId: primaryChannelId,
+	}
+}
+
+// SetHedgeChannel 记录对冲请求已发往的渠道
+func (o *HedgeOutcome) SetHedgeChannel(channelId int) {
+	o.mu.Lock()
+	o.hedgeChannelId = channelId
+	o.mu.Unlock()
+}
+
+// SetWinner 记录先写出响应的一方
+func (o *HedgeOutcome) SetWinner(winner string) {
+	o.mu.Lock()
+	o.winner = winner
+	o.mu.Unlock()
+}
+
+func (o *HedgeOutcome) Winner() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.winner
+}
+
+// Snapshot 返回写入日志的快照
+func (o *HedgeOutcome) Snapshot() map[string]interface{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	snapshot := map[string]interface{}{
+		"policy_id":          o.policyId,
+		"policy_name":        o.policyName,
+		"delay_ms":           o.delayMs,
+		"delay_source":       o.delaySource,
+		"primary_channel_id": o.primaryChannelId,
+		"fired":              o.hedgeChannelId > 0,
+	}
+	if o.hedgeChannelId > 0 {
+		snapshot["hedge_channel_id"] = o.hedgeChannelId
+	}
+	if o.winner != "" {
+		snapshot["winner"] = o.winner
+	}
+	return snapshot
+}
+
+// AppendHedgeAdminInfo 把对冲过程写入日志的 admin_info
+func AppendHedgeAdminInfo(c *gin.Context, adminInfo map[string]interface{}) {
+	if c == nil || adminInfo == nil {
+		return
+	}
+	if outcome, ok := common.GetContextKeyType[*HedgeOutcome](c, constant.ContextKeyHedge); ok && outcome != nil {
+		adminInfo["hedge"] = outcome.Snapshot()
+	}
+}
+
+// SettleHedgeLoser 落选的对冲分支不向用户结算，返回 true 时调用方应跳过结算与消费日志。
+// 策略指定了计费账户时，落选方已产生的部分用量记到该账户并留一条系统日志。
+func SettleHedgeLoser(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, quota int, promptTokens int, completionTokens int) bool {
+	arm := relayInfo.HedgeArm
+	if !arm.Lost() {
+		return false
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("对冲请求落选分支（渠道 #%d）不向用户 %d 计费，已产生用量 %s",
+		relayInfo.ChannelId, relayInfo.UserId, logger.FormatQuota(quota)))
+	if quota <= 0 || arm.LoserBillingUserId <= 0 {
+		return true
+	}
+	if err := model.DecreaseUserQuota(arm.LoserBillingUserId, quota, false); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to bill hedge loser usage to user %d: %s", arm.LoserBillingUserId, err.Error()))
+		return true
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(arm.LoserBillingUserId, quota)
+	model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+	adminInfo := map[string]interface{}{
+		"request_id":        ctx.GetString(common.RequestIdKey),
+		"user_id":           relayInfo.UserId,
+		"channel_id":        relayInfo.ChannelId,
+		"model_name":        relayInfo.OriginModelName,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"quota":             quota,
+	}
+	AppendHedgeAdminInfo(ctx, adminInfo)
+	model.RecordLogWithAdminInfo(arm.LoserBillingUserId, model.LogTypeSystem,
+		fmt.Sprintf("对冲请求落选分支用量：用户 %d，模型 %s，渠道 #%d，消耗 %s",
+			relayInfo.UserId, relayInfo.OriginModelName, relayInfo.ChannelId, logger.LogQuota(quota)), adminInfo)
+	return true
+}
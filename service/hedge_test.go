@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestMatchHedgePolicyPrefersMostSpecific(t *testing.T) {
+	truncate(t)
+	InvalidateHedgePolicyCache()
+	t.Cleanup(InvalidateHedgePolicyCache)
+
+	for _, policy := range []*model.HedgePolicy{
+		{Name: "group", Enabled: true, Group: "vip"},
+		{Name: "model", Enabled: true, ModelName: "gpt-4o"},
+		{Name: "both", Enabled: true, ModelName: "gpt-4o", Group: "vip"},
+		{Name: "off", ModelName: "gpt-4o-mini"},
+	} {
+		require.NoError(t, policy.Normalize())
+		require.NoError(t, policy.Insert())
+	}
+
+	assert.Equal(t, "both", MatchHedgePolicy("gpt-4o", "vip").Name)
+	assert.Equal(t, "model", MatchHedgePolicy("gpt-4o", "default").Name)
+	assert.Equal(t, "group", MatchHedgePolicy("claude-sonnet", "vip").Name)
+	assert.Nil(t, MatchHedgePolicy("gpt-4o-mini", "default"), "disabled policies never match")
+
+	matched := MatchHedgePolicy("gpt-4o", "vip")
+	matched.DelayMs = 1
+	assert.Zero(t, MatchHedgePolicy("gpt-4o", "vip").DelayMs, "callers get a copy of the cached policy")
+}
+
+func TestHedgeDelaySources(t *testing.T) {
+	policy := &model.HedgePolicy{DelayMs: 300, FallbackDelayMs: 1500}
+	delay, source := HedgeDelay(policy, "hedge-delay-model", true)
+	assert.Equal(t, 300*time.Millisecond, delay)
+	assert.Equal(t, HedgeDelaySourceFixed, source)
+
+	policy.DelayMs = 0
+	delay, source = HedgeDelay(policy, "hedge-delay-model", true)
+	assert.Equal(t, 1500*time.Millisecond, delay)
+	assert.Equal(t, HedgeDelaySourceFallback, source, "no samples yet")
+
+	for i := 1; i <= hedgeMinObservedSamples; i++ {
+		perfmetrics.Record(perfmetrics.Sample{Model: "hedge-delay-model", LatencyMs: int64(i) * 1000, TtftMs: int64(i) * 100, HasTtft: true, Success: true})
+	}
+	perfmetrics.Record(perfmetrics.Sample{Model: "hedge-delay-model", LatencyMs: 1, Success: false})
+
+	delay, source = HedgeDelay(policy, "hedge-delay-model", true)
+	assert.Equal(t, 1800*time.Millisecond, delay, "streaming requests use the p90 time to first token")
+	assert.Equal(t, HedgeDelaySourceObserved, source)
+	delay, source = HedgeDelay(policy, "hedge-delay-model", false)
+	assert.Equal(t, 18*time.Second, delay, "other requests use the p90 latency")
+	assert.Equal(t, HedgeDelaySourceObserved, source)
+}
+
+func TestSettleHedgeLoserBillsAdminAccount(t *testing.T) {
+	truncate(t)
+	seedUser(t, 1, 0)
+	admin := &model.User{Id: 2, Username: "hedge_admin", AffCode: "hedge", Quota: 10000, Status: common.UserStatusEnabled}
+	require.NoError(t, model.DB.Create(admin).Error)
+	seedChannel(t, 5)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(common.RequestIdKey, "req-hedge-loser")
+	outcome := NewHedgeOutcome(&model.HedgePolicy{Id: 3, Name: "chat"}, time.Second, HedgeDelaySourceFixed, 5)
+	common.SetContextKey(c, constant.ContextKeyHedge, outcome)
+	relayInfo := &relaycommon.RelayInfo{
+		UserId:          1,
+		OriginModelName: "gpt-4o",
+		ChannelMeta:     &relaycommon.ChannelMeta{ChannelId: 5},
+		HedgeArm:        relaycommon.NewHedgeArm(context.Background(), false, 2),
+	}
+
+	assert.False(t, SettleHedgeLoser(c, relayInfo, 300, 10, 20), "the winner settles normally")
+	assert.Equal(t, 10000, getUserQuota(t, 2))
+
+	relayInfo.HedgeArm.MarkLost()
+	assert.True(t, SettleHedgeLoser(c, relayInfo, 300, 10, 20))
+	assert.Equal(t, 9700, getUserQuota(t, 2))
+	assert.Equal(t, 0, getUserQuota(t, 1), "the requesting user is not billed")
+	assert.EqualValues(t, 300, getChannelUsedQuota(t, 5))
+	log := getLastLog(t)
+	require.NotNil(t, log)
+	assert.Equal(t, 2, log.UserId)
+	assert.Equal(t, model.LogTypeSystem, log.Type)
+	assert.EqualValues(t, 3, gjson.Get(log.Other, "admin_info.hedge.policy_id").Int())
+	assert.EqualValues(t, 1, gjson.Get(log.Other, "admin_info.user_id").Int())
+
+	relayInfo.HedgeArm = relaycommon.NewHedgeArm(context.Background(), true, 0)
+	relayInfo.HedgeArm.MarkLost()
+	assert.True(t, SettleHedgeLoser(c, relayInfo, 300, 10, 20), "without an admin account the usage is dropped")
+	assert.Equal(t, 9700, getUserQuota(t, 2))
+}
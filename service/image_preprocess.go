@@ -0,0 +1,489 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+)
+
+// ImagePreprocessStats summarizes image preprocessing for one request. It is
+// stored in the gin context and written to the consume log's other field.
+type ImagePreprocessStats struct {
+	Images        int   `json:"images"`
+	Resized       int   `json:"resized"`
+	OriginalBytes int64 `json:"original_bytes"`
+	FinalBytes    int64 `json:"final_bytes"`
+	// SavedTokens is the estimated prompt token difference between the
+	// original and the actually resized images (OpenAI tile/patch billing
+	// only). It has already been taken off the request's token estimate.
+	SavedTokens int `json:"saved_tokens,omitempty"`
+}
+
+// ImagePreprocessResult is the outcome of preprocessing a single image.
+type ImagePreprocessResult struct {
+	Data           []byte
+	MimeType       string
+	OriginalWidth  int
+	OriginalHeight int
+	Width          int
+	Height         int
+	Changed        bool
+}
+
+// ResolveImagePreprocessPolicy returns the policy that applies to the model on
+// the given channel. A channel policy overrides the global model policies.
+func ResolveImagePreprocessPolicy(channelSetting *dto.ChannelSettings, model string) *dto.ImagePreprocessPolicy {
+	if channelSetting != nil && channelSetting.ImagePreprocess != nil {
+		policy := channelSetting.ImagePreprocess
+		if policy.Enabled && imagePreprocessPolicyMatches(policy, model) {
+			return policy
+		}
+		return nil
+	}
+	policies := model_setting.GetImagePreprocessSettings().Policies
+	for i := range policies {
+		if policies[i].Enabled && imagePreprocessPolicyMatches(&policies[i], model) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+func imagePreprocessPolicyMatches(policy *dto.ImagePreprocessPolicy, model string) bool {
+	if len(policy.ModelPatterns) == 0 {
+		return true
+	}
+	return matchAnyModelPattern(policy.ModelPatterns, model)
+}
+
+func getImagePreprocessStats(c *gin.Context) *ImagePreprocessStats {
+	if stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats); ok && stats != nil {
+		return stats
+	}
+	stats := &ImagePreprocessStats{}
+	common.SetContextKey(c, constant.ContextKeyImagePreprocessStats, stats)
+	return stats
+}
+
+// PreprocessImageBytes decodes an image and applies the policy's size limits
+// and target encoding. When nothing needs to change, or re-encoding would only
+// make the image larger, the original bytes are returned with Changed=false.
+// Animated GIFs are left untouched because re-encoding keeps only one frame.
+// Images declaring more pixels than the configured source limit are rejected
+// before their pixel data is decoded.
+func PreprocessImageBytes(data []byte, policy *dto.ImagePreprocessPolicy) (*ImagePreprocessResult, error) {
+	config, format, err := decodeImageConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	maxPixels := model_setting.GetImagePreprocessSettings().GetMaxSourcePixels()
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, fmt.Errorf("image %dx%d exceeds the %d pixel preprocessing limit", config.Width, config.Height, maxPixels)
+	}
+	result := &ImagePreprocessResult{
+		Data:           data,
+		MimeType:       "image/" + format,
+		OriginalWidth:  config.Width,
+		OriginalHeight: config.Height,
+		Width:          config.Width,
+		Height:         config.Height,
+	}
+	if policy == nil || format == "gif" {
+		return result, nil
+	}
+
+	width, height, resize := policy.TargetSize(config.Width, config.Height)
+	targetFormat := policy.TargetFormat(format)
+	if !resize && targetFormat == format {
+		return result, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if resize {
+		dst := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+		img = dst
+	}
+
+	encoded, mimeType, err := encodePreprocessedImage(img, targetFormat, policy.GetQuality())
+	if err != nil {
+		return nil, err
+	}
+	if !resize && len(encoded) >= len(data) {
+		return result, nil
+	}
+	result.Data = encoded
+	result.MimeType = mimeType
+	result.Width = width
+	result.Height = height
+	result.Changed = true
+	return result, nil
+}
+
+func encodePreprocessedImage(img image.Image, format string, quality int) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	switch format {
+	case dto.ImagePreprocessFormatJPEG:
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	case dto.ImagePreprocessFormatWebP:
+		if err := nativewebp.Encode(buf, img, nil); err != nil {
+			return nil, "", fmt.Errorf("failed to encode webp: %w", err)
+		}
+		return buf.Bytes(), "image/webp", nil
+	case dto.ImagePreprocessFormatPNG:
+		if err := png.Encode(buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode png: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	default:
+		// Source formats without an encoder (e.g. bmp) fall back to PNG.
+		return encodePreprocessedImage(img, dto.ImagePreprocessFormatPNG, quality)
+	}
+}
+
+// preprocessImageSource loads an image source (base64 or URL) and preprocesses
+// it. It returns the new base64 payload and the preprocessing result, or a nil
+// result when the original should be forwarded unchanged.
+func preprocessImageSource(c *gin.Context, source types.FileSource, policy *dto.ImagePreprocessPolicy, stats *ImagePreprocessStats) (string, *ImagePreprocessResult) {
+	if source == nil {
+		return "", nil
+	}
+	base64Data, _, err := GetBase64Data(c, source, "image_preprocess")
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("image preprocess: failed to load %s: %s", source.GetIdentifier(), err.Error()))
+		return "", nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("image preprocess: invalid base64 for %s: %s", source.GetIdentifier(), err.Error()))
+		return "", nil
+	}
+	result, err := PreprocessImageBytes(raw, policy)
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("image preprocess: skip %s: %s", source.GetIdentifier(), err.Error()))
+		return "", nil
+	}
+	stats.Images++
+	stats.OriginalBytes += int64(len(raw))
+	stats.FinalBytes += int64(len(result.Data))
+	if !result.Changed {
+		return "", nil
+	}
+	if result.Width != result.OriginalWidth || result.Height != result.OriginalHeight {
+		stats.Resized++
+	}
+	logger.LogDebug(c, "image preprocess: %dx%d -> %dx%d, %d -> %d bytes, %s",
+		result.OriginalWidth, result.OriginalHeight, result.Width, result.Height, len(raw), len(result.Data), result.MimeType)
+	return base64.StdEncoding.EncodeToString(result.Data), result
+}
+
+// ResetImagePreprocessStats clears the previous attempt's statistics and gives
+// its token saving back to the estimate. It runs at the start of every attempt,
+// so a retry that does not preprocess (no matching policy, or pass-through)
+// neither logs nor bills the images of an earlier attempt.
+func ResetImagePreprocessStats(c *gin.Context, info *relaycommon.RelayInfo) {
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats)
+	if !ok || stats == nil {
+		return
+	}
+	if stats.SavedTokens != 0 && info != nil {
+		info.SetEstimatePromptTokens(info.GetEstimatePromptTokens() + stats.SavedTokens)
+	}
+	*stats = ImagePreprocessStats{}
+}
+
+// PreprocessOpenAIRequestImages applies the channel/model image policy to the
+// image_url parts of a chat completions request, replacing them with data URLs.
+// Failures on individual images are logged and the original image is kept.
+// The request's prompt token estimate is lowered for the images actually resized.
+func PreprocessOpenAIRequestImages(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) {
+	ResetImagePreprocessStats(c, info)
+	policy := ResolveImagePreprocessPolicy(&info.ChannelSetting, info.OriginModelName)
+	if policy == nil || request == nil {
+		return
+	}
+	stats := getImagePreprocessStats(c)
+	savedTokens := 0
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		if message.IsStringContent() {
+			continue
+		}
+		contents := message.ParseContent()
+		changed := false
+		for j := range contents {
+			if contents[j].Type != dto.ContentTypeImageURL {
+				continue
+			}
+			img := contents[j].GetImageMedia()
+			if img == nil || img.Url == "" {
+				continue
+			}
+			data, result := preprocessImageSource(c, contents[j].ToFileSource(), policy, stats)
+			if result == nil {
+				continue
+			}
+			savedTokens += imageResizeSavedTokens(c, info, img.Detail, result)
+			contents[j].ImageUrl = &dto.MessageImageUrl{
+				Url:      fmt.Sprintf("data:%s;base64,%s", result.MimeType, data),
+				Detail:   img.Detail,
+				MimeType: result.MimeType,
+			}
+			changed = true
+		}
+		if changed {
+			message.SetMediaContent(contents)
+		}
+	}
+	settleImagePreprocessSavedTokens(info, stats, savedTokens)
+}
+
+// PreprocessClaudeRequestImages applies the channel/model image policy to the
+// image blocks of a Claude messages request, rewriting them as base64 sources.
+func PreprocessClaudeRequestImages(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) {
+	ResetImagePreprocessStats(c, info)
+	policy := ResolveImagePreprocessPolicy(&info.ChannelSetting, info.OriginModelName)
+	if policy == nil || request == nil {
+		return
+	}
+	stats := getImagePreprocessStats(c)
+	savedTokens := 0
+	for i := range request.Messages {
+		message := &request.Messages[i]
+		if message.IsStringContent() {
+			continue
+		}
+		contents, err := message.ParseContent()
+		if err != nil {
+			continue
+		}
+		changed := false
+		for j := range contents {
+			source := contents[j].Source
+			if contents[j].Type != "image" || source == nil {
+				continue
+			}
+			var fileSource types.FileSource
+			switch source.Type {
+			case "base64":
+				data, _ := source.Data.(string)
+				if data == "" {
+					continue
+				}
+				fileSource = types.NewBase64FileSource(data, source.MediaType)
+			case "url":
+				if source.Url == "" {
+					continue
+				}
+				fileSource = types.NewURLFileSource(source.Url)
+			default:
+				continue
+			}
+			data, result := preprocessImageSource(c, fileSource, policy, stats)
+			if result == nil {
+				continue
+			}
+			savedTokens += imageResizeSavedTokens(c, info, "", result)
+			contents[j].Source = &dto.ClaudeMessageSource{
+				Type:      "base64",
+				MediaType: result.MimeType,
+				Data:      data,
+			}
+			changed = true
+		}
+		if changed {
+			message.Content = contents
+		}
+	}
+	settleImagePreprocessSavedTokens(info, stats, savedTokens)
+}
+
+// PreprocessResponsesRequestImages applies the channel/model image policy to the
+// input_image parts of a Responses request, replacing them with data URLs.
+// Images referenced by file_id are forwarded untouched. Only the input items
+// holding a rewritten image are re-encoded.
+func PreprocessResponsesRequestImages(c *gin.Context, info *relaycommon.RelayInfo, request *dto.OpenAIResponsesRequest) {
+	ResetImagePreprocessStats(c, info)
+	policy := ResolveImagePreprocessPolicy(&info.ChannelSetting, info.OriginModelName)
+	if policy == nil || request == nil || common.GetJsonType(request.Input) != "array" {
+		return
+	}
+	var items []json.RawMessage
+	if err := common.Unmarshal(request.Input, &items); err != nil {
+		return
+	}
+	stats := getImagePreprocessStats(c)
+	savedTokens := 0
+	inputChanged := false
+	for i := range items {
+		var item map[string]json.RawMessage
+		if err := common.Unmarshal(items[i], &item); err != nil || common.GetJsonType(item["content"]) != "array" {
+			continue
+		}
+		var parts []map[string]json.RawMessage
+		if err := common.Unmarshal(item["content"], &parts); err != nil {
+			continue
+		}
+		changed := false
+		for _, part := range parts {
+			saved, ok := preprocessResponsesInputImage(c, info, part, policy, stats)
+			if !ok {
+				continue
+			}
+			savedTokens += saved
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		content, err := common.Marshal(parts)
+		if err != nil {
+			continue
+		}
+		item["content"] = content
+		if encoded, err := common.Marshal(item); err == nil {
+			items[i] = encoded
+			inputChanged = true
+		}
+	}
+	if inputChanged {
+		if input, err := common.Marshal(items); err == nil {
+			request.Input = input
+		}
+	}
+	settleImagePreprocessSavedTokens(info, stats, savedTokens)
+}
+
+// preprocessResponsesInputImage rewrites a single input_image part in place.
+// It returns the prompt tokens saved and whether the part was changed.
+func preprocessResponsesInputImage(c *gin.Context, info *relaycommon.RelayInfo, part map[string]json.RawMessage, policy *dto.ImagePreprocessPolicy, stats *ImagePreprocessStats) (int, bool) {
+	var partType string
+	if err := common.Unmarshal(part["type"], &partType); err != nil || partType != "input_image" {
+		return 0, false
+	}
+	var url, detail string
+	switch common.GetJsonType(part["image_url"]) {
+	case "string":
+		_ = common.Unmarshal(part["image_url"], &url)
+	case "object":
+		var imageUrl dto.MessageImageUrl
+		_ = common.Unmarshal(part["image_url"], &imageUrl)
+		url = imageUrl.Url
+	}
+	if url == "" {
+		return 0, false
+	}
+	if part["detail"] != nil {
+		_ = common.Unmarshal(part["detail"], &detail)
+	}
+	data, result := preprocessImageSource(c, types.NewFileSourceFromData(url, ""), policy, stats)
+	if result == nil {
+		return 0, false
+	}
+	encoded, err := common.Marshal(fmt.Sprintf("data:%s;base64,%s", result.MimeType, data))
+	if err != nil {
+		return 0, false
+	}
+	part["image_url"] = encoded
+	return imageResizeSavedTokens(c, info, detail, result), true
+}
+
+// PreprocessGeminiRequestImages applies the channel/model image policy to the
+// inlineData image parts of a Gemini request. fileData parts point at files
+// kept by the provider (Files API or GCS URIs) and are forwarded untouched.
+func PreprocessGeminiRequestImages(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) {
+	ResetImagePreprocessStats(c, info)
+	policy := ResolveImagePreprocessPolicy(&info.ChannelSetting, info.OriginModelName)
+	if policy == nil || request == nil {
+		return
+	}
+	stats := getImagePreprocessStats(c)
+	savedTokens := 0
+	for i := range request.Contents {
+		parts := request.Contents[i].Parts
+		for j := range parts {
+			inline := parts[j].InlineData
+			if inline == nil || !strings.HasPrefix(inline.MimeType, "image/") {
+				continue
+			}
+			data, result := preprocessImageSource(c, inline.ToFileSource(), policy, stats)
+			if result == nil {
+				continue
+			}
+			savedTokens += imageResizeSavedTokens(c, info, "", result)
+			parts[j].InlineData = &dto.GeminiInlineData{MimeType: result.MimeType, Data: data}
+		}
+	}
+	settleImagePreprocessSavedTokens(info, stats, savedTokens)
+}
+
+// imageResizeSavedTokens returns how many fewer prompt tokens the estimate
+// charges for a resized image than for the original one.
+func imageResizeSavedTokens(c *gin.Context, info *relaycommon.RelayInfo, detail string, result *ImagePreprocessResult) int {
+	if result.Width == result.OriginalWidth && result.Height == result.OriginalHeight {
+		return 0
+	}
+	pricing := getImageTokenPricing(info.OriginModelName)
+	if !pricing.sizeBased(detail, info.IsStream) {
+		return 0
+	}
+	original := calcImageTokensBySize(c, result.OriginalWidth, result.OriginalHeight, pricing)
+	resized := calcImageTokensBySize(c, result.Width, result.Height, pricing)
+	return max(original-resized, 0)
+}
+
+// settleImagePreprocessSavedTokens takes the tokens saved by this attempt off
+// the prompt token estimate.
+func settleImagePreprocessSavedTokens(info *relaycommon.RelayInfo, stats *ImagePreprocessStats, saved int) {
+	if saved == 0 {
+		return
+	}
+	stats.SavedTokens = saved
+	info.SetEstimatePromptTokens(info.GetEstimatePromptTokens() - saved)
+}
+
+func appendImagePreprocessInfo(ctx *gin.Context, other map[string]interface{}) {
+	if ctx == nil || other == nil {
+		return
+	}
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](ctx, constant.ContextKeyImagePreprocessStats)
+	if !ok || stats == nil || (stats.Images == 0 && stats.SavedTokens == 0) {
+		return
+	}
+	info := map[string]interface{}{
+		"images":         stats.Images,
+		"resized":        stats.Resized,
+		"original_bytes": stats.OriginalBytes,
+		"final_bytes":    stats.FinalBytes,
+	}
+	if stats.OriginalBytes > stats.FinalBytes {
+		info["saved_bytes"] = stats.OriginalBytes - stats.FinalBytes
+	}
+	if stats.SavedTokens > 0 {
+		info["saved_tokens"] = stats.SavedTokens
+	}
+	other["image_preprocess"] = info
+}
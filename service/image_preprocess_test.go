@@ -0,0 +1,348 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/relaykit/types"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x + y), A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestImagePreprocessPolicyTargetSize(t *testing.T) {
+	policy := &dto.ImagePreprocessPolicy{MaxLongEdge: 1000}
+	w, h, ok := policy.TargetSize(4000, 2000)
+	assert.True(t, ok)
+	assert.Equal(t, 1000, w)
+	assert.Equal(t, 500, h)
+
+	_, _, ok = policy.TargetSize(800, 600)
+	assert.False(t, ok)
+
+	policy = &dto.ImagePreprocessPolicy{MaxPixels: 10000}
+	w, h, ok = policy.TargetSize(400, 100)
+	assert.True(t, ok)
+	assert.LessOrEqual(t, w*h, 10000)
+	assert.Equal(t, 200, w)
+	assert.Equal(t, 50, h)
+}
+
+func TestPreprocessImageBytesResizesAndReencodes(t *testing.T) {
+	data := newTestPNG(t, 256, 128)
+
+	result, err := PreprocessImageBytes(data, &dto.ImagePreprocessPolicy{
+		Enabled:     true,
+		MaxLongEdge: 64,
+		Format:      dto.ImagePreprocessFormatJPEG,
+		Quality:     70,
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, "image/jpeg", result.MimeType)
+	assert.Equal(t, 64, result.Width)
+	assert.Equal(t, 32, result.Height)
+
+	config, format, err := decodeImageConfig(result.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 64, config.Width)
+	assert.Equal(t, 32, config.Height)
+
+	result, err = PreprocessImageBytes(data, &dto.ImagePreprocessPolicy{
+		Enabled:     true,
+		MaxLongEdge: 100,
+		Format:      dto.ImagePreprocessFormatWebP,
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, "image/webp", result.MimeType)
+	config, format, err = decodeImageConfig(result.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "webp", format)
+	assert.Equal(t, 100, config.Width)
+	assert.Equal(t, 50, config.Height)
+}
+
+func TestPreprocessImageBytesKeepsSmallImage(t *testing.T) {
+	data := newTestPNG(t, 32, 32)
+	result, err := PreprocessImageBytes(data, &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 512})
+	require.NoError(t, err)
+	assert.False(t, result.Changed)
+	assert.Equal(t, data, result.Data)
+}
+
+func TestPreprocessImageBytesRejectsOversizedCanvas(t *testing.T) {
+	settings := model_setting.GetImagePreprocessSettings()
+	original := settings.MaxSourcePixels
+	t.Cleanup(func() { settings.MaxSourcePixels = original })
+	settings.MaxSourcePixels = 100 * 100
+
+	_, err := PreprocessImageBytes(newTestPNG(t, 200, 100), &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50})
+	assert.ErrorContains(t, err, "pixel preprocessing limit", "the declared size is checked before decoding")
+
+	result, err := PreprocessImageBytes(newTestPNG(t, 100, 100), &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50})
+	require.NoError(t, err)
+	assert.True(t, result.Changed)
+}
+
+func TestResolveImagePreprocessPolicy(t *testing.T) {
+	settings := model_setting.GetImagePreprocessSettings()
+	original := settings.Policies
+	t.Cleanup(func() { settings.Policies = original })
+	settings.Policies = []dto.ImagePreprocessPolicy{
+		{Enabled: true, ModelPatterns: []string{"^gpt-4o"}, MaxLongEdge: 1024},
+	}
+
+	policy := ResolveImagePreprocessPolicy(nil, "gpt-4o-mini")
+	require.NotNil(t, policy)
+	assert.Equal(t, 1024, policy.MaxLongEdge)
+	assert.Nil(t, ResolveImagePreprocessPolicy(nil, "claude-sonnet-4"))
+
+	channelSetting := &dto.ChannelSettings{ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: false}}
+	assert.Nil(t, ResolveImagePreprocessPolicy(channelSetting, "gpt-4o-mini"))
+
+	channelSetting.ImagePreprocess = &dto.ImagePreprocessPolicy{Enabled: true, MaxPixels: 1000}
+	policy = ResolveImagePreprocessPolicy(channelSetting, "claude-sonnet-4")
+	require.NotNil(t, policy)
+	assert.Equal(t, 1000, policy.MaxPixels)
+}
+
+func TestPreprocessOpenAIRequestImagesRewritesDataURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(newTestPNG(t, 200, 100))
+	request := &dto.GeneralOpenAIRequest{
+		Model: "gpt-4o",
+		Messages: []dto.Message{{
+			Role: "user",
+			Content: []any{
+				map[string]any{"type": "text", "text": "describe"},
+				map[string]any{"type": "image_url", "image_url": map[string]any{"url": dataURL, "detail": "low"}},
+			},
+		}},
+	}
+	channelSetting := &dto.ChannelSettings{ImagePreprocess: &dto.ImagePreprocessPolicy{
+		Enabled:     true,
+		MaxLongEdge: 50,
+		Format:      dto.ImagePreprocessFormatJPEG,
+	}}
+
+	info := &relaycommon.RelayInfo{OriginModelName: "gpt-4o", ChannelMeta: &relaycommon.ChannelMeta{ChannelSetting: *channelSetting}}
+	PreprocessOpenAIRequestImages(c, info, request)
+
+	contents := request.Messages[0].ParseContent()
+	require.Len(t, contents, 2)
+	img := contents[1].GetImageMedia()
+	require.NotNil(t, img)
+	assert.Equal(t, "low", img.Detail)
+	config, format, _, err := DecodeBase64ImageData(img.Url)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 50, config.Width)
+	assert.Equal(t, 25, config.Height)
+
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats)
+	require.True(t, ok)
+	assert.Equal(t, 1, stats.Images)
+	assert.Equal(t, 1, stats.Resized)
+	assert.Positive(t, stats.FinalBytes)
+
+	other := map[string]interface{}{}
+	appendImagePreprocessInfo(c, other)
+	assert.Contains(t, other, "image_preprocess")
+}
+
+func TestPreprocessLowersTokenEstimateOnlyForResizedImages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	originalMediaToken := constant.GetMediaToken
+	originalNotStream := constant.GetMediaTokenNotStream
+	t.Cleanup(func() {
+		constant.GetMediaToken = originalMediaToken
+		constant.GetMediaTokenNotStream = originalNotStream
+	})
+	constant.GetMediaToken = true
+	constant.GetMediaTokenNotStream = true
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	imageData := base64.StdEncoding.EncodeToString(newTestPNG(t, 1024, 512))
+	newRequest := func() *dto.GeneralOpenAIRequest {
+		return &dto.GeneralOpenAIRequest{
+			Model: "gpt-4.1-mini",
+			Messages: []dto.Message{{
+				Role: "user",
+				Content: []any{
+					map[string]any{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64," + imageData}},
+				},
+			}},
+		}
+	}
+
+	// 32px patches with the gpt-4.1-mini multiplier: 32x16 patches before, 16x8 after
+	imageTokens, err := getImageToken(c, &types.FileMeta{Source: types.NewBase64FileSource(imageData, "image/png")}, "gpt-4.1-mini", false)
+	require.NoError(t, err)
+	assert.Equal(t, 829, imageTokens, "the estimate prices the original image")
+	saved := 829 - 207
+
+	info := &relaycommon.RelayInfo{OriginModelName: "gpt-4.1-mini", ChannelMeta: &relaycommon.ChannelMeta{ChannelSetting: dto.ChannelSettings{
+		ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 512},
+	}}}
+	info.SetEstimatePromptTokens(1000)
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	assert.Equal(t, 1000-saved, info.GetEstimatePromptTokens())
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats)
+	require.True(t, ok)
+	assert.Equal(t, saved, stats.SavedTokens)
+
+	// a retry on a channel without a policy forwards the full-size image again
+	info.ChannelSetting = dto.ChannelSettings{}
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	assert.Equal(t, 1000, info.GetEstimatePromptTokens())
+	assert.Zero(t, stats.SavedTokens)
+
+	// images that are not resized keep their estimate
+	info.ChannelSetting = dto.ChannelSettings{ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 2048}}
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	assert.Equal(t, 1000, info.GetEstimatePromptTokens())
+}
+
+func TestImagePreprocessStatsResetOnEveryAttempt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(newTestPNG(t, 200, 100))
+	newRequest := func() *dto.GeneralOpenAIRequest {
+		return &dto.GeneralOpenAIRequest{
+			Model: "gpt-4o",
+			Messages: []dto.Message{{
+				Role:    "user",
+				Content: []any{map[string]any{"type": "image_url", "image_url": map[string]any{"url": dataURL}}},
+			}},
+		}
+	}
+
+	info := &relaycommon.RelayInfo{OriginModelName: "gpt-4o", ChannelMeta: &relaycommon.ChannelMeta{ChannelSetting: dto.ChannelSettings{
+		ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50},
+	}}}
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats)
+	require.True(t, ok)
+	require.Equal(t, 1, stats.Images)
+
+	// a retry on a channel without a policy must not log the previous attempt's images
+	info.ChannelSetting = dto.ChannelSettings{}
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	assert.Equal(t, ImagePreprocessStats{}, *stats)
+	other := map[string]interface{}{}
+	appendImagePreprocessInfo(c, other)
+	assert.NotContains(t, other, "image_preprocess")
+
+	// a pass-through retry never calls the preprocessors, the attempt reset covers it
+	info.ChannelSetting = dto.ChannelSettings{ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50}}
+	PreprocessOpenAIRequestImages(c, info, newRequest())
+	require.Equal(t, 1, stats.Images)
+	ResetImagePreprocessStats(c, info)
+	assert.Equal(t, ImagePreprocessStats{}, *stats)
+}
+
+func TestPreprocessResponsesRequestImagesRewritesInputImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/responses", nil)
+
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(newTestPNG(t, 200, 100))
+	input, err := common.Marshal([]any{
+		map[string]any{"role": "system", "content": "be brief"},
+		map[string]any{"role": "user", "content": []any{
+			map[string]any{"type": "input_text", "text": "describe"},
+			map[string]any{"type": "input_image", "image_url": dataURL, "detail": "low"},
+			map[string]any{"type": "input_image", "file_id": "file-123"},
+		}},
+	})
+	require.NoError(t, err)
+	request := &dto.OpenAIResponsesRequest{Model: "gpt-4o", Input: input}
+	info := &relaycommon.RelayInfo{OriginModelName: "gpt-4o", ChannelMeta: &relaycommon.ChannelMeta{ChannelSetting: dto.ChannelSettings{
+		ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50, Format: dto.ImagePreprocessFormatJPEG},
+	}}}
+	PreprocessResponsesRequestImages(c, info, request)
+
+	var items []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	require.NoError(t, common.Unmarshal(request.Input, &items))
+	require.Len(t, items, 2)
+	assert.Equal(t, `"be brief"`, string(items[0].Content))
+	var parts []map[string]any
+	require.NoError(t, common.Unmarshal(items[1].Content, &parts))
+	require.Len(t, parts, 3)
+	assert.Equal(t, "describe", parts[0]["text"])
+	assert.Equal(t, "low", parts[1]["detail"])
+	imageUrl, _ := parts[1]["image_url"].(string)
+	config, format, _, err := DecodeBase64ImageData(imageUrl)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 50, config.Width)
+	assert.Equal(t, "file-123", parts[2]["file_id"])
+
+	stats, ok := common.GetContextKeyType[*ImagePreprocessStats](c, constant.ContextKeyImagePreprocessStats)
+	require.True(t, ok)
+	assert.Equal(t, 1, stats.Images)
+	assert.Equal(t, 1, stats.Resized)
+}
+
+func TestPreprocessGeminiRequestImagesRewritesInlineData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-flash:generateContent", nil)
+
+	request := &dto.GeminiChatRequest{Contents: []dto.GeminiChatContent{{
+		Role: "user",
+		Parts: []dto.GeminiPart{
+			{Text: "describe"},
+			{InlineData: &dto.GeminiInlineData{MimeType: "image/png", Data: base64.StdEncoding.EncodeToString(newTestPNG(t, 100, 200))}},
+			{FileData: &dto.GeminiFileData{MimeType: "image/png", FileUri: "gs://bucket/image.png"}},
+		},
+	}}}
+	info := &relaycommon.RelayInfo{OriginModelName: "gemini-2.5-flash", ChannelMeta: &relaycommon.ChannelMeta{ChannelSetting: dto.ChannelSettings{
+		ImagePreprocess: &dto.ImagePreprocessPolicy{Enabled: true, MaxLongEdge: 50, Format: dto.ImagePreprocessFormatJPEG},
+	}}}
+	PreprocessGeminiRequestImages(c, info, request)
+
+	parts := request.Contents[0].Parts
+	require.NotNil(t, parts[1].InlineData)
+	assert.Equal(t, "image/jpeg", parts[1].InlineData.MimeType)
+	config, format, _, err := DecodeBase64ImageData(parts[1].InlineData.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 25, config.Width)
+	assert.Equal(t, 50, config.Height)
+	assert.Equal(t, "gs://bucket/image.png", parts[2].FileData.FileUri)
+}
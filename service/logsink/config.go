@@ -0,0 +1,183 @@
+package logsink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OverflowSpool = "spool"
+	OverflowDrop  = "drop"
+
+	defaultQueueSize     = 10000
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+	defaultReplayEvery   = 30 * time.Second
+	defaultSpoolMaxBytes = int64(1 << 30)
+	spoolDirName         = "log-spool"
+)
+
+// logTypeNames maps the names accepted in log_types to model log types.
+var logTypeNames = map[string]int{
+	"topup":   model.LogTypeTopup,
+	"consume": model.LogTypeConsume,
+	"manage":  model.LogTypeManage,
+	"system":  model.LogTypeSystem,
+	"error":   model.LogTypeError,
+	"refund":  model.LogTypeRefund,
+	"login":   model.LogTypeLogin,
+}
+
+// logTypeName is the inverse of logTypeNames, "log" for unknown types.
+func logTypeName(logType int) string {
+	for name, t := range logTypeNames {
+		if t == logType {
+			return name
+		}
+	}
+	return "log"
+}
+
+// Config is the content of the file referenced by LOG_SINKS_CONFIG.
+type Config struct {
+	// SpoolDir holds batches that could not be delivered. Each sink gets its
+	// own sub directory. Defaults to log-spool in the disk cache directory,
+	// which should be a persistent volume when delivery must survive restarts.
+	SpoolDir string       `yaml:"spool_dir"`
+	Sinks    []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig configures one sink and the pipeline that feeds it.
+type SinkConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// LogTypes limits the sink to these log types (consume, error, refund...).
+	// Empty means every log type handed to the pipeline.
+	LogTypes []string `yaml:"log_types"`
+
+	QueueSize     int           `yaml:"queue_size"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	MaxRetries    int           `yaml:"max_retries"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff"`
+	// ReplayInterval is how often spooled batches are retried.
+	ReplayInterval time.Duration `yaml:"replay_interval"`
+	// Overflow decides what happens when the queue is full: spool writes the
+	// events to disk for later delivery, drop discards them.
+	Overflow      string `yaml:"overflow"`
+	SpoolMaxBytes int64  `yaml:"spool_max_bytes"`
+
+	Kafka  *KafkaConfig  `yaml:"kafka"`
+	Syslog *SyslogConfig `yaml:"syslog"`
+	HTTP   *HTTPConfig   `yaml:"http"`
+	S3     *S3Config     `yaml:"s3"`
+
+	logTypes map[int]bool
+}
+
+// LoadConfig reads a YAML (or JSON) sink configuration. ${VAR} references
+// are expanded from the environment so credentials can stay out of the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig([]byte(os.ExpandEnv(string(data))))
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid log sink config: %w", err)
+	}
+	if err := config.normalize(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (config *Config) normalize() error {
+	if config.SpoolDir == "" {
+		config.SpoolDir = filepath.Join(common.GetDiskCacheDir(), spoolDirName)
+	}
+	names := make(map[string]bool, len(config.Sinks))
+	for i := range config.Sinks {
+		sink := &config.Sinks[i]
+		if sink.Name == "" {
+			sink.Name = sink.Type
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("duplicate log sink name %q", sink.Name)
+		}
+		names[sink.Name] = true
+		if err := sink.normalize(); err != nil {
+			return fmt.Errorf("log sink %q: %w", sink.Name, err)
+		}
+	}
+	return nil
+}
+
+func (sink *SinkConfig) normalize() error {
+	if strings.ContainsAny(sink.Name, `/\`) || sink.Name == "." || sink.Name == ".." {
+		return fmt.Errorf("name must not contain path separators")
+	}
+	if _, ok := getSinkFactory(sink.Type); !ok {
+		return fmt.Errorf("unknown sink type %q", sink.Type)
+	}
+	if len(sink.LogTypes) > 0 {
+		sink.logTypes = make(map[int]bool, len(sink.LogTypes))
+		for _, name := range sink.LogTypes {
+			logType, ok := logTypeNames[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("unknown log type %q", name)
+			}
+			sink.logTypes[logType] = true
+		}
+	}
+	if sink.QueueSize <= 0 {
+		sink.QueueSize = defaultQueueSize
+	}
+	if sink.BatchSize <= 0 {
+		sink.BatchSize = defaultBatchSize
+	}
+	if sink.FlushInterval <= 0 {
+		sink.FlushInterval = defaultFlushInterval
+	}
+	if sink.MaxRetries < 0 {
+		sink.MaxRetries = 0
+	} else if sink.MaxRetries == 0 {
+		sink.MaxRetries = defaultMaxRetries
+	}
+	if sink.RetryBackoff <= 0 {
+		sink.RetryBackoff = defaultRetryBackoff
+	}
+	if sink.ReplayInterval <= 0 {
+		sink.ReplayInterval = defaultReplayEvery
+	}
+	if sink.SpoolMaxBytes <= 0 {
+		sink.SpoolMaxBytes = defaultSpoolMaxBytes
+	}
+	switch sink.Overflow {
+	case "":
+		sink.Overflow = OverflowSpool
+	case OverflowSpool, OverflowDrop:
+	default:
+		return fmt.Errorf("overflow must be %q or %q", OverflowSpool, OverflowDrop)
+	}
+	return nil
+}
+
+// accepts reports whether the sink wants logs of this type.
+func (sink *SinkConfig) accepts(logType int) bool {
+	return len(sink.logTypes) == 0 || sink.logTypes[logType]
+}
@@ -0,0 +1,97 @@
+package logsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultSinkTimeout = 10 * time.Second
+
+func init() {
+	RegisterSinkFactory("http", newHTTPSink)
+}
+
+// HTTPConfig posts each batch as NDJSON to an HTTP endpoint.
+type HTTPConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Gzip    bool              `yaml:"gzip"`
+}
+
+type httpSink struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+func newHTTPSink(config SinkConfig) (Sink, error) {
+	if config.HTTP == nil || config.HTTP.URL == "" {
+		return nil, errors.New("http.url is required")
+	}
+	target, err := url.Parse(config.HTTP.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid http.url %q", config.HTTP.URL)
+	}
+	timeout := config.HTTP.Timeout
+	if timeout <= 0 {
+		timeout = defaultSinkTimeout
+	}
+	return &httpSink{config: *config.HTTP, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (sink *httpSink) Send(ctx context.Context, batch []Record) error {
+	body := encodeNDJSON(batch)
+	if sink.config.Gzip {
+		compressed, err := gzipBytes(body)
+		if err != nil {
+			return err
+		}
+		body = compressed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if sink.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range sink.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("http sink returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (sink *httpSink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
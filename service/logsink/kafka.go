@@ -0,0 +1,140 @@
+package logsink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"github.com/tidwall/gjson"
+)
+
+func init() {
+	RegisterSinkFactory("kafka", newKafkaSink)
+}
+
+// KafkaConfig produces one message per log. Messages are keyed by user id
+// so a user's logs stay ordered within a partition.
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+	// RequiredAcks is all (default), one or none.
+	RequiredAcks string `yaml:"required_acks"`
+	// Compression is none (default), gzip, snappy, lz4 or zstd.
+	Compression           string           `yaml:"compression"`
+	ClientID              string           `yaml:"client_id"`
+	Timeout               time.Duration    `yaml:"timeout"`
+	TLS                   bool             `yaml:"tls"`
+	TLSInsecureSkipVerify bool             `yaml:"tls_insecure_skip_verify"`
+	SASL                  *KafkaSASLConfig `yaml:"sasl"`
+}
+
+type KafkaSASLConfig struct {
+	// Mechanism is plain, scram-sha-256 or scram-sha-512.
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+// kafkaWriter is the part of kafka.Writer the sink uses, replaced in tests.
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+type kafkaSink struct {
+	writer kafkaWriter
+}
+
+func newKafkaSink(config SinkConfig) (Sink, error) {
+	cfg := config.Kafka
+	if cfg == nil || len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, errors.New("kafka.brokers and kafka.topic are required")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSinkTimeout
+	}
+	transport := &kafka.Transport{DialTimeout: timeout, ClientID: cfg.ClientID}
+	if cfg.TLS {
+		transport.TLS = &tls.Config{InsecureSkipVerify: cfg.TLSInsecureSkipVerify}
+	}
+	if cfg.SASL != nil {
+		mechanism, err := kafkaSASLMechanism(cfg.SASL)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Topic:    cfg.Topic,
+		Balancer: &kafka.Hash{},
+		// The pipeline retries and spools, so the writer only tries once.
+		MaxAttempts:  1,
+		BatchSize:    config.BatchSize,
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: timeout,
+		ReadTimeout:  timeout,
+		Transport:    transport,
+	}
+	switch strings.ToLower(cfg.RequiredAcks) {
+	case "", "all":
+		writer.RequiredAcks = kafka.RequireAll
+	case "one":
+		writer.RequiredAcks = kafka.RequireOne
+	case "none":
+		writer.RequiredAcks = kafka.RequireNone
+	default:
+		return nil, fmt.Errorf("kafka.required_acks must be all, one or none")
+	}
+	switch strings.ToLower(cfg.Compression) {
+	case "", "none":
+	case "gzip":
+		writer.Compression = kafka.Gzip
+	case "snappy":
+		writer.Compression = kafka.Snappy
+	case "lz4":
+		writer.Compression = kafka.Lz4
+	case "zstd":
+		writer.Compression = kafka.Zstd
+	default:
+		return nil, fmt.Errorf("kafka.compression must be none, gzip, snappy, lz4 or zstd")
+	}
+	return &kafkaSink{writer: writer}, nil
+}
+
+func kafkaSASLMechanism(cfg *KafkaSASLConfig) (sasl.Mechanism, error) {
+	switch strings.ToLower(cfg.Mechanism) {
+	case "", "plain":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("kafka.sasl.mechanism must be plain, scram-sha-256 or scram-sha-512")
+	}
+}
+
+func (sink *kafkaSink) Send(ctx context.Context, batch []Record) error {
+	messages := make([]kafka.Message, 0, len(batch))
+	for _, record := range batch {
+		messages = append(messages, kafka.Message{
+			Key:     []byte(gjson.GetBytes(record.Data, "user_id").Raw),
+			Value:   record.Data,
+			Headers: []kafka.Header{{Key: "log_type", Value: []byte(logTypeName(record.Type))}},
+		})
+	}
+	return sink.writer.WriteMessages(ctx, messages...)
+}
+
+func (sink *kafkaSink) Close() error {
+	return sink.writer.Close()
+}
@@ -0,0 +1,156 @@
+// Package logsink ships consume, error and task billing logs to external
+// systems (Kafka, syslog, HTTP NDJSON endpoints and S3-compatible storage)
+// in addition to the log database.
+//
+// Every configured sink gets its own pipeline: events are queued without
+// blocking the request path, sent in batches, retried with backoff and,
+// when delivery keeps failing or the queue is full, spooled to disk and
+// replayed later. Delivery is at-least-once; consumers should deduplicate
+// on request_id when that matters.
+package logsink
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/tidwall/gjson"
+)
+
+// Record is one log event. Data is the JSON encoding of model.Log, which is
+// also the line written to NDJSON payloads and spool files.
+type Record struct {
+	Type      int
+	CreatedAt int64
+	Data      []byte
+}
+
+func recordFromJSON(line []byte) Record {
+	data := make([]byte, len(line))
+	copy(data, line)
+	result := gjson.GetManyBytes(data, "type", "created_at")
+	return Record{Type: int(result[0].Int()), CreatedAt: result[1].Int(), Data: data}
+}
+
+// Sink delivers batches to one external system. Send is only called from the
+// sink's pipeline goroutine, must not retain the batch, and may be called
+// again with the same batch after an error.
+type Sink interface {
+	Send(ctx context.Context, batch []Record) error
+	Close() error
+}
+
+// SinkFactory builds a sink from its configuration.
+type SinkFactory func(config SinkConfig) (Sink, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{}
+)
+
+// RegisterSinkFactory makes a sink type available to the configuration. The
+// built-in sinks register themselves in init.
+func RegisterSinkFactory(sinkType string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	sinkFactories[sinkType] = factory
+}
+
+func getSinkFactory(sinkType string) (SinkFactory, bool) {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+	factory, ok := sinkFactories[sinkType]
+	return factory, ok
+}
+
+// Manager owns the pipelines built from one configuration.
+type Manager struct {
+	pipelines []*pipeline
+}
+
+// NewManager builds every sink and starts its pipeline.
+func NewManager(config *Config) (*Manager, error) {
+	manager := &Manager{}
+	for _, sinkConfig := range config.Sinks {
+		factory, _ := getSinkFactory(sinkConfig.Type)
+		sink, err := factory(sinkConfig)
+		if err != nil {
+			manager.Shutdown(context.Background())
+			return nil, fmt.Errorf("log sink %q: %w", sinkConfig.Name, err)
+		}
+		p, err := newPipeline(sinkConfig, sink, config.SpoolDir)
+		if err != nil {
+			_ = sink.Close()
+			manager.Shutdown(context.Background())
+			return nil, fmt.Errorf("log sink %q: %w", sinkConfig.Name, err)
+		}
+		p.start()
+		manager.pipelines = append(manager.pipelines, p)
+	}
+	return manager, nil
+}
+
+// Publish hands a log to every sink that accepts its type. It never blocks.
+func (manager *Manager) Publish(log *model.Log) {
+	if log == nil || len(manager.pipelines) == 0 {
+		return
+	}
+	data, err := common.Marshal(log)
+	if err != nil {
+		common.SysError("log sink: failed to encode log: " + err.Error())
+		return
+	}
+	record := Record{Type: log.Type, CreatedAt: log.CreatedAt, Data: data}
+	for _, p := range manager.pipelines {
+		if p.config.accepts(record.Type) {
+			p.enqueue(record)
+		}
+	}
+}
+
+// Shutdown flushes queued events. Batches that cannot be delivered before
+// ctx expires are spooled and sent after the next start.
+func (manager *Manager) Shutdown(ctx context.Context) {
+	for _, p := range manager.pipelines {
+		p.stopAndWait(ctx)
+	}
+}
+
+var defaultManager *Manager
+
+// Init starts the pipelines configured by the file in LOG_SINKS_CONFIG and
+// registers them as model.LogShipper. Without the variable it does nothing.
+func Init() error {
+	path := os.Getenv("LOG_SINKS_CONFIG")
+	if path == "" {
+		return nil
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if len(config.Sinks) == 0 {
+		return nil
+	}
+	manager, err := NewManager(config)
+	if err != nil {
+		return err
+	}
+	defaultManager = manager
+	model.LogShipper = manager.Publish
+	common.SysLog(fmt.Sprintf("log shipping enabled with %d sink(s), spool directory %s", len(config.Sinks), config.SpoolDir))
+	return nil
+}
+
+// Shutdown stops accepting logs and flushes the pipelines started by Init.
+func Shutdown(ctx context.Context) {
+	if defaultManager == nil {
+		return
+	}
+	model.LogShipper = nil
+	defaultManager.Shutdown(ctx)
+}
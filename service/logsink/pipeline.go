@@ -0,0 +1,236 @@
+package logsink
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// pipeline feeds one sink. A single worker goroutine owns batching, retries
+// and spool replay, so sinks never see concurrent calls.
+type pipeline struct {
+	config SinkConfig
+	sink   Sink
+	spool  *spool
+	queue  chan Record
+
+	// overflow collects events that did not fit in the queue until a full
+	// batch (or the next flush tick) is written to the spool.
+	overflowMu sync.Mutex
+	overflow   []Record
+	dropped    atomic.Int64
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newPipeline(config SinkConfig, sink Sink, spoolDir string) (*pipeline, error) {
+	s, err := newSpool(filepath.Join(spoolDir, config.Name), config.SpoolMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pipeline{
+		config: config,
+		sink:   sink,
+		spool:  s,
+		queue:  make(chan Record, config.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+func (p *pipeline) start() {
+	go p.run()
+}
+
+// enqueue never blocks: when the queue is full the event is spooled or
+// dropped according to the overflow policy.
+func (p *pipeline) enqueue(record Record) {
+	select {
+	case p.queue <- record:
+		return
+	default:
+	}
+	if p.config.Overflow == OverflowDrop {
+		p.dropped.Add(1)
+		return
+	}
+	p.overflowMu.Lock()
+	p.overflow = append(p.overflow, record)
+	var full []Record
+	if len(p.overflow) >= p.config.BatchSize {
+		full = p.overflow
+		p.overflow = nil
+	}
+	p.overflowMu.Unlock()
+	if full != nil {
+		p.spoolBatch(full)
+	}
+}
+
+func (p *pipeline) run() {
+	defer close(p.done)
+	flushTicker := time.NewTicker(p.config.FlushInterval)
+	defer flushTicker.Stop()
+	replayTicker := time.NewTicker(p.config.ReplayInterval)
+	defer replayTicker.Stop()
+
+	// Resend whatever the previous run left in the spool first.
+	p.replaySpool()
+	batch := make([]Record, 0, p.config.BatchSize)
+	for {
+		select {
+		case record := <-p.queue:
+			batch = append(batch, record)
+			if len(batch) >= p.config.BatchSize {
+				p.deliver(batch)
+				batch = make([]Record, 0, p.config.BatchSize)
+			}
+		case <-flushTicker.C:
+			if len(batch) > 0 {
+				p.deliver(batch)
+				batch = make([]Record, 0, p.config.BatchSize)
+			}
+			p.flushOverflow()
+			p.reportDropped()
+		case <-replayTicker.C:
+			p.replaySpool()
+		case <-p.stop:
+			p.drain(batch)
+			return
+		}
+	}
+}
+
+// drain delivers whatever is still queued when the pipeline stops.
+func (p *pipeline) drain(batch []Record) {
+	for {
+		select {
+		case record := <-p.queue:
+			batch = append(batch, record)
+			if len(batch) >= p.config.BatchSize {
+				p.deliver(batch)
+				batch = make([]Record, 0, p.config.BatchSize)
+			}
+		default:
+			if len(batch) > 0 {
+				p.deliver(batch)
+			}
+			p.flushOverflow()
+			p.reportDropped()
+			return
+		}
+	}
+}
+
+func (p *pipeline) deliver(batch []Record) {
+	if err := p.send(batch); err != nil {
+		common.SysError(fmt.Sprintf("log sink %s: failed to deliver %d events, spooling: %v", p.config.Name, len(batch), err))
+		p.spoolBatch(batch)
+	}
+}
+
+// send tries the batch up to MaxRetries+1 times with exponential backoff.
+func (p *pipeline) send(batch []Record) error {
+	backoff := p.config.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-p.ctx.Done():
+				timer.Stop()
+				return err
+			}
+			backoff *= 2
+		}
+		if err = p.sink.Send(p.ctx, batch); err == nil {
+			return nil
+		}
+		if p.ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *pipeline) spoolBatch(batch []Record) {
+	if err := p.spool.write(batch); err != nil {
+		p.dropped.Add(int64(len(batch)))
+		common.SysError(fmt.Sprintf("log sink %s: failed to spool %d events: %v", p.config.Name, len(batch), err))
+	}
+}
+
+func (p *pipeline) flushOverflow() {
+	p.overflowMu.Lock()
+	pending := p.overflow
+	p.overflow = nil
+	p.overflowMu.Unlock()
+	if len(pending) > 0 {
+		p.spoolBatch(pending)
+	}
+}
+
+func (p *pipeline) reportDropped() {
+	if dropped := p.dropped.Swap(0); dropped > 0 {
+		common.SysError(fmt.Sprintf("log sink %s: dropped %d events", p.config.Name, dropped))
+	}
+}
+
+// replaySpool resends spooled batches oldest first and stops at the first
+// failure, leaving the rest for the next replay.
+func (p *pipeline) replaySpool() {
+	names, err := p.spool.files()
+	if err != nil {
+		common.SysError(fmt.Sprintf("log sink %s: failed to list spool: %v", p.config.Name, err))
+		return
+	}
+	for _, name := range names {
+		if p.ctx.Err() != nil {
+			return
+		}
+		batch, err := p.spool.read(name)
+		if err != nil {
+			common.SysError(fmt.Sprintf("log sink %s: removing unreadable spool file %s: %v", p.config.Name, name, err))
+			_ = p.spool.remove(name)
+			continue
+		}
+		if len(batch) > 0 {
+			if err := p.sink.Send(p.ctx, batch); err != nil {
+				return
+			}
+		}
+		if err := p.spool.remove(name); err != nil {
+			common.SysError(fmt.Sprintf("log sink %s: failed to remove spool file %s: %v", p.config.Name, name, err))
+			return
+		}
+	}
+}
+
+// stopAndWait flushes the pipeline. If ctx expires first, in-flight sends
+// are cancelled and the remaining batches go to the spool.
+func (p *pipeline) stopAndWait(ctx context.Context) {
+	p.stopOnce.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+	}
+	p.cancel()
+	if err := p.sink.Close(); err != nil {
+		common.SysError(fmt.Sprintf("log sink %s: failed to close: %v", p.config.Name, err))
+	}
+}
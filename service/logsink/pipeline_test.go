@@ -0,0 +1,175 @@
+package logsink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	fail    int
+	calls   int
+	batches [][]Record
+	closed  bool
+}
+
+func (sink *memorySink) Send(ctx context.Context, batch []Record) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.calls++
+	if sink.fail != 0 {
+		if sink.fail > 0 {
+			sink.fail--
+		}
+		return errors.New("unavailable")
+	}
+	sink.batches = append(sink.batches, append([]Record(nil), batch...))
+	return nil
+}
+
+func (sink *memorySink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.closed = true
+	return nil
+}
+
+func (sink *memorySink) setFail(fail int) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.fail = fail
+}
+
+func (sink *memorySink) delivered() []int64 {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	var ids []int64
+	for _, batch := range sink.batches {
+		for _, record := range batch {
+			ids = append(ids, record.CreatedAt)
+		}
+	}
+	return ids
+}
+
+func testSinkConfig(t *testing.T, yamlBody string) SinkConfig {
+	t.Helper()
+	config, err := ParseConfig([]byte("spool_dir: " + t.TempDir() + "\nsinks:\n  - type: http\n    http: {url: http://127.0.0.1}\n" + yamlBody))
+	require.NoError(t, err)
+	return config.Sinks[0]
+}
+
+func newTestPipeline(t *testing.T, config SinkConfig, sink Sink) *pipeline {
+	t.Helper()
+	p, err := newPipeline(config, sink, t.TempDir())
+	require.NoError(t, err)
+	return p
+}
+
+func testRecord(id int64) Record {
+	return Record{Type: model.LogTypeConsume, CreatedAt: id, Data: []byte(fmt.Sprintf(`{"type":2,"created_at":%d}`, id))}
+}
+
+func TestPipelineBatchesBySizeAndInterval(t *testing.T) {
+	sink := &memorySink{}
+	p := newTestPipeline(t, testSinkConfig(t, "    batch_size: 2\n    flush_interval: 20ms\n"), sink)
+	p.start()
+	for i := int64(1); i <= 3; i++ {
+		p.enqueue(testRecord(i))
+	}
+	require.Eventually(t, func() bool { return len(sink.delivered()) == 3 }, time.Second, 5*time.Millisecond)
+	p.stopAndWait(context.Background())
+
+	assert.Equal(t, []int64{1, 2, 3}, sink.delivered())
+	assert.Len(t, sink.batches[0], 2)
+	assert.True(t, sink.closed)
+}
+
+func TestPipelineRetriesBeforeSpooling(t *testing.T) {
+	sink := &memorySink{fail: 2}
+	p := newTestPipeline(t, testSinkConfig(t, "    max_retries: 2\n    retry_backoff: 1ms\n"), sink)
+	p.deliver([]Record{testRecord(1)})
+
+	assert.Equal(t, 3, sink.calls)
+	assert.Equal(t, []int64{1}, sink.delivered())
+	names, err := p.spool.files()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestPipelineSpoolsAndReplaysInOrder(t *testing.T) {
+	sink := &memorySink{fail: -1}
+	p := newTestPipeline(t, testSinkConfig(t, "    max_retries: -1\n"), sink)
+	p.deliver([]Record{testRecord(1), testRecord(2)})
+	p.deliver([]Record{testRecord(3)})
+	names, err := p.spool.files()
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+
+	// Nothing is removed while the sink is still down.
+	p.replaySpool()
+	names, _ = p.spool.files()
+	assert.Len(t, names, 2)
+
+	sink.setFail(0)
+	p.replaySpool()
+	names, _ = p.spool.files()
+	assert.Empty(t, names)
+	assert.Equal(t, []int64{1, 2, 3}, sink.delivered())
+	assert.Equal(t, model.LogTypeConsume, sink.batches[0][0].Type)
+}
+
+func TestPipelineOverflowSpoolsAndSurvivesRestart(t *testing.T) {
+	sink := &memorySink{}
+	config := testSinkConfig(t, "    queue_size: 1\n    batch_size: 2\n")
+	spoolDir := t.TempDir()
+	p, err := newPipeline(config, sink, spoolDir)
+	require.NoError(t, err)
+	// 1 fills the queue, 2 and 3 make a full overflow batch that is spooled
+	// right away, 4 waits in memory for the next flush.
+	for i := int64(1); i <= 4; i++ {
+		p.enqueue(testRecord(i))
+	}
+	names, err := p.spool.files()
+	require.NoError(t, err)
+	assert.Len(t, names, 1)
+
+	p.start()
+	p.stopAndWait(context.Background())
+	assert.Equal(t, []int64{2, 3, 1}, sink.delivered())
+	names, _ = p.spool.files()
+	assert.Len(t, names, 1)
+
+	restarted, err := newPipeline(config, sink, spoolDir)
+	require.NoError(t, err)
+	restarted.start()
+	restarted.stopAndWait(context.Background())
+	assert.Equal(t, []int64{2, 3, 1, 4}, sink.delivered())
+	names, _ = restarted.spool.files()
+	assert.Empty(t, names)
+}
+
+func TestPipelineOverflowDrop(t *testing.T) {
+	p := newTestPipeline(t, testSinkConfig(t, "    queue_size: 1\n    overflow: drop\n"), &memorySink{})
+	p.enqueue(testRecord(1))
+	p.enqueue(testRecord(2))
+	assert.EqualValues(t, 1, p.dropped.Load())
+	names, err := p.spool.files()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestSpoolRespectsMaxBytes(t *testing.T) {
+	s, err := newSpool(t.TempDir(), 40)
+	require.NoError(t, err)
+	require.NoError(t, s.write([]Record{testRecord(1)}))
+	assert.ErrorIs(t, s.write([]Record{testRecord(2)}), errSpoolFull)
+}
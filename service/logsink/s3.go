@@ -0,0 +1,165 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+func init() {
+	RegisterSinkFactory("s3", newS3Sink)
+}
+
+// S3Config uploads each batch as one NDJSON object to S3 or any
+// S3-compatible store (MinIO, R2, OSS...). Objects are written under
+// prefix/YYYY/MM/DD/HH/ by the time of the first event and named after the
+// payload hash, so a retried batch overwrites instead of duplicating.
+type S3Config struct {
+	// Endpoint such as https://s3.us-east-1.amazonaws.com or http://minio:9000.
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	// PathStyle addresses the bucket as endpoint/bucket/key, which MinIO and
+	// most self-hosted stores need, instead of bucket.endpoint/key.
+	PathStyle bool          `yaml:"path_style"`
+	Gzip      bool          `yaml:"gzip"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+type s3Sink struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	signer   *v4.Signer
+	now      func() time.Time
+}
+
+func newS3Sink(config SinkConfig) (Sink, error) {
+	if config.S3 == nil || config.S3.Endpoint == "" || config.S3.Bucket == "" {
+		return nil, errors.New("s3.endpoint and s3.bucket are required")
+	}
+	cfg := *config.S3
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3.access_key_id and s3.secret_access_key are required")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3.endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSinkTimeout
+	}
+	return &s3Sink{
+		config:   cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+		signer: v4.NewSigner(func(options *v4.SignerOptions) {
+			// S3 signs the path exactly as sent.
+			options.DisableURIPathEscaping = true
+		}),
+		now: time.Now,
+	}, nil
+}
+
+func (sink *s3Sink) objectKey(batch []Record, payload []byte) string {
+	eventTime := sink.now()
+	if batch[0].CreatedAt > 0 {
+		eventTime = time.Unix(batch[0].CreatedAt, 0)
+	}
+	sum := sha256.Sum256(payload)
+	name := eventTime.UTC().Format("2006/01/02/15/") + hex.EncodeToString(sum[:16]) + ".ndjson"
+	if sink.config.Gzip {
+		name += ".gz"
+	}
+	if sink.config.Prefix != "" {
+		name = sink.config.Prefix + "/" + name
+	}
+	return name
+}
+
+func (sink *s3Sink) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	target := *sink.endpoint
+	basePath := strings.TrimSuffix(target.Path, "/")
+	if sink.config.PathStyle {
+		target.Path = basePath + "/" + sink.config.Bucket + "/" + strings.Join(segments, "/")
+	} else {
+		target.Host = sink.config.Bucket + "." + target.Host
+		target.Path = basePath + "/" + strings.Join(segments, "/")
+	}
+	target.RawPath = ""
+	return target.String()
+}
+
+func (sink *s3Sink) Send(ctx context.Context, batch []Record) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	body := encodeNDJSON(batch)
+	key := sink.objectKey(batch, body)
+	if sink.config.Gzip {
+		compressed, err := gzipBytes(body)
+		if err != nil {
+			return err
+		}
+		body = compressed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sink.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if sink.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	credentials := aws.Credentials{
+		AccessKeyID:     sink.config.AccessKeyID,
+		SecretAccessKey: sink.config.SecretAccessKey,
+		SessionToken:    sink.config.SessionToken,
+	}
+	if err := sink.signer.SignHTTP(ctx, credentials, req, payloadHash, "s3", sink.config.Region, sink.now()); err != nil {
+		return err
+	}
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 sink returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (sink *s3Sink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
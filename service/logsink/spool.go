@@ -0,0 +1,133 @@
+package logsink
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const spoolFileSuffix = ".ndjson"
+
+var errSpoolFull = errors.New("spool is full")
+
+// spool keeps undelivered batches on disk, one NDJSON file per batch. File
+// names start with a zero padded timestamp so lexical order is write order.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu  sync.Mutex
+	seq atomic.Uint64
+}
+
+func newSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	// A crash between write and rename leaves a temporary file behind.
+	if leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp")); err == nil {
+		for _, leftover := range leftovers {
+			_ = os.Remove(leftover)
+		}
+	}
+	return &spool{dir: dir, maxBytes: maxBytes}, nil
+}
+
+func encodeNDJSON(batch []Record) []byte {
+	size := 0
+	for _, record := range batch {
+		size += len(record.Data) + 1
+	}
+	buf := make([]byte, 0, size)
+	for _, record := range batch {
+		buf = append(buf, record.Data...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+func (s *spool) write(batch []Record) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	data := encodeNDJSON(batch)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	used, err := s.size()
+	if err != nil {
+		return err
+	}
+	if used+int64(len(data)) > s.maxBytes {
+		return errSpoolFull
+	}
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq.Add(1)%1000000, spoolFileSuffix)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+func (s *spool) size() (int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// files lists spooled batches, oldest first.
+func (s *spool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spoolFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *spool) read(name string) ([]Record, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var batch []Record
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		batch = append(batch, recordFromJSON(line))
+	}
+	return batch, scanner.Err()
+}
+
+func (s *spool) remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
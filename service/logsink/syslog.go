@@ -0,0 +1,190 @@
+package logsink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+const (
+	defaultSyslogFacility = 16 // local0
+	syslogSeverityError   = 3
+	syslogSeverityInfo    = 6
+)
+
+func init() {
+	RegisterSinkFactory("syslog", newSyslogSink)
+}
+
+// SyslogConfig sends RFC 5424 messages whose MSG part is the log JSON.
+// TCP and TLS use octet-counting framing (RFC 6587 / RFC 5425).
+type SyslogConfig struct {
+	// Network is udp, tcp or tls.
+	Network               string        `yaml:"network"`
+	Address               string        `yaml:"address"`
+	Facility              *int          `yaml:"facility"`
+	AppName               string        `yaml:"app_name"`
+	Hostname              string        `yaml:"hostname"`
+	Timeout               time.Duration `yaml:"timeout"`
+	TLSInsecureSkipVerify bool          `yaml:"tls_insecure_skip_verify"`
+}
+
+type syslogSink struct {
+	config   SyslogConfig
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	timeout  time.Duration
+	conn     net.Conn
+}
+
+func newSyslogSink(config SinkConfig) (Sink, error) {
+	if config.Syslog == nil || config.Syslog.Address == "" {
+		return nil, errors.New("syslog.address is required")
+	}
+	cfg := *config.Syslog
+	switch cfg.Network {
+	case "":
+		cfg.Network = "udp"
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("syslog.network must be udp, tcp or tls")
+	}
+	facility := defaultSyslogFacility
+	if cfg.Facility != nil {
+		facility = *cfg.Facility
+		if facility < 0 || facility > 23 {
+			return nil, fmt.Errorf("syslog.facility must be between 0 and 23")
+		}
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname = common.NodeName
+	}
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "new-api"
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSinkTimeout
+	}
+	return &syslogSink{
+		config:   cfg,
+		facility: facility,
+		hostname: syslogHeaderField(hostname, 255),
+		appName:  syslogHeaderField(appName, 48),
+		procID:   strconv.Itoa(os.Getpid()),
+		timeout:  timeout,
+	}, nil
+}
+
+// syslogHeaderField makes a value safe for an RFC 5424 header field:
+// printable US-ASCII without spaces, "-" when empty.
+func syslogHeaderField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func (sink *syslogSink) format(record Record) []byte {
+	severity := syslogSeverityInfo
+	if record.Type == model.LogTypeError {
+		severity = syslogSeverityError
+	}
+	timestamp := "-"
+	if record.CreatedAt > 0 {
+		timestamp = time.Unix(record.CreatedAt, 0).UTC().Format(time.RFC3339)
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+		sink.facility*8+severity, timestamp, sink.hostname, sink.appName, sink.procID, logTypeName(record.Type))
+	return append([]byte(header), record.Data...)
+}
+
+func (sink *syslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: sink.timeout}
+	if sink.config.Network == "tls" {
+		host, _, _ := net.SplitHostPort(sink.config.Address)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: sink.config.TLSInsecureSkipVerify,
+		}}
+		return tlsDialer.DialContext(ctx, "tcp", sink.config.Address)
+	}
+	return dialer.DialContext(ctx, sink.config.Network, sink.config.Address)
+}
+
+func (sink *syslogSink) Send(ctx context.Context, batch []Record) error {
+	if sink.conn == nil {
+		conn, err := sink.dial(ctx)
+		if err != nil {
+			return err
+		}
+		sink.conn = conn
+	}
+	deadline := time.Now().Add(sink.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := sink.conn.SetWriteDeadline(deadline); err != nil {
+		return sink.reset(err)
+	}
+	if sink.config.Network == "udp" {
+		for _, record := range batch {
+			if _, err := sink.conn.Write(sink.format(record)); err != nil {
+				return sink.reset(err)
+			}
+		}
+		return nil
+	}
+	var buf []byte
+	for _, record := range batch {
+		message := sink.format(record)
+		buf = strconv.AppendInt(buf, int64(len(message)), 10)
+		buf = append(buf, ' ')
+		buf = append(buf, message...)
+	}
+	if _, err := sink.conn.Write(buf); err != nil {
+		return sink.reset(err)
+	}
+	return nil
+}
+
+// reset drops a broken connection so the next Send reconnects.
+func (sink *syslogSink) reset(err error) error {
+	_ = sink.conn.Close()
+	sink.conn = nil
+	return err
+}
+
+func (sink *syslogSink) Close() error {
+	if sink.conn == nil {
+		return nil
+	}
+	err := sink.conn.Close()
+	sink.conn = nil
+	return err
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/tidwall/gjson"
+)
+
+// telegramAPIBase 可在测试中替换为本地服务
+var telegramAPIBase = "https://api.telegram.org"
+
+// IsChatNotifyType 聊天机器人类通知方式，消息为纯文本
+func IsChatNotifyType(notifyType string) bool {
+	switch notifyType {
+	case dto.NotifyTypeSlack, dto.NotifyTypeTeams, dto.NotifyTypeDiscord,
+		dto.NotifyTypeTelegram, dto.NotifyTypeFeishu, dto.NotifyTypeDingTalk:
+		return true
+	}
+	return false
+}
+
+// isPlainTextNotifyType 不支持 HTML 的通知方式（Bark 另有更短的格式）
+func isPlainTextNotifyType(notifyType string) bool {
+	return notifyType == dto.NotifyTypeGotify || IsChatNotifyType(notifyType)
+}
+
+func renderNotifyContent(data dto.Notify) string {
+	content := data.Content
+	for _, value := range data.Values {
+		content = strings.Replace(content, dto.ContentValueParam, fmt.Sprintf("%v", value), 1)
+	}
+	return content
+}
+
+func truncateNotifyText(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	for maxLen > 0 && !utf8.RuneStart(text[maxLen]) {
+		maxLen--
+	}
+	return text[:maxLen]
+}
+
+// sendChatNotify 按通知方式构造机器人消息并发送
+func sendChatNotify(notifyType string, userSetting dto.UserSetting, data dto.Notify) error {
+	content := renderNotifyContent(data)
+	text := data.Title + "\n" + content
+	targetURL := userSetting.ChatWebhookUrl
+	var payload any
+	switch notifyType {
+	case dto.NotifyTypeSlack:
+		payload = map[string]any{"text": "*" + data.Title + "*\n" + content}
+	case dto.NotifyTypeTeams:
+		payload = map[string]any{
+			"type": "message",
+			"attachments": []any{map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []any{
+						map[string]any{"type": "TextBlock", "text": data.Title, "weight": "Bolder", "size": "Medium", "wrap": true},
+						map[string]any{"type": "TextBlock", "text": content, "wrap": true},
+					},
+				},
+			}},
+		}
+	case dto.NotifyTypeDiscord:
+		payload = map[string]any{"embeds": []any{map[string]any{
+			"title":       truncateNotifyText(data.Title, 256),
+			"description": truncateNotifyText(content, 4096),
+		}}}
+	case dto.NotifyTypeTelegram:
+		targetURL = strings.TrimSuffix(telegramAPIBase, "/") + "/bot" + userSetting.TelegramBotToken + "/sendMessage"
+		payload = map[string]any{
+			"chat_id":                  userSetting.TelegramChatId,
+			"text":                     truncateNotifyText(text, 4096),
+			"disable_web_page_preview": true,
+		}
+	case dto.NotifyTypeFeishu:
+		message := map[string]any{"msg_type": "text", "content": map[string]any{"text": text}}
+		if userSetting.ChatWebhookSecret != "" {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			// 飞书签名以 timestamp+"\n"+secret 为密钥对空串做 HMAC-SHA256
+			mac := hmac.New(sha256.New, []byte(timestamp+"\n"+userSetting.ChatWebhookSecret))
+			message["timestamp"] = timestamp
+			message["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		}
+		payload = message
+	case dto.NotifyTypeDingTalk:
+		payload = map[string]any{"msgtype": "text", "text": map[string]any{"content": text}}
+		if userSetting.ChatWebhookSecret != "" {
+			timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+			mac := hmac.New(sha256.New, []byte(userSetting.ChatWebhookSecret))
+			mac.Write([]byte(timestamp + "\n" + userSetting.ChatWebhookSecret))
+			separator := "?"
+			if strings.Contains(targetURL, "?") {
+				separator = "&"
+			}
+			targetURL += separator + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		}
+	default:
+		return fmt.Errorf("unsupported chat notify type: %s", notifyType)
+	}
+
+	payloadBytes, err := common.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s payload: %v", notifyType, err)
+	}
+	body, err := postNotifyJSON(targetURL, payloadBytes)
+	if err != nil {
+		return fmt.Errorf("%s notify failed: %v", notifyType, err)
+	}
+	// 以下平台出错时仍可能返回 200，需要检查响应体中的错误码
+	switch notifyType {
+	case dto.NotifyTypeTelegram:
+		if !gjson.GetBytes(body, "ok").Bool() {
+			return fmt.Errorf("telegram notify failed: %s", gjson.GetBytes(body, "description").String())
+		}
+	case dto.NotifyTypeFeishu:
+		if code := gjson.GetBytes(body, "code").Int(); code != 0 {
+			return fmt.Errorf("feishu notify failed: %d %s", code, gjson.GetBytes(body, "msg").String())
+		}
+	case dto.NotifyTypeDingTalk:
+		if code := gjson.GetBytes(body, "errcode").Int(); code != 0 {
+			return fmt.Errorf("dingtalk notify failed: %d %s", code, gjson.GetBytes(body, "errmsg").String())
+		}
+	}
+	return nil
+}
+
+// postNotifyJSON 发送 JSON 通知请求，与 webhook 通知一样优先经由 Worker，否则做 SSRF 校验后直连
+func postNotifyJSON(targetURL string, payload []byte) ([]byte, error) {
+	var resp *http.Response
+	var err error
+	if system_setting.EnableWorker() {
+		resp, err = DoWorkerRequest(&WorkerRequest{
+			URL:    targetURL,
+			Key:    system_setting.WorkerValidKey,
+			Method: http.MethodPost,
+			Headers: map[string]string{
+				"Content-Type": "application/json; charset=utf-8",
+				"User-Agent":   "NewAPI-Notify/1.0",
+			},
+			Body: payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request through worker: %v", err)
+		}
+	} else {
+		if err := ValidateSSRFProtectedFetchURL(targetURL); err != nil {
+			return nil, fmt.Errorf("request reject: %v", err)
+		}
+		req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("User-Agent", "NewAPI-Notify/1.0")
+		resp, err = GetSSRFProtectedHTTPClient().Do(req)
+		if err != nil {
+			// 去掉错误中的完整 URL，Telegram 机器人令牌位于路径中
+			if urlErr, ok := err.(*url.Error); ok {
+				err = urlErr.Err
+			}
+			return nil, fmt.Errorf("failed to send request: %v", err)
+		}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/tidwall/gjson"
+)
+
+// 影子流量：主请求成功后按规则采样，把请求异步复制到影子渠道，记录两侧的耗时、错误与用量差异。
+// 规则在各节点内存中缓存 shadowRuleCacheTTL，影子消耗同时累加到缓存与数据库，
+// 多节点部署时预算最多超出各节点一个缓存周期内的消耗。
+
+const (
+	shadowRuleCacheTTL = 30 * time.Second
+	// MaxConcurrentShadowRequests 单节点同时进行的影子请求上限，超出时放弃本次采样而不是排队
+	MaxConcurrentShadowRequests = 32
+)
+
+var (
+	shadowRuleMu        sync.Mutex
+	shadowRules         []*model.ShadowRule
+	shadowRuleExpiresAt time.Time
+
+	shadowSlots = make(chan struct{}, MaxConcurrentShadowRequests)
+)
+
+// InvalidateShadowRuleCache 规则增删改后调用，下次采样时重新加载
+func InvalidateShadowRuleCache() {
+	shadowRuleMu.Lock()
+	shadowRuleExpiresAt = time.Time{}
+	shadowRuleMu.Unlock()
+}
+
+func loadShadowRulesLocked(now time.Time) []*model.ShadowRule {
+	if now.Before(shadowRuleExpiresAt) {
+		return shadowRules
+	}
+	rules, err := model.GetEnabledShadowRules()
+	if err != nil {
+		common.SysError("failed to load shadow rules: " + err.Error())
+		// 加载失败时沿用旧规则，稍后重试，避免每个请求都查询数据库
+		shadowRuleExpiresAt = now.Add(shadowRuleCacheTTL)
+		return shadowRules
+	}
+	shadowRules = rules
+	shadowRuleExpiresAt = now.Add(shadowRuleCacheTTL)
+	return shadowRules
+}
+
+// SampleShadowRule 按 id 顺序取第一条命中模型与分组且预算未耗尽的规则，再按其采样比例决定本次是否镜像，
+// 不镜像时返回 nil。返回值是副本，可以跨协程使用。
+func SampleShadowRule(modelName string, group string) *model.ShadowRule {
+	shadowRuleMu.Lock()
+	defer shadowRuleMu.Unlock()
+	for _, rule := range loadShadowRulesLocked(time.Now()) {
+		if !rule.Matches(modelName, group) || rule.BudgetExhausted() {
+			continue
+		}
+		if rand.Float64()*100 >= rule.SampleRate {
+			return nil
+		}
+		sampled := *rule
+		return &sampled
+	}
+	return nil
+}
+
+// AddShadowRuleUsage 累加影子请求的消耗
+func AddShadowRuleUsage(ruleId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	shadowRuleMu.Lock()
+	for _, rule := range shadowRules {
+		if rule.Id == ruleId {
+			rule.UsedQuota += quota
+		}
+	}
+	shadowRuleMu.Unlock()
+	return model.AddShadowRuleUsedQuota(ruleId, quota)
+}
+
+// TryAcquireShadowSlot 获取影子请求并发名额，成功后必须调用 ReleaseShadowSlot
+func TryAcquireShadowSlot() bool {
+	select {
+	case shadowSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func ReleaseShadowSlot() {
+	<-shadowSlots
+}
+
+// ShadowResponseText 提取响应中的生成文本，用于主、影子响应的相似度对比。
+// 支持 OpenAI Chat/Completions、Responses、Claude Messages、Gemini 的普通响应与 SSE 流。
+func ShadowResponseText(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	if body[0] == '{' {
+		appendShadowText(&sb, gjson.ParseBytes(body), false)
+		return sb.String()
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" || !gjson.Valid(data) {
+			continue
+		}
+		appendShadowText(&sb, gjson.Parse(data), true)
+	}
+	return sb.String()
+}
+
+var shadowTextPaths = []string{
+	"choices.#.message.content",
+	"choices.#.delta.content",
+	"choices.#.text",
+	"content.#.text",
+	"delta.text",
+	"candidates.#.content.parts.#.text",
+	"output.#.content.#.text",
+}
+
+func appendShadowText(sb *strings.Builder, event gjson.Result, stream bool) {
+	eventType := event.Get("type").String()
+	if stream && strings.HasPrefix(eventType, "response.") {
+		// Responses 流的 completed 等事件会重复携带完整输出，只取增量
+		if eventType == "response.output_text.delta" {
+			sb.WriteString(event.Get("delta").String())
+		}
+		return
+	}
+	for _, path := range shadowTextPaths {
+		appendShadowResult(sb, event.Get(path))
+	}
+}
+
+func appendShadowResult(sb *strings.Builder, result gjson.Result) {
+	if result.IsArray() {
+		for _, item := range result.Array() {
+			appendShadowResult(sb, item)
+		}
+		return
+	}
+	if result.Type == gjson.String {
+		sb.WriteString(result.Str)
+	}
+}
+
+// ShadowResponseSimilarity 计算两段文本词频向量的余弦相似度（0-1），
+// 中日韩文字逐字计词，其余按字母数字连续片段计词；任一方为空时返回 false。
+func ShadowResponseSimilarity(a string, b string) (float64, bool) {
+	termsA := shadowTermFrequency(a)
+	termsB := shadowTermFrequency(b)
+	if len(termsA) == 0 || len(termsB) == 0 {
+		return 0, false
+	}
+	var dot, normA, normB float64
+	for term, countA := range termsA {
+		normA += countA * countA
+		if countB, ok := termsB[term]; ok {
+			dot += countA * countB
+		}
+	}
+	for _, countB := range termsB {
+		normB += countB * countB
+	}
+	similarity := dot / (math.Sqrt(normA) * math.Sqrt(normB))
+	return math.Min(similarity, 1), true
+}
+
+func shadowTermFrequency(text string) map[string]float64 {
+	terms := make(map[string]float64)
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			terms[word.String()]++
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			terms[string(r)]++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowResponseText(t *testing.T) {
+	assert.Equal(t, "hello world", ShadowResponseText([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello world"}}]}`)))
+	assert.Equal(t, "hi there", ShadowResponseText([]byte(`{"content":[{"type":"text","text":"hi "},{"type":"text","text":"there"}]}`)))
+	assert.Equal(t, "gemini", ShadowResponseText([]byte(`{"candidates":[{"content":{"parts":[{"text":"gemini"}]}}]}`)))
+	assert.Equal(t, "responses", ShadowResponseText([]byte(`{"output":[{"type":"message","content":[{"type":"output_text","text":"responses"}]}]}`)))
+	assert.Empty(t, ShadowResponseText([]byte(`{"data":[{"embedding":[0.1,0.2]}]}`)))
+
+	chatStream := "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\ndata: [DONE]\n\n"
+	assert.Equal(t, "Hello", ShadowResponseText([]byte(chatStream)))
+
+	claudeStream := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n"
+	assert.Equal(t, "Hi", ShadowResponseText([]byte(claudeStream)))
+
+	responsesStream := "data: {\"type\":\"response.output_text.delta\",\"delta\":\"Yes\"}\n\n" +
+		"data: {\"type\":\"response.completed\",\"response\":{\"output\":[{\"content\":[{\"text\":\"Yes\"}]}]}}\n\n"
+	assert.Equal(t, "Yes", ShadowResponseText([]byte(responsesStream)), "completed events repeat the output and are skipped")
+}
+
+func TestShadowResponseSimilarity(t *testing.T) {
+	similarity, ok := ShadowResponseSimilarity("The quick brown fox", "the quick brown fox!")
+	assert.True(t, ok)
+	assert.InDelta(t, 1, similarity, 1e-9)
+
+	similarity, ok = ShadowResponseSimilarity("red apple", "green pear")
+	assert.True(t, ok)
+	assert.Zero(t, similarity)
+
+	similarity, ok = ShadowResponseSimilarity("今天天气很好", "今天天气不错")
+	assert.True(t, ok)
+	assert.Greater(t, similarity, 0.5)
+	assert.Less(t, similarity, 1.0)
+
+	_, ok = ShadowResponseSimilarity("", "anything")
+	assert.False(t, ok)
+}
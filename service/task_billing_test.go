@@ -50,6 +50,12 @@ func TestMain(m *testing.M) {
 		&model.UserSubscription{},
 		&model.SystemTask{},
 		&model.SystemTaskLock{},
+		&model.AlertRule{},
+		&model.QuotaData{},
+		&model.UsageAnomaly{},
+		&model.UsageForecast{},
+		&model.TrafficSplitRule{},
+		&model.HedgePolicy{},
 	); err != nil {
 		panic("failed to migrate: " + err.Error())
 	}
@@ -74,6 +80,12 @@ func truncate(t *testing.T) {
 		model.DB.Exec("DELETE FROM user_subscriptions")
 		model.DB.Exec("DELETE FROM system_task_locks")
 		model.DB.Exec("DELETE FROM system_tasks")
+		model.DB.Exec("DELETE FROM alert_rules")
+		model.DB.Exec("DELETE FROM quota_data")
+		model.DB.Exec("DELETE FROM usage_anomalies")
+		model.DB.Exec("DELETE FROM usage_forecasts")
+		model.DB.Exec("DELETE FROM traffic_split_rules")
+		model.DB.Exec("DELETE FROM hedge_policies")
 	})
 }
 
@@ -1226,3 +1238,36 @@ func TestSettle_NonPerCallBilling_AppliesAdaptorAdjustment(t *testing.T) {
 	require.NotNil(t, log)
 	assert.Equal(t, model.LogTypeRefund, log.Type)
 }
+
+type perCallSettlingAdaptor struct {
+	mockAdaptor
+}
+
+func (m *perCallSettlingAdaptor) SettlesPerCallBilling() bool { return true }
+
+func TestSettle_PerCallBilling_SettlingAdaptorAppliesAdjustment(t *testing.T) {
+	truncate(t)
+	ctx := context.Background()
+
+	const userID, tokenID, channelID = 33, 33, 33
+	const initQuota, preConsumed = 10000, 5000
+	const adaptorQuota = 1000
+	const tokenRemain = 8000
+
+	seedUser(t, userID, initQuota)
+	seedToken(t, tokenID, userID, "sk-percall-settling", tokenRemain)
+	seedChannel(t, channelID)
+
+	task := makeTask(userID, channelID, preConsumed, tokenID, BillingSourceWallet, 0)
+	task.PrivateData.BillingContext.PerCallBilling = true
+
+	adaptor := &perCallSettlingAdaptor{mockAdaptor{adjustReturn: adaptorQuota}}
+	taskResult := &relaycommon.TaskInfo{Status: model.TaskStatusSuccess}
+
+	settleTaskBillingOnComplete(ctx, adaptor, task, taskResult)
+
+	// Per-request billing (e.g. message batches) still settles to the succeeded count
+	assert.Equal(t, initQuota+(preConsumed-adaptorQuota), getUserQuota(t, userID))
+	assert.Equal(t, tokenRemain+(preConsumed-adaptorQuota), getTokenRemainQuota(t, tokenID))
+	assert.Equal(t, adaptorQuota, task.Quota)
+}
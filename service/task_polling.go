@@ -34,6 +34,12 @@ type TaskPollingAdaptor interface {
 	AdjustBillingOnComplete(task *model.Task, taskResult *relaycommon.TaskInfo) int
 }
 
+// PerCallSettlementAdaptor 由按请求条数计费的适配器实现（如消息批处理）：
+// 按次计费的任务预扣的是全部请求条数，完成后仍需按实际成功条数结算。
+type PerCallSettlementAdaptor interface {
+	SettlesPerCallBilling() bool
+}
+
 // GetTaskAdaptorFunc 由 main 包注入，用于获取指定平台的任务适配器。
 // 打破 service -> relay -> relay/channel -> service 的循环依赖。
 var GetTaskAdaptorFunc func(platform constant.TaskPlatform) TaskPollingAdaptor
@@ -46,18 +52,25 @@ func sweepTimedOutTasks(ctx context.Context) {
 		return
 	}
 	cutoff := time.Now().Unix() - int64(constant.TaskTimeoutMinutes)*60
-	tasks := model.GetTimedOutUnfinishedTasks(cutoff, 100)
+	batchTimeoutMinutes := max(constant.TaskTimeoutMinutes, constant.ClaudeBatchTimeoutMinutes)
+	batchCutoff := time.Now().Unix() - int64(batchTimeoutMinutes)*60
+	tasks := model.GetTimedOutUnfinishedTasks(cutoff, batchCutoff, 100)
 	if len(tasks) == 0 {
 		return
 	}
 
-	reason := fmt.Sprintf("任务超时（%d分钟）", constant.TaskTimeoutMinutes)
+	defaultReason := fmt.Sprintf("任务超时（%d分钟）", constant.TaskTimeoutMinutes)
+	batchReason := fmt.Sprintf("任务超时（%d分钟）", batchTimeoutMinutes)
 	legacyReason := "任务超时（旧系统遗留任务，不进行退款，请联系管理员）"
 	now := time.Now().Unix()
 	timedOutCount := 0
 
 	for _, task := range tasks {
 		isLegacy := task.SubmitTime > 0 && task.SubmitTime < model.TaskRefundLegacyCutoff
+		reason := defaultReason
+		if task.Platform == constant.TaskPlatformClaudeBatch {
+			reason = batchReason
+		}
 
 		oldStatus := task.Status
 		task.Status = model.TaskStatusFailure
@@ -460,8 +473,9 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 		key = privateData.Key
 	}
 	resp, err := adaptor.FetchTask(baseURL, key, map[string]any{
-		"task_id": task.GetUpstreamTaskID(),
-		"action":  task.Action,
+		"task_id":      task.GetUpstreamTaskID(),
+		"action":       task.Action,
+		"channel_type": ch.Type,
 	}, proxy)
 	if err != nil {
 		return fmt.Errorf("fetchTask failed for task %s: %w", taskId, err)
@@ -641,8 +655,8 @@ func truncateBase64(s string) string {
 //  2. taskResult.TotalTokens > 0 → 按 token 重算
 //  3. 都不满足 → 保持预扣额度不变
 func settleTaskBillingOnComplete(ctx context.Context, adaptor TaskPollingAdaptor, task *model.Task, taskResult *relaycommon.TaskInfo) {
-	// 0. 按次计费的任务不做差额结算
-	if bc := task.PrivateData.BillingContext; bc != nil && bc.PerCallBilling {
+	// 0. 按次计费的任务不做差额结算，按请求条数计费的适配器除外
+	if bc := task.PrivateData.BillingContext; bc != nil && bc.PerCallBilling && !settlesPerCallBilling(adaptor) {
 		logger.LogInfo(ctx, fmt.Sprintf("任务 %s 按次计费，跳过差额结算", task.TaskID))
 		return
 	}
@@ -658,3 +672,8 @@ func settleTaskBillingOnComplete(ctx context.Context, adaptor TaskPollingAdaptor
 	}
 	// 3. 无调整，保持预扣额度
 }
+
+func settlesPerCallBilling(adaptor TaskPollingAdaptor) bool {
+	s, ok := adaptor.(PerCallSettlementAdaptor)
+	return ok && s.SettlesPerCallBilling()
+}
@@ -496,3 +496,42 @@ func TestSweepTimedOutTasksHonorsRefundRolloutBoundary(t *testing.T) {
 	assert.Equal(t, initialQuota+modernTaskQuota, getUserQuota(t, userID))
 	assert.Equal(t, int64(1), countLogs(t))
 }
+
+func TestSweepTimedOutTasksKeepsClaudeBatchesWithinBatchWindow(t *testing.T) {
+	truncate(t)
+
+	const userID = 404
+	seedUser(t, userID, 10_000)
+
+	now := time.Now().Unix()
+	videoTask := makeTask(userID, 0, 0, 0, BillingSourceWallet, 0)
+	videoTask.TaskID = "video_timed_out"
+	videoTask.Progress = "50%"
+	videoTask.SubmitTime = now - 25*3600
+	require.NoError(t, model.DB.Create(videoTask).Error)
+
+	batchTask := makeTask(userID, 0, 0, 0, BillingSourceWallet, 0)
+	batchTask.TaskID = "batch_still_running"
+	batchTask.Platform = constant.TaskPlatformClaudeBatch
+	batchTask.Progress = "50%"
+	batchTask.SubmitTime = now - 25*3600
+	require.NoError(t, model.DB.Create(batchTask).Error)
+
+	previousTimeout := constant.TaskTimeoutMinutes
+	previousBatchTimeout := constant.ClaudeBatchTimeoutMinutes
+	constant.TaskTimeoutMinutes = 1440
+	constant.ClaudeBatchTimeoutMinutes = 1800
+	t.Cleanup(func() {
+		constant.TaskTimeoutMinutes = previousTimeout
+		constant.ClaudeBatchTimeoutMinutes = previousBatchTimeout
+	})
+
+	sweepTimedOutTasks(context.Background())
+
+	var reloadedVideo model.Task
+	var reloadedBatch model.Task
+	require.NoError(t, model.DB.First(&reloadedVideo, videoTask.ID).Error)
+	require.NoError(t, model.DB.First(&reloadedBatch, batchTask.ID).Error)
+	assert.EqualValues(t, model.TaskStatusFailure, reloadedVideo.Status)
+	assert.EqualValues(t, model.TaskStatusInProgress, reloadedBatch.Status)
+}
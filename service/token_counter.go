@@ -19,76 +19,102 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, stream bool) (int, error) {
-	if fileMeta == nil || fileMeta.Source == nil {
-		return 0, fmt.Errorf("image_url_is_nil")
-	}
+// imageTokenPricing describes how a model bills image prompt tokens.
+type imageTokenPricing struct {
+	// fixed is a flat per-image price; zero means the size-based rules apply
+	fixed      int
+	patchBased bool
+	multiplier float64
+	baseTokens int
+	tileTokens int
+}
 
+func getImageTokenPricing(model string) imageTokenPricing {
 	// Defaults for 4o/4.1/4.5 family unless overridden below
-	baseTokens := 85
-	tileTokens := 170
+	pricing := imageTokenPricing{multiplier: 1.0, baseTokens: 85, tileTokens: 170}
 
 	// Model classification
 	lowerModel := strings.ToLower(model)
 
 	// Special cases from existing behavior
 	if strings.HasPrefix(lowerModel, "glm-4") {
-		return 1047, nil
+		pricing.fixed = 1047
+		return pricing
 	}
 
 	// Patch-based models (32x32 patches, capped at 1536, with multiplier)
-	isPatchBased := false
-	multiplier := 1.0
 	switch {
 	case strings.Contains(lowerModel, "gpt-4.1-mini"):
-		isPatchBased = true
-		multiplier = 1.62
+		pricing.patchBased = true
+		pricing.multiplier = 1.62
 	case strings.Contains(lowerModel, "gpt-4.1-nano"):
-		isPatchBased = true
-		multiplier = 2.46
+		pricing.patchBased = true
+		pricing.multiplier = 2.46
 	case strings.HasPrefix(lowerModel, "o4-mini"):
-		isPatchBased = true
-		multiplier = 1.72
+		pricing.patchBased = true
+		pricing.multiplier = 1.72
 	case strings.HasPrefix(lowerModel, "gpt-5-mini"):
-		isPatchBased = true
-		multiplier = 1.62
+		pricing.patchBased = true
+		pricing.multiplier = 1.62
 	case strings.HasPrefix(lowerModel, "gpt-5-nano"):
-		isPatchBased = true
-		multiplier = 2.46
+		pricing.patchBased = true
+		pricing.multiplier = 2.46
 	}
 
 	// Tile-based model tokens and bases per doc
-	if !isPatchBased {
+	if !pricing.patchBased {
 		if strings.HasPrefix(lowerModel, "gpt-4o-mini") {
-			baseTokens = 2833
-			tileTokens = 5667
+			pricing.baseTokens = 2833
+			pricing.tileTokens = 5667
 		} else if strings.HasPrefix(lowerModel, "gpt-5-chat-latest") || (strings.HasPrefix(lowerModel, "gpt-5") && !strings.Contains(lowerModel, "mini") && !strings.Contains(lowerModel, "nano")) {
-			baseTokens = 70
-			tileTokens = 140
+			pricing.baseTokens = 70
+			pricing.tileTokens = 140
 		} else if strings.HasPrefix(lowerModel, "o1") || strings.HasPrefix(lowerModel, "o3") || strings.HasPrefix(lowerModel, "o1-pro") {
-			baseTokens = 75
-			tileTokens = 150
+			pricing.baseTokens = 75
+			pricing.tileTokens = 150
 		} else if strings.Contains(lowerModel, "computer-use-preview") {
-			baseTokens = 65
-			tileTokens = 129
+			pricing.baseTokens = 65
+			pricing.tileTokens = 129
 		} else if strings.Contains(lowerModel, "4.1") || strings.Contains(lowerModel, "4o") || strings.Contains(lowerModel, "4.5") {
-			baseTokens = 85
-			tileTokens = 170
+			pricing.baseTokens = 85
+			pricing.tileTokens = 170
 		}
 	}
+	return pricing
+}
 
+// sizeBased reports whether getImageToken bills the image by its dimensions
+// rather than a flat amount.
+func (p imageTokenPricing) sizeBased(detail string, stream bool) bool {
+	if p.fixed > 0 {
+		return false
+	}
 	// Respect existing feature flags/short-circuits
-	if fileMeta.Detail == "low" && !isPatchBased {
-		return baseTokens, nil
+	if detail == "low" && !p.patchBased {
+		return false
 	}
-
 	// Whether to count image tokens at all
 	if !constant.GetMediaToken {
-		return 3 * baseTokens, nil
+		return false
+	}
+	return constant.GetMediaTokenNotStream || stream
+}
+
+func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, stream bool) (int, error) {
+	if fileMeta == nil || fileMeta.Source == nil {
+		return 0, fmt.Errorf("image_url_is_nil")
 	}
 
-	if !constant.GetMediaTokenNotStream && !stream {
-		return 3 * baseTokens, nil
+	pricing := getImageTokenPricing(model)
+	if !pricing.sizeBased(fileMeta.Detail, stream) {
+		switch {
+		case pricing.fixed > 0:
+			return pricing.fixed, nil
+		case fileMeta.Detail == "low" && !pricing.patchBased:
+			return pricing.baseTokens, nil
+		default:
+			return 3 * pricing.baseTokens, nil
+		}
 	}
 	// Normalize detail
 	if fileMeta.Detail == "auto" || fileMeta.Detail == "" {
@@ -104,16 +130,21 @@ func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, strea
 		// not an image, but might be a valid file
 		if format != "" {
 			// file type
-			return 3 * baseTokens, nil
+			return 3 * pricing.baseTokens, nil
 		}
 		return 0, errors.New(fmt.Sprintf("fail to decode image config: %s", fileMeta.GetIdentifier()))
 	}
 
-	width := config.Width
-	height := config.Height
-	logger.LogDebug(c, "image token input: format=%s, width=%d, height=%d", format, width, height)
+	logger.LogDebug(c, "image token input: format=%s, width=%d, height=%d", format, config.Width, config.Height)
+
+	// 图片预处理在转发时才知道是否真正缩放了图片，这里按原图计费，实际缩放后再由预处理扣减
+	return calcImageTokensBySize(c, config.Width, config.Height, pricing), nil
+}
 
-	if isPatchBased {
+// calcImageTokensBySize computes image prompt tokens from pixel dimensions
+// using either the 32px patch model or the 512px tile model.
+func calcImageTokensBySize(c *gin.Context, width, height int, pricing imageTokenPricing) int {
+	if pricing.patchBased {
 		// 32x32 patch-based calculation with 1536 cap and model multiplier
 		ceilDiv := func(a, b int) int { return (a + b - 1) / b }
 		rawPatchesW := ceilDiv(width, 32)
@@ -140,11 +171,11 @@ func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, strea
 			if imageTokens > 1536 {
 				imageTokens = 1536
 			}
-			return int(math.Round(float64(imageTokens) * multiplier)), nil
+			return int(math.Round(float64(imageTokens) * pricing.multiplier))
 		}
 		// below cap
 		imageTokens := rawPatches
-		return int(math.Round(float64(imageTokens) * multiplier)), nil
+		return int(math.Round(float64(imageTokens) * pricing.multiplier))
 	}
 
 	// Tile-based calculation for 4o/4.1/4.5/o1/o3/etc.
@@ -160,7 +191,7 @@ func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, strea
 	// Step 2: scale so that shortest side is exactly 768
 	minSide := math.Min(float64(fitW), float64(fitH))
 	if minSide == 0 {
-		return baseTokens, nil
+		return pricing.baseTokens
 	}
 	shortScale := 768.0 / minSide
 	finalW := int(math.Round(float64(fitW) * shortScale))
@@ -173,7 +204,7 @@ func getImageToken(c *gin.Context, fileMeta *types.FileMeta, model string, strea
 
 	logger.LogDebug(c, "image token scaled size: width=%d, height=%d, tiles=%d", finalW, finalH, tiles)
 
-	return tiles*tileTokens + baseTokens, nil
+	return tiles*pricing.tileTokens + pricing.baseTokens
 }
 
 func EstimateRequestToken(c *gin.Context, meta *types.TokenCountMeta, info *relaycommon.RelayInfo) (int, error) {
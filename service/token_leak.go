@@ -0,0 +1,330 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 令牌泄露检测：令牌鉴权通过后记录其访问特征（来源 IP、网段、国家、User-Agent 客户端、Origin），
+// 检测窗口内不同 IP 数或新出现的特征数达到阈值时标记令牌、通知所有者，并可自动隔离。
+// 特征画像保存在各节点内存中，多节点部署时每个节点独立判断，标记本身通过数据库条件更新去重。
+// 仓库不附带 GeoIP/ASN 数据库：国家取自可信反向代理写入的请求头，网段（IPv4 /24、IPv6 /48）近似代替 ASN。
+
+type tokenLeakDimension int
+
+const (
+	tokenLeakDimensionNetwork tokenLeakDimension = iota
+	tokenLeakDimensionCountry
+	tokenLeakDimensionUserAgent
+	tokenLeakDimensionOrigin
+	tokenLeakDimensionCount
+)
+
+var tokenLeakDimensionSignals = [tokenLeakDimensionCount]string{"new_network", "new_country", "new_user_agent", "new_origin"}
+
+const (
+	tokenLeakShardCount       = 64
+	tokenLeakMaxKnownValues   = 256
+	tokenLeakMaxRecentIps     = 1024
+	tokenLeakMaxValueLength   = 128
+	tokenLeakSweepInterval    = 10 * 60
+	tokenLeakReasonSamples    = 5
+	tokenLeakUnknownCountry   = "XX"
+	tokenLeakSignalDistinctIp = "distinct_ip"
+)
+
+// TokenLeakRequest 单次请求中参与检测的特征
+type TokenLeakRequest struct {
+	ClientIP  string
+	Country   string
+	UserAgent string
+	Origin    string
+}
+
+type tokenLeakProfile struct {
+	firstSeen int64
+	lastSeen  int64
+	known     [tokenLeakDimensionCount]map[string]int64 // 已知特征 -> 最近出现时间
+	fresh     [tokenLeakDimensionCount]map[string]int64 // 学习期后首次出现的特征 -> 首次出现时间
+	recentIps map[string]int64                          // 窗口内的来源 IP -> 最近出现时间
+}
+
+func newTokenLeakProfile(now int64) *tokenLeakProfile {
+	profile := &tokenLeakProfile{firstSeen: now, lastSeen: now, recentIps: make(map[string]int64)}
+	for i := range profile.known {
+		profile.known[i] = make(map[string]int64)
+		profile.fresh[i] = make(map[string]int64)
+	}
+	return profile
+}
+
+type tokenLeakShard struct {
+	mu       sync.Mutex
+	profiles map[int]*tokenLeakProfile
+}
+
+type tokenLeakDetector struct {
+	shards    [tokenLeakShardCount]tokenLeakShard
+	lastSweep atomic.Int64
+}
+
+func newTokenLeakDetector() *tokenLeakDetector {
+	detector := &tokenLeakDetector{}
+	for i := range detector.shards {
+		detector.shards[i].profiles = make(map[int]*tokenLeakProfile)
+	}
+	return detector
+}
+
+var tokenLeakDetectorInstance = newTokenLeakDetector()
+
+func (d *tokenLeakDetector) shard(tokenId int) *tokenLeakShard {
+	return &d.shards[uint(tokenId)%tokenLeakShardCount]
+}
+
+// observe 记录一次请求并返回触发的检测信号，未触发时返回空字符串。
+// confirmedAt 为所有者最近一次确认安全的时间，此前的窗口数据不再计入。
+func (d *tokenLeakDetector) observe(setting *operation_setting.TokenLeakSetting, tokenId int, confirmedAt int64, request TokenLeakRequest, now int64) string {
+	d.maybeSweep(setting, now)
+
+	shard := d.shard(tokenId)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	profile, ok := shard.profiles[tokenId]
+	if !ok {
+		profile = newTokenLeakProfile(now)
+		shard.profiles[tokenId] = profile
+	}
+	profile.lastSeen = now
+
+	learningSince := max(profile.firstSeen, confirmedAt)
+	learning := now < learningSince+int64(setting.LearningHours)*3600
+	since := max(now-int64(setting.WindowMinutes)*60, confirmedAt)
+
+	pruneTokenLeakValues(profile.recentIps, since)
+	if request.ClientIP != "" && (len(profile.recentIps) < tokenLeakMaxRecentIps || profile.recentIps[request.ClientIP] > 0) {
+		profile.recentIps[request.ClientIP] = now
+	}
+	values := tokenLeakRequestValues(request)
+	for dim := range values {
+		fresh := profile.fresh[dim]
+		pruneTokenLeakValues(fresh, since)
+		value := values[dim]
+		if value == "" {
+			continue
+		}
+		known := profile.known[dim]
+		if _, ok := known[value]; !ok {
+			if len(known) >= tokenLeakMaxKnownValues {
+				evictOldestTokenLeakValue(known)
+			}
+			if !learning {
+				fresh[value] = now
+			}
+		}
+		known[value] = now
+	}
+	if learning {
+		return ""
+	}
+
+	reasons := make([]string, 0)
+	if setting.DistinctIpThreshold > 0 && len(profile.recentIps) >= setting.DistinctIpThreshold {
+		reasons = append(reasons, fmt.Sprintf("%s: %d IPs in %d minutes", tokenLeakSignalDistinctIp, len(profile.recentIps), setting.WindowMinutes))
+	}
+	thresholds := [tokenLeakDimensionCount]int{
+		setting.NewNetworkThreshold, setting.NewCountryThreshold, setting.NewUserAgentThreshold, setting.NewOriginThreshold,
+	}
+	for dim, threshold := range thresholds {
+		if threshold > 0 && len(profile.fresh[dim]) >= threshold {
+			reasons = append(reasons, tokenLeakDimensionSignals[dim]+": "+sampleTokenLeakValues(profile.fresh[dim]))
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// reset 清除令牌的特征画像，所有者确认或更换密钥后重新学习
+func (d *tokenLeakDetector) reset(tokenId int) {
+	shard := d.shard(tokenId)
+	shard.mu.Lock()
+	delete(shard.profiles, tokenId)
+	shard.mu.Unlock()
+}
+
+// maybeSweep 定期清理长期未使用的画像与过期的已知特征，避免内存随令牌数量无限增长
+func (d *tokenLeakDetector) maybeSweep(setting *operation_setting.TokenLeakSetting, now int64) {
+	last := d.lastSweep.Load()
+	if now-last < tokenLeakSweepInterval || !d.lastSweep.CompareAndSwap(last, now) {
+		return
+	}
+	expireBefore := now - int64(setting.ProfileDays)*86400
+	gopool.Go(func() {
+		d.sweep(expireBefore)
+	})
+}
+
+func (d *tokenLeakDetector) sweep(expireBefore int64) {
+	for i := range d.shards {
+		shard := &d.shards[i]
+		shard.mu.Lock()
+		for tokenId, profile := range shard.profiles {
+			if profile.lastSeen < expireBefore {
+				delete(shard.profiles, tokenId)
+				continue
+			}
+			for _, known := range profile.known {
+				pruneTokenLeakValues(known, expireBefore)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+func pruneTokenLeakValues(values map[string]int64, before int64) {
+	for value, seen := range values {
+		if seen < before {
+			delete(values, value)
+		}
+	}
+}
+
+func evictOldestTokenLeakValue(values map[string]int64) {
+	oldest := ""
+	oldestSeen := int64(-1)
+	for value, seen := range values {
+		if oldestSeen < 0 || seen < oldestSeen {
+			oldest, oldestSeen = value, seen
+		}
+	}
+	delete(values, oldest)
+}
+
+func sampleTokenLeakValues(values map[string]int64) string {
+	samples := make([]string, 0, len(values))
+	for value := range values {
+		samples = append(samples, value)
+	}
+	sort.Strings(samples)
+	if len(samples) > tokenLeakReasonSamples {
+		samples = append(samples[:tokenLeakReasonSamples], "...")
+	}
+	return strings.Join(samples, ", ")
+}
+
+func tokenLeakRequestValues(request TokenLeakRequest) [tokenLeakDimensionCount]string {
+	var values [tokenLeakDimensionCount]string
+	values[tokenLeakDimensionNetwork] = tokenLeakNetwork(request.ClientIP)
+	country := strings.ToUpper(strings.TrimSpace(request.Country))
+	if country != tokenLeakUnknownCountry {
+		values[tokenLeakDimensionCountry] = truncateTokenLeakValue(country)
+	}
+	values[tokenLeakDimensionUserAgent] = tokenLeakUserAgentFamily(request.UserAgent)
+	values[tokenLeakDimensionOrigin] = truncateTokenLeakValue(strings.ToLower(strings.TrimSpace(request.Origin)))
+	return values
+}
+
+// tokenLeakNetwork 返回 IP 所在网段：IPv4 取 /24，IPv6 取 /48
+func tokenLeakNetwork(clientIp string) string {
+	ip := net.ParseIP(clientIp)
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// tokenLeakUserAgentFamily 只保留 User-Agent 的首个产品名（如 openai-python/1.2 -> openai-python），
+// 客户端升级版本不会被视为新特征
+func tokenLeakUserAgentFamily(userAgent string) string {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if index := strings.IndexAny(userAgent, "/ "); index >= 0 {
+		userAgent = userAgent[:index]
+	}
+	return truncateTokenLeakValue(userAgent)
+}
+
+func truncateTokenLeakValue(value string) string {
+	if len(value) > tokenLeakMaxValueLength {
+		return value[:tokenLeakMaxValueLength]
+	}
+	return value
+}
+
+// ObserveTokenRequest 在令牌鉴权通过后记录请求特征；检测到泄露迹象且令牌尚未被标记时，
+// 标记令牌（按配置同时隔离）并异步通知所有者。标记结果直接写回 token，本次请求即按隔离处理。
+func ObserveTokenRequest(c *gin.Context, token *model.Token) {
+	setting := operation_setting.GetTokenLeakSetting()
+	if !setting.Enabled {
+		return
+	}
+	request := TokenLeakRequest{
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Origin:    c.GetHeader("Origin"),
+	}
+	if setting.CountryHeader != "" {
+		request.Country = c.GetHeader(setting.CountryHeader)
+	}
+	now := time.Now().Unix()
+	reason := tokenLeakDetectorInstance.observe(setting, token.Id, token.LeakConfirmedAt, request, now)
+	if reason == "" || token.LeakFlaggedAt != 0 {
+		return
+	}
+	flagged, err := model.FlagTokenLeak(token, reason, setting.AutoQuarantine, now)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to flag leaked token %d: %v", token.Id, err))
+		return
+	}
+	if !flagged {
+		return
+	}
+	common.SysLog(fmt.Sprintf("token %d of user %d flagged as possibly leaked (quarantined=%t): %s", token.Id, token.UserId, token.Quarantined, reason))
+	flaggedToken := *token
+	gopool.Go(func() {
+		if err := notifyTokenLeak(&flaggedToken); err != nil {
+			common.SysError(fmt.Sprintf("failed to send token leak notify to user %d: %v", flaggedToken.UserId, err))
+		}
+	})
+}
+
+// ResetTokenLeakProfile 清除本节点上令牌的特征画像，所有者确认或更换密钥后调用
+func ResetTokenLeakProfile(tokenId int) {
+	tokenLeakDetectorInstance.reset(tokenId)
+}
+
+func notifyTokenLeak(token *model.Token) error {
+	user, err := model.GetUserById(token.UserId, false)
+	if err != nil {
+		return err
+	}
+	userSetting := user.GetSetting()
+	return NotifyUser(user.Id, user.Email, userSetting, buildTokenLeakNotify(token, userSetting.NotifyType))
+}
+
+func buildTokenLeakNotify(token *model.Token, notifyType string) dto.Notify {
+	separator := "<br/>"
+	if isPlainTextNotifyType(notifyType) {
+		separator = "\n"
+	}
+	content := "令牌 {{value}} (#{{value}}) 的访问特征出现异常，可能已经泄露。" + separator + "检测信号：{{value}}"
+	values := []interface{}{token.Name, token.Id, token.LeakReason}
+	if token.Quarantined {
+		content += separator + "该令牌已被隔离，仅能以较低频率调用少数模型。"
+	}
+	content += separator + "如确认为本人使用，请在令牌管理中确认安全；否则请立即更换密钥。"
+	return dto.NewNotify(dto.NotifyTypeTokenLeak, "令牌疑似泄露", content, values)
+}
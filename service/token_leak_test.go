@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func testTokenLeakSetting() *operation_setting.TokenLeakSetting {
+	return &operation_setting.TokenLeakSetting{
+		Enabled:               true,
+		WindowMinutes:         10,
+		DistinctIpThreshold:   5,
+		NewNetworkThreshold:   3,
+		NewCountryThreshold:   2,
+		NewUserAgentThreshold: 0,
+		NewOriginThreshold:    1,
+		LearningHours:         1,
+		ProfileDays:           7,
+	}
+}
+
+func TestTokenLeakDetectorLearnsBeforeFlagging(t *testing.T) {
+	detector := newTokenLeakDetector()
+	setting := testTokenLeakSetting()
+	now := int64(1_700_000_000)
+
+	// Everything seen during the learning period becomes part of the profile.
+	for i := 0; i < 10; i++ {
+		request := TokenLeakRequest{ClientIP: fmt.Sprintf("10.0.%d.1", i), Country: "US", UserAgent: "openai-python/1.0"}
+		assert.Empty(t, detector.observe(setting, 1, 0, request, now+int64(i)))
+	}
+
+	now += 3600
+	assert.Empty(t, detector.observe(setting, 1, 0, TokenLeakRequest{ClientIP: "10.0.1.9", Country: "us", UserAgent: "OpenAI-Python/2.0"}, now),
+		"known network, country and client family")
+	assert.Empty(t, detector.observe(setting, 1, 0, TokenLeakRequest{ClientIP: "203.0.113.5", Country: "DE"}, now+1))
+	reason := detector.observe(setting, 1, 0, TokenLeakRequest{ClientIP: "198.51.100.7", Country: "BR", UserAgent: "curl/8.0"}, now+2)
+	assert.Equal(t, "new_country: BR, DE", reason)
+
+	// Signals leave the window and the new values are known from now on.
+	now += 11 * 60
+	assert.Empty(t, detector.observe(setting, 1, 0, TokenLeakRequest{ClientIP: "198.51.100.8", Country: "BR"}, now))
+
+	reason = detector.observe(setting, 1, 0, TokenLeakRequest{ClientIP: "10.0.1.9", Origin: "https://Evil.example"}, now+1)
+	assert.Equal(t, "new_origin: https://evil.example", reason)
+}
+
+func TestTokenLeakDetectorCountsDistinctIps(t *testing.T) {
+	detector := newTokenLeakDetector()
+	setting := testTokenLeakSetting()
+	setting.NewNetworkThreshold = 0
+	now := int64(1_700_000_000)
+	detector.observe(setting, 2, 0, TokenLeakRequest{ClientIP: "10.0.0.1"}, now)
+
+	now += 7200
+	for i := 1; i <= 4; i++ {
+		assert.Empty(t, detector.observe(setting, 2, 0, TokenLeakRequest{ClientIP: fmt.Sprintf("10.0.0.%d", i)}, now+int64(i)))
+	}
+	assert.Equal(t, "distinct_ip: 5 IPs in 10 minutes", detector.observe(setting, 2, 0, TokenLeakRequest{ClientIP: "10.0.0.5"}, now+5))
+
+	// After the owner confirms, earlier requests no longer count and the
+	// profile learns again.
+	confirmedAt := now + 6
+	assert.Empty(t, detector.observe(setting, 2, confirmedAt, TokenLeakRequest{ClientIP: "10.0.0.6"}, now+7))
+	for i := 7; i <= 12; i++ {
+		assert.Empty(t, detector.observe(setting, 2, confirmedAt, TokenLeakRequest{ClientIP: fmt.Sprintf("10.0.0.%d", i)}, now+int64(i)))
+	}
+}
+
+func TestTokenLeakNetwork(t *testing.T) {
+	assert.Equal(t, "192.0.2.0/24", tokenLeakNetwork("192.0.2.77"))
+	assert.Equal(t, "2001:db8:1::/48", tokenLeakNetwork("2001:db8:1:2::1"))
+	assert.Empty(t, tokenLeakNetwork("not-an-ip"))
+	assert.Equal(t, "anthropic-typescript", tokenLeakUserAgentFamily("Anthropic-TypeScript/0.30 (node)"))
+}
+
+func TestObserveTokenRequestFlagsQuarantinesAndNotifies(t *testing.T) {
+	truncate(t)
+	gin.SetMode(gin.TestMode)
+	server, captured := newNotifyTestServer(t, "ok")
+	originalLimit := constant.NotifyLimitCount
+	constant.NotifyLimitCount = 10
+	t.Cleanup(func() { constant.NotifyLimitCount = originalLimit })
+	current := operation_setting.GetTokenLeakSetting()
+	original := *current
+	t.Cleanup(func() { *current = original })
+	*current = *testTokenLeakSetting()
+	current.LearningHours = 0
+	current.CountryHeader = "CF-IPCountry"
+	current.AutoQuarantine = true
+
+	user := &model.User{Id: 9401, Username: "leak_user", Status: common.UserStatusEnabled}
+	user.SetSetting(dto.UserSetting{NotifyType: dto.NotifyTypeWebhook, WebhookUrl: server.URL})
+	require.NoError(t, model.DB.Create(user).Error)
+	seedToken(t, 9402, user.Id, "leak-token-key", 0)
+	t.Cleanup(func() { ResetTokenLeakProfile(9402) })
+
+	observe := func(country string) *model.Token {
+		token, err := model.GetTokenById(9402)
+		require.NoError(t, err)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		c.Request.RemoteAddr = "192.0.2.10:4000"
+		c.Request.Header.Set("CF-IPCountry", country)
+		ObserveTokenRequest(c, token)
+		return token
+	}
+
+	assert.False(t, observe("US").Quarantined)
+	token := observe("FR")
+	assert.True(t, token.Quarantined)
+	assert.Equal(t, "new_country: FR, US", token.LeakReason)
+	stored, err := model.GetTokenById(9402)
+	require.NoError(t, err)
+	assert.True(t, stored.Quarantined)
+	assert.NotZero(t, stored.LeakFlaggedAt)
+
+	require.Eventually(t, func() bool { return len(captured()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, dto.NotifyTypeTokenLeak, gjson.Get(captured()[0].Body, "type").String())
+
+	// A flagged token is not flagged or notified again.
+	observe("JP")
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, captured(), 1)
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 灰度分流：按用户或令牌哈希把命中规则的请求稳定地分到 canary 或 control 分支，
+// canary 分支在首次尝试时使用规则指定的渠道或上游模型名，失败重试回到正常选路。
+// 放量比例由规则的开始时间推算；canary 首次尝试的结果记录在各节点内存中，
+// 与 perf_metrics 中同模型非 canary 请求的基线比较，超出阈值时由任一节点把规则标记为已回滚。
+
+const (
+	trafficSplitCacheTTL = 30 * time.Second
+	// trafficSplitBuckets 分桶精度，放量比例可以精确到 0.01%
+	trafficSplitBuckets = 10000
+	// trafficSplitEvalWindow canary 与基线只比较这段时间内的样本
+	trafficSplitEvalWindow = 30 * time.Minute
+	// trafficSplitEvalInterval 同一规则在单节点上的评估间隔
+	trafficSplitEvalInterval = 10 * time.Second
+	trafficSplitWindowSize   = 500
+
+	TrafficSplitArmCanary  = "canary"
+	TrafficSplitArmControl = "control"
+)
+
+var (
+	trafficSplitMu        sync.Mutex
+	trafficSplitRules     []*model.TrafficSplitRule
+	trafficSplitExpiresAt time.Time
+
+	trafficSplitStatsMu sync.Mutex
+	trafficSplitStats   = map[int]*trafficSplitCanaryStats{}
+)
+
+// trafficSplitCanaryStats 单节点上某条规则 canary 分支的近期结果，规则重新放量后清空
+type trafficSplitCanaryStats struct {
+	rampStartedAt int64
+	window        *perfmetrics.Window
+	lastEvalAt    time.Time
+}
+
+// TrafficSplitAssignment 一次请求的分流结果，写入日志 admin_info.traffic_split
+type TrafficSplitAssignment struct {
+	RuleId          int     `json:"rule_id"`
+	RuleName        string  `json:"rule_name"`
+	Arm             string  `json:"arm"`
+	Percent         float64 `json:"percent"`
+	CanaryChannelId int     `json:"canary_channel_id,omitempty"`
+	CanaryModel     string  `json:"canary_model,omitempty"`
+	// FallbackReason canary 分支不可用而改走 control 的原因
+	FallbackReason string `json:"fallback_reason,omitempty"`
+	// CanaryFailed canary 首次尝试失败，响应由重试的渠道返回
+	CanaryFailed bool `json:"canary_failed,omitempty"`
+
+	rule *model.TrafficSplitRule
+}
+
+// IsCanary 判断请求是否走 canary 分支，nil 安全
+func (a *TrafficSplitAssignment) IsCanary() bool {
+	return a != nil && a.Arm == TrafficSplitArmCanary
+}
+
+// Fallback canary 分支无法使用时改走 control，并记录原因
+func (a *TrafficSplitAssignment) Fallback(reason string) {
+	a.Arm = TrafficSplitArmControl
+	a.FallbackReason = reason
+}
+
+// InvalidateTrafficSplitCache 规则增删改后调用，下次请求时重新加载
+func InvalidateTrafficSplitCache() {
+	trafficSplitMu.Lock()
+	trafficSplitExpiresAt = time.Time{}
+	trafficSplitMu.Unlock()
+}
+
+func loadTrafficSplitRulesLocked(now time.Time) []*model.TrafficSplitRule {
+	if now.Before(trafficSplitExpiresAt) {
+		return trafficSplitRules
+	}
+	rules, err := model.GetActiveTrafficSplitRules()
+	if err != nil {
+		common.SysError("failed to load traffic split rules: " + err.Error())
+		trafficSplitExpiresAt = now.Add(trafficSplitCacheTTL)
+		return trafficSplitRules
+	}
+	trafficSplitRules = rules
+	trafficSplitExpiresAt = now.Add(trafficSplitCacheTTL)
+	return trafficSplitRules
+}
+
+// matchTrafficSplitRule 按 id 顺序取第一条命中模型与分组的规则，返回副本
+func matchTrafficSplitRule(modelName string, group string) *model.TrafficSplitRule {
+	trafficSplitMu.Lock()
+	defer trafficSplitMu.Unlock()
+	for _, rule := range loadTrafficSplitRulesLocked(time.Now()) {
+		if rule.Matches(modelName, group) {
+			matched := *rule
+			return &matched
+		}
+	}
+	return nil
+}
+
+// TrafficSplitBucket 把主体哈希到 [0, trafficSplitBuckets)。同一主体在同一规则下始终落在同一个桶，
+// 放量比例提升时已经进入 canary 的主体保持不变；不同规则使用不同的哈希，避免总是同一批用户做灰度。
+func TrafficSplitBucket(ruleId int, subject string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strconv.Itoa(ruleId) + ":" + subject))
+	return int(h.Sum32() % trafficSplitBuckets)
+}
+
+// trafficSplitSubject 分桶主体，按令牌分桶但请求没有令牌（如操练场）时退回按用户
+func trafficSplitSubject(c *gin.Context, hashKey string) string {
+	if hashKey == model.TrafficSplitHashToken {
+		if tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId); tokenId > 0 {
+			return "token:" + strconv.Itoa(tokenId)
+		}
+	}
+	return "user:" + strconv.Itoa(common.GetContextKeyInt(c, constant.ContextKeyUserId))
+}
+
+// AssignTrafficSplit 为命中规则的请求选择分支并写入上下文，未命中任何规则时返回 nil
+func AssignTrafficSplit(c *gin.Context, modelName string, group string) *TrafficSplitAssignment {
+	rule := matchTrafficSplitRule(modelName, group)
+	if rule == nil {
+		return nil
+	}
+	percent := rule.CurrentPercent(common.GetTimestamp())
+	assignment := &TrafficSplitAssignment{
+		RuleId:          rule.Id,
+		RuleName:        rule.Name,
+		Arm:             TrafficSplitArmControl,
+		Percent:         percent,
+		CanaryChannelId: rule.CanaryChannelId,
+		CanaryModel:     rule.CanaryModel,
+		rule:            rule,
+	}
+	if float64(TrafficSplitBucket(rule.Id, trafficSplitSubject(c, rule.HashKey))) < percent*trafficSplitBuckets/100 {
+		assignment.Arm = TrafficSplitArmCanary
+	}
+	common.SetContextKey(c, constant.ContextKeyTrafficSplit, assignment)
+	return assignment
+}
+
+// GetTrafficSplitAssignment 返回请求的分流结果，未命中规则时返回 nil
+func GetTrafficSplitAssignment(c *gin.Context) *TrafficSplitAssignment {
+	assignment, ok := common.GetContextKeyType[*TrafficSplitAssignment](c, constant.ContextKeyTrafficSplit)
+	if !ok {
+		return nil
+	}
+	return assignment
+}
+
+// ApplyTrafficSplitModelMapping 在已选渠道的模型映射上追加 原始模型 -> canary 模型，
+// 渠道对 canary 模型自身的映射仍按链式重定向生效
+func ApplyTrafficSplitModelMapping(c *gin.Context, assignment *TrafficSplitAssignment, originModel string) {
+	if !assignment.IsCanary() || assignment.CanaryModel == "" {
+		return
+	}
+	modelMap := make(map[string]string)
+	if mapping := common.GetContextKeyString(c, constant.ContextKeyChannelModelMapping); mapping != "" && mapping != "{}" {
+		if err := json.Unmarshal([]byte(mapping), &modelMap); err != nil {
+			// 渠道映射无法解析时保持原样，由转发时的映射处理报错
+			return
+		}
+	}
+	modelMap[originModel] = assignment.CanaryModel
+	mapping, err := json.Marshal(modelMap)
+	if err != nil {
+		return
+	}
+	common.SetContextKey(c, constant.ContextKeyChannelModelMapping, string(mapping))
+}
+
+// AppendTrafficSplitAdminInfo 把分流结果写入日志的 admin_info
+func AppendTrafficSplitAdminInfo(c *gin.Context, adminInfo map[string]interface{}) {
+	if c == nil || adminInfo == nil {
+		return
+	}
+	if assignment := GetTrafficSplitAssignment(c); assignment != nil {
+		adminInfo["traffic_split"] = assignment
+	}
+}
+
+// RecordTrafficSplitResult 记录 canary 分支首次尝试的结果，并按间隔评估是否需要回滚
+func RecordTrafficSplitResult(assignment *TrafficSplitAssignment, latencyMs int64, success bool) {
+	if !assignment.IsCanary() || assignment.rule == nil {
+		return
+	}
+	if !success {
+		assignment.CanaryFailed = true
+	}
+	rule := assignment.rule
+	now := time.Now()
+
+	trafficSplitStatsMu.Lock()
+	stats, ok := trafficSplitStats[rule.Id]
+	if !ok || stats.rampStartedAt != rule.RampStartedAt {
+		stats = &trafficSplitCanaryStats{
+			rampStartedAt: rule.RampStartedAt,
+			window:        perfmetrics.NewWindow(trafficSplitWindowSize),
+		}
+		trafficSplitStats[rule.Id] = stats
+	}
+	stats.window.Add(now, latencyMs, success)
+	evaluate := now.Sub(stats.lastEvalAt) >= trafficSplitEvalInterval
+	if evaluate {
+		stats.lastEvalAt = now
+	}
+	trafficSplitStatsMu.Unlock()
+	if !evaluate {
+		return
+	}
+
+	since := now.Add(-trafficSplitEvalWindow)
+	canarySince := since
+	if rampStart := time.Unix(rule.RampStartedAt, 0); rampStart.After(canarySince) {
+		canarySince = rampStart
+	}
+	canary := stats.window.Stats(canarySince)
+	baseline := perfmetrics.RecentBaseline(rule.ModelName, since)
+	if reason := TrafficSplitRollbackReason(rule, canary, baseline); reason != "" {
+		gopool.Go(func() {
+			rollbackTrafficSplitRule(rule, reason)
+		})
+	}
+}
+
+// TrafficSplitRollbackReason 比较 canary 与基线，超出规则阈值时返回回滚原因；
+// 任一方样本不足或未超出阈值时返回空串
+func TrafficSplitRollbackReason(rule *model.TrafficSplitRule, canary perfmetrics.WindowStats, baseline perfmetrics.WindowStats) string {
+	if canary.Samples < rule.MinSamples || baseline.Samples < rule.MinSamples {
+		return ""
+	}
+	if rule.MaxErrorRateIncrease > 0 && canary.ErrorRate-baseline.ErrorRate > rule.MaxErrorRateIncrease {
+		return fmt.Sprintf("canary error rate %.2f%% exceeds baseline %.2f%% by more than %g points (%d/%d samples)",
+			canary.ErrorRate, baseline.ErrorRate, rule.MaxErrorRateIncrease, canary.Samples, baseline.Samples)
+	}
+	if rule.MaxLatencyIncrease > 0 && canary.P95LatencyMs > 0 && baseline.P95LatencyMs > 0 &&
+		float64(canary.P95LatencyMs) > float64(baseline.P95LatencyMs)*(1+rule.MaxLatencyIncrease/100) {
+		return fmt.Sprintf("canary p95 latency %dms exceeds baseline %dms by more than %g%% (%d/%d samples)",
+			canary.P95LatencyMs, baseline.P95LatencyMs, rule.MaxLatencyIncrease, canary.Samples, baseline.Samples)
+	}
+	return ""
+}
+
+func rollbackTrafficSplitRule(rule *model.TrafficSplitRule, reason string) {
+	rolledBack, err := model.RollbackTrafficSplitRule(rule.Id, reason)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to roll back traffic split rule %d: %s", rule.Id, err.Error()))
+		return
+	}
+	InvalidateTrafficSplitCache()
+	if !rolledBack {
+		return
+	}
+	common.SysLog(fmt.Sprintf("灰度分流规则「%s」（#%d）已自动回滚：%s", rule.Name, rule.Id, reason))
+	subject := fmt.Sprintf("灰度分流规则「%s」已自动回滚", rule.Name)
+	content := fmt.Sprintf("灰度分流规则「%s」（#%d，模型 %s）已自动回滚，全部请求恢复正常选路。原因：%s",
+		rule.Name, rule.Id, rule.ModelName, reason)
+	NotifyRootUser(fmt.Sprintf("%s_%d", dto.NotifyTypeTrafficSplit, rule.Id), subject, content)
+}
+
+// GetTrafficSplitStats 返回本节点上规则 canary 分支与模型基线在评估窗口内的统计
+func GetTrafficSplitStats(rule *model.TrafficSplitRule) (canary perfmetrics.WindowStats, baseline perfmetrics.WindowStats) {
+	since := time.Now().Add(-trafficSplitEvalWindow)
+	baseline = perfmetrics.RecentBaseline(rule.ModelName, since)
+	trafficSplitStatsMu.Lock()
+	stats, ok := trafficSplitStats[rule.Id]
+	trafficSplitStatsMu.Unlock()
+	if !ok || stats.rampStartedAt != rule.RampStartedAt {
+		return canary, baseline
+	}
+	if rampStart := time.Unix(rule.RampStartedAt, 0); rampStart.After(since) {
+		since = rampStart
+	}
+	return stats.window.Stats(since), baseline
+}
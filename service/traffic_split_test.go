@@ -0,0 +1,115 @@
+package service
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	perfmetrics "github.com/QuantumNous/new-api/pkg/perf_metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficSplitBucketIsStableAcrossRamp(t *testing.T) {
+	inCanary := func(subject string, percent float64) bool {
+		return float64(TrafficSplitBucket(7, subject)) < percent*trafficSplitBuckets/100
+	}
+	const users = 4000
+	small, large := 0, 0
+	for i := 0; i < users; i++ {
+		subject := "user:" + strconv.Itoa(i)
+		assert.Equal(t, TrafficSplitBucket(7, subject), TrafficSplitBucket(7, subject))
+		if inCanary(subject, 5) {
+			small++
+			assert.True(t, inCanary(subject, 25), "users in the canary stay there when the ramp grows")
+		}
+		if inCanary(subject, 25) {
+			large++
+		}
+	}
+	assert.InDelta(t, users*0.05, small, users*0.02)
+	assert.InDelta(t, users*0.25, large, users*0.04)
+}
+
+func TestTrafficSplitRollbackReason(t *testing.T) {
+	rule := &model.TrafficSplitRule{MinSamples: 10, MaxErrorRateIncrease: 5, MaxLatencyIncrease: 50}
+	baseline := perfmetrics.WindowStats{Samples: 100, ErrorRate: 1, P95LatencyMs: 1000}
+
+	assert.Empty(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 9, ErrorRate: 100}, baseline), "not enough canary samples")
+	assert.Empty(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 20, ErrorRate: 100}, perfmetrics.WindowStats{Samples: 5}), "not enough baseline samples")
+	assert.Empty(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 20, ErrorRate: 6, P95LatencyMs: 1500}, baseline))
+	assert.Contains(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 20, ErrorRate: 6.5, P95LatencyMs: 1000}, baseline), "error rate")
+	assert.Contains(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 20, ErrorRate: 0, P95LatencyMs: 1501}, baseline), "p95 latency")
+
+	rule.MaxErrorRateIncrease = 0
+	rule.MaxLatencyIncrease = 0
+	assert.Empty(t, TrafficSplitRollbackReason(rule, perfmetrics.WindowStats{Samples: 20, ErrorRate: 100, P95LatencyMs: 99999}, baseline), "checks are off")
+}
+
+func TestApplyTrafficSplitModelMapping(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyChannelModelMapping, `{"gpt-4o-next":"deployment-next"}`)
+
+	ApplyTrafficSplitModelMapping(c, &TrafficSplitAssignment{Arm: TrafficSplitArmControl, CanaryModel: "gpt-4o-next"}, "gpt-4o")
+	assert.Equal(t, `{"gpt-4o-next":"deployment-next"}`, common.GetContextKeyString(c, constant.ContextKeyChannelModelMapping), "control requests keep the channel mapping")
+
+	ApplyTrafficSplitModelMapping(c, &TrafficSplitAssignment{Arm: TrafficSplitArmCanary, CanaryModel: "gpt-4o-next"}, "gpt-4o")
+	assert.JSONEq(t, `{"gpt-4o":"gpt-4o-next","gpt-4o-next":"deployment-next"}`, common.GetContextKeyString(c, constant.ContextKeyChannelModelMapping))
+}
+
+func TestTrafficSplitAssignAndAutoRollback(t *testing.T) {
+	truncate(t)
+	InvalidateTrafficSplitCache()
+	t.Cleanup(InvalidateTrafficSplitCache)
+
+	rule := &model.TrafficSplitRule{Name: "next", Enabled: true, ModelName: "traffic-split-model", CanaryModel: "traffic-split-model-next",
+		RampSteps: "100", MaxErrorRateIncrease: 10, MinSamples: 5}
+	require.NoError(t, rule.Normalize())
+	require.NoError(t, rule.Insert())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyUserId, 42)
+	assert.Nil(t, AssignTrafficSplit(c, "other-model", "default"))
+	assignment := AssignTrafficSplit(c, "traffic-split-model", "default")
+	require.NotNil(t, assignment)
+	assert.True(t, assignment.IsCanary())
+	assert.Equal(t, 100.0, assignment.Percent)
+	assert.Same(t, assignment, GetTrafficSplitAssignment(c))
+
+	adminInfo := map[string]interface{}{}
+	AppendTrafficSplitAdminInfo(c, adminInfo)
+	assert.Same(t, assignment, adminInfo["traffic_split"])
+
+	for i := 0; i < 10; i++ {
+		perfmetrics.Record(perfmetrics.Sample{Model: rule.ModelName, LatencyMs: 100, Success: true})
+	}
+	perfmetrics.Record(perfmetrics.Sample{Model: rule.ModelName, LatencyMs: 100, Success: false, Canary: true})
+	assert.Equal(t, 10, perfmetrics.RecentBaseline(rule.ModelName, time.Now().Add(-time.Minute)).Samples, "canary samples stay out of the baseline")
+
+	for i := 0; i < 5; i++ {
+		trafficSplitStatsMu.Lock()
+		if stats, ok := trafficSplitStats[rule.Id]; ok {
+			stats.lastEvalAt = time.Time{}
+		}
+		trafficSplitStatsMu.Unlock()
+		RecordTrafficSplitResult(assignment, 200, i%2 == 0)
+	}
+	assert.True(t, assignment.CanaryFailed)
+
+	require.Eventually(t, func() bool {
+		stored, err := model.GetTrafficSplitRuleById(rule.Id)
+		return err == nil && stored.Status == model.TrafficSplitStatusRolledBack
+	}, 5*time.Second, 20*time.Millisecond)
+	stored, err := model.GetTrafficSplitRuleById(rule.Id)
+	require.NoError(t, err)
+	assert.Contains(t, stored.RollbackReason, "error rate")
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyUserId, 42)
+	assert.Nil(t, AssignTrafficSplit(c, "traffic-split-model", "default"), "rolled back rules no longer split traffic")
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+const (
+	usageAnomalyUserBatch = 100
+	// 基线标准差的下限为期望值的该比例，避免历史消耗非常平稳时微小波动也产生很大的 z 值
+	usageAnomalyMinStdDevRatio = 0.25
+	// 日均消耗至少按该天数计算，避免月初或新用户按几个小时的消耗外推
+	usageForecastMinRateDays = 1.0
+	usageForecastRateDays    = 7
+)
+
+// usageAnomalyHandler 定期根据 quota_data 小时数据检测异常消耗并刷新本月消费预测。
+// quota_data 只有开启数据看板时才会写入，因此同时要求 DataExportEnabled。
+type usageAnomalyHandler struct{}
+
+func (usageAnomalyHandler) Type() string { return model.SystemTaskTypeUsageAnomaly }
+
+func (usageAnomalyHandler) Enabled() bool {
+	return operation_setting.GetAnomalySetting().Enabled && common.DataExportEnabled
+}
+
+func (usageAnomalyHandler) Interval() time.Duration {
+	return time.Duration(operation_setting.GetAnomalySetting().IntervalMinutes) * time.Minute
+}
+
+func (usageAnomalyHandler) NewPayload() any { return nil }
+
+func (usageAnomalyHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	summary, err := RunUsageAnomalyOnce(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() != nil {
+			logSystemTaskLockError(ctx, task, model.ErrSystemTaskLockLost)
+			return
+		}
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, summary, ""); err != nil {
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+func init() {
+	RegisterSystemTaskHandler(usageAnomalyHandler{})
+}
+
+type UsageAnomalySummary struct {
+	Series    int `json:"series"`
+	Anomalies int `json:"anomalies"`
+	Notified  int `json:"notified"`
+	Suspended int `json:"suspended"`
+	Forecasts int `json:"forecasts"`
+	Failed    int `json:"failed"`
+}
+
+// usageSeriesKey TokenId 为 0 表示用户维度
+type usageSeriesKey struct {
+	UserId  int
+	TokenId int
+}
+
+// usageBaseline 某序列某小时的基线统计
+type usageBaseline struct {
+	ActiveHours int
+	Mean        float64
+	StdDev      float64
+	Seasonal    float64
+}
+
+// RunUsageAnomalyOnce 检测上一个小时和当前小时的异常，然后刷新本月消费预测
+func RunUsageAnomalyOnce(ctx context.Context, now time.Time) (*UsageAnomalySummary, error) {
+	setting := operation_setting.GetAnomalySetting()
+	summary := &UsageAnomalySummary{}
+	if err := detectUsageAnomalies(ctx, setting, now, summary); err != nil {
+		return summary, err
+	}
+	if err := refreshUsageForecasts(ctx, now, summary); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+func detectUsageAnomalies(ctx context.Context, setting *operation_setting.AnomalySetting, now time.Time, summary *UsageAnomalySummary) error {
+	currentHour := now.Unix() - now.Unix()%3600
+	hours := []int64{currentHour - 3600, currentHour}
+	historyStart := hours[0] - int64(setting.LookbackDays)*24*3600
+	end := currentHour + 3600
+
+	userIds, err := model.GetUsageActiveUserIds(hours[0], end, setting.MinHourlyQuota)
+	if err != nil {
+		return err
+	}
+	users := map[int]*model.User{}
+	for start := 0; start < len(userIds); start += usageAnomalyUserBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := userIds[start:min(start+usageAnomalyUserBatch, len(userIds))]
+		rows, err := model.GetUsageHourlyQuotas(batch, historyStart, end)
+		if err != nil {
+			return err
+		}
+		series := map[usageSeriesKey]map[int64]int64{}
+		add := func(key usageSeriesKey, hour int64, quota int64) {
+			if series[key] == nil {
+				series[key] = map[int64]int64{}
+			}
+			series[key][hour] += quota
+		}
+		for _, row := range rows {
+			add(usageSeriesKey{UserId: row.UserId}, row.CreatedAt, row.Quota)
+			if row.TokenId != 0 {
+				add(usageSeriesKey{UserId: row.UserId, TokenId: row.TokenId}, row.CreatedAt, row.Quota)
+			}
+		}
+		for key, quotas := range series {
+			summary.Series++
+			for _, hour := range hours {
+				anomaly := evaluateUsageAnomaly(setting, key, quotas, hour)
+				if anomaly == nil {
+					continue
+				}
+				if err := handleUsageAnomaly(setting, anomaly, users, summary); err != nil {
+					summary.Failed++
+					common.SysLog(fmt.Sprintf("failed to handle usage anomaly of user %d token %d: %s", key.UserId, key.TokenId, err.Error()))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateUsageAnomaly 计算某序列某小时相对基线的 z 值，未达到 warning 时返回 nil
+func evaluateUsageAnomaly(setting *operation_setting.AnomalySetting, key usageSeriesKey, quotas map[int64]int64, hour int64) *model.UsageAnomaly {
+	quota := quotas[hour]
+	if quota <= 0 || quota < int64(setting.MinHourlyQuota) {
+		return nil
+	}
+	baseline := computeUsageBaseline(quotas, hour, setting.LookbackDays)
+	if baseline.ActiveHours < setting.MinActiveHours {
+		return nil
+	}
+	expected := math.Max(baseline.Mean, baseline.Seasonal)
+	scale := math.Max(math.Max(baseline.StdDev, expected*usageAnomalyMinStdDevRatio), 1)
+	z := (float64(quota) - expected) / scale
+	severity := usageAnomalySeverity(z, setting.ZScoreThreshold)
+	if severity == "" {
+		return nil
+	}
+	return &model.UsageAnomaly{
+		UserId:    key.UserId,
+		TokenId:   key.TokenId,
+		HourStart: hour,
+		Quota:     quota,
+		Expected:  roundAnomalyValue(expected),
+		StdDev:    roundAnomalyValue(baseline.StdDev),
+		ZScore:    roundAnomalyValue(z),
+		Ratio:     roundAnomalyValue(float64(quota) / math.Max(expected, 1)),
+		Severity:  severity,
+	}
+}
+
+// computeUsageBaseline 以 hour 之前 lookbackDays*24 个小时为历史（无数据的小时按 0 计），
+// 同时计算此前每天同一小时的季节均值
+func computeUsageBaseline(quotas map[int64]int64, hour int64, lookbackDays int) usageBaseline {
+	n := lookbackDays * 24
+	var baseline usageBaseline
+	var sum, sumSquares float64
+	for i := 1; i <= n; i++ {
+		value := float64(quotas[hour-int64(i)*3600])
+		if value > 0 {
+			baseline.ActiveHours++
+		}
+		sum += value
+		sumSquares += value * value
+	}
+	baseline.Mean = sum / float64(n)
+	baseline.StdDev = math.Sqrt(math.Max(sumSquares/float64(n)-baseline.Mean*baseline.Mean, 0))
+	var seasonal float64
+	for day := 1; day <= lookbackDays; day++ {
+		seasonal += float64(quotas[hour-int64(day)*86400])
+	}
+	baseline.Seasonal = seasonal / float64(lookbackDays)
+	return baseline
+}
+
+// usageAnomalySeverity warning / high / critical 分别对应阈值的 1 / 2 / 3 倍
+func usageAnomalySeverity(z float64, threshold float64) string {
+	switch {
+	case z >= threshold*3:
+		return model.AnomalySeverityCritical
+	case z >= threshold*2:
+		return model.AnomalySeverityHigh
+	case z >= threshold:
+		return model.AnomalySeverityWarning
+	}
+	return ""
+}
+
+func roundAnomalyValue(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// handleUsageAnomaly 保存异常；新发现或级别升高时按配置停用令牌并通知用户
+func handleUsageAnomaly(setting *operation_setting.AnomalySetting, anomaly *model.UsageAnomaly, users map[int]*model.User, summary *UsageAnomalySummary) error {
+	user, ok := users[anomaly.UserId]
+	if !ok {
+		var err error
+		user, err = model.GetUserById(anomaly.UserId, false)
+		if err != nil {
+			user = nil
+		}
+		users[anomaly.UserId] = user
+	}
+	if user == nil {
+		return nil
+	}
+	anomaly.Username = user.Username
+	var token *model.Token
+	if anomaly.TokenId != 0 {
+		if found, err := model.GetTokenByIds(anomaly.TokenId, anomaly.UserId); err == nil {
+			token = found
+			anomaly.TokenName = found.Name
+		}
+	}
+	fresh, err := model.UpsertUsageAnomaly(anomaly)
+	if err != nil || !fresh {
+		return err
+	}
+	summary.Anomalies++
+
+	suspended := false
+	severityRank := model.AnomalySeverityRank(anomaly.Severity)
+	suspendRank := model.AnomalySeverityRank(setting.AutoSuspendSeverity)
+	if token != nil && suspendRank > 0 && severityRank >= suspendRank && token.Status == common.TokenStatusEnabled {
+		if err := suspendAnomalyToken(token); err != nil {
+			return err
+		}
+		if err := model.SetUsageAnomalyAction(anomaly.Id, model.AnomalyActionTokenSuspended); err != nil {
+			return err
+		}
+		anomaly.Action = model.AnomalyActionTokenSuspended
+		suspended = true
+		summary.Suspended++
+		common.SysLog(fmt.Sprintf("token %d of user %d suspended by usage anomaly detection (z=%.2f)", token.Id, token.UserId, anomaly.ZScore))
+	}
+
+	notifyRank := model.AnomalySeverityRank(setting.NotifySeverity)
+	if !suspended && (notifyRank == 0 || severityRank < notifyRank) {
+		return nil
+	}
+	userSetting := user.GetSetting()
+	if err := NotifyUser(user.Id, user.Email, userSetting, buildUsageAnomalyNotify(anomaly, userSetting.NotifyType)); err != nil {
+		return err
+	}
+	summary.Notified++
+	return nil
+}
+
+// suspendAnomalyToken 停用令牌并回收其签发的子令牌，与管理员停用令牌的处理一致
+func suspendAnomalyToken(token *model.Token) error {
+	token.Status = common.TokenStatusDisabled
+	if err := token.SelectUpdate(); err != nil {
+		return err
+	}
+	return model.RevokeChildTokens(token.Id)
+}
+
+func buildUsageAnomalyNotify(anomaly *model.UsageAnomaly, notifyType string) dto.Notify {
+	scope := "全部令牌"
+	if anomaly.TokenId != 0 {
+		scope = fmt.Sprintf("令牌 %s (#%d)", anomaly.TokenName, anomaly.TokenId)
+	}
+	separator := "<br/>"
+	if isPlainTextNotifyType(notifyType) {
+		separator = "\n"
+	}
+	content := "检测到异常消耗：{{value}} 在 {{value}} 这一小时内消耗 {{value}}，约为平时的 {{value}} 倍（级别：{{value}}）。"
+	values := []interface{}{
+		scope,
+		time.Unix(anomaly.HourStart, 0).Format("2006-01-02 15:00"),
+		logger.FormatQuota(int(anomaly.Quota)),
+		fmt.Sprintf("%.1f", anomaly.Ratio),
+		anomaly.Severity,
+	}
+	if anomaly.Action == model.AnomalyActionTokenSuspended {
+		content += separator + "该令牌已被自动停用，确认安全后可在令牌管理中重新启用。"
+	} else {
+		content += separator + "如非本人操作，请及时检查并停用相关令牌。"
+	}
+	return dto.NewNotify(dto.NotifyTypeUsageAnomaly, "用量异常提醒", content, values)
+}
+
+// refreshUsageForecasts 按本月已消耗和近期日均消耗预测月末消耗，并估算余额可用天数
+func refreshUsageForecasts(ctx context.Context, now time.Time, summary *UsageAnomalySummary) error {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	rateStart := now.AddDate(0, 0, -usageForecastRateDays)
+	if rateStart.Before(monthStart) {
+		rateStart = monthStart
+	}
+	rateDays := math.Max(now.Sub(rateStart).Hours()/24, usageForecastMinRateDays)
+	remainingDays := monthEnd.Sub(now).Hours() / 24
+	end := now.Unix() + 1
+
+	spent, err := model.SumUsageQuotaByUser(monthStart.Unix(), end)
+	if err != nil {
+		return err
+	}
+	recent, err := model.SumUsageQuotaByUser(rateStart.Unix(), end)
+	if err != nil {
+		return err
+	}
+	recentByUser := make(map[int]int64, len(recent))
+	for _, row := range recent {
+		recentByUser[row.UserId] = row.Quota
+	}
+
+	for start := 0; start < len(spent); start += usageAnomalyUserBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := spent[start:min(start+usageAnomalyUserBatch, len(spent))]
+		ids := make([]int, 0, len(batch))
+		for _, row := range batch {
+			ids = append(ids, row.UserId)
+		}
+		users, err := model.GetUsageForecastUsers(ids)
+		if err != nil {
+			return err
+		}
+		usersById := make(map[int]*model.User, len(users))
+		for _, user := range users {
+			usersById[user.Id] = user
+		}
+		for _, row := range batch {
+			user := usersById[row.UserId]
+			if user == nil {
+				continue
+			}
+			forecast := buildUsageForecast(user, row.Quota, float64(recentByUser[row.UserId])/rateDays, remainingDays)
+			forecast.MonthStart = monthStart.Unix()
+			if err := model.UpsertUsageForecast(forecast); err != nil {
+				summary.Failed++
+				common.SysLog(fmt.Sprintf("failed to save usage forecast of user %d: %s", row.UserId, err.Error()))
+				continue
+			}
+			summary.Forecasts++
+		}
+	}
+	return model.DeleteStaleUsageForecasts(monthStart.Unix())
+}
+
+func buildUsageForecast(user *model.User, spent int64, dailyRate float64, remainingDays float64) *model.UsageForecast {
+	forecast := &model.UsageForecast{
+		UserId:             user.Id,
+		Username:           user.Username,
+		SpentToDate:        spent,
+		DailyRate:          roundAnomalyValue(dailyRate),
+		ProjectedMonthEnd:  spent + int64(math.Round(dailyRate*remainingDays)),
+		Balance:            int64(user.Quota),
+		DaysUntilExhausted: -1,
+	}
+	if dailyRate > 0 {
+		forecast.DaysUntilExhausted = roundAnomalyValue(math.Max(float64(user.Quota), 0) / dailyRate)
+	}
+	return forecast
+}
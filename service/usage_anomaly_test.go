@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func withAnomalySetting(t *testing.T, setting operation_setting.AnomalySetting) {
+	t.Helper()
+	current := operation_setting.GetAnomalySetting()
+	original := *current
+	t.Cleanup(func() { *current = original })
+	*current = setting
+}
+
+func seedHourlyQuota(t *testing.T, userId int, tokenId int, hour int64, quota int) {
+	t.Helper()
+	require.NoError(t, model.DB.Create(&model.QuotaData{UserID: userId, TokenID: tokenId, ModelName: "gpt-4o", CreatedAt: hour, Count: 1, Quota: quota}).Error)
+}
+
+func TestRunUsageAnomalyOnceDetectsAndSuspends(t *testing.T) {
+	truncate(t)
+	server, captured := newNotifyTestServer(t, "ok")
+	originalLimit := constant.NotifyLimitCount
+	constant.NotifyLimitCount = 10
+	t.Cleanup(func() { constant.NotifyLimitCount = originalLimit })
+	withAnomalySetting(t, operation_setting.AnomalySetting{
+		Enabled:             true,
+		IntervalMinutes:     15,
+		LookbackDays:        3,
+		MinActiveHours:      24,
+		ZScoreThreshold:     4,
+		MinHourlyQuota:      5000,
+		NotifySeverity:      model.AnomalySeverityHigh,
+		AutoSuspendSeverity: model.AnomalySeverityCritical,
+	})
+
+	user := &model.User{Id: 9201, Username: "anomaly_user", Quota: 500000, Status: common.UserStatusEnabled}
+	user.SetSetting(dto.UserSetting{NotifyType: dto.NotifyTypeWebhook, WebhookUrl: server.URL})
+	require.NoError(t, model.DB.Create(user).Error)
+	seedToken(t, 9301, user.Id, "anomaly-token-key", 0)
+
+	now := time.Date(2024, 3, 20, 10, 30, 0, 0, time.Local)
+	currentHour := now.Unix() - now.Unix()%3600
+	for i := 1; i <= 73; i++ {
+		seedHourlyQuota(t, user.Id, 9301, currentHour-int64(i)*3600, 1000)
+	}
+	seedHourlyQuota(t, user.Id, 9301, currentHour, 100000)
+	// Below the minimum hourly quota and without a user row, so it is neither
+	// evaluated nor forecast.
+	seedHourlyQuota(t, 9202, 0, currentHour, 4000)
+
+	summary, err := RunUsageAnomalyOnce(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, UsageAnomalySummary{Series: 2, Anomalies: 2, Notified: 2, Suspended: 1, Forecasts: 1}, *summary)
+
+	anomalies, total, err := model.GetUsageAnomalies(model.UsageAnomalyFilter{UserId: user.Id}, 0, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	for _, anomaly := range anomalies {
+		assert.Equal(t, currentHour, anomaly.HourStart)
+		assert.Equal(t, model.AnomalySeverityCritical, anomaly.Severity)
+		assert.EqualValues(t, 1000, anomaly.Expected)
+		assert.EqualValues(t, 100, anomaly.Ratio)
+		if anomaly.TokenId == 0 {
+			assert.Empty(t, anomaly.Action)
+		} else {
+			assert.Equal(t, "test_token", anomaly.TokenName)
+			assert.Equal(t, model.AnomalyActionTokenSuspended, anomaly.Action)
+		}
+	}
+	token, err := model.GetTokenById(9301)
+	require.NoError(t, err)
+	assert.Equal(t, common.TokenStatusDisabled, token.Status)
+
+	requests := captured()
+	require.Len(t, requests, 2)
+	for _, request := range requests {
+		assert.Equal(t, dto.NotifyTypeUsageAnomaly, gjson.Get(request.Body, "type").String())
+	}
+
+	forecast, err := model.GetUserUsageForecast(user.Id)
+	require.NoError(t, err)
+	assert.EqualValues(t, 173000, forecast.SpentToDate)
+	assert.InDelta(t, 173000.0/7, forecast.DailyRate, 0.01)
+	remainingDays := time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local).Sub(now).Hours() / 24
+	assert.InDelta(t, 173000+173000.0/7*remainingDays, float64(forecast.ProjectedMonthEnd), 1)
+	assert.InDelta(t, 500000/(173000.0/7), forecast.DaysUntilExhausted, 0.01)
+
+	// The same hour is only reported again when its severity increases.
+	summary, err = RunUsageAnomalyOnce(context.Background(), now)
+	require.NoError(t, err)
+	assert.Zero(t, summary.Anomalies)
+	assert.Len(t, captured(), 2)
+}
+
+func TestEvaluateUsageAnomalyRequiresHistory(t *testing.T) {
+	setting := &operation_setting.AnomalySetting{LookbackDays: 3, MinActiveHours: 24, ZScoreThreshold: 4, MinHourlyQuota: 100}
+	hour := int64(1710928800)
+	quotas := map[int64]int64{hour: 50000}
+	for i := 1; i <= 12; i++ {
+		quotas[hour-int64(i)*3600] = 1000
+	}
+	key := usageSeriesKey{UserId: 1, TokenId: 2}
+	assert.Nil(t, evaluateUsageAnomaly(setting, key, quotas, hour), "only 12 active hours of history")
+
+	setting.MinActiveHours = 12
+	anomaly := evaluateUsageAnomaly(setting, key, quotas, hour)
+	require.NotNil(t, anomaly)
+	assert.Equal(t, model.AnomalySeverityCritical, anomaly.Severity)
+
+	setting.ZScoreThreshold = 100
+	anomaly = evaluateUsageAnomaly(setting, key, quotas, hour)
+	require.NotNil(t, anomaly)
+	assert.Equal(t, model.AnomalySeverityWarning, anomaly.Severity)
+}
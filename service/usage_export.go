@@ -0,0 +1,487 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	UsageExportFormatCSV     = "csv"
+	UsageExportFormatJSONL   = "jsonl"
+	UsageExportFormatParquet = "parquet"
+
+	usageExportBatchSize = 1000
+	// usageExportRetention is how long a finished export can be downloaded.
+	// Older files are removed when the next export starts.
+	usageExportRetention = 24 * time.Hour
+	// UsageExportURLTTL is the lifetime of a signed download URL.
+	UsageExportURLTTL = 5 * time.Minute
+	// Exports live in a sub directory so the disk cache cleanup, which only
+	// removes top level files, never deletes them.
+	usageExportDirName = "usage-exports"
+)
+
+var (
+	ErrUsageExportInProgress = errors.New("an export is already in progress, wait until it finishes")
+	ErrUsageExportNotReady   = errors.New("export is not finished")
+	ErrUsageExportExpired    = errors.New("export file has expired")
+)
+
+type UsageExportPayload struct {
+	// OwnerId is the user who requested the export. Self-service exports are
+	// always limited to the owner's own logs.
+	OwnerId     int                   `json:"owner_id"`
+	SelfService bool                  `json:"self_service"`
+	Format      string                `json:"format"`
+	Filter      model.LogExportFilter `json:"filter"`
+}
+
+type UsageExportResult struct {
+	FileName  string `json:"file_name"`
+	Format    string `json:"format"`
+	Rows      int64  `json:"rows"`
+	Size      int64  `json:"size"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// usageExportHandler writes filtered log rows to a file in the disk cache
+// directory. It is created on demand via StartUsageExportTask.
+type usageExportHandler struct{}
+
+func (usageExportHandler) Type() string { return model.SystemTaskTypeUsageExport }
+
+func (usageExportHandler) Run(ctx context.Context, task *model.SystemTask, runnerID string) {
+	runUsageExportTask(ctx, task, runnerID)
+}
+
+func init() {
+	RegisterSystemTaskHandler(usageExportHandler{})
+}
+
+func usageExportActiveKey(ownerId int) string {
+	return fmt.Sprintf("%s:%d", model.SystemTaskTypeUsageExport, ownerId)
+}
+
+func IsValidUsageExportFormat(format string) bool {
+	switch format {
+	case UsageExportFormatCSV, UsageExportFormatJSONL, UsageExportFormatParquet:
+		return true
+	default:
+		return false
+	}
+}
+
+// StartUsageExportTask queues an export. Each user can have one export pending
+// or running at a time; exports of all users run one after another.
+func StartUsageExportTask(payload UsageExportPayload) (*model.SystemTask, error) {
+	payload.Format = strings.ToLower(strings.TrimSpace(payload.Format))
+	if !IsValidUsageExportFormat(payload.Format) {
+		return nil, errors.New("format must be one of csv, jsonl, parquet")
+	}
+	filter := &payload.Filter
+	if filter.StartTimestamp <= 0 || filter.EndTimestamp <= 0 {
+		return nil, errors.New("start and end timestamps are required")
+	}
+	if filter.EndTimestamp < filter.StartTimestamp {
+		return nil, errors.New("end timestamp must not be earlier than start timestamp")
+	}
+	if payload.SelfService {
+		filter.UserId = payload.OwnerId
+		filter.Username = ""
+		filter.ChannelId = 0
+	}
+
+	activeKey := usageExportActiveKey(payload.OwnerId)
+	activeTask, err := model.GetActiveSystemTaskByKey(activeKey)
+	if err != nil {
+		return nil, err
+	}
+	if activeTask != nil {
+		return nil, ErrUsageExportInProgress
+	}
+	task, err := model.CreateSystemTaskWithActiveKey(model.SystemTaskTypeUsageExport, activeKey, payload, nil)
+	if err != nil {
+		activeTask, activeErr := model.GetActiveSystemTaskByKey(activeKey)
+		if activeErr == nil && activeTask != nil {
+			return nil, ErrUsageExportInProgress
+		}
+		return nil, err
+	}
+	notifySystemTaskRunner()
+	return task, nil
+}
+
+func usageExportDir() string {
+	return filepath.Join(common.GetDiskCacheDir(), usageExportDirName)
+}
+
+func runUsageExportTask(ctx context.Context, task *model.SystemTask, runnerID string) {
+	payload := UsageExportPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if !IsValidUsageExportFormat(payload.Format) {
+		failSystemTask(task, runnerID, fmt.Errorf("unsupported export format %q", payload.Format))
+		return
+	}
+
+	dir := usageExportDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	cleanupExpiredUsageExports(dir, time.Now())
+
+	fileName := task.TaskID + "." + payload.Format
+	finalPath := filepath.Join(dir, fileName)
+	tmpPath := finalPath + ".tmp"
+	rows, err := writeUsageExportFile(ctx, tmpPath, payload, NewSystemTaskProgressReporter(task, runnerID))
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		if ctx.Err() != nil {
+			logSystemTaskLockError(ctx, task, model.ErrSystemTaskLockLost)
+			return
+		}
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		_ = os.Remove(tmpPath)
+		failSystemTask(task, runnerID, err)
+		return
+	}
+	info, err := os.Stat(finalPath)
+	if err != nil {
+		failSystemTask(task, runnerID, err)
+		return
+	}
+
+	result := UsageExportResult{
+		FileName:  fileName,
+		Format:    payload.Format,
+		Rows:      rows,
+		Size:      info.Size(),
+		ExpiresAt: time.Now().Add(usageExportRetention).Unix(),
+	}
+	if err := model.FinishSystemTask(task.TaskID, runnerID, model.SystemTaskStatusSucceeded, result, ""); err != nil {
+		_ = os.Remove(finalPath)
+		logSystemTaskLockError(ctx, task, err)
+	}
+}
+
+func writeUsageExportFile(ctx context.Context, path string, payload UsageExportPayload, progress func(processed, total int)) (int64, error) {
+	total, err := model.CountExportLogs(ctx, payload.Filter)
+	if err != nil {
+		return 0, err
+	}
+	progress(0, int(total))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	w, err := newUsageExportWriter(file, payload.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		processed int64
+		cursor    *model.LogExportCursor
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return processed, err
+		}
+		logs, err := model.FindExportLogBatch(ctx, payload.Filter, cursor, usageExportBatchSize)
+		if err != nil {
+			return processed, err
+		}
+		if len(logs) == 0 {
+			break
+		}
+		last := logs[len(logs)-1]
+		cursor = &model.LogExportCursor{CreatedAt: last.CreatedAt, Id: last.Id, RequestId: last.RequestId}
+		for _, log := range logs {
+			if payload.SelfService {
+				model.SanitizeUserExportLog(log)
+			}
+			if err := w.Write(newUsageExportRow(log)); err != nil {
+				return processed, err
+			}
+		}
+		processed += int64(len(logs))
+		// Rows logged while the export runs can push processed past the
+		// initial count; keep the reported total in step.
+		if processed > total {
+			total = processed
+		}
+		progress(int(processed), int(total))
+		if len(logs) < usageExportBatchSize {
+			break
+		}
+	}
+	if err := w.Close(); err != nil {
+		return processed, err
+	}
+	progress(int(processed), int(processed))
+	return processed, file.Close()
+}
+
+// cleanupExpiredUsageExports removes export files older than the retention
+// window, including temporary files left behind by an interrupted run.
+func cleanupExpiredUsageExports(dir string, now time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) <= usageExportRetention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			logger.LogWarn(context.Background(), fmt.Sprintf("failed to remove expired usage export %s: %v", entry.Name(), err))
+		}
+	}
+}
+
+// usageExportRow is the exported shape of a log row. The same struct drives
+// the CSV header, the JSONL objects and the Parquet schema.
+type usageExportRow struct {
+	Id                int64  `json:"id" parquet:"name=id, type=INT64"`
+	CreatedAt         int64  `json:"created_at" parquet:"name=created_at, type=INT64"`
+	Type              int32  `json:"type" parquet:"name=type, type=INT32"`
+	UserId            int64  `json:"user_id" parquet:"name=user_id, type=INT64"`
+	Username          string `json:"username" parquet:"name=username, type=BYTE_ARRAY, convertedtype=UTF8"`
+	TokenId           int64  `json:"token_id" parquet:"name=token_id, type=INT64"`
+	TokenName         string `json:"token_name" parquet:"name=token_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ModelName         string `json:"model_name" parquet:"name=model_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Group             string `json:"group" parquet:"name=group, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ChannelId         int64  `json:"channel_id" parquet:"name=channel_id, type=INT64"`
+	Quota             int64  `json:"quota" parquet:"name=quota, type=INT64"`
+	PromptTokens      int64  `json:"prompt_tokens" parquet:"name=prompt_tokens, type=INT64"`
+	CompletionTokens  int64  `json:"completion_tokens" parquet:"name=completion_tokens, type=INT64"`
+	UseTime           int64  `json:"use_time" parquet:"name=use_time, type=INT64"`
+	IsStream          bool   `json:"is_stream" parquet:"name=is_stream, type=BOOLEAN"`
+	Ip                string `json:"ip" parquet:"name=ip, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RequestId         string `json:"request_id" parquet:"name=request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	UpstreamRequestId string `json:"upstream_request_id" parquet:"name=upstream_request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EndUserId         string `json:"end_user_id" parquet:"name=end_user_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Labels            string `json:"labels" parquet:"name=labels, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Content           string `json:"content" parquet:"name=content, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Other             string `json:"other" parquet:"name=other, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+var usageExportCSVHeader = []string{
+	"id", "created_at", "type", "user_id", "username", "token_id", "token_name", "model_name", "group",
+	"channel_id", "quota", "prompt_tokens", "completion_tokens", "use_time", "is_stream", "ip",
+	"request_id", "upstream_request_id", "end_user_id", "labels", "content", "other",
+}
+
+func newUsageExportRow(log *model.Log) *usageExportRow {
+	return &usageExportRow{
+		Id:                int64(log.Id),
+		CreatedAt:         log.CreatedAt,
+		Type:              int32(log.Type),
+		UserId:            int64(log.UserId),
+		Username:          log.Username,
+		TokenId:           int64(log.TokenId),
+		TokenName:         log.TokenName,
+		ModelName:         log.ModelName,
+		Group:             log.Group,
+		ChannelId:         int64(log.ChannelId),
+		Quota:             int64(log.Quota),
+		PromptTokens:      int64(log.PromptTokens),
+		CompletionTokens:  int64(log.CompletionTokens),
+		UseTime:           int64(log.UseTime),
+		IsStream:          log.IsStream,
+		Ip:                log.Ip,
+		RequestId:         log.RequestId,
+		UpstreamRequestId: log.UpstreamRequestId,
+		EndUserId:         log.EndUserId,
+		Labels:            log.Labels,
+		Content:           log.Content,
+		Other:             log.Other,
+	}
+}
+
+func (row *usageExportRow) csvRecord() []string {
+	return []string{
+		strconv.FormatInt(row.Id, 10),
+		strconv.FormatInt(row.CreatedAt, 10),
+		strconv.FormatInt(int64(row.Type), 10),
+		strconv.FormatInt(row.UserId, 10),
+		row.Username,
+		strconv.FormatInt(row.TokenId, 10),
+		row.TokenName,
+		row.ModelName,
+		row.Group,
+		strconv.FormatInt(row.ChannelId, 10),
+		strconv.FormatInt(row.Quota, 10),
+		strconv.FormatInt(row.PromptTokens, 10),
+		strconv.FormatInt(row.CompletionTokens, 10),
+		strconv.FormatInt(row.UseTime, 10),
+		strconv.FormatBool(row.IsStream),
+		row.Ip,
+		row.RequestId,
+		row.UpstreamRequestId,
+		row.EndUserId,
+		row.Labels,
+		row.Content,
+		row.Other,
+	}
+}
+
+type usageExportWriter interface {
+	Write(row *usageExportRow) error
+	// Close flushes buffered rows and footers; it does not close the file.
+	Close() error
+}
+
+func newUsageExportWriter(w io.Writer, format string) (usageExportWriter, error) {
+	switch format {
+	case UsageExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(usageExportCSVHeader); err != nil {
+			return nil, err
+		}
+		return &csvUsageExportWriter{w: cw}, nil
+	case UsageExportFormatJSONL:
+		return &jsonlUsageExportWriter{w: w}, nil
+	case UsageExportFormatParquet:
+		pw, err := writer.NewParquetWriterFromWriter(w, new(usageExportRow), 1)
+		if err != nil {
+			return nil, err
+		}
+		pw.CompressionType = parquet.CompressionCodec_SNAPPY
+		return &parquetUsageExportWriter{w: pw}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+type csvUsageExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvUsageExportWriter) Write(row *usageExportRow) error {
+	return e.w.Write(row.csvRecord())
+}
+
+func (e *csvUsageExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlUsageExportWriter struct {
+	w io.Writer
+}
+
+func (e *jsonlUsageExportWriter) Write(row *usageExportRow) error {
+	data, err := common.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+func (e *jsonlUsageExportWriter) Close() error {
+	return nil
+}
+
+type parquetUsageExportWriter struct {
+	w *writer.ParquetWriter
+}
+
+func (e *parquetUsageExportWriter) Write(row *usageExportRow) error {
+	return e.w.Write(row)
+}
+
+func (e *parquetUsageExportWriter) Close() error {
+	return e.w.WriteStop()
+}
+
+func usageExportSignature(taskID string, expires int64) string {
+	return common.GenerateHMAC(fmt.Sprintf("%s:%s:%d", model.SystemTaskTypeUsageExport, taskID, expires))
+}
+
+// UsageExportDownloadURL returns a relative download URL for a finished export
+// that is valid for UsageExportURLTTL without any other credential.
+func UsageExportDownloadURL(taskID string, now time.Time) (string, int64) {
+	expires := now.Add(UsageExportURLTTL).Unix()
+	query := url.Values{}
+	query.Set("task_id", taskID)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", usageExportSignature(taskID, expires))
+	return "/api/log/export/download?" + query.Encode(), expires
+}
+
+// VerifyUsageExportSignature checks a signed download URL.
+func VerifyUsageExportSignature(taskID string, expires int64, signature string, now time.Time) bool {
+	if taskID == "" || signature == "" || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(usageExportSignature(taskID, expires)), []byte(signature))
+}
+
+// UsageExportFile returns the payload of an export task and, once the task has
+// succeeded and the file is still kept, the file path and result.
+func UsageExportFile(task *model.SystemTask) (string, *UsageExportResult, error) {
+	if task == nil || task.Type != model.SystemTaskTypeUsageExport {
+		return "", nil, errors.New("export not found")
+	}
+	if task.Status != model.SystemTaskStatusSucceeded {
+		return "", nil, ErrUsageExportNotReady
+	}
+	result := &UsageExportResult{}
+	if err := common.UnmarshalJsonStr(task.Result, result); err != nil {
+		return "", nil, err
+	}
+	if result.FileName == "" || common.GetTimestamp() > result.ExpiresAt {
+		return "", nil, ErrUsageExportExpired
+	}
+	path := filepath.Join(usageExportDir(), filepath.Base(result.FileName))
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, ErrUsageExportExpired
+		}
+		return "", nil, err
+	}
+	return path, result, nil
+}
+
+// UsageExportOwner returns the user who requested an export task.
+func UsageExportOwner(task *model.SystemTask) (int, bool) {
+	if task == nil || task.Type != model.SystemTaskTypeUsageExport {
+		return 0, false
+	}
+	payload := UsageExportPayload{}
+	if err := task.DecodePayload(&payload); err != nil {
+		return 0, false
+	}
+	return payload.OwnerId, true
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withUsageExportDir(t *testing.T) {
+	t.Helper()
+	saved := common.GetDiskCacheConfig()
+	config := saved
+	config.Path = t.TempDir()
+	common.SetDiskCacheConfig(config)
+	t.Cleanup(func() { common.SetDiskCacheConfig(saved) })
+}
+
+func runClaimedUsageExport(t *testing.T, task *model.SystemTask) *model.SystemTask {
+	t.Helper()
+	claimed, ok, err := model.ClaimSystemTask(task.ID, task.Type, "runner-test", systemTaskLockUntil())
+	require.NoError(t, err)
+	require.True(t, ok)
+	runUsageExportTask(context.Background(), claimed, "runner-test")
+	finished, err := model.GetSystemTaskByTaskID(task.TaskID)
+	require.NoError(t, err)
+	return finished
+}
+
+func TestUsageExportSelfServiceOnlyExportsOwnRows(t *testing.T) {
+	truncate(t)
+	withUsageExportDir(t)
+
+	other := `{"admin_info":{"use_channel":[1]},"cache_tokens":3}`
+	require.NoError(t, model.LOG_DB.Create(&[]model.Log{
+		{UserId: 1, Username: "alice", CreatedAt: 100, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 10, Other: other},
+		{UserId: 2, Username: "bob", CreatedAt: 110, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 20},
+		{UserId: 1, Username: "alice", CreatedAt: 120, Type: model.LogTypeConsume, ModelName: "claude", Quota: 30},
+	}).Error)
+
+	task, err := StartUsageExportTask(UsageExportPayload{
+		OwnerId:     1,
+		SelfService: true,
+		Format:      "JSONL",
+		// A self-service export can never widen its scope to another user.
+		Filter: model.LogExportFilter{LogType: model.LogTypeConsume, StartTimestamp: 1, EndTimestamp: 1000, Username: "bob"},
+	})
+	require.NoError(t, err)
+
+	_, err = StartUsageExportTask(UsageExportPayload{
+		OwnerId:     1,
+		SelfService: true,
+		Format:      UsageExportFormatCSV,
+		Filter:      model.LogExportFilter{StartTimestamp: 1, EndTimestamp: 1000},
+	})
+	assert.ErrorIs(t, err, ErrUsageExportInProgress)
+
+	finished := runClaimedUsageExport(t, task)
+	require.Equal(t, model.SystemTaskStatusSucceeded, finished.Status, finished.Error)
+
+	path, result, err := UsageExportFile(finished)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, result.Rows)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var rows []usageExportRow
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var row usageExportRow
+		require.NoError(t, common.Unmarshal(scanner.Bytes(), &row))
+		rows = append(rows, row)
+	}
+	require.Len(t, rows, 2)
+	assert.Equal(t, []int64{10, 30}, []int64{rows[0].Quota, rows[1].Quota})
+	assert.Zero(t, rows[0].Id)
+	assert.NotContains(t, rows[0].Other, "admin_info")
+	assert.Contains(t, rows[0].Other, "cache_tokens")
+}
+
+func TestUsageExportWritesCSVAndParquet(t *testing.T) {
+	truncate(t)
+	withUsageExportDir(t)
+
+	require.NoError(t, model.LOG_DB.Create(&[]model.Log{
+		{UserId: 1, Username: "alice", CreatedAt: 100, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 10, Content: "a,\"quoted\""},
+		{UserId: 2, Username: "bob", CreatedAt: 110, Type: model.LogTypeConsume, ModelName: "gpt-4o", Quota: 20},
+	}).Error)
+
+	filter := model.LogExportFilter{LogType: model.LogTypeConsume, StartTimestamp: 1, EndTimestamp: 1000}
+	task, err := StartUsageExportTask(UsageExportPayload{OwnerId: 99, Format: UsageExportFormatCSV, Filter: filter})
+	require.NoError(t, err)
+	finished := runClaimedUsageExport(t, task)
+	require.Equal(t, model.SystemTaskStatusSucceeded, finished.Status, finished.Error)
+	path, _, err := UsageExportFile(finished)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, usageExportCSVHeader, records[0])
+	assert.Equal(t, "alice", records[1][4])
+	assert.Equal(t, "a,\"quoted\"", records[1][20])
+
+	task, err = StartUsageExportTask(UsageExportPayload{OwnerId: 99, Format: UsageExportFormatParquet, Filter: filter})
+	require.NoError(t, err)
+	finished = runClaimedUsageExport(t, task)
+	require.Equal(t, model.SystemTaskStatusSucceeded, finished.Status, finished.Error)
+	path, result, err := UsageExportFile(finished)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, result.Rows)
+	assert.Equal(t, ".parquet", filepath.Ext(path))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "PAR1", string(data[:4]))
+}
+
+func TestUsageExportRequiresTimeRangeAndKnownFormat(t *testing.T) {
+	_, err := StartUsageExportTask(UsageExportPayload{OwnerId: 1, Format: "xlsx", Filter: model.LogExportFilter{StartTimestamp: 1, EndTimestamp: 2}})
+	assert.Error(t, err)
+	_, err = StartUsageExportTask(UsageExportPayload{OwnerId: 1, Format: UsageExportFormatCSV, Filter: model.LogExportFilter{EndTimestamp: 2}})
+	assert.Error(t, err)
+	_, err = StartUsageExportTask(UsageExportPayload{OwnerId: 1, Format: UsageExportFormatCSV, Filter: model.LogExportFilter{StartTimestamp: 3, EndTimestamp: 2}})
+	assert.Error(t, err)
+}
+
+func TestUsageExportDownloadSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	link, expires := UsageExportDownloadURL("systask_abc", now)
+	assert.True(t, strings.HasPrefix(link, "/api/log/export/download?"))
+	assert.Equal(t, now.Add(UsageExportURLTTL).Unix(), expires)
+
+	signature := usageExportSignature("systask_abc", expires)
+	assert.True(t, VerifyUsageExportSignature("systask_abc", expires, signature, now))
+	assert.False(t, VerifyUsageExportSignature("systask_other", expires, signature, now))
+	assert.False(t, VerifyUsageExportSignature("systask_abc", expires+60, signature, now))
+	assert.False(t, VerifyUsageExportSignature("systask_abc", expires, signature, now.Add(UsageExportURLTTL+time.Second)))
+}
@@ -62,6 +62,15 @@ func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data
 	if !canSend {
 		return fmt.Errorf("notification limit exceeded for user %d with type %s", userId, notifyType)
 	}
+	return sendUserNotify(userId, userEmail, userSetting, data)
+}
+
+// sendUserNotify 按用户设置的通知方式发送，不做频率限制，调用方需自行调用 CheckNotificationLimit
+func sendUserNotify(userId int, userEmail string, userSetting dto.UserSetting, data dto.Notify) error {
+	notifyType := userSetting.NotifyType
+	if notifyType == "" {
+		notifyType = dto.NotifyTypeEmail
+	}
 
 	switch notifyType {
 	case dto.NotifyTypeEmail:
@@ -100,6 +109,18 @@ func NotifyUser(userId int, userEmail string, userSetting dto.UserSetting, data
 			return nil
 		}
 		return sendGotifyNotify(gotifyUrl, gotifyToken, userSetting.GotifyPriority, data)
+	case dto.NotifyTypeTelegram:
+		if userSetting.TelegramBotToken == "" || userSetting.TelegramChatId == "" {
+			common.SysLog(fmt.Sprintf("user %d has no telegram bot token or chat id, skip sending telegram", userId))
+			return nil
+		}
+		return sendChatNotify(notifyType, userSetting, data)
+	case dto.NotifyTypeSlack, dto.NotifyTypeTeams, dto.NotifyTypeDiscord, dto.NotifyTypeFeishu, dto.NotifyTypeDingTalk:
+		if userSetting.ChatWebhookUrl == "" {
+			common.SysLog(fmt.Sprintf("user %d has no %s webhook url, skip sending %s", userId, notifyType, notifyType))
+			return nil
+		}
+		return sendChatNotify(notifyType, userSetting, data)
 	}
 	return nil
 }
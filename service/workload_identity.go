@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/samber/hot"
+	"gorm.io/gorm"
+)
+
+// ErrWorkloadIdentityDenied 表示工作负载 JWT 未通过校验或没有匹配的规则。
+// 具体原因按签发方写入日志，不返回给调用方。
+var ErrWorkloadIdentityDenied = errors.New("workload identity token denied")
+
+const (
+	WorkloadTokenModeDirect   = "direct"
+	WorkloadTokenModeExchange = "exchange"
+
+	workloadTokenNameMaxLength = 128
+
+	workloadDirectJWTCacheNamespace = "new-api:workload_direct_jwt:v1"
+	workloadDirectJWTCacheCapacity  = 100_000
+)
+
+var (
+	workloadDirectJWTCache     *cachex.HybridCache[string]
+	workloadDirectJWTCacheOnce sync.Once
+)
+
+func init() {
+	// JWKS 使用共享的出站客户端，与其它外部请求共用连接池与代理设置
+	oauth.WorkloadJWKSHTTPClient = GetHttpClient
+}
+
+type workloadGrant struct {
+	issuer   *model.WorkloadIdentityIssuer
+	identity *oauth.WorkloadIdentity
+	rule     *oauth.WorkloadRule
+}
+
+// IsWorkloadJWT 判断中转请求携带的凭据是否为工作负载身份 JWT（而非 sk- 令牌密钥）。
+func IsWorkloadJWT(raw string) bool {
+	_, ok := oauth.WorkloadJWTIssuer(raw)
+	return ok
+}
+
+// AuthenticateWorkloadJWT 以工作负载 JWT 直接鉴权中转请求。同一签发方下同一 sub 的工作负载
+// 共用一个网关令牌（额度按工作负载而非按 JWT 计算）：每个 JWT 首次使用时校验签名并续期该令牌，
+// 之后直到 JWT 过期都直接命中令牌，沿用令牌的缓存、额度扣减与状态检查，不再重复验签。
+func AuthenticateWorkloadJWT(ctx context.Context, raw string, clientIp string) (*model.Token, error) {
+	cacheKey := workloadDirectJWTCacheKey(raw)
+	keyHash, found, err := getWorkloadDirectJWTCache().Get(cacheKey)
+	if err != nil {
+		common.SysLog("failed to read workload JWT cache: " + err.Error())
+	}
+	if found {
+		token, err := model.GetTokenByKeyHash(keyHash, false)
+		if err == nil {
+			return token, model.ValidateTokenStatus(token)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %v", model.ErrDatabase, err)
+		}
+		// 令牌已被吊销或清理，重新校验 JWT
+	}
+	grant, err := verifyWorkloadGrant(ctx, raw, true)
+	if err != nil {
+		return nil, err
+	}
+	token, err := workloadDirectToken(grant, clientIp)
+	if errors.Is(err, model.ErrWorkloadQuotaExhausted) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrDatabase, err)
+	}
+	// 令牌可能已被更晚的 JWT 续期，缓存只保留到本 JWT 自身允许的时间
+	if ttl := time.Until(workloadTokenExpiresAt(grant)); ttl > 0 {
+		if err := getWorkloadDirectJWTCache().SetWithTTL(cacheKey, token.Key, ttl); err != nil {
+			common.SysLog("failed to write workload JWT cache: " + err.Error())
+		}
+	}
+	return token, model.ValidateTokenStatus(token)
+}
+
+// workloadDirectToken 续期工作负载已有的直连令牌，没有时签发一个新令牌。
+func workloadDirectToken(grant *workloadGrant, clientIp string) (*model.Token, error) {
+	subject := workloadSubjectHash(grant.identity.Subject)
+	existing, err := model.GetWorkloadDirectToken(grant.issuer.Id, grant.rule.UserId, subject)
+	if err == nil {
+		if err := model.RenewWorkloadDirectToken(existing, workloadTokenExpiresAt(grant).Unix()); err != nil {
+			return nil, err
+		}
+		// 经由密钥摘要读取，与密钥鉴权共用缓存中的实时余额
+		token, err := model.GetTokenByKeyHash(existing.Key, false)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, err
+		}
+		// 续期时恰好被过期清理删除，重新签发
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	token, err := mintWorkloadToken(grant, key, true)
+	if err != nil {
+		return nil, err
+	}
+	recordWorkloadTokenAudit(grant, token, clientIp, WorkloadTokenModeDirect)
+	return token, nil
+}
+
+// ExchangeWorkloadJWT 用工作负载 JWT 换取一个新的短期网关令牌（RFC 8693 风格），
+// 返回的明文密钥只在此处出现一次。新令牌与该工作负载的其它令牌共用规则的额度上限。
+func ExchangeWorkloadJWT(ctx context.Context, raw string, clientIp string) (string, *model.Token, error) {
+	grant, err := verifyWorkloadGrant(ctx, raw, false)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := common.GenerateKey()
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := mintWorkloadToken(grant, key, false)
+	if errors.Is(err, model.ErrWorkloadQuotaExhausted) {
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", model.ErrDatabase, err)
+	}
+	recordWorkloadTokenAudit(grant, token, clientIp, WorkloadTokenModeExchange)
+	return key, token, nil
+}
+
+func verifyWorkloadGrant(ctx context.Context, raw string, direct bool) (*workloadGrant, error) {
+	iss, ok := oauth.WorkloadJWTIssuer(raw)
+	if !ok {
+		return nil, ErrWorkloadIdentityDenied
+	}
+	issuer, err := model.GetEnabledWorkloadIdentityIssuer(iss)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWorkloadIdentityDenied
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrDatabase, err)
+	}
+	deny := func(reason string) error {
+		logger.LogWarn(ctx, fmt.Sprintf("workload identity issuer %s (ID: %d) denied a token: %s", issuer.Name, issuer.Id, reason))
+		return ErrWorkloadIdentityDenied
+	}
+	if direct && !issuer.AllowDirect {
+		return nil, deny("direct bearer use is disabled")
+	}
+	identity, err := oauth.VerifyWorkloadJWT(ctx, issuer, raw)
+	if err != nil {
+		return nil, deny(err.Error())
+	}
+	if !identity.ExpiresAt.After(time.Now()) {
+		return nil, deny("token is expired")
+	}
+	rules, err := oauth.ParseWorkloadRules(issuer.Rules)
+	if err != nil {
+		return nil, deny("rules are invalid: " + err.Error())
+	}
+	rule, ok := oauth.MatchWorkloadRule(rules, identity.Claims)
+	if !ok {
+		return nil, deny("no rule matches subject " + identity.Subject)
+	}
+	user, err := model.GetUserCache(rule.UserId)
+	if err != nil || user.Status != common.UserStatusEnabled {
+		return nil, deny(fmt.Sprintf("user %d is unavailable", rule.UserId))
+	}
+	return &workloadGrant{issuer: issuer, identity: identity, rule: rule}, nil
+}
+
+// workloadTokenExpiresAt 令牌有效期取签发方 token_ttl 与 JWT exp 中较早者。
+func workloadTokenExpiresAt(grant *workloadGrant) time.Time {
+	expiresAt := time.Now().Add(time.Duration(grant.issuer.TokenTTL) * time.Second)
+	if grant.identity.ExpiresAt.Before(expiresAt) {
+		expiresAt = grant.identity.ExpiresAt
+	}
+	return expiresAt
+}
+
+// mintWorkloadToken 按规则的令牌模板签发网关令牌，额度从该工作负载尚未发放的上限中划出。
+func mintWorkloadToken(grant *workloadGrant, key string, direct bool) (*model.Token, error) {
+	now := time.Now()
+	expiresAt := workloadTokenExpiresAt(grant)
+	token := &model.Token{
+		UserId:             grant.rule.UserId,
+		Name:               workloadTokenName(grant),
+		Status:             common.TokenStatusEnabled,
+		CreatedTime:        now.Unix(),
+		AccessedTime:       now.Unix(),
+		ExpiredTime:        expiresAt.Unix(),
+		UnlimitedQuota:     grant.rule.UnlimitedQuota,
+		ModelLimitsEnabled: len(grant.rule.ModelLimits) > 0,
+		ModelLimits:        strings.Join(grant.rule.ModelLimits, ","),
+		Group:              grant.rule.Group,
+		WorkloadIssuerId:   grant.issuer.Id,
+		WorkloadSubject:    workloadSubjectHash(grant.identity.Subject),
+		WorkloadDirect:     direct,
+	}
+	token.SetKey(key)
+	if err := model.CreateWorkloadToken(token, grant.rule.Quota); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func getWorkloadDirectJWTCache() *cachex.HybridCache[string] {
+	workloadDirectJWTCacheOnce.Do(func() {
+		workloadDirectJWTCache = cachex.NewHybridCache[string](cachex.HybridCacheConfig[string]{
+			Namespace: cachex.Namespace(workloadDirectJWTCacheNamespace),
+			Redis:     common.RDB,
+			RedisEnabled: func() bool {
+				return common.RedisEnabled && common.RDB != nil
+			},
+			RedisCodec: cachex.StringCodec{},
+			Memory: func() *hot.HotCache[string, string] {
+				return hot.NewHotCache[string, string](hot.LRU, workloadDirectJWTCacheCapacity).
+					WithTTL(time.Duration(oauth.MaxWorkloadTokenTTL) * time.Second).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return workloadDirectJWTCache
+}
+
+// workloadDirectJWTCacheKey 已验签的 JWT 按摘要缓存到其令牌的密钥摘要，缓存不保存 JWT 本身。
+func workloadDirectJWTCacheKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func workloadSubjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
+
+func workloadTokenName(grant *workloadGrant) string {
+	name := []rune(grant.issuer.Name + ":" + grant.identity.Subject)
+	if len(name) > workloadTokenNameMaxLength {
+		name = name[:workloadTokenNameMaxLength]
+	}
+	return string(name)
+}
+
+func recordWorkloadTokenAudit(grant *workloadGrant, token *model.Token, clientIp string, mode string) {
+	params := map[string]interface{}{
+		"issuer":    grant.issuer.Name,
+		"subject":   grant.identity.Subject,
+		"tokenName": token.Name,
+		"mode":      mode,
+	}
+	content := fmt.Sprintf("%s issued gateway key %s to workload %s (%s)",
+		grant.issuer.Name, token.Name, grant.identity.Subject, mode)
+	model.RecordOperationAuditLog(token.UserId, content, clientIp, "workload_identity.token_issue", params,
+		map[string]interface{}{"auth_method": "workload_identity", "issuer_id": grant.issuer.Id},
+		map[string]interface{}{"token_id": token.Id, "expired_time": token.ExpiredTime})
+}
@@ -21,6 +21,8 @@ type ClaudeSettings struct {
 	DefaultMaxTokens                      map[string]int                 `json:"default_max_tokens"`
 	ThinkingAdapterEnabled                bool                           `json:"thinking_adapter_enabled"`
 	ThinkingAdapterBudgetTokensPercentage float64                        `json:"thinking_adapter_budget_tokens_percentage"`
+	// BatchDiscountRatio 是 Message Batches 相对同步调用的计费折扣（0-1，默认 0.5）
+	BatchDiscountRatio float64 `json:"batch_discount_ratio"`
 }
 
 // 默认配置
@@ -31,6 +33,7 @@ var defaultClaudeSettings = ClaudeSettings{
 		"default": 8192,
 	},
 	ThinkingAdapterBudgetTokensPercentage: 0.8,
+	BatchDiscountRatio:                    0.5,
 }
 
 // 全局实例
@@ -50,6 +53,14 @@ func GetClaudeSettings() *ClaudeSettings {
 	return &claudeSettings
 }
 
+// GetBatchDiscountRatio 返回 Message Batches 的计费折扣，非法值回退到 0.5
+func (c *ClaudeSettings) GetBatchDiscountRatio() float64 {
+	if c.BatchDiscountRatio <= 0 || c.BatchDiscountRatio > 1 {
+		return 0.5
+	}
+	return c.BatchDiscountRatio
+}
+
 func (c *ClaudeSettings) WriteHeaders(originModel string, httpHeader *http.Header) {
 	if headers, ok := c.HeadersSettings[originModel]; ok {
 		for headerKey, headerValues := range headers {
@@ -0,0 +1,45 @@
+package model_setting
+
+import (
+	"github.com/QuantumNous/new-api/relaykit/dto"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ImagePreprocessSettings holds the global per-model image preprocessing
+// policies. The first enabled policy whose model patterns match wins; a
+// channel-level policy (ChannelSettings.ImagePreprocess) takes precedence.
+type ImagePreprocessSettings struct {
+	Policies []dto.ImagePreprocessPolicy `json:"policies"`
+	// MaxSourcePixels caps the declared width*height of an image that is
+	// decoded for preprocessing. Larger images are forwarded unchanged so a
+	// small file declaring a huge canvas cannot exhaust memory. Zero uses
+	// DefaultImagePreprocessMaxSourcePixels.
+	MaxSourcePixels int64 `json:"max_source_pixels"`
+}
+
+// DefaultImagePreprocessMaxSourcePixels is about 5000x5000; decoding it takes
+// roughly 100 MiB.
+const DefaultImagePreprocessMaxSourcePixels int64 = 25_000_000
+
+var defaultImagePreprocessSettings = ImagePreprocessSettings{
+	Policies:        []dto.ImagePreprocessPolicy{},
+	MaxSourcePixels: DefaultImagePreprocessMaxSourcePixels,
+}
+
+var imagePreprocessSettings = defaultImagePreprocessSettings
+
+func init() {
+	config.GlobalConfig.Register("image_preprocess", &imagePreprocessSettings)
+}
+
+func GetImagePreprocessSettings() *ImagePreprocessSettings {
+	return &imagePreprocessSettings
+}
+
+// GetMaxSourcePixels returns the effective source pixel limit.
+func (s *ImagePreprocessSettings) GetMaxSourcePixels() int64 {
+	if s.MaxSourcePixels <= 0 {
+		return DefaultImagePreprocessMaxSourcePixels
+	}
+	return s.MaxSourcePixels
+}
@@ -0,0 +1,62 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// AnomalySetting 用量异常检测与消费预测配置，依赖数据看板（DATA_EXPORT_ENABLED）的小时数据
+type AnomalySetting struct {
+	Enabled             bool    `json:"enabled"`               // 是否启用 usage_anomaly 任务
+	IntervalMinutes     int     `json:"interval_minutes"`      // 任务执行间隔
+	LookbackDays        int     `json:"lookback_days"`         // 基线使用的历史天数
+	MinActiveHours      int     `json:"min_active_hours"`      // 历史中有消耗的小时数不足时不评估，避免新用户误报
+	ZScoreThreshold     float64 `json:"z_score_threshold"`     // warning 阈值，high / critical 分别为其 2 倍和 3 倍
+	MinHourlyQuota      int     `json:"min_hourly_quota"`      // 单小时消耗低于该额度时不视为异常
+	NotifySeverity      string  `json:"notify_severity"`       // 达到该级别时通知用户，空表示不通知
+	AutoSuspendSeverity string  `json:"auto_suspend_severity"` // 达到该级别时自动停用令牌，空表示不停用
+}
+
+const (
+	DefaultAnomalyIntervalMinutes = 15
+	DefaultAnomalyLookbackDays    = 14
+	MinAnomalyLookbackDays        = 3
+	MaxAnomalyLookbackDays        = 60
+)
+
+// 默认配置
+var anomalySetting = AnomalySetting{
+	Enabled:             false,
+	IntervalMinutes:     DefaultAnomalyIntervalMinutes,
+	LookbackDays:        DefaultAnomalyLookbackDays,
+	MinActiveHours:      24,
+	ZScoreThreshold:     4,
+	MinHourlyQuota:      250000, // 约 0.5 USD
+	NotifySeverity:      "high",
+	AutoSuspendSeverity: "",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("anomaly_setting", &anomalySetting)
+}
+
+// GetAnomalySetting 获取异常检测配置，越界的数值会被修正为默认值或边界值
+func GetAnomalySetting() *AnomalySetting {
+	if anomalySetting.IntervalMinutes < 1 {
+		anomalySetting.IntervalMinutes = DefaultAnomalyIntervalMinutes
+	}
+	if anomalySetting.LookbackDays < MinAnomalyLookbackDays {
+		anomalySetting.LookbackDays = MinAnomalyLookbackDays
+	}
+	if anomalySetting.LookbackDays > MaxAnomalyLookbackDays {
+		anomalySetting.LookbackDays = MaxAnomalyLookbackDays
+	}
+	if anomalySetting.MinActiveHours < 0 {
+		anomalySetting.MinActiveHours = 0
+	}
+	if anomalySetting.ZScoreThreshold <= 0 {
+		anomalySetting.ZScoreThreshold = 4
+	}
+	if anomalySetting.MinHourlyQuota < 0 {
+		anomalySetting.MinHourlyQuota = 0
+	}
+	return &anomalySetting
+}
@@ -0,0 +1,81 @@
+package operation_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// TokenLeakSetting 令牌泄露检测与隔离配置。
+// 各阈值表示检测窗口内出现的数量达到该值即标记，0 表示不检测该信号。
+type TokenLeakSetting struct {
+	Enabled               bool     `json:"enabled"`                  // 是否在令牌鉴权时检测泄露迹象
+	WindowMinutes         int      `json:"window_minutes"`           // 检测窗口
+	DistinctIpThreshold   int      `json:"distinct_ip_threshold"`    // 窗口内不同来源 IP 数
+	NewNetworkThreshold   int      `json:"new_network_threshold"`    // 窗口内首次出现的网段数（IPv4 /24、IPv6 /48），用于近似 ASN
+	NewCountryThreshold   int      `json:"new_country_threshold"`    // 窗口内首次出现的国家数，取自 CountryHeader
+	NewUserAgentThreshold int      `json:"new_user_agent_threshold"` // 窗口内首次出现的 User-Agent 客户端数
+	NewOriginThreshold    int      `json:"new_origin_threshold"`     // 窗口内首次出现的 Origin 数
+	LearningHours         int      `json:"learning_hours"`           // 首次请求或所有者确认后的学习期，期间新特征只记录不计数
+	ProfileDays           int      `json:"profile_days"`             // 已知特征的保留天数
+	CountryHeader         string   `json:"country_header"`           // 由可信反向代理写入的国家代码请求头，为空时不检测国家
+	AutoQuarantine        bool     `json:"auto_quarantine"`          // 标记时自动隔离令牌
+	QuarantineModels      []string `json:"quarantine_models"`        // 隔离期间允许调用的模型，为空时拒绝全部请求
+	QuarantineRateLimit   int      `json:"quarantine_rate_limit"`    // 隔离期间每分钟请求数，0 表示不额外限速
+}
+
+const (
+	DefaultTokenLeakWindowMinutes = 10
+	DefaultTokenLeakLearningHours = 24
+	DefaultTokenLeakProfileDays   = 7
+)
+
+// 默认配置
+var tokenLeakSetting = TokenLeakSetting{
+	Enabled:               false,
+	WindowMinutes:         DefaultTokenLeakWindowMinutes,
+	DistinctIpThreshold:   30,
+	NewNetworkThreshold:   5,
+	NewCountryThreshold:   2,
+	NewUserAgentThreshold: 3,
+	NewOriginThreshold:    1,
+	LearningHours:         DefaultTokenLeakLearningHours,
+	ProfileDays:           DefaultTokenLeakProfileDays,
+	CountryHeader:         "CF-IPCountry",
+	AutoQuarantine:        false,
+	QuarantineModels:      []string{},
+	QuarantineRateLimit:   10,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("token_leak_setting", &tokenLeakSetting)
+}
+
+// GetTokenLeakSetting 获取令牌泄露检测配置，越界的数值会被修正为默认值
+func GetTokenLeakSetting() *TokenLeakSetting {
+	if tokenLeakSetting.WindowMinutes < 1 {
+		tokenLeakSetting.WindowMinutes = DefaultTokenLeakWindowMinutes
+	}
+	if tokenLeakSetting.LearningHours < 0 {
+		tokenLeakSetting.LearningHours = DefaultTokenLeakLearningHours
+	}
+	if tokenLeakSetting.ProfileDays < 1 {
+		tokenLeakSetting.ProfileDays = DefaultTokenLeakProfileDays
+	}
+	if tokenLeakSetting.QuarantineRateLimit < 0 {
+		tokenLeakSetting.QuarantineRateLimit = 0
+	}
+	return &tokenLeakSetting
+}
+
+// GetQuarantineModels 返回隔离期间允许调用的模型，忽略空白项
+func (s *TokenLeakSetting) GetQuarantineModels() []string {
+	models := make([]string, 0, len(s.QuarantineModels))
+	for _, model := range s.QuarantineModels {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
@@ -0,0 +1,61 @@
+package system_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// LDAPSettings 控制 LDAP / Active Directory 登录。
+// 登录时先用 BindDN 搜索用户条目，再以条目 DN 和用户密码重新绑定校验密码。
+// UserFilter 中的 %s 会被替换为转义后的登录名；GroupMapping 与 RoleMapping
+// 的键可以是组的完整 DN，也可以是组 DN 的首个 RDN 值（如 CN=Admins 中的 Admins）。
+type LDAPSettings struct {
+	Enabled              bool              `json:"enabled"`
+	DisplayName          string            `json:"display_name"`
+	ServerURL            string            `json:"server_url"`
+	StartTLS             bool              `json:"start_tls"`
+	InsecureSkipVerify   bool              `json:"insecure_skip_verify"`
+	BindDN               string            `json:"bind_dn"`
+	BindSecret           string            `json:"bind_secret"`
+	BaseDN               string            `json:"base_dn"`
+	UserFilter           string            `json:"user_filter"`
+	IdentityAttribute    string            `json:"identity_attribute"`
+	UsernameAttribute    string            `json:"username_attribute"`
+	EmailAttribute       string            `json:"email_attribute"`
+	DisplayNameAttribute string            `json:"display_name_attribute"`
+	GroupMembershipAttr  string            `json:"group_membership_attribute"`
+	GroupMapping         map[string]string `json:"group_mapping"`
+	RoleMapping          map[string]string `json:"role_mapping"`
+}
+
+// 默认配置，属性名默认按 OpenLDAP 取值；Active Directory 通常改为
+// (sAMAccountName=%s) / objectGUID / sAMAccountName / displayName。
+var defaultLDAPSettings = LDAPSettings{
+	UserFilter:           "(uid=%s)",
+	IdentityAttribute:    "entryUUID",
+	UsernameAttribute:    "uid",
+	EmailAttribute:       "mail",
+	DisplayNameAttribute: "cn",
+	GroupMembershipAttr:  "memberOf",
+	GroupMapping:         map[string]string{},
+	RoleMapping:          map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("ldap", &defaultLDAPSettings)
+}
+
+func GetLDAPSettings() *LDAPSettings {
+	return &defaultLDAPSettings
+}
+
+// GetEffectiveDisplayName returns the admin-configured display name, or
+// "LDAP" when none has been set.
+func (s *LDAPSettings) GetEffectiveDisplayName() string {
+	if trimmed := strings.TrimSpace(s.DisplayName); trimmed != "" {
+		return trimmed
+	}
+	return "LDAP"
+}
@@ -15,6 +15,9 @@ type OIDCSettings struct {
 	AuthorizationEndpoint string `json:"authorization_endpoint"`
 	TokenEndpoint         string `json:"token_endpoint"`
 	UserInfoEndpoint      string `json:"user_info_endpoint"`
+	// ClaimMapping 是 JSON 格式的 claim 映射规则（见 oauth.ClaimMapping），
+	// 登录时据此同步用户分组、可用分组与角色
+	ClaimMapping string `json:"claim_mapping"`
 }
 
 // 默认配置
@@ -0,0 +1,38 @@
+package system_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// SCIMSettings 控制 /scim/v2 用户与组开通接口。
+// GroupMapping 把 SCIM 组显示名映射为用户分组（未配置时同名分组直接生效），
+// RoleMapping 把 SCIM 组显示名映射为 authz 内置角色（仅支持 admin）。
+type SCIMSettings struct {
+	Enabled      bool              `json:"enabled"`
+	BearerSecret string            `json:"bearer_secret"`
+	GroupMapping map[string]string `json:"group_mapping"`
+	RoleMapping  map[string]string `json:"role_mapping"`
+}
+
+// 默认配置
+var defaultSCIMSettings = SCIMSettings{
+	GroupMapping: map[string]string{},
+	RoleMapping:  map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("scim", &defaultSCIMSettings)
+}
+
+func GetSCIMSettings() *SCIMSettings {
+	return &defaultSCIMSettings
+}
+
+// IsSCIMEnabled reports whether SCIM provisioning is switched on and has a
+// bearer secret configured; an empty secret never authenticates anything.
+func (s *SCIMSettings) IsSCIMEnabled() bool {
+	return s.Enabled && strings.TrimSpace(s.BearerSecret) != ""
+}
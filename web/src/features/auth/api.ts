@@ -54,6 +54,20 @@ export async function login(payload: LoginPayload) {
   return res.data
 }
 
+// Directory (LDAP / Active Directory) login with username and password
+export async function loginLdap(payload: LoginPayload) {
+  const turnstile = payload.turnstile ?? ''
+  const res = await api.post<LoginResponse>(
+    `/api/user/login/ldap?turnstile=${turnstile}`,
+    {
+      username: payload.username,
+      password: payload.password,
+    },
+    { skipAuthRefresh: true }
+  )
+  return res.data
+}
+
 // Two-factor authentication login
 export async function login2fa(payload: TwoFAPayload) {
   const res = await api.post<Login2FAResponse>('/api/user/login/2fa', payload, {
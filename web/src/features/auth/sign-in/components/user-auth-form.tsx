@@ -30,6 +30,7 @@ import { Dialog } from '@/components/dialog'
 import { PasswordInput } from '@/components/password-input'
 import { Turnstile } from '@/components/turnstile'
 import { Button } from '@/components/ui/button'
+import { Checkbox } from '@/components/ui/checkbox'
 import {
   Form,
   FormControl,
@@ -40,7 +41,7 @@ import {
 } from '@/components/ui/form'
 import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
-import { login, wechatLoginByCode } from '@/features/auth/api'
+import { login, loginLdap, wechatLoginByCode } from '@/features/auth/api'
 import { LegalConsent } from '@/features/auth/components/legal-consent'
 import { OAuthProviders } from '@/features/auth/components/oauth-providers'
 import { loginFormSchema } from '@/features/auth/constants'
@@ -73,6 +74,7 @@ export function UserAuthForm({
   const [isWeChatDialogOpen, setIsWeChatDialogOpen] = useState(false)
   const [isWeChatSubmitting, setIsWeChatSubmitting] = useState(false)
   const [turnstileWidgetKey, setTurnstileWidgetKey] = useState(0)
+  const [useLdapLogin, setUseLdapLogin] = useState(false)
   const legalConsentErrorMessage = t('Please agree to the legal terms first')
   const loginFailedMessage = t('Login failed')
 
@@ -84,6 +86,16 @@ export function UserAuthForm({
     (status?.password_login_enabled ??
       status?.data?.password_login_enabled ??
       true) !== false
+  const ldapLoginEnabled = Boolean(
+    status?.ldap_enabled ?? status?.data?.ldap_enabled
+  )
+  const ldapDisplayName =
+    (status?.ldap_display_name ?? status?.data?.ldap_display_name)?.trim() ||
+    'LDAP'
+  const credentialLoginEnabled = passwordLoginEnabled || ldapLoginEnabled
+  // Without password login the credential form can only target the directory
+  const isLdapLogin =
+    ldapLoginEnabled && (useLdapLogin || !passwordLoginEnabled)
   const {
     isTurnstileEnabled,
     turnstileSiteKey,
@@ -167,7 +179,7 @@ export function UserAuthForm({
 
     setIsLoading(true)
     try {
-      const res = await login({
+      const res = await (isLdapLogin ? loginLdap : login)({
         username: data.username,
         password: data.password,
         turnstile: submittedTurnstileToken,
@@ -357,7 +369,7 @@ export function UserAuthForm({
       >
         {hasAlternativeLogin && alternativeLoginMethods}
 
-        {passwordLoginEnabled && (
+        {credentialLoginEnabled && (
           <>
             {/* Username Field */}
             <FormField
@@ -365,10 +377,18 @@ export function UserAuthForm({
               name='username'
               render={({ field }) => (
                 <FormItem>
-                  <FormLabel>{t('Username or Email')}</FormLabel>
+                  <FormLabel>
+                    {isLdapLogin
+                      ? t('{{name}} username', { name: ldapDisplayName })
+                      : t('Username or Email')}
+                  </FormLabel>
                   <FormControl>
                     <Input
-                      placeholder={t('Enter your username or email')}
+                      placeholder={
+                        isLdapLogin
+                          ? t('Enter your directory username')
+                          : t('Enter your username or email')
+                      }
                       {...field}
                     />
                   </FormControl>
@@ -391,16 +411,38 @@ export function UserAuthForm({
                     />
                   </FormControl>
                   <FormMessage />
-                  <Link
-                    to='/forgot-password'
-                    className='text-muted-foreground absolute end-0 -top-0.5 z-10 text-sm font-medium hover:opacity-75'
-                  >
-                    {t('Forgot password?')}
-                  </Link>
+                  {!isLdapLogin && (
+                    <Link
+                      to='/forgot-password'
+                      className='text-muted-foreground absolute end-0 -top-0.5 z-10 text-sm font-medium hover:opacity-75'
+                    >
+                      {t('Forgot password?')}
+                    </Link>
+                  )}
                 </FormItem>
               )}
             />
 
+            {ldapLoginEnabled && passwordLoginEnabled && (
+              <div className='flex items-center gap-2'>
+                <Checkbox
+                  id='ldap-login'
+                  checked={useLdapLogin}
+                  onCheckedChange={(checked) =>
+                    setUseLdapLogin(checked === true)
+                  }
+                />
+                <Label
+                  htmlFor='ldap-login'
+                  className='text-muted-foreground text-sm font-normal'
+                >
+                  {t('Sign in with {{name}} account', {
+                    name: ldapDisplayName,
+                  })}
+                </Label>
+              </div>
+            )}
+
             {/* Submit Button */}
             <Button
               type='submit'
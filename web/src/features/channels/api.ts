@@ -26,6 +26,8 @@ import type {
   Channel,
   ChannelBalanceResponse,
   ChannelOpsResponse,
+  ChannelReplayParams,
+  ChannelReplayResponse,
   ChannelTestResponse,
   CopyChannelParams,
   CopyChannelResponse,
@@ -33,11 +35,19 @@ import type {
   GetChannelResponse,
   GetChannelsParams,
   GetChannelsResponse,
+  HedgePoliciesResponse,
+  HedgePolicyParams,
   MultiKeyManageParams,
   MultiKeyStatusResponse,
   SearchChannelsParams,
   SearchChannelsResponse,
+  ShadowRuleParams,
+  ShadowRulesResponse,
+  ShadowRuleSummaryResponse,
   TagOperationParams,
+  TrafficSplitRuleParams,
+  TrafficSplitRulesResponse,
+  TrafficSplitStatsResponse,
 } from './types'
 
 const channelActionConfig = (
@@ -221,6 +231,201 @@ export async function testChannel(
   return res.data
 }
 
+/**
+ * Replay a request against channels without billing
+ */
+export async function replayChannelRequest(
+  params: ChannelReplayParams
+): Promise<ChannelReplayResponse> {
+  const res = await api.post(
+    '/api/channel/replay',
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * List shadow traffic rules
+ */
+export async function getShadowRules(): Promise<ShadowRulesResponse> {
+  const res = await api.get('/api/channel/shadow_rules')
+  return res.data
+}
+
+/**
+ * Create a shadow traffic rule
+ */
+export async function createShadowRule(
+  params: ShadowRuleParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.post(
+    '/api/channel/shadow_rules',
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Update a shadow traffic rule, the used budget is kept
+ */
+export async function updateShadowRule(
+  id: number,
+  params: ShadowRuleParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.put(
+    `/api/channel/shadow_rules/${id}`,
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Delete a shadow traffic rule and its metrics
+ */
+export async function deleteShadowRule(
+  id: number
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.delete(`/api/channel/shadow_rules/${id}`)
+  return res.data
+}
+
+/**
+ * Reset the used budget of a shadow traffic rule
+ */
+export async function resetShadowRuleUsage(
+  id: number
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.post(`/api/channel/shadow_rules/${id}/reset_usage`)
+  return res.data
+}
+
+/**
+ * Get aggregated shadow metrics of a rule
+ */
+export async function getShadowRuleSummary(
+  id: number
+): Promise<ShadowRuleSummaryResponse> {
+  const res = await api.get(`/api/channel/shadow_rules/${id}/summary`)
+  return res.data
+}
+
+/**
+ * List traffic split (canary) rules
+ */
+export async function getTrafficSplitRules(): Promise<TrafficSplitRulesResponse> {
+  const res = await api.get('/api/channel/traffic_splits')
+  return res.data
+}
+
+/**
+ * Create a traffic split rule, the ramp starts immediately
+ */
+export async function createTrafficSplitRule(
+  params: TrafficSplitRuleParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.post(
+    '/api/channel/traffic_splits',
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Update a traffic split rule, the ramp progress is kept
+ */
+export async function updateTrafficSplitRule(
+  id: number,
+  params: TrafficSplitRuleParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.put(
+    `/api/channel/traffic_splits/${id}`,
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Delete a traffic split rule
+ */
+export async function deleteTrafficSplitRule(
+  id: number
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.delete(`/api/channel/traffic_splits/${id}`)
+  return res.data
+}
+
+/**
+ * Clear the rollback state and restart the ramp from the first step
+ */
+export async function restartTrafficSplitRule(
+  id: number
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.post(`/api/channel/traffic_splits/${id}/restart`)
+  return res.data
+}
+
+/**
+ * Get recent canary and baseline stats of a rule on the serving node
+ */
+export async function getTrafficSplitStats(
+  id: number
+): Promise<TrafficSplitStatsResponse> {
+  const res = await api.get(`/api/channel/traffic_splits/${id}/stats`)
+  return res.data
+}
+
+/**
+ * List hedged request policies
+ */
+export async function getHedgePolicies(): Promise<HedgePoliciesResponse> {
+  const res = await api.get('/api/channel/hedge_policies')
+  return res.data
+}
+
+/**
+ * Create a hedged request policy
+ */
+export async function createHedgePolicy(
+  params: HedgePolicyParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.post(
+    '/api/channel/hedge_policies',
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Update a hedged request policy
+ */
+export async function updateHedgePolicy(
+  id: number,
+  params: HedgePolicyParams
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.put(
+    `/api/channel/hedge_policies/${id}`,
+    params,
+    channelActionConfig()
+  )
+  return res.data
+}
+
+/**
+ * Delete a hedged request policy
+ */
+export async function deleteHedgePolicy(
+  id: number
+): Promise<{ success: boolean; message?: string }> {
+  const res = await api.delete(`/api/channel/hedge_policies/${id}`)
+  return res.data
+}
+
 /**
  * Update channel balance
  */
@@ -22,9 +22,13 @@ import { ChannelTestDialog } from './dialogs/channel-test-dialog'
 import { CopyChannelDialog } from './dialogs/copy-channel-dialog'
 import { EditTagDialog } from './dialogs/edit-tag-dialog'
 import { FetchModelsDialog } from './dialogs/fetch-models-dialog'
+import { HedgePoliciesDialog } from './dialogs/hedge-policies-dialog'
 import { MultiKeyManageDialog } from './dialogs/multi-key-manage-dialog'
 import { OllamaModelsDialog } from './dialogs/ollama-models-dialog'
+import { ReplayRequestDialog } from './dialogs/replay-request-dialog'
+import { ShadowRulesDialog } from './dialogs/shadow-rules-dialog'
 import { TagBatchEditDialog } from './dialogs/tag-batch-edit-dialog'
+import { TrafficSplitsDialog } from './dialogs/traffic-splits-dialog'
 import { UpstreamUpdateDialog } from './dialogs/upstream-update-dialog'
 import { ChannelMutateDrawer } from './drawers/channel-mutate-drawer'
 
@@ -46,6 +50,30 @@ export function ChannelsDialogs() {
         onOpenChange={(v) => !v && setOpen(null)}
       />
 
+      {/* Replay Request Dialog */}
+      <ReplayRequestDialog
+        open={open === 'replay-request'}
+        onOpenChange={(v) => !v && setOpen(null)}
+      />
+
+      {/* Shadow Traffic Rules Dialog */}
+      <ShadowRulesDialog
+        open={open === 'shadow-rules'}
+        onOpenChange={(v) => !v && setOpen(null)}
+      />
+
+      {/* Traffic Split (Canary) Rules Dialog */}
+      <TrafficSplitsDialog
+        open={open === 'traffic-splits'}
+        onOpenChange={(v) => !v && setOpen(null)}
+      />
+
+      {/* Hedged Request Policies Dialog */}
+      <HedgePoliciesDialog
+        open={open === 'hedge-policies'}
+        onOpenChange={(v) => !v && setOpen(null)}
+      />
+
       {/* Balance Query Dialog */}
       <BalanceQueryDialog
         open={open === 'balance-query'}
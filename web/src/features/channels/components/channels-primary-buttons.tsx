@@ -29,6 +29,9 @@ import {
   SortAsc,
   RefreshCw,
   ArrowUpFromLine,
+  Split,
+  GitFork,
+  Timer,
 } from 'lucide-react'
 import { useState } from 'react'
 import { useTranslation } from 'react-i18next'
@@ -253,6 +256,27 @@ export function ChannelsPrimaryButtons() {
 
             <DropdownMenuSeparator />
 
+            <DropdownMenuItem onClick={() => setOpen('shadow-rules')}>
+              {t('Shadow Traffic')}
+              <DropdownMenuShortcut>
+                <Split className='h-4 w-4' />
+              </DropdownMenuShortcut>
+            </DropdownMenuItem>
+
+            <DropdownMenuItem onClick={() => setOpen('traffic-splits')}>
+              {t('Canary Rollout')}
+              <DropdownMenuShortcut>
+                <GitFork className='h-4 w-4' />
+              </DropdownMenuShortcut>
+            </DropdownMenuItem>
+
+            <DropdownMenuItem onClick={() => setOpen('hedge-policies')}>
+              {t('Hedged Requests')}
+              <DropdownMenuShortcut>
+                <Timer className='h-4 w-4' />
+              </DropdownMenuShortcut>
+            </DropdownMenuItem>
+
             <DropdownMenuItem
               onSelect={(e) => {
                 e.preventDefault()
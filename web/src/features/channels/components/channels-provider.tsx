@@ -38,6 +38,10 @@ type DialogType =
   | 'create-channel'
   | 'update-channel'
   | 'test-channel'
+  | 'replay-request'
+  | 'shadow-rules'
+  | 'traffic-splits'
+  | 'hedge-policies'
   | 'balance-query'
   | 'fetch-models'
   | 'ollama-models'
@@ -33,6 +33,7 @@ import {
   Trash2,
   RefreshCw,
   Loader2,
+  Repeat,
 } from 'lucide-react'
 import { useContext, useState } from 'react'
 import { useTranslation } from 'react-i18next'
@@ -118,6 +119,11 @@ export function DataTableRowActions({ row }: DataTableRowActionsProps) {
     }
   }
 
+  const handleReplay = () => {
+    setCurrentRow(channel)
+    setOpen('replay-request')
+  }
+
   const handleQueryBalance = () => {
     setCurrentRow(channel)
     setOpen('balance-query')
@@ -281,6 +287,14 @@ export function DataTableRowActions({ row }: DataTableRowActionsProps) {
             </DropdownMenuShortcut>
           </DropdownMenuItem>
 
+          {/* Replay Request */}
+          <DropdownMenuItem onClick={handleReplay}>
+            {t('Replay Request')}
+            <DropdownMenuShortcut>
+              <Repeat size={16} />
+            </DropdownMenuShortcut>
+          </DropdownMenuItem>
+
           {/* Query Balance */}
           <DropdownMenuItem onClick={handleQueryBalance}>
             {t('Query Balance')}
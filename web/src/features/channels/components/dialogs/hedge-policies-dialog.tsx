@@ -0,0 +1,296 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { useQuery, useQueryClient } from '@tanstack/react-query'
+import { Loader2, Pencil, Trash2 } from 'lucide-react'
+import { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+
+import { Dialog } from '@/components/dialog'
+import { StatusBadge } from '@/components/status-badge'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Switch } from '@/components/ui/switch'
+
+import {
+  createHedgePolicy,
+  deleteHedgePolicy,
+  getHedgePolicies,
+  updateHedgePolicy,
+} from '../../api'
+import type { HedgePolicy, HedgePolicyParams } from '../../types'
+
+type HedgePoliciesDialogProps = {
+  open: boolean
+  onOpenChange: (open: boolean) => void
+}
+
+type HedgePolicyForm = {
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  delay_ms: string
+  fallback_delay_ms: string
+  loser_billing_user_id: string
+}
+
+const HEDGE_POLICIES_QUERY_KEY = ['channel-hedge-policies']
+
+const emptyForm: HedgePolicyForm = {
+  name: '',
+  enabled: true,
+  model_name: '',
+  group: '',
+  delay_ms: '0',
+  fallback_delay_ms: '2000',
+  loser_billing_user_id: '',
+}
+
+function policyToForm(policy: HedgePolicy): HedgePolicyForm {
+  return {
+    name: policy.name,
+    enabled: policy.enabled,
+    model_name: policy.model_name,
+    group: policy.group,
+    delay_ms: String(policy.delay_ms),
+    fallback_delay_ms: String(policy.fallback_delay_ms),
+    loser_billing_user_id: policy.loser_billing_user_id
+      ? String(policy.loser_billing_user_id)
+      : '',
+  }
+}
+
+function formToParams(form: HedgePolicyForm): HedgePolicyParams {
+  return {
+    name: form.name,
+    enabled: form.enabled,
+    model_name: form.model_name,
+    group: form.group,
+    delay_ms: Number(form.delay_ms),
+    fallback_delay_ms: Number(form.fallback_delay_ms),
+    loser_billing_user_id: Number(form.loser_billing_user_id),
+  }
+}
+
+export function HedgePoliciesDialog(props: HedgePoliciesDialogProps) {
+  const { t } = useTranslation()
+  const queryClient = useQueryClient()
+  const [form, setForm] = useState<HedgePolicyForm>(emptyForm)
+  const [editingId, setEditingId] = useState<number | null>(null)
+  const [isSaving, setIsSaving] = useState(false)
+  const { data, isLoading } = useQuery({
+    queryKey: HEDGE_POLICIES_QUERY_KEY,
+    queryFn: getHedgePolicies,
+    enabled: props.open,
+  })
+  const policies = data?.data ?? []
+
+  const refresh = () =>
+    queryClient.invalidateQueries({ queryKey: HEDGE_POLICIES_QUERY_KEY })
+
+  const resetForm = () => {
+    setForm(emptyForm)
+    setEditingId(null)
+  }
+
+  const handleSave = async () => {
+    setIsSaving(true)
+    try {
+      const params = formToParams(form)
+      const res = editingId
+        ? await updateHedgePolicy(editingId, params)
+        : await createHedgePolicy(params)
+      if (!res.success) {
+        toast.error(res.message || t('Save failed'))
+        return
+      }
+      toast.success(t('Saved successfully'))
+      resetForm()
+      refresh()
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleDelete = async (policy: HedgePolicy) => {
+    const res = await deleteHedgePolicy(policy.id)
+    if (res.success) {
+      if (editingId === policy.id) resetForm()
+      refresh()
+    }
+  }
+
+  const setField = <K extends keyof HedgePolicyForm>(
+    key: K,
+    value: HedgePolicyForm[K]
+  ) => setForm((prev) => ({ ...prev, [key]: value }))
+
+  return (
+    <Dialog
+      open={props.open}
+      onOpenChange={props.onOpenChange}
+      title={t('Hedged Requests')}
+      description={t(
+        'When the first channel has not sent a first byte within the delay, send the same request to a second channel, stream whichever responds first and cancel the other. Only the winner is billed to the user.'
+      )}
+      contentClassName='max-h-[90vh] overflow-hidden sm:max-w-4xl'
+      contentHeight='auto'
+      bodyClassName='space-y-4'
+    >
+      <div className='grid gap-3 rounded-lg border p-3 sm:grid-cols-4'>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-name'>{t('Name')}</Label>
+          <Input
+            id='hedge-name'
+            value={form.name}
+            onChange={(e) => setField('name', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-model'>{t('Model')}</Label>
+          <Input
+            id='hedge-model'
+            value={form.model_name}
+            onChange={(e) => setField('model_name', e.target.value)}
+            placeholder={t('All models')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-group'>{t('Group')}</Label>
+          <Input
+            id='hedge-group'
+            value={form.group}
+            onChange={(e) => setField('group', e.target.value)}
+            placeholder={t('All groups')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-delay'>{t('Hedge delay (ms)')}</Label>
+          <Input
+            id='hedge-delay'
+            type='number'
+            min={0}
+            value={form.delay_ms}
+            onChange={(e) => setField('delay_ms', e.target.value)}
+            placeholder={t('0 uses the observed p90')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-fallback'>{t('Fallback delay (ms)')}</Label>
+          <Input
+            id='hedge-fallback'
+            type='number'
+            min={0}
+            value={form.fallback_delay_ms}
+            onChange={(e) => setField('fallback_delay_ms', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='hedge-loser-user'>
+            {t('Bill loser usage to user ID')}
+          </Label>
+          <Input
+            id='hedge-loser-user'
+            type='number'
+            min={0}
+            value={form.loser_billing_user_id}
+            onChange={(e) => setField('loser_billing_user_id', e.target.value)}
+            placeholder={t('Not billed')}
+          />
+        </div>
+        <div className='flex items-center gap-2 pt-6'>
+          <Switch
+            id='hedge-enabled'
+            checked={form.enabled}
+            onCheckedChange={(v) => setField('enabled', v)}
+          />
+          <Label htmlFor='hedge-enabled'>{t('Enabled')}</Label>
+        </div>
+        <div className='flex justify-end gap-2 sm:col-span-4'>
+          {editingId && (
+            <Button variant='outline' onClick={resetForm}>
+              {t('Cancel')}
+            </Button>
+          )}
+          <Button onClick={handleSave} disabled={isSaving}>
+            {isSaving && <Loader2 className='mr-2 size-4 animate-spin' />}
+            {editingId ? t('Save') : t('Add Policy')}
+          </Button>
+        </div>
+      </div>
+
+      {isLoading && <Loader2 className='mx-auto size-5 animate-spin' />}
+      <div className='space-y-3'>
+        {policies.map((policy) => (
+          <div
+            key={policy.id}
+            className='flex flex-wrap items-center gap-2 rounded-lg border p-3'
+          >
+            <span className='font-medium'>{policy.name}</span>
+            <StatusBadge
+              label={policy.enabled ? t('Enabled') : t('Disabled')}
+              variant={policy.enabled ? 'success' : 'neutral'}
+              copyable={false}
+            />
+            <span className='text-muted-foreground text-xs'>
+              {[
+                policy.model_name || t('All models'),
+                policy.group || t('All groups'),
+                policy.delay_ms
+                  ? `${policy.delay_ms} ms`
+                  : t('Observed p90, fallback {{ms}} ms', {
+                      ms: policy.fallback_delay_ms,
+                    }),
+                policy.loser_billing_user_id
+                  ? t('Loser usage billed to user {{id}}', {
+                      id: policy.loser_billing_user_id,
+                    })
+                  : '',
+              ]
+                .filter(Boolean)
+                .join(' · ')}
+            </span>
+            <Button
+              variant='ghost'
+              size='icon'
+              className='ml-auto'
+              aria-label={t('Edit')}
+              onClick={() => {
+                setEditingId(policy.id)
+                setForm(policyToForm(policy))
+              }}
+            >
+              <Pencil className='size-4' />
+            </Button>
+            <Button
+              variant='ghost'
+              size='icon'
+              aria-label={t('Delete')}
+              onClick={() => handleDelete(policy)}
+            >
+              <Trash2 className='text-destructive size-4' />
+            </Button>
+          </div>
+        ))}
+      </div>
+    </Dialog>
+  )
+}
@@ -0,0 +1,258 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { Loader2, Repeat } from 'lucide-react'
+import { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+
+import {
+  CodeBlock,
+  CodeBlockCopyButton,
+} from '@/components/ai-elements/code-block'
+import { Dialog } from '@/components/dialog'
+import { StatusBadge } from '@/components/status-badge'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Textarea } from '@/components/ui/textarea'
+
+import { replayChannelRequest } from '../../api'
+import type { ChannelReplayResult } from '../../types'
+import { useChannels } from '../channels-provider'
+
+type ReplayRequestDialogProps = {
+  open: boolean
+  onOpenChange: (open: boolean) => void
+}
+
+const MAX_REPLAY_CHANNELS = 5
+
+function prettyJson(value: string) {
+  try {
+    return JSON.stringify(JSON.parse(value), null, 2)
+  } catch {
+    return value
+  }
+}
+
+function formatUpstreamRequest(result: ChannelReplayResult) {
+  const upstream = result.upstream
+  if (!upstream) return ''
+  const headers = Object.entries(upstream.request_headers ?? {})
+    .map(([name, value]) => `${name}: ${value}`)
+    .join('\n')
+  const requestBody = prettyJson(upstream.request_body)
+  return `${upstream.method} ${upstream.url}\n${headers}\n\n${requestBody}`
+}
+
+function ReplayResultCard({ result }: { result: ChannelReplayResult }) {
+  const { t } = useTranslation()
+  const statusCode = result.upstream?.status_code
+  const failed = Boolean(result.error)
+
+  return (
+    <div className='min-w-0 space-y-3 rounded-lg border p-3'>
+      <div className='flex flex-wrap items-center gap-2'>
+        <span className='font-medium'>
+          #{result.channel_id} {result.channel_name}
+        </span>
+        <StatusBadge
+          label={statusCode ? String(statusCode) : t('No response')}
+          variant={failed ? 'danger' : 'success'}
+          copyable={false}
+        />
+        {result.upstream_model && (
+          <StatusBadge
+            label={result.upstream_model}
+            variant='neutral'
+            copyable={false}
+          />
+        )}
+      </div>
+      <div className='text-muted-foreground text-xs'>
+        {t('Headers received in {{ms}} ms', { ms: result.header_latency_ms })}
+        {' · '}
+        {t('Total {{ms}} ms', { ms: result.latency_ms })}
+        {result.usage &&
+          ` · ${t('Prompt {{prompt}} / Completion {{completion}} tokens', {
+            prompt: result.usage.prompt_tokens,
+            completion: result.usage.completion_tokens,
+          })}`}
+      </div>
+      {result.error && (
+        <p className='text-destructive text-sm break-words'>{result.error}</p>
+      )}
+      {result.upstream && (
+        <CodeBlock
+          code={formatUpstreamRequest(result)}
+          language='http'
+          maxExpandedLines={16}
+          title={t('Upstream request')}
+        >
+          <CodeBlockCopyButton />
+        </CodeBlock>
+      )}
+      {result.upstream && (
+        <CodeBlock
+          code={prettyJson(result.upstream.response_body)}
+          language='json'
+          maxExpandedLines={16}
+          title={t('Raw upstream response')}
+        >
+          <CodeBlockCopyButton />
+        </CodeBlock>
+      )}
+      {result.response && (
+        <CodeBlock
+          code={prettyJson(result.response)}
+          language='json'
+          maxExpandedLines={16}
+          title={t('Response returned to the client')}
+        >
+          <CodeBlockCopyButton />
+        </CodeBlock>
+      )}
+    </div>
+  )
+}
+
+export function ReplayRequestDialog(props: ReplayRequestDialogProps) {
+  const { t } = useTranslation()
+  const { currentRow } = useChannels()
+  const [requestId, setRequestId] = useState('')
+  const [path, setPath] = useState('')
+  const [otherChannels, setOtherChannels] = useState('')
+  const [body, setBody] = useState('')
+  const [isReplaying, setIsReplaying] = useState(false)
+  const [results, setResults] = useState<ChannelReplayResult[]>([])
+
+  if (!currentRow) return null
+
+  const handleClose = () => {
+    setResults([])
+    props.onOpenChange(false)
+  }
+
+  const handleReplay = async () => {
+    const channelIds = [
+      currentRow.id,
+      ...otherChannels
+        .split(/[\s,]+/)
+        .map((id) => Number(id))
+        .filter((id) => Number.isInteger(id) && id > 0),
+    ]
+    setIsReplaying(true)
+    try {
+      const res = await replayChannelRequest({
+        request_id: requestId.trim() || undefined,
+        path: path.trim() || undefined,
+        body,
+        channel_ids: Array.from(new Set(channelIds)),
+      })
+      if (!res.success || !res.data) {
+        toast.error(res.message || t('Replay failed'))
+        return
+      }
+      setResults(res.data.results)
+    } catch (error: unknown) {
+      toast.error(error instanceof Error ? error.message : t('Replay failed'))
+    } finally {
+      setIsReplaying(false)
+    }
+  }
+
+  return (
+    <Dialog
+      open={props.open}
+      onOpenChange={handleClose}
+      title={t('Replay Request')}
+      description={t(
+        'Send a request through the normal conversion and override pipeline without billing. Channel keys are masked in the results.'
+      )}
+      contentClassName='max-h-[90vh] overflow-hidden sm:max-w-6xl'
+      contentHeight='auto'
+      bodyClassName='space-y-4'
+      footer={
+        <>
+          <Button variant='outline' onClick={handleClose}>
+            {t('Close')}
+          </Button>
+          <Button onClick={handleReplay} disabled={isReplaying}>
+            {isReplaying ? (
+              <Loader2 className='mr-2 size-4 animate-spin' />
+            ) : (
+              <Repeat className='mr-2 size-4' />
+            )}
+            {t('Replay')}
+          </Button>
+        </>
+      }
+    >
+      <div className='grid gap-4 sm:grid-cols-3'>
+        <div className='space-y-2'>
+          <Label htmlFor='replay-request-id'>{t('Request ID')}</Label>
+          <Input
+            id='replay-request-id'
+            value={requestId}
+            onChange={(e) => setRequestId(e.target.value)}
+            placeholder={t('Optional, fills path, model and group')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='replay-path'>{t('Request path')}</Label>
+          <Input
+            id='replay-path'
+            value={path}
+            onChange={(e) => setPath(e.target.value)}
+            placeholder='/v1/chat/completions'
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='replay-channels'>{t('Compare with channels')}</Label>
+          <Input
+            id='replay-channels'
+            value={otherChannels}
+            onChange={(e) => setOtherChannels(e.target.value)}
+            placeholder={t('Channel IDs, up to {{max}} in total', {
+              max: MAX_REPLAY_CHANNELS,
+            })}
+          />
+        </div>
+      </div>
+      <div className='space-y-2'>
+        <Label htmlFor='replay-body'>{t('Request body')}</Label>
+        <Textarea
+          id='replay-body'
+          rows={8}
+          className='font-mono text-xs'
+          value={body}
+          onChange={(e) => setBody(e.target.value)}
+          placeholder='{"model": "gpt-4o-mini", "messages": [...]}'
+        />
+      </div>
+      {results.length > 0 && (
+        <div className='grid gap-4 lg:grid-cols-2'>
+          {results.map((result) => (
+            <ReplayResultCard key={result.channel_id} result={result} />
+          ))}
+        </div>
+      )}
+    </Dialog>
+  )
+}
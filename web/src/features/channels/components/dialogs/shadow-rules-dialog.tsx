@@ -0,0 +1,369 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { useQuery, useQueryClient } from '@tanstack/react-query'
+import { Loader2, Pencil, RotateCcw, Trash2 } from 'lucide-react'
+import { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+
+import { Dialog } from '@/components/dialog'
+import { StatusBadge } from '@/components/status-badge'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Switch } from '@/components/ui/switch'
+import {
+  formatPercent,
+  formatQuota,
+  getEditableQuotaStep,
+  parseQuotaFromDollars,
+  quotaUnitsToEditableAmount,
+} from '@/lib/format'
+
+import {
+  createShadowRule,
+  deleteShadowRule,
+  getShadowRules,
+  getShadowRuleSummary,
+  resetShadowRuleUsage,
+  updateShadowRule,
+} from '../../api'
+import type { ShadowRule, ShadowRuleParams } from '../../types'
+
+type ShadowRulesDialogProps = {
+  open: boolean
+  onOpenChange: (open: boolean) => void
+}
+
+type ShadowRuleForm = {
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  shadow_channel_id: string
+  sample_rate: string
+  compare_response: boolean
+  budget: string
+}
+
+const SHADOW_RULES_QUERY_KEY = ['channel-shadow-rules']
+
+const emptyForm: ShadowRuleForm = {
+  name: '',
+  enabled: true,
+  model_name: '',
+  group: '',
+  shadow_channel_id: '',
+  sample_rate: '1',
+  compare_response: false,
+  budget: '',
+}
+
+function ruleToForm(rule: ShadowRule): ShadowRuleForm {
+  return {
+    name: rule.name,
+    enabled: rule.enabled,
+    model_name: rule.model_name,
+    group: rule.group,
+    shadow_channel_id: String(rule.shadow_channel_id),
+    sample_rate: String(rule.sample_rate),
+    compare_response: rule.compare_response,
+    budget: String(quotaUnitsToEditableAmount(rule.budget_quota)),
+  }
+}
+
+function formToParams(form: ShadowRuleForm): ShadowRuleParams {
+  return {
+    name: form.name,
+    enabled: form.enabled,
+    model_name: form.model_name,
+    group: form.group,
+    shadow_channel_id: Number(form.shadow_channel_id),
+    sample_rate: Number(form.sample_rate),
+    compare_response: form.compare_response,
+    budget_quota: parseQuotaFromDollars(Number(form.budget)),
+  }
+}
+
+function ShadowRuleSummaryLine({ rule }: { rule: ShadowRule }) {
+  const { t } = useTranslation()
+  const { data } = useQuery({
+    queryKey: [...SHADOW_RULES_QUERY_KEY, rule.id, 'summary'],
+    queryFn: () => getShadowRuleSummary(rule.id),
+  })
+  const summary = data?.data?.summary
+  if (!summary || summary.count === 0) {
+    return (
+      <p className='text-muted-foreground text-xs'>{t('No samples yet')}</p>
+    )
+  }
+
+  return (
+    <div className='text-muted-foreground grid gap-1 text-xs sm:grid-cols-2'>
+      <span>
+        {t('Samples {{count}}, shadow error rate {{rate}}', {
+          count: summary.count,
+          rate: formatPercent(summary.error_rate * 100),
+        })}
+      </span>
+      <span>
+        {t('Avg latency: primary {{primary}} ms / shadow {{shadow}} ms', {
+          primary: Math.round(summary.avg_primary_latency_ms),
+          shadow: Math.round(summary.avg_shadow_latency_ms),
+        })}
+      </span>
+      <span>
+        {t('Tokens: primary {{primary}} / shadow {{shadow}}', {
+          primary:
+            summary.primary_prompt_tokens + summary.primary_completion_tokens,
+          shadow:
+            summary.shadow_prompt_tokens + summary.shadow_completion_tokens,
+        })}
+      </span>
+      <span>
+        {summary.compared_count > 0
+          ? t('Avg similarity {{similarity}} over {{count}} responses', {
+              similarity: formatPercent(summary.avg_similarity * 100),
+              count: summary.compared_count,
+            })
+          : t('Response comparison off')}
+      </span>
+    </div>
+  )
+}
+
+export function ShadowRulesDialog(props: ShadowRulesDialogProps) {
+  const { t } = useTranslation()
+  const queryClient = useQueryClient()
+  const [form, setForm] = useState<ShadowRuleForm>(emptyForm)
+  const [editingId, setEditingId] = useState<number | null>(null)
+  const [isSaving, setIsSaving] = useState(false)
+  const { data, isLoading } = useQuery({
+    queryKey: SHADOW_RULES_QUERY_KEY,
+    queryFn: getShadowRules,
+    enabled: props.open,
+  })
+  const rules = data?.data ?? []
+
+  const refresh = () =>
+    queryClient.invalidateQueries({ queryKey: SHADOW_RULES_QUERY_KEY })
+
+  const resetForm = () => {
+    setForm(emptyForm)
+    setEditingId(null)
+  }
+
+  const handleSave = async () => {
+    setIsSaving(true)
+    try {
+      const params = formToParams(form)
+      const res = editingId
+        ? await updateShadowRule(editingId, params)
+        : await createShadowRule(params)
+      if (!res.success) {
+        toast.error(res.message || t('Save failed'))
+        return
+      }
+      toast.success(t('Saved successfully'))
+      resetForm()
+      refresh()
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleDelete = async (rule: ShadowRule) => {
+    const res = await deleteShadowRule(rule.id)
+    if (res.success) {
+      if (editingId === rule.id) resetForm()
+      refresh()
+    }
+  }
+
+  const handleResetUsage = async (rule: ShadowRule) => {
+    const res = await resetShadowRuleUsage(rule.id)
+    if (res.success) refresh()
+  }
+
+  const setField = <K extends keyof ShadowRuleForm>(
+    key: K,
+    value: ShadowRuleForm[K]
+  ) => setForm((prev) => ({ ...prev, [key]: value }))
+
+  return (
+    <Dialog
+      open={props.open}
+      onOpenChange={props.onOpenChange}
+      title={t('Shadow Traffic')}
+      description={t(
+        'Copy a sample of successful requests to a candidate channel after the response is served. Shadow requests never affect clients and are charged to the rule budget instead of users.'
+      )}
+      contentClassName='max-h-[90vh] overflow-hidden sm:max-w-4xl'
+      contentHeight='auto'
+      bodyClassName='space-y-4'
+    >
+      <div className='grid gap-3 rounded-lg border p-3 sm:grid-cols-4'>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-name'>{t('Name')}</Label>
+          <Input
+            id='shadow-name'
+            value={form.name}
+            onChange={(e) => setField('name', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-model'>{t('Model')}</Label>
+          <Input
+            id='shadow-model'
+            value={form.model_name}
+            onChange={(e) => setField('model_name', e.target.value)}
+            placeholder={t('All models')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-group'>{t('Group')}</Label>
+          <Input
+            id='shadow-group'
+            value={form.group}
+            onChange={(e) => setField('group', e.target.value)}
+            placeholder={t('All groups')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-channel'>{t('Shadow channel ID')}</Label>
+          <Input
+            id='shadow-channel'
+            type='number'
+            min={1}
+            value={form.shadow_channel_id}
+            onChange={(e) => setField('shadow_channel_id', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-rate'>{t('Sample rate (%)')}</Label>
+          <Input
+            id='shadow-rate'
+            type='number'
+            min={0}
+            max={100}
+            step={0.1}
+            value={form.sample_rate}
+            onChange={(e) => setField('sample_rate', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='shadow-budget'>{t('Budget')}</Label>
+          <Input
+            id='shadow-budget'
+            type='number'
+            min={0}
+            step={getEditableQuotaStep()}
+            value={form.budget}
+            onChange={(e) => setField('budget', e.target.value)}
+          />
+        </div>
+        <div className='flex items-center gap-2 pt-6'>
+          <Switch
+            id='shadow-compare'
+            checked={form.compare_response}
+            onCheckedChange={(v) => setField('compare_response', v)}
+          />
+          <Label htmlFor='shadow-compare'>{t('Compare responses')}</Label>
+        </div>
+        <div className='flex items-center gap-2 pt-6'>
+          <Switch
+            id='shadow-enabled'
+            checked={form.enabled}
+            onCheckedChange={(v) => setField('enabled', v)}
+          />
+          <Label htmlFor='shadow-enabled'>{t('Enabled')}</Label>
+        </div>
+        <div className='flex justify-end gap-2 sm:col-span-4'>
+          {editingId && (
+            <Button variant='outline' onClick={resetForm}>
+              {t('Cancel')}
+            </Button>
+          )}
+          <Button onClick={handleSave} disabled={isSaving}>
+            {isSaving && <Loader2 className='mr-2 size-4 animate-spin' />}
+            {editingId ? t('Save') : t('Add Rule')}
+          </Button>
+        </div>
+      </div>
+
+      {isLoading && <Loader2 className='mx-auto size-5 animate-spin' />}
+      <div className='space-y-3'>
+        {rules.map((rule) => (
+          <div key={rule.id} className='space-y-2 rounded-lg border p-3'>
+            <div className='flex flex-wrap items-center gap-2'>
+              <span className='font-medium'>{rule.name}</span>
+              <StatusBadge
+                label={rule.enabled ? t('Enabled') : t('Disabled')}
+                variant={rule.enabled ? 'success' : 'neutral'}
+                copyable={false}
+              />
+              <span className='text-muted-foreground text-xs'>
+                {[
+                  rule.model_name || t('All models'),
+                  rule.group || t('All groups'),
+                  `#${rule.shadow_channel_id}`,
+                  formatPercent(rule.sample_rate),
+                ].join(' · ')}
+              </span>
+              <span className='text-muted-foreground ml-auto text-xs'>
+                {t('Budget used {{used}} / {{budget}}', {
+                  used: formatQuota(rule.used_quota),
+                  budget: formatQuota(rule.budget_quota),
+                })}
+              </span>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Edit')}
+                onClick={() => {
+                  setEditingId(rule.id)
+                  setForm(ruleToForm(rule))
+                }}
+              >
+                <Pencil className='size-4' />
+              </Button>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Reset budget usage')}
+                onClick={() => handleResetUsage(rule)}
+              >
+                <RotateCcw className='size-4' />
+              </Button>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Delete')}
+                onClick={() => handleDelete(rule)}
+              >
+                <Trash2 className='text-destructive size-4' />
+              </Button>
+            </div>
+            <ShadowRuleSummaryLine rule={rule} />
+          </div>
+        ))}
+      </div>
+    </Dialog>
+  )
+}
@@ -0,0 +1,417 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { useQuery, useQueryClient } from '@tanstack/react-query'
+import { Loader2, Pencil, RotateCcw, Trash2 } from 'lucide-react'
+import { useState } from 'react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+
+import { Dialog } from '@/components/dialog'
+import { StatusBadge } from '@/components/status-badge'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Switch } from '@/components/ui/switch'
+import { formatPercent } from '@/lib/format'
+
+import {
+  createTrafficSplitRule,
+  deleteTrafficSplitRule,
+  getTrafficSplitRules,
+  getTrafficSplitStats,
+  restartTrafficSplitRule,
+  updateTrafficSplitRule,
+} from '../../api'
+import type { TrafficSplitRule, TrafficSplitRuleParams } from '../../types'
+
+type TrafficSplitsDialogProps = {
+  open: boolean
+  onOpenChange: (open: boolean) => void
+}
+
+type TrafficSplitForm = {
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  canary_channel_id: string
+  canary_model: string
+  hash_by_token: boolean
+  ramp_steps: string
+  ramp_interval_minutes: string
+  max_error_rate_increase: string
+  max_latency_increase: string
+  min_samples: string
+}
+
+const TRAFFIC_SPLITS_QUERY_KEY = ['channel-traffic-splits']
+
+const emptyForm: TrafficSplitForm = {
+  name: '',
+  enabled: true,
+  model_name: '',
+  group: '',
+  canary_channel_id: '',
+  canary_model: '',
+  hash_by_token: false,
+  ramp_steps: '5,25,50,100',
+  ramp_interval_minutes: '60',
+  max_error_rate_increase: '5',
+  max_latency_increase: '50',
+  min_samples: '20',
+}
+
+function ruleToForm(rule: TrafficSplitRule): TrafficSplitForm {
+  return {
+    name: rule.name,
+    enabled: rule.enabled,
+    model_name: rule.model_name,
+    group: rule.group,
+    canary_channel_id: rule.canary_channel_id
+      ? String(rule.canary_channel_id)
+      : '',
+    canary_model: rule.canary_model,
+    hash_by_token: rule.hash_key === 'token',
+    ramp_steps: rule.ramp_steps,
+    ramp_interval_minutes: String(rule.ramp_interval_minutes),
+    max_error_rate_increase: String(rule.max_error_rate_increase),
+    max_latency_increase: String(rule.max_latency_increase),
+    min_samples: String(rule.min_samples),
+  }
+}
+
+function formToParams(form: TrafficSplitForm): TrafficSplitRuleParams {
+  return {
+    name: form.name,
+    enabled: form.enabled,
+    model_name: form.model_name,
+    group: form.group,
+    canary_channel_id: Number(form.canary_channel_id),
+    canary_model: form.canary_model,
+    hash_key: form.hash_by_token ? 'token' : 'user',
+    ramp_steps: form.ramp_steps,
+    ramp_interval_minutes: Number(form.ramp_interval_minutes),
+    max_error_rate_increase: Number(form.max_error_rate_increase),
+    max_latency_increase: Number(form.max_latency_increase),
+    min_samples: Number(form.min_samples),
+  }
+}
+
+function TrafficSplitStatsLine({ rule }: { rule: TrafficSplitRule }) {
+  const { t } = useTranslation()
+  const { data } = useQuery({
+    queryKey: [...TRAFFIC_SPLITS_QUERY_KEY, rule.id, 'stats'],
+    queryFn: () => getTrafficSplitStats(rule.id),
+  })
+  const stats = data?.data
+  if (!stats) return null
+
+  return (
+    <div className='text-muted-foreground grid gap-1 text-xs sm:grid-cols-2'>
+      <span>
+        {t('Canary: {{count}} samples, errors {{rate}}, p95 {{p95}} ms', {
+          count: stats.canary.samples,
+          rate: formatPercent(stats.canary.error_rate),
+          p95: stats.canary.p95_latency_ms,
+        })}
+      </span>
+      <span>
+        {t('Baseline: {{count}} samples, errors {{rate}}, p95 {{p95}} ms', {
+          count: stats.baseline.samples,
+          rate: formatPercent(stats.baseline.error_rate),
+          p95: stats.baseline.p95_latency_ms,
+        })}
+      </span>
+    </div>
+  )
+}
+
+export function TrafficSplitsDialog(props: TrafficSplitsDialogProps) {
+  const { t } = useTranslation()
+  const queryClient = useQueryClient()
+  const [form, setForm] = useState<TrafficSplitForm>(emptyForm)
+  const [editingId, setEditingId] = useState<number | null>(null)
+  const [isSaving, setIsSaving] = useState(false)
+  const { data, isLoading } = useQuery({
+    queryKey: TRAFFIC_SPLITS_QUERY_KEY,
+    queryFn: getTrafficSplitRules,
+    enabled: props.open,
+  })
+  const rules = data?.data ?? []
+
+  const refresh = () =>
+    queryClient.invalidateQueries({ queryKey: TRAFFIC_SPLITS_QUERY_KEY })
+
+  const resetForm = () => {
+    setForm(emptyForm)
+    setEditingId(null)
+  }
+
+  const handleSave = async () => {
+    setIsSaving(true)
+    try {
+      const params = formToParams(form)
+      const res = editingId
+        ? await updateTrafficSplitRule(editingId, params)
+        : await createTrafficSplitRule(params)
+      if (!res.success) {
+        toast.error(res.message || t('Save failed'))
+        return
+      }
+      toast.success(t('Saved successfully'))
+      resetForm()
+      refresh()
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleDelete = async (rule: TrafficSplitRule) => {
+    const res = await deleteTrafficSplitRule(rule.id)
+    if (res.success) {
+      if (editingId === rule.id) resetForm()
+      refresh()
+    }
+  }
+
+  const handleRestart = async (rule: TrafficSplitRule) => {
+    const res = await restartTrafficSplitRule(rule.id)
+    if (res.success) refresh()
+  }
+
+  const setField = <K extends keyof TrafficSplitForm>(
+    key: K,
+    value: TrafficSplitForm[K]
+  ) => setForm((prev) => ({ ...prev, [key]: value }))
+
+  return (
+    <Dialog
+      open={props.open}
+      onOpenChange={props.onOpenChange}
+      title={t('Canary Rollout')}
+      description={t(
+        'Send a stable share of users or tokens to a canary channel or upstream model, ramp the share on a schedule, and roll back automatically when the canary error rate or p95 latency exceeds the model baseline.'
+      )}
+      contentClassName='max-h-[90vh] overflow-hidden sm:max-w-4xl'
+      contentHeight='auto'
+      bodyClassName='space-y-4'
+    >
+      <div className='grid gap-3 rounded-lg border p-3 sm:grid-cols-4'>
+        <div className='space-y-2'>
+          <Label htmlFor='split-name'>{t('Name')}</Label>
+          <Input
+            id='split-name'
+            value={form.name}
+            onChange={(e) => setField('name', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-model'>{t('Model')}</Label>
+          <Input
+            id='split-model'
+            value={form.model_name}
+            onChange={(e) => setField('model_name', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-group'>{t('Group')}</Label>
+          <Input
+            id='split-group'
+            value={form.group}
+            onChange={(e) => setField('group', e.target.value)}
+            placeholder={t('All groups')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-channel'>{t('Canary channel ID')}</Label>
+          <Input
+            id='split-channel'
+            type='number'
+            min={0}
+            value={form.canary_channel_id}
+            onChange={(e) => setField('canary_channel_id', e.target.value)}
+            placeholder={t('Normal routing')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-canary-model'>{t('Canary model')}</Label>
+          <Input
+            id='split-canary-model'
+            value={form.canary_model}
+            onChange={(e) => setField('canary_model', e.target.value)}
+            placeholder={t('Unchanged')}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-steps'>{t('Ramp steps (%)')}</Label>
+          <Input
+            id='split-steps'
+            value={form.ramp_steps}
+            onChange={(e) => setField('ramp_steps', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-interval'>{t('Step duration (minutes)')}</Label>
+          <Input
+            id='split-interval'
+            type='number'
+            min={0}
+            value={form.ramp_interval_minutes}
+            onChange={(e) => setField('ramp_interval_minutes', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-min-samples'>{t('Minimum samples')}</Label>
+          <Input
+            id='split-min-samples'
+            type='number'
+            min={1}
+            value={form.min_samples}
+            onChange={(e) => setField('min_samples', e.target.value)}
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-error-rate'>
+            {t('Max error rate increase (points)')}
+          </Label>
+          <Input
+            id='split-error-rate'
+            type='number'
+            min={0}
+            max={100}
+            step={0.1}
+            value={form.max_error_rate_increase}
+            onChange={(e) =>
+              setField('max_error_rate_increase', e.target.value)
+            }
+          />
+        </div>
+        <div className='space-y-2'>
+          <Label htmlFor='split-latency'>
+            {t('Max p95 latency increase (%)')}
+          </Label>
+          <Input
+            id='split-latency'
+            type='number'
+            min={0}
+            value={form.max_latency_increase}
+            onChange={(e) => setField('max_latency_increase', e.target.value)}
+          />
+        </div>
+        <div className='flex items-center gap-2 pt-6'>
+          <Switch
+            id='split-hash-token'
+            checked={form.hash_by_token}
+            onCheckedChange={(v) => setField('hash_by_token', v)}
+          />
+          <Label htmlFor='split-hash-token'>{t('Split by token')}</Label>
+        </div>
+        <div className='flex items-center gap-2 pt-6'>
+          <Switch
+            id='split-enabled'
+            checked={form.enabled}
+            onCheckedChange={(v) => setField('enabled', v)}
+          />
+          <Label htmlFor='split-enabled'>{t('Enabled')}</Label>
+        </div>
+        <div className='flex justify-end gap-2 sm:col-span-4'>
+          {editingId && (
+            <Button variant='outline' onClick={resetForm}>
+              {t('Cancel')}
+            </Button>
+          )}
+          <Button onClick={handleSave} disabled={isSaving}>
+            {isSaving && <Loader2 className='mr-2 size-4 animate-spin' />}
+            {editingId ? t('Save') : t('Add Rule')}
+          </Button>
+        </div>
+      </div>
+
+      {isLoading && <Loader2 className='mx-auto size-5 animate-spin' />}
+      <div className='space-y-3'>
+        {rules.map((rule) => (
+          <div key={rule.id} className='space-y-2 rounded-lg border p-3'>
+            <div className='flex flex-wrap items-center gap-2'>
+              <span className='font-medium'>{rule.name}</span>
+              <StatusBadge
+                label={rule.enabled ? t('Enabled') : t('Disabled')}
+                variant={rule.enabled ? 'success' : 'neutral'}
+                copyable={false}
+              />
+              {rule.status === 'rolled_back' && (
+                <StatusBadge
+                  label={t('Rolled back')}
+                  variant='danger'
+                  copyable={false}
+                />
+              )}
+              <span className='text-muted-foreground text-xs'>
+                {[
+                  rule.model_name,
+                  rule.group || t('All groups'),
+                  rule.canary_channel_id ? `#${rule.canary_channel_id}` : '',
+                  rule.canary_model,
+                  `${rule.ramp_steps}%`,
+                ]
+                  .filter(Boolean)
+                  .join(' · ')}
+              </span>
+              <span className='text-muted-foreground ml-auto text-xs'>
+                {t('Current share {{percent}}', {
+                  percent: formatPercent(rule.current_percent),
+                })}
+              </span>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Edit')}
+                onClick={() => {
+                  setEditingId(rule.id)
+                  setForm(ruleToForm(rule))
+                }}
+              >
+                <Pencil className='size-4' />
+              </Button>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Restart rollout')}
+                onClick={() => handleRestart(rule)}
+              >
+                <RotateCcw className='size-4' />
+              </Button>
+              <Button
+                variant='ghost'
+                size='icon'
+                aria-label={t('Delete')}
+                onClick={() => handleDelete(rule)}
+              >
+                <Trash2 className='text-destructive size-4' />
+              </Button>
+            </div>
+            {rule.rollback_reason && (
+              <p className='text-destructive text-xs'>{rule.rollback_reason}</p>
+            )}
+            <TrafficSplitStatsLine rule={rule} />
+          </div>
+        ))}
+      </div>
+    </Dialog>
+  )
+}
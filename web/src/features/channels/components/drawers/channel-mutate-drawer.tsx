@@ -284,6 +284,8 @@ const SENSITIVE_FORM_FIELDS = [
   'vertex_key_type',
   'aws_key_type',
   'azure_responses_version',
+  'batch_storage_uri',
+  'batch_role_arn',
   'force_format',
   'thinking_to_content',
   'proxy',
@@ -962,7 +964,9 @@ export function ChannelMutateDrawer({
     formErrors.key_mode ||
     formErrors.vertex_key_type ||
     formErrors.aws_key_type ||
-    formErrors.azure_responses_version
+    formErrors.azure_responses_version ||
+    formErrors.batch_storage_uri ||
+    formErrors.batch_role_arn
   )
   const modelsHaveErrors = Boolean(
     formErrors.models || formErrors.group || formErrors.model_mapping
@@ -2302,59 +2306,115 @@ export function ChannelMutateDrawer({
 
                             {/* AWS (type 33) */}
                             {currentType === 33 && (
-                              <FormField
-                                control={form.control}
-                                name='aws_key_type'
-                                render={({ field }) => (
-                                  <FormItem>
-                                    <FormLabel>{t('AWS Key Format')}</FormLabel>
-                                    <Select
-                                      items={[
-                                        {
-                                          value: 'ak_sk',
-                                          label: t(
-                                            'AccessKey / SecretAccessKey'
-                                          ),
-                                        },
-                                        {
-                                          value: 'api_key',
-                                          label: t('API Key'),
-                                        },
-                                      ]}
-                                      onValueChange={field.onChange}
-                                      value={field.value}
-                                    >
+                              <>
+                                <FormField
+                                  control={form.control}
+                                  name='aws_key_type'
+                                  render={({ field }) => (
+                                    <FormItem>
+                                      <FormLabel>
+                                        {t('AWS Key Format')}
+                                      </FormLabel>
+                                      <Select
+                                        items={[
+                                          {
+                                            value: 'ak_sk',
+                                            label: t(
+                                              'AccessKey / SecretAccessKey'
+                                            ),
+                                          },
+                                          {
+                                            value: 'api_key',
+                                            label: t('API Key'),
+                                          },
+                                        ]}
+                                        onValueChange={field.onChange}
+                                        value={field.value}
+                                      >
+                                        <FormControl>
+                                          <SelectTrigger>
+                                            <SelectValue
+                                              placeholder={t(
+                                                'Select key format'
+                                              )}
+                                            />
+                                          </SelectTrigger>
+                                        </FormControl>
+                                        <SelectContent
+                                          alignItemWithTrigger={false}
+                                        >
+                                          <SelectGroup>
+                                            <SelectItem value='ak_sk'>
+                                              {t('AccessKey / SecretAccessKey')}
+                                            </SelectItem>
+                                            <SelectItem value='api_key'>
+                                              {t('API Key')}
+                                            </SelectItem>
+                                          </SelectGroup>
+                                        </SelectContent>
+                                      </Select>
+                                      <FormDescription>
+                                        {field.value === 'api_key'
+                                          ? t('API Key mode: use APIKey|Region')
+                                          : t(
+                                              'AK/SK mode: use AccessKey|SecretAccessKey|Region'
+                                            )}
+                                      </FormDescription>
+                                      <FormMessage />
+                                    </FormItem>
+                                  )}
+                                />
+                                <FormField
+                                  control={form.control}
+                                  name='batch_storage_uri'
+                                  render={({ field }) => (
+                                    <FormItem>
+                                      <FormLabel>
+                                        {t('Message Batch Storage')}
+                                      </FormLabel>
                                       <FormControl>
-                                        <SelectTrigger>
-                                          <SelectValue
-                                            placeholder={t('Select key format')}
-                                          />
-                                        </SelectTrigger>
+                                        <Input
+                                          placeholder={t(
+                                            'e.g., s3://bucket/claude-batches'
+                                          )}
+                                          {...field}
+                                        />
                                       </FormControl>
-                                      <SelectContent
-                                        alignItemWithTrigger={false}
-                                      >
-                                        <SelectGroup>
-                                          <SelectItem value='ak_sk'>
-                                            {t('AccessKey / SecretAccessKey')}
-                                          </SelectItem>
-                                          <SelectItem value='api_key'>
-                                            {t('API Key')}
-                                          </SelectItem>
-                                        </SelectGroup>
-                                      </SelectContent>
-                                    </Select>
-                                    <FormDescription>
-                                      {field.value === 'api_key'
-                                        ? t('API Key mode: use APIKey|Region')
-                                        : t(
-                                            'AK/SK mode: use AccessKey|SecretAccessKey|Region'
+                                      <FormDescription>
+                                        {t(
+                                          'S3 location for Claude message batch input and output. Requires an AccessKey|SecretAccessKey|Region key.'
+                                        )}
+                                      </FormDescription>
+                                      <FormMessage />
+                                    </FormItem>
+                                  )}
+                                />
+                                <FormField
+                                  control={form.control}
+                                  name='batch_role_arn'
+                                  render={({ field }) => (
+                                    <FormItem>
+                                      <FormLabel>
+                                        {t('Message Batch Service Role ARN')}
+                                      </FormLabel>
+                                      <FormControl>
+                                        <Input
+                                          placeholder={t(
+                                            'e.g., arn:aws:iam::123456789012:role/BedrockBatch'
                                           )}
-                                    </FormDescription>
-                                    <FormMessage />
-                                  </FormItem>
-                                )}
-                              />
+                                          {...field}
+                                        />
+                                      </FormControl>
+                                      <FormDescription>
+                                        {t(
+                                          'Service role that Bedrock batch inference jobs use to read and write the S3 location'
+                                        )}
+                                      </FormDescription>
+                                      <FormMessage />
+                                    </FormItem>
+                                  )}
+                                />
+                              </>
                             )}
 
                             {/* AI Proxy Library (type 21) */}
@@ -2652,6 +2712,31 @@ export function ChannelMutateDrawer({
                                     </FormItem>
                                   )}
                                 />
+                                <FormField
+                                  control={form.control}
+                                  name='batch_storage_uri'
+                                  render={({ field }) => (
+                                    <FormItem>
+                                      <FormLabel>
+                                        {t('Message Batch Storage')}
+                                      </FormLabel>
+                                      <FormControl>
+                                        <Input
+                                          placeholder={t(
+                                            'e.g., gs://bucket/claude-batches'
+                                          )}
+                                          {...field}
+                                        />
+                                      </FormControl>
+                                      <FormDescription>
+                                        {t(
+                                          'Cloud Storage location for Claude message batch input and output. Requires a JSON key and a regional deployment.'
+                                        )}
+                                      </FormDescription>
+                                      <FormMessage />
+                                    </FormItem>
+                                  )}
+                                />
                               </>
                             )}
 
@@ -76,6 +76,16 @@ export const ADVANCED_CUSTOM_CONVERTER_OPTIONS: Array<{
     label: 'OpenAI Chat to Gemini Generate Content',
     triggerLabel: 'To Gemini Generate Content',
   },
+  {
+    value: 'openai_chat_completions_to_bedrock_converse',
+    label: 'OpenAI Chat to Bedrock Converse',
+    triggerLabel: 'To Bedrock Converse',
+  },
+  {
+    value: 'openai_chat_completions_to_cohere_chat',
+    label: 'OpenAI Chat to Cohere Chat',
+    triggerLabel: 'To Cohere Chat',
+  },
 ]
 
 export type AdvancedCustomAuthMode = 'default' | AdvancedCustomAuthType
@@ -198,6 +208,8 @@ const openAIChatPath = '/v1/chat/completions'
 const openAIResponsesPath = '/v1/responses'
 const claudeMessagesPath = '/v1/messages'
 const geminiGenerateContentPath = '/v1beta/models/{model}:generateContent'
+const bedrockConversePath = '/model/{model}/converse'
+const cohereChatPath = '/v2/chat'
 
 const bearerHeaderAuth = (): AdvancedCustomRouteAuth => ({
   type: 'header',
@@ -461,6 +473,12 @@ export function getAdvancedCustomConverterDefaults(
   ) {
     return { upstream_path: geminiGenerateContentPath, auth: geminiQueryAuth() }
   }
+  if (converter === 'openai_chat_completions_to_bedrock_converse') {
+    return { upstream_path: bedrockConversePath, auth: bearerHeaderAuth() }
+  }
+  if (converter === 'openai_chat_completions_to_cohere_chat') {
+    return { upstream_path: cohereChatPath, auth: bearerHeaderAuth() }
+  }
 
   return {
     upstream_path: normalizedIncomingPath || openAIChatPath,
@@ -906,7 +924,9 @@ function isConverterPathAllowed(
   if (
     converter === 'openai_chat_completions_to_anthropic_messages' ||
     converter === 'openai_chat_completions_to_openai_responses' ||
-    converter === 'openai_chat_completions_to_gemini_generate_content'
+    converter === 'openai_chat_completions_to_gemini_generate_content' ||
+    converter === 'openai_chat_completions_to_bedrock_converse' ||
+    converter === 'openai_chat_completions_to_cohere_chat'
   ) {
     return incomingPath === '/v1/chat/completions'
   }
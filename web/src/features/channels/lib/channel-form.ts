@@ -269,6 +269,8 @@ export const channelFormSchema = z
     vertex_key_type: z.enum(['json', 'api_key']).optional(), // Vertex AI specific
     aws_key_type: z.enum(['ak_sk', 'api_key']).optional(), // AWS specific
     azure_responses_version: z.string().optional(), // Azure specific
+    batch_storage_uri: z.string().optional(), // Vertex AI / AWS message batches
+    batch_role_arn: z.string().optional(), // AWS message batches
     // Field passthrough controls (stored in settings JSON)
     allow_service_tier: z.boolean().optional(), // OpenAI/Anthropic
     disable_store: z.boolean().optional(), // OpenAI only
@@ -441,6 +443,8 @@ export const CHANNEL_FORM_DEFAULT_VALUES: ChannelFormValues = {
   vertex_key_type: 'json',
   aws_key_type: 'ak_sk',
   azure_responses_version: '',
+  batch_storage_uri: '',
+  batch_role_arn: '',
   // Field passthrough controls
   allow_service_tier: false,
   disable_store: false,
@@ -506,6 +510,8 @@ export function transformChannelToFormDefaults(
   let azureResponsesVersion = ''
   let isEnterpriseAccount = false
   let awsKeyType: 'ak_sk' | 'api_key' = 'ak_sk'
+  let batchStorageUri = ''
+  let batchRoleArn = ''
   let allowServiceTier = false
   let disableStore = false
   let allowSafetyIdentifier = false
@@ -526,6 +532,8 @@ export function transformChannelToFormDefaults(
       azureResponsesVersion = parsed.azure_responses_version || ''
       isEnterpriseAccount = parsed.openrouter_enterprise === true
       awsKeyType = parsed.aws_key_type || 'ak_sk'
+      batchStorageUri = parsed.batch_storage_uri || ''
+      batchRoleArn = parsed.batch_role_arn || ''
       allowServiceTier = parsed.allow_service_tier === true
       disableStore = parsed.disable_store === true
       allowSafetyIdentifier = parsed.allow_safety_identifier === true
@@ -585,6 +593,8 @@ export function transformChannelToFormDefaults(
     vertex_key_type: vertexKeyType,
     azure_responses_version: azureResponsesVersion,
     aws_key_type: awsKeyType,
+    batch_storage_uri: batchStorageUri,
+    batch_role_arn: batchRoleArn,
     allow_service_tier: allowServiceTier,
     disable_store: disableStore,
     allow_include_obfuscation: allowIncludeObfuscation,
@@ -673,6 +683,20 @@ function buildSettingsJSON(formData: ChannelFormValues): string {
     delete settingsObj.aws_key_type
   }
 
+  // Add message batch storage for Vertex AI (type 41) and AWS (type 33)
+  const batchStorageUri = formData.batch_storage_uri?.trim()
+  if ((formData.type === 41 || formData.type === 33) && batchStorageUri) {
+    settingsObj.batch_storage_uri = batchStorageUri
+  } else if ('batch_storage_uri' in settingsObj) {
+    delete settingsObj.batch_storage_uri
+  }
+  const batchRoleArn = formData.batch_role_arn?.trim()
+  if (formData.type === 33 && batchRoleArn) {
+    settingsObj.batch_role_arn = batchRoleArn
+  } else if ('batch_role_arn' in settingsObj) {
+    delete settingsObj.batch_role_arn
+  }
+
   // Field passthrough controls:
   // - OpenAI, Anthropic, Codex, and New API: allow_service_tier
   // - OpenAI request fields: OpenAI, Codex, and New API
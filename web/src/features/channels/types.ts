@@ -95,6 +95,8 @@ export interface ChannelOtherSettings {
   vertex_key_type?: 'json' | 'api_key'
   openrouter_enterprise?: boolean
   aws_key_type?: 'ak_sk' | 'api_key'
+  batch_storage_uri?: string
+  batch_role_arn?: string
   allow_service_tier?: boolean
   disable_store?: boolean
   allow_safety_identifier?: boolean
@@ -138,6 +140,8 @@ export type AdvancedCustomConverter =
   | 'openai_responses_to_gemini_generate_content'
   | 'gemini_generate_content_to_openai_chat_completions'
   | 'openai_chat_completions_to_gemini_generate_content'
+  | 'openai_chat_completions_to_bedrock_converse'
+  | 'openai_chat_completions_to_cohere_chat'
 
 export type AdvancedCustomAuthType = 'none' | 'header' | 'query'
 
@@ -192,6 +196,187 @@ export interface ChannelTestResponse {
   }
 }
 
+export interface ChannelReplayParams {
+  request_id?: string
+  path?: string
+  model?: string
+  group?: string
+  body: string
+  headers?: Record<string, string>
+  channel_ids: number[]
+}
+
+export interface ChannelReplayUpstream {
+  method: string
+  url: string
+  request_headers?: Record<string, string>
+  request_body: string
+  status_code: number
+  response_headers?: Record<string, string>
+  response_body: string
+  truncated?: boolean
+}
+
+export interface ChannelReplayResult {
+  channel_id: number
+  channel_name: string
+  channel_type: number
+  upstream_model?: string
+  upstream?: ChannelReplayUpstream
+  response: string
+  header_latency_ms: number
+  latency_ms: number
+  usage?: {
+    prompt_tokens: number
+    completion_tokens: number
+    total_tokens: number
+  }
+  error?: string
+  error_code?: string
+}
+
+export interface ChannelReplayResponse {
+  success: boolean
+  message?: string
+  data?: {
+    path: string
+    model: string
+    group: string
+    results: ChannelReplayResult[]
+  }
+}
+
+export interface ShadowRule {
+  id: number
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  shadow_channel_id: number
+  sample_rate: number
+  compare_response: boolean
+  budget_quota: number
+  used_quota: number
+  created_at: number
+  updated_at: number
+}
+
+export type ShadowRuleParams = Omit<
+  ShadowRule,
+  'id' | 'used_quota' | 'created_at' | 'updated_at'
+>
+
+export interface ShadowMetricSummary {
+  count: number
+  shadow_errors: number
+  error_rate: number
+  avg_primary_latency_ms: number
+  avg_shadow_latency_ms: number
+  primary_prompt_tokens: number
+  primary_completion_tokens: number
+  shadow_prompt_tokens: number
+  shadow_completion_tokens: number
+  compared_count: number
+  avg_similarity: number
+  shadow_quota: number
+}
+
+export interface ShadowRulesResponse {
+  success: boolean
+  message?: string
+  data?: ShadowRule[]
+}
+
+export interface ShadowRuleSummaryResponse {
+  success: boolean
+  message?: string
+  data?: {
+    rule: ShadowRule
+    summary: ShadowMetricSummary
+  }
+}
+
+export interface TrafficSplitRule {
+  id: number
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  canary_channel_id: number
+  canary_model: string
+  hash_key: 'user' | 'token'
+  ramp_steps: string
+  ramp_interval_minutes: number
+  max_error_rate_increase: number
+  max_latency_increase: number
+  min_samples: number
+  status: 'active' | 'rolled_back'
+  rollback_reason: string
+  ramp_started_at: number
+  rolled_back_at: number
+  current_percent: number
+  created_at: number
+  updated_at: number
+}
+
+export type TrafficSplitRuleParams = Omit<
+  TrafficSplitRule,
+  | 'id'
+  | 'status'
+  | 'rollback_reason'
+  | 'ramp_started_at'
+  | 'rolled_back_at'
+  | 'current_percent'
+  | 'created_at'
+  | 'updated_at'
+>
+
+export interface TrafficSplitWindowStats {
+  samples: number
+  error_rate: number
+  p95_latency_ms: number
+}
+
+export interface TrafficSplitRulesResponse {
+  success: boolean
+  message?: string
+  data?: TrafficSplitRule[]
+}
+
+export interface TrafficSplitStatsResponse {
+  success: boolean
+  message?: string
+  data?: {
+    rule: TrafficSplitRule
+    canary: TrafficSplitWindowStats
+    baseline: TrafficSplitWindowStats
+  }
+}
+
+export interface HedgePolicy {
+  id: number
+  name: string
+  enabled: boolean
+  model_name: string
+  group: string
+  delay_ms: number
+  fallback_delay_ms: number
+  loser_billing_user_id: number
+  created_at: number
+  updated_at: number
+}
+
+export type HedgePolicyParams = Omit<
+  HedgePolicy,
+  'id' | 'created_at' | 'updated_at'
+>
+
+export interface HedgePoliciesResponse {
+  success: boolean
+  message?: string
+  data?: HedgePolicy[]
+}
+
 export interface ChannelBalanceResponse {
   success: boolean
   message?: string
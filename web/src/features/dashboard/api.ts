@@ -20,8 +20,12 @@ import { api } from '@/lib/api'
 
 import type {
   FlowQuotaDataItem,
+  PagedResponse,
   QuotaDataItem,
   UptimeGroupResult,
+  UsageAnomaly,
+  UsageAnomalyParams,
+  UsageForecast,
 } from './types'
 
 // ============================================================================
@@ -84,6 +88,42 @@ export async function getFlowQuotaDates(
   return res.data
 }
 
+// ----------------------------------------------------------------------------
+// Usage Anomalies & Forecasts
+// ----------------------------------------------------------------------------
+
+// Admin users may filter anomalies of any user by user_id.
+export async function getUsageAnomalies(
+  params: UsageAnomalyParams,
+  isAdmin = false
+) {
+  const endpoint = isAdmin ? '/api/data/anomaly' : '/api/data/anomaly/self'
+  const res = await api.get<PagedResponse<UsageAnomaly>>(endpoint, { params })
+  return res.data
+}
+
+// data is null until the first forecast of the month has been computed
+export async function getSelfUsageForecast() {
+  const res = await api.get<{
+    success: boolean
+    data: UsageForecast | null
+    message?: string
+  }>('/api/data/forecast/self')
+  return res.data
+}
+
+export async function getUsageForecasts(params: {
+  p?: number
+  page_size?: number
+  username?: string
+}) {
+  const res = await api.get<PagedResponse<UsageForecast>>(
+    '/api/data/forecast',
+    { params }
+  )
+  return res.data
+}
+
 // Get uptime monitoring status for all services
 export async function getUptimeStatus() {
   const res = await api.get<{ success: boolean; data: UptimeGroupResult[] }>(
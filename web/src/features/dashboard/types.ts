@@ -33,6 +33,61 @@ export interface QuotaDataItem {
   quota?: number
 }
 
+export type UsageAnomalySeverity = 'warning' | 'high' | 'critical'
+
+export interface UsageAnomaly {
+  id: number
+  user_id: number
+  // 0 means the anomaly covers all of the user's tokens
+  token_id: number
+  hour_start: number
+  username: string
+  token_name: string
+  quota: number
+  expected: number
+  std_dev: number
+  z_score: number
+  ratio: number
+  severity: UsageAnomalySeverity
+  action: '' | 'token_suspended'
+  created_at: number
+  updated_at: number
+}
+
+export interface UsageAnomalyParams {
+  p?: number
+  page_size?: number
+  user_id?: number
+  token_id?: number
+  severity?: UsageAnomalySeverity
+  start_timestamp?: number
+  end_timestamp?: number
+}
+
+export interface UsageForecast {
+  user_id: number
+  username: string
+  month_start: number
+  spent_to_date: number
+  daily_rate: number
+  projected_month_end: number
+  balance: number
+  // -1 when there has been no recent usage
+  days_until_exhausted: number
+  updated_at: number
+}
+
+export interface PagedResponse<T> {
+  success: boolean
+  message?: string
+  data?: {
+    items: T[]
+    total: number
+    page: number
+    page_size: number
+  }
+}
+
 export interface FlowQuotaDataItem {
   user_id?: number
   username?: string
@@ -116,6 +116,28 @@ export async function fetchTokenKey(
   return res.data
 }
 
+// Generate a new request signing secret; the previous one stops working
+export async function rotateTokenSigningSecret(
+  id: number
+): Promise<ApiResponse<{ signing_secret: string }>> {
+  const res = await api.post(`/api/token/${id}/signing_secret`)
+  return res.data
+}
+
+// Replace the key of a token and keep its settings; the old key stops working
+export async function rotateTokenKey(id: number): Promise<ApiResponse<ApiKey>> {
+  const res = await api.post(`/api/token/${id}/rotate`)
+  return res.data
+}
+
+// Confirm a token flagged as possibly leaked is safe and lift its quarantine
+export async function confirmTokenLeak(
+  id: number
+): Promise<ApiResponse<ApiKey>> {
+  const res = await api.post(`/api/token/${id}/leak/confirm`)
+  return res.data
+}
+
 // Batch fetch real (unmasked) keys for multiple tokens
 export async function fetchTokenKeysBatch(ids: number[]): Promise<{
   success: boolean
@@ -0,0 +1,95 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { useTranslation } from 'react-i18next'
+
+import { CopyButton } from '@/components/copy-button'
+import { Dialog } from '@/components/dialog'
+import { Button } from '@/components/ui/button'
+
+import { useApiKeys } from './api-keys-provider'
+
+export function ApiKeyCreatedDialog() {
+  const { t } = useTranslation()
+  const { open, setOpen, createdKeys } = useApiKeys()
+  const keyedCreated = createdKeys.filter((created) => created.key)
+  const allKeys = keyedCreated.map((created) => `sk-${created.key}`).join('\n')
+  const secretOnly = keyedCreated.length === 0
+
+  return (
+    <Dialog
+      open={open === 'created'}
+      onOpenChange={(isOpen) => !isOpen && setOpen(null)}
+      title={
+        secretOnly ? t('Save your signing secret') : t('Save your API Key')
+      }
+      description={
+        secretOnly
+          ? t(
+              'This is the only time the signing secret is shown. Copy it now and store it somewhere safe.'
+            )
+          : t(
+              'This is the only time the full key is shown. Copy it now and store it somewhere safe.'
+            )
+      }
+      contentClassName='sm:max-w-xl'
+      bodyClassName='space-y-2'
+      footer={
+        <>
+          {keyedCreated.length > 1 && (
+            <CopyButton value={allKeys} variant='outline' size='default'>
+              {t('Copy all')}
+            </CopyButton>
+          )}
+          <Button onClick={() => setOpen(null)}>{t('Done')}</Button>
+        </>
+      }
+    >
+      {createdKeys.map((created) => (
+        <div key={created.id} className='space-y-2'>
+          {created.key && (
+            <div className='flex items-center gap-2'>
+              <div className='min-w-0 flex-1'>
+                <div className='text-muted-foreground text-xs'>
+                  {created.name}
+                </div>
+                <code className='block font-mono text-sm break-all'>
+                  sk-{created.key}
+                </code>
+              </div>
+              <CopyButton value={`sk-${created.key}`} />
+            </div>
+          )}
+          {created.signing_secret && (
+            <div className='flex items-center gap-2'>
+              <div className='min-w-0 flex-1'>
+                <div className='text-muted-foreground text-xs'>
+                  {t('Signing secret for {{name}}', { name: created.name })}
+                </div>
+                <code className='block font-mono text-sm break-all'>
+                  {created.signing_secret}
+                </code>
+              </div>
+              <CopyButton value={created.signing_secret} />
+            </div>
+          )}
+        </div>
+      ))}
+    </Dialog>
+  )
+}
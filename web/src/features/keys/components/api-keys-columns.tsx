@@ -119,13 +119,29 @@ export function useApiKeysColumns(now: number): ColumnDef<ApiKey>[] {
       cell: ({ row }) => {
         const statusConfig = API_KEY_STATUSES[row.getValue('status') as number]
         if (!statusConfig) return null
+        const apiKey = row.original
         return (
-          <StatusBadge
-            label={t(statusConfig.label)}
-            variant={statusConfig.variant}
-            copyable={false}
-            className='-ml-1.5'
-          />
+          <div className='-ml-1.5 flex items-center gap-1'>
+            <StatusBadge
+              label={t(statusConfig.label)}
+              variant={statusConfig.variant}
+              copyable={false}
+            />
+            {!!apiKey.leak_flagged_at && (
+              <Tooltip>
+                <TooltipTrigger render={<span />}>
+                  <StatusBadge
+                    label={t(
+                      apiKey.quarantined ? 'Quarantined' : 'Possibly leaked'
+                    )}
+                    variant={apiKey.quarantined ? 'danger' : 'warning'}
+                    copyable={false}
+                  />
+                </TooltipTrigger>
+                <TooltipContent>{apiKey.leak_reason}</TooltipContent>
+              </Tooltip>
+            )}
+          </div>
         )
       },
       filterFn: (row, id, value) => value.includes(String(row.getValue(id))),
@@ -81,7 +81,7 @@ import {
   transformFormDataToPayload,
   transformApiKeyToFormDefaults,
 } from '../lib'
-import type { ApiKey } from '../types'
+import type { ApiKey, CreatedApiKey } from '../types'
 import {
   ApiKeyGroupCombobox,
   type ApiKeyGroupOption,
@@ -103,7 +103,7 @@ export function ApiKeysMutateDrawer({
   const { t } = useTranslation()
   const isUpdate = !!currentRow
   const currentRowId = currentRow?.id
-  const { triggerRefresh } = useApiKeys()
+  const { triggerRefresh, showCreatedKeys } = useApiKeys()
   const { status, loading: statusLoading } = useStatus()
   const [isSubmitting, setIsSubmitting] = useState(false)
   const [advancedOpen, setAdvancedOpen] = useState(false)
@@ -291,6 +291,16 @@ export function ApiKeysMutateDrawer({
           toast.success(t(SUCCESS_MESSAGES.API_KEY_UPDATED))
           onOpenChange(false)
           triggerRefresh()
+          if (result.data?.signing_secret) {
+            showCreatedKeys([
+              {
+                id: currentRow.id,
+                name: result.data.name,
+                key: '',
+                signing_secret: result.data.signing_secret,
+              },
+            ])
+          }
         } else {
           toast.error(result.message || t(ERROR_MESSAGES.UPDATE_FAILED))
         }
@@ -298,6 +308,7 @@ export function ApiKeysMutateDrawer({
         // Create mode - handle batch creation
         const count = data.tokenCount || 1
         let successCount = 0
+        const createdKeys: CreatedApiKey[] = []
 
         for (let i = 0; i < count; i++) {
           const result = await createApiKey({
@@ -309,6 +320,14 @@ export function ApiKeysMutateDrawer({
           })
           if (result.success) {
             successCount++
+            if (result.data?.key) {
+              createdKeys.push({
+                id: result.data.id,
+                name: result.data.name,
+                key: result.data.key,
+                signing_secret: result.data.signing_secret,
+              })
+            }
           } else {
             toast.error(result.message || t(ERROR_MESSAGES.CREATE_FAILED))
             break
@@ -323,6 +342,7 @@ export function ApiKeysMutateDrawer({
           )
           onOpenChange(false)
           triggerRefresh()
+          showCreatedKeys(createdKeys)
         }
       }
     } catch {
@@ -744,6 +764,183 @@ export function ApiKeysMutateDrawer({
                         </FormItem>
                       )}
                     />
+
+                    <FormField
+                      control={form.control}
+                      name='labels'
+                      render={({ field }) => (
+                        <FormItem>
+                          <FormLabel>{t('Cost Allocation Labels')}</FormLabel>
+                          <FormControl>
+                            <Textarea
+                              {...field}
+                              className='min-h-20 resize-none font-mono'
+                              placeholder={'team=search\nenv=prod'}
+                              rows={3}
+                            />
+                          </FormControl>
+                          <FormDescription>
+                            {t(
+                              'One key=value per line. Labels are copied into usage logs; requests can add more with the X-NewAPI-Tags header.'
+                            )}
+                          </FormDescription>
+                          <FormMessage />
+                        </FormItem>
+                      )}
+                    />
+
+                    <FormField
+                      control={form.control}
+                      name='end_user_header'
+                      render={({ field }) => (
+                        <FormItem>
+                          <FormLabel>{t('End User Header')}</FormLabel>
+                          <FormControl>
+                            <Input {...field} placeholder='X-End-User-Id' />
+                          </FormControl>
+                          <FormDescription>
+                            {t(
+                              'Request header carrying the end-user ID. When absent, the user or metadata.user_id body field is used.'
+                            )}
+                          </FormDescription>
+                          <FormMessage />
+                        </FormItem>
+                      )}
+                    />
+
+                    <div className='grid gap-4 sm:grid-cols-2'>
+                      <FormField
+                        control={form.control}
+                        name='end_user_rate_limit'
+                        render={({ field }) => (
+                          <FormItem>
+                            <FormLabel>
+                              {t('End User Rate Limit (requests/min)')}
+                            </FormLabel>
+                            <FormControl>
+                              <Input
+                                {...field}
+                                type='number'
+                                min='0'
+                                step={1}
+                                onChange={(e) =>
+                                  field.onChange(
+                                    Number.parseInt(e.target.value, 10) || 0
+                                  )
+                                }
+                              />
+                            </FormControl>
+                            <FormDescription>
+                              {t('0 means unlimited')}
+                            </FormDescription>
+                            <FormMessage />
+                          </FormItem>
+                        )}
+                      />
+
+                      <FormField
+                        control={form.control}
+                        name='end_user_daily_quota_dollars'
+                        render={({ field }) => (
+                          <FormItem>
+                            <FormLabel>
+                              {t('End User Daily Quota ({{currency}})', {
+                                currency: currencyLabel,
+                              })}
+                            </FormLabel>
+                            <FormControl>
+                              <Input
+                                {...field}
+                                type='number'
+                                min='0'
+                                step={tokensOnly ? 1 : 0.01}
+                                onChange={(e) =>
+                                  field.onChange(
+                                    Number.parseFloat(e.target.value) || 0
+                                  )
+                                }
+                              />
+                            </FormControl>
+                            <FormDescription>
+                              {t('0 means unlimited')}
+                            </FormDescription>
+                            <FormMessage />
+                          </FormItem>
+                        )}
+                      />
+                    </div>
+
+                    <FormField
+                      control={form.control}
+                      name='client_cert'
+                      render={({ field }) => (
+                        <FormItem>
+                          <FormLabel>{t('Client Certificate')}</FormLabel>
+                          <FormControl>
+                            <Input
+                              {...field}
+                              placeholder='san:spiffe://example.org/workload'
+                            />
+                          </FormControl>
+                          <FormDescription>
+                            {t(
+                              'Bind a verified mTLS client certificate by sha256:<fingerprint>, san:<value> or subject:<DN>. Requests presenting it authenticate without a key.'
+                            )}
+                          </FormDescription>
+                          <FormMessage />
+                        </FormItem>
+                      )}
+                    />
+
+                    <FormField
+                      control={form.control}
+                      name='client_cert_required'
+                      render={({ field }) => (
+                        <FormItem className={sideDrawerSwitchItemClassName()}>
+                          <div className='flex flex-col gap-0.5'>
+                            <FormLabel className='text-sm'>
+                              {t('Require client certificate')}
+                            </FormLabel>
+                            <FormDescription className='line-clamp-2 text-xs sm:line-clamp-none'>
+                              {t(
+                                'Reject requests using this key unless the bound client certificate is presented.'
+                              )}
+                            </FormDescription>
+                          </div>
+                          <FormControl>
+                            <Switch
+                              checked={!!field.value}
+                              onCheckedChange={field.onChange}
+                            />
+                          </FormControl>
+                        </FormItem>
+                      )}
+                    />
+
+                    <FormField
+                      control={form.control}
+                      name='signing_required'
+                      render={({ field }) => (
+                        <FormItem className={sideDrawerSwitchItemClassName()}>
+                          <div className='flex flex-col gap-0.5'>
+                            <FormLabel className='text-sm'>
+                              {t('Require request signing')}
+                            </FormLabel>
+                            <FormDescription className='line-clamp-2 text-xs sm:line-clamp-none'>
+                              {t(
+                                'Requests must carry an HMAC signature made with the signing secret. The secret is shown once when first enabled.'
+                              )}
+                            </FormDescription>
+                          </div>
+                          <FormControl>
+                            <Switch
+                              checked={!!field.value}
+                              onCheckedChange={field.onChange}
+                            />
+                          </FormControl>
+                        </FormItem>
+                      )}
+                    />
                   </div>
                 </CollapsibleContent>
               </SideDrawerSection>
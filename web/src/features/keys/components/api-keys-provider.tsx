@@ -24,7 +24,11 @@ import useDialogState from '@/hooks/use-dialog'
 
 import { fetchTokenKey, fetchTokenKeysBatch } from '../api'
 import { ERROR_MESSAGES } from '../constants'
-import { type ApiKey, type ApiKeysDialogType } from '../types'
+import {
+  type ApiKey,
+  type ApiKeysDialogType,
+  type CreatedApiKey,
+} from '../types'
 
 type ApiKeysContextType = {
   open: ApiKeysDialogType | null
@@ -41,6 +45,8 @@ type ApiKeysContextType = {
   loadingKeys: Record<number, boolean>
   copiedKeyId: number | null
   markKeyCopied: (id: number) => void
+  createdKeys: CreatedApiKey[]
+  showCreatedKeys: (keys: CreatedApiKey[]) => void
 }
 
 const ApiKeysContext = React.createContext<ApiKeysContextType | null>(null)
@@ -56,6 +62,8 @@ export function ApiKeysProvider({ children }: { children: React.ReactNode }) {
   const [loadingKeys, setLoadingKeys] = useState<Record<number, boolean>>({})
   const pendingRequests = useRef<Record<number, Promise<string | null>>>({})
 
+  const [createdKeys, setCreatedKeys] = useState<CreatedApiKey[]>([])
+
   const [copiedKeyId, setCopiedKeyId] = useState<number | null>(null)
   const copiedTimerRef = useRef<ReturnType<typeof setTimeout>>(undefined)
 
@@ -73,6 +81,24 @@ export function ApiKeysProvider({ children }: { children: React.ReactNode }) {
     setRefreshTrigger((prev) => prev + 1)
   }, [])
 
+  // Full keys are only returned at creation: keep them for copy actions in
+  // this session and show them once.
+  const showCreatedKeys = useCallback(
+    (keys: CreatedApiKey[]) => {
+      if (keys.length === 0) return
+      setResolvedKeys((prev) => {
+        const next = { ...prev }
+        for (const created of keys) {
+          if (created.key) next[created.id] = `sk-${created.key}`
+        }
+        return next
+      })
+      setCreatedKeys(keys)
+      setOpen('created')
+    },
+    [setOpen]
+  )
+
   const resolveRealKey = useCallback(
     async (id: number): Promise<string | null> => {
       if (resolvedKeys[id]) return resolvedKeys[id]
@@ -171,6 +197,8 @@ export function ApiKeysProvider({ children }: { children: React.ReactNode }) {
         loadingKeys,
         copiedKeyId,
         markKeyCopied,
+        createdKeys,
+        showCreatedKeys,
       }}
     >
       {children}
@@ -25,8 +25,11 @@ import {
   ExternalLink,
   ArrowRightLeft,
   Copy,
+  KeyRound,
   Link,
   Loader2,
+  RefreshCw,
+  ShieldCheck,
 } from 'lucide-react'
 import { useCallback, useState } from 'react'
 import { useTranslation } from 'react-i18next'
@@ -53,7 +56,12 @@ import { sendToFluent } from '@/features/chat/lib/send-to-fluent'
 import { encodeChannelConnectionInfo } from '@/lib/channel-connection-info'
 import { copyToClipboard } from '@/lib/copy-to-clipboard'
 
-import { updateApiKeyStatus } from '../api'
+import {
+  confirmTokenLeak,
+  rotateTokenKey,
+  rotateTokenSigningSecret,
+  updateApiKeyStatus,
+} from '../api'
 import { API_KEY_STATUS, ERROR_MESSAGES, SUCCESS_MESSAGES } from '../constants'
 import { apiKeySchema } from '../types'
 import { useApiKeys } from './api-keys-provider'
@@ -85,6 +93,7 @@ export function DataTableRowActions<TData>({
     setCurrentRow,
     triggerRefresh,
     setResolvedKey,
+    showCreatedKeys,
     resolveRealKey,
     resolvedKeys,
     loadingKeys,
@@ -182,6 +191,56 @@ export function DataTableRowActions<TData>({
     }
   }
 
+  const handleRotateSigningSecret = async () => {
+    try {
+      const result = await rotateTokenSigningSecret(apiKey.id)
+      if (result.success && result.data?.signing_secret) {
+        showCreatedKeys([
+          {
+            id: apiKey.id,
+            name: apiKey.name,
+            key: '',
+            signing_secret: result.data.signing_secret,
+          },
+        ])
+      } else {
+        toast.error(result.message || t(ERROR_MESSAGES.UPDATE_FAILED))
+      }
+    } catch {
+      toast.error(t(ERROR_MESSAGES.UNEXPECTED))
+    }
+  }
+
+  const handleRotateKey = async () => {
+    try {
+      const result = await rotateTokenKey(apiKey.id)
+      if (result.success && result.data?.key) {
+        showCreatedKeys([
+          { id: apiKey.id, name: apiKey.name, key: result.data.key },
+        ])
+        triggerRefresh()
+      } else {
+        toast.error(result.message || t(ERROR_MESSAGES.UPDATE_FAILED))
+      }
+    } catch {
+      toast.error(t(ERROR_MESSAGES.UNEXPECTED))
+    }
+  }
+
+  const handleConfirmLeak = async () => {
+    try {
+      const result = await confirmTokenLeak(apiKey.id)
+      if (result.success) {
+        toast.success(t(SUCCESS_MESSAGES.API_KEY_LEAK_CONFIRMED))
+        triggerRefresh()
+      } else {
+        toast.error(result.message || t(ERROR_MESSAGES.UPDATE_FAILED))
+      }
+    } catch {
+      toast.error(t(ERROR_MESSAGES.UNEXPECTED))
+    }
+  }
+
   let statusIcon = <Power className='size-4' />
   if (isTogglingStatus) {
     statusIcon = <Loader2 className='size-4 animate-spin' />
@@ -268,6 +327,28 @@ export function DataTableRowActions<TData>({
             <Link size={16} />
           </DropdownMenuShortcut>
         </DropdownMenuItem>
+        <DropdownMenuItem onClick={handleRotateKey}>
+          {t('Rotate Key')}
+          <DropdownMenuShortcut>
+            <RefreshCw size={16} />
+          </DropdownMenuShortcut>
+        </DropdownMenuItem>
+        {!!apiKey.leak_flagged_at && (
+          <DropdownMenuItem onClick={handleConfirmLeak}>
+            {t('Mark as Safe')}
+            <DropdownMenuShortcut>
+              <ShieldCheck size={16} />
+            </DropdownMenuShortcut>
+          </DropdownMenuItem>
+        )}
+        {apiKey.signing_required && (
+          <DropdownMenuItem onClick={handleRotateSigningSecret}>
+            {t('Rotate Signing Secret')}
+            <DropdownMenuShortcut>
+              <KeyRound size={16} />
+            </DropdownMenuShortcut>
+          </DropdownMenuItem>
+        )}
         <DropdownMenuSeparator />
         <DropdownMenuItem
           onClick={async () => {
@@ -97,4 +97,5 @@ export const SUCCESS_MESSAGES = {
   API_KEY_DELETED: 'API Key deleted successfully',
   API_KEY_ENABLED: 'API Key enabled successfully',
   API_KEY_DISABLED: 'API Key disabled successfully',
+  API_KEY_LEAK_CONFIRMED: 'API Key marked as safe',
 } as const
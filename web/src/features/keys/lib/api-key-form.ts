@@ -24,6 +24,40 @@ import { parseQuotaFromDollars, quotaUnitsToDollars } from '@/lib/format'
 import { DEFAULT_GROUP } from '../constants'
 import type { ApiKey, ApiKeyFormData } from '../types'
 
+// ============================================================================
+// Labels
+// ============================================================================
+
+const MAX_TOKEN_LABELS = 10
+const LABEL_KEY_PATTERN = /^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$/
+
+/**
+ * Parse "key=value" lines into a label map. Returns null when a line is
+ * malformed so the form can report it.
+ */
+export function parseLabelsText(text: string): Record<string, string> | null {
+  const labels: Record<string, string> = {}
+  for (const rawLine of text.split('\n')) {
+    const line = rawLine.trim()
+    if (!line) continue
+    const index = line.indexOf('=')
+    if (index <= 0) return null
+    const key = line.slice(0, index).trim()
+    const value = line.slice(index + 1).trim()
+    if (!LABEL_KEY_PATTERN.test(key) || !value || /[,=]/.test(value)) {
+      return null
+    }
+    labels[key] = value
+  }
+  return labels
+}
+
+export function formatLabelsText(labels?: Record<string, string> | null) {
+  return Object.entries(labels ?? {})
+    .map(([key, value]) => `${key}=${value}`)
+    .join('\n')
+}
+
 // ============================================================================
 // Form Schema
 // ============================================================================
@@ -44,6 +78,16 @@ export function getApiKeyFormSchema(t: TFunction, maxAutoGroups = 5) {
       auto_groups_mode: z.enum(['inherit', 'custom']),
       auto_groups: z.array(z.string()),
       cross_group_retry: z.boolean().optional(),
+      end_user_header: z
+        .string()
+        .regex(/^[A-Za-z0-9_-]*$/, t('Invalid header name'))
+        .optional(),
+      end_user_rate_limit: z.number().int().min(0).optional(),
+      end_user_daily_quota_dollars: z.number().min(0).optional(),
+      client_cert: z.string().optional(),
+      client_cert_required: z.boolean().optional(),
+      signing_required: z.boolean().optional(),
+      labels: z.string().optional(),
       tokenCount: z.number().min(1).optional(),
     })
     .superRefine((data, ctx) => {
@@ -80,6 +124,31 @@ export function getApiKeyFormSchema(t: TFunction, maxAutoGroups = 5) {
         }
       }
 
+      if (data.client_cert_required && !data.client_cert?.trim()) {
+        ctx.addIssue({
+          code: 'custom',
+          path: ['client_cert'],
+          message: t('Enter the client certificate to require'),
+        })
+      }
+
+      const labels = parseLabelsText(data.labels ?? '')
+      if (!labels) {
+        ctx.addIssue({
+          code: 'custom',
+          path: ['labels'],
+          message: t('Enter one key=value label per line'),
+        })
+      } else if (Object.keys(labels).length > MAX_TOKEN_LABELS) {
+        ctx.addIssue({
+          code: 'custom',
+          path: ['labels'],
+          message: t('At most {{max}} labels are allowed', {
+            max: MAX_TOKEN_LABELS,
+          }),
+        })
+      }
+
       if (data.unlimited_quota) {
         return
       }
@@ -114,6 +183,13 @@ export const API_KEY_FORM_DEFAULT_VALUES: ApiKeyFormValues = {
   auto_groups_mode: 'inherit',
   auto_groups: [],
   cross_group_retry: true,
+  end_user_header: '',
+  end_user_rate_limit: 0,
+  end_user_daily_quota_dollars: 0,
+  client_cert: '',
+  client_cert_required: false,
+  signing_required: false,
+  labels: '',
   tokenCount: 1,
 }
 
@@ -157,6 +233,15 @@ export function transformFormDataToPayload(
         ? data.auto_groups
         : [],
     cross_group_retry: data.group === 'auto' ? !!data.cross_group_retry : false,
+    end_user_header: data.end_user_header?.trim() || '',
+    end_user_rate_limit: data.end_user_rate_limit || 0,
+    end_user_daily_quota: parseQuotaFromDollars(
+      data.end_user_daily_quota_dollars || 0
+    ),
+    client_cert: data.client_cert?.trim() || '',
+    client_cert_required: !!data.client_cert_required,
+    signing_required: !!data.signing_required,
+    labels: parseLabelsText(data.labels ?? '') ?? {},
   }
 }
 
@@ -193,6 +278,15 @@ export function transformApiKeyToFormDefaults(
     auto_groups_mode: autoGroupsMode,
     auto_groups: autoGroups,
     cross_group_retry: !!apiKey.cross_group_retry,
+    end_user_header: apiKey.end_user_header || '',
+    end_user_rate_limit: apiKey.end_user_rate_limit || 0,
+    end_user_daily_quota_dollars: quotaUnitsToDollars(
+      apiKey.end_user_daily_quota || 0
+    ),
+    client_cert: apiKey.client_cert || '',
+    client_cert_required: !!apiKey.client_cert_required,
+    signing_required: !!apiKey.signing_required,
+    labels: formatLabelsText(apiKey.labels),
     tokenCount: 1,
   }
 }
@@ -46,6 +46,19 @@ export const apiKeySchema = z.object({
   model_limits_enabled: z.boolean(),
   model_limits: z.string().nullish().default(''),
   allow_ips: z.string().nullish().default(''),
+  end_user_header: z.string().nullish(),
+  end_user_rate_limit: z.number().optional(),
+  end_user_daily_quota: z.number().optional(),
+  client_cert: z.string().nullish(),
+  client_cert_required: z.boolean().optional(),
+  signing_required: z.boolean().optional(),
+  labels: z.record(z.string(), z.string()).nullish(),
+  leak_flagged_at: z.number().optional(),
+  leak_reason: z.string().nullish(),
+  quarantined: z.boolean().optional(),
+  leak_confirmed_at: z.number().optional(),
+  // Only returned when a signing secret is generated
+  signing_secret: z.string().optional(),
 })
 
 export type ApiKey = z.infer<typeof apiKeySchema>
@@ -94,6 +107,13 @@ export interface ApiKeyFormData {
   group: string
   auto_groups: string[]
   cross_group_retry: boolean
+  end_user_header: string
+  end_user_rate_limit: number
+  end_user_daily_quota: number
+  client_cert: string
+  client_cert_required: boolean
+  signing_required: boolean
+  labels: Record<string, string>
 }
 
 export interface TokenAutoGroupsConfig {
@@ -105,9 +125,19 @@ export interface TokenAutoGroupsConfig {
 // Dialog Types
 // ============================================================================
 
+// A freshly created key; the backend returns the full key only once.
+// key is empty when only a new request signing secret was issued.
+export interface CreatedApiKey {
+  id: number
+  name: string
+  key: string
+  signing_secret?: string
+}
+
 export type ApiKeysDialogType =
   | 'create'
   | 'update'
   | 'delete'
   | 'batch-delete'
   | 'cc-switch'
+  | 'created'
@@ -28,6 +28,8 @@ import type {
   DeleteAccountRequest,
   CheckinStatusResponse,
   CheckinResponse,
+  AlertRule,
+  AlertRuleRequest,
 } from './types'
 
 // ============================================================================
@@ -64,6 +66,55 @@ export async function updateUserSettings(
   return res.data
 }
 
+// ============================================================================
+// Budget Alert Rule APIs
+// ============================================================================
+
+/**
+ * List the current user's budget alert rules
+ */
+export async function getAlertRules(): Promise<ApiResponse<AlertRule[]>> {
+  const res = await api.get('/api/user/alert_rules')
+  return res.data
+}
+
+/**
+ * Create a budget alert rule
+ */
+export async function createAlertRule(
+  data: AlertRuleRequest
+): Promise<ApiResponse<AlertRule>> {
+  const res = await api.post('/api/user/alert_rules', data)
+  return res.data
+}
+
+/**
+ * Update a budget alert rule
+ */
+export async function updateAlertRule(
+  id: number,
+  data: AlertRuleRequest
+): Promise<ApiResponse<AlertRule>> {
+  const res = await api.put(`/api/user/alert_rules/${id}`, data)
+  return res.data
+}
+
+/**
+ * Delete a budget alert rule
+ */
+export async function deleteAlertRule(id: number): Promise<ApiResponse> {
+  const res = await api.delete(`/api/user/alert_rules/${id}`)
+  return res.data
+}
+
+/**
+ * Send a sample alert of a rule through the current notification method
+ */
+export async function testAlertRule(id: number): Promise<ApiResponse> {
+  const res = await api.post(`/api/user/alert_rules/${id}/test`)
+  return res.data
+}
+
 /**
  * Update interface language preference
  */
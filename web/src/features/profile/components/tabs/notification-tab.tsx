@@ -16,7 +16,19 @@ along with this program. If not, see <https://www.gnu.org/licenses/>.
 
 For commercial licensing, please contact support@quantumnous.com
 */
-import { Bell, Loader2, Mail, Server, Webhook } from 'lucide-react'
+import {
+  Bell,
+  Bot,
+  Hash,
+  Loader2,
+  Mail,
+  MessageCircle,
+  MessagesSquare,
+  Send,
+  Server,
+  Users,
+  Webhook,
+} from 'lucide-react'
 import { useState, useEffect, useCallback } from 'react'
 import { useTranslation } from 'react-i18next'
 import { toast } from 'sonner'
@@ -31,6 +43,7 @@ import { ROLE } from '@/lib/roles'
 
 import { updateUserSettings } from '../../api'
 import {
+  CHAT_WEBHOOK_METHODS,
   DEFAULT_QUOTA_WARNING_THRESHOLD,
   NOTIFICATION_METHODS,
 } from '../../constants'
@@ -42,8 +55,16 @@ const NOTIFICATION_ICONS: Record<NotifyType, typeof Mail> = {
   webhook: Webhook,
   bark: Bell,
   gotify: Server,
+  slack: Hash,
+  teams: Users,
+  discord: MessageCircle,
+  telegram: Send,
+  feishu: MessagesSquare,
+  dingtalk: Bot,
 }
 
+const CHAT_WEBHOOK_VALUES = new Set<NotifyType>(CHAT_WEBHOOK_METHODS)
+
 const NOTIFICATION_VALUES = new Set<NotifyType>(
   NOTIFICATION_METHODS.map((method) => method.value)
 )
@@ -78,6 +99,10 @@ export function NotificationTab({ profile, onUpdate }: NotificationTabProps) {
     gotify_url: '',
     gotify_token: '',
     gotify_priority: 5,
+    chat_webhook_url: '',
+    chat_webhook_secret: '',
+    telegram_bot_token: '',
+    telegram_chat_id: '',
     accept_unset_model_ratio_model: false,
     record_ip_log: false,
     upstream_model_update_notify_enabled: false,
@@ -105,6 +130,10 @@ export function NotificationTab({ profile, onUpdate }: NotificationTabProps) {
         gotify_url: parsed.gotify_url ?? '',
         gotify_token: parsed.gotify_token ?? '',
         gotify_priority: parsed.gotify_priority ?? 5,
+        chat_webhook_url: parsed.chat_webhook_url ?? '',
+        chat_webhook_secret: parsed.chat_webhook_secret ?? '',
+        telegram_bot_token: parsed.telegram_bot_token ?? '',
+        telegram_chat_id: parsed.telegram_chat_id ?? '',
         accept_unset_model_ratio_model:
           parsed.accept_unset_model_ratio_model || false,
         record_ip_log: parsed.record_ip_log || false,
@@ -150,7 +179,7 @@ export function NotificationTab({ profile, onUpdate }: NotificationTabProps) {
           variant='outline'
           size='lg'
           spacing={2}
-          className='grid w-full grid-cols-2 gap-2 sm:grid-cols-4 sm:gap-3'
+          className='grid w-full grid-cols-2 gap-2 sm:grid-cols-5 sm:gap-3'
         >
           {NOTIFICATION_METHODS.map((method) => {
             const Icon = NOTIFICATION_ICONS[method.value]
@@ -320,6 +349,64 @@ export function NotificationTab({ profile, onUpdate }: NotificationTabProps) {
         </>
       )}
 
+      {/* Chat Bot Webhook Settings */}
+      {CHAT_WEBHOOK_VALUES.has(notifyType) && (
+        <>
+          <div className='space-y-1.5'>
+            <Label htmlFor='chatWebhookUrl'>{t('Bot Webhook URL')}</Label>
+            <Input
+              id='chatWebhookUrl'
+              type='url'
+              className='h-9'
+              value={settings.chat_webhook_url}
+              onChange={(e) => updateField('chat_webhook_url', e.target.value)}
+              placeholder={t('Incoming webhook URL of the bot')}
+            />
+          </div>
+          {(notifyType === 'feishu' || notifyType === 'dingtalk') && (
+            <div className='space-y-1.5'>
+              <Label htmlFor='chatWebhookSecret'>{t('Signing Secret')}</Label>
+              <PasswordInput
+                id='chatWebhookSecret'
+                value={settings.chat_webhook_secret}
+                onChange={(e) =>
+                  updateField('chat_webhook_secret', e.target.value)
+                }
+                placeholder={t('Leave empty if signature check is disabled')}
+              />
+            </div>
+          )}
+        </>
+      )}
+
+      {/* Telegram Settings */}
+      {notifyType === 'telegram' && (
+        <>
+          <div className='space-y-1.5'>
+            <Label htmlFor='telegramBotToken'>{t('Telegram Bot Token')}</Label>
+            <PasswordInput
+              id='telegramBotToken'
+              value={settings.telegram_bot_token}
+              onChange={(e) => updateField('telegram_bot_token', e.target.value)}
+              placeholder='123456:ABC-DEF...'
+            />
+          </div>
+          <div className='space-y-1.5'>
+            <Label htmlFor='telegramChatId'>{t('Telegram Chat ID')}</Label>
+            <Input
+              id='telegramChatId'
+              className='h-9'
+              value={settings.telegram_chat_id}
+              onChange={(e) => updateField('telegram_chat_id', e.target.value)}
+              placeholder='-1001234567890'
+            />
+            <p className='text-muted-foreground text-xs'>
+              {t('Send a message to the bot first so it can reach the chat')}
+            </p>
+          </div>
+        </>
+      )}
+
       {/* Divider */}
       <div className='border-t' />
 
@@ -33,4 +33,21 @@ export const NOTIFICATION_METHODS = [
   { value: 'webhook' as const, label: 'Webhook' },
   { value: 'bark' as const, label: 'Bark' },
   { value: 'gotify' as const, label: 'Gotify' },
+  { value: 'slack' as const, label: 'Slack' },
+  { value: 'teams' as const, label: 'Microsoft Teams' },
+  { value: 'discord' as const, label: 'Discord' },
+  { value: 'telegram' as const, label: 'Telegram' },
+  { value: 'feishu' as const, label: 'Feishu / Lark' },
+  { value: 'dingtalk' as const, label: 'DingTalk' },
+] as const
+
+/**
+ * Notification methods that post to a chat bot incoming webhook URL
+ */
+export const CHAT_WEBHOOK_METHODS = [
+  'slack',
+  'teams',
+  'discord',
+  'feishu',
+  'dingtalk',
 ] as const
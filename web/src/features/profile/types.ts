@@ -86,7 +86,17 @@ export interface UserProfile {
 /**
  * Notification type
  */
-export type NotifyType = 'email' | 'webhook' | 'bark' | 'gotify'
+export type NotifyType =
+  | 'email'
+  | 'webhook'
+  | 'bark'
+  | 'gotify'
+  | 'slack'
+  | 'teams'
+  | 'discord'
+  | 'telegram'
+  | 'feishu'
+  | 'dingtalk'
 
 /**
  * Parsed user settings
@@ -110,6 +120,14 @@ export interface UserSettings {
   gotify_token?: string
   /** Gotify message priority (0-10) */
   gotify_priority?: number
+  /** Incoming webhook URL of the chat bot */
+  chat_webhook_url?: string
+  /** Signing secret of a Feishu / DingTalk bot */
+  chat_webhook_secret?: string
+  /** Telegram bot token */
+  telegram_bot_token?: string
+  /** Telegram chat ID */
+  telegram_chat_id?: string
   /** Accept unset model ratio model */
   accept_unset_model_ratio_model?: boolean
   /** Record IP log */
@@ -142,11 +160,64 @@ export interface UpdateUserSettingsRequest {
   gotify_url?: string
   gotify_token?: string
   gotify_priority?: number
+  chat_webhook_url?: string
+  chat_webhook_secret?: string
+  telegram_bot_token?: string
+  telegram_chat_id?: string
   accept_unset_model_ratio_model?: boolean
   record_ip_log?: boolean
   upstream_model_update_notify_enabled?: boolean
 }
 
+/**
+ * Budget alert rule type
+ */
+export type AlertRuleType =
+  | 'balance_percent'
+  | 'spend'
+  | 'velocity'
+  | 'channel_error'
+
+/**
+ * Budget alert rule, evaluated every minute and delivered through the
+ * notification method in the user settings
+ */
+export interface AlertRule {
+  id: number
+  name: string
+  type: AlertRuleType
+  enabled: boolean
+  /** Percent, quota, multiplier or error count depending on type */
+  threshold: number
+  /** Spend period: day | week */
+  period?: string
+  /** Window of velocity / channel_error rules */
+  window_minutes?: number
+  /** Velocity rules ignore windows that spent less than this quota */
+  min_quota?: number
+  /** Limit spend / velocity rules to a token, 0 for all */
+  token_id?: number
+  /** Limit spend / velocity rules to a model, empty for all */
+  model_name?: string
+  /** Limit channel_error rules to a channel, 0 for all */
+  channel_id?: number
+  /** Title template, empty for the default */
+  title?: string
+  /** Message template, empty for the default */
+  template?: string
+  last_triggered_at?: number
+  created_at?: number
+  updated_at?: number
+}
+
+/**
+ * Alert rule create / update request
+ */
+export type AlertRuleRequest = Omit<
+  AlertRule,
+  'id' | 'last_triggered_at' | 'created_at' | 'updated_at'
+>
+
 /**
  * Account deletion request
  */
@@ -84,6 +84,8 @@ const TYPE_LABEL: Record<string, string> = {
   model_update: 'Batch upstream model update',
   midjourney_poll: 'Drawing task polling',
   async_task_poll: 'Async task polling',
+  budget_alert: 'Budget alerts',
+  usage_anomaly: 'Usage anomaly detection',
 }
 
 const TYPE_DISPLAY_ID: Record<string, string> = {
@@ -106,6 +106,14 @@ const defaultBillingSettings: BillingSettings = {
   'checkin_setting.enabled': false,
   'checkin_setting.min_quota': 1000,
   'checkin_setting.max_quota': 10000,
+  'anomaly_setting.enabled': false,
+  'anomaly_setting.interval_minutes': 15,
+  'anomaly_setting.lookback_days': 14,
+  'anomaly_setting.min_active_hours': 24,
+  'anomaly_setting.z_score_threshold': 4,
+  'anomaly_setting.min_hourly_quota': 250000,
+  'anomaly_setting.notify_severity': 'high',
+  'anomaly_setting.auto_suspend_severity': '',
 }
 
 export function BillingSettings() {
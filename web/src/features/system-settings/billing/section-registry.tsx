@@ -21,6 +21,7 @@ import { parseCurrencyDisplayType } from '@/lib/currency'
 import { CheckinSettingsSection } from '../general/checkin-settings-section'
 import { PricingSection } from '../general/pricing-section'
 import { QuotaSettingsSection } from '../general/quota-settings-section'
+import { UsageAnomalySettingsSection } from '../general/usage-anomaly-settings-section'
 import { PaymentSettingsSection } from '../integrations/payment-settings-section'
 import { RatioSettingsCard } from '../models/ratio-settings-card'
 import type { BillingSettings } from '../types'
@@ -201,6 +202,25 @@ const BILLING_SECTIONS = [
       />
     ),
   },
+  {
+    id: 'usage-anomaly',
+    titleKey: 'Usage Anomaly Detection',
+    build: (settings: BillingSettings) => (
+      <UsageAnomalySettingsSection
+        defaultValues={{
+          enabled: settings['anomaly_setting.enabled'],
+          intervalMinutes: settings['anomaly_setting.interval_minutes'],
+          lookbackDays: settings['anomaly_setting.lookback_days'],
+          minActiveHours: settings['anomaly_setting.min_active_hours'],
+          zScoreThreshold: settings['anomaly_setting.z_score_threshold'],
+          minHourlyQuota: settings['anomaly_setting.min_hourly_quota'],
+          notifySeverity: settings['anomaly_setting.notify_severity'],
+          autoSuspendSeverity:
+            settings['anomaly_setting.auto_suspend_severity'],
+        }}
+      />
+    ),
+  },
 ] as const
 
 export type BillingSectionId = (typeof BILLING_SECTIONS)[number]['id']
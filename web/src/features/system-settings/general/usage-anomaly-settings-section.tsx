@@ -0,0 +1,297 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { zodResolver } from '@hookform/resolvers/zod'
+import { useForm, type Resolver } from 'react-hook-form'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+import { z } from 'zod'
+
+import {
+  Form,
+  FormControl,
+  FormDescription,
+  FormField,
+  FormItem,
+  FormLabel,
+  FormMessage,
+} from '@/components/ui/form'
+import { Input } from '@/components/ui/input'
+import {
+  Select,
+  SelectContent,
+  SelectGroup,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from '@/components/ui/select'
+import { Switch } from '@/components/ui/switch'
+
+import {
+  SettingsForm,
+  SettingsSwitchContent,
+  SettingsSwitchItem,
+} from '../components/settings-form-layout'
+import { SettingsPageFormActions } from '../components/settings-page-context'
+import { SettingsSection } from '../components/settings-section'
+import { useUpdateOption } from '../hooks/use-update-option'
+
+// The backend stores an empty string when notifications or suspension are off
+const SEVERITY_OFF = 'off'
+const SEVERITIES = ['warning', 'high', 'critical'] as const
+
+const severitySchema = z.enum([SEVERITY_OFF, ...SEVERITIES])
+
+const schema = z.object({
+  enabled: z.boolean(),
+  intervalMinutes: z.coerce.number().int().min(1),
+  lookbackDays: z.coerce.number().int().min(3).max(60),
+  minActiveHours: z.coerce.number().int().min(0),
+  zScoreThreshold: z.coerce.number().positive(),
+  minHourlyQuota: z.coerce.number().int().min(0),
+  notifySeverity: severitySchema,
+  autoSuspendSeverity: severitySchema,
+})
+
+type Values = z.infer<typeof schema>
+
+type UsageAnomalyDefaults = {
+  enabled: boolean
+  intervalMinutes: number
+  lookbackDays: number
+  minActiveHours: number
+  zScoreThreshold: number
+  minHourlyQuota: number
+  notifySeverity: string
+  autoSuspendSeverity: string
+}
+
+const OPTION_KEYS: Record<keyof Values, string> = {
+  enabled: 'anomaly_setting.enabled',
+  intervalMinutes: 'anomaly_setting.interval_minutes',
+  lookbackDays: 'anomaly_setting.lookback_days',
+  minActiveHours: 'anomaly_setting.min_active_hours',
+  zScoreThreshold: 'anomaly_setting.z_score_threshold',
+  minHourlyQuota: 'anomaly_setting.min_hourly_quota',
+  notifySeverity: 'anomaly_setting.notify_severity',
+  autoSuspendSeverity: 'anomaly_setting.auto_suspend_severity',
+}
+
+function toSeverityValue(value: string): Values['notifySeverity'] {
+  const parsed = severitySchema.safeParse(value)
+  return parsed.success ? parsed.data : SEVERITY_OFF
+}
+
+function toOptionValue(values: Values, key: keyof Values) {
+  const value = values[key]
+  return value === SEVERITY_OFF ? '' : String(value)
+}
+
+export function UsageAnomalySettingsSection({
+  defaultValues,
+}: {
+  defaultValues: UsageAnomalyDefaults
+}) {
+  const { t } = useTranslation()
+  const updateOption = useUpdateOption()
+
+  const initialValues: Values = {
+    ...defaultValues,
+    notifySeverity: toSeverityValue(defaultValues.notifySeverity),
+    autoSuspendSeverity: toSeverityValue(defaultValues.autoSuspendSeverity),
+  }
+
+  const form = useForm<Values>({
+    resolver: zodResolver(schema) as unknown as Resolver<Values>,
+    defaultValues: initialValues,
+  })
+
+  const { isDirty, isSubmitting } = form.formState
+  const enabled = form.watch('enabled')
+  const severityItems = [
+    { value: SEVERITY_OFF, label: t('Disabled') },
+    { value: 'warning', label: t('Warning') },
+    { value: 'high', label: t('High') },
+    { value: 'critical', label: t('Critical') },
+  ]
+
+  async function onSubmit(values: Values) {
+    const keys = Object.keys(OPTION_KEYS) as Array<keyof Values>
+    const updates = keys
+      .filter((key) => values[key] !== initialValues[key])
+      .map((key) => ({
+        key: OPTION_KEYS[key],
+        value: toOptionValue(values, key),
+      }))
+
+    if (updates.length === 0) {
+      toast.info(t('No changes to save'))
+      return
+    }
+
+    for (const update of updates) {
+      await updateOption.mutateAsync(update)
+    }
+
+    form.reset(values)
+  }
+
+  const numberField = (
+    name:
+      | 'intervalMinutes'
+      | 'lookbackDays'
+      | 'minActiveHours'
+      | 'zScoreThreshold'
+      | 'minHourlyQuota',
+    label: string,
+    description: string
+  ) => (
+    <FormField
+      control={form.control}
+      name={name}
+      render={({ field }) => (
+        <FormItem>
+          <FormLabel>{label}</FormLabel>
+          <FormControl>
+            <Input type='number' min={0} step='any' {...field} />
+          </FormControl>
+          <FormDescription>{description}</FormDescription>
+          <FormMessage />
+        </FormItem>
+      )}
+    />
+  )
+
+  const severityField = (
+    name: 'notifySeverity' | 'autoSuspendSeverity',
+    label: string,
+    description: string
+  ) => (
+    <FormField
+      control={form.control}
+      name={name}
+      render={({ field }) => (
+        <FormItem>
+          <FormLabel>{label}</FormLabel>
+          <Select
+            items={severityItems}
+            value={field.value}
+            onValueChange={field.onChange}
+          >
+            <FormControl>
+              <SelectTrigger>
+                <SelectValue />
+              </SelectTrigger>
+            </FormControl>
+            <SelectContent alignItemWithTrigger={false}>
+              <SelectGroup>
+                {severityItems.map((item) => (
+                  <SelectItem key={item.value} value={item.value}>
+                    {item.label}
+                  </SelectItem>
+                ))}
+              </SelectGroup>
+            </SelectContent>
+          </Select>
+          <FormDescription>{description}</FormDescription>
+          <FormMessage />
+        </FormItem>
+      )}
+    />
+  )
+
+  return (
+    <SettingsSection title={t('Usage Anomaly Detection')}>
+      <Form {...form}>
+        <SettingsForm onSubmit={form.handleSubmit(onSubmit)} autoComplete='off'>
+          <SettingsPageFormActions
+            onSave={form.handleSubmit(onSubmit)}
+            isSaving={updateOption.isPending || isSubmitting}
+            isSaveDisabled={!isDirty}
+            saveLabel='Save anomaly detection settings'
+          />
+          <FormField
+            control={form.control}
+            name='enabled'
+            render={({ field }) => (
+              <SettingsSwitchItem>
+                <SettingsSwitchContent>
+                  <FormLabel>{t('Enable usage anomaly detection')}</FormLabel>
+                  <FormDescription>
+                    {t(
+                      'Compare hourly usage with each user and token baseline and forecast month-end spend. Requires data dashboard export to be enabled.'
+                    )}
+                  </FormDescription>
+                </SettingsSwitchContent>
+                <FormControl>
+                  <Switch
+                    checked={field.value}
+                    onCheckedChange={field.onChange}
+                    disabled={updateOption.isPending || isSubmitting}
+                  />
+                </FormControl>
+              </SettingsSwitchItem>
+            )}
+          />
+
+          {enabled && (
+            <div className='grid gap-6 sm:grid-cols-2'>
+              {numberField(
+                'intervalMinutes',
+                t('Check interval (minutes)'),
+                t('How often anomalies and forecasts are recalculated')
+              )}
+              {numberField(
+                'lookbackDays',
+                t('Baseline days'),
+                t('Days of hourly history used as the baseline (3-60)')
+              )}
+              {numberField(
+                'minActiveHours',
+                t('Minimum active hours'),
+                t(
+                  'Series with fewer hours of usage in the baseline are skipped'
+                )
+              )}
+              {numberField(
+                'zScoreThreshold',
+                t('Z-score threshold'),
+                t('Warning at this value, high at 2x and critical at 3x')
+              )}
+              {numberField(
+                'minHourlyQuota',
+                t('Minimum hourly quota'),
+                t('Hours below this quota are never reported as anomalies')
+              )}
+              {severityField(
+                'notifySeverity',
+                t('Notify at severity'),
+                t('Notify the user when an anomaly reaches this severity')
+              )}
+              {severityField(
+                'autoSuspendSeverity',
+                t('Auto-suspend at severity'),
+                t('Disable the offending token when this severity is reached')
+              )}
+            </div>
+          )}
+        </SettingsForm>
+      </Form>
+    </SettingsSection>
+  )
+}
@@ -0,0 +1,321 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import { zodResolver } from '@hookform/resolvers/zod'
+import { useForm, type Resolver } from 'react-hook-form'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+import { z } from 'zod'
+
+import {
+  Form,
+  FormControl,
+  FormDescription,
+  FormField,
+  FormItem,
+  FormLabel,
+  FormMessage,
+} from '@/components/ui/form'
+import { Input } from '@/components/ui/input'
+import { Switch } from '@/components/ui/switch'
+import { Textarea } from '@/components/ui/textarea'
+
+import {
+  SettingsForm,
+  SettingsSwitchContent,
+  SettingsSwitchItem,
+} from '../components/settings-form-layout'
+import { SettingsPageFormActions } from '../components/settings-page-context'
+import { SettingsSection } from '../components/settings-section'
+import { useUpdateOption } from '../hooks/use-update-option'
+
+const schema = z.object({
+  enabled: z.boolean(),
+  windowMinutes: z.coerce.number().int().min(1),
+  distinctIpThreshold: z.coerce.number().int().min(0),
+  newNetworkThreshold: z.coerce.number().int().min(0),
+  newCountryThreshold: z.coerce.number().int().min(0),
+  newUserAgentThreshold: z.coerce.number().int().min(0),
+  newOriginThreshold: z.coerce.number().int().min(0),
+  learningHours: z.coerce.number().int().min(0),
+  profileDays: z.coerce.number().int().min(1),
+  countryHeader: z.string(),
+  autoQuarantine: z.boolean(),
+  quarantineModels: z.string(),
+  quarantineRateLimit: z.coerce.number().int().min(0),
+})
+
+type Values = z.infer<typeof schema>
+
+type TokenLeakDefaults = Omit<Values, 'quarantineModels'> & {
+  quarantineModels: string[]
+}
+
+const OPTION_KEYS: Record<keyof Values, string> = {
+  enabled: 'token_leak_setting.enabled',
+  windowMinutes: 'token_leak_setting.window_minutes',
+  distinctIpThreshold: 'token_leak_setting.distinct_ip_threshold',
+  newNetworkThreshold: 'token_leak_setting.new_network_threshold',
+  newCountryThreshold: 'token_leak_setting.new_country_threshold',
+  newUserAgentThreshold: 'token_leak_setting.new_user_agent_threshold',
+  newOriginThreshold: 'token_leak_setting.new_origin_threshold',
+  learningHours: 'token_leak_setting.learning_hours',
+  profileDays: 'token_leak_setting.profile_days',
+  countryHeader: 'token_leak_setting.country_header',
+  autoQuarantine: 'token_leak_setting.auto_quarantine',
+  quarantineModels: 'token_leak_setting.quarantine_models',
+  quarantineRateLimit: 'token_leak_setting.quarantine_rate_limit',
+}
+
+const splitLines = (value: string) =>
+  value
+    .split('\n')
+    .map((entry) => entry.trim())
+    .filter(Boolean)
+
+function toOptionValue(values: Values, key: keyof Values) {
+  if (key === 'quarantineModels') {
+    return JSON.stringify(splitLines(values.quarantineModels))
+  }
+  return String(values[key])
+}
+
+export function TokenLeakSection({
+  defaultValues,
+}: {
+  defaultValues: TokenLeakDefaults
+}) {
+  const { t } = useTranslation()
+  const updateOption = useUpdateOption()
+
+  const initialValues: Values = {
+    ...defaultValues,
+    quarantineModels: (defaultValues.quarantineModels ?? []).join('\n'),
+  }
+
+  const form = useForm<Values>({
+    resolver: zodResolver(schema) as unknown as Resolver<Values>,
+    defaultValues: initialValues,
+  })
+
+  const { isDirty, isSubmitting } = form.formState
+  const enabled = form.watch('enabled')
+  const autoQuarantine = form.watch('autoQuarantine')
+
+  async function onSubmit(values: Values) {
+    const keys = Object.keys(OPTION_KEYS) as Array<keyof Values>
+    const updates = keys
+      .filter((key) => values[key] !== initialValues[key])
+      .map((key) => ({
+        key: OPTION_KEYS[key],
+        value: toOptionValue(values, key),
+      }))
+
+    if (updates.length === 0) {
+      toast.info(t('No changes to save'))
+      return
+    }
+
+    for (const update of updates) {
+      await updateOption.mutateAsync(update)
+    }
+
+    form.reset(values)
+  }
+
+  const numberField = (
+    name:
+      | 'windowMinutes'
+      | 'distinctIpThreshold'
+      | 'newNetworkThreshold'
+      | 'newCountryThreshold'
+      | 'newUserAgentThreshold'
+      | 'newOriginThreshold'
+      | 'learningHours'
+      | 'profileDays'
+      | 'quarantineRateLimit',
+    label: string,
+    description: string
+  ) => (
+    <FormField
+      control={form.control}
+      name={name}
+      render={({ field }) => (
+        <FormItem>
+          <FormLabel>{label}</FormLabel>
+          <FormControl>
+            <Input type='number' min={0} {...field} />
+          </FormControl>
+          <FormDescription>{description}</FormDescription>
+          <FormMessage />
+        </FormItem>
+      )}
+    />
+  )
+
+  const switchField = (
+    name: 'enabled' | 'autoQuarantine',
+    label: string,
+    description: string
+  ) => (
+    <FormField
+      control={form.control}
+      name={name}
+      render={({ field }) => (
+        <SettingsSwitchItem>
+          <SettingsSwitchContent>
+            <FormLabel>{label}</FormLabel>
+            <FormDescription>{description}</FormDescription>
+          </SettingsSwitchContent>
+          <FormControl>
+            <Switch
+              checked={field.value}
+              onCheckedChange={field.onChange}
+              disabled={updateOption.isPending || isSubmitting}
+            />
+          </FormControl>
+        </SettingsSwitchItem>
+      )}
+    />
+  )
+
+  return (
+    <SettingsSection title={t('Leaked Key Detection')}>
+      <Form {...form}>
+        <SettingsForm onSubmit={form.handleSubmit(onSubmit)} autoComplete='off'>
+          <SettingsPageFormActions
+            onSave={form.handleSubmit(onSubmit)}
+            isSaving={updateOption.isPending || isSubmitting}
+            isSaveDisabled={!isDirty}
+            saveLabel='Save leaked key detection settings'
+          />
+          {switchField(
+            'enabled',
+            t('Enable leaked key detection'),
+            t(
+              'Flag API keys whose source networks, countries, clients or origins suddenly change and notify the owner'
+            )
+          )}
+
+          {enabled && (
+            <>
+              <div className='grid gap-6 sm:grid-cols-2'>
+                {numberField(
+                  'windowMinutes',
+                  t('Detection window (minutes)'),
+                  t('Signals are counted within this sliding window')
+                )}
+                {numberField(
+                  'distinctIpThreshold',
+                  t('Distinct IP threshold'),
+                  t('Flag when this many source IPs appear in the window')
+                )}
+                {numberField(
+                  'newNetworkThreshold',
+                  t('New network threshold'),
+                  t('New /24 (IPv4) or /48 (IPv6) networks; 0 disables')
+                )}
+                {numberField(
+                  'newCountryThreshold',
+                  t('New country threshold'),
+                  t('New countries from the country header; 0 disables')
+                )}
+                {numberField(
+                  'newUserAgentThreshold',
+                  t('New client threshold'),
+                  t('New User-Agent client names; 0 disables')
+                )}
+                {numberField(
+                  'newOriginThreshold',
+                  t('New origin threshold'),
+                  t('New Origin headers; 0 disables')
+                )}
+                {numberField(
+                  'learningHours',
+                  t('Learning period (hours)'),
+                  t(
+                    'New keys and keys marked as safe are only profiled during this period'
+                  )
+                )}
+                {numberField(
+                  'profileDays',
+                  t('Profile retention (days)'),
+                  t('Networks and clients not seen for this long are forgotten')
+                )}
+              </div>
+              <FormField
+                control={form.control}
+                name='countryHeader'
+                render={({ field }) => (
+                  <FormItem>
+                    <FormLabel>{t('Country header')}</FormLabel>
+                    <FormControl>
+                      <Input placeholder='CF-IPCountry' {...field} />
+                    </FormControl>
+                    <FormDescription>
+                      {t(
+                        'Request header set by a trusted proxy with the client country code; leave empty to skip country checks'
+                      )}
+                    </FormDescription>
+                    <FormMessage />
+                  </FormItem>
+                )}
+              />
+              {switchField(
+                'autoQuarantine',
+                t('Quarantine flagged keys'),
+                t(
+                  'Flagged keys can only call the models below at a low rate until the owner marks them as safe or rotates them'
+                )
+              )}
+              {autoQuarantine && (
+                <div className='grid gap-6 sm:grid-cols-2'>
+                  <FormField
+                    control={form.control}
+                    name='quarantineModels'
+                    render={({ field }) => (
+                      <FormItem>
+                        <FormLabel>{t('Quarantine models')}</FormLabel>
+                        <FormControl>
+                          <Textarea rows={4} {...field} />
+                        </FormControl>
+                        <FormDescription>
+                          {t(
+                            'One model per line; leave empty to block all requests'
+                          )}
+                        </FormDescription>
+                        <FormMessage />
+                      </FormItem>
+                    )}
+                  />
+                  {numberField(
+                    'quarantineRateLimit',
+                    t('Quarantine rate limit'),
+                    t(
+                      'Requests per minute for a quarantined key; 0 is unlimited'
+                    )
+                  )}
+                </div>
+              )}
+            </>
+          )}
+        </SettingsForm>
+      </Form>
+    </SettingsSection>
+  )
+}
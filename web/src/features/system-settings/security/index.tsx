@@ -42,6 +42,19 @@ const defaultSecuritySettings: SecuritySettings = {
   'fetch_setting.allowed_ports': [],
   'fetch_setting.apply_ip_filter_for_domain': false,
   'token_setting.max_user_tokens': 1000,
+  'token_leak_setting.enabled': false,
+  'token_leak_setting.window_minutes': 10,
+  'token_leak_setting.distinct_ip_threshold': 30,
+  'token_leak_setting.new_network_threshold': 5,
+  'token_leak_setting.new_country_threshold': 2,
+  'token_leak_setting.new_user_agent_threshold': 3,
+  'token_leak_setting.new_origin_threshold': 1,
+  'token_leak_setting.learning_hours': 24,
+  'token_leak_setting.profile_days': 7,
+  'token_leak_setting.country_header': 'CF-IPCountry',
+  'token_leak_setting.auto_quarantine': false,
+  'token_leak_setting.quarantine_models': [],
+  'token_leak_setting.quarantine_rate_limit': 10,
 }
 
 export function SecuritySettings() {
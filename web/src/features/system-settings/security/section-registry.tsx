@@ -19,6 +19,7 @@ For commercial licensing, please contact support@quantumnous.com
 import { RateLimitSection } from '../request-limits/rate-limit-section'
 import { SensitiveWordsSection } from '../request-limits/sensitive-words-section'
 import { SSRFSection } from '../request-limits/ssrf-section'
+import { TokenLeakSection } from '../request-limits/token-leak-section'
 import { TokenLimitSection } from '../request-limits/token-limit-section'
 import type { SecuritySettings } from '../types'
 import { createSectionRegistry } from '../utils/section-registry'
@@ -90,6 +91,35 @@ const SECURITY_SECTIONS = [
       />
     ),
   },
+  {
+    id: 'token-leak',
+    titleKey: 'Leaked Key Detection',
+    build: (settings: SecuritySettings) => (
+      <TokenLeakSection
+        defaultValues={{
+          enabled: settings['token_leak_setting.enabled'],
+          windowMinutes: settings['token_leak_setting.window_minutes'],
+          distinctIpThreshold:
+            settings['token_leak_setting.distinct_ip_threshold'],
+          newNetworkThreshold:
+            settings['token_leak_setting.new_network_threshold'],
+          newCountryThreshold:
+            settings['token_leak_setting.new_country_threshold'],
+          newUserAgentThreshold:
+            settings['token_leak_setting.new_user_agent_threshold'],
+          newOriginThreshold:
+            settings['token_leak_setting.new_origin_threshold'],
+          learningHours: settings['token_leak_setting.learning_hours'],
+          profileDays: settings['token_leak_setting.profile_days'],
+          countryHeader: settings['token_leak_setting.country_header'],
+          autoQuarantine: settings['token_leak_setting.auto_quarantine'],
+          quarantineModels: settings['token_leak_setting.quarantine_models'],
+          quarantineRateLimit:
+            settings['token_leak_setting.quarantine_rate_limit'],
+        }}
+      />
+    ),
+  },
 ] as const
 
 export type SecuritySectionId = (typeof SECURITY_SECTIONS)[number]['id']
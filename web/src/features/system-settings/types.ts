@@ -334,6 +334,14 @@ export type BillingSettings = {
   'checkin_setting.enabled': boolean
   'checkin_setting.min_quota': number
   'checkin_setting.max_quota': number
+  'anomaly_setting.enabled': boolean
+  'anomaly_setting.interval_minutes': number
+  'anomaly_setting.lookback_days': number
+  'anomaly_setting.min_active_hours': number
+  'anomaly_setting.z_score_threshold': number
+  'anomaly_setting.min_hourly_quota': number
+  'anomaly_setting.notify_severity': string
+  'anomaly_setting.auto_suspend_severity': string
 }
 
 export type OperationsSettings = {
@@ -386,6 +394,19 @@ export type SecuritySettings = {
   'fetch_setting.allowed_ports': number[]
   'fetch_setting.apply_ip_filter_for_domain': boolean
   'token_setting.max_user_tokens': number
+  'token_leak_setting.enabled': boolean
+  'token_leak_setting.window_minutes': number
+  'token_leak_setting.distinct_ip_threshold': number
+  'token_leak_setting.new_network_threshold': number
+  'token_leak_setting.new_country_threshold': number
+  'token_leak_setting.new_user_agent_threshold': number
+  'token_leak_setting.new_origin_threshold': number
+  'token_leak_setting.learning_hours': number
+  'token_leak_setting.profile_days': number
+  'token_leak_setting.country_header': string
+  'token_leak_setting.auto_quarantine': boolean
+  'token_leak_setting.quarantine_models': string[]
+  'token_leak_setting.quarantine_rate_limit': number
 }
 
 export type UpstreamChannel = {
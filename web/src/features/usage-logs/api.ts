@@ -26,6 +26,8 @@ import type {
   GetLogStatsResponse,
   GetMidjourneyLogsParams,
   GetTaskLogsParams,
+  UsageExportRequest,
+  UsageExportResponse,
   UserInfo,
 } from './types'
 
@@ -110,3 +112,25 @@ export const getAllTaskLogs = (params: GetTaskLogsParams) =>
 
 export const getUserTaskLogs = (params: GetTaskLogsParams) =>
   fetchLogs('/api/task', params, false)
+
+// ============================================================================
+// Usage Export API
+// ============================================================================
+
+export async function createUsageExport(
+  params: UsageExportRequest,
+  isAdmin: boolean
+): Promise<UsageExportResponse> {
+  const path = buildApiPath('/api/log', isAdmin)
+  const res = await api.post(`${path}/export`, params)
+  return res.data
+}
+
+export async function getUsageExport(
+  taskId: string,
+  isAdmin: boolean
+): Promise<UsageExportResponse> {
+  const path = buildApiPath('/api/log', isAdmin)
+  const res = await api.get(`${path}/export/${taskId}`)
+  return res.data
+}
@@ -16,7 +16,8 @@ along with this program. If not, see <https://www.gnu.org/licenses/>.
 
 For commercial licensing, please contact support@quantumnous.com
 */
-import { Eye, EyeOff } from 'lucide-react'
+import { useState } from 'react'
+import { Eye, EyeOff, FileDown } from 'lucide-react'
 import { useTranslation } from 'react-i18next'
 
 import { Button } from '@/components/ui/button'
@@ -27,18 +28,22 @@ import {
 } from '@/components/ui/tooltip'
 
 import { CommonLogsStats } from './common-logs-stats'
-import { useUsageLogsContext } from './usage-logs-provider'
+import { ExportLogsDialog } from './dialogs/export-logs-dialog'
+import { useLogsViewScope, useUsageLogsContext } from './usage-logs-provider'
 
 /**
  * Page-header actions for the Common Logs view: live usage stats plus a
  * toggle for masking sensitive values (token names, usernames, group names,
  * and the quota figure shown in stats). Both controls live in the page
  * header so the toolbar below stays focused on filter inputs and form
- * actions only.
+ * actions only. The export button exports the rows matching the current
+ * filters.
  */
 export function CommonLogsHeaderActions() {
   const { t } = useTranslation()
   const { sensitiveVisible, setSensitiveVisible } = useUsageLogsContext()
+  const { isAdminView: isAdmin } = useLogsViewScope()
+  const [exportOpen, setExportOpen] = useState(false)
 
   return (
     <div className='flex flex-wrap items-center gap-2'>
@@ -61,6 +66,27 @@ export function CommonLogsHeaderActions() {
           {sensitiveVisible ? t('Hide') : t('Show')}
         </TooltipContent>
       </Tooltip>
+      <Tooltip>
+        <TooltipTrigger
+          render={
+            <Button
+              variant='ghost'
+              size='icon'
+              onClick={() => setExportOpen(true)}
+              aria-label={t('Export Logs')}
+              className='text-muted-foreground hover:text-foreground size-7'
+            />
+          }
+        >
+          <FileDown />
+        </TooltipTrigger>
+        <TooltipContent>{t('Export Logs')}</TooltipContent>
+      </Tooltip>
+      <ExportLogsDialog
+        isAdmin={isAdmin}
+        open={exportOpen}
+        onOpenChange={setExportOpen}
+      />
     </div>
   )
 }
@@ -66,6 +66,7 @@ import { cn } from '@/lib/utils'
 import type { UsageLog } from '../../data/schema'
 import {
   parseLogOther,
+  formatLogLabels,
   getParamOverrideActionLabel,
   parseAuditLine,
   decodeBillingExprB64,
@@ -680,6 +681,22 @@ export function DetailsDialog(props: DetailsDialogProps) {
             <DetailRow label={t('Token')} value={props.log.token_name} mono />
           )}
 
+          {props.log.end_user_id && (
+            <DetailRow
+              label={t('End User')}
+              value={props.log.end_user_id}
+              mono
+            />
+          )}
+
+          {props.log.labels && (
+            <DetailRow
+              label={t('Labels')}
+              value={formatLogLabels(props.log.labels)}
+              mono
+            />
+          )}
+
           {(props.log.group || other?.group) && (
             <DetailRow
               label={t('Group')}
@@ -0,0 +1,204 @@
+/*
+Copyright (C) 2023-2026 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+import { useState } from 'react'
+import { useMutation, useQuery } from '@tanstack/react-query'
+import { getRouteApi } from '@tanstack/react-router'
+import { Download } from 'lucide-react'
+import { useTranslation } from 'react-i18next'
+import { toast } from 'sonner'
+
+import { Dialog } from '@/components/dialog'
+import { Button } from '@/components/ui/button'
+import { Label } from '@/components/ui/label'
+import { Progress } from '@/components/ui/progress'
+import {
+  Select,
+  SelectContent,
+  SelectGroup,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from '@/components/ui/select'
+
+import { createUsageExport, getUsageExport } from '../../api'
+import { buildApiParams } from '../../lib/utils'
+import type { UsageExportFormat } from '../../types'
+
+const route = getRouteApi('/_authenticated/usage-logs/$section')
+
+const EXPORT_POLL_INTERVAL_MS = 2000
+
+const EXPORT_FORMATS: Array<{ value: UsageExportFormat; label: string }> = [
+  { value: 'csv', label: 'CSV' },
+  { value: 'jsonl', label: 'JSONL' },
+  { value: 'parquet', label: 'Parquet' },
+]
+
+interface ExportLogsDialogProps {
+  isAdmin: boolean
+  open: boolean
+  onOpenChange: (open: boolean) => void
+}
+
+/**
+ * Exports the logs matching the current filters as a background task. The
+ * dialog polls the task and offers a short-lived signed download link once
+ * the file is ready.
+ */
+export function ExportLogsDialog({
+  isAdmin,
+  open,
+  onOpenChange,
+}: ExportLogsDialogProps) {
+  const { t } = useTranslation()
+  const searchParams = route.useSearch()
+  const [format, setFormat] = useState<UsageExportFormat>('csv')
+  const [taskId, setTaskId] = useState<string | null>(null)
+
+  const createMutation = useMutation({
+    mutationFn: () => {
+      const params = buildApiParams({
+        page: 1,
+        pageSize: 1,
+        searchParams,
+        isAdmin,
+      })
+      return createUsageExport(
+        {
+          format,
+          type: params.type,
+          start_timestamp: params.start_timestamp,
+          end_timestamp: params.end_timestamp,
+          username: params.username,
+          token_name: params.token_name,
+          model_name: params.model_name,
+          group: params.group,
+          channel: params.channel,
+        },
+        isAdmin
+      )
+    },
+    onSuccess: (res) => {
+      if (!res.success || !res.data) {
+        toast.error(res.message || t('Failed to start export'))
+        return
+      }
+      setTaskId(res.data.task_id)
+    },
+  })
+
+  const { data: task } = useQuery({
+    queryKey: ['usage-logs-export', isAdmin, taskId],
+    queryFn: async () => {
+      const res = await getUsageExport(taskId as string, isAdmin)
+      if (!res.success || !res.data) {
+        throw new Error(res.message || t('Failed to load export'))
+      }
+      return res.data
+    },
+    enabled: open && !!taskId,
+    refetchInterval: (query) => {
+      const status = query.state.data?.status
+      return status === 'succeeded' || status === 'failed'
+        ? false
+        : EXPORT_POLL_INTERVAL_MS
+    },
+  })
+
+  const running =
+    createMutation.isPending ||
+    (!!taskId && task?.status !== 'succeeded' && task?.status !== 'failed')
+  const progress = task?.state?.progress ?? 0
+
+  const handleOpenChange = (nextOpen: boolean) => {
+    if (!nextOpen && !running) {
+      setTaskId(null)
+    }
+    onOpenChange(nextOpen)
+  }
+
+  return (
+    <Dialog
+      open={open}
+      onOpenChange={handleOpenChange}
+      title={t('Export Logs')}
+      description={t(
+        'Export the logs matching the current filters. Large exports run in the background.'
+      )}
+      contentClassName='sm:max-w-md'
+      contentHeight='auto'
+      bodyClassName='space-y-4'
+      footer={
+        task?.status === 'succeeded' && task.download_url ? (
+          <Button
+            render={<a href={task.download_url} download />}
+          >
+            <Download />
+            {t('Download')}
+          </Button>
+        ) : (
+          <Button onClick={() => createMutation.mutate()} disabled={running}>
+            {running ? t('Exporting...') : t('Start Export')}
+          </Button>
+        )
+      }
+    >
+      <div className='space-y-2'>
+        <Label>{t('Format')}</Label>
+        <Select
+          items={EXPORT_FORMATS}
+          value={format}
+          onValueChange={(value) => {
+            if (value) setFormat(value as UsageExportFormat)
+          }}
+          disabled={running}
+        >
+          <SelectTrigger className='w-full'>
+            <SelectValue />
+          </SelectTrigger>
+          <SelectContent alignItemWithTrigger={false}>
+            <SelectGroup>
+              {EXPORT_FORMATS.map((item) => (
+                <SelectItem key={item.value} value={item.value}>
+                  {item.label}
+                </SelectItem>
+              ))}
+            </SelectGroup>
+          </SelectContent>
+        </Select>
+      </div>
+      {taskId && task?.status !== 'failed' ? (
+        <div className='space-y-2'>
+          <Progress value={progress} />
+          <p className='text-muted-foreground text-xs'>
+            {task?.status === 'succeeded'
+              ? t('{{count}} rows exported. The link expires in 5 minutes.', {
+                  count: task.result?.rows ?? 0,
+                })
+              : t('{{progress}}% exported', { progress })}
+          </p>
+        </div>
+      ) : null}
+      {task?.status === 'failed' ? (
+        <p className='text-destructive text-sm'>
+          {task.error || t('Export failed')}
+        </p>
+      ) : null}
+    </Dialog>
+  )
+}
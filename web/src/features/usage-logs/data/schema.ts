@@ -45,6 +45,8 @@ export const usageLogSchema = z.object({
   other: z.string().default(''),
   request_id: z.string().default(''),
   upstream_request_id: z.string().default(''),
+  end_user_id: z.string().default(''),
+  labels: z.string().default(''),
 })
 
 export type UsageLog = z.infer<typeof usageLogSchema>
@@ -58,6 +58,7 @@ export function buildSearchParams(
         ...(commonFilters.upstreamRequestId && {
           upstreamRequestId: commonFilters.upstreamRequestId,
         }),
+        ...(commonFilters.endUserId && { endUserId: commonFilters.endUserId }),
       }
     }
     case 'drawing': {
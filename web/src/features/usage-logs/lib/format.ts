@@ -167,6 +167,20 @@ export function parseLogOther(other: string): LogOtherData | null {
   }
 }
 
+/**
+ * Format the JSON-encoded cost-allocation labels as "key=value" pairs
+ */
+export function formatLogLabels(labels: string): string {
+  if (!labels) return ''
+  try {
+    return Object.entries(JSON.parse(labels) as Record<string, string>)
+      .map(([key, value]) => `${key}=${value}`)
+      .join(', ')
+  } catch {
+    return labels
+  }
+}
+
 export function getReasoningEffortVariant(
   effort: string | undefined
 ): StatusBadgeProps['variant'] {
@@ -405,6 +419,17 @@ const AUDIT_TEMPLATES: Record<string, string> = {
   'user.topup_complete': 'Completed top-up order for the user',
   'user.reset_passkey': 'Reset the user passkey',
   'user.oauth_unbind': 'Removed an OAuth binding for the user',
+  // Token management
+  'token.admin_enable': 'Enabled token {{name}} (ID: {{id}}) of the user',
+  'token.admin_disable': 'Disabled token {{name}} (ID: {{id}}) of the user',
+  // Usage logs
+  'log.export': 'Exported usage logs as {{format}}',
+  // Authorization roles
+  'authz.role_create': 'Created authorization role {{name}} ({{key}})',
+  'authz.role_update': 'Updated authorization role {{name}} ({{key}})',
+  'authz.role_delete': 'Deleted authorization role {{key}}',
+  'authz.user_role':
+    'Set authorization role of user {{username}} to {{role}}',
   // System settings
   'option.update': 'Updated system setting {{key}}',
   'option.payment_compliance': 'Confirmed payment compliance',
@@ -414,6 +439,12 @@ const AUDIT_TEMPLATES: Record<string, string> = {
   'custom_oauth.create': 'Created a custom OAuth provider',
   'custom_oauth.update': 'Updated a custom OAuth provider',
   'custom_oauth.delete': 'Deleted a custom OAuth provider',
+  // Workload identity
+  'workload_identity.create': 'Created a workload identity issuer',
+  'workload_identity.update': 'Updated a workload identity issuer',
+  'workload_identity.delete': 'Deleted a workload identity issuer',
+  'workload_identity.token_issue':
+    '{{issuer}} issued gateway key {{tokenName}} to workload {{subject}} ({{mode}})',
   // Performance / cache
   'performance.clear_disk_cache': 'Cleared disk cache',
   'performance.gc': 'Triggered garbage collection',
@@ -464,6 +495,25 @@ const AUDIT_TEMPLATES: Record<string, string> = {
   'subscription.plan_create': 'Created a subscription plan',
   'subscription.plan_update': 'Updated a subscription plan',
   'subscription.bind': 'Bound a subscription',
+  // SCIM provisioning
+  'scim.user_create': 'SCIM provisioned user {{username}} ({{userName}})',
+  'scim.user_update': 'SCIM updated {{fields}} of user {{username}}',
+  'scim.user_deactivate': 'SCIM deactivated user {{username}}',
+  'scim.user_reactivate': 'SCIM reactivated user {{username}}',
+  'scim.user_delete': 'SCIM deleted user {{username}} ({{userName}})',
+  'scim.user_access_sync':
+    'SCIM set user {{username}} to group {{group}} and role {{role}}',
+  'scim.group_create': 'SCIM created group {{displayName}} (ID: {{id}})',
+  'scim.group_update': 'SCIM updated group {{displayName}} (ID: {{id}})',
+  'scim.group_delete': 'SCIM deleted group {{displayName}} (ID: {{id}})',
+  'scim.group_members':
+    'SCIM changed members of group {{displayName}} (added {{added}}, removed {{removed}})',
+  // LDAP login
+  'ldap.user_access_sync':
+    'LDAP set user {{username}} to group {{group}} and role {{role}}',
+  // OIDC / custom OAuth claim mapping
+  'oauth.user_access_sync':
+    '{{provider}} set user {{username}} to group {{group}}, role {{role}} and usable groups {{usableGroups}}',
   // Logs
   'log.clear': 'Cleared historical logs',
   'log.cleanup_start': 'Log cleanup task started.',
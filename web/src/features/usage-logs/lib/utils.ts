@@ -215,6 +215,9 @@ export function buildApiParams(config: {
     ...(searchParams.upstreamRequestId
       ? { upstream_request_id: String(searchParams.upstreamRequestId) }
       : {}),
+    ...(searchParams.endUserId
+      ? { end_user_id: String(searchParams.endUserId) }
+      : {}),
     ...buildTimeRangeParams(searchParams, false),
   }
 
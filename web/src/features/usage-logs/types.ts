@@ -54,6 +54,7 @@ export interface CommonLogFilters extends CommonFilters {
   username?: string
   requestId?: string
   upstreamRequestId?: string
+  endUserId?: string
 }
 
 /**
@@ -324,6 +325,7 @@ export interface GetLogsParams {
   group?: string
   request_id?: string
   upstream_request_id?: string
+  end_user_id?: string
 }
 
 export interface GetLogsResponse {
@@ -415,3 +417,44 @@ export interface UserInfo {
   aff_quota?: number
   remark?: string
 }
+
+// ============================================================================
+// Usage Export Types
+// ============================================================================
+
+export type UsageExportFormat = 'csv' | 'jsonl' | 'parquet'
+
+export interface UsageExportRequest {
+  format: UsageExportFormat
+  type?: number
+  start_timestamp?: number
+  end_timestamp?: number
+  username?: string
+  token_name?: string
+  model_name?: string
+  group?: string
+  channel?: number
+}
+
+export interface UsageExportTask {
+  task_id: string
+  status: 'pending' | 'running' | 'succeeded' | 'failed'
+  state?: { total?: number; processed?: number; progress?: number } | null
+  result?: {
+    file_name: string
+    format: UsageExportFormat
+    rows: number
+    size: number
+    expires_at: number
+  } | null
+  error: string
+  download_url?: string
+  created_at: number
+  updated_at: number
+}
+
+export interface UsageExportResponse {
+  success: boolean
+  message?: string
+  data?: UsageExportTask
+}
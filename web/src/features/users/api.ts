@@ -28,6 +28,7 @@ import type {
   UserFormData,
   ManageUserAction,
   ManageUserQuotaPayload,
+  UserAuthzRoleResult,
   ApiResponse,
 } from './types'
 
@@ -175,6 +176,18 @@ export async function getPermissionCatalog(): Promise<PermissionCatalog> {
   }
 }
 
+/**
+ * Assign a custom authorization role to an admin user (root only).
+ * An empty role restores the built-in admin baseline.
+ */
+export async function setUserAuthzRole(
+  id: number,
+  role: string
+): Promise<ApiResponse<UserAuthzRoleResult>> {
+  const res = await api.put(`/api/authz/users/${id}/role`, { role })
+  return res.data
+}
+
 // ============================================================================
 // Admin Binding Management APIs
 // ============================================================================
@@ -65,6 +65,7 @@ import { Textarea } from '@/components/ui/textarea'
 import {
   ADMIN_PERMISSION_ACTIONS,
   ADMIN_PERMISSION_RESOURCES,
+  ADMIN_ROLE_KEY,
   EMPTY_PERMISSION_CATALOG,
   hasPermission,
   normalizeAdminPermissions,
@@ -80,6 +81,7 @@ import {
   getUser,
   getGroups,
   getPermissionCatalog,
+  setUserAuthzRole,
 } from '../api'
 import { BINDING_FIELDS, ERROR_MESSAGES, SUCCESS_MESSAGES } from '../constants'
 import {
@@ -110,6 +112,8 @@ export function UsersMutateDrawer({
   const currentUser = useAuthStore((s) => s.auth.user)
   const [isSubmitting, setIsSubmitting] = useState(false)
   const [quotaDialogOpen, setQuotaDialogOpen] = useState(false)
+  // Custom authorization role of an admin user; empty means the admin baseline
+  const [authzRole, setAuthzRole] = useState('')
 
   // Fetch groups
   const { data: groupsData } = useQuery({
@@ -139,6 +143,7 @@ export function UsersMutateDrawer({
       getUser(currentRow.id).then((result) => {
         if (result.success && result.data) {
           form.reset(transformUserToFormDefaults(result.data))
+          setAuthzRole(result.data.authz_role ?? '')
         }
       })
     } else if (open && !isUpdate) {
@@ -155,6 +160,27 @@ export function UsersMutateDrawer({
   const selectedRole = form.watch('role')
   const canEditAdminPermissions = currentUser?.role === ROLE.SUPER_ADMIN
   const targetIsAdmin = (selectedRole ?? currentRow?.role ?? 0) >= ROLE.ADMIN
+  const customRoles = permissionCatalog.roles.filter(
+    (role) => !role.built_in && (role.enabled || role.key === authzRole)
+  )
+  const canAssignAuthzRole =
+    canEditAdminPermissions &&
+    isUpdate &&
+    currentRow?.role === ROLE.ADMIN &&
+    customRoles.length > 0
+
+  const handleAuthzRoleChange = async (value: string) => {
+    if (!currentRow) return
+    const role = value === ADMIN_ROLE_KEY ? '' : value
+    const result = await setUserAuthzRole(currentRow.id, role)
+    if (!result.success || !result.data) {
+      toast.error(result.message || t(ERROR_MESSAGES.UPDATE_FAILED))
+      return
+    }
+    setAuthzRole(result.data.authz_role)
+    form.setValue('admin_permissions', result.data.admin_permissions)
+    toast.success(t('Authorization role updated'))
+  }
 
   const onSubmit = async (data: UserFormValues) => {
     if (!isUpdate) {
@@ -462,6 +488,45 @@ export function UsersMutateDrawer({
                         'Default administrator permissions can be overridden for this user.'
                       )}
                     </p>
+                    {canAssignAuthzRole && (
+                      <div className='space-y-2'>
+                        <Label>{t('Authorization Role')}</Label>
+                        <Select
+                          items={[
+                            { value: ADMIN_ROLE_KEY, label: t('Admin') },
+                            ...customRoles.map((role) => ({
+                              value: role.key,
+                              label: role.name,
+                            })),
+                          ]}
+                          onValueChange={(value) =>
+                            value !== null && handleAuthzRoleChange(value)
+                          }
+                          value={authzRole || ADMIN_ROLE_KEY}
+                        >
+                          <SelectTrigger>
+                            <SelectValue />
+                          </SelectTrigger>
+                          <SelectContent alignItemWithTrigger={false}>
+                            <SelectGroup>
+                              <SelectItem value={ADMIN_ROLE_KEY}>
+                                {t('Admin')}
+                              </SelectItem>
+                              {customRoles.map((role) => (
+                                <SelectItem key={role.key} value={role.key}>
+                                  {role.name}
+                                </SelectItem>
+                              ))}
+                            </SelectGroup>
+                          </SelectContent>
+                        </Select>
+                        <p className='text-muted-foreground text-xs'>
+                          {t(
+                            'The role replaces the default administrator permissions and applies immediately.'
+                          )}
+                        </p>
+                      </div>
+                    )}
                     <FormField
                       control={form.control}
                       name='admin_permissions'
@@ -62,6 +62,7 @@ export const userSchema = z.object({
   admin_permissions: z
     .record(z.string(), z.record(z.string(), z.boolean()))
     .optional(),
+  authz_role: z.string().optional(),
 })
 export type User = z.infer<typeof userSchema>
 
@@ -128,6 +129,11 @@ export interface UserFormData {
   admin_permissions?: AdminPermissionMatrix
 }
 
+export interface UserAuthzRoleResult {
+  authz_role: string
+  admin_permissions: AdminPermissionMatrix
+}
+
 export type ManageUserAction =
   | 'promote'
   | 'demote'
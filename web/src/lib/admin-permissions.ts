@@ -57,8 +57,10 @@ export interface PermissionResourceDef {
 export interface PermissionRoleDef {
   key: string
   name: string
+  description?: string
   built_in: boolean
   superuser: boolean
+  enabled: boolean
   grants: AdminPermissionMatrix
 }
 